@@ -0,0 +1,384 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bandwidth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/projectcalico/felix/netlinkshim"
+)
+
+const (
+	// ifbPrefix is the name prefix of the IFB devices that we create to shape the traffic from workloads.  The name
+	// is completed with a hash of the workload interface name.
+	ifbPrefix  = "bwcal"
+	ifbHashLen = 10
+
+	// latencyMicros is the maximum time that a packet can wait in a TBF queue before being dropped.
+	latencyMicros = 25000
+
+	// minDefaultBurstBits is the smallest burst that we use when a limit has no burst size.  It must be larger than
+	// the MTU or the TBF would never send a packet.
+	minDefaultBurstBits = 64 * 1024 * 8
+)
+
+var (
+	tbfHandle     = netlink.MakeHandle(1, 0)
+	ingressHandle = netlink.MakeHandle(0xffff, 0)
+)
+
+// Limits are the bandwidth limits for a workload interface.  Ingress is traffic to the workload and egress is traffic
+// from the workload.  Rates are in bits per second and bursts are in bits; a zero rate means no limit and a zero burst
+// means a default burst of 100ms of traffic at the rate.
+type Limits struct {
+	IngressRate  uint64
+	IngressBurst uint64
+	EgressRate   uint64
+	EgressBurst  uint64
+}
+
+// Shaper programs tc qdiscs to enforce the bandwidth limits of workload interfaces, in the same way as the CNI
+// bandwidth plugin.  Traffic to the workload is shaped by a TBF qdisc at the root of the workload's host-side
+// interface.  Traffic from the workload arrives as ingress traffic on that interface, so we redirect it to an IFB
+// device and shape it with a TBF qdisc at the root of the IFB device.
+//
+// The redirect needs an ingress qdisc on the workload interface, which clashes with the clsact qdisc that the BPF
+// dataplane uses, so egress limits can be disabled.
+type Shaper struct {
+	egressEnabled bool
+
+	newNetlinkHandle    func() (netlinkshim.Interface, error)
+	cachedNetlinkHandle netlinkshim.Interface
+}
+
+func New(egressEnabled bool) *Shaper {
+	return NewWithShims(egressEnabled, netlinkshim.NewRealNetlink)
+}
+
+func NewWithShims(egressEnabled bool, newNetlinkHandle func() (netlinkshim.Interface, error)) *Shaper {
+	return &Shaper{
+		egressEnabled:    egressEnabled,
+		newNetlinkHandle: newNetlinkHandle,
+	}
+}
+
+// SetLimits programs the bandwidth limits for a workload interface, and removes any limits that are no longer
+// required.  Removing all the limits also removes the interface's IFB device, even if the interface no longer exists.
+func (s *Shaper) SetLimits(ifaceName string, limits Limits) (err error) {
+	nl, err := s.getNetlink()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil && !netlinkshim.IsNotExist(err) {
+			// Reconnect next time in case the failure was due to the netlink socket.
+			s.closeNetlink()
+		}
+	}()
+
+	link, err := nl.LinkByName(ifaceName)
+	if netlinkshim.IsNotExist(err) && limits == (Limits{}) {
+		log.WithField("ifaceName", ifaceName).Debug("Interface is gone, removing its IFB device")
+		return deleteIFB(nl, ifbName(ifaceName))
+	} else if err != nil {
+		return err
+	}
+
+	if err = setIngressLimit(nl, link, limits.IngressRate, limits.IngressBurst); err != nil {
+		return err
+	}
+	egressRate := limits.EgressRate
+	if egressRate != 0 && !s.egressEnabled {
+		log.WithField("ifaceName", ifaceName).Warn(
+			"Workload has an egress bandwidth limit but egress limits are not supported, ignoring it")
+		egressRate = 0
+	}
+	return setEgressLimit(nl, link, egressRate, limits.EgressBurst)
+}
+
+// CleanUpOrphans removes IFB devices whose workload interface no longer exists.
+func (s *Shaper) CleanUpOrphans() (err error) {
+	nl, err := s.getNetlink()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			s.closeNetlink()
+		}
+	}()
+
+	links, err := nl.LinkList()
+	if err != nil {
+		return err
+	}
+	inUse := map[string]bool{}
+	for _, link := range links {
+		inUse[ifbName(link.Attrs().Name)] = true
+	}
+	for _, link := range links {
+		name := link.Attrs().Name
+		if link.Type() != "ifb" || !strings.HasPrefix(name, ifbPrefix) || inUse[name] {
+			continue
+		}
+		log.WithField("ifbName", name).Info("Removing IFB device for workload interface that no longer exists")
+		if err = nl.LinkDel(link); err != nil && !netlinkshim.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// setIngressLimit shapes the traffic to the workload with a TBF qdisc on the workload interface.
+func setIngressLimit(nl netlinkshim.Interface, link netlink.Link, rate, burst uint64) error {
+	if rate == 0 {
+		return deleteQdisc(nl, link, netlink.HANDLE_ROOT, "tbf", tbfHandle)
+	}
+	return ensureTBF(nl, link, rate, burst)
+}
+
+// setEgressLimit shapes the traffic from the workload by redirecting the ingress traffic of the workload interface
+// to an IFB device that has a TBF qdisc.
+func setEgressLimit(nl netlinkshim.Interface, link netlink.Link, rate, burst uint64) error {
+	name := ifbName(link.Attrs().Name)
+	if rate == 0 {
+		if err := deleteQdisc(nl, link, netlink.HANDLE_INGRESS, "ingress", ingressHandle); err != nil {
+			return err
+		}
+		return deleteIFB(nl, name)
+	}
+
+	ifb, err := ensureIFB(nl, name, link.Attrs().MTU)
+	if err != nil {
+		return err
+	}
+	if err := ensureTBF(nl, ifb, rate, burst); err != nil {
+		return err
+	}
+	return ensureRedirect(nl, link, ifb.Attrs().Index)
+}
+
+func ensureTBF(nl netlinkshim.Interface, link netlink.Link, rate, burst uint64) error {
+	tbf := newTBF(link.Attrs().Index, rate, burst)
+	existing, err := findQdisc(nl, link, netlink.HANDLE_ROOT, "tbf", tbfHandle)
+	if err != nil {
+		return err
+	}
+	if existing, ok := existing.(*netlink.Tbf); ok &&
+		existing.Rate == tbf.Rate && existing.Limit == tbf.Limit && existing.Buffer == tbf.Buffer {
+		return nil
+	}
+	log.WithFields(log.Fields{"ifaceName": link.Attrs().Name, "rate": rate, "burst": burst}).Info(
+		"Setting bandwidth limit")
+	return nl.QdiscReplace(tbf)
+}
+
+// newTBF returns a TBF qdisc for the given rate and burst, both in bits, using the same calculations as tc and the CNI
+// bandwidth plugin.
+func newTBF(linkIndex int, rate, burst uint64) *netlink.Tbf {
+	if burst == 0 {
+		burst = rate / 10
+		if burst < minDefaultBurstBits {
+			burst = minDefaultBurstBits
+		}
+	}
+	rateBytes := rate / 8
+	if rateBytes == 0 {
+		rateBytes = 1
+	}
+	burstBytes := burst / 8
+	if burstBytes > math.MaxUint32 {
+		burstBytes = math.MaxUint32
+	}
+	limitBytes := rateBytes*latencyMicros/netlink.TIME_UNITS_PER_SEC + burstBytes
+	if limitBytes > math.MaxUint32 {
+		limitBytes = math.MaxUint32
+	}
+	return &netlink.Tbf{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: linkIndex,
+			Handle:    tbfHandle,
+			Parent:    netlink.HANDLE_ROOT,
+		},
+		Rate:   rateBytes,
+		Limit:  uint32(limitBytes),
+		Buffer: uint32(netlink.Xmittime(rateBytes, uint32(burstBytes))),
+	}
+}
+
+func ensureIFB(nl netlinkshim.Interface, name string, mtu int) (netlink.Link, error) {
+	ifb, err := nl.LinkByName(name)
+	if netlinkshim.IsNotExist(err) {
+		log.WithField("ifbName", name).Info("Creating IFB device")
+		err = nl.LinkAdd(&netlink.Ifb{
+			LinkAttrs: netlink.LinkAttrs{
+				Name:   name,
+				Flags:  net.FlagUp,
+				MTU:    mtu,
+				TxQLen: 1000,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		ifb, err = nl.LinkByName(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ifb.Attrs().Flags&net.FlagUp == 0 {
+		if err := nl.LinkSetUp(ifb); err != nil {
+			return nil, err
+		}
+	}
+	return ifb, nil
+}
+
+func deleteIFB(nl netlinkshim.Interface, name string) error {
+	ifb, err := nl.LinkByName(name)
+	if netlinkshim.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	log.WithField("ifbName", name).Info("Removing IFB device")
+	err = nl.LinkDel(ifb)
+	if netlinkshim.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// ensureRedirect makes sure that the ingress traffic of the link is redirected to the IFB device with the given index.
+func ensureRedirect(nl netlinkshim.Interface, link netlink.Link, ifbIndex int) error {
+	ingress, err := findQdisc(nl, link, netlink.HANDLE_INGRESS, "ingress", ingressHandle)
+	if err != nil {
+		return err
+	}
+	if ingress != nil {
+		filters, err := nl.FilterList(link, ingressHandle)
+		if err != nil {
+			return err
+		}
+		for _, f := range filters {
+			if redirectsTo(f, ifbIndex) {
+				return nil
+			}
+		}
+		// Our qdisc but with the wrong filters, for example because the IFB device has been re-created.  Start again.
+		if err := nl.QdiscDel(ingress); err != nil && !netlinkshim.IsNotExist(err) {
+			return err
+		}
+	}
+
+	log.WithFields(log.Fields{"ifaceName": link.Attrs().Name, "ifbIndex": ifbIndex}).Info(
+		"Redirecting workload traffic to IFB device")
+	err = nl.QdiscReplace(&netlink.Ingress{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    ingressHandle,
+			Parent:    netlink.HANDLE_INGRESS,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return nl.FilterAdd(&netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    ingressHandle,
+			Priority:  1,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		ClassId: netlink.MakeHandle(1, 1),
+		Actions: []netlink.Action{netlink.NewMirredAction(ifbIndex)},
+	})
+}
+
+func redirectsTo(filter netlink.Filter, ifbIndex int) bool {
+	u32, ok := filter.(*netlink.U32)
+	if !ok {
+		return false
+	}
+	for _, action := range u32.Actions {
+		if mirred, ok := action.(*netlink.MirredAction); ok &&
+			mirred.MirredAction == netlink.TCA_EGRESS_REDIR && mirred.Ifindex == ifbIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// findQdisc returns the qdisc of the given type and handle that is attached to the link at the given parent, or nil if
+// there is no such qdisc.
+func findQdisc(nl netlinkshim.Interface, link netlink.Link, parent uint32, qdiscType string, handle uint32) (netlink.Qdisc, error) {
+	qdiscs, err := nl.QdiscList(link)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range qdiscs {
+		if q.Attrs().Parent == parent && q.Type() == qdiscType && q.Attrs().Handle == handle {
+			return q, nil
+		}
+	}
+	return nil, nil
+}
+
+func deleteQdisc(nl netlinkshim.Interface, link netlink.Link, parent uint32, qdiscType string, handle uint32) error {
+	q, err := findQdisc(nl, link, parent, qdiscType, handle)
+	if err != nil || q == nil {
+		return err
+	}
+	log.WithFields(log.Fields{"ifaceName": link.Attrs().Name, "qdisc": qdiscType}).Info("Removing bandwidth limit")
+	err = nl.QdiscDel(q)
+	if netlinkshim.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// ifbName returns the name of the IFB device for a workload interface.
+func ifbName(ifaceName string) string {
+	hash := sha256.Sum256([]byte(ifaceName))
+	return ifbPrefix + hex.EncodeToString(hash[:])[:ifbHashLen]
+}
+
+func (s *Shaper) getNetlink() (netlinkshim.Interface, error) {
+	if s.cachedNetlinkHandle == nil {
+		nl, err := s.newNetlinkHandle()
+		if err != nil {
+			log.WithError(err).Error("Failed to connect to netlink")
+			return nil, err
+		}
+		s.cachedNetlinkHandle = nl
+	}
+	return s.cachedNetlinkHandle, nil
+}
+
+func (s *Shaper) closeNetlink() {
+	if s.cachedNetlinkHandle == nil {
+		return
+	}
+	s.cachedNetlinkHandle.Delete()
+	s.cachedNetlinkHandle = nil
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bandwidth
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+
+	mocknetlink "github.com/projectcalico/felix/netlinkshim/mocknetlink"
+)
+
+var _ = Describe("Bandwidth shaper", func() {
+	const ifaceName = "cali12345"
+	var (
+		dataplane *mocknetlink.MockNetlinkDataplane
+		shaper    *Shaper
+		veth      *mocknetlink.MockLink
+	)
+
+	BeforeEach(func() {
+		dataplane = mocknetlink.New()
+		veth = dataplane.AddIface(10, ifaceName, true, true)
+		veth.LinkAttrs.MTU = 1440
+		shaper = NewWithShims(true, dataplane.NewMockNetlink)
+	})
+
+	qdisc := func(linkIndex int, parent uint32) netlink.Qdisc {
+		return dataplane.LinkIndexToQdiscs[linkIndex][parent]
+	}
+
+	ifb := func() *mocknetlink.MockLink {
+		return dataplane.NameToLink[ifbName(ifaceName)]
+	}
+
+	It("should limit traffic to the workload with a TBF qdisc", func() {
+		Expect(shaper.SetLimits(ifaceName, Limits{IngressRate: 10000000})).To(Succeed())
+		Expect(qdisc(10, netlink.HANDLE_ROOT)).To(Equal(&netlink.Tbf{
+			QdiscAttrs: netlink.QdiscAttrs{
+				LinkIndex: 10,
+				Handle:    netlink.MakeHandle(1, 0),
+				Parent:    netlink.HANDLE_ROOT,
+			},
+			Rate:   1250000,
+			Limit:  156250,
+			Buffer: uint32(netlink.Xmittime(1250000, 125000)),
+		}))
+		Expect(qdisc(10, netlink.HANDLE_INGRESS)).To(BeNil())
+		Expect(ifb()).To(BeNil())
+
+		By("removing the limit")
+		Expect(shaper.SetLimits(ifaceName, Limits{})).To(Succeed())
+		Expect(dataplane.LinkIndexToQdiscs[10]).To(BeEmpty())
+	})
+
+	It("should use a minimum default burst", func() {
+		Expect(shaper.SetLimits(ifaceName, Limits{IngressRate: 1000000})).To(Succeed())
+		tbf := qdisc(10, netlink.HANDLE_ROOT).(*netlink.Tbf)
+		Expect(tbf.Buffer).To(Equal(uint32(netlink.Xmittime(125000, 64*1024))))
+	})
+
+	It("should limit traffic from the workload by redirecting it to an IFB device", func() {
+		Expect(shaper.SetLimits(ifaceName, Limits{EgressRate: 8000000, EgressBurst: 800000})).To(Succeed())
+		Expect(qdisc(10, netlink.HANDLE_ROOT)).To(BeNil())
+
+		Expect(ifb()).NotTo(BeNil())
+		Expect(ifb().Type()).To(Equal("ifb"))
+		Expect(ifb().LinkAttrs.MTU).To(Equal(1440))
+		ifbIndex := ifb().LinkAttrs.Index
+		Expect(qdisc(ifbIndex, netlink.HANDLE_ROOT)).To(Equal(newTBF(ifbIndex, 8000000, 800000)))
+
+		Expect(qdisc(10, netlink.HANDLE_INGRESS).Type()).To(Equal("ingress"))
+		filters := dataplane.LinkIndexToFilters[10]
+		Expect(filters).To(HaveLen(1))
+		Expect(redirectsTo(filters[0], ifbIndex)).To(BeTrue())
+
+		By("reapplying the same limit")
+		Expect(shaper.SetLimits(ifaceName, Limits{EgressRate: 8000000, EgressBurst: 800000})).To(Succeed())
+		Expect(dataplane.LinkIndexToFilters[10]).To(HaveLen(1))
+		Expect(dataplane.NumLinkAddCalls).To(Equal(1))
+
+		By("removing the limit")
+		Expect(shaper.SetLimits(ifaceName, Limits{})).To(Succeed())
+		Expect(dataplane.LinkIndexToQdiscs[10]).To(BeEmpty())
+		Expect(dataplane.LinkIndexToFilters[10]).To(BeEmpty())
+		Expect(ifb()).To(BeNil())
+	})
+
+	It("should fix up a redirect to a stale IFB device", func() {
+		Expect(shaper.SetLimits(ifaceName, Limits{EgressRate: 8000000})).To(Succeed())
+		delete(dataplane.NameToLink, ifbName(ifaceName))
+
+		Expect(shaper.SetLimits(ifaceName, Limits{EgressRate: 8000000})).To(Succeed())
+		filters := dataplane.LinkIndexToFilters[10]
+		Expect(filters).To(HaveLen(1))
+		Expect(redirectsTo(filters[0], ifb().LinkAttrs.Index)).To(BeTrue())
+	})
+
+	It("should ignore egress limits when they are disabled", func() {
+		shaper = NewWithShims(false, dataplane.NewMockNetlink)
+		Expect(shaper.SetLimits(ifaceName, Limits{IngressRate: 10000000, EgressRate: 8000000})).To(Succeed())
+		Expect(qdisc(10, netlink.HANDLE_ROOT)).NotTo(BeNil())
+		Expect(qdisc(10, netlink.HANDLE_INGRESS)).To(BeNil())
+		Expect(ifb()).To(BeNil())
+	})
+
+	It("should remove the IFB device of an interface that has gone", func() {
+		Expect(shaper.SetLimits(ifaceName, Limits{EgressRate: 8000000})).To(Succeed())
+		delete(dataplane.NameToLink, ifaceName)
+
+		Expect(shaper.SetLimits(ifaceName, Limits{EgressRate: 8000000})).NotTo(Succeed())
+		Expect(shaper.SetLimits(ifaceName, Limits{})).To(Succeed())
+		Expect(ifb()).To(BeNil())
+	})
+
+	It("should clean up orphaned IFB devices", func() {
+		Expect(shaper.SetLimits(ifaceName, Limits{EgressRate: 8000000})).To(Succeed())
+		dataplane.AddIface(11, "cali67890", true, true)
+		Expect(shaper.SetLimits("cali67890", Limits{EgressRate: 8000000})).To(Succeed())
+		orphan := ifbName("cali67890")
+		delete(dataplane.NameToLink, "cali67890")
+
+		Expect(shaper.CleanUpOrphans()).To(Succeed())
+		Expect(dataplane.NameToLink).NotTo(HaveKey(orphan))
+		Expect(ifb()).NotTo(BeNil())
+		Expect(dataplane.NameToLink).To(HaveKey(ifaceName))
+	})
+
+	It("should reconnect to netlink after a failure", func() {
+		dataplane.FailuresToSimulate = mocknetlink.FailNextQdiscReplace
+		Expect(shaper.SetLimits(ifaceName, Limits{IngressRate: 10000000})).NotTo(Succeed())
+		Expect(dataplane.NetlinkOpen).To(BeFalse())
+
+		Expect(shaper.SetLimits(ifaceName, Limits{IngressRate: 10000000})).To(Succeed())
+		Expect(qdisc(10, netlink.HANDLE_ROOT)).NotTo(BeNil())
+		Expect(dataplane.NumNewNetlinkCalls).To(Equal(2))
+	})
+
+	It("should generate IFB device names that are valid and don't look like workload interfaces", func() {
+		name := ifbName("cali1234567890a")
+		Expect(len(name)).To(BeNumerically("<=", 15))
+		Expect(name).To(HavePrefix(ifbPrefix))
+		Expect(name).NotTo(HavePrefix("cali"))
+		Expect(ifbName("cali1234567890b")).NotTo(Equal(name))
+	})
+})
@@ -0,0 +1,87 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __CALI_CONNTRACK_TYPES_V6_H__
+#define __CALI_CONNTRACK_TYPES_V6_H__
+
+// IPv6 connection tracking.  The entry types, flags, legs and result codes are shared with
+// IPv4, see conntrack_types.h; only the addresses differ.
+//
+// WARNING: must be kept in sync with the Go definitions in bpf/conntrack/map_v6.go.
+
+struct calico_ct_key_v6 {
+	__u32 protocol;
+	ipv6_addr_t addr_a, addr_b; // NBO
+	__u16 port_a, port_b; // HBO
+};
+
+struct calico_ct_value_v6 {
+	__u64 created;
+	__u64 last_seen; // 8
+	__u8 type;		 // 16
+	__u8 flags;
+
+	// Explicit padding, see struct calico_ct_value.
+	__u8 pad0[6];
+	union {
+		// CALI_CT_TYPE_NORMAL and CALI_CT_TYPE_NAT_REV.
+		struct {
+			struct calico_ct_leg a_to_b; // 24
+			struct calico_ct_leg b_to_a; // 36
+
+			// CALI_CT_TYPE_NAT_REV
+			ipv6_addr_t orig_ip;               // 48
+			__u16 orig_port;                   // 64
+			__u8 pad1[6];                      // 66
+		};
+
+		// CALI_CT_TYPE_NAT_FWD; key for the CALI_CT_TYPE_NAT_REV entry.
+		struct {
+			struct calico_ct_key_v6 nat_rev_key;  // 24
+			__u8 pad2[8];                         // 64
+		};
+	};
+};
+
+struct ct_ctx_v6 {
+	struct __sk_buff *skb;
+	__u8 proto;
+	ipv6_addr_t src;
+	ipv6_addr_t orig_dst;
+	ipv6_addr_t dst;
+	__u16 sport;
+	__u16 dport;
+	__u16 orig_dport;
+	struct tcphdr *tcp;
+	__u8 flags;
+};
+
+CALI_MAP_V1(cali_v6_ct,
+		BPF_MAP_TYPE_HASH,
+		struct calico_ct_key_v6, struct calico_ct_value_v6,
+		512000, BPF_F_NO_PREALLOC, MAP_PIN_GLOBAL)
+
+struct calico_ct_result_v6 {
+	__s16 rc;
+	__u16 flags;
+	ipv6_addr_t nat_ip;
+	__u32 nat_port;
+	__u32 ifindex_fwd; /* if set, the ifindex where the packet should be forwarded */
+	__u32 ifindex_created; /* See struct calico_ct_result. */
+};
+
+#endif /* __CALI_CONNTRACK_TYPES_V6_H__ */
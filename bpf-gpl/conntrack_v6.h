@@ -0,0 +1,579 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __CALI_CONNTRACK_V6_H__
+#define __CALI_CONNTRACK_V6_H__
+
+#include <linux/in.h>
+#include <linux/icmpv6.h>
+#include "bpf.h"
+#include "conntrack.h"
+#include "routes_v6.h"
+#include "skb_v6.h"
+#include "types_v6.h"
+
+// IPv6 connection tracking.  This mirrors the IPv4 code in conntrack.h but there are no
+// tunnels (and hence no tunnel IPs) for IPv6.
+
+static CALI_BPF_INLINE bool src_lt_dest_v6(ipv6_addr_t *ip_src, ipv6_addr_t *ip_dst,
+					   __u16 sport, __u16 dport)
+{
+	return ipv6_addr_lt(ip_src, ip_dst) || (ipv6_addr_eq(ip_src, ip_dst) && sport < dport);
+}
+
+static CALI_BPF_INLINE struct calico_ct_key_v6 ct_make_key_v6(bool sltd, __u8 proto,
+							      ipv6_addr_t *ip_src, ipv6_addr_t *ip_dst,
+							      __u16 sport, __u16 dport)
+{
+	struct calico_ct_key_v6 k = {
+		.protocol = proto,
+	};
+
+	if (sltd) {
+		k.addr_a = *ip_src;
+		k.port_a = sport;
+		k.addr_b = *ip_dst;
+		k.port_b = dport;
+	} else {
+		k.addr_a = *ip_dst;
+		k.port_a = dport;
+		k.addr_b = *ip_src;
+		k.port_b = sport;
+	}
+
+	CALI_VERB("CT-ALL   key A=..%x:%d proto=%d\n", ipv6_addr_log(&k.addr_a), k.port_a, (int)k.protocol);
+	CALI_VERB("CT-ALL   key B=..%x:%d size=%d\n", ipv6_addr_log(&k.addr_b), k.port_b,
+			(int)sizeof(struct calico_ct_key_v6));
+	return k;
+}
+
+static CALI_BPF_INLINE int calico_ct_v6_create_tracking(struct ct_ctx_v6 *ct_ctx,
+							struct calico_ct_key_v6 *k,
+							enum cali_ct_type type)
+{
+	__u16 sport = ct_ctx->sport;
+	__u16 dport = ct_ctx->dport;
+	int err = 0;
+
+	__be32 seq = 0;
+	bool syn = false;
+	__u64 now;
+
+	if (ct_ctx->tcp) {
+		seq = ct_ctx->tcp->seq;
+		syn = ct_ctx->tcp->syn;
+	}
+
+	bool srcLTDest = src_lt_dest_v6(&ct_ctx->src, &ct_ctx->dst, sport, dport);
+	*k = ct_make_key_v6(srcLTDest, ct_ctx->proto, &ct_ctx->src, &ct_ctx->dst, sport, dport);
+
+	CALI_DEBUG("CT-ALL packet mark is: 0x%x\n", ct_ctx->skb->mark);
+	if (skb_seen(ct_ctx->skb)) {
+		/* Packet already marked as being from another workload, which will
+		 * have created a conntrack entry.  Look that one up instead of
+		 * creating one.
+		 */
+		CALI_DEBUG("CT-ALL Asked to create entry but packet is marked as "
+				"from another endpoint, doing lookup\n");
+		struct calico_ct_value_v6 *ct_value = cali_v6_ct_lookup_elem(k);
+		if (!ct_value) {
+			CALI_VERB("CT Packet marked as from workload but got a conntrack miss!\n");
+			goto create;
+		}
+		CALI_VERB("CT Found expected entry, updating...\n");
+		if (srcLTDest) {
+			ct_value->a_to_b.seqno = seq;
+			ct_value->a_to_b.syn_seen = syn;
+			if (CALI_F_TO_HOST) {
+				ct_value->a_to_b.whitelisted = 1;
+			} else {
+				ct_value->b_to_a.whitelisted = 1;
+			}
+		} else  {
+			ct_value->b_to_a.seqno = seq;
+			ct_value->b_to_a.syn_seen = syn;
+			if (CALI_F_TO_HOST) {
+				ct_value->b_to_a.whitelisted = 1;
+			} else {
+				ct_value->a_to_b.whitelisted = 1;
+			}
+		}
+
+		return 0;
+	}
+
+create:
+	now = bpf_ktime_get_ns();
+	CALI_DEBUG("CT-ALL Creating tracking entry type %d at %llu.\n", type, now);
+
+	struct calico_ct_value_v6 ct_value = {
+		.created=now,
+		.last_seen=now,
+		.type = type,
+		.orig_ip = ct_ctx->orig_dst,
+		.orig_port = ct_ctx->orig_dport,
+	};
+
+	ct_value.flags = ct_ctx->flags;
+	CALI_DEBUG("CT-ALL tracking entry flags 0x%x\n", ct_value.flags);
+
+	struct calico_ct_leg *src_to_dst, *dst_to_src;
+
+	if (srcLTDest) {
+		CALI_VERB("CT-ALL src_to_dst A->B\n");
+		src_to_dst = &ct_value.a_to_b;
+		dst_to_src = &ct_value.b_to_a;
+	} else  {
+		CALI_VERB("CT-ALL src_to_dst B->A\n");
+		src_to_dst = &ct_value.b_to_a;
+		dst_to_src = &ct_value.a_to_b;
+	}
+
+	src_to_dst->seqno = seq;
+	src_to_dst->syn_seen = syn;
+	src_to_dst->opener = 1;
+	if (CALI_F_TO_HOST) {
+		src_to_dst->ifindex = skb_ingress_ifindex(ct_ctx->skb);
+	} else {
+		src_to_dst->ifindex = CT_INVALID_IFINDEX;
+	}
+	dst_to_src->ifindex = CT_INVALID_IFINDEX;
+
+	if (CALI_F_TO_HOST) {
+		/* src is the from the EP, policy whitelisted this side */
+		src_to_dst->whitelisted = 1;
+		CALI_DEBUG("CT-ALL Whitelisted source side\n");
+	} else {
+		/* dst is to the EP, policy whitelisted this side */
+		dst_to_src->whitelisted = 1;
+		CALI_DEBUG("CT-ALL Whitelisted dest side - to EP\n");
+	}
+
+	err = cali_v6_ct_update_elem(k, &ct_value, 0);
+
+	CALI_VERB("CT-ALL Create result: %d.\n", err);
+	return err;
+}
+
+static CALI_BPF_INLINE int calico_ct_v6_create_nat_fwd(struct ct_ctx_v6 *ct_ctx,
+						       struct calico_ct_key_v6 *rk)
+{
+	__u8 ip_proto = ct_ctx->proto;
+	__u16 sport = ct_ctx->sport;
+	__u16 dport = ct_ctx->orig_dport;
+
+	__u64 now = bpf_ktime_get_ns();
+
+	CALI_DEBUG("CT-%d Creating FWD entry at %llu.\n", ip_proto, now);
+	struct calico_ct_value_v6 ct_value = {
+		.type = CALI_CT_TYPE_NAT_FWD,
+		.last_seen = now,
+		.created = now,
+	};
+
+	bool srcLTDest = src_lt_dest_v6(&ct_ctx->src, &ct_ctx->orig_dst, sport, dport);
+	struct calico_ct_key_v6 k = ct_make_key_v6(srcLTDest, ip_proto,
+						   &ct_ctx->src, &ct_ctx->orig_dst, sport, dport);
+
+	ct_value.nat_rev_key = *rk;
+	int err = cali_v6_ct_update_elem(&k, &ct_value, 0);
+	CALI_VERB("CT-%d Create result: %d.\n", ip_proto, err);
+	return err;
+}
+
+/* creates connection tracking for tracked protocols */
+static CALI_BPF_INLINE int conntrack_v6_create(struct ct_ctx_v6 *ct_ctx, int nat)
+{
+	struct calico_ct_key_v6 k;
+	int err;
+
+	if (nat == CT_CREATE_NORMAL) {
+		return calico_ct_v6_create_tracking(ct_ctx, &k, CALI_CT_TYPE_NORMAL);
+	}
+
+	err = calico_ct_v6_create_tracking(ct_ctx, &k, CALI_CT_TYPE_NAT_REV);
+	if (!err) {
+		err = calico_ct_v6_create_nat_fwd(ct_ctx, &k);
+		if (err) {
+			/* XXX we should clean up the tracking entry */
+		}
+	}
+
+	return err;
+}
+
+static CALI_BPF_INLINE bool icmp6_type_is_err(__u8 type)
+{
+	switch (type) {
+	case ICMPV6_DEST_UNREACH:
+	case ICMPV6_PKT_TOOBIG:
+	case ICMPV6_TIME_EXCEED:
+	case ICMPV6_PARAMPROB:
+		return true;
+	}
+
+	return false;
+}
+
+/* skb_icmp6_err_unpack is the IPv6 version of skb_icmp_err_unpack.  It updates the ct_ctx with
+ * the protocol/src/dst/ports of the packet inside the ICMPv6 error.  Inner packets with
+ * extension headers are not unpacked.
+ */
+static CALI_BPF_INLINE bool skb_icmp6_err_unpack(struct cali_tc_ctx_v6 *ctx, struct ct_ctx_v6 *ct_ctx)
+{
+	if (skb_v6_refresh_validate_ptrs(ctx, ICMPV6_SIZE + IPV6_SIZE + 8)) {
+		ctx->fwd.reason = CALI_REASON_SHORT;
+		ctx->fwd.res = TC_ACT_SHOT;
+		CALI_DEBUG("ICMPv6 error: too short getting hdr\n");
+		return false;
+	}
+
+	struct ipv6hdr *ip_inner;
+	ip_inner = (struct ipv6hdr *)(ctx->icmp_header + 1); /* skip to inner ip */
+	CALI_DEBUG("CT-ICMPv6: proto %d\n", ip_inner->nexthdr);
+
+	ct_ctx->proto = ip_inner->nexthdr;
+	ct_ctx->src = ipv6_addr_from_in6(&ip_inner->saddr);
+	ct_ctx->dst = ipv6_addr_from_in6(&ip_inner->daddr);
+
+	switch (ip_inner->nexthdr) {
+	case IPPROTO_TCP:
+		{
+			struct tcphdr *tcp = (struct tcphdr *)(ip_inner + 1);
+			ct_ctx->sport = bpf_ntohs(tcp->source);
+			ct_ctx->dport = bpf_ntohs(tcp->dest);
+			ct_ctx->tcp = tcp;
+		}
+		break;
+	case IPPROTO_UDP:
+		{
+			struct udphdr *udp = (struct udphdr *)(ip_inner + 1);
+			ct_ctx->sport = bpf_ntohs(udp->source);
+			ct_ctx->dport = bpf_ntohs(udp->dest);
+		}
+		break;
+	};
+
+	return true;
+}
+
+static CALI_BPF_INLINE struct calico_ct_result_v6 calico_ct_v6_lookup(struct cali_tc_ctx_v6 *tc_ctx)
+{
+	struct ct_ctx_v6 ct_lookup_ctx = {
+		.skb = tc_ctx->skb,
+		.proto	= tc_ctx->state->ip_proto,
+		.src	= tc_ctx->state->ip_src,
+		.sport	= tc_ctx->state->sport,
+		.dst	= tc_ctx->state->ip_dst,
+		.dport	= tc_ctx->state->dport,
+	};
+	struct ct_ctx_v6 *ct_ctx = &ct_lookup_ctx;
+	if (tc_ctx->state->ip_proto == IPPROTO_TCP) {
+		if (skb_v6_refresh_validate_ptrs(tc_ctx, TCP_SIZE)) {
+			tc_ctx->fwd.reason = CALI_REASON_SHORT;
+			CALI_DEBUG("Too short\n");
+			bpf_exit(TC_ACT_SHOT);
+		}
+		ct_lookup_ctx.tcp = tc_ctx->tcp_header;
+	}
+
+	__u8 proto_orig = ct_ctx->proto;
+	__u16 sport = ct_ctx->sport;
+	struct tcphdr *tcp_header = ct_ctx->tcp;
+	bool related = false;
+
+	CALI_CT_DEBUG("lookup from ..%x:%d\n", ipv6_addr_log(&ct_ctx->src), sport);
+	CALI_CT_DEBUG("lookup to   ..%x:%d\n", ipv6_addr_log(&ct_ctx->dst), ct_ctx->dport);
+
+	struct calico_ct_result_v6 result = {
+		.rc = CALI_CT_NEW, /* it is zero, but make it explicit in the code */
+		.ifindex_created = CT_INVALID_IFINDEX,
+	};
+
+	bool srcLTDest = src_lt_dest_v6(&ct_ctx->src, &ct_ctx->dst, sport, ct_ctx->dport);
+	struct calico_ct_key_v6 k = ct_make_key_v6(srcLTDest, ct_ctx->proto,
+						   &ct_ctx->src, &ct_ctx->dst, sport, ct_ctx->dport);
+	bool syn = tcp_header && tcp_header->syn && !tcp_header->ack;
+
+	struct calico_ct_value_v6 *v = cali_v6_ct_lookup_elem(&k);
+	if (!v) {
+		if (syn) {
+			// SYN packet (new flow); send it to policy.
+			CALI_CT_DEBUG("Miss for TCP SYN, NEW flow.\n");
+			goto out_lookup_fail;
+		}
+		if (CALI_F_FROM_HOST && proto_orig == IPPROTO_TCP) {
+			// Mid-flow TCP packet with no conntrack entry leaving the host namespace.
+			if ((tc_ctx->skb->mark & CALI_SKB_MARK_CT_ESTABLISHED_MASK) == CALI_SKB_MARK_CT_ESTABLISHED) {
+				// Linux Conntrack has marked the packet as part of an established flow.
+				CALI_DEBUG("BPF CT Miss but have Linux CT entry: established\n");
+				result.rc = CALI_CT_ESTABLISHED;
+				return result;
+			}
+			CALI_DEBUG("BPF CT Miss but Linux CT entry not signalled\n");
+			result.rc = CALI_CT_MID_FLOW_MISS;
+			return result;
+		}
+		if (CALI_F_TO_HOST && proto_orig == IPPROTO_TCP) {
+			// Miss for a mid-flow TCP packet towards the host.  This may be part of a
+			// connection that predates the BPF program so we need to let it fall through
+			// to iptables.
+			CALI_DEBUG("BPF CT Miss for mid-flow TCP\n");
+			result.rc = CALI_CT_MID_FLOW_MISS;
+			return result;
+		}
+		if (ct_ctx->proto != IPPROTO_ICMPV6) {
+			// Not ICMPv6 so can't be a "related" packet.
+			CALI_CT_DEBUG("Miss.\n");
+			goto out_lookup_fail;
+		}
+
+		if (!icmp6_type_is_err(tc_ctx->icmp_header->icmp6_type)) {
+			// ICMPv6 but not an error response packet.
+			CALI_DEBUG("CT-ICMPv6: type %d not an error\n", tc_ctx->icmp_header->icmp6_type);
+			goto out_lookup_fail;
+		}
+
+		// ICMPv6 error packets are a response to a failed UDP/TCP/etc packet.  Try to
+		// extract the details of the inner packet.
+		if (!skb_icmp6_err_unpack(tc_ctx, ct_ctx)) {
+			CALI_CT_DEBUG("Failed to parse ICMPv6 error packet.\n");
+			goto out_invalid;
+		}
+
+		CALI_CT_DEBUG("related lookup from ..%x:%d\n", ipv6_addr_log(&ct_ctx->src), ct_ctx->sport);
+		CALI_CT_DEBUG("related lookup to   ..%x:%d\n", ipv6_addr_log(&ct_ctx->dst), ct_ctx->dport);
+
+		srcLTDest = src_lt_dest_v6(&ct_ctx->src, &ct_ctx->dst, ct_ctx->sport, ct_ctx->dport);
+		k = ct_make_key_v6(srcLTDest, ct_ctx->proto, &ct_ctx->src, &ct_ctx->dst,
+				   ct_ctx->sport, ct_ctx->dport);
+		v = cali_v6_ct_lookup_elem(&k);
+		if (!v) {
+			if (CALI_F_TO_HOST) {
+				// Miss for a related packet towards the host.  This may be part of
+				// a connection that predates the BPF program so we need to let it
+				// fall through to iptables.
+				CALI_DEBUG("BPF CT related miss\n");
+				result.rc = CALI_CT_MID_FLOW_MISS;
+				return result;
+			}
+
+			CALI_CT_DEBUG("Miss on ICMPv6 related\n");
+			goto out_lookup_fail;
+		}
+
+		sport = ct_ctx->sport;
+		tcp_header = ct_ctx->tcp;
+
+		related = true;
+	}
+
+	__u64 now = bpf_ktime_get_ns();
+	v->last_seen = now;
+
+	result.flags = v->flags;
+
+	// Return the if_index where the CT state was created.
+	if (v->a_to_b.opener) {
+		result.ifindex_created = v->a_to_b.ifindex;
+	} else if (v->b_to_a.opener) {
+		result.ifindex_created = v->b_to_a.ifindex;
+	}
+
+	struct calico_ct_leg *src_to_dst, *dst_to_src;
+
+	struct calico_ct_value_v6 *tracking_v;
+	switch (v->type) {
+	case CALI_CT_TYPE_NAT_FWD:
+		// This is a forward NAT entry; since we do the bookkeeping on the
+		// reverse entry, we need to do a second lookup.
+		CALI_CT_DEBUG("Hit! NAT FWD entry, doing secondary lookup.\n");
+		tracking_v = cali_v6_ct_lookup_elem(&v->nat_rev_key);
+		if (!tracking_v) {
+			CALI_CT_DEBUG("Miss when looking for secondary entry.\n");
+			goto out_lookup_fail;
+		}
+		// Record timestamp.
+		tracking_v->last_seen = now;
+
+		if (ipv6_addr_eq(&ct_ctx->src, &v->nat_rev_key.addr_a) && sport == v->nat_rev_key.port_a) {
+			CALI_VERB("CT-ALL FWD-REV src_to_dst A->B\n");
+			src_to_dst = &tracking_v->a_to_b;
+			dst_to_src = &tracking_v->b_to_a;
+			result.nat_ip = v->nat_rev_key.addr_b;
+			result.nat_port = v->nat_rev_key.port_b;
+		} else {
+			CALI_VERB("CT-ALL FWD-REV src_to_dst B->A\n");
+			src_to_dst = &tracking_v->b_to_a;
+			dst_to_src = &tracking_v->a_to_b;
+			result.nat_ip = v->nat_rev_key.addr_a;
+			result.nat_port = v->nat_rev_key.port_a;
+		}
+		// flags are in the tracking entry
+		result.flags = tracking_v->flags;
+
+		if (CALI_F_TO_HOST) {
+			// Since we found a forward NAT entry, we know that it's the destination
+			// that needs to be NATted.
+			result.rc =	CALI_CT_ESTABLISHED_DNAT;
+		} else {
+			result.rc =	CALI_CT_ESTABLISHED;
+		}
+		break;
+	case CALI_CT_TYPE_NAT_REV:
+		if (srcLTDest) {
+			CALI_VERB("CT-ALL REV src_to_dst A->B\n");
+			src_to_dst = &v->a_to_b;
+			dst_to_src = &v->b_to_a;
+		} else {
+			CALI_VERB("CT-ALL REV src_to_dst B->A\n");
+			src_to_dst = &v->b_to_a;
+			dst_to_src = &v->a_to_b;
+		}
+
+		/* A reverse NAT entry; see calico_ct_v4_lookup.  Reverse the NAT only for
+		 * packets that are heading away from the host namespace towards the opener. */
+		if (CALI_F_FROM_HOST && dst_to_src->opener) {
+			CALI_CT_DEBUG("Hit! NAT REV entry at ingress to connection opener: SNAT.\n");
+			result.rc =	CALI_CT_ESTABLISHED_SNAT;
+			result.nat_ip = v->orig_ip;
+			result.nat_port = v->orig_port;
+		} else {
+			CALI_CT_DEBUG("Hit! NAT REV entry but not connection opener: ESTABLISHED.\n");
+			result.rc =	CALI_CT_ESTABLISHED;
+		}
+		break;
+
+	case CALI_CT_TYPE_NORMAL:
+		CALI_CT_DEBUG("Hit! NORMAL entry.\n");
+		CALI_CT_VERB("A: whitelisted %d.\n", v->a_to_b.whitelisted);
+		CALI_CT_VERB("B: whitelisted %d.\n", v->b_to_a.whitelisted);
+
+		if (v->a_to_b.whitelisted && v->b_to_a.whitelisted) {
+			result.rc = CALI_CT_ESTABLISHED_BYPASS;
+		} else {
+			result.rc = CALI_CT_ESTABLISHED;
+		}
+
+		if (srcLTDest) {
+			src_to_dst = &v->a_to_b;
+			dst_to_src = &v->b_to_a;
+		} else {
+			src_to_dst = &v->b_to_a;
+			dst_to_src = &v->a_to_b;
+		}
+
+		break;
+	default:
+		CALI_CT_DEBUG("Hit! UNKNOWN entry type.\n");
+		goto out_lookup_fail;
+	}
+
+	if (related) {
+		/* flip src/dst as ICMPv6 related carries the original ip/l4 headers in
+		 * opposite direction - it is a reaction on the original packet.
+		 */
+		struct calico_ct_leg *tmp;
+
+		tmp = src_to_dst;
+		src_to_dst = dst_to_src;
+		dst_to_src = tmp;
+
+		/* We don't translate the addresses inside related ICMPv6 errors so, for
+		 * simplicity, we only allow them for flows that are not NATted. */
+		if (ct_result_rc(result.rc) == CALI_CT_ESTABLISHED_DNAT ||
+				ct_result_rc(result.rc) == CALI_CT_ESTABLISHED_SNAT) {
+			CALI_CT_DEBUG("ICMPv6 related to NAT flow not supported.\n");
+			goto out_invalid;
+		}
+	}
+
+	if (CALI_F_TO_HOST) {
+		/* Source of the packet is the endpoint, so check the src whitelist. */
+		if (src_to_dst->whitelisted) {
+			CALI_CT_VERB("Packet whitelisted by this workload's policy.\n");
+		} else {
+			/* Only whitelisted by the other side (so far)?  Unlike
+			 * TCP we have no way to distinguish packets that open a
+			 * new connection so we have to return NEW here in order
+			 * to invoke policy.
+			 */
+			CALI_CT_DEBUG("Packet not allowed by ingress/egress whitelist flags (TH).\n");
+			result.rc = tcp_header ? CALI_CT_INVALID : CALI_CT_NEW;
+		}
+	} else {
+		/* Dest of the packet is the endpoint, so check the dest whitelist. */
+		if (dst_to_src->whitelisted) {
+			// Packet was whitelisted by the policy attached to this endpoint.
+			CALI_CT_VERB("Packet whitelisted by this workload's policy.\n");
+		} else {
+			CALI_CT_DEBUG("Packet not allowed by ingress/egress whitelist flags (FH).\n");
+			result.rc = (tcp_header && !syn) ? CALI_CT_INVALID : CALI_CT_NEW;
+		}
+	}
+
+	if (tcp_header && !related) {
+		ct_tcp_entry_update(tcp_header, src_to_dst, dst_to_src);
+	}
+
+	__u32 ifindex = skb_ingress_ifindex(ct_ctx->skb);
+
+	if (src_to_dst->ifindex != ifindex) {
+		if (CALI_F_TO_HOST) {
+			if (src_to_dst->ifindex == CT_INVALID_IFINDEX) {
+				CALI_CT_DEBUG("First response packet? ifindex=%d\n", ifindex);
+			} else {
+				CALI_CT_DEBUG("CT RPF failed ifindex %d != %d\n",
+						src_to_dst->ifindex, ifindex);
+			}
+			if (ct_result_rc(result.rc) == CALI_CT_ESTABLISHED_BYPASS) {
+				// Disable bypass so the kernel can do its RPF check.
+				CALI_CT_DEBUG("Disabling bypass to allow kernel RPF.\n");
+				ct_result_set_rc(result.rc, CALI_CT_ESTABLISHED);
+			}
+			ct_result_set_flag(result.rc, CALI_CT_RPF_FAILED);
+		} else if (src_to_dst->ifindex != CT_INVALID_IFINDEX) {
+			CALI_CT_DEBUG("Updating ifindex from %d to %d\n",
+					src_to_dst->ifindex, ifindex);
+			src_to_dst->ifindex = ifindex;
+		}
+	}
+
+	if (CALI_F_TO_HOST) {
+		result.ifindex_fwd = dst_to_src->ifindex;
+	}
+
+	CALI_CT_DEBUG("result: %d\n", result.rc);
+
+	if (related) {
+		ct_result_set_flag(result.rc, CALI_CT_RELATED);
+		CALI_CT_DEBUG("result: related\n");
+	}
+
+	return result;
+
+out_lookup_fail:
+	result.rc = CALI_CT_NEW;
+	CALI_CT_DEBUG("result: NEW.\n");
+	return result;
+out_invalid:
+	result.rc = CALI_CT_INVALID;
+	CALI_CT_DEBUG("result: INVALID.\n");
+	return result;
+}
+
+#endif /* __CALI_CONNTRACK_V6_H__ */
@@ -0,0 +1,68 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __CALI_FIB_V6_H__
+#define __CALI_FIB_V6_H__
+
+#include "types_v6.h"
+
+/* forward_or_drop_v6 is the IPv6 version of forward_or_drop.  The IPv6 programs don't
+ * redirect packets themselves so this just marks packets that are going to the host and
+ * logs the result. */
+static CALI_BPF_INLINE int forward_or_drop_v6(struct cali_tc_ctx_v6 *ctx)
+{
+	int rc = ctx->fwd.res;
+	enum calico_reason reason = ctx->fwd.reason;
+	struct cali_tc_state_v6 *state = ctx->state;
+
+	if (rc == TC_ACT_SHOT) {
+		goto deny;
+	}
+
+	if (CALI_F_TO_HOST) {
+		/* Packet is towards host namespace, mark it so that downstream
+		 * programs know that they're not the first to see the packet.
+		 */
+		ctx->fwd.mark |=  CALI_SKB_MARK_SEEN;
+		if (ctx->state->ct_result.flags & CALI_CT_FLAG_EXT_LOCAL) {
+			CALI_DEBUG("To host marked with FLAG_EXT_LOCAL\n");
+			ctx->fwd.mark |= EXT_TO_SVC_MARK;
+		}
+		CALI_DEBUG("Traffic is towards host namespace, marking with %x.\n", ctx->fwd.mark);
+		/* See forward_or_drop for why we don't mask the mark. */
+		ctx->skb->mark = ctx->fwd.mark; /* make sure that each pkt has SEEN mark */
+	}
+
+	if (CALI_LOG_LEVEL >= CALI_LOG_LEVEL_INFO) {
+		__u64 prog_end_time = bpf_ktime_get_ns();
+		CALI_INFO("Final result=ALLOW (%d). Program execution time: %lluns\n",
+				reason, prog_end_time-state->prog_start_time);
+	}
+
+	return rc;
+
+deny:
+	if (CALI_LOG_LEVEL >= CALI_LOG_LEVEL_INFO) {
+		__u64 prog_end_time = bpf_ktime_get_ns();
+		CALI_INFO("Final result=DENY (%x). Program execution time: %lluns\n",
+				reason, prog_end_time-state->prog_start_time);
+	}
+
+	return TC_ACT_SHOT;
+}
+
+#endif /* __CALI_FIB_V6_H__ */
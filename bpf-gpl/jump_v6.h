@@ -0,0 +1,62 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __CALI_BPF_JUMP_V6_H__
+#define __CALI_BPF_JUMP_V6_H__
+
+#include "types_v6.h"
+#include "policy_v6.h"
+
+CALI_MAP_V1(cali_v6_state,
+		BPF_MAP_TYPE_PERCPU_ARRAY,
+		__u32, struct cali_tc_state_v6,
+		1, 0, MAP_PIN_GLOBAL)
+
+static CALI_BPF_INLINE struct cali_tc_state_v6 *state_v6_get(void)
+{
+	__u32 key = 0;
+	return cali_v6_state_lookup_elem(&key);
+}
+
+/* The IPv6 programs have their own jump map; it is pinned per-object, like the IPv4 one. */
+struct bpf_map_def_extended __attribute__((section("maps"))) cali_jump = {
+	.type = BPF_MAP_TYPE_PROG_ARRAY,
+	.key_size = 4,
+	.value_size = 4,
+	.max_entries = 8,
+#ifndef __BPFTOOL_LOADER__
+	.map_id = 1,
+	.pinning_strategy = 1 /* object namespace */,
+#endif
+};
+
+static CALI_BPF_INLINE void tc_state_v6_fill_from_iphdr(struct cali_tc_state_v6 *state,
+							struct ipv6hdr *ip)
+{
+	state->ip_src = ipv6_addr_from_in6(&ip->saddr);
+	state->ip_dst = ipv6_addr_from_in6(&ip->daddr);
+	state->ip_proto = ip->nexthdr;
+}
+
+/* Same program indices as the IPv4 programs, see enum cali_jump_index.  There is no ICMP
+ * program for IPv6 (yet). */
+enum cali_jump_v6_index {
+	PROG_V6_INDEX_POLICY,
+	PROG_V6_INDEX_EPILOGUE,
+};
+
+#endif /* __CALI_BPF_JUMP_V6_H__ */
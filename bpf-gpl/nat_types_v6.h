@@ -0,0 +1,66 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __CALI_NAT_TYPES_V6_H__
+#define __CALI_NAT_TYPES_V6_H__
+
+// WARNING: must be kept in sync with the Go definitions in bpf/nat/maps_v6.go.
+
+/* Map: IPv6 NAT level one.  Dest IP, port and src IP -> ID and num backends.  Like the IPv4
+ * map, it is an LPM trie so that we can drop packets from outside a load balancer's source
+ * ranges.  The value is the same as for IPv4.
+ */
+struct __attribute__((__packed__)) calico_nat_v6_key {
+	__u32 prefixlen;
+	ipv6_addr_t addr; // NBO
+	__u16 port; // HBO
+	__u8 protocol;
+	ipv6_addr_t saddr;
+	__u8 pad;
+};
+
+/* Prefix len = (dst_addr + port + protocol + src_addr) in bits. */
+#define NAT_V6_PREFIX_LEN_WITH_SRC_MATCH  (sizeof(struct calico_nat_v6_key) - \
+					   sizeof(((struct calico_nat_v6_key*)0)->prefixlen) - \
+					   sizeof(((struct calico_nat_v6_key*)0)->pad))
+
+#define NAT_V6_PREFIX_LEN_WITH_SRC_MATCH_IN_BITS (NAT_V6_PREFIX_LEN_WITH_SRC_MATCH * 8)
+
+union calico_nat_v6_lpm_key {
+        struct bpf_lpm_trie_key lpm;
+        struct calico_nat_v6_key key;
+};
+
+CALI_MAP_V1(cali_v6_nat_fe,
+		BPF_MAP_TYPE_LPM_TRIE,
+		union calico_nat_v6_lpm_key, struct calico_nat_v4_value,
+		511000, BPF_F_NO_PREALLOC, MAP_PIN_GLOBAL)
+
+// Map: IPv6 NAT level two.  ID and ordinal (same key as IPv4) -> new dest and port.
+
+struct calico_nat_dest_v6 {
+	ipv6_addr_t addr;
+	__u16 port;
+	__u8 pad[2];
+};
+
+CALI_MAP_V1(cali_v6_nat_be,
+		BPF_MAP_TYPE_HASH,
+		struct calico_nat_secondary_v4_key, struct calico_nat_dest_v6,
+		510000, BPF_F_NO_PREALLOC, MAP_PIN_GLOBAL)
+
+#endif /*  __CALI_NAT_TYPES_V6_H__ */
@@ -0,0 +1,123 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __CALI_NAT_V6_H__
+#define __CALI_NAT_V6_H__
+
+#include "bpf.h"
+#include "nat.h"
+#include "types_v6.h"
+
+/* skb_nat_l4_csum_ipv6 is the IPv6 version of skb_nat_l4_csum_ipv4.  IPv6 has no L3 checksum
+ * but the L4 checksum covers the pseudo header, which includes the 128-bit addresses. */
+static CALI_BPF_INLINE int skb_nat_l4_csum_ipv6(struct __sk_buff *skb, size_t off,
+						ipv6_addr_t *ip_from, ipv6_addr_t *ip_to,
+						__u16 port_from, __u16 port_to,
+						__u64 flags)
+{
+	int ret = 0;
+
+	if (!ipv6_addr_eq(ip_from, ip_to)) {
+		CALI_DEBUG("L4 checksum update (csum is at %d) IP from ..%x to ..%x\n", off,
+				ipv6_addr_log(ip_from), ipv6_addr_log(ip_to));
+		__wsum diff = bpf_csum_diff((__be32 *)ip_from, sizeof(*ip_from),
+					    (__be32 *)ip_to, sizeof(*ip_to), 0);
+		ret = bpf_l4_csum_replace(skb, off, 0, diff, flags | BPF_F_PSEUDO_HDR);
+		CALI_DEBUG("bpf_l4_csum_replace(IP): %d\n", ret);
+	}
+	if (port_from != port_to) {
+		CALI_DEBUG("L4 checksum update (csum is at %d) port from %d to %d\n",
+				off, bpf_ntohs(port_from), bpf_ntohs(port_to));
+		int rc = bpf_l4_csum_replace(skb, off, port_from, port_to, flags | 2);
+		CALI_DEBUG("bpf_l4_csum_replace(port): %d\n", rc);
+		ret |= rc;
+	}
+
+	return ret;
+}
+
+/* calico_v6_nat_lookup is the IPv6 version of calico_v4_nat_lookup2.  Node ports and session
+ * affinity are not supported for IPv6 so only the frontend for the packet's own dest can match.
+ */
+static CALI_BPF_INLINE struct calico_nat_dest_v6* calico_v6_nat_lookup(ipv6_addr_t *ip_src,
+								       ipv6_addr_t *ip_dst,
+								       __u8 ip_proto,
+								       __u16 dport,
+								       nat_lookup_result *res)
+{
+	union calico_nat_v6_lpm_key nat_key = {
+		.key = {
+			.prefixlen = NAT_V6_PREFIX_LEN_WITH_SRC_MATCH_IN_BITS,
+			.addr = *ip_dst,
+			.port = dport,
+			.protocol = ip_proto,
+			.saddr = *ip_src,
+		},
+	};
+	struct calico_nat_v4_value *nat_lv1_val;
+	struct calico_nat_secondary_v4_key nat_lv2_key;
+	struct calico_nat_dest_v6 *nat_lv2_val;
+
+	if (!CALI_F_TO_HOST) {
+		// Skip NAT lookup for traffic leaving the host namespace.
+		return NULL;
+	}
+
+	nat_lv1_val = cali_v6_nat_fe_lookup_elem(&nat_key);
+	CALI_DEBUG("NAT: 1st level lookup addr=..%x port=%d protocol=%d.\n",
+		ipv6_addr_log(ip_dst), (int)dport, (int)ip_proto);
+
+	if (!nat_lv1_val) {
+		CALI_DEBUG("NAT: Miss.\n");
+		return NULL;
+	}
+
+	/* With LB source range, we install a drop entry in the NAT FE map
+	 * with count equal to 0xffffffff. If we hit this entry,
+	 * packet is dropped.
+	 */
+	if (nat_lv1_val->count == NAT_FE_DROP_COUNT) {
+		*res = NAT_FE_LOOKUP_DROP;
+		return NULL;
+	}
+
+	CALI_DEBUG("NAT: 1st level hit; id=%d\n", nat_lv1_val->id);
+
+	if (nat_lv1_val->count == 0) {
+		CALI_DEBUG("NAT: no backend\n");
+		*res = NAT_NO_BACKEND;
+		return NULL;
+	}
+
+	nat_lv2_key.id = nat_lv1_val->id;
+	nat_lv2_key.ordinal = bpf_get_prandom_u32();
+	nat_lv2_key.ordinal %= nat_lv1_val->count;
+
+	CALI_DEBUG("NAT: 1st level hit; id=%d ordinal=%d\n", nat_lv2_key.id, nat_lv2_key.ordinal);
+
+	if (!(nat_lv2_val = cali_v6_nat_be_lookup_elem(&nat_lv2_key))) {
+		CALI_DEBUG("NAT: backend miss\n");
+		*res = NAT_NO_BACKEND;
+		return NULL;
+	}
+
+	CALI_DEBUG("NAT: backend selected ..%x:%d\n", ipv6_addr_log(&nat_lv2_val->addr), nat_lv2_val->port);
+
+	return nat_lv2_val;
+}
+
+#endif /* __CALI_NAT_V6_H__ */
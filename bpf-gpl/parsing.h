@@ -26,6 +26,12 @@ static CALI_BPF_INLINE int parse_packet_ip(struct cali_tc_ctx *ctx) {
 		CALI_DEBUG("ARP: allowing packet\n");
 		goto allow_no_fib;
 	case ETH_P_IPV6:
+		if (IPV6_ENABLED) {
+			/* IPv6 is handled by a separate program, which is attached after this one;
+			 * returning TC_ACT_UNSPEC passes the packet on to it. */
+			CALI_DEBUG("IPv6: pass to IPv6 program\n");
+			goto allow_no_fib;
+		}
 		if (CALI_F_WEP) {
 			CALI_DEBUG("IPv6 from workload: drop\n");
 			goto deny;
@@ -0,0 +1,109 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __CALI_PARSING_V6_H__
+#define __CALI_PARSING_V6_H__
+
+#include <linux/icmpv6.h>
+
+#include "skb_v6.h"
+#include "types_v6.h"
+
+/* Neighbour discovery message types, from RFC 4861; the kernel doesn't export these. */
+#define CALI_ND_ROUTER_SOLICIT		133
+#define CALI_ND_ROUTER_ADVERT		134
+#define CALI_ND_NEIGHBOUR_SOLICIT	135
+#define CALI_ND_NEIGHBOUR_ADVERT	136
+#define CALI_ND_REDIRECT		137
+
+#define ipv6_addr_is_multicast(addr) (((addr)->a[0] & bpf_htonl(0xff000000)) == bpf_htonl(0xff000000))
+
+/* icmp6_is_link_control returns true for the neighbour discovery and multicast listener
+ * messages, which IPv6 needs in order to work at all, so we don't subject them to policy. */
+static CALI_BPF_INLINE bool icmp6_is_link_control(__u8 type)
+{
+	switch (type) {
+	case ICMPV6_MGM_QUERY:
+	case ICMPV6_MGM_REPORT:
+	case ICMPV6_MGM_REDUCTION:
+	case ICMPV6_MLD2_REPORT:
+	case CALI_ND_ROUTER_SOLICIT:
+	case CALI_ND_ROUTER_ADVERT:
+	case CALI_ND_NEIGHBOUR_SOLICIT:
+	case CALI_ND_NEIGHBOUR_ADVERT:
+	case CALI_ND_REDIRECT:
+		return true;
+	}
+
+	return false;
+}
+
+static CALI_BPF_INLINE int parse_packet_ipv6(struct cali_tc_ctx_v6 *ctx) {
+	if (bpf_htons(ctx->skb->protocol) != ETH_P_IPV6) {
+		CALI_DEBUG("Not IPv6 (%x), allow\n", bpf_ntohs(ctx->skb->protocol));
+		goto allow_no_fib;
+	}
+
+	if (skb_v6_refresh_validate_ptrs(ctx, UDP_SIZE)) {
+		ctx->fwd.reason = CALI_REASON_SHORT;
+		CALI_DEBUG("Too short\n");
+		goto deny;
+	}
+
+	switch (ctx->ip_header->nexthdr) {
+	case IPPROTO_TCP:
+	case IPPROTO_UDP:
+		break;
+	case IPPROTO_ICMPV6:
+		if (icmp6_is_link_control(ctx->icmp_header->icmp6_type)) {
+			CALI_DEBUG("ICMPv6 link control (%d): allow\n", ctx->icmp_header->icmp6_type);
+			goto allow_no_fib;
+		}
+		break;
+	case IPPROTO_HOPOPTS:
+		/* MLD reports carry a router alert hop-by-hop option; allow it for link-scoped
+		 * multicast traffic only. */
+		if (ipv6_addr_is_multicast((ipv6_addr_t *)&ctx->ip_header->daddr)) {
+			CALI_DEBUG("Hop-by-hop options to multicast: allow\n");
+			goto allow_no_fib;
+		}
+		/* fall through */
+	case IPPROTO_ROUTING:
+	case IPPROTO_FRAGMENT:
+	case IPPROTO_AH:
+	case IPPROTO_DSTOPTS:
+		/* We don't (yet) walk the IPv6 extension header chain. */
+		if (CALI_F_WEP) {
+			ctx->fwd.reason = CALI_REASON_IP_OPTIONS;
+			CALI_DEBUG("Drop packets with IPv6 extension headers\n");
+			goto deny;
+		}
+		CALI_DEBUG("Allow packets with IPv6 extension headers on host interface\n");
+		goto allow_no_fib;
+	}
+
+	return 0;
+
+allow_no_fib:
+	ctx->fwd.res = TC_ACT_UNSPEC;
+	return -1;
+deny:
+	ctx->fwd.res = TC_ACT_SHOT;
+	return -1;
+}
+
+#endif /* __CALI_PARSING_V6_H__ */
@@ -0,0 +1,70 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+/*
+ * This file contains "default" implementations of the IPv6 policy program; see
+ * policy_program.h for the IPv4 equivalent.
+ */
+
+#ifndef __CALI_POL_PROG_V6_H__
+#define __CALI_POL_PROG_V6_H__
+
+#ifndef CALI_NO_DEFAULT_POLICY_PROG
+
+static CALI_BPF_INLINE enum calico_policy_result execute_policy_norm_v6(struct __sk_buff *skb)
+{
+#pragma clang diagnostic push
+#pragma clang diagnostic ignored "-Wunused-label"
+
+	RULE_START(0);
+#ifdef CALI_DEBUG_ALLOW_ALL
+	RULE_END(0, allow);
+#else
+	RULE_END(0, deny);
+#endif
+
+	return CALI_POL_NO_MATCH;
+deny:
+	return CALI_POL_DENY;
+allow:
+	return CALI_POL_ALLOW;
+#pragma clang diagnostic pop
+}
+
+__attribute__((section("1/0")))
+int calico_tc_v6_norm_pol_tail(struct __sk_buff *skb)
+{
+	CALI_DEBUG("Entering normal IPv6 policy tail call\n");
+
+	struct cali_tc_state_v6 *state = state_v6_get();
+	if (!state) {
+	        CALI_DEBUG("State map lookup failed: DROP\n");
+	        goto deny;
+	}
+
+	state->pol_rc = execute_policy_norm_v6(skb);
+
+	bpf_tail_call(skb, &cali_jump, PROG_V6_INDEX_EPILOGUE);
+	CALI_DEBUG("Tail call to post-policy program failed: DROP\n");
+
+deny:
+	return TC_ACT_SHOT;
+}
+
+#endif /* CALI_NO_DEFAULT_POLICY_PROG */
+
+#endif /*  __CALI_POL_PROG_V6_H__ */
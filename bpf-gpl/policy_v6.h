@@ -0,0 +1,53 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __CALI_POLICY_V6_H__
+#define __CALI_POLICY_V6_H__
+
+#include "policy.h"
+#include "types_v6.h"
+
+// IPv6 IP sets, all stored in one big map with a prefix to identify the set.
+
+// WARNING: must be kept in sync with the definitions in bpf/polprog/pol_prog_builder.go.
+// WARNING: must be kept in sync with the definitions in bpf/ipsets/map_v6.go.
+struct ip6_set_key {
+	__u32 mask;
+	__be64 set_id;
+	ipv6_addr_t addr;
+	__u16 port;
+	__u8 protocol;
+	__u8 pad;
+} __attribute__((packed));
+
+union ip6_set_lpm_key {
+	struct bpf_lpm_trie_key lpm;
+	struct ip6_set_key ip;
+};
+
+struct bpf_map_def_extended __attribute__((section("maps"))) cali_v6_ip_sets = {
+	.type           = BPF_MAP_TYPE_LPM_TRIE,
+	.key_size       = sizeof(union ip6_set_lpm_key),
+	.value_size     = sizeof(__u32),
+	.max_entries    = 1024*1024,
+	.map_flags      = BPF_F_NO_PREALLOC,
+#ifndef __BPFTOOL_LOADER__
+	.pinning_strategy        = MAP_PIN_GLOBAL,
+#endif
+};
+
+#endif /* __CALI_POLICY_V6_H__ */
@@ -0,0 +1,73 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __CALI_ROUTES_V6_H__
+#define __CALI_ROUTES_V6_H__
+
+#include "routes.h"
+#include "types_v6.h"
+
+// Map: IPv6 routes.  Uses the same flags as the IPv4 map.
+
+struct cali_rt_v6_key {
+	__u32 prefixlen;
+	ipv6_addr_t addr; // NBO
+};
+
+union cali_rt_v6_lpm_key {
+	struct bpf_lpm_trie_key lpm;
+	struct cali_rt_v6_key key;
+};
+
+struct cali_rt_v6 {
+	__u32 flags; /* enum cali_rt_flags */
+	union {
+		// IP encap next hop for remote workload routes.
+		ipv6_addr_t next_hop;
+		// Interface index for local workload routes.
+		__u32 if_index;
+	};
+};
+
+CALI_MAP_V1(cali_v6_routes,
+		BPF_MAP_TYPE_LPM_TRIE,
+		union cali_rt_v6_lpm_key, struct cali_rt_v6,
+		1024*1024, BPF_F_NO_PREALLOC, MAP_PIN_GLOBAL)
+
+static CALI_BPF_INLINE struct cali_rt_v6 *cali_rt_v6_lookup(ipv6_addr_t *addr)
+{
+	union cali_rt_v6_lpm_key k;
+	k.key.prefixlen = 128;
+	k.key.addr = *addr;
+	return cali_v6_routes_lookup_elem(&k);
+}
+
+static CALI_BPF_INLINE enum cali_rt_flags cali_rt_v6_lookup_flags(ipv6_addr_t *addr)
+{
+	struct cali_rt_v6 *rt = cali_rt_v6_lookup(addr);
+	if (!rt) {
+		return CALI_RT_UNKNOWN;
+	}
+	return rt->flags;
+}
+
+static CALI_BPF_INLINE bool rt_v6_addr_is_local_host(ipv6_addr_t *addr)
+{
+	return  cali_rt_flags_local_host(cali_rt_v6_lookup_flags(addr));
+}
+
+#endif /* __CALI_ROUTES_V6_H__ */
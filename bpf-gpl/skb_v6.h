@@ -0,0 +1,68 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __SKB_V6_H__
+#define __SKB_V6_H__
+
+#include <linux/ipv6.h>
+
+#include "skb.h"
+#include "types_v6.h"
+
+#define IPV6_SIZE (sizeof(struct ipv6hdr))
+#define ICMPV6_SIZE (sizeof(struct icmp6hdr))
+
+static CALI_BPF_INLINE void skb_v6_refresh_start_end(struct cali_tc_ctx_v6 *ctx) {
+	ctx->data_start = skb_start_ptr(ctx->skb);
+	ctx->data_end = skb_end_ptr(ctx->skb);
+}
+
+static CALI_BPF_INLINE void skb_v6_refresh_hdr_ptrs(struct cali_tc_ctx_v6 *ctx)
+{
+	ctx->ip_header = ctx->data_start + skb_iphdr_offset(ctx->skb);
+	ctx->nh = (void*)(ctx->ip_header+1);
+}
+
+/* skb_v6_refresh_validate_ptrs is the IPv6 version of skb_refresh_validate_ptrs.  It validates
+ * access to the fixed IPv6 header + nh_len bytes; extension headers are not supported so the
+ * next header is assumed to follow the fixed header.
+ */
+static CALI_BPF_INLINE int skb_v6_refresh_validate_ptrs(struct cali_tc_ctx_v6 *ctx, long nh_len) {
+	int min_size = skb_iphdr_offset(ctx->skb) + IPV6_SIZE;
+	skb_v6_refresh_start_end(ctx);
+	if (ctx->data_start + (min_size + nh_len) > ctx->data_end) {
+		// Try to pull in more data.  Ideally enough for TCP, or, failing that, the
+		// minimum we've been asked for.
+		if (nh_len > TCP_SIZE || bpf_skb_pull_data(ctx->skb, min_size + TCP_SIZE)) {
+			CALI_DEBUG("Pulling %d bytes.\n", min_size + nh_len);
+			if (bpf_skb_pull_data(ctx->skb, min_size + nh_len)) {
+				CALI_DEBUG("Pull failed (min len)\n");
+				return -1;
+			}
+		}
+		CALI_DEBUG("Pulled data\n");
+		skb_v6_refresh_start_end(ctx);
+		if (ctx->data_start + (min_size + nh_len) > ctx->data_end) {
+			return -2;
+		}
+	}
+	// Success, refresh the IP header and next header.
+	skb_v6_refresh_hdr_ptrs(ctx);
+	return 0;
+}
+
+#endif /* __SKB_V6_H__ */
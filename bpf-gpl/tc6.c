@@ -0,0 +1,584 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#include <linux/types.h>
+#include <linux/bpf.h>
+#include <linux/pkt_cls.h>
+#include <linux/ipv6.h>
+#include <linux/icmpv6.h>
+#include <linux/tcp.h>
+#include <linux/in.h>
+#include <linux/udp.h>
+#include <linux/if_ether.h>
+#include <iproute2/bpf_elf.h>
+
+// stdbool.h has no deps so it's OK to include; stdint.h pulls in parts
+// of the std lib that aren't compatible with BPF.
+#include <stdbool.h>
+
+#include "bpf.h"
+#include "types.h"
+#include "types_v6.h"
+#include "log.h"
+#include "skb_v6.h"
+#include "policy_v6.h"
+#include "conntrack_v6.h"
+#include "nat_v6.h"
+#include "routes_v6.h"
+#include "jump_v6.h"
+#include "reasons.h"
+#include "fib.h"
+#include "fib_v6.h"
+#include "policy_program_v6.h"
+#include "parsing_v6.h"
+
+/* tc6.c contains the IPv6 tc programs.  They are attached to the same hooks as the IPv4
+ * programs, but with a "protocol ipv6" filter, and they follow the same flow as calico_tc in
+ * tc.c.  Compared with IPv4, they don't support FIB lookups, node ports, DSR, tunnels,
+ * failsafe ports or generating ICMP errors.
+ */
+
+static CALI_BPF_INLINE struct fwd calico_tc_v6_skb_accepted(struct cali_tc_ctx_v6 *ctx,
+							    struct calico_nat_dest_v6 *nat_dest);
+
+static CALI_BPF_INLINE int calico_tc_v6(struct __sk_buff *skb)
+{
+#ifdef CALI_SET_SKB_MARK
+	/* UT-only workaround to allow us to run the program with BPF_TEST_PROG_RUN
+	 * and simulate a specific mark
+	 */
+	skb->mark = CALI_SET_SKB_MARK;
+#endif
+	CALI_DEBUG("New IPv6 packet at ifindex=%d; mark=%x\n", skb->ifindex, skb->mark);
+
+	/* Optimisation: if another BPF program has already pre-approved the packet,
+	 * skip all processing. */
+	if (!CALI_F_TO_HOST && skb->mark == CALI_SKB_MARK_BYPASS) {
+		CALI_INFO("Final result=ALLOW (%d). Bypass mark bit set.\n", CALI_REASON_BYPASS);
+		return TC_ACT_UNSPEC;
+	}
+
+	struct cali_tc_ctx_v6 ctx = {
+		.state = state_v6_get(),
+		.skb = skb,
+		.fwd = {
+			.res = TC_ACT_UNSPEC,
+			.reason = CALI_REASON_UNKNOWN,
+		},
+	};
+	if (!ctx.state) {
+		CALI_DEBUG("State map lookup failed: DROP\n");
+		return TC_ACT_SHOT;
+	}
+	__builtin_memset(ctx.state, 0, sizeof(*ctx.state));
+
+	if (CALI_LOG_LEVEL >= CALI_LOG_LEVEL_INFO) {
+		ctx.state->prog_start_time = bpf_ktime_get_ns();
+	}
+
+	if ((CALI_F_TO_HEP || CALI_F_TO_WEP) &&
+			(skb->mark & CALI_SKB_MARK_BYPASS_MASK) == CALI_SKB_MARK_BYPASS_FWD) {
+		CALI_DEBUG("Packet approved for forward.\n");
+		ctx.fwd.reason = CALI_REASON_BYPASS;
+		goto allow;
+	}
+
+	/* Parse the packet as far as the IP header; as a side-effect this validates the packet size
+	 * is large enough for UDP. */
+	if (parse_packet_ipv6(&ctx)) {
+		// Either a problem or a packet that we automatically let through.
+		goto finalize;
+	}
+
+	/* Copy fields that are needed by downstream programs from the packet to the state. */
+	tc_state_v6_fill_from_iphdr(ctx.state, ctx.ip_header);
+
+	/* Parse out the source/dest ports (or type/code for ICMPv6). */
+	switch (ctx.state->ip_proto) {
+	case IPPROTO_TCP:
+		// Re-check buffer space for TCP (has larger headers than UDP).
+		if (skb_v6_refresh_validate_ptrs(&ctx, TCP_SIZE)) {
+			ctx.fwd.reason = CALI_REASON_SHORT;
+			CALI_DEBUG("Too short\n");
+			goto deny;
+		}
+		ctx.state->sport = bpf_ntohs(ctx.tcp_header->source);
+		ctx.state->dport = bpf_ntohs(ctx.tcp_header->dest);
+		CALI_DEBUG("TCP; ports: s=%d d=%d\n", ctx.state->sport, ctx.state->dport);
+		break;
+	case IPPROTO_UDP:
+		ctx.state->sport = bpf_ntohs(ctx.udp_header->source);
+		ctx.state->dport = bpf_ntohs(ctx.udp_header->dest);
+		CALI_DEBUG("UDP; ports: s=%d d=%d\n", ctx.state->sport, ctx.state->dport);
+		break;
+	case IPPROTO_ICMPV6:
+		CALI_DEBUG("ICMPv6; type=%d code=%d\n",
+				ctx.icmp_header->icmp6_type, ctx.icmp_header->icmp6_code);
+		break;
+	default:
+		CALI_DEBUG("Unknown protocol (%d), unable to extract ports\n", (int)ctx.state->ip_proto);
+	}
+
+	ctx.state->pol_rc = CALI_POL_NO_MATCH;
+
+	/* Do conntrack lookup before anything else */
+	ctx.state->ct_result = calico_ct_v6_lookup(&ctx);
+	CALI_DEBUG("conntrack entry flags 0x%x\n", ctx.state->ct_result.flags);
+
+	if (ctx.state->ct_result.flags & CALI_CT_FLAG_NAT_OUT) {
+		ctx.state->flags |= CALI_ST_NAT_OUTGOING;
+	}
+
+	if (ct_result_rc(ctx.state->ct_result.rc) == CALI_CT_MID_FLOW_MISS) {
+		if (CALI_F_TO_HOST) {
+			/* Mid-flow miss: let ip6tables handle it in case it's an existing flow
+			 * in the Linux conntrack table. */
+			CALI_DEBUG("CT mid-flow miss; fall through to ip6tables.\n");
+			ctx.fwd.mark = CALI_SKB_MARK_FALLTHROUGH;
+			goto finalize;
+		} else {
+			if (CALI_F_HEP) {
+				CALI_DEBUG("CT mid-flow miss away from host with no Linux conntrack entry, allow.\n");
+				goto allow;
+			} else {
+				CALI_DEBUG("CT mid-flow miss away from host with no Linux conntrack entry, drop.\n");
+				goto deny;
+			}
+		}
+	}
+
+	/* Skip policy if we get conntrack hit */
+	if (ct_result_rc(ctx.state->ct_result.rc) != CALI_CT_NEW) {
+		CALI_DEBUG("CT Hit\n");
+		goto skip_policy;
+	}
+
+	/* No conntrack entry, check if we should do NAT */
+	nat_lookup_result nat_res = NAT_LOOKUP_ALLOW;
+	ctx.nat_dest = calico_v6_nat_lookup(&ctx.state->ip_src, &ctx.state->ip_dst,
+					    ctx.state->ip_proto, ctx.state->dport, &nat_res);
+
+	if (nat_res == NAT_FE_LOOKUP_DROP) {
+		CALI_DEBUG("Packet is from an unauthorised source: DROP\n");
+		ctx.fwd.reason = CALI_REASON_UNAUTH_SOURCE;
+		goto deny;
+	}
+	if (ctx.nat_dest != NULL) {
+		ctx.state->post_nat_ip_dst = ctx.nat_dest->addr;
+		ctx.state->post_nat_dport = ctx.nat_dest->port;
+	} else if (nat_res == NAT_NO_BACKEND) {
+		/* We don't generate ICMPv6 errors (yet) so just drop. */
+		CALI_DEBUG("Service has no backends: DROP\n");
+		goto deny;
+	} else {
+		ctx.state->post_nat_ip_dst = ctx.state->ip_dst;
+		ctx.state->post_nat_dport = ctx.state->dport;
+	}
+
+	if (CALI_F_TO_WEP && !skb_seen(skb) &&
+			cali_rt_flags_local_host(cali_rt_v6_lookup_flags(&ctx.state->ip_src))) {
+		/* Host to workload traffic always allowed.  We discount traffic that was
+		 * seen by another program since it must have come in via another interface.
+		 */
+		CALI_DEBUG("Packet is from the host: ACCEPT\n");
+		ctx.state->pol_rc = CALI_POL_ALLOW;
+		goto skip_policy;
+	}
+
+	if (CALI_F_FROM_WEP) {
+		/* Do RPF check since it's our responsibility to police that. */
+		CALI_DEBUG("Workload RPF check src=..%x skb iface=%d.\n",
+				ipv6_addr_log(&ctx.state->ip_src), skb->ifindex);
+		struct cali_rt_v6 *r = cali_rt_v6_lookup(&ctx.state->ip_src);
+		if (!r) {
+			CALI_INFO("Workload RPF fail: missing route.\n");
+			goto deny;
+		}
+		if (!cali_rt_flags_local_workload(r->flags)) {
+			CALI_INFO("Workload RPF fail: not a local workload.\n");
+			goto deny;
+		}
+		if (r->if_index != skb->ifindex) {
+			CALI_INFO("Workload RPF fail skb iface (%d) != route iface (%d)\n",
+					skb->ifindex, r->if_index);
+			goto deny;
+		}
+
+		// Check whether the workload needs outgoing NAT to this address.
+		if (r->flags & CALI_RT_NAT_OUT) {
+			if (!(cali_rt_v6_lookup_flags(&ctx.state->post_nat_ip_dst) & CALI_RT_IN_POOL)) {
+				CALI_DEBUG("Source is in NAT-outgoing pool "
+					   "but dest is not, need to SNAT.\n");
+				ctx.state->flags |= CALI_ST_NAT_OUTGOING;
+			}
+		}
+	}
+
+	/* See the equivalent revalidation in calico_tc. */
+	if (skb_v6_refresh_validate_ptrs(&ctx, UDP_SIZE)) {
+		ctx.fwd.reason = CALI_REASON_SHORT;
+		CALI_DEBUG("Too short\n");
+		goto deny;
+	}
+
+	/* icmp_type and icmp_code share storage with the ports; now we've used
+	 * the ports set to 0 to do the conntrack lookup, we can set the ICMP fields
+	 * for policy.
+	 */
+	if (ctx.state->ip_proto == IPPROTO_ICMPV6) {
+		ctx.state->icmp_type = ctx.icmp_header->icmp6_type;
+		ctx.state->icmp_code = ctx.icmp_header->icmp6_code;
+	}
+
+	ctx.state->pol_rc = CALI_POL_NO_MATCH;
+	if (ctx.nat_dest) {
+		ctx.state->nat_dest.addr = ctx.nat_dest->addr;
+		ctx.state->nat_dest.port = ctx.nat_dest->port;
+	} else {
+		__builtin_memset(&ctx.state->nat_dest, 0, sizeof(ctx.state->nat_dest));
+	}
+
+	ctx.state->pre_nat_ip_dst = ctx.state->ip_dst;
+	ctx.state->pre_nat_dport = ctx.state->dport;
+
+	if (rt_v6_addr_is_local_host(&ctx.state->post_nat_ip_dst)) {
+		CALI_DEBUG("Post-NAT dest IP is local host.\n");
+		ctx.state->flags |= CALI_ST_DEST_IS_HOST;
+	}
+	if (rt_v6_addr_is_local_host(&ctx.state->ip_src)) {
+		CALI_DEBUG("Source IP is local host.\n");
+		ctx.state->flags |= CALI_ST_SRC_IS_HOST;
+	}
+
+	CALI_DEBUG("About to jump to policy program.\n");
+	bpf_tail_call(skb, &cali_jump, PROG_V6_INDEX_POLICY);
+	if (CALI_F_HEP) {
+		CALI_DEBUG("HEP with no policy, allow.\n");
+		ctx.state->pol_rc = CALI_POL_ALLOW;
+		goto skip_policy;
+	} else {
+		/* should not reach here */
+		CALI_DEBUG("WEP with no policy, deny.\n");
+		goto deny;
+	}
+
+skip_policy:
+	if (skb_v6_refresh_validate_ptrs(&ctx, UDP_SIZE)) {
+		ctx.fwd.reason = CALI_REASON_SHORT;
+		CALI_DEBUG("Too short\n");
+		goto deny;
+	}
+
+	ctx.fwd = calico_tc_v6_skb_accepted(&ctx, ctx.nat_dest);
+
+allow:
+finalize:
+	return forward_or_drop_v6(&ctx);
+deny:
+	ctx.fwd.res = TC_ACT_SHOT;
+	goto finalize;
+}
+
+__attribute__((section("1/1")))
+int calico_tc_v6_skb_accepted_entrypoint(struct __sk_buff *skb)
+{
+	CALI_DEBUG("Entering calico_tc_v6_skb_accepted_entrypoint\n");
+	struct cali_tc_ctx_v6 ctx = {
+		.state = state_v6_get(),
+		.skb = skb,
+		.fwd = {
+			.res = TC_ACT_UNSPEC,
+			.reason = CALI_REASON_UNKNOWN,
+		},
+	};
+	if (!ctx.state) {
+		CALI_DEBUG("State map lookup failed: DROP\n");
+		return TC_ACT_SHOT;
+	}
+
+	if (skb_v6_refresh_validate_ptrs(&ctx, UDP_SIZE)) {
+		ctx.fwd.reason = CALI_REASON_SHORT;
+		CALI_DEBUG("Too short\n");
+		goto deny;
+	}
+
+	struct calico_nat_dest_v6 *nat_dest = NULL;
+	struct calico_nat_dest_v6 nat_dest_2 = {
+		.addr = ctx.state->nat_dest.addr,
+		.port = ctx.state->nat_dest.port,
+	};
+	if (!ipv6_addr_is_zero(&ctx.state->nat_dest.addr)) {
+		nat_dest = &nat_dest_2;
+	}
+
+	ctx.fwd = calico_tc_v6_skb_accepted(&ctx, nat_dest);
+	return forward_or_drop_v6(&ctx);
+
+deny:
+	return TC_ACT_SHOT;
+}
+
+static CALI_BPF_INLINE struct fwd calico_tc_v6_skb_accepted(struct cali_tc_ctx_v6 *ctx,
+							    struct calico_nat_dest_v6 *nat_dest)
+{
+	CALI_DEBUG("Entering calico_tc_v6_skb_accepted\n");
+	struct __sk_buff *skb = ctx->skb;
+	struct cali_tc_state_v6 *state = ctx->state;
+
+	enum calico_reason reason = CALI_REASON_UNKNOWN;
+	int rc = TC_ACT_UNSPEC;
+	struct ct_ctx_v6 ct_ctx_nat = {};
+	int ct_rc = ct_result_rc(state->ct_result.rc);
+	bool ct_related = ct_result_is_related(state->ct_result.rc);
+	__u32 seen_mark = CALI_SKB_MARK_SEEN;
+	size_t l4_csum_off = 0;
+	int res = 0;
+
+	CALI_DEBUG("src=..%x dst=..%x\n", ipv6_addr_log(&state->ip_src), ipv6_addr_log(&state->ip_dst));
+	CALI_DEBUG("post_nat=..%x:%d\n", ipv6_addr_log(&state->post_nat_ip_dst), state->post_nat_dport);
+	CALI_DEBUG("pol_rc=%d\n", state->pol_rc);
+	CALI_DEBUG("flags=%x\n", state->flags);
+	CALI_DEBUG("ct_rc=%d\n", ct_rc);
+	CALI_DEBUG("ct_related=%d\n", ct_related);
+
+	// Set the dport to 0, to make sure conntrack entries for icmp is proper as we use
+	// dport to hold icmp type and code
+	if (state->ip_proto == IPPROTO_ICMPV6) {
+		state->dport = 0;
+	}
+
+	if (CALI_F_FROM_WEP && (state->flags & CALI_ST_NAT_OUTGOING)) {
+		// We are going to SNAT this traffic, using ip6tables SNAT so set the mark
+		// to trigger that.
+		seen_mark = CALI_SKB_MARK_NAT_OUT;
+	}
+
+	if (!ct_related) {
+		switch (ctx->ip_header->nexthdr) {
+		case IPPROTO_TCP:
+			l4_csum_off = skb_iphdr_offset(skb) + IPV6_SIZE + offsetof(struct tcphdr, check);
+			break;
+		case IPPROTO_UDP:
+			l4_csum_off = skb_iphdr_offset(skb) + IPV6_SIZE + offsetof(struct udphdr, check);
+			break;
+		case IPPROTO_ICMPV6:
+			l4_csum_off = skb_iphdr_offset(skb) + IPV6_SIZE + offsetof(struct icmp6hdr, icmp6_cksum);
+			break;
+		}
+	}
+
+	switch (ct_rc){
+	case CALI_CT_NEW:
+		switch (state->pol_rc) {
+		case CALI_POL_NO_MATCH:
+			CALI_DEBUG("Implicitly denied by policy: DROP\n");
+			goto deny;
+		case CALI_POL_DENY:
+			CALI_DEBUG("Denied by policy: DROP\n");
+			goto deny;
+		case CALI_POL_ALLOW:
+			CALI_DEBUG("Allowed by policy: ACCEPT\n");
+		}
+
+		if (CALI_F_FROM_WEP &&
+				CALI_DROP_WORKLOAD_TO_HOST &&
+				cali_rt_flags_local_host(
+					cali_rt_v6_lookup_flags(&state->post_nat_ip_dst))) {
+			CALI_DEBUG("Workload to host traffic blocked by "
+				   "DefaultEndpointToHostAction: DROP\n");
+			goto deny;
+		}
+
+		ct_ctx_nat.skb = skb;
+		ct_ctx_nat.proto = state->ip_proto;
+		ct_ctx_nat.src = state->ip_src;
+		ct_ctx_nat.sport = state->sport;
+		ct_ctx_nat.dst = state->post_nat_ip_dst;
+		ct_ctx_nat.dport = state->post_nat_dport;
+		if (state->flags & CALI_ST_NAT_OUTGOING) {
+			ct_ctx_nat.flags |= CALI_CT_FLAG_NAT_OUT;
+		}
+
+		if (state->ip_proto == IPPROTO_TCP) {
+			if (skb_v6_refresh_validate_ptrs(ctx, TCP_SIZE)) {
+				CALI_DEBUG("Too short for TCP: DROP\n");
+				goto deny;
+			}
+			ct_ctx_nat.tcp = ctx->tcp_header;
+		}
+
+		// If we get here, we've passed policy.
+
+		if (nat_dest == NULL) {
+			if (conntrack_v6_create(&ct_ctx_nat, CT_CREATE_NORMAL)) {
+				CALI_DEBUG("Creating normal conntrack failed\n");
+
+				if ((CALI_F_FROM_HEP && rt_v6_addr_is_local_host(&ct_ctx_nat.dst)) ||
+						(CALI_F_TO_HEP && rt_v6_addr_is_local_host(&ct_ctx_nat.src))) {
+					CALI_DEBUG("Allowing local host traffic without CT\n");
+					goto allow;
+				}
+
+				goto deny;
+			}
+			goto allow;
+		}
+
+		ct_ctx_nat.orig_dst = state->ip_dst;
+		ct_ctx_nat.orig_dport = state->dport;
+
+		if (conntrack_v6_create(&ct_ctx_nat, CT_CREATE_NAT)) {
+			CALI_DEBUG("Creating NAT conntrack failed\n");
+			goto deny;
+		}
+		/* fall through as DNAT is now established */
+
+	case CALI_CT_ESTABLISHED_DNAT:
+		/* align with CALI_CT_NEW */
+		if (ct_rc == CALI_CT_ESTABLISHED_DNAT) {
+			state->post_nat_ip_dst = state->ct_result.nat_ip;
+			state->post_nat_dport = state->ct_result.nat_port;
+		}
+
+		CALI_DEBUG("CT: DNAT to ..%x:%d\n",
+				ipv6_addr_log(&state->post_nat_ip_dst), state->post_nat_dport);
+
+		if (skb_v6_refresh_validate_ptrs(ctx, UDP_SIZE)) {
+			reason = CALI_REASON_SHORT;
+			goto deny;
+		}
+
+		ctx->ip_header->daddr = *(struct in6_addr *)&state->post_nat_ip_dst;
+
+		switch (ctx->ip_header->nexthdr) {
+		case IPPROTO_TCP:
+			ctx->tcp_header->dest = bpf_htons(state->post_nat_dport);
+			break;
+		case IPPROTO_UDP:
+			ctx->udp_header->dest = bpf_htons(state->post_nat_dport);
+			break;
+		}
+
+		CALI_VERB("L4 csum at %d\n", l4_csum_off);
+
+		if (l4_csum_off) {
+			res = skb_nat_l4_csum_ipv6(skb, l4_csum_off, &state->ip_dst,
+					&state->post_nat_ip_dst, bpf_htons(state->dport),
+					bpf_htons(state->post_nat_dport),
+					ctx->ip_header->nexthdr == IPPROTO_UDP ? BPF_F_MARK_MANGLED_0 : 0);
+		}
+
+		if (res) {
+			reason = CALI_REASON_CSUM_FAIL;
+			goto deny;
+		}
+
+		state->dport = state->post_nat_dport;
+		state->ip_dst = state->post_nat_ip_dst;
+
+		goto allow;
+
+	case CALI_CT_ESTABLISHED_SNAT:
+		CALI_DEBUG("CT: SNAT from ..%x:%d\n",
+				ipv6_addr_log(&state->ct_result.nat_ip), state->ct_result.nat_port);
+
+		if (skb_v6_refresh_validate_ptrs(ctx, UDP_SIZE)) {
+			reason = CALI_REASON_SHORT;
+			goto deny;
+		}
+
+		// Actually do the NAT.
+		ctx->ip_header->saddr = *(struct in6_addr *)&state->ct_result.nat_ip;
+
+		switch (ctx->ip_header->nexthdr) {
+		case IPPROTO_TCP:
+			ctx->tcp_header->source = bpf_htons(state->ct_result.nat_port);
+			break;
+		case IPPROTO_UDP:
+			ctx->udp_header->source = bpf_htons(state->ct_result.nat_port);
+			break;
+		}
+
+		CALI_VERB("L4 csum at %d\n", l4_csum_off);
+
+		if (l4_csum_off) {
+			res = skb_nat_l4_csum_ipv6(skb, l4_csum_off, &state->ip_src,
+					&state->ct_result.nat_ip, bpf_htons(state->sport),
+					bpf_htons(state->ct_result.nat_port),
+					ctx->ip_header->nexthdr == IPPROTO_UDP ? BPF_F_MARK_MANGLED_0 : 0);
+		}
+
+		if (res) {
+			reason = CALI_REASON_CSUM_FAIL;
+			goto deny;
+		}
+
+		state->sport = state->ct_result.nat_port;
+		state->ip_src = state->ct_result.nat_ip;
+
+		goto allow;
+
+	case CALI_CT_ESTABLISHED_BYPASS:
+		seen_mark = CALI_SKB_MARK_BYPASS;
+		// fall through
+	case CALI_CT_ESTABLISHED:
+		goto allow;
+	default:
+		if (CALI_F_FROM_HEP) {
+			/* See calico_tc_skb_accepted. */
+			CALI_DEBUG("Traffic is towards host namespace but not conntracked, "
+				"falling through to ip6tables\n");
+			goto allow;
+		}
+		goto deny;
+	}
+
+	CALI_INFO("We should never fall through here\n");
+	goto deny;
+
+allow:
+	{
+		struct fwd fwd = {
+			.res = rc,
+			.mark = seen_mark,
+		};
+		return fwd;
+	}
+
+deny:
+	{
+		struct fwd fwd = {
+			.res = TC_ACT_SHOT,
+			.reason = reason,
+		};
+		return fwd;
+	}
+}
+
+#ifndef CALI_ENTRYPOINT_NAME
+#define CALI_ENTRYPOINT_NAME calico_entrypoint_v6
+#endif
+
+// Entrypoint with definable name.  It's useful to redefine the name for each entrypoint
+// because the name is exposed by bpftool et al.
+__attribute__((section(XSTR(CALI_ENTRYPOINT_NAME))))
+int tc_calico_entry_v6(struct __sk_buff *skb)
+{
+	return calico_tc_v6(skb);
+}
+
+char ____license[] __attribute__((section("license"), used)) = "GPL";
@@ -0,0 +1,144 @@
+// Project Calico BPF dataplane programs.
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation; either version 2 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program; if not, write to the Free Software Foundation, Inc.,
+// 51 Franklin Street, Fifth Floor, Boston, MA 02110-1301 USA.
+
+#ifndef __CALI_BPF_TYPES_V6_H__
+#define __CALI_BPF_TYPES_V6_H__
+
+#include <linux/types.h>
+#include <linux/bpf.h>
+#include <linux/ipv6.h>
+#include <linux/icmpv6.h>
+#include <linux/tcp.h>
+#include <linux/udp.h>
+#include "bpf.h"
+#include "types.h"
+
+/* ipv6_addr_t holds an IPv6 address in network byte order.  We use 32-bit words, rather than
+ * struct in6_addr, so that comparisons and copies compile to a handful of 32-bit loads, which
+ * the verifier is happy with. */
+typedef struct {
+	__be32 a[4];
+} ipv6_addr_t;
+
+static CALI_BPF_INLINE bool ipv6_addr_eq(ipv6_addr_t *x, ipv6_addr_t *y)
+{
+	return x->a[0] == y->a[0] && x->a[1] == y->a[1] &&
+		x->a[2] == y->a[2] && x->a[3] == y->a[3];
+}
+
+/* ipv6_addr_lt compares the addresses as 128-bit numbers; it is used to pick the "A" side of a
+ * conntrack key so it only needs to be consistent. */
+static CALI_BPF_INLINE bool ipv6_addr_lt(ipv6_addr_t *x, ipv6_addr_t *y)
+{
+	/* Written out long-hand so that there's no loop for the verifier to worry about. */
+	if (x->a[0] != y->a[0]) {
+		return bpf_ntohl(x->a[0]) < bpf_ntohl(y->a[0]);
+	}
+	if (x->a[1] != y->a[1]) {
+		return bpf_ntohl(x->a[1]) < bpf_ntohl(y->a[1]);
+	}
+	if (x->a[2] != y->a[2]) {
+		return bpf_ntohl(x->a[2]) < bpf_ntohl(y->a[2]);
+	}
+	return bpf_ntohl(x->a[3]) < bpf_ntohl(y->a[3]);
+}
+
+static CALI_BPF_INLINE bool ipv6_addr_is_zero(ipv6_addr_t *x)
+{
+	return !(x->a[0] | x->a[1] | x->a[2] | x->a[3]);
+}
+
+#define ipv6_addr_from_in6(in6) (*(ipv6_addr_t *)(in6))
+
+/* Logging helper: IPv6 addresses are logged as their last 32 bits, which is enough to tell
+ * the endpoints apart in the trace output. */
+#define ipv6_addr_log(addr) bpf_ntohl((addr)->a[3])
+
+#include "conntrack_types_v6.h"
+#include "nat_types_v6.h"
+
+// struct cali_tc_state_v6 holds state that is passed between the IPv6 BPF programs.  It mirrors
+// struct cali_tc_state but without the fields that are only used for IPv4 tunnels.
+// WARNING: must be kept in sync with
+// - the IPv6 offsets in bpf/polprog/pol_prog_builder.go.
+// - the Go version of the struct in bpf/state/map_v6.go
+struct cali_tc_state_v6 {
+	ipv6_addr_t ip_src;
+	ipv6_addr_t ip_dst;
+	ipv6_addr_t pre_nat_ip_dst;
+	ipv6_addr_t post_nat_ip_dst;
+	/* Return code from the policy program CALI_POL_DENY/ALLOW etc. */
+	__s32 pol_rc;
+	__u16 sport;
+	union
+	{
+		__u16 dport;
+		struct
+		{
+			__u8 icmp_type;
+			__u8 icmp_code;
+		};
+	};
+	__u16 pre_nat_dport;
+	__u16 post_nat_dport;
+	__u8 ip_proto;
+	/* Flags from enum cali_state_flags. */
+	__u8 flags;
+	__u8 pad[2];
+
+	/* Result of the conntrack lookup. */
+	struct calico_ct_result_v6 ct_result;
+
+	/* Result of the NAT calculation.  Zeroed if there is no DNAT. */
+	struct calico_nat_dest_v6 nat_dest;
+	__u64 prog_start_time;
+};
+
+struct cali_tc_ctx_v6 {
+  struct __sk_buff *skb;
+
+  /* Our single copies of the data start/end pointers loaded from the skb. */
+  union {
+	void *data_start;
+	struct ethhdr *eth; /* If there is an ethhdr it's at the start. */
+  };
+  void *data_end;
+
+  struct cali_tc_state_v6 *state;
+
+  struct ipv6hdr *ip_header;
+  union {
+    void *nh;
+    struct tcphdr *tcp_header;
+    struct udphdr *udp_header;
+    struct icmp6hdr *icmp_header;
+  };
+
+  struct calico_nat_dest_v6 *nat_dest;
+  struct fwd fwd;
+};
+
+// Map: IPv6 neighbours.  The IPv6 equivalent of cali_v4_arp.
+
+struct arp_v6_key {
+	ipv6_addr_t ip;
+	__u32 ifindex;
+};
+
+CALI_MAP_V1(cali_v6_arp, BPF_MAP_TYPE_LRU_HASH, struct arp_v6_key, struct arp_value, 10000, 0, MAP_PIN_GLOBAL)
+
+#endif /* __CALI_BPF_TYPES_V6_H__ */
@@ -38,6 +38,20 @@ func Map(mc *bpf.MapContext) bpf.Map {
 	return mc.NewPinnedMap(MapParams)
 }
 
+var MapV6Params = bpf.MapParameters{
+	Filename:   "/sys/fs/bpf/tc/globals/cali_v6_arp",
+	Type:       "lru_hash",
+	KeySize:    KeyV6Size,
+	ValueSize:  ValueSize,
+	MaxEntries: 10000,
+	Name:       "cali_v6_arp",
+}
+
+// MapV6 returns the IPv6 neighbour map.  It shares its value format with the IPv4 map.
+func MapV6(mc *bpf.MapContext) bpf.Map {
+	return mc.NewPinnedMap(MapV6Params)
+}
+
 const KeySize = 8
 
 type Key [KeySize]byte
@@ -68,6 +82,35 @@ func (k Key) String() string {
 	return fmt.Sprintf("ip %s ifindex %d", k.IP(), k.IfIndex())
 }
 
+const KeyV6Size = 20
+
+type KeyV6 [KeyV6Size]byte
+
+func NewKeyV6(ip net.IP, ifIndex uint32) KeyV6 {
+	var k KeyV6
+
+	if ip.To4() != nil || len(ip) != 16 {
+		log.WithField("ip", ip).Panic("Bad IPv6")
+	}
+
+	copy(k[:16], ip)
+	binary.LittleEndian.PutUint32(k[16:20], ifIndex)
+
+	return k
+}
+
+func (k KeyV6) IP() net.IP {
+	return net.IP(k[:16])
+}
+
+func (k KeyV6) IfIndex() uint32 {
+	return binary.LittleEndian.Uint32(k[16:20])
+}
+
+func (k KeyV6) String() string {
+	return fmt.Sprintf("ip %s ifindex %d", k.IP(), k.IfIndex())
+}
+
 const ValueSize = 12
 
 type Value [ValueSize]byte
@@ -120,6 +120,9 @@ const (
 	StoreReg32 OpCode = OpClassStoreReg | MemOpModeMem | MemOpSize32
 	StoreReg64 OpCode = OpClassStoreReg | MemOpModeMem | MemOpSize64
 
+	// AtomicAdd64 atomically adds the src register to the 64-bit value at dst+offset.
+	AtomicAdd64 OpCode = OpClassStoreReg | MemOpModeXADD | MemOpSize64
+
 	// TODO: check these opcodes, should they be OpClassStoreMem with an immediate source instead?
 	StoreImm8  OpCode = OpClassStoreImm | MemOpModeImm | MemOpSize8
 	StoreImm16 OpCode = OpClassStoreImm | MemOpModeImm | MemOpSize16
@@ -402,6 +405,11 @@ func (b *Block) Store64(dst Reg, ptrReg Reg, offset int16) {
 	b.add(StoreReg64, dst, ptrReg, offset, 0)
 }
 
+// AtomicAdd64 atomically adds the value in the src register to the 64-bit value at dst+offset.
+func (b *Block) AtomicAdd64(dst Reg, src Reg, offset int16) {
+	b.add(AtomicAdd64, dst, src, offset, 0)
+}
+
 func (b *Block) LoadStack8(dst Reg, offset int16) {
 	b.Load8(dst, R10, offset)
 }
@@ -502,6 +510,10 @@ func (b *Block) JumpEqImm32(ra Reg, imm int32, label string) {
 	b.addWithOffsetFixup(JumpEqImm32, ra, 0, label, imm)
 }
 
+func (b *Block) JumpNEImm32(ra Reg, imm int32, label string) {
+	b.addWithOffsetFixup(JumpNEImm32, ra, 0, label, imm)
+}
+
 func (b *Block) JumpLE32(ra, rb Reg, label string) {
 	b.addWithOffsetFixup(JumpLE32, ra, rb, label, 0)
 }
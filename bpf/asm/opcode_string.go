@@ -12,6 +12,7 @@ func _() {
 	_ = x[StoreReg16-107]
 	_ = x[StoreReg32-99]
 	_ = x[StoreReg64-123]
+	_ = x[AtomicAdd64-219]
 	_ = x[StoreImm8-18]
 	_ = x[StoreImm16-10]
 	_ = x[StoreImm32-2]
@@ -125,7 +126,7 @@ func _() {
 	_ = x[EndianImm32-212]
 }
 
-const _OpCode_name = "LoadImm64Pt2StoreImm32AddImm32JumpAAddImm64StoreImm16Add32Add64StoreImm8SubImm32JumpEqImm64JumpEqImm32SubImm64LoadImm64StoreImm64Sub32JumpEq64JumpEq32Sub64MulImm32JumpGTImm64JumpGTImm32MulImm64Mul32JumpGT64JumpGT32Mul64DivImm32JumpGEImm64JumpGEImm32DivImm64Div32JumpGE64JumpGE32Div64OrImm32JumpSetImm64JumpSetImm32OrImm64Or32JumpSet64JumpSet32Or64AndImm32JumpNEImm64JumpNEImm32AndImm64And32JumpNE64JumpNE32And64LoadReg32StoreReg32ShiftLImm32JumpSGTImm64JumpSGTImm32ShiftLImm64LoadReg16StoreReg16ShiftL32JumpSGT64JumpSGT32ShiftL64LoadReg8StoreReg8ShiftRImm32JumpSGEImm64JumpSGEImm32ShiftRImm64LoadReg64StoreReg64ShiftR32JumpSGE64JumpSGE32ShiftR64CallNegate32Negate64ModImm32ExitModImm64Mod32Mod64XORImm32JumpLTImm64JumpLTImm32XORImm64XOR32JumpLT64JumpLT32XOR64MovImm32JumpLEImm64JumpLEImm32MovImm64Mov32JumpLE64JumpLE32Mov64AShiftRImm32JumpSLTImm64JumpSLTImm32AShiftRImm64AShiftR32JumpSLT64JumpSLT32AShiftR64EndianImm32JumpSLEImm64JumpSLEImm32EndianImm64Endian32JumpSLE64JumpSLE32Endian64"
+const _OpCode_name = "LoadImm64Pt2StoreImm32AddImm32JumpAAddImm64StoreImm16Add32Add64StoreImm8SubImm32JumpEqImm64JumpEqImm32SubImm64LoadImm64StoreImm64Sub32JumpEq64JumpEq32Sub64MulImm32JumpGTImm64JumpGTImm32MulImm64Mul32JumpGT64JumpGT32Mul64DivImm32JumpGEImm64JumpGEImm32DivImm64Div32JumpGE64JumpGE32Div64OrImm32JumpSetImm64JumpSetImm32OrImm64Or32JumpSet64JumpSet32Or64AndImm32JumpNEImm64JumpNEImm32AndImm64And32JumpNE64JumpNE32And64LoadReg32StoreReg32ShiftLImm32JumpSGTImm64JumpSGTImm32ShiftLImm64LoadReg16StoreReg16ShiftL32JumpSGT64JumpSGT32ShiftL64LoadReg8StoreReg8ShiftRImm32JumpSGEImm64JumpSGEImm32ShiftRImm64LoadReg64StoreReg64ShiftR32JumpSGE64JumpSGE32ShiftR64CallNegate32Negate64ModImm32ExitModImm64Mod32Mod64XORImm32JumpLTImm64JumpLTImm32XORImm64XOR32JumpLT64JumpLT32XOR64MovImm32JumpLEImm64JumpLEImm32MovImm64Mov32JumpLE64JumpLE32Mov64AShiftRImm32JumpSLTImm64JumpSLTImm32AShiftRImm64AShiftR32JumpSLT64JumpSLT32AShiftR64EndianImm32JumpSLEImm64JumpSLEImm32EndianImm64AtomicAdd64Endian32JumpSLE64JumpSLE32Endian64"
 
 var _OpCode_map = map[OpCode]string{
 	0:   _OpCode_name[0:12],
@@ -239,10 +240,11 @@ var _OpCode_map = map[OpCode]string{
 	213: _OpCode_name[918:930],
 	214: _OpCode_name[930:942],
 	215: _OpCode_name[942:953],
-	220: _OpCode_name[953:961],
-	221: _OpCode_name[961:970],
-	222: _OpCode_name[970:979],
-	223: _OpCode_name[979:987],
+	219: _OpCode_name[953:964],
+	220: _OpCode_name[964:972],
+	221: _OpCode_name[972:981],
+	222: _OpCode_name[981:990],
+	223: _OpCode_name[990:998],
 }
 
 func (i OpCode) String() string {
@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/projectcalico/felix/bpf"
+)
+
+// The audit map holds a packet counter for each rule of each audit-only policy.  The policy
+// program increments the counter for the first rule that matches a packet in place of taking the
+// rule's action.
+//
+// WARNING: must be kept in sync with the definitions in bpf/polprog/pol_prog_builder.go.
+// uint64 counter ID HE  8
+const KeySize = 8
+
+// uint64 packet count HE  8
+const ValueSize = 8
+
+func Map(mc *bpf.MapContext) bpf.Map {
+	return mc.NewPinnedMap(bpf.MapParameters{
+		Filename:   "/sys/fs/bpf/tc/globals/cali_v4_audit",
+		Type:       "hash",
+		KeySize:    KeySize,
+		ValueSize:  ValueSize,
+		MaxEntries: 64 * 1024,
+		Name:       "cali_v4_audit",
+		Flags:      unix.BPF_F_NO_PREALLOC,
+	})
+}
+
+// RuleCounterID returns the ID of the counter for the rule with the given ID (as calculated by
+// the calculation graph, which takes the policy name and direction into account).
+func RuleCounterID(ruleID string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(ruleID))
+	return h.Sum64()
+}
+
+// ReadCounters returns the current value of each counter in the map, indexed by counter ID.
+func ReadCounters(m bpf.Map) (map[uint64]uint64, error) {
+	counters := map[uint64]uint64{}
+	err := m.Iter(func(k, v []byte) bpf.IteratorAction {
+		counters[binary.LittleEndian.Uint64(k)] = binary.LittleEndian.Uint64(v)
+		return bpf.IterNone
+	})
+	return counters, err
+}
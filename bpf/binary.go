@@ -139,6 +139,17 @@ func (b *Binary) PatchExtToServiceConnmark(mark uint32) {
 	b.patchU32Placeholder("MARK", uint32(mark))
 }
 
+// PatchIPv6Enabled replaces the IPV6 placeholder with 1 if the IPv6 programs are attached
+// alongside the IPv4 ones, or 0 otherwise.
+func (b *Binary) PatchIPv6Enabled(enabled bool) {
+	logrus.WithField("enabled", enabled).Debug("Patching IPv6 enabled")
+	var v uint32
+	if enabled {
+		v = 1
+	}
+	b.patchU32Placeholder("IPV6", v)
+}
+
 // patchU32Placeholder replaces a placeholder with the given value.
 func (b *Binary) patchU32Placeholder(from string, to uint32) {
 	toBytes := make([]byte, 4)
@@ -86,6 +86,19 @@ func WithTimeShim(shim timeshim.Interface) LivenessScannerOpt {
 }
 
 func (l *LivenessScanner) Check(ctKey Key, ctVal Value, get EntryGet) ScanVerdict {
+	return l.check(ctKey.Proto(), ctVal, func() (EntryValue, error) {
+		return get(ctVal.ReverseNATKey())
+	})
+}
+
+// CheckV6 is the IPv6 equivalent of Check.
+func (l *LivenessScanner) CheckV6(ctKey KeyV6, ctVal ValueV6, get EntryGetV6) ScanVerdict {
+	return l.check(ctKey.Proto(), ctVal, func() (EntryValue, error) {
+		return get(ctVal.ReverseNATKey())
+	})
+}
+
+func (l *LivenessScanner) check(proto uint8, ctVal EntryValue, getRev func() (EntryValue, error)) ScanVerdict {
 	if l.cachedKTime == 0 || l.time.Since(l.goTimeOfLastKTimeLookup) > time.Second {
 		l.cachedKTime = l.time.KTimeNanos()
 		l.goTimeOfLastKTimeLookup = l.time.Now()
@@ -97,7 +110,7 @@ func (l *LivenessScanner) Check(ctKey Key, ctVal Value, get EntryGet) ScanVerdic
 	switch ctVal.Type() {
 	case TypeNATForward:
 		// Look up the reverse entry, where we do the book-keeping.
-		revEntry, err := get(ctVal.ReverseNATKey())
+		revEntry, err := getRev()
 		if err != nil && bpf.IsNotExists(err) {
 			// Forward entry exists but no reverse entry. We might have come across the reverse
 			// entry first and removed it. It is useless on its own, so delete it now.
@@ -112,7 +125,7 @@ func (l *LivenessScanner) Check(ctKey Key, ctVal Value, get EntryGet) ScanVerdic
 			log.WithError(err).Warn("Failed to look up conntrack entry.")
 			return ScanVerdictOK
 		}
-		if reason, expired := l.timeouts.EntryExpired(now, ctKey.Proto(), revEntry); expired {
+		if reason, expired := l.timeouts.EntryExpired(now, proto, revEntry); expired {
 			if debug {
 				log.WithField("reason", reason).Debug("Deleting expired conntrack forward-NAT entry")
 			}
@@ -122,14 +135,14 @@ func (l *LivenessScanner) Check(ctKey Key, ctVal Value, get EntryGet) ScanVerdic
 			// it once we come across it again.
 		}
 	case TypeNATReverse:
-		if reason, expired := l.timeouts.EntryExpired(now, ctKey.Proto(), ctVal); expired {
+		if reason, expired := l.timeouts.EntryExpired(now, proto, ctVal); expired {
 			if debug {
 				log.WithField("reason", reason).Debug("Deleting expired conntrack reverse-NAT entry")
 			}
 			return ScanVerdictDelete
 		}
 	case TypeNormal:
-		if reason, expired := l.timeouts.EntryExpired(now, ctKey.Proto(), ctVal); expired {
+		if reason, expired := l.timeouts.EntryExpired(now, proto, ctVal); expired {
 			if debug {
 				log.WithField("reason", reason).Debug("Deleting expired normal conntrack entry")
 			}
@@ -142,9 +155,18 @@ func (l *LivenessScanner) Check(ctKey Key, ctVal Value, get EntryGet) ScanVerdic
 	return ScanVerdictOK
 }
 
+// EntryValue is the part of a conntrack entry that is common to the IPv4 and IPv6 maps.
+type EntryValue interface {
+	Type() uint8
+	Created() int64
+	LastSeen() int64
+	IsForwardDSR() bool
+	Data() EntryData
+}
+
 // EntryExpired checks whether a given conntrack table entry for a given
 // protocol and time, is expired.
-func (t *Timeouts) EntryExpired(nowNanos int64, proto uint8, entry Value) (reason string, expired bool) {
+func (t *Timeouts) EntryExpired(nowNanos int64, proto uint8, entry EntryValue) (reason string, expired bool) {
 	sinceCreation := time.Duration(nowNanos - entry.Created())
 	if sinceCreation < t.CreationGracePeriod {
 		log.Debug("Conntrack entry in creation grace period. Ignoring.")
@@ -174,7 +196,7 @@ func (t *Timeouts) EntryExpired(nowNanos int64, proto uint8, entry Value) (reaso
 			}
 		}
 		return "", false
-	case ProtoICMP:
+	case ProtoICMP, ProtoICMPv6:
 		if age > t.ICMPLastSeen {
 			return "no traffic on ICMP flow for too long", true
 		}
@@ -211,11 +233,29 @@ func NewStaleNATScanner(frontendHasBackend NATChecker) *StaleNATScanner {
 	}
 }
 
+// entryKey is the part of a conntrack key that is common to the IPv4 and IPv6 maps.
+type entryKey interface {
+	Proto() uint8
+	AddrA() net.IP
+	PortA() uint16
+	AddrB() net.IP
+	PortB() uint16
+}
+
 // Check checks the conntrack entry
 func (sns *StaleNATScanner) Check(k Key, v Value, _ EntryGet) ScanVerdict {
+	return sns.check(k, v.Type(), v.OrigIP(), v.OrigPort(), v.ReverseNATKey())
+}
+
+// CheckV6 checks the IPv6 conntrack entry
+func (sns *StaleNATScanner) CheckV6(k KeyV6, v ValueV6, _ EntryGetV6) ScanVerdict {
+	return sns.check(k, v.Type(), v.OrigIP(), v.OrigPort(), v.ReverseNATKey())
+}
+
+func (sns *StaleNATScanner) check(k entryKey, typ uint8, origIP net.IP, origPort uint16, revKey entryKey) ScanVerdict {
 	debug := log.GetLevel() >= log.DebugLevel
 
-	switch v.Type() {
+	switch typ {
 	case TypeNormal:
 		// skip non-NAT entry
 
@@ -227,8 +267,8 @@ func (sns *StaleNATScanner) Check(k Key, v Value, _ EntryGet) ScanVerdict {
 		portA := k.PortA()
 		portB := k.PortB()
 
-		svcIP := v.OrigIP()
-		svcPort := v.OrigPort()
+		svcIP := origIP
+		svcPort := origPort
 
 		// We cannot tell which leg is EP and which is the client, we must
 		// try both. If there is a record for one of them, it is still most
@@ -250,7 +290,6 @@ func (sns *StaleNATScanner) Check(k Key, v Value, _ EntryGet) ScanVerdict {
 		kAport := k.PortA()
 		kB := k.AddrB()
 		kBport := k.PortB()
-		revKey := v.ReverseNATKey()
 		revA := revKey.AddrA()
 		revAport := revKey.PortA()
 		revB := revKey.AddrB()
@@ -285,7 +324,7 @@ func (sns *StaleNATScanner) Check(k Key, v Value, _ EntryGet) ScanVerdict {
 			svcIP = kA
 			svcPort = kAport
 		} else {
-			log.WithFields(log.Fields{"key": k, "value": v}).Error("Mismatch between key and rev key")
+			log.WithFields(log.Fields{"key": k, "revKey": revKey}).Error("Mismatch between key and rev key")
 			return ScanVerdictOK // don't touch, will get deleted when expired
 		}
 
@@ -300,7 +339,7 @@ func (sns *StaleNATScanner) Check(k Key, v Value, _ EntryGet) ScanVerdict {
 		}
 
 	default:
-		log.WithField("conntrack.Value.Type()", v.Type()).Warn("Unknown type")
+		log.WithField("conntrack.Value.Type()", typ).Warn("Unknown type")
 	}
 
 	return ScanVerdictOK
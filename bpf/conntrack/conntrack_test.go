@@ -154,6 +154,67 @@ var _ = Describe("BPF Conntrack LivenessCalculator", func() {
 	)
 })
 
+var _ = Describe("BPF IPv6 Conntrack LivenessCalculator", func() {
+	var scanner *conntrack.ScannerV6
+	var ctMap *mock.Map
+	var mockTime *mocktime.MockTime
+
+	ip6a := net.ParseIP("dead:beef::1")
+	ip6b := net.ParseIP("dead:beef::2")
+
+	BeforeEach(func() {
+		mockTime = mocktime.New()
+		ctMap = mock.NewMockMap(conntrack.MapParamsV6)
+		lc := conntrack.NewLivenessScanner(timeouts, false, conntrack.WithTimeShim(mockTime))
+		scanner = conntrack.NewScannerV6(ctMap, lc)
+	})
+
+	DescribeTable(
+		"expiry tests",
+		func(proto uint8, entry conntrack.Value, expExpired bool) {
+			key := conntrack.NewKeyV6(proto, ip6a, 1234, ip6b, 3456)
+			data := entry.Data()
+			value := conntrack.NewValueV6Normal(time.Duration(entry.Created()), time.Duration(entry.LastSeen()),
+				0, data.A2B, data.B2A)
+
+			err := ctMap.Update(key.AsBytes(), value.AsBytes())
+			Expect(err).NotTo(HaveOccurred())
+
+			scanner.Scan()
+			_, err = ctMap.Get(key.AsBytes())
+			if expExpired {
+				Expect(bpf.IsNotExists(err)).To(BeTrue(), "Scan() should have cleaned up entry")
+			} else {
+				Expect(err).NotTo(HaveOccurred(), "Scan() deleted entry unexpectedly")
+			}
+		},
+		Entry("TCP established", uint8(conntrack.ProtoTCP), tcpEstablished, false),
+		Entry("TCP established timed out", uint8(conntrack.ProtoTCP), tcpEstablishedTimeout, true),
+		Entry("UDP almost timed out", uint8(conntrack.ProtoUDP), udpAlmostTimedOut, false),
+		Entry("UDP timed out", uint8(conntrack.ProtoUDP), udpTimedOut, true),
+		Entry("ICMPv6 almost timed out", uint8(conntrack.ProtoICMPv6), icmpAlmostTimedOut, false),
+		Entry("ICMPv6 timed out", uint8(conntrack.ProtoICMPv6), icmpTimedOut, true),
+	)
+
+	It("should clean up a forward NAT entry along with its reverse entry", func() {
+		revKey := conntrack.NewKeyV6(conntrack.ProtoUDP, ip6a, 1234, ip6b, 3456)
+		fwdKey := conntrack.NewKeyV6(conntrack.ProtoUDP, ip6a, 1234, net.ParseIP("cafe::1"), 80)
+		rev := conntrack.NewValueV6NATReverse(now-(2*time.Minute), now-(61*time.Second), 0,
+			conntrack.Leg{Whitelisted: true}, conntrack.Leg{}, net.ParseIP("cafe::1"), 80)
+		fwd := conntrack.NewValueV6NATForward(now-(2*time.Minute), now-(61*time.Second), 0, revKey)
+		Expect(ctMap.Update(revKey.AsBytes(), rev.AsBytes())).To(Succeed())
+		Expect(ctMap.Update(fwdKey.AsBytes(), fwd.AsBytes())).To(Succeed())
+
+		scanner.Scan()
+		scanner.Scan()
+
+		_, err := ctMap.Get(revKey.AsBytes())
+		Expect(bpf.IsNotExists(err)).To(BeTrue())
+		_, err = ctMap.Get(fwdKey.AsBytes())
+		Expect(bpf.IsNotExists(err)).To(BeTrue())
+	})
+})
+
 type dummyNATChecker struct {
 	check func(fIP net.IP, fPort uint16, bIP net.IP, bPort uint16, proto uint8) bool
 }
@@ -391,9 +391,10 @@ func Map(mc *bpf.MapContext) bpf.Map {
 }
 
 const (
-	ProtoICMP = 1
-	ProtoTCP  = 6
-	ProtoUDP  = 17
+	ProtoICMP   = 1
+	ProtoTCP    = 6
+	ProtoUDP    = 17
+	ProtoICMPv6 = 58
 )
 
 func KeyFromBytes(k []byte) Key {
@@ -0,0 +1,162 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conntrack
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/bpf"
+	"github.com/projectcalico/felix/jitter"
+)
+
+// EntryGetV6 is the IPv6 equivalent of EntryGet.
+type EntryGetV6 func(KeyV6) (ValueV6, error)
+
+// EntryScannerV6 is the IPv6 equivalent of EntryScanner.
+type EntryScannerV6 interface {
+	CheckV6(KeyV6, ValueV6, EntryGetV6) ScanVerdict
+}
+
+// ScannerV6 iterates over the IPv6 conntrack map in the same way that Scanner
+// iterates over the IPv4 map.
+type ScannerV6 struct {
+	ctMap    bpf.Map
+	scanners []EntryScannerV6
+
+	wg       sync.WaitGroup
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewScannerV6 returns a scanner for the given IPv6 conntrack map and the set of
+// EntryScannerV6. They are executed in the provided order on each entry.
+func NewScannerV6(ctMap bpf.Map, scanners ...EntryScannerV6) *ScannerV6 {
+	return &ScannerV6{
+		ctMap:    ctMap,
+		scanners: scanners,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Scan executes a scanning iteration
+func (s *ScannerV6) Scan() {
+	s.iterStart()
+	defer s.iterEnd()
+
+	debug := log.GetLevel() >= log.DebugLevel
+
+	var ctKey KeyV6
+	var ctVal ValueV6
+
+	err := s.ctMap.Iter(func(k, v []byte) bpf.IteratorAction {
+		copy(ctKey[:], k[:])
+		copy(ctVal[:], v[:])
+
+		if debug {
+			log.WithFields(log.Fields{
+				"key":   ctKey,
+				"entry": ctVal,
+			}).Debug("Examining IPv6 conntrack entry")
+		}
+
+		for _, scanner := range s.scanners {
+			if verdict := scanner.CheckV6(ctKey, ctVal, s.get); verdict == ScanVerdictDelete {
+				if debug {
+					log.Debug("Deleting IPv6 conntrack entry.")
+				}
+				return bpf.IterDelete
+			}
+		}
+		return bpf.IterNone
+	})
+
+	if err != nil {
+		log.WithError(err).Warn("Failed to iterate over IPv6 conntrack map")
+	}
+}
+
+func (s *ScannerV6) get(k KeyV6) (ValueV6, error) {
+	v, err := s.ctMap.Get(k.AsBytes())
+
+	if err != nil {
+		return ValueV6{}, err
+	}
+
+	return ValueV6FromBytes(v), nil
+}
+
+// iterationSynced is implemented by the scanners that need to know when an iteration
+// starts and ends, see EntryScannerSynced.
+type iterationSynced interface {
+	IterationStart()
+	IterationEnd()
+}
+
+func (s *ScannerV6) iterStart() {
+	for _, scanner := range s.scanners {
+		if synced, ok := scanner.(iterationSynced); ok {
+			synced.IterationStart()
+		}
+	}
+}
+
+func (s *ScannerV6) iterEnd() {
+	for i := len(s.scanners) - 1; i >= 0; i-- {
+		if synced, ok := s.scanners[i].(iterationSynced); ok {
+			synced.IterationEnd()
+		}
+	}
+}
+
+// AddUnlocked adds an additional EntryScannerV6 to a non-running ScannerV6
+func (s *ScannerV6) AddUnlocked(scanner EntryScannerV6) {
+	s.scanners = append(s.scanners, scanner)
+}
+
+// Start the periodic scanner
+func (s *ScannerV6) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		log.Debug("IPv6 conntrack scanner thread started")
+		defer log.Debug("IPv6 conntrack scanner thread stopped")
+
+		ticker := jitter.NewTicker(ScanPeriod, 100*time.Millisecond)
+
+		for {
+			s.Scan()
+
+			select {
+			case <-ticker.C:
+				log.Debug("IPv6 conntrack cleanup timer popped")
+			case <-s.stopCh:
+				log.Debug("IPv6 conntrack cleanup got stop signal")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the ScannerV6 and waits for it finishing.
+func (s *ScannerV6) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+		s.wg.Wait()
+	})
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"fmt"
+
+	"github.com/projectcalico/felix/dryrun"
+)
+
+// dryRunMap wraps a map for observe-only mode.  Reads go to the underlying map but updates and
+// deletions, including deletions requested by Iter callbacks, are passed to the recorder instead.
+// The map itself is still created if it doesn't exist so that its users can read it.
+type dryRunMap struct {
+	Map
+	recorder *dryrun.Recorder
+}
+
+func (m *dryRunMap) component() string {
+	return "bpf-map-" + m.GetName()
+}
+
+func (m *dryRunMap) Update(k, v []byte) error {
+	m.recorder.Record(m.component(), fmt.Sprintf("update key %x value %x", k, v))
+	return nil
+}
+
+func (m *dryRunMap) Delete(k []byte) error {
+	m.recorder.Record(m.component(), fmt.Sprintf("delete key %x", k))
+	return nil
+}
+
+func (m *dryRunMap) Iter(f IterCallback) error {
+	return m.Map.Iter(func(k, v []byte) IteratorAction {
+		if f(k, v) == IterDelete {
+			m.recorder.Record(m.component(), fmt.Sprintf("delete key %x", k))
+		}
+		return IterNone
+	})
+}
@@ -16,6 +16,7 @@ package ipsets
 
 import (
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -98,6 +99,7 @@ func (m *bpfIPSets) getOrCreateIPSet(setID string) *bpfIPSet {
 	ipSet := m.ipSets[id]
 	if ipSet == nil {
 		ipSet = &bpfIPSet{
+			Family:         m.IPVersionConfig.Family,
 			ID:             id,
 			OriginalID:     setID,
 			DesiredEntries: set.New(),
@@ -167,9 +169,9 @@ func (m *bpfIPSets) AddMembers(setID string, newMembers []string) {
 		"added":    len(newMembers),
 	}).Info("IP delta update (adding)")
 	for _, member := range newMembers {
-		entry := ProtoIPSetMemberToBPFEntry(ipSet.ID, member)
+		entry := memberToEntry(ipSet.Family, ipSet.ID, member)
 		if entry != nil {
-			ipSet.AddMember(*entry)
+			ipSet.AddMember(entry)
 		}
 	}
 	m.markIPSetDirty(ipSet)
@@ -193,9 +195,9 @@ func (m *bpfIPSets) RemoveMembers(setID string, removedMembers []string) {
 		"removed":  len(removedMembers),
 	}).Info("IP delta update (removing)")
 	for _, member := range removedMembers {
-		entry := ProtoIPSetMemberToBPFEntry(ipSet.ID, member)
+		entry := memberToEntry(ipSet.Family, ipSet.ID, member)
 		if entry != nil {
-			ipSet.RemoveMember(*entry)
+			ipSet.RemoveMember(entry)
 		}
 	}
 	m.markIPSetDirty(ipSet)
@@ -249,10 +251,9 @@ func (m *bpfIPSets) ApplyUpdates() {
 			ipSet.PendingRemoves.Clear()
 		}
 
-		var unknownEntries []IPSetEntry
+		var unknownEntries []ipSetEntry
 		err := m.bpfMap.Iter(func(k, v []byte) bpf.IteratorAction {
-			var entry IPSetEntry
-			copy(entry[:], k)
+			entry := entryFromBytes(m.IPVersionConfig.Family, k)
 			setID := entry.SetID()
 			if debug {
 				log.WithFields(log.Fields{"setID": setID,
@@ -279,7 +280,7 @@ func (m *bpfIPSets) ApplyUpdates() {
 		}
 
 		for _, entry := range unknownEntries {
-			err := m.bpfMap.Delete(entry[:])
+			err := m.bpfMap.Delete(entry.AsBytes())
 			if err != nil {
 				log.WithError(err).WithField("key", entry).Error("Failed to remove unexpected IP set entry")
 				m.resyncScheduled = true
@@ -304,11 +305,11 @@ func (m *bpfIPSets) ApplyUpdates() {
 		}
 
 		ipSet.PendingRemoves.Iter(func(item interface{}) error {
-			entry := item.(IPSetEntry)
+			entry := item.(ipSetEntry)
 			if debug {
 				log.WithFields(log.Fields{"setID": setID, "entry": entry}).Debug("Removing entry from IP set")
 			}
-			err := m.bpfMap.Delete(entry[:])
+			err := m.bpfMap.Delete(entry.AsBytes())
 			if err != nil {
 				log.WithFields(log.Fields{"setID": setID, "entry": entry}).WithError(err).Error("Failed to remove IP set entry")
 				leaveDirty = true
@@ -319,11 +320,11 @@ func (m *bpfIPSets) ApplyUpdates() {
 		})
 
 		ipSet.PendingAdds.Iter(func(item interface{}) error {
-			entry := item.(IPSetEntry)
+			entry := item.(ipSetEntry)
 			if debug {
 				log.WithFields(log.Fields{"setID": setID, "entry": entry}).Debug("Adding entry to IP set")
 			}
-			err := m.bpfMap.Update(entry[:], DummyValue)
+			err := m.bpfMap.Update(entry.AsBytes(), DummyValue)
 			if err != nil {
 				log.WithFields(log.Fields{"setID": setID, "entry": entry}).WithError(err).Error("Failed to add IP set entry")
 				leaveDirty = true
@@ -370,17 +371,53 @@ func (m *bpfIPSets) markIPSetDirty(data *bpfIPSet) {
 	m.dirtyIPSetIDs.Add(data.ID)
 }
 
+// ipSetEntry is implemented by IPSetEntry and IPSetEntryV6.  Both are arrays so they can be stored
+// directly in a set.Set.
+type ipSetEntry interface {
+	SetID() uint64
+	Addr() net.IP
+	PrefixLen() uint32
+	AsBytes() []byte
+}
+
+// memberToEntry converts an IP set member to an entry of the given family; returns nil if the member
+// is of the wrong IP version.
+func memberToEntry(family ipsets.IPFamily, id uint64, member string) ipSetEntry {
+	if family == ipsets.IPFamilyV6 {
+		if entry := ProtoIPSetMemberToBPFEntryV6(id, member); entry != nil {
+			return *entry
+		}
+		return nil
+	}
+	if entry := ProtoIPSetMemberToBPFEntry(id, member); entry != nil {
+		return *entry
+	}
+	return nil
+}
+
+func entryFromBytes(family ipsets.IPFamily, k []byte) ipSetEntry {
+	if family == ipsets.IPFamilyV6 {
+		var entry IPSetEntryV6
+		copy(entry[:], k)
+		return entry
+	}
+	var entry IPSetEntry
+	copy(entry[:], k)
+	return entry
+}
+
 type bpfIPSet struct {
 	OriginalID string
 	ID         uint64
+	Family     ipsets.IPFamily
 
 	// DesiredEntries contains all the entries that we _want_ to be in the set.
-	DesiredEntries set.Set /* of IPSetEntry */
+	DesiredEntries set.Set /* of ipSetEntry */
 	// PendingAdds contains all the entries that we need to add to bring the dataplane into sync with DesiredEntries.
-	PendingAdds set.Set /* of IPSetEntry */
+	PendingAdds set.Set /* of ipSetEntry */
 	// PendingRemoves contains all the entries that we need to remove from the dataplane to bring the
 	// dataplane into sync with DesiredEntries.
-	PendingRemoves set.Set /* of IPSetEntry */
+	PendingRemoves set.Set /* of ipSetEntry */
 
 	Deleted bool
 
@@ -394,7 +431,7 @@ func (m *bpfIPSet) ReplaceMembers(members []string) {
 
 func (m *bpfIPSet) RemoveAll() {
 	m.DesiredEntries.Iter(func(item interface{}) error {
-		entry := item.(IPSetEntry)
+		entry := item.(ipSetEntry)
 		m.RemoveMember(entry)
 		return nil
 	})
@@ -402,15 +439,15 @@ func (m *bpfIPSet) RemoveAll() {
 
 func (m *bpfIPSet) AddMembers(members []string) {
 	for _, member := range members {
-		entry := ProtoIPSetMemberToBPFEntry(m.ID, member)
+		entry := memberToEntry(m.Family, m.ID, member)
 		if entry != nil {
-			m.AddMember(*entry)
+			m.AddMember(entry)
 		}
 	}
 }
 
 // AddMember adds a member to the set of desired entries. Idempotent, if the member is already present, makes no change.
-func (m *bpfIPSet) AddMember(entry IPSetEntry) {
+func (m *bpfIPSet) AddMember(entry ipSetEntry) {
 	if m.DesiredEntries.Contains(entry) {
 		return
 	}
@@ -424,7 +461,7 @@ func (m *bpfIPSet) AddMember(entry IPSetEntry) {
 
 // RemoveMember removes a member from the set of desired entries. Idempotent, if the member is no present, makes no
 // change.
-func (m *bpfIPSet) RemoveMember(entry IPSetEntry) {
+func (m *bpfIPSet) RemoveMember(entry ipSetEntry) {
 	if !m.DesiredEntries.Contains(entry) {
 		return
 	}
@@ -72,6 +72,10 @@ func (e IPSetEntry) Port() uint16 {
 	return binary.LittleEndian.Uint16(e[16:18])
 }
 
+func (e IPSetEntry) AsBytes() []byte {
+	return e[:]
+}
+
 func MakeBPFIPSetEntry(setID uint64, cidr ip.V4CIDR, port uint16, proto uint8) *IPSetEntry {
 	var entry IPSetEntry
 	// TODO Detect endianness
@@ -25,6 +25,8 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/dryrun"
 )
 
 type IteratorAction string
@@ -82,6 +84,9 @@ func (mp *MapParameters) versionedFilename() string {
 
 type MapContext struct {
 	RepinningEnabled bool
+	// DryRun, if non-nil, makes the maps pass their updates and deletions to the recorder
+	// instead of writing them.
+	DryRun *dryrun.Recorder
 }
 
 func (c *MapContext) NewPinnedMap(params MapParameters) Map {
@@ -93,6 +98,9 @@ func (c *MapContext) NewPinnedMap(params MapParameters) Map {
 		MapParameters: params,
 		perCPU:        strings.Contains(params.Type, "percpu"),
 	}
+	if c.DryRun != nil {
+		return &dryRunMap{Map: m, recorder: c.DryRun}
+	}
 	return m
 }
 
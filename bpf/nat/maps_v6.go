@@ -0,0 +1,222 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/bpf"
+	"github.com/projectcalico/felix/ip"
+)
+
+// struct calico_nat_v6_key {
+//    uint32_t prefixLen;
+//    ipv6_addr_t addr; // NBO
+//    uint16_t port; // HBO
+//    uint8_t protocol;
+//    ipv6_addr_t saddr;
+//    uint8_t pad;
+// };
+const frontendKeyV6Size = 40
+
+// The IPv6 frontend map shares its value and the backend key with the IPv4 maps.
+//
+// struct calico_nat_dest_v6 {
+//    ipv6_addr_t addr;
+//    uint16_t port;
+//    uint8_t pad[2];
+// };
+const backendValueV6Size = 20
+
+//(sizeof(addr) + sizeof(port) + sizeof(proto)) in bits
+const ZeroCIDRV6PrefixLen = 152
+
+var ZeroCIDRV6 = ip.MustParseCIDROrIP("::/0").(ip.V6CIDR)
+
+type FrontendKeyV6 [frontendKeyV6Size]byte
+
+func NewNATKeyV6(addr net.IP, port uint16, protocol uint8) FrontendKeyV6 {
+	return NewNATKeyV6Src(addr, port, protocol, ZeroCIDRV6)
+}
+
+func NewNATKeyV6Src(addr net.IP, port uint16, protocol uint8, cidr ip.V6CIDR) FrontendKeyV6 {
+	var k FrontendKeyV6
+	if addr.To4() != nil || len(addr) != 16 {
+		log.WithField("ip", addr).Panic("Bad IPv6")
+	}
+	binary.LittleEndian.PutUint32(k[:4], uint32(ZeroCIDRV6PrefixLen)+uint32(cidr.Prefix()))
+	copy(k[4:20], addr)
+	binary.LittleEndian.PutUint16(k[20:22], port)
+	k[22] = protocol
+	copy(k[23:39], cidr.Addr().AsNetIP().To16())
+	return k
+}
+
+func (k FrontendKeyV6) Proto() uint8 {
+	return k[22]
+}
+
+func (k FrontendKeyV6) Addr() net.IP {
+	return k[4:20]
+}
+
+func (k FrontendKeyV6) srcAddr() ip.Addr {
+	var addr ip.V6Addr
+	copy(addr[:], k[23:39])
+	return addr
+}
+
+// This function returns the Prefix length of the source CIDR
+func (k FrontendKeyV6) SrcPrefixLen() uint32 {
+	return k.PrefixLen() - ZeroCIDRV6PrefixLen
+}
+
+func (k FrontendKeyV6) SrcCIDR() ip.CIDR {
+	return ip.CIDRFromAddrAndPrefix(k.srcAddr(), int(k.SrcPrefixLen()))
+}
+
+func (k FrontendKeyV6) PrefixLen() uint32 {
+	return binary.LittleEndian.Uint32(k[0:4])
+}
+
+func (k FrontendKeyV6) Port() uint16 {
+	return binary.LittleEndian.Uint16(k[20:22])
+}
+
+func (k FrontendKeyV6) AsBytes() []byte {
+	return k[:]
+}
+
+func (k FrontendKeyV6) String() string {
+	return fmt.Sprintf("NATKeyV6{Proto:%v Addr:%v Port:%v SrcAddr:%v}", k.Proto(), k.Addr(), k.Port(), k.SrcCIDR())
+}
+
+type BackendValueV6 [backendValueV6Size]byte
+
+func NewNATBackendValueV6(addr net.IP, port uint16) BackendValueV6 {
+	var k BackendValueV6
+	if addr.To4() != nil || len(addr) != 16 {
+		log.WithField("ip", addr).Panic("Bad IPv6")
+	}
+	copy(k[:16], addr)
+	binary.LittleEndian.PutUint16(k[16:18], port)
+	return k
+}
+
+func (k BackendValueV6) Addr() net.IP {
+	return k[:16]
+}
+
+func (k BackendValueV6) Port() uint16 {
+	return binary.LittleEndian.Uint16(k[16:18])
+}
+
+func (k BackendValueV6) String() string {
+	return fmt.Sprintf("NATBackendValueV6{Addr:%v Port:%v}", k.Addr(), k.Port())
+}
+
+func (k BackendValueV6) AsBytes() []byte {
+	return k[:]
+}
+
+var FrontendMapV6Parameters = bpf.MapParameters{
+	Filename:   "/sys/fs/bpf/tc/globals/cali_v6_nat_fe",
+	Type:       "lpm_trie",
+	KeySize:    frontendKeyV6Size,
+	ValueSize:  frontendValueSize,
+	MaxEntries: 511000,
+	Name:       "cali_v6_nat_fe",
+	Flags:      unix.BPF_F_NO_PREALLOC,
+}
+
+func FrontendMapV6(mc *bpf.MapContext) bpf.Map {
+	return mc.NewPinnedMap(FrontendMapV6Parameters)
+}
+
+var BackendMapV6Parameters = bpf.MapParameters{
+	Filename:   "/sys/fs/bpf/tc/globals/cali_v6_nat_be",
+	Type:       "hash",
+	KeySize:    backendKeySize,
+	ValueSize:  backendValueV6Size,
+	MaxEntries: 510000,
+	Name:       "cali_v6_nat_be",
+	Flags:      unix.BPF_F_NO_PREALLOC,
+}
+
+func BackendMapV6(mc *bpf.MapContext) bpf.Map {
+	return mc.NewPinnedMap(BackendMapV6Parameters)
+}
+
+// MapMemV6 represents FrontendMapV6 loaded into memory
+type MapMemV6 map[FrontendKeyV6]FrontendValue
+
+// LoadFrontendMapV6 loads the IPv6 NAT map into a go map or returns an error
+func LoadFrontendMapV6(m bpf.Map) (MapMemV6, error) {
+	ret := make(MapMemV6)
+
+	if err := m.Open(); err != nil {
+		return nil, err
+	}
+
+	err := m.Iter(func(k, v []byte) bpf.IteratorAction {
+		var key FrontendKeyV6
+		copy(key[:], k)
+
+		var val FrontendValue
+		copy(val[:], v)
+
+		ret[key] = val
+		return bpf.IterNone
+	})
+	if err != nil {
+		ret = nil
+	}
+
+	return ret, err
+}
+
+// BackendMapMemV6 represents BackendMapV6 loaded into memory
+type BackendMapMemV6 map[BackendKey]BackendValueV6
+
+// LoadBackendMapV6 loads the IPv6 NAT backend map into a go map or returns an error
+func LoadBackendMapV6(m bpf.Map) (BackendMapMemV6, error) {
+	ret := make(BackendMapMemV6)
+
+	if err := m.Open(); err != nil {
+		return nil, err
+	}
+
+	err := m.Iter(func(k, v []byte) bpf.IteratorAction {
+		var key BackendKey
+		copy(key[:], k)
+
+		var val BackendValueV6
+		copy(val[:], v)
+
+		ret[key] = val
+		return bpf.IterNone
+	})
+	if err != nil {
+		ret = nil
+	}
+
+	return ret, err
+}
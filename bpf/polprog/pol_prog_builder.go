@@ -20,7 +20,9 @@ import (
 	"math/bits"
 	"strings"
 
+	"github.com/projectcalico/felix/bpf/audit"
 	"github.com/projectcalico/felix/bpf/ipsets"
+	"github.com/projectcalico/felix/bpf/rulecounters"
 
 	"github.com/projectcalico/felix/bpf"
 
@@ -44,18 +46,53 @@ type Builder struct {
 	ipSetMapFD bpf.MapFD
 	stateMapFD bpf.MapFD
 	jumpMapFD  bpf.MapFD
+	auditMapFD bpf.MapFD
+
+	// ruleCountersMapFD, if non-zero, is the map in which we count the packets and bytes that
+	// match each rule.
+	ruleCountersMapFD bpf.MapFD
+
+	// ipv6 is set if we're generating the policy program for the IPv6 tc programs.  The state
+	// struct and IP set keys have a different layout in that case.
+	ipv6 bool
+	offs *stateOffsets
+}
+
+// Option configures optional behaviour of the Builder.
+type Option func(*Builder)
+
+// WithRuleCounters makes the policy program count the packets and bytes that match each rule
+// (that has a rule ID) in the given rule counters map.
+func WithRuleCounters(mapFD bpf.MapFD) Option {
+	return func(p *Builder) {
+		p.ruleCountersMapFD = mapFD
+	}
+}
+
+// WithIPv6 makes the Builder generate a policy program for the IPv6 tc programs, which use the
+// IPv6 state map and IP sets map.  Only the IPv6 version of each rule is rendered.
+func WithIPv6() Option {
+	return func(p *Builder) {
+		p.ipv6 = true
+		p.offs = &stateOffsetsV6
+	}
 }
 
 type ipSetIDProvider interface {
 	GetNoAlloc(ipSetID string) uint64
 }
 
-func NewBuilder(ipSetIDProvider ipSetIDProvider, ipsetMapFD, stateMapFD, jumpMapFD bpf.MapFD) *Builder {
+func NewBuilder(ipSetIDProvider ipSetIDProvider, ipsetMapFD, stateMapFD, jumpMapFD, auditMapFD bpf.MapFD, opts ...Option) *Builder {
 	b := &Builder{
 		ipSetIDProvider: ipSetIDProvider,
 		ipSetMapFD:      ipsetMapFD,
 		stateMapFD:      stateMapFD,
 		jumpMapFD:       jumpMapFD,
+		auditMapFD:      auditMapFD,
+		offs:            &stateOffsetsV4,
+	}
+	for _, o := range opts {
+		o(b)
 	}
 	return b
 }
@@ -82,6 +119,13 @@ var (
 	offStateKey    = nextOffset(4, 4)
 	offSrcIPSetKey = nextOffset(ipsets.IPSetEntrySize, 8)
 	offDstIPSetKey = nextOffset(ipsets.IPSetEntrySize, 8)
+	offAuditKey    = nextOffset(audit.KeySize, 8)
+	offAuditValue  = nextOffset(audit.ValueSize, 8)
+	offRuleCtrKey  = nextOffset(rulecounters.KeySize, 8)
+	offRuleCtrVal  = nextOffset(rulecounters.ValueSize, 8)
+
+	offSrcIPSetKeyV6 = nextOffset(ipsets.IPSetEntryV6Size, 8)
+	offDstIPSetKeyV6 = nextOffset(ipsets.IPSetEntryV6Size, 8)
 
 	// Offsets within the cal_tc_state struct.
 	// WARNING: must be kept in sync with the definitions in bpf/include/jump.h.
@@ -101,8 +145,56 @@ var (
 	stateOffIPProto        int16 = stateEventHdrSize + 32
 	stateOffFlags          int16 = stateEventHdrSize + 33
 
+	// Offsets within the cali_tc_state_v6 struct.
+	// WARNING: must be kept in sync with the definitions in bpf-gpl/types_v6.h.
+	stateV6OffIPSrc          int16 = stateEventHdrSize + 0
+	stateV6OffPreNATIPDst    int16 = stateEventHdrSize + 32
+	stateV6OffPostNATIPDst   int16 = stateEventHdrSize + 48
+	stateV6OffPolResult      int16 = stateEventHdrSize + 64
+	stateV6OffSrcPort        int16 = stateEventHdrSize + 68
+	stateV6OffDstPort        int16 = stateEventHdrSize + 70
+	stateV6OffPreNATDstPort  int16 = stateEventHdrSize + 72
+	stateV6OffPostNATDstPort int16 = stateEventHdrSize + 74
+	stateV6OffIPProto        int16 = stateEventHdrSize + 76
+	stateV6OffFlags          int16 = stateEventHdrSize + 77
+
+	stateOffsetsV4 = stateOffsets{
+		ipSrc:          stateOffIPSrc,
+		preNATIPDst:    stateOffPreNATIPDst,
+		postNATIPDst:   stateOffPostNATIPDst,
+		polResult:      stateOffPolResult,
+		srcPort:        stateOffSrcPort,
+		icmpType:       stateOffICMPType,
+		preNATDstPort:  stateOffPreNATDstPort,
+		postNATDstPort: stateOffPostNATDstPort,
+		ipProto:        stateOffIPProto,
+		flags:          stateOffFlags,
+
+		srcIPSetKey: offSrcIPSetKey,
+		dstIPSetKey: offDstIPSetKey,
+	}
+	stateOffsetsV6 = stateOffsets{
+		ipSrc:          stateV6OffIPSrc,
+		preNATIPDst:    stateV6OffPreNATIPDst,
+		postNATIPDst:   stateV6OffPostNATIPDst,
+		polResult:      stateV6OffPolResult,
+		srcPort:        stateV6OffSrcPort,
+		icmpType:       stateV6OffDstPort,
+		preNATDstPort:  stateV6OffPreNATDstPort,
+		postNATDstPort: stateV6OffPostNATDstPort,
+		ipProto:        stateV6OffIPProto,
+		flags:          stateV6OffFlags,
+
+		srcIPSetKey: offSrcIPSetKeyV6,
+		dstIPSetKey: offDstIPSetKeyV6,
+	}
+
+	// Offsets within struct __sk_buff.
+	skbOffLen int16 = 0
+
 	// Compile-time check that IPSetEntrySize hasn't changed; if it changes, the code will need to change.
 	_ = [1]struct{}{{}}[20-ipsets.IPSetEntrySize]
+	_ = [1]struct{}{{}}[32-ipsets.IPSetEntryV6Size]
 
 	// Offsets within struct ip4_set_key.
 	// WARNING: must be kept in sync with the definitions in bpf/ipsets/map.go.
@@ -114,11 +206,37 @@ var (
 	ipsKeyProto  int16 = 18
 	ipsKeyPad    int16 = 19
 
+	// Offsets within struct ip6_set_key.
+	// WARNING: must be kept in sync with the definitions in bpf/ipsets/map_v6.go.
+	// WARNING: must be kept in sync with the definitions in bpf-gpl/policy_v6.h.
+	ipsKeyV6Addr  int16 = 12
+	ipsKeyV6Port  int16 = 28
+	ipsKeyV6Proto int16 = 30
+	ipsKeyV6Pad   int16 = 31
+
 	// Bits in the state flags field.
 	FlagDestIsHost uint8 = 1 << 2
 	FlagSrcIsHost  uint8 = 1 << 3
 )
 
+// stateOffsets holds the offsets of the fields that the policy program uses within the state
+// struct, which differs between IPv4 and IPv6, along with the stack slots for the IP set keys.
+type stateOffsets struct {
+	ipSrc          int16
+	preNATIPDst    int16
+	postNATIPDst   int16
+	polResult      int16
+	srcPort        int16
+	icmpType       int16
+	preNATDstPort  int16
+	postNATDstPort int16
+	ipProto        int16
+	flags          int16
+
+	srcIPSetKey int16
+	dstIPSetKey int16
+}
+
 type Rule struct {
 	*proto.Rule
 }
@@ -126,6 +244,9 @@ type Rule struct {
 type Policy struct {
 	Name  string
 	Rules []Rule
+	// AuditOnly policies count the packets that match each rule (in the audit map) instead of
+	// taking the rule's action.
+	AuditOnly bool
 }
 
 type Tier struct {
@@ -264,7 +385,7 @@ const (
 
 func (p *Builder) writeJumpIfToOrFromHost(label string) {
 	// Load state flags.
-	p.b.Load8(R1, R9, stateOffFlags)
+	p.b.Load8(R1, R9, p.offs.flags)
 
 	// Mask against host bits.
 	p.b.AndImm32(R1, int32(FlagDestIsHost|FlagSrcIsHost))
@@ -284,7 +405,7 @@ func (p *Builder) writeProgramFooter() {
 		p.b.LabelNextInsn("allow")
 		// Store the policy result in the state for the next program to see.
 		p.b.MovImm32(R1, int32(state.PolicyAllow))
-		p.b.Store32(R9, R1, stateOffPolResult)
+		p.b.Store32(R9, R1, p.offs.polResult)
 		// Execute the tail call.
 		p.b.Mov64(R1, R6)                      // First arg is the context.
 		p.b.LoadMapFD(R2, uint32(p.jumpMapFD)) // Second arg is the map.
@@ -293,7 +414,7 @@ func (p *Builder) writeProgramFooter() {
 
 		// Fall through if tail call fails.
 		p.b.MovImm32(R1, state.PolicyTailCallFailed)
-		p.b.Store32(R9, R1, stateOffPolResult)
+		p.b.Store32(R9, R1, p.offs.polResult)
 		p.b.MovImm64(R0, 2 /* TC_ACT_SHOT */)
 		p.b.Exit()
 	}
@@ -302,18 +423,22 @@ func (p *Builder) writeProgramFooter() {
 func (p *Builder) setUpIPSetKey(ipsetID uint64, keyOffset, ipOffset, portOffset int16) {
 	// TODO track whether we've already done an initialisation and skip the parts that don't change.
 	// Zero the padding.
-	p.b.MovImm64(R1, 0) // R1 = 0
-	p.b.StoreStack8(R1, keyOffset+ipsKeyPad)
-	p.b.MovImm64(R1, 128) // R1 = 128
-	p.b.StoreStack32(R1, keyOffset+ipsKeyPrefix)
-
-	// Store the IP address, port and protocol.
-	p.b.Load32(R1, R9, ipOffset)
-	p.b.StoreStack32(R1, keyOffset+ipsKeyAddr)
-	p.b.Load16(R1, R9, portOffset)
-	p.b.StoreStack16(R1, keyOffset+ipsKeyPort)
-	p.b.Load8(R1, R9, stateOffIPProto)
-	p.b.StoreStack8(R1, keyOffset+ipsKeyProto)
+	if p.ipv6 {
+		p.setUpIPSetKeyV6(keyOffset, ipOffset, portOffset)
+	} else {
+		p.b.MovImm64(R1, 0) // R1 = 0
+		p.b.StoreStack8(R1, keyOffset+ipsKeyPad)
+		p.b.MovImm64(R1, 128) // R1 = 128
+		p.b.StoreStack32(R1, keyOffset+ipsKeyPrefix)
+
+		// Store the IP address, port and protocol.
+		p.b.Load32(R1, R9, ipOffset)
+		p.b.StoreStack32(R1, keyOffset+ipsKeyAddr)
+		p.b.Load16(R1, R9, portOffset)
+		p.b.StoreStack16(R1, keyOffset+ipsKeyPort)
+		p.b.Load8(R1, R9, p.offs.ipProto)
+		p.b.StoreStack8(R1, keyOffset+ipsKeyProto)
+	}
 
 	// Store the IP set ID.  It is 64-bit but, since it's a packed struct, we have to write it in two
 	// 32-bit chunks.
@@ -324,6 +449,23 @@ func (p *Builder) setUpIPSetKey(ipsetID uint64, keyOffset, ipOffset, portOffset
 	p.b.StoreStack32(R1, keyOffset+ipsKeyID+4)
 }
 
+func (p *Builder) setUpIPSetKeyV6(keyOffset, ipOffset, portOffset int16) {
+	p.b.MovImm64(R1, 0) // R1 = 0
+	p.b.StoreStack8(R1, keyOffset+ipsKeyV6Pad)
+	p.b.MovImm64(R1, 224) // R1 = 224
+	p.b.StoreStack32(R1, keyOffset+ipsKeyPrefix)
+
+	// Store the IP address, one 32-bit word at a time, then the port and protocol.
+	for i := int16(0); i < 16; i += 4 {
+		p.b.Load32(R1, R9, ipOffset+i)
+		p.b.StoreStack32(R1, keyOffset+ipsKeyV6Addr+i)
+	}
+	p.b.Load16(R1, R9, portOffset)
+	p.b.StoreStack16(R1, keyOffset+ipsKeyV6Port)
+	p.b.Load8(R1, R9, p.offs.ipProto)
+	p.b.StoreStack8(R1, keyOffset+ipsKeyV6Proto)
+}
+
 func (p *Builder) writeTiers(tiers []Tier, destLeg matchLeg, allowLabel string) {
 	actionLabels := map[string]string{
 		"allow": allowLabel,
@@ -344,6 +486,11 @@ func (p *Builder) writeTiers(tiers []Tier, destLeg matchLeg, allowLabel string)
 		if action == TierEndUndef {
 			action = TierEndDeny
 		}
+		if tierIsAuditOnly(tier) {
+			// Audit-only policies never allow or deny traffic so a tier that only contains
+			// audit-only policies behaves as if it wasn't there.
+			action = TierEndPass
+		}
 		log.Debugf("End of tier %d %q: %s", p.tierID, tier.Name, action)
 		p.writeRule(Rule{
 			Rule: &proto.Rule{},
@@ -353,6 +500,20 @@ func (p *Builder) writeTiers(tiers []Tier, destLeg matchLeg, allowLabel string)
 	}
 }
 
+// tierIsAuditOnly returns true if the tier has policies and they are all audit-only.  A tier
+// without any policies keeps its normal end-of-tier action.
+func tierIsAuditOnly(tier Tier) bool {
+	if len(tier.Policies) == 0 {
+		return false
+	}
+	for _, pol := range tier.Policies {
+		if !pol.AuditOnly {
+			return false
+		}
+	}
+	return true
+}
+
 func (p *Builder) writeProfiles(profiles []Policy, allowLabel string) {
 	log.Debugf("Start of profiles")
 	for idx, prof := range profiles {
@@ -380,11 +541,79 @@ func (p *Builder) writePolicyRules(policy Policy, actionLabels map[string]string
 
 func (p *Builder) writePolicy(policy Policy, actionLabels map[string]string, destLeg matchLeg) {
 	log.Debugf("Start of policy %q %d", policy.Name, p.policyID)
-	p.writePolicyRules(policy, actionLabels, destLeg)
+	if policy.AuditOnly {
+		p.writeAuditPolicyRules(policy, destLeg)
+	} else {
+		p.writePolicyRules(policy, actionLabels, destLeg)
+	}
 	log.Debugf("End of policy %q %d", policy.Name, p.policyID)
 	p.policyID++
 }
 
+// writeAuditPolicyRules writes the rules of an audit-only policy.  The first rule that matches
+// the packet increments its counter and then we skip to the next policy, leaving the packet
+// unaffected.
+func (p *Builder) writeAuditPolicyRules(policy Policy, destLeg matchLeg) {
+	endOfPolicyLabel := fmt.Sprint("end_of_policy_", p.policyID)
+	var counterIDs []uint64
+	for ruleIdx, rule := range policy.Rules {
+		log.Debugf("Start of audit rule %d", ruleIdx)
+		if strings.ToLower(rule.Action) == "log" {
+			log.Debug("Skipping log rule.  Not supported in BPF mode.")
+			continue
+		}
+		p.writeRule(rule, p.auditCounterLabel(len(counterIDs)), destLeg)
+		counterIDs = append(counterIDs, audit.RuleCounterID(rule.RuleId))
+		log.Debugf("End of audit rule %d", ruleIdx)
+	}
+	// No rule matched.
+	p.b.Jump(endOfPolicyLabel)
+
+	// Write the counter updates out of line.  If a rule was skipped because it didn't apply to
+	// this IP version then nothing jumps to its counter update and the assembler drops it as
+	// unreachable.
+	for idx, counterID := range counterIDs {
+		p.b.LabelNextInsn(p.auditCounterLabel(idx))
+		p.writeAuditCounterIncrement(counterID, idx, endOfPolicyLabel)
+	}
+	p.b.LabelNextInsn(endOfPolicyLabel)
+}
+
+func (p *Builder) auditCounterLabel(idx int) string {
+	return fmt.Sprintf("policy_%d_audit_%d", p.policyID, idx)
+}
+
+func (p *Builder) writeAuditCounterIncrement(counterID uint64, idx int, doneLabel string) {
+	// Put the key on the stack and look up the counter.
+	p.b.LoadImm64(R1, int64(counterID))
+	p.b.StoreStack64(R1, offAuditKey)
+	p.b.LoadMapFD(R1, uint32(p.auditMapFD))
+	p.b.Mov64(R2, R10)
+	p.b.AddImm64(R2, int32(offAuditKey))
+	p.b.Call(HelperMapLookupElem)
+	newCounterLabel := fmt.Sprintf("policy_%d_audit_%d_new", p.policyID, idx)
+	p.b.JumpEqImm64(R0, 0, newCounterLabel)
+
+	// Counter exists, increment it.
+	p.b.MovImm64(R1, 1)
+	p.b.AtomicAdd64(R0, R1, 0)
+	p.b.Jump(doneLabel)
+
+	// First hit on this counter, create it.  If another CPU creates it at the same time, we lose
+	// one of the hits; that's acceptable for an audit counter.
+	p.b.LabelNextInsn(newCounterLabel)
+	p.b.MovImm64(R1, 1)
+	p.b.StoreStack64(R1, offAuditValue)
+	p.b.LoadMapFD(R1, uint32(p.auditMapFD))
+	p.b.Mov64(R2, R10)
+	p.b.AddImm64(R2, int32(offAuditKey))
+	p.b.Mov64(R3, R10)
+	p.b.AddImm64(R3, int32(offAuditValue))
+	p.b.MovImm64(R4, 1 /* BPF_NOEXIST */)
+	p.b.Call(HelperMapUpdateElem)
+	p.b.Jump(doneLabel)
+}
+
 func (p *Builder) writeProfile(profile Profile, idx int, allowLabel string) {
 	actionLabels := map[string]string{
 		"allow":     allowLabel,
@@ -406,33 +635,33 @@ const (
 	legDestPreNAT matchLeg = "destPreNAT"
 )
 
-func (leg matchLeg) offsetToStateIPAddressField() (offset int16) {
+func (leg matchLeg) offsetToStateIPAddressField(offs *stateOffsets) (offset int16) {
 	if leg == legSource {
-		offset = stateOffIPSrc
+		offset = offs.ipSrc
 	} else if leg == legDestPreNAT {
-		offset = stateOffPreNATIPDst
+		offset = offs.preNATIPDst
 	} else {
-		offset = stateOffPostNATIPDst
+		offset = offs.postNATIPDst
 	}
 	return
 }
 
-func (leg matchLeg) offsetToStatePortField() (portOffset int16) {
+func (leg matchLeg) offsetToStatePortField(offs *stateOffsets) (portOffset int16) {
 	if leg == legSource {
-		portOffset = stateOffSrcPort
+		portOffset = offs.srcPort
 	} else if leg == legDestPreNAT {
-		portOffset = stateOffPreNATDstPort
+		portOffset = offs.preNATDstPort
 	} else {
-		portOffset = stateOffPostNATDstPort
+		portOffset = offs.postNATDstPort
 	}
 	return
 }
 
-func (leg matchLeg) stackOffsetToIPSetKey() (keyOffset int16) {
+func (leg matchLeg) stackOffsetToIPSetKey(offs *stateOffsets) (keyOffset int16) {
 	if leg == legSource {
-		keyOffset = offSrcIPSetKey
+		keyOffset = offs.srcIPSetKey
 	} else {
-		keyOffset = offDstIPSetKey
+		keyOffset = offs.dstIPSetKey
 	}
 	return
 }
@@ -442,7 +671,11 @@ func (p *Builder) writeRule(r Rule, actionLabel string, destLeg matchLeg) {
 		log.Panic("empty action label")
 	}
 
-	rule := rules.FilterRuleToIPVersion(4, r.Rule)
+	ipVersion := uint8(4)
+	if p.ipv6 {
+		ipVersion = 6
+	}
+	rule := rules.FilterRuleToIPVersion(ipVersion, r.Rule)
 	if rule == nil {
 		log.Debugf("Version mismatch, skipping rule")
 		return
@@ -548,13 +781,55 @@ func (p *Builder) writeEndOfRule(rule Rule, actionLabel string) {
 	// If all the match criteria are met, we fall through to the end of the rule
 	// so all that's left to do is to jump to the relevant action.
 	// TODO log and log-and-xxx actions
+	if p.ruleCountersMapFD != 0 && rule.RuleId != "" {
+		p.writeRuleCounterIncrement(rulecounters.CounterID(rule.RuleId))
+	}
 	p.b.Jump(actionLabel)
 
 	p.b.LabelNextInsn(p.endOfRuleLabel())
 }
 
+// writeRuleCounterIncrement adds the packet to the packet and byte counters of the current rule.
+func (p *Builder) writeRuleCounterIncrement(counterID uint64) {
+	newCounterLabel := fmt.Sprintf("rule_%d_counter_new", p.ruleID)
+	countedLabel := fmt.Sprintf("rule_%d_counted", p.ruleID)
+
+	// Put the key on the stack and look up the counters.
+	p.b.LoadImm64(R1, int64(counterID))
+	p.b.StoreStack64(R1, offRuleCtrKey)
+	p.b.LoadMapFD(R1, uint32(p.ruleCountersMapFD))
+	p.b.Mov64(R2, R10)
+	p.b.AddImm64(R2, int32(offRuleCtrKey))
+	p.b.Call(HelperMapLookupElem)
+	p.b.JumpEqImm64(R0, 0, newCounterLabel)
+
+	// Counters exist, increment them.
+	p.b.MovImm64(R1, 1)
+	p.b.AtomicAdd64(R0, R1, 0)
+	p.b.Load32(R1, R6, skbOffLen)
+	p.b.AtomicAdd64(R0, R1, 8)
+	p.b.Jump(countedLabel)
+
+	// First hit on this rule, create the counters.  As for the audit counters, if another CPU
+	// creates them at the same time, we lose one of the hits.
+	p.b.LabelNextInsn(newCounterLabel)
+	p.b.MovImm64(R1, 1)
+	p.b.StoreStack64(R1, offRuleCtrVal)
+	p.b.Load32(R1, R6, skbOffLen)
+	p.b.StoreStack64(R1, offRuleCtrVal+8)
+	p.b.LoadMapFD(R1, uint32(p.ruleCountersMapFD))
+	p.b.Mov64(R2, R10)
+	p.b.AddImm64(R2, int32(offRuleCtrKey))
+	p.b.Mov64(R3, R10)
+	p.b.AddImm64(R3, int32(offRuleCtrVal))
+	p.b.MovImm64(R4, 1 /* BPF_NOEXIST */)
+	p.b.Call(HelperMapUpdateElem)
+
+	p.b.LabelNextInsn(countedLabel)
+}
+
 func (p *Builder) writeProtoMatch(negate bool, protocol *proto.Protocol) {
-	p.b.Load8(R1, R9, stateOffIPProto)
+	p.b.Load8(R1, R9, p.offs.ipProto)
 	protoNum := protocolToNumber(protocol)
 	if negate {
 		p.b.JumpEqImm64(R1, int32(protoNum), p.endOfRuleLabel())
@@ -564,7 +839,7 @@ func (p *Builder) writeProtoMatch(negate bool, protocol *proto.Protocol) {
 }
 
 func (p *Builder) writeICMPTypeMatch(negate bool, icmpType uint8) {
-	p.b.Load8(R1, R9, stateOffICMPType)
+	p.b.Load8(R1, R9, p.offs.icmpType)
 	if negate {
 		p.b.JumpEqImm64(R1, int32(icmpType), p.endOfRuleLabel())
 	} else {
@@ -573,7 +848,7 @@ func (p *Builder) writeICMPTypeMatch(negate bool, icmpType uint8) {
 }
 
 func (p *Builder) writeICMPTypeCodeMatch(negate bool, icmpType, icmpCode uint8) {
-	p.b.Load16(R1, R9, stateOffICMPType)
+	p.b.Load16(R1, R9, p.offs.icmpType)
 	if negate {
 		p.b.JumpEqImm64(R1, (int32(icmpCode)<<8)|int32(icmpType), p.endOfRuleLabel())
 	} else {
@@ -581,7 +856,11 @@ func (p *Builder) writeICMPTypeCodeMatch(negate bool, icmpType, icmpCode uint8)
 	}
 }
 func (p *Builder) writeCIDRSMatch(negate bool, leg matchLeg, cidrs []string) {
-	p.b.Load32(R1, R9, leg.offsetToStateIPAddressField())
+	if p.ipv6 {
+		p.writeCIDRSMatchV6(negate, leg, cidrs)
+		return
+	}
+	p.b.Load32(R1, R9, leg.offsetToStateIPAddressField(p.offs))
 
 	var onMatchLabel string
 	if negate {
@@ -593,7 +872,7 @@ func (p *Builder) writeCIDRSMatch(negate bool, leg matchLeg, cidrs []string) {
 	}
 	for _, cidrStr := range cidrs {
 		cidr := ip.MustParseCIDROrIP(cidrStr)
-		addrU32 := bits.ReverseBytes32(cidr.Addr().(ip.V4Addr).AsUint32()) // TODO IPv6
+		addrU32 := bits.ReverseBytes32(cidr.Addr().(ip.V4Addr).AsUint32())
 		maskU32 := bits.ReverseBytes32(math.MaxUint32 << (32 - cidr.Prefix()) & math.MaxUint32)
 
 		p.b.MovImm32(R2, int32(maskU32))
@@ -608,6 +887,58 @@ func (p *Builder) writeCIDRSMatch(negate bool, leg matchLeg, cidrs []string) {
 	}
 }
 
+// writeCIDRSMatchV6 is the IPv6 version of writeCIDRSMatch.  The address is compared one 32-bit
+// word at a time; if any word doesn't match, we skip to the next CIDR.
+func (p *Builder) writeCIDRSMatchV6(negate bool, leg matchLeg, cidrs []string) {
+	addrOffset := leg.offsetToStateIPAddressField(p.offs)
+
+	var onMatchLabel string
+	if negate {
+		// Match negated, if we match any CIDR then we jump to the next rule.
+		onMatchLabel = p.endOfRuleLabel()
+	} else {
+		// Match is non-negated, if we match, got to the next match criteria.
+		onMatchLabel = p.freshPerRuleLabel()
+	}
+	for _, cidrStr := range cidrs {
+		cidr := ip.MustParseCIDROrIP(cidrStr)
+		addr := cidr.Addr().AsNetIP().To16()
+		prefix := int(cidr.Prefix())
+		nextCIDRLabel := p.freshPerRuleLabel()
+
+		for word := 0; word < 4; word++ {
+			// Number of bits of the prefix that fall in this word.
+			wordBits := prefix - word*32
+			if wordBits <= 0 {
+				// Rest of the address is masked out.
+				break
+			}
+			if wordBits > 32 {
+				wordBits = 32
+			}
+			maskU32 := uint32(math.MaxUint32 << (32 - wordBits) & math.MaxUint32)
+			addrU32 := uint32(addr[word*4])<<24 | uint32(addr[word*4+1])<<16 |
+				uint32(addr[word*4+2])<<8 | uint32(addr[word*4+3])
+
+			p.b.Load32(R1, R9, addrOffset+int16(word*4))
+			if wordBits < 32 {
+				p.b.MovImm32(R2, int32(bits.ReverseBytes32(maskU32)))
+				p.b.And32(R1, R2)
+			}
+			p.b.JumpNEImm32(R1, int32(bits.ReverseBytes32(addrU32&maskU32)), nextCIDRLabel)
+		}
+		// All the words matched.
+		p.b.Jump(onMatchLabel)
+		p.b.LabelNextInsn(nextCIDRLabel)
+	}
+	if !negate {
+		// If we fall through then none of the CIDRs matched so the rule doesn't match.
+		p.b.Jump(p.endOfRuleLabel())
+		// Label the next match so we can skip to it on success.
+		p.b.LabelNextInsn(onMatchLabel)
+	}
+}
+
 func (p *Builder) writeIPSetMatch(negate bool, leg matchLeg, ipSets []string) {
 	// IP sets are different to CIDRs, if we have multiple IP sets then they all have to match
 	// so we treat them as independent match criteria.
@@ -617,8 +948,8 @@ func (p *Builder) writeIPSetMatch(negate bool, leg matchLeg, ipSets []string) {
 			log.WithField("setID", ipSetID).Panic("Failed to look up IP set ID.")
 		}
 
-		keyOffset := leg.stackOffsetToIPSetKey()
-		p.setUpIPSetKey(id, keyOffset, leg.offsetToStateIPAddressField(), leg.offsetToStatePortField())
+		keyOffset := leg.stackOffsetToIPSetKey(p.offs)
+		p.setUpIPSetKey(id, keyOffset, leg.offsetToStateIPAddressField(p.offs), leg.offsetToStatePortField(p.offs))
 		p.b.LoadMapFD(R1, uint32(p.ipSetMapFD))
 		p.b.Mov64(R2, R10)
 		p.b.AddImm64(R2, int32(keyOffset))
@@ -647,8 +978,8 @@ func (p *Builder) writeIPSetOrMatch(leg matchLeg, ipSets []string) {
 			log.WithField("setID", ipSetID).Panic("Failed to look up IP set ID.")
 		}
 
-		keyOffset := leg.stackOffsetToIPSetKey()
-		p.setUpIPSetKey(id, keyOffset, leg.offsetToStateIPAddressField(), leg.offsetToStatePortField())
+		keyOffset := leg.stackOffsetToIPSetKey(p.offs)
+		p.setUpIPSetKey(id, keyOffset, leg.offsetToStateIPAddressField(p.offs), leg.offsetToStatePortField(p.offs))
 		p.b.LoadMapFD(R1, uint32(p.ipSetMapFD))
 		p.b.Mov64(R2, R10)
 		p.b.AddImm64(R2, int32(keyOffset))
@@ -678,7 +1009,7 @@ func (p *Builder) writePortsMatch(negate bool, leg matchLeg, ports []*proto.Port
 	}
 
 	// R1 = port to test against.
-	p.b.Load16(R1, R9, leg.offsetToStatePortField())
+	p.b.Load16(R1, R9, leg.offsetToStatePortField(p.offs))
 
 	for _, portRange := range ports {
 		if portRange.First == portRange.Last {
@@ -706,8 +1037,8 @@ func (p *Builder) writePortsMatch(negate bool, leg matchLeg, ports []*proto.Port
 			log.WithField("setID", ipSetID).Panic("Failed to look up IP set ID.")
 		}
 
-		keyOffset := leg.stackOffsetToIPSetKey()
-		p.setUpIPSetKey(id, keyOffset, leg.offsetToStateIPAddressField(), leg.offsetToStatePortField())
+		keyOffset := leg.stackOffsetToIPSetKey(p.offs)
+		p.setUpIPSetKey(id, keyOffset, leg.offsetToStateIPAddressField(p.offs), leg.offsetToStatePortField(p.offs))
 		p.b.LoadMapFD(R1, uint32(p.ipSetMapFD))
 		p.b.Mov64(R2, R10)
 		p.b.AddImm64(R2, int32(keyOffset))
@@ -745,6 +1076,8 @@ func protocolToNumber(protocol *proto.Protocol) uint8 {
 			pcol = 17
 		case "icmp":
 			pcol = 1
+		case "icmpv6":
+			pcol = 58
 		case "sctp":
 			pcol = 132
 		}
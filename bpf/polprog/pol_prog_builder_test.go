@@ -19,6 +19,7 @@ import (
 
 	. "github.com/onsi/gomega"
 
+	"github.com/projectcalico/felix/bpf/asm"
 	"github.com/projectcalico/felix/idalloc"
 	"github.com/projectcalico/felix/proto"
 )
@@ -33,7 +34,7 @@ func TestPolicySanityCheck(t *testing.T) {
 		alloc.GetOrAlloc(id)
 		return id
 	}
-	pg := NewBuilder(alloc, 1, 2, 3)
+	pg := NewBuilder(alloc, 1, 2, 3, 4)
 	insns, err := pg.Instructions(Rules{
 		Tiers: []Tier{{
 			Policies: []Policy{{
@@ -77,7 +78,7 @@ func TestLogActionIgnored(t *testing.T) {
 	RegisterTestingT(t)
 	alloc := idalloc.New()
 
-	pg := NewBuilder(alloc, 1, 2, 3)
+	pg := NewBuilder(alloc, 1, 2, 3, 4)
 	insns, err := pg.Instructions(Rules{
 		Tiers: []Tier{{
 			Name: "default",
@@ -90,7 +91,7 @@ func TestLogActionIgnored(t *testing.T) {
 		}}})
 	Expect(err).NotTo(HaveOccurred())
 
-	pg = NewBuilder(alloc, 1, 2, 3)
+	pg = NewBuilder(alloc, 1, 2, 3, 4)
 	noOpInsns, err := pg.Instructions(Rules{
 		Tiers: []Tier{{
 			Name:     "default",
@@ -99,3 +100,149 @@ func TestLogActionIgnored(t *testing.T) {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(noOpInsns).To(Equal(insns))
 }
+
+func TestAuditOnlyPolicyCountsMatches(t *testing.T) {
+	RegisterTestingT(t)
+	alloc := idalloc.New()
+
+	pg := NewBuilder(alloc, 1, 2, 3, 4)
+	insns, err := pg.Instructions(Rules{
+		Tiers: []Tier{{
+			Name: "default",
+			Policies: []Policy{{
+				Name:      "staged:test policy",
+				AuditOnly: true,
+				Rules: []Rule{
+					{Rule: &proto.Rule{
+						Action:   "Deny",
+						RuleId:   "rule-1",
+						Protocol: &proto.Protocol{NumberOrName: &proto.Protocol_Number{Number: 6}},
+					}},
+					{Rule: &proto.Rule{Action: "Allow", RuleId: "rule-2"}},
+				},
+			}},
+		}}})
+	Expect(err).NotTo(HaveOccurred())
+
+	numAtomicAdds := 0
+	for _, in := range insns {
+		if asm.OpCode(in[0]) == asm.AtomicAdd64 {
+			numAtomicAdds++
+		}
+	}
+	Expect(numAtomicAdds).To(Equal(2))
+}
+
+func TestRuleCountersIncrementedWhenEnabled(t *testing.T) {
+	RegisterTestingT(t)
+	alloc := idalloc.New()
+
+	rules := Rules{
+		Tiers: []Tier{{
+			Name: "default",
+			Policies: []Policy{{
+				Name: "test policy",
+				Rules: []Rule{
+					{Rule: &proto.Rule{
+						Action:   "Deny",
+						RuleId:   "rule-1",
+						Protocol: &proto.Protocol{NumberOrName: &proto.Protocol_Number{Number: 6}},
+					}},
+					{Rule: &proto.Rule{
+						Action:   "Deny",
+						Protocol: &proto.Protocol{NumberOrName: &proto.Protocol_Number{Number: 17}},
+					}},
+					{Rule: &proto.Rule{Action: "Allow", RuleId: "rule-3"}},
+				},
+			}},
+		}}}
+	countAtomicAdds := func(insns asm.Insns) int {
+		n := 0
+		for _, in := range insns {
+			if asm.OpCode(in[0]) == asm.AtomicAdd64 {
+				n++
+			}
+		}
+		return n
+	}
+
+	insns, err := NewBuilder(alloc, 1, 2, 3, 4).Instructions(rules)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(countAtomicAdds(insns)).To(Equal(0))
+
+	// Packet and byte counters for each rule that has an ID.
+	insns, err = NewBuilder(alloc, 1, 2, 3, 4, WithRuleCounters(5)).Instructions(rules)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(countAtomicAdds(insns)).To(Equal(4))
+}
+
+func TestIPv6PolicySanityCheck(t *testing.T) {
+	RegisterTestingT(t)
+	alloc := idalloc.New()
+	setID := func(id string) string {
+		alloc.GetOrAlloc(id)
+		return id
+	}
+	pg := NewBuilder(alloc, 1, 2, 3, 4, WithIPv6())
+	insns, err := pg.Instructions(Rules{
+		Tiers: []Tier{{
+			Policies: []Policy{{
+				Rules: []Rule{{
+					Rule: &proto.Rule{
+						Action:               "Allow",
+						IpVersion:            6,
+						Protocol:             &proto.Protocol{NumberOrName: &proto.Protocol_Name{Name: "ICMPv6"}},
+						SrcNet:               []string{"fd00::/8", "2001:db8::1/128"},
+						DstNet:               []string{"fd00:1:2:3:4::/70"},
+						NotDstNet:            []string{"fd00:ffff::/32"},
+						SrcNamedPortIpSetIds: []string{setID("n:abcdef1234567890")},
+						SrcIpSetIds:          []string{setID("s:sbcdef1234567890")},
+						DstIpSetIds:          []string{setID("s:dbcdef1234567890")},
+						Icmp:                 &proto.Rule_IcmpTypeCode{IcmpTypeCode: &proto.IcmpTypeAndCode{Type: 128, Code: 0}},
+					},
+				}},
+			}},
+		}},
+	})
+
+	Expect(err).NotTo(HaveOccurred())
+	for i, in := range insns {
+		t.Log(i, ": ", in)
+	}
+}
+
+func TestIPv6BuilderSkipsIPv4Rules(t *testing.T) {
+	RegisterTestingT(t)
+	alloc := idalloc.New()
+
+	insns, err := NewBuilder(alloc, 1, 2, 3, 4, WithIPv6()).Instructions(Rules{
+		Tiers: []Tier{{
+			Name: "default",
+			Policies: []Policy{{
+				Name: "test policy",
+				Rules: []Rule{{Rule: &proto.Rule{
+					Action:    "Allow",
+					IpVersion: 4,
+					SrcNet:    []string{"10.0.0.0/8"},
+				}}},
+			}},
+		}}})
+	Expect(err).NotTo(HaveOccurred())
+
+	noOpInsns, err := NewBuilder(alloc, 1, 2, 3, 4, WithIPv6()).Instructions(Rules{
+		Tiers: []Tier{{
+			Name:     "default",
+			Policies: []Policy{},
+		}}})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(noOpInsns).To(Equal(insns))
+
+	// The IPv4 program uses a different layout for the state so it must differ.
+	v4Insns, err := NewBuilder(alloc, 1, 2, 3, 4).Instructions(Rules{
+		Tiers: []Tier{{
+			Name:     "default",
+			Policies: []Policy{},
+		}}})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(v4Insns).NotTo(Equal(insns))
+}
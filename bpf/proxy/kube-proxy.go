@@ -52,6 +52,8 @@ type KubeProxy struct {
 	opts        []Option
 
 	dsrEnabled bool
+	// ipv6 is set for the kube-proxy that syncs the IPv6 services, see WithIPv6.
+	ipv6 bool
 }
 
 // StartKubeProxy start a new kube-proxy if there was no error
@@ -78,6 +80,11 @@ func StartKubeProxy(k8s kubernetes.Interface, hostname string,
 		}
 	}
 
+	if kp.ipv6 {
+		// The IPv6 syncer doesn't handle NodePorts so it doesn't need to wait for the host IPs.
+		kp.hostIPUpdates <- nil
+	}
+
 	go func() {
 		err := kp.start()
 		if err != nil {
@@ -106,14 +113,24 @@ func (kp *KubeProxy) run(hostIPs []net.IP) error {
 	kp.lock.Lock()
 	defer kp.lock.Unlock()
 
-	withLocalNP := make([]net.IP, len(hostIPs), len(hostIPs)+1)
-	copy(withLocalNP, hostIPs)
-	withLocalNP = append(withLocalNP, podNPIP)
+	var syncer DPSyncer
+	var err error
+
+	if kp.ipv6 {
+		feCache := cachingmap.New(nat.FrontendMapV6Parameters, kp.frontendMap)
+		beCache := cachingmap.New(nat.BackendMapV6Parameters, kp.backendMap)
 
-	feCache := cachingmap.New(nat.FrontendMapParameters, kp.frontendMap)
-	beCache := cachingmap.New(nat.BackendMapParameters, kp.backendMap)
+		syncer, err = NewSyncerV6(feCache, beCache)
+	} else {
+		withLocalNP := make([]net.IP, len(hostIPs), len(hostIPs)+1)
+		copy(withLocalNP, hostIPs)
+		withLocalNP = append(withLocalNP, podNPIP)
+
+		feCache := cachingmap.New(nat.FrontendMapParameters, kp.frontendMap)
+		beCache := cachingmap.New(nat.BackendMapParameters, kp.backendMap)
 
-	syncer, err := NewSyncer(withLocalNP, feCache, beCache, kp.affinityMap, kp.rt)
+		syncer, err = NewSyncer(withLocalNP, feCache, beCache, kp.affinityMap, kp.rt)
+	}
 	if err != nil {
 		return errors.WithMessage(err, "new bpf syncer")
 	}
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 )
 
 // Option defines Proxy options
@@ -76,3 +77,17 @@ func WithDSREnabled() Option {
 		return nil
 	})
 }
+
+// WithIPv6 makes the proxy sync IPv6 services into the IPv6 NAT maps
+func WithIPv6() Option {
+	return func(P Proxy) error {
+		switch p := P.(type) {
+		case *proxy:
+			p.ipFamily = v1.IPv6Protocol
+			log.Infof("proxy.WithIPv6()")
+		case *KubeProxy:
+			p.ipv6 = true
+		}
+		return nil
+	}
+}
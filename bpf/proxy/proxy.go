@@ -79,6 +79,9 @@ type proxy struct {
 
 	endpointSlicesEnabled bool
 
+	// ipFamily is the IP family of the services and endpoints that we sync.
+	ipFamily v1.IPFamily
+
 	dpSyncer DPSyncer
 	// executes periodic the dataplane updates
 	runner *async.BoundedFrequencyRunner
@@ -125,6 +128,8 @@ func New(k8s kubernetes.Interface, dp DPSyncer, hostname string, opts ...Option)
 
 		recorder: new(loggerRecorder),
 
+		ipFamily: v1.IPv4Protocol,
+
 		minDPSyncPeriod: 30 * time.Second, // XXX revisit the default
 
 		stopCh: make(chan struct{}),
@@ -146,12 +151,12 @@ func New(k8s kubernetes.Interface, dp DPSyncer, hostname string, opts ...Option)
 
 	p.epsChanges = k8sp.NewEndpointChangeTracker(p.hostname,
 		nil, // change if you want to provide more ctx
-		v1.IPv4Protocol,
+		p.ipFamily,
 		p.recorder,
 		p.endpointSlicesEnabled,
 		nil,
 	)
-	p.svcChanges = k8sp.NewServiceChangeTracker(nil, v1.IPv4Protocol, p.recorder, nil)
+	p.svcChanges = k8sp.NewServiceChangeTracker(nil, p.ipFamily, p.recorder, nil)
 
 	noProxyName, err := labels.NewRequirement(apis.LabelServiceProxyName, selection.DoesNotExist, nil)
 	if err != nil {
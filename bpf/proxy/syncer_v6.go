@@ -0,0 +1,237 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	k8sp "k8s.io/kubernetes/pkg/proxy"
+
+	"github.com/projectcalico/felix/bpf/cachingmap"
+	"github.com/projectcalico/felix/bpf/nat"
+)
+
+// SyncerV6 is the IPv6 implementation of DPSyncer.  It programs the IPv6 NAT maps with the
+// cluster, external and load balancer IPs of IPv6 services.  NodePorts, session affinity and
+// load balancer source ranges are not supported for IPv6 yet.
+//
+// Like Syncer, it is not thread safe and should be called only once at a time.
+type SyncerV6 struct {
+	bpfSvcs *cachingmap.CachingMap
+	bpfEps  *cachingmap.CachingMap
+
+	// svcIDs keeps the IDs of the frontends stable across updates (and restarts) so that
+	// we only rewrite the backends that changed.
+	svcIDs    map[nat.FrontendKeyV6]uint32
+	nextSvcID uint32
+
+	// activeEpsMap contains the backends of each frontend as of the end of the last Apply().
+	activeEpsMap map[ipPortProto]map[ipPort]struct{}
+	// Protects activeEpsMap
+	mapsLck sync.Mutex
+
+	triggerFn func()
+}
+
+// NewSyncerV6 returns a SyncerV6 that programs the given IPv6 frontend and backend maps.
+func NewSyncerV6(svcsmap, epsmap *cachingmap.CachingMap) (*SyncerV6, error) {
+	s := &SyncerV6{
+		bpfSvcs:      svcsmap,
+		bpfEps:       epsmap,
+		svcIDs:       make(map[nat.FrontendKeyV6]uint32),
+		activeEpsMap: make(map[ipPortProto]map[ipPort]struct{}),
+	}
+
+	if err := s.bpfEps.LoadCacheFromDataplane(); err != nil {
+		return nil, err
+	}
+	if err := s.bpfSvcs.LoadCacheFromDataplane(); err != nil {
+		return nil, err
+	}
+
+	// Reuse the IDs that are already in the dataplane so that we don't disturb existing
+	// connections on restart.
+	s.bpfSvcs.IterDataplaneCache(func(k, v []byte) {
+		var key nat.FrontendKeyV6
+		var val nat.FrontendValue
+		copy(key[:], k)
+		copy(val[:], v)
+		s.svcIDs[key] = val.ID()
+		if val.ID() >= s.nextSvcID {
+			s.nextSvcID = val.ID() + 1
+		}
+	})
+
+	return s, nil
+}
+
+// Apply applies the new state
+func (s *SyncerV6) Apply(state DPSyncerState) error {
+	log.Infof("Applying new IPv6 state, %d service", len(state.SvcMap))
+
+	s.bpfSvcs.DeleteAllDesired()
+	s.bpfEps.DeleteAllDesired()
+
+	svcIDs := make(map[nat.FrontendKeyV6]uint32, len(state.SvcMap))
+	activeEps := make(map[ipPortProto]map[ipPort]struct{}, len(state.SvcMap))
+
+	for sname, sinfo := range state.SvcMap {
+		proto, err := ProtoV1ToInt(sinfo.Protocol())
+		if err != nil {
+			log.WithError(err).WithField("service", sname).Warn("Skipping IPv6 service")
+			continue
+		}
+
+		ips := []net.IP{sinfo.ClusterIP()}
+		for _, extIP := range sinfo.ExternalIPStrings() {
+			ips = append(ips, net.ParseIP(extIP))
+		}
+		for _, lbIP := range sinfo.LoadBalancerIPStrings() {
+			ips = append(ips, net.ParseIP(lbIP))
+		}
+
+		eps := state.EpsMap[sname]
+
+		for _, addr := range ips {
+			if addr == nil || addr.To4() != nil {
+				// Not an IPv6 address, the IPv4 syncer handles those.
+				continue
+			}
+			key := nat.NewNATKeyV6(addr.To16(), uint16(sinfo.Port()), proto)
+			id, ok := svcIDs[key]
+			if !ok {
+				id, ok = s.svcIDs[key]
+				if !ok {
+					id = s.nextSvcID
+					s.nextSvcID++
+				}
+				svcIDs[key] = id
+			}
+
+			count, local, err := s.writeSvcBackends(id, eps)
+			if err != nil {
+				return err
+			}
+
+			val := nat.NewNATValue(id, uint32(count), uint32(local), 0)
+			if log.GetLevel() >= log.DebugLevel {
+				log.Debugf("bpf map writing %s:%s", key, val)
+			}
+			s.bpfSvcs.SetDesired(key[:], val[:])
+
+			epsmap := make(map[ipPort]struct{}, len(eps))
+			for _, ep := range eps {
+				port, _ := ep.Port() // checked by writeSvcBackends
+				epsmap[ipPort{ip: net.ParseIP(ep.IP()).String(), port: port}] = struct{}{}
+			}
+			activeEps[ipPortProto{ipPort: ipPort{ip: addr.String(), port: sinfo.Port()}, proto: proto}] = epsmap
+		}
+	}
+
+	// Same ordering as the IPv4 syncer: remove frontends so that the backends become
+	// unreachable, add backends before the frontends that use them and then remove
+	// the unused backends.
+	if err := s.bpfSvcs.ApplyDeletionsOnly(); err != nil {
+		return err
+	}
+	if err := s.bpfEps.ApplyUpdatesOnly(); err != nil {
+		return err
+	}
+	if err := s.bpfSvcs.ApplyUpdatesOnly(); err != nil {
+		return err
+	}
+	if err := s.bpfEps.ApplyDeletionsOnly(); err != nil {
+		return err
+	}
+
+	s.svcIDs = svcIDs
+
+	s.mapsLck.Lock()
+	s.activeEpsMap = activeEps
+	s.mapsLck.Unlock()
+
+	log.Info("new IPv6 state written")
+
+	return nil
+}
+
+// writeSvcBackends writes the backends of a frontend, local ones first, and returns the
+// total and local counts.
+func (s *SyncerV6) writeSvcBackends(svcID uint32, eps []k8sp.Endpoint) (int, int, error) {
+	cnt := 0
+	local := 0
+
+	for _, wantLocal := range []bool{true, false} {
+		for _, ep := range eps {
+			if ep.GetIsLocal() != wantLocal {
+				continue
+			}
+			addr := net.ParseIP(ep.IP())
+			if addr == nil || addr.To4() != nil {
+				continue
+			}
+			tgtPort, err := ep.Port()
+			if err != nil {
+				return 0, 0, errors.Errorf("no port for endpoint %q: %s", ep, err)
+			}
+
+			key := nat.NewNATBackendKey(svcID, uint32(cnt))
+			val := nat.NewNATBackendValueV6(addr.To16(), uint16(tgtPort))
+			s.bpfEps.SetDesired(key[:], val[:])
+
+			cnt++
+			if wantLocal {
+				local++
+			}
+		}
+	}
+
+	return cnt, local, nil
+}
+
+// ConntrackScanStart to satisfy DPSyncer.
+func (s *SyncerV6) ConntrackScanStart() {
+	s.mapsLck.Lock()
+}
+
+// ConntrackScanEnd to satisfy DPSyncer.
+func (s *SyncerV6) ConntrackScanEnd() {
+	s.mapsLck.Unlock()
+}
+
+// ConntrackFrontendHasBackend returns true if the given backend is still a backend of the
+// given frontend.  It must be called between ConntrackScanStart and ConntrackScanEnd.
+func (s *SyncerV6) ConntrackFrontendHasBackend(ip net.IP, port uint16,
+	backendIP net.IP, backendPort uint16, proto uint8) bool {
+
+	epsmap, ok := s.activeEpsMap[ipPortProto{ipPort: ipPort{ip: ip.String(), port: int(port)}, proto: proto}]
+	if !ok {
+		return false
+	}
+	_, ok = epsmap[ipPort{ip: backendIP.String(), port: int(backendPort)}]
+	return ok
+}
+
+// SetTriggerFn to satisfy DPSyncer.  The IPv6 syncer has no background threads that would
+// need to trigger an Apply().
+func (s *SyncerV6) SetTriggerFn(f func()) {
+	s.triggerFn = f
+}
+
+// Stop to satisfy DPSyncer.
+func (s *SyncerV6) Stop() {}
@@ -0,0 +1,138 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy_test
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sp "k8s.io/kubernetes/pkg/proxy"
+
+	"github.com/projectcalico/felix/bpf/cachingmap"
+	"github.com/projectcalico/felix/bpf/mock"
+	"github.com/projectcalico/felix/bpf/nat"
+	proxy "github.com/projectcalico/felix/bpf/proxy"
+)
+
+var _ = Describe("BPF IPv6 Syncer", func() {
+	var (
+		svcs *mock.Map
+		eps  *mock.Map
+		s    *proxy.SyncerV6
+	)
+
+	svcKey := k8sp.ServicePortName{
+		NamespacedName: types.NamespacedName{
+			Namespace: "default",
+			Name:      "test-service",
+		},
+	}
+
+	svcIP := net.ParseIP("fd00:96::1")
+	extIP := net.ParseIP("2001:db8::10")
+
+	BeforeEach(func() {
+		svcs = mock.NewMockMap(nat.FrontendMapV6Parameters)
+		eps = mock.NewMockMap(nat.BackendMapV6Parameters)
+
+		var err error
+		s, err = proxy.NewSyncerV6(
+			cachingmap.New(nat.FrontendMapV6Parameters, svcs),
+			cachingmap.New(nat.BackendMapV6Parameters, eps),
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should program the cluster and external IPs of a service", func() {
+		state := proxy.DPSyncerState{
+			SvcMap: k8sp.ServiceMap{
+				svcKey: proxy.NewK8sServicePort(svcIP, 1234, v1.ProtocolTCP,
+					proxy.K8sSvcWithExternalIPs([]string{extIP.String(), "10.0.0.1"})),
+			},
+			EpsMap: k8sp.EndpointsMap{
+				svcKey: []k8sp.Endpoint{
+					&k8sp.BaseEndpointInfo{Endpoint: "[fd00:10::1]:5555"},
+					&k8sp.BaseEndpointInfo{Endpoint: "[fd00:10::2]:5555", IsLocal: true},
+				},
+			},
+		}
+
+		Expect(s.Apply(state)).To(Succeed())
+
+		fe, err := nat.LoadFrontendMapV6(svcs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fe).To(HaveLen(2), "the IPv4 external IP should be ignored")
+
+		val, ok := fe[nat.NewNATKeyV6(svcIP, 1234, 6)]
+		Expect(ok).To(BeTrue())
+		Expect(val.Count()).To(Equal(uint32(2)))
+		Expect(val.LocalCount()).To(Equal(uint32(1)))
+
+		extVal, ok := fe[nat.NewNATKeyV6(extIP, 1234, 6)]
+		Expect(ok).To(BeTrue())
+		Expect(extVal.Count()).To(Equal(uint32(2)))
+
+		be, err := nat.LoadBackendMapV6(eps)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be).To(HaveLen(4))
+		Expect(be[nat.NewNATBackendKey(val.ID(), 0)]).To(Equal(
+			nat.NewNATBackendValueV6(net.ParseIP("fd00:10::2"), 5555)), "local backend should be first")
+		Expect(be[nat.NewNATBackendKey(val.ID(), 1)]).To(Equal(
+			nat.NewNATBackendValueV6(net.ParseIP("fd00:10::1"), 5555)))
+
+		s.ConntrackScanStart()
+		Expect(s.ConntrackFrontendHasBackend(svcIP, 1234, net.ParseIP("fd00:10::1"), 5555, 6)).To(BeTrue())
+		Expect(s.ConntrackFrontendHasBackend(svcIP, 1234, net.ParseIP("fd00:10::3"), 5555, 6)).To(BeFalse())
+		s.ConntrackScanEnd()
+
+		By("removing the service")
+		Expect(s.Apply(proxy.DPSyncerState{
+			SvcMap: k8sp.ServiceMap{},
+			EpsMap: k8sp.EndpointsMap{},
+		})).To(Succeed())
+		Expect(svcs.Contents).To(BeEmpty())
+		Expect(eps.Contents).To(BeEmpty())
+	})
+
+	It("should keep the IDs of existing frontends after a restart", func() {
+		state := proxy.DPSyncerState{
+			SvcMap: k8sp.ServiceMap{
+				svcKey: proxy.NewK8sServicePort(svcIP, 1234, v1.ProtocolUDP),
+			},
+			EpsMap: k8sp.EndpointsMap{
+				svcKey: []k8sp.Endpoint{&k8sp.BaseEndpointInfo{Endpoint: "[fd00:10::1]:53"}},
+			},
+		}
+		Expect(s.Apply(state)).To(Succeed())
+		fe, err := nat.LoadFrontendMapV6(svcs)
+		Expect(err).NotTo(HaveOccurred())
+
+		s2, err := proxy.NewSyncerV6(
+			cachingmap.New(nat.FrontendMapV6Parameters, svcs),
+			cachingmap.New(nat.BackendMapV6Parameters, eps),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		updates := svcs.UpdateCount + eps.UpdateCount
+		Expect(s2.Apply(state)).To(Succeed())
+		Expect(svcs.UpdateCount+eps.UpdateCount).To(Equal(updates), "nothing should have been rewritten")
+
+		fe2, err := nat.LoadFrontendMapV6(svcs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fe2).To(Equal(fe))
+	})
+})
@@ -0,0 +1,78 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rulecounters
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/projectcalico/felix/bpf"
+	"github.com/projectcalico/felix/bpf/audit"
+)
+
+// The rule counters map holds packet and byte counters for each policy rule.  When enabled, the
+// policy program increments the counters of a rule each time the rule matches a packet, before
+// taking the rule's action.
+//
+// WARNING: must be kept in sync with the definitions in bpf/polprog/pol_prog_builder.go.
+// uint64 counter ID HE  8
+const KeySize = 8
+
+// uint64 packet count HE  8
+// uint64 byte count   HE  8
+const ValueSize = 16
+
+func Map(mc *bpf.MapContext) bpf.Map {
+	return mc.NewPinnedMap(bpf.MapParameters{
+		Filename:   "/sys/fs/bpf/tc/globals/cali_v4_rule_ctrs",
+		Type:       "hash",
+		KeySize:    KeySize,
+		ValueSize:  ValueSize,
+		MaxEntries: 64 * 1024,
+		Name:       "cali_v4_rule_ctrs",
+		Flags:      unix.BPF_F_NO_PREALLOC,
+	})
+}
+
+// CounterID returns the ID of the counters for the rule with the given ID.  It uses the same
+// hash as the audit map.
+func CounterID(ruleID string) uint64 {
+	return audit.RuleCounterID(ruleID)
+}
+
+type Counters struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// ReadCounters returns the current value of the counters in the map, indexed by counter ID.  If
+// isActive is non-nil, it deletes the entries for which isActive returns false so that the map
+// doesn't fill up with the counters of rules that no longer exist.
+func ReadCounters(m bpf.Map, isActive func(counterID uint64) bool) (map[uint64]Counters, error) {
+	counters := map[uint64]Counters{}
+	err := m.Iter(func(k, v []byte) bpf.IteratorAction {
+		id := binary.LittleEndian.Uint64(k)
+		if isActive != nil && !isActive(id) {
+			return bpf.IterDelete
+		}
+		counters[id] = Counters{
+			Packets: binary.LittleEndian.Uint64(v[0:8]),
+			Bytes:   binary.LittleEndian.Uint64(v[8:16]),
+		}
+		return bpf.IterNone
+	})
+	return counters, err
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"unsafe"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/bpf"
+)
+
+// struct cali_tc_state_v6 {
+//    ipv6_addr_t ip_src; 0
+//    ipv6_addr_t ip_dst; 16
+//    ipv6_addr_t pre_nat_ip_dst; 32
+//    ipv6_addr_t post_nat_ip_dst; 48
+//    __s32 pol_rc; 64
+//    __u16 sport; 68
+//    __u16 dport; 70
+//    __u16 pre_nat_dport; 72
+//    __u16 post_nat_dport; 74
+//    __u8 ip_proto; 76
+//    __u8 flags; 77
+//    __u8 pad[2]; 78
+//    struct calico_ct_result_v6 ct_result; 80
+//    struct calico_nat_dest_v6 nat_dest; 112
+//    __u64 prog_start_time; 136
+// };
+type StateV6 struct {
+	SrcAddr             [16]byte
+	DstAddr             [16]byte
+	PreNATDstAddr       [16]byte
+	PostNATDstAddr      [16]byte
+	PolicyRC            PolicyResult
+	SrcPort             uint16
+	DstPort             uint16
+	PreNATDstPort       uint16
+	PostNATDstPort      uint16
+	IPProto             uint8
+	Flags               uint8
+	_                   [2]byte
+	ConntrackRCFlags    uint32
+	ConntrackNATIP      [16]byte
+	ConntrackNATPort    uint32
+	ConntrackIfIndexFwd uint32
+	ConntrackIfIndexCtd uint32
+	NATDstAddr          [16]byte
+	NATDstPort          uint16
+	_                   [6]byte
+	ProgStartTime       uint64
+}
+
+const expectedSizeV6 = 144
+
+func (s *StateV6) AsBytes() []byte {
+	size := unsafe.Sizeof(StateV6{})
+	if size != expectedSizeV6 {
+		log.WithField("size", size).Panic("Incorrect struct size")
+	}
+	bPtr := (*[expectedSizeV6]byte)(unsafe.Pointer(s))
+	bytes := make([]byte, expectedSizeV6)
+	copy(bytes, bPtr[:])
+	return bytes
+}
+
+func StateV6FromBytes(bytes []byte) StateV6 {
+	s := StateV6{}
+	bPtr := (*[expectedSizeV6]byte)(unsafe.Pointer(&s))
+	copy(bPtr[:], bytes)
+	return s
+}
+
+func MapV6(mc *bpf.MapContext) bpf.Map {
+	return mc.NewPinnedMap(bpf.MapParameters{
+		Filename:   "/sys/fs/bpf/tc/globals/cali_v6_state",
+		Type:       "percpu_array",
+		KeySize:    4,
+		ValueSize:  expectedSizeV6,
+		MaxEntries: 1,
+		Name:       "cali_v6_state",
+	})
+}
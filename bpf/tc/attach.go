@@ -53,6 +53,12 @@ type AttachPoint struct {
 	TunnelMTU            uint16
 	VXLANPort            uint16
 	ExtToServiceConnmark uint32
+	// IPv6 selects the IPv6 program, which is attached alongside the IPv4 one with a
+	// "protocol ipv6" filter.
+	IPv6 bool
+	// IPv6Enabled tells the IPv4 program that the IPv6 program is attached so that it passes
+	// IPv6 packets on to it.
+	IPv6Enabled bool
 }
 
 var tcLock sync.RWMutex
@@ -94,10 +100,12 @@ func (ap AttachPoint) AttachProgram() error {
 		return err
 	}
 
-	_, err = ExecTC("filter", "add", "dev", ap.Iface, string(ap.Hook),
-		"bpf", "da", "obj", tempBinary,
-		"sec", SectionName(ap.Type, ap.ToOrFrom),
-	)
+	args := []string{"filter", "add", "dev", ap.Iface, string(ap.Hook)}
+	if ap.IPv6 {
+		args = append(args, "protocol", "ipv6")
+	}
+	args = append(args, "bpf", "da", "obj", tempBinary, "sec", ap.ProgramName())
+	_, err = ExecTC(args...)
 	if err != nil {
 		return err
 	}
@@ -166,11 +174,16 @@ func (ap AttachPoint) listAttachedPrograms() ([]attachedProg, error) {
 	}
 	// Lines look like this; the section name always includes calico.
 	// filter protocol all pref 49152 bpf chain 0 handle 0x1 to_hep_no_log.o:[calico_to_host_ep] direct-action not_in_hw id 821 tag ee402594f8f85ac3 jited
+	// The IPv6 programs' object and section names end in _v6; we only return the programs of
+	// our own IP family.
 	var progsToClean []attachedProg
 	for _, line := range strings.Split(string(out), "\n") {
 		if !strings.Contains(line, "calico") {
 			continue
 		}
+		if strings.Contains(line, "_v6") != ap.IPv6 {
+			continue
+		}
 		// find the pref and the handle
 		if sm := prefHandleRe.FindStringSubmatch(line); len(sm) > 0 {
 			p := attachedProg{
@@ -190,6 +203,13 @@ func (ap AttachPoint) patchBinary(logCtx *log.Entry, ifile, ofile string) error
 		return fmt.Errorf("failed to read pre-compiled BPF binary: %w", err)
 	}
 
+	if ap.IPv6 {
+		// The IPv6 programs only use the log prefix and the to-service mark.
+		b.PatchLogPrefix(ap.Iface)
+		b.PatchExtToServiceConnmark(ap.ExtToServiceConnmark)
+		return b.WriteToFile(ofile)
+	}
+
 	logCtx.WithField("ip", ap.HostIP).Debug("Patching in IP")
 	err = b.PatchIPv4(ap.HostIP)
 	if err != nil {
@@ -204,6 +224,7 @@ func (ap AttachPoint) patchBinary(logCtx *log.Entry, ifile, ofile string) error
 	}
 	b.PatchVXLANPort(vxlanPort)
 	b.PatchExtToServiceConnmark(uint32(ap.ExtToServiceConnmark))
+	b.PatchIPv6Enabled(ap.IPv6Enabled)
 
 	err = b.PatchIntfAddr(ap.IntfIP)
 	if err != nil {
@@ -220,12 +241,12 @@ func (ap AttachPoint) patchBinary(logCtx *log.Entry, ifile, ofile string) error
 
 // ProgramName returns the name of the program associated with this AttachPoint
 func (ap AttachPoint) ProgramName() string {
-	return SectionName(ap.Type, ap.ToOrFrom)
+	return SectionName(ap.Type, ap.ToOrFrom, ap.IPv6)
 }
 
 // FileName return the file the AttachPoint will load the program from
 func (ap AttachPoint) FileName() string {
-	return ProgFilename(ap.Type, ap.ToOrFrom, ap.ToHostDrop, ap.FIB, ap.DSR, ap.LogLevel)
+	return ProgFilename(ap.Type, ap.ToOrFrom, ap.ToHostDrop, ap.FIB, ap.DSR, ap.LogLevel, ap.IPv6)
 }
 
 func (ap AttachPoint) IsAttached() (bool, error) {
@@ -48,11 +48,15 @@ const (
 	EpTypeWireguard EndpointType = "wireguard"
 )
 
-func SectionName(endpointType EndpointType, fromOrTo ToOrFromEp) string {
-	return fmt.Sprintf("calico_%s_%s_ep", fromOrTo, endpointType)
+func SectionName(endpointType EndpointType, fromOrTo ToOrFromEp, ipv6 bool) string {
+	name := fmt.Sprintf("calico_%s_%s_ep", fromOrTo, endpointType)
+	if ipv6 {
+		name += "_v6"
+	}
+	return name
 }
 
-func ProgFilename(epType EndpointType, toOrFrom ToOrFromEp, epToHostDrop, fib, dsr bool, logLevel string) string {
+func ProgFilename(epType EndpointType, toOrFrom ToOrFromEp, epToHostDrop, fib, dsr bool, logLevel string, ipv6 bool) string {
 	if epToHostDrop && (epType != EpTypeWorkload || toOrFrom == ToEp) {
 		// epToHostDrop only makes sense in the from-workload program.
 		logrus.Debug("Ignoring epToHostDrop, doesn't apply to this target")
@@ -91,6 +95,10 @@ func ProgFilename(epType EndpointType, toOrFrom ToOrFromEp, epToHostDrop, fib, d
 	case EpTypeWireguard:
 		epTypeShort = "wg"
 	}
+	if ipv6 {
+		// The IPv6 programs don't support the FIB or DSR.
+		return fmt.Sprintf("%v_%v_%s%v_v6.o", toOrFrom, epTypeShort, hostDropPart, logLevel)
+	}
 	oFileName := fmt.Sprintf("%v_%v_%s%s%s%v.o",
 		toOrFrom, epTypeShort, hostDropPart, fibPart, dsrPart, logLevel)
 	return oFileName
@@ -39,6 +39,7 @@ import (
 
 	"github.com/projectcalico/felix/bpf"
 	"github.com/projectcalico/felix/bpf/arp"
+	"github.com/projectcalico/felix/bpf/audit"
 	"github.com/projectcalico/felix/bpf/conntrack"
 	"github.com/projectcalico/felix/bpf/failsafes"
 	"github.com/projectcalico/felix/bpf/ipsets"
@@ -221,6 +222,7 @@ outter:
 	Expect(err).NotTo(HaveOccurred())
 	bin.PatchTunnelMTU(natTunnelMTU)
 	bin.PatchVXLANPort(testVxlanPort)
+	bin.PatchIPv6Enabled(false)
 	tempObj := tempDir + "bpf.o"
 	err = bin.WriteToFile(tempObj)
 	Expect(err).NotTo(HaveOccurred())
@@ -235,7 +237,7 @@ outter:
 
 	if rules != nil {
 		alloc := &forceAllocator{alloc: idalloc.New()}
-		pg := polprog.NewBuilder(alloc, ipsMap.MapFD(), stateMap.MapFD(), jumpMap.MapFD())
+		pg := polprog.NewBuilder(alloc, ipsMap.MapFD(), stateMap.MapFD(), jumpMap.MapFD(), auditMap.MapFD())
 		insns, err := pg.Instructions(*rules)
 		Expect(err).NotTo(HaveOccurred())
 		polProgFD, err := bpf.LoadBPFProgramFromInsns(insns, "Apache-2.0")
@@ -294,8 +296,11 @@ func bpftool(args ...string) ([]byte, error) {
 var (
 	mapInitOnce sync.Once
 
-	natMap, natBEMap, ctMap, rtMap, ipsMap, stateMap, testStateMap, jumpMap, affinityMap, arpMap, fsafeMap bpf.Map
-	allMaps, progMaps                                                                                      []bpf.Map
+	natMap, natBEMap, ctMap, rtMap, ipsMap, stateMap, testStateMap, jumpMap, affinityMap, arpMap, fsafeMap, auditMap bpf.Map
+	allMaps, progMaps                                                                                                []bpf.Map
+
+	natMapV6, natBEMapV6, ctMapV6, rtMapV6, ipsMapV6, stateMapV6, arpMapV6 bpf.Map
+	progMapsV6                                                             []bpf.Map
 )
 
 func initMapsOnce() {
@@ -313,8 +318,18 @@ func initMapsOnce() {
 		affinityMap = nat.AffinityMap(mc)
 		arpMap = arp.Map(mc)
 		fsafeMap = failsafes.Map(mc)
-
-		allMaps = []bpf.Map{natMap, natBEMap, ctMap, rtMap, ipsMap, stateMap, testStateMap, jumpMap, affinityMap, arpMap, fsafeMap}
+		auditMap = audit.Map(mc)
+
+		natMapV6 = nat.FrontendMapV6(mc)
+		natBEMapV6 = nat.BackendMapV6(mc)
+		ctMapV6 = conntrack.MapV6(mc)
+		rtMapV6 = routes.MapV6(mc)
+		ipsMapV6 = ipsets.MapV6(mc)
+		stateMapV6 = state.MapV6(mc)
+		arpMapV6 = arp.MapV6(mc)
+
+		allMaps = []bpf.Map{natMap, natBEMap, ctMap, rtMap, ipsMap, stateMap, testStateMap, jumpMap, affinityMap, arpMap, fsafeMap, auditMap,
+			natMapV6, natBEMapV6, ctMapV6, rtMapV6, ipsMapV6, stateMapV6, arpMapV6}
 		for _, m := range allMaps {
 			err := m.EnsureExists()
 			if err != nil {
@@ -334,6 +349,15 @@ func initMapsOnce() {
 			fsafeMap,
 		}
 
+		progMapsV6 = []bpf.Map{
+			natMapV6,
+			natBEMapV6,
+			ctMapV6,
+			rtMapV6,
+			jumpMap,
+			stateMapV6,
+			arpMapV6,
+		}
 	})
 }
 
@@ -345,7 +369,7 @@ func cleanUpMaps() {
 	defer log.SetLevel(logLevel)
 
 	for _, m := range allMaps {
-		if m == stateMap || m == testStateMap || m == jumpMap {
+		if m == stateMap || m == testStateMap || m == jumpMap || m == stateMapV6 {
 			continue // Can't clean up array maps
 		}
 		log.WithField("map", m.GetName()).Info("Cleaning")
@@ -511,6 +535,7 @@ outter:
 	Expect(err).NotTo(HaveOccurred())
 	bin.PatchTunnelMTU(natTunnelMTU)
 	bin.PatchVXLANPort(testVxlanPort)
+	bin.PatchIPv6Enabled(false)
 	tempObj := tempDir + "bpf.o"
 	err = bin.WriteToFile(tempObj)
 	Expect(err).NotTo(HaveOccurred())
@@ -944,7 +969,7 @@ func TestJumpMap(t *testing.T) {
 	RegisterTestingT(t)
 
 	jumpMapFD := jumpMap.MapFD()
-	pg := polprog.NewBuilder(idalloc.New(), ipsMap.MapFD(), stateMap.MapFD(), jumpMapFD)
+	pg := polprog.NewBuilder(idalloc.New(), ipsMap.MapFD(), stateMap.MapFD(), jumpMapFD, auditMap.MapFD())
 	rules := polprog.Rules{}
 	insns, err := pg.Instructions(rules)
 	Expect(err).NotTo(HaveOccurred())
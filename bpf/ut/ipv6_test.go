@@ -0,0 +1,328 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ut_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/bpf"
+	"github.com/projectcalico/felix/bpf/conntrack"
+	"github.com/projectcalico/felix/bpf/nat"
+	"github.com/projectcalico/felix/bpf/polprog"
+	"github.com/projectcalico/felix/bpf/routes"
+	"github.com/projectcalico/felix/idalloc"
+	"github.com/projectcalico/felix/ip"
+)
+
+var (
+	srcIPv6     = net.ParseIP("fd00::1:1")
+	dstIPv6     = net.ParseIP("fd00::2:2")
+	srcV6CIDR   = ip.CIDRFromNetIP(srcIPv6).(ip.V6CIDR)
+	ipv6Default = &layers.IPv6{
+		Version:    6,
+		HopLimit:   64,
+		SrcIP:      srcIPv6,
+		DstIP:      dstIPv6,
+		NextHeader: layers.IPProtocolUDP,
+	}
+
+	rtKeySrcV6        = routes.NewKeyV6(srcV6CIDR).AsBytes()
+	rtValGoodV6       = routes.NewValueV6WithIfIndex(routes.FlagsLocalWorkload, 1).AsBytes()
+	rtValWrongIfaceV6 = routes.NewValueV6WithIfIndex(routes.FlagsLocalWorkload, 2).AsBytes()
+)
+
+func TestIPv6CompileTemplateRun(t *testing.T) {
+	runBpfTestV6(t, "calico_to_workload_ep_v6", &polprog.Rules{}, func(bpfrun bpfProgRunFn) {
+		_, _, _, pktBytes, err := testPacketV6UDPDefault()
+		Expect(err).NotTo(HaveOccurred())
+
+		res, err := bpfrun(pktBytes)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Implicitly denied by normal policy: DROP
+		Expect(res.Retval).To(Equal(resTC_ACT_SHOT))
+	})
+}
+
+func TestIPv6WorkloadSpoof(t *testing.T) {
+	RegisterTestingT(t)
+	cleanUpMaps()
+	defer cleanUpMaps()
+
+	t.Log("Missing return route -> DROP")
+	runSpoofTestV6(t, resTC_ACT_SHOT)
+	cleanUpMaps()
+
+	t.Log("Correct return route -> ALLOW")
+	err := rtMapV6.Update(rtKeySrcV6, rtValGoodV6)
+	Expect(err).NotTo(HaveOccurred())
+	runSpoofTestV6(t, resTC_ACT_UNSPEC)
+	cleanUpMaps()
+
+	t.Log("Incorrect if_index -> DROP")
+	err = rtMapV6.Update(rtKeySrcV6, rtValWrongIfaceV6)
+	Expect(err).NotTo(HaveOccurred())
+	runSpoofTestV6(t, resTC_ACT_SHOT)
+	cleanUpMaps()
+}
+
+func runSpoofTestV6(t *testing.T, expRC int) {
+	_, _, _, pktBytes, err := testPacketV6UDPDefault()
+	Expect(err).NotTo(HaveOccurred())
+	runBpfTestV6(t, "calico_from_workload_ep_v6", rulesDefaultAllow, func(bpfrun bpfProgRunFn) {
+		res, err := bpfrun(pktBytes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Retval).To(Equal(expRC))
+	})
+}
+
+func TestIPv6ConntrackAndNAT(t *testing.T) {
+	RegisterTestingT(t)
+	cleanUpMaps()
+	defer cleanUpMaps()
+
+	bpfIfaceName = "V6NAT"
+	defer func() { bpfIfaceName = "" }()
+
+	ipv6, l4, _, pktBytes, err := testPacketV6UDPDefault()
+	Expect(err).NotTo(HaveOccurred())
+	udp := l4.(*layers.UDP)
+
+	err = rtMapV6.Update(rtKeySrcV6, rtValGoodV6)
+	Expect(err).NotTo(HaveOccurred())
+
+	t.Log("No service -> ALLOW with a normal conntrack entry")
+	runBpfTestV6(t, "calico_from_workload_ep_v6", rulesDefaultAllow, func(bpfrun bpfProgRunFn) {
+		res, err := bpfrun(pktBytes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Retval).To(Equal(resTC_ACT_UNSPEC))
+		Expect(res.dataOut).To(Equal(pktBytes))
+	})
+
+	ct, err := conntrack.LoadMapMemV6(ctMapV6)
+	Expect(err).NotTo(HaveOccurred())
+	ctKey := conntrack.NewKeyV6(uint8(layers.IPProtocolUDP),
+		ipv6.SrcIP, uint16(udp.SrcPort), ipv6.DstIP, uint16(udp.DstPort))
+	Expect(ct).To(HaveKey(ctKey))
+	Expect(ct[ctKey].Type()).To(Equal(conntrack.TypeNormal))
+
+	resetMap(ctMapV6)
+
+	t.Log("Service -> DNAT to the backend")
+	natIP := net.ParseIP("fd00::8:8")
+	natPort := uint16(666)
+
+	err = natMapV6.Update(
+		nat.NewNATKeyV6(ipv6.DstIP, uint16(udp.DstPort), uint8(layers.IPProtocolUDP)).AsBytes(),
+		nat.NewNATValue(0, 1, 0, 0).AsBytes(),
+	)
+	Expect(err).NotTo(HaveOccurred())
+	err = natBEMapV6.Update(
+		nat.NewNATBackendKey(0, 0).AsBytes(),
+		nat.NewNATBackendValueV6(natIP, natPort).AsBytes(),
+	)
+	Expect(err).NotTo(HaveOccurred())
+
+	runBpfTestV6(t, "calico_from_workload_ep_v6", rulesDefaultAllow, func(bpfrun bpfProgRunFn) {
+		res, err := bpfrun(pktBytes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Retval).To(Equal(resTC_ACT_UNSPEC))
+
+		pktR := gopacket.NewPacket(res.dataOut, layers.LayerTypeEthernet, gopacket.Default)
+		fmt.Printf("pktR = %+v\n", pktR)
+
+		ipv6L := pktR.Layer(layers.LayerTypeIPv6)
+		Expect(ipv6L).NotTo(BeNil())
+		ipv6R := ipv6L.(*layers.IPv6)
+		Expect(ipv6R.SrcIP.String()).To(Equal(ipv6.SrcIP.String()))
+		Expect(ipv6R.DstIP.String()).To(Equal(natIP.String()))
+
+		udpL := pktR.Layer(layers.LayerTypeUDP)
+		Expect(udpL).NotTo(BeNil())
+		udpR := udpL.(*layers.UDP)
+		Expect(udpR.SrcPort).To(Equal(udp.SrcPort))
+		Expect(udpR.DstPort).To(Equal(layers.UDPPort(natPort)))
+	})
+
+	ct, err = conntrack.LoadMapMemV6(ctMapV6)
+	Expect(err).NotTo(HaveOccurred())
+	fwdKey := conntrack.NewKeyV6(uint8(layers.IPProtocolUDP),
+		ipv6.SrcIP, uint16(udp.SrcPort), ipv6.DstIP, uint16(udp.DstPort))
+	Expect(ct).To(HaveKey(fwdKey))
+	Expect(ct[fwdKey].Type()).To(Equal(conntrack.TypeNATForward))
+
+	revKey := ct[fwdKey].ReverseNATKey()
+	Expect(ct).To(HaveKey(revKey))
+	Expect(ct[revKey].Type()).To(Equal(conntrack.TypeNATReverse))
+	Expect(ct[revKey].OrigIP().String()).To(Equal(ipv6.DstIP.String()))
+	Expect(ct[revKey].OrigPort()).To(Equal(uint16(udp.DstPort)))
+}
+
+// runBpfTestV6 is the IPv6 equivalent of runBpfTest; it loads the IPv6 UT program for the given
+// section, along with the IPv6 maps, and an IPv6 policy program for the rules.
+func runBpfTestV6(t *testing.T, section string, rules *polprog.Rules, testFn func(bpfProgRunFn)) {
+	RegisterTestingT(t)
+
+	tempDir, err := ioutil.TempDir("", "calico-bpf-")
+	Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(tempDir)
+
+	unique := path.Base(tempDir)
+	bpfFsDir := "/sys/fs/bpf/" + unique
+
+	err = os.Mkdir(bpfFsDir, os.ModePerm)
+	Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(bpfFsDir)
+
+	obj := "../../bpf-gpl/bin/test_"
+	if strings.Contains(section, "from") {
+		obj += "from_"
+	} else {
+		obj += "to_"
+	}
+
+	progLog := ""
+	if strings.Contains(section, "host") {
+		obj += "hep_"
+		progLog = "HEP"
+	} else {
+		obj += "wep_"
+		progLog = "WEP"
+	}
+	obj += "debug_skb0x0_v6.o"
+	log.Infof("Patching binary %s", obj)
+
+	bin, err := bpf.BinaryFromFile(obj)
+	Expect(err).NotTo(HaveOccurred())
+	bin.PatchLogPrefix(progLog + "-" + bpfIfaceName)
+	tempObj := tempDir + "bpf.o"
+	err = bin.WriteToFile(tempObj)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = bpftoolProgLoadAllV6(tempObj, bpfFsDir, progMapsV6...)
+	Expect(err).NotTo(HaveOccurred())
+
+	alloc := &forceAllocator{alloc: idalloc.New()}
+	pg := polprog.NewBuilder(alloc, ipsMapV6.MapFD(), stateMapV6.MapFD(), jumpMap.MapFD(), auditMap.MapFD(),
+		polprog.WithIPv6())
+	insns, err := pg.Instructions(*rules)
+	Expect(err).NotTo(HaveOccurred())
+	polProgFD, err := bpf.LoadBPFProgramFromInsns(insns, "Apache-2.0")
+	Expect(err).NotTo(HaveOccurred())
+	defer func() { _ = polProgFD.Close() }()
+	progFDBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(progFDBytes, uint32(polProgFD))
+	err = jumpMap.Update([]byte{0, 0, 0, 0}, progFDBytes)
+	Expect(err).NotTo(HaveOccurred())
+
+	progName := bpfFsDir + "/" + section
+	t.Run(section, func(_ *testing.T) {
+		testFn(func(dataIn []byte) (bpfRunResult, error) {
+			res, err := bpftoolProgRun(progName, dataIn)
+			log.Debugf("dataIn  = %+v", dataIn)
+			if err == nil {
+				log.Debugf("dataOut = %+v", res.dataOut)
+			}
+			return res, err
+		})
+	})
+}
+
+// bpftoolProgLoadAllV6 loads the IPv6 program and hooks up its epilogue.  Unlike the IPv4
+// programs, there is no ICMP program to hook up.
+func bpftoolProgLoadAllV6(fname, bpfFsDir string, maps ...bpf.Map) error {
+	args := []string{"prog", "loadall", fname, bpfFsDir, "type", "classifier"}
+
+	for _, m := range maps {
+		args = append(args, "map", "name", m.GetName(), "pinned", m.Path())
+	}
+
+	log.WithField("program", fname).Debug("Loading BPF program")
+	_, err := bpftool(args...)
+	if err != nil {
+		return err
+	}
+
+	_, err = bpftool("map", "update", "pinned", jumpMap.Path(), "key", "1", "0", "0", "0", "value", "pinned", path.Join(bpfFsDir, "1_1"))
+	if err != nil {
+		return errors.Wrap(err, "failed to update jump map (epilogue program)")
+	}
+
+	return nil
+}
+
+func testPacketV6(ipv6Alt *layers.IPv6, l4Alt gopacket.Layer, payloadAlt []byte) (
+	*layers.IPv6, gopacket.Layer, []byte, []byte, error) {
+
+	ipv6 := new(layers.IPv6)
+	if ipv6Alt != nil {
+		*ipv6 = *ipv6Alt
+	} else {
+		*ipv6 = *ipv6Default
+	}
+
+	payload := payloadDefault
+	if payloadAlt != nil {
+		payload = payloadAlt
+	}
+
+	var l4 gopacket.SerializableLayer
+	switch v := l4Alt.(type) {
+	case nil:
+		udp := *udpDefault
+		udp.Length = uint16(8 + len(payload))
+		ipv6.NextHeader = layers.IPProtocolUDP
+		_ = udp.SetNetworkLayerForChecksum(ipv6)
+		l4Alt = &udp
+		l4 = &udp
+	case *layers.UDP:
+		v.Length = uint16(8 + len(payload))
+		ipv6.NextHeader = layers.IPProtocolUDP
+		_ = v.SetNetworkLayerForChecksum(ipv6)
+		l4 = v
+	case *layers.TCP:
+		ipv6.NextHeader = layers.IPProtocolTCP
+		_ = v.SetNetworkLayerForChecksum(ipv6)
+		l4 = v
+	default:
+		return nil, nil, nil, nil, errors.Errorf("unrecognized l4 layer type %t", l4Alt)
+	}
+
+	eth := *ethDefault
+	eth.EthernetType = layers.EthernetTypeIPv6
+
+	pkt := gopacket.NewSerializeBuffer()
+	err := gopacket.SerializeLayers(pkt, gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true},
+		&eth, ipv6, l4, gopacket.Payload(payload))
+
+	return ipv6, l4Alt, payload, pkt.Bytes(), err
+}
+
+func testPacketV6UDPDefault() (*layers.IPv6, gopacket.Layer, []byte, []byte, error) {
+	return testPacketV6(nil, nil, nil)
+}
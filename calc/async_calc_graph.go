@@ -86,6 +86,12 @@ type AsyncCalcGraph struct {
 	dirty            bool
 
 	debugHangC <-chan time.Time
+
+	recorder *UpdateRecorder
+
+	ruleAnalysisInterval time.Duration
+	ruleAnalysisTicks    <-chan time.Time
+	ruleAnalysisReporter *ruleAnalysisReporter
 }
 
 const (
@@ -106,12 +112,26 @@ func NewAsyncCalcGraph(
 		outputChannels:   outputChannels,
 		eventSequencer:   eventSequencer,
 		healthAggregator: healthAggregator,
+
+		ruleAnalysisInterval: conf.RuleAnalysisInterval,
+		ruleAnalysisReporter: newRuleAnalysisReporter(),
 	}
 	if conf.DebugSimulateCalcGraphHangAfter != 0 {
 		log.WithField("delay", conf.DebugSimulateCalcGraphHangAfter).Warn(
 			"Simulating a calculation graph hang.")
 		g.debugHangC = time.After(conf.DebugSimulateCalcGraphHangAfter)
 	}
+	if conf.DebugCalcGraphRecordingFile != "" {
+		recorder, err := NewUpdateRecorder(conf.DebugCalcGraphRecordingFile, conf)
+		if err != nil {
+			log.WithError(err).WithField("file", conf.DebugCalcGraphRecordingFile).Error(
+				"Failed to open calculation graph recording file; not recording.")
+		} else {
+			log.WithField("file", conf.DebugCalcGraphRecordingFile).Warn(
+				"Recording all calculation graph updates.")
+			g.recorder = recorder
+		}
+	}
 	eventSequencer.Callback = g.onEvent
 	if healthAggregator != nil {
 		healthAggregator.RegisterReporter(healthName, &health.HealthReport{Live: true, Ready: true}, healthInterval*2)
@@ -133,6 +153,44 @@ func (acg *AsyncCalcGraph) OnStatusUpdated(status api.SyncStatus) {
 	}
 }
 
+// graphQuery is sent to the calculation graph goroutine to run a read-only query against the graph.
+type graphQuery struct {
+	fn   func()
+	done chan struct{}
+}
+
+// runQuery runs fn on the calculation graph's goroutine, so that it sees a consistent view of the
+// graph's state, and waits for it to finish.
+func (acg *AsyncCalcGraph) runQuery(fn func()) {
+	q := graphQuery{fn: fn, done: make(chan struct{})}
+	acg.inputEvents <- q
+	<-q.done
+}
+
+// Explain evaluates the given packet against the current policy.  See CalcGraph.Explain.
+func (acg *AsyncCalcGraph) Explain(req *ExplainRequest) (explanation *Explanation, err error) {
+	acg.runQuery(func() {
+		explanation, err = acg.CalcGraph.Explain(req)
+	})
+	return
+}
+
+// Snapshot returns a snapshot of the calculation graph's state.  See CalcGraph.Snapshot.
+func (acg *AsyncCalcGraph) Snapshot() (snapshot *CalcGraphSnapshot) {
+	acg.runQuery(func() {
+		snapshot = acg.CalcGraph.Snapshot()
+	})
+	return
+}
+
+// AnalyseRules looks for shadowed rules and unreachable policies.  See CalcGraph.AnalyseRules.
+func (acg *AsyncCalcGraph) AnalyseRules() (analysis *RuleAnalysis) {
+	acg.runQuery(func() {
+		analysis = acg.CalcGraph.AnalyseRules()
+	})
+	return
+}
+
 func (acg *AsyncCalcGraph) loop() {
 	log.Info("AsyncCalcGraph running")
 	acg.reportHealth()
@@ -143,6 +201,9 @@ func (acg *AsyncCalcGraph) loop() {
 			case []api.Update:
 				// Update; send it to the dispatcher.
 				log.Debug("Pulled []KVPair off channel")
+				if acg.recorder != nil {
+					acg.recorder.RecordUpdates(update)
+				}
 				for i, upd := range update {
 					// Send the updates individually so that we can report live in between
 					// each update.  (The dispatcher sends individual updates anyway so this makes
@@ -160,6 +221,9 @@ func (acg *AsyncCalcGraph) loop() {
 				// Sync status changed, check if we're now in-sync.
 				log.WithField("status", update).Debug(
 					"Pulled status update off channel")
+				if acg.recorder != nil {
+					acg.recorder.RecordStatus(update)
+				}
 				acg.syncStatusNow = update
 				acg.AllUpdDispatcher.OnStatusUpdated(update)
 				if update == api.InSync && !acg.beenInSync {
@@ -173,6 +237,11 @@ func (acg *AsyncCalcGraph) loop() {
 					}
 				}
 				acg.reportHealth()
+			case graphQuery:
+				// Read-only query, doesn't make the graph dirty.
+				update.fn()
+				close(update.done)
+				continue
 			default:
 				log.Panicf("Unexpected update: %#v", update)
 			}
@@ -184,6 +253,10 @@ func (acg *AsyncCalcGraph) loop() {
 			}
 		case <-acg.healthTicks:
 			acg.reportHealth()
+		case <-acg.ruleAnalysisTicks:
+			if acg.beenInSync {
+				acg.ruleAnalysisReporter.report(acg.CalcGraph.AnalyseRules())
+			}
 		case <-acg.debugHangC:
 			log.Warning("Debug hang simulation timer popped, hanging the calculation graph!!")
 			time.Sleep(1 * time.Hour)
@@ -255,5 +328,8 @@ func (acg *AsyncCalcGraph) Start() {
 	log.Info("Starting AsyncCalcGraph")
 	acg.flushTicks = time.NewTicker(tickInterval).C
 	acg.healthTicks = time.NewTicker(healthInterval).C
+	if acg.ruleAnalysisInterval > 0 {
+		acg.ruleAnalysisTicks = time.NewTicker(acg.ruleAnalysisInterval).C
+	}
 	go acg.loop()
 }
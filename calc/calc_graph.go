@@ -46,6 +46,7 @@ func init() {
 
 type ipSetUpdateCallbacks interface {
 	OnIPSetAdded(setID string, ipSetType proto.IPSetUpdate_IPSetType)
+	OnDomainIPSetAdded(setID string, domains []string)
 	OnIPSetMemberAdded(setID string, ip labelindex.IPSetMember)
 	OnIPSetMemberRemoved(setID string, ip labelindex.IPSetMember)
 	OnIPSetRemoved(setID string)
@@ -78,7 +79,7 @@ type passthruCallbacks interface {
 	OnServiceAccountRemove(proto.ServiceAccountID)
 	OnNamespaceUpdate(*proto.NamespaceUpdate)
 	OnNamespaceRemove(proto.NamespaceID)
-	OnWireguardUpdate(string, *model.Wireguard)
+	OnWireguardUpdate(string, *model.Wireguard, *net.IP)
 	OnWireguardRemove(string)
 	OnGlobalBGPConfigUpdate(*v3.BGPConfiguration)
 }
@@ -107,6 +108,11 @@ type CalcGraph struct {
 	// AllUpdDispatcher is the input node to the calculation graph.
 	AllUpdDispatcher      *dispatcher.Dispatcher
 	activeRulesCalculator *ActiveRulesCalculator
+	ruleScanner           *RuleScanner
+	policyResolver        *PolicyResolver
+	ipsetMemberIndex      *labelindex.SelectorAndNamedPortIndex
+	l3RouteResolver       *L3RouteResolver
+	vxlanResolver         *VXLANResolver
 }
 
 func NewCalculationGraph(callbacks PipelineCallbacks, conf *config.Config) *CalcGraph {
@@ -227,13 +233,20 @@ func NewCalculationGraph(callbacks PipelineCallbacks, conf *config.Config) *Calc
 	ipsetMemberIndex.RegisterWith(allUpdDispatcher)
 	ruleScanner.OnIPSetActive = func(ipSet *IPSetData) {
 		log.WithField("ipSet", ipSet).Info("IPSet now active")
-		callbacks.OnIPSetAdded(ipSet.UniqueID(), ipSet.DataplaneProtocolType())
-		ipsetMemberIndex.UpdateIPSet(ipSet.UniqueID(), ipSet.Selector, ipSet.NamedPortProtocol, ipSet.NamedPort)
+		if len(ipSet.Domains) > 0 {
+			// Domain-based IP sets are populated by the dataplane, from snooped DNS responses.
+			callbacks.OnDomainIPSetAdded(ipSet.UniqueID(), ipSet.Domains)
+		} else {
+			callbacks.OnIPSetAdded(ipSet.UniqueID(), ipSet.DataplaneProtocolType())
+			ipsetMemberIndex.UpdateIPSet(ipSet.UniqueID(), ipSet.Selector, ipSet.NamedPortProtocol, ipSet.NamedPort)
+		}
 		gaugeNumActiveSelectors.Inc()
 	}
 	ruleScanner.OnIPSetInactive = func(ipSet *IPSetData) {
 		log.WithField("ipSet", ipSet).Info("IPSet now inactive")
-		ipsetMemberIndex.DeleteIPSet(ipSet.UniqueID())
+		if len(ipSet.Domains) == 0 {
+			ipsetMemberIndex.DeleteIPSet(ipSet.UniqueID())
+		}
 		callbacks.OnIPSetRemoved(ipSet.UniqueID())
 		gaugeNumActiveSelectors.Dec()
 	}
@@ -301,6 +314,14 @@ func NewCalculationGraph(callbacks PipelineCallbacks, conf *config.Config) *Calc
 	hostIPPassthru := NewDataplanePassthru(callbacks)
 	hostIPPassthru.RegisterWith(allUpdDispatcher)
 
+	cg := &CalcGraph{
+		AllUpdDispatcher:      allUpdDispatcher,
+		activeRulesCalculator: activeRulesCalc,
+		ruleScanner:           ruleScanner,
+		policyResolver:        polResolver,
+		ipsetMemberIndex:      ipsetMemberIndex,
+	}
+
 	if conf.BPFEnabled || conf.VXLANEnabled || conf.WireguardEnabled {
 		// Calculate simple node-ownership routes.
 		//        ...
@@ -314,8 +335,10 @@ func NewCalculationGraph(callbacks PipelineCallbacks, conf *config.Config) *Calc
 		//         |
 		//      <dataplane>
 		//
-		l3RR := NewL3RouteResolver(hostname, callbacks, conf.UseNodeResourceUpdates(), conf.RouteSource)
+		l3RR := NewL3RouteResolver(hostname, callbacks, conf.UseNodeResourceUpdates(), conf.RouteSource,
+			conf.RouteMultipathEnabled)
 		l3RR.RegisterWith(allUpdDispatcher, localEndpointDispatcher)
+		cg.l3RouteResolver = l3RR
 	}
 
 	// Calculate VXLAN routes.
@@ -333,6 +356,7 @@ func NewCalculationGraph(callbacks PipelineCallbacks, conf *config.Config) *Calc
 	if conf.VXLANEnabled {
 		vxlanResolver := NewVXLANResolver(hostname, callbacks, conf.UseNodeResourceUpdates())
 		vxlanResolver.RegisterWith(allUpdDispatcher)
+		cg.vxlanResolver = vxlanResolver
 	}
 
 	// Register for config updates.
@@ -368,10 +392,7 @@ func NewCalculationGraph(callbacks PipelineCallbacks, conf *config.Config) *Calc
 	profileDecoder := NewProfileDecoder(callbacks)
 	profileDecoder.RegisterWith(allUpdDispatcher)
 
-	return &CalcGraph{
-		AllUpdDispatcher:      allUpdDispatcher,
-		activeRulesCalculator: activeRulesCalc,
-	}
+	return cg
 }
 
 type localEndpointDispatcherReg dispatcher.Dispatcher
@@ -268,10 +268,16 @@ var baseTests = []StateList{
 		// Add it back again.
 		vxlanWithBlock,
 
-		// Adding/removing IPv6 pool should cause no problems.
+		// Adding/removing a non-VXLAN IPv6 pool and block should only affect the IPv6 routes.
 		vxlanWithIPv6Resources,
 		vxlanWithBlock,
 	},
+	{
+		// IPv6 VXLAN, which needs node resources for the nodes' IPv6 addresses.
+		vxlanV6WithBlockNodeRes,
+		vxlanV6TunnelIPDeleteNodeRes,
+		vxlanV6WithBlockNodeRes,
+	},
 	{
 		// This sequence switches the IP pool between VXLAN and IPIP.
 		vxlanWithBlock,
@@ -346,6 +352,15 @@ var baseTests = []StateList{
 		vxlanWithWEPIPsAndWEPDuplicate,
 		vxlanWithWEPIPsAndWEP,
 	},
+	{
+		// As above but with multipath enabled, so that the workloads with the same IP on different nodes
+		// give an ECMP route, weighted by the number of workloads on each node.
+		vxlanWithWEPIPsMultipath,
+		vxlanWithWEPIPsAndWEPMultipath,
+		vxlanWithWEPIPsAndECMPWEPs,
+		vxlanWithWEPIPsAndWeightedECMPWEPs,
+		vxlanWithWEPIPsAndWEPMultipath,
+	},
 	{
 		// Test corner case where the IP pool and block share a /32.
 		// Should be able to add or remove the block or pool in either order and get the same result.
@@ -483,6 +498,7 @@ var _ = Describe("Async calculation graph state sequencing tests:", func() {
 					conf.BPFEnabled = true
 					conf.SetUseNodeResourceUpdates(test.UsesNodeResources())
 					conf.RouteSource = test.RouteSource()
+					conf.RouteMultipathEnabled = test.RouteMultipathEnabled()
 					outputChan := make(chan interface{})
 					asyncGraph := NewAsyncCalcGraph(conf, []chan<- interface{}{outputChan}, nil)
 					// And a validation filter, with a channel between it
@@ -623,6 +639,7 @@ func doStateSequenceTest(expandedTest StateList, flushStrategy flushStrategy) {
 		conf.BPFEnabled = true
 		conf.SetUseNodeResourceUpdates(expandedTest.UsesNodeResources())
 		conf.RouteSource = expandedTest.RouteSource()
+		conf.RouteMultipathEnabled = expandedTest.RouteMultipathEnabled()
 		mockDataplane = mock.NewMockDataplane()
 		eventBuf = NewEventSequencer(mockDataplane)
 		eventBuf.Callback = mockDataplane.OnEvent
@@ -24,6 +24,10 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/net"
 )
 
+// WireguardIPv6InterfaceAddrAnnotation is the Node annotation that holds the IPv6 address of the node's wireguard
+// interface.  The Node resource has no field for it, so Felix publishes the address it has configured here.
+const WireguardIPv6InterfaceAddrAnnotation = "projectcalico.org/WireguardInterfaceIPv6Address"
+
 // DataplanePassthru passes through some datamodel updates to the dataplane layer, removing some
 // duplicates along the way.  It maps OnUpdate() calls to dedicated method calls for consistency
 // with the rest of the dataplane API.
@@ -31,12 +35,19 @@ type DataplanePassthru struct {
 	callbacks passthruCallbacks
 
 	hostIPs map[string]*net.IP
+
+	// Wireguard configuration is sent as a combination of the Wireguard model and the IPv6 interface address from
+	// the Node annotation, so we cache both.
+	wireguard          map[string]*model.Wireguard
+	wireguardIPv6Addrs map[string]*net.IP
 }
 
 func NewDataplanePassthru(callbacks passthruCallbacks) *DataplanePassthru {
 	return &DataplanePassthru{
-		callbacks: callbacks,
-		hostIPs:   map[string]*net.IP{},
+		callbacks:          callbacks,
+		hostIPs:            map[string]*net.IP{},
+		wireguard:          map[string]*model.Wireguard{},
+		wireguardIPv6Addrs: map[string]*net.IP{},
 	}
 }
 
@@ -81,14 +92,18 @@ func (h *DataplanePassthru) OnUpdate(update api.Update) (filterOut bool) {
 	case model.WireguardKey:
 		if update.Value == nil {
 			log.WithField("update", update).Debug("Passing-through Wireguard deletion")
+			delete(h.wireguard, key.NodeName)
 			h.callbacks.OnWireguardRemove(key.NodeName)
 		} else {
 			log.WithField("update", update).Debug("Passing-through Wireguard update")
 			wg := update.Value.(*model.Wireguard)
-			h.callbacks.OnWireguardUpdate(key.NodeName, wg)
+			h.wireguard[key.NodeName] = wg
+			h.callbacks.OnWireguardUpdate(key.NodeName, wg, h.wireguardIPv6Addrs[key.NodeName])
 		}
 	case model.ResourceKey:
-		if key.Kind == v3.KindBGPConfiguration && key.Name == "default" {
+		if key.Kind == v3.KindNode {
+			h.onNodeUpdate(key.Name, update.Value)
+		} else if key.Kind == v3.KindBGPConfiguration && key.Name == "default" {
 			log.WithField("update", update).Debug("Passing through global BGPConfiguration")
 			bgpConfig, _ := update.Value.(*v3.BGPConfiguration)
 			h.callbacks.OnGlobalBGPConfigUpdate(bgpConfig)
@@ -98,3 +113,46 @@ func (h *DataplanePassthru) OnUpdate(update api.Update) (filterOut bool) {
 	}
 	return
 }
+
+// onNodeUpdate tracks the wireguard IPv6 interface address annotation on the node and resends the node's wireguard
+// configuration if it changes.
+func (h *DataplanePassthru) onNodeUpdate(nodeName string, value interface{}) {
+	var newAddr *net.IP
+	if node, ok := value.(*v3.Node); ok && node != nil {
+		newAddr = wireguardIPv6InterfaceAddr(node)
+	}
+	oldAddr := h.wireguardIPv6Addrs[nodeName]
+	if (oldAddr == nil && newAddr == nil) || (oldAddr != nil && newAddr != nil && oldAddr.IP.Equal(newAddr.IP)) {
+		return
+	}
+	if newAddr == nil {
+		delete(h.wireguardIPv6Addrs, nodeName)
+	} else {
+		h.wireguardIPv6Addrs[nodeName] = newAddr
+	}
+	if wg := h.wireguard[nodeName]; wg != nil {
+		log.WithFields(log.Fields{
+			"node": nodeName,
+			"addr": newAddr,
+		}).Debug("Wireguard IPv6 interface address changed, passing-through Wireguard update")
+		h.callbacks.OnWireguardUpdate(nodeName, wg, newAddr)
+	}
+}
+
+// wireguardIPv6InterfaceAddr returns the IPv6 wireguard interface address annotated on the node, or nil if the
+// node does not have a valid one.
+func wireguardIPv6InterfaceAddr(node *v3.Node) *net.IP {
+	addrStr := node.Annotations[WireguardIPv6InterfaceAddrAnnotation]
+	if addrStr == "" {
+		return nil
+	}
+	addr := net.ParseIP(addrStr)
+	if addr == nil || addr.Version() != 6 {
+		log.WithFields(log.Fields{
+			"node": node.Name,
+			"addr": addrStr,
+		}).Warn("Ignoring invalid wireguard IPv6 interface address")
+		return nil
+	}
+	return addr
+}
@@ -47,6 +47,7 @@ type EventSequencer struct {
 	// Buffers used to hold data that we haven't flushed yet so we can coalesce multiple
 	// updates and generate updates in dependency order.
 	pendingAddedIPSets           map[string]proto.IPSetUpdate_IPSetType
+	pendingIPSetDomains          map[string][]string
 	pendingRemovedIPSets         set.Set
 	pendingAddedIPSetMembers     multidict.StringToIface
 	pendingRemovedIPSetMembers   multidict.StringToIface
@@ -72,7 +73,7 @@ type EventSequencer struct {
 	pendingRouteDeletes          set.Set
 	pendingVTEPUpdates           map[string]*proto.VXLANTunnelEndpointUpdate
 	pendingVTEPDeletes           set.Set
-	pendingWireguardUpdates      map[string]*model.Wireguard
+	pendingWireguardUpdates      map[string]*proto.WireguardEndpointUpdate
 	pendingWireguardDeletes      set.Set
 	pendingGlobalBGPConfig       *proto.GlobalBGPConfigUpdate
 
@@ -106,6 +107,7 @@ func NewEventSequencer(conf configInterface) *EventSequencer {
 	buf := &EventSequencer{
 		config:                     conf,
 		pendingAddedIPSets:         map[string]proto.IPSetUpdate_IPSetType{},
+		pendingIPSetDomains:        map[string][]string{},
 		pendingRemovedIPSets:       set.New(),
 		pendingAddedIPSetMembers:   multidict.NewStringToIface(),
 		pendingRemovedIPSetMembers: multidict.NewStringToIface(),
@@ -129,7 +131,7 @@ func NewEventSequencer(conf configInterface) *EventSequencer {
 		pendingRouteDeletes:          set.New(),
 		pendingVTEPUpdates:           map[string]*proto.VXLANTunnelEndpointUpdate{},
 		pendingVTEPDeletes:           set.New(),
-		pendingWireguardUpdates:      map[string]*model.Wireguard{},
+		pendingWireguardUpdates:      map[string]*proto.WireguardEndpointUpdate{},
 		pendingWireguardDeletes:      set.New(),
 
 		// Sets to record what we've sent downstream.  Updated whenever we flush.
@@ -164,6 +166,14 @@ func (buf *EventSequencer) OnIPSetAdded(setID string, ipSetType proto.IPSetUpdat
 	buf.pendingRemovedIPSetMembers.DiscardKey(setID)
 }
 
+// OnDomainIPSetAdded is like OnIPSetAdded but for an IP set whose members are the addresses that
+// the given domains resolve to.  The dataplane fills in the members so the calculation graph never
+// sends any.
+func (buf *EventSequencer) OnDomainIPSetAdded(setID string, domains []string) {
+	buf.OnIPSetAdded(setID, proto.IPSetUpdate_IP)
+	buf.pendingIPSetDomains[setID] = domains
+}
+
 func (buf *EventSequencer) OnIPSetRemoved(setID string) {
 	log.Debugf("IP set %v no longer active", setID)
 	_, updatePending := buf.pendingAddedIPSets[setID]
@@ -174,6 +184,7 @@ func (buf *EventSequencer) OnIPSetRemoved(setID string) {
 		buf.pendingRemovedIPSets.Add(setID)
 	}
 	delete(buf.pendingAddedIPSets, setID)
+	delete(buf.pendingIPSetDomains, setID)
 	buf.pendingAddedIPSetMembers.DiscardKey(setID)
 	buf.pendingRemovedIPSetMembers.DiscardKey(setID)
 }
@@ -282,6 +293,7 @@ func ParsedRulesToActivePolicyUpdate(key model.PolicyKey, rules *ParsedRules) *p
 			),
 			Untracked: rules.Untracked,
 			PreDnat:   rules.PreDNAT,
+			AuditOnly: rules.AuditOnly,
 		},
 	}
 }
@@ -356,7 +368,7 @@ func ModelWorkloadEndpointToProto(ep *model.WorkloadEndpoint, tiers []*proto.Tie
 	if ep.Mac != nil {
 		mac = ep.Mac.String()
 	}
-	return &proto.WorkloadEndpoint{
+	protoEp := &proto.WorkloadEndpoint{
 		State:      ep.State,
 		Name:       ep.Name,
 		Mac:        mac,
@@ -367,6 +379,8 @@ func ModelWorkloadEndpointToProto(ep *model.WorkloadEndpoint, tiers []*proto.Tie
 		Ipv4Nat:    natsToProtoNatInfo(ep.IPv4NAT),
 		Ipv6Nat:    natsToProtoNatInfo(ep.IPv6NAT),
 	}
+	setBandwidthLimits(protoEp, ep.Labels)
+	return protoEp
 }
 
 func ModelHostEndpointToProto(ep *model.HostEndpoint, tiers, untrackedTiers, preDNATTiers []*proto.TierInfo, forwardTiers []*proto.TierInfo) *proto.HostEndpoint {
@@ -518,16 +532,8 @@ func (buf *EventSequencer) flushIPPoolUpdates() {
 }
 
 func (buf *EventSequencer) flushHostWireguardUpdates() {
-	for nodename, wg := range buf.pendingWireguardUpdates {
-		var ipstr string
-		if wg.InterfaceIPv4Addr != nil {
-			ipstr = wg.InterfaceIPv4Addr.String()
-		}
-		buf.Callback(&proto.WireguardEndpointUpdate{
-			Hostname:          nodename,
-			PublicKey:         wg.PublicKey,
-			InterfaceIpv4Addr: ipstr,
-		})
+	for nodename, upd := range buf.pendingWireguardUpdates {
+		buf.Callback(upd)
 		buf.sentWireguard.Add(nodename)
 		delete(buf.pendingWireguardUpdates, nodename)
 	}
@@ -579,9 +585,11 @@ func (buf *EventSequencer) flushAddedIPSets() {
 			Id:      setID,
 			Members: members,
 			Type:    setType,
+			Domains: buf.pendingIPSetDomains[setID],
 		})
 		buf.sentIPSets.Add(setID)
 		delete(buf.pendingAddedIPSets, setID)
+		delete(buf.pendingIPSetDomains, setID)
 	}
 }
 
@@ -751,12 +759,22 @@ func (buf *EventSequencer) OnNamespaceRemove(id proto.NamespaceID) {
 	}
 }
 
-func (buf *EventSequencer) OnWireguardUpdate(nodename string, wg *model.Wireguard) {
+func (buf *EventSequencer) OnWireguardUpdate(nodename string, wg *model.Wireguard, ipv6InterfaceAddr *net.IP) {
 	log.WithFields(log.Fields{
 		"nodename": nodename,
 	}).Debug("Wireguard updated")
+	upd := &proto.WireguardEndpointUpdate{
+		Hostname:  nodename,
+		PublicKey: wg.PublicKey,
+	}
+	if wg.InterfaceIPv4Addr != nil {
+		upd.InterfaceIpv4Addr = wg.InterfaceIPv4Addr.String()
+	}
+	if ipv6InterfaceAddr != nil {
+		upd.InterfaceIpv6Addr = ipv6InterfaceAddr.String()
+	}
 	buf.pendingWireguardDeletes.Discard(nodename)
-	buf.pendingWireguardUpdates[nodename] = wg
+	buf.pendingWireguardUpdates[nodename] = upd
 }
 
 func (buf *EventSequencer) OnWireguardRemove(nodename string) {
@@ -64,6 +64,31 @@ var _ = DescribeTable("ModelWorkloadEndpointToProto",
 		},
 		Ipv6Nat: []*proto.NatInfo{},
 	}),
+	Entry("workload endpoint with bandwidth limits", model.WorkloadEndpoint{
+		State:      "up",
+		Name:       "bill",
+		ProfileIDs: []string{},
+		IPv4Nets:   []net.IPNet{mustParseNet("10.28.0.13/32")},
+		IPv6Nets:   []net.IPNet{},
+		Labels: map[string]string{
+			calc.IngressBandwidthLabel: "10M",
+			calc.IngressBurstLabel:     "1Mi",
+			calc.EgressBandwidthLabel:  "1G",
+			calc.EgressBurstLabel:      "not-a-quantity",
+		},
+	}, proto.WorkloadEndpoint{
+		State:            "up",
+		Name:             "bill",
+		ProfileIds:       []string{},
+		Ipv4Nets:         []string{"10.28.0.13/32"},
+		Ipv6Nets:         []string{},
+		Tiers:            []*proto.TierInfo{},
+		Ipv4Nat:          []*proto.NatInfo{},
+		Ipv6Nat:          []*proto.NatInfo{},
+		IngressBandwidth: 10000000,
+		IngressBurst:     1048576,
+		EgressBandwidth:  1000000000,
+	}),
 )
 
 var _ = Describe("ParsedRulesToActivePolicyUpdate", func() {
@@ -78,6 +103,7 @@ var _ = Describe("ParsedRulesToActivePolicyUpdate", func() {
 			},
 			PreDNAT:   true,
 			Untracked: true,
+			AuditOnly: true,
 		}
 		fullyLoadedProtoRules = proto.ActivePolicyUpdate{
 			Id: &proto.PolicyID{
@@ -90,6 +116,7 @@ var _ = Describe("ParsedRulesToActivePolicyUpdate", func() {
 				OutboundRules: []*proto.Rule{{Action: "Allow"}},
 				Untracked:     true,
 				PreDnat:       true,
+				AuditOnly:     true,
 			},
 		}
 	)
@@ -262,6 +289,45 @@ var _ = Describe("ServiceAccount update/remove", func() {
 	})
 })
 
+var _ = Describe("Domain IP set add/remove", func() {
+	var uut *calc.EventSequencer
+	var recorder *dataplaneRecorder
+
+	BeforeEach(func() {
+		uut = calc.NewEventSequencer(&dummyConfigInterface{})
+		recorder = &dataplaneRecorder{}
+		uut.Callback = recorder.record
+	})
+
+	It("should send the domains with no members", func() {
+		uut.OnDomainIPSetAdded("d:abcd", []string{"*.example.org", "example.com"})
+		uut.Flush()
+		Expect(recorder.Messages).To(Equal([]interface{}{&proto.IPSetUpdate{
+			Id:      "d:abcd",
+			Members: []string{},
+			Type:    proto.IPSetUpdate_IP,
+			Domains: []string{"*.example.org", "example.com"},
+		}}))
+	})
+
+	It("should coalesce add + remove", func() {
+		uut.OnDomainIPSetAdded("d:abcd", []string{"example.com"})
+		uut.OnIPSetRemoved("d:abcd")
+		uut.Flush()
+		Expect(recorder.Messages).To(BeNil())
+	})
+
+	It("should send remove for flushed sets", func() {
+		uut.OnDomainIPSetAdded("d:abcd", []string{"example.com"})
+		uut.Flush()
+		recorder.Messages = make([]interface{}, 0)
+
+		uut.OnIPSetRemoved("d:abcd")
+		uut.Flush()
+		Expect(recorder.Messages).To(Equal([]interface{}{&proto.IPSetRemove{Id: "d:abcd"}}))
+	})
+})
+
 var _ = Describe("Namespace update/remove", func() {
 	var uut *calc.EventSequencer
 	var recorder *dataplaneRecorder
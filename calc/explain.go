@@ -0,0 +1,439 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	calinet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+
+	"github.com/projectcalico/felix/ip"
+	"github.com/projectcalico/felix/labelindex"
+)
+
+const (
+	VerdictAllow = "allow"
+	VerdictDeny  = "deny"
+)
+
+var ErrNoLocalEndpoint = errors.New("neither source nor destination IP belongs to a local endpoint")
+
+// ExplainRequest describes a hypothetical packet (or, rather, the first packet of a connection)
+// to evaluate against the active policy.
+type ExplainRequest struct {
+	SrcIP    net.IP `json:"srcIP"`
+	DstIP    net.IP `json:"dstIP"`
+	Protocol uint8  `json:"protocol"`
+	SrcPort  uint16 `json:"srcPort,omitempty"`
+	DstPort  uint16 `json:"dstPort,omitempty"`
+	ICMPType *int   `json:"icmpType,omitempty"`
+	ICMPCode *int   `json:"icmpCode,omitempty"`
+}
+
+// Explanation is the result of evaluating an ExplainRequest.  Egress is filled in if the source
+// IP belongs to a local endpoint, Ingress if the destination does.  Verdict is the overall
+// verdict; a connection is only allowed if it is allowed in both directions.
+type Explanation struct {
+	Egress  *EndpointExplanation `json:"egress,omitempty"`
+	Ingress *EndpointExplanation `json:"ingress,omitempty"`
+	Verdict string               `json:"verdict"`
+}
+
+// EndpointExplanation records how the policy for one endpoint, in one direction, was evaluated.
+// Steps lists the rules that matched, in the order that they were hit.
+type EndpointExplanation struct {
+	Endpoint  string        `json:"endpoint"`
+	Direction string        `json:"direction"`
+	Steps     []ExplainStep `json:"steps"`
+	Verdict   string        `json:"verdict"`
+	Reason    string        `json:"reason"`
+}
+
+type ExplainStep struct {
+	Tier      string `json:"tier,omitempty"`
+	Policy    string `json:"policy,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	RuleIndex int    `json:"ruleIndex"`
+	Action    string `json:"action"`
+}
+
+func (s ExplainStep) String() string {
+	if s.Profile != "" {
+		return fmt.Sprintf("profile %s rule %d: %s", s.Profile, s.RuleIndex, s.Action)
+	}
+	return fmt.Sprintf("tier %s policy %s rule %d: %s", s.Tier, s.Policy, s.RuleIndex, s.Action)
+}
+
+// Explain evaluates the given request against the current state of the calculation graph.  It
+// must be called from the goroutine that owns the graph.  It mirrors the chain structure that
+// the rules package renders: policies are evaluated tier by tier and, if no tier had any policies
+// for the relevant direction, or a policy passed, the endpoint's profiles are evaluated.
+//
+// Matches that the calculation graph doesn't have enough information to evaluate (such as
+// HTTP matches, which are enforced outside of Felix) are ignored.
+func (cg *CalcGraph) Explain(req *ExplainRequest) (*Explanation, error) {
+	srcAddr := ip.FromNetIP(req.SrcIP)
+	dstAddr := ip.FromNetIP(req.DstIP)
+	if srcAddr == nil || dstAddr == nil {
+		return nil, errors.New("source and destination IPs must be valid")
+	}
+	if srcAddr.Version() != dstAddr.Version() {
+		return nil, errors.New("source and destination IPs must be the same IP version")
+	}
+	e := &explainer{
+		req:       req,
+		srcAddr:   srcAddr,
+		dstAddr:   dstAddr,
+		ipVersion: int(srcAddr.Version()),
+		cg:        cg,
+	}
+
+	result := &Explanation{Verdict: VerdictAllow}
+	if key, ep := cg.policyResolver.localEndpointWithIP(req.SrcIP); key != nil {
+		result.Egress = e.explainEndpoint(key, ep, false)
+	}
+	if key, ep := cg.policyResolver.localEndpointWithIP(req.DstIP); key != nil {
+		result.Ingress = e.explainEndpoint(key, ep, true)
+	}
+	if result.Egress == nil && result.Ingress == nil {
+		return nil, ErrNoLocalEndpoint
+	}
+	for _, epExp := range []*EndpointExplanation{result.Egress, result.Ingress} {
+		if epExp != nil && epExp.Verdict != VerdictAllow {
+			result.Verdict = epExp.Verdict
+		}
+	}
+	return result, nil
+}
+
+// localEndpointWithIP returns the local endpoint that owns the given IP, or nil if there is no
+// such endpoint.  If more than one endpoint claims the IP, the one with the lowest key wins so
+// that the result is deterministic.
+func (pr *PolicyResolver) localEndpointWithIP(addr net.IP) (model.Key, interface{}) {
+	var keys []model.Key
+	for key, ep := range pr.endpoints {
+		if endpointHasIP(ep, addr) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys[0], pr.endpoints[keys[0]]
+}
+
+func endpointHasIP(ep interface{}, addr net.IP) bool {
+	switch ep := ep.(type) {
+	case *model.WorkloadEndpoint:
+		for _, nets := range [][]calinet.IPNet{ep.IPv4Nets, ep.IPv6Nets} {
+			for _, n := range nets {
+				if n.Contains(addr) {
+					return true
+				}
+			}
+		}
+	case *model.HostEndpoint:
+		for _, addrs := range [][]calinet.IP{ep.ExpectedIPv4Addrs, ep.ExpectedIPv6Addrs} {
+			for _, a := range addrs {
+				if a.Equal(addr) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+type explainer struct {
+	req       *ExplainRequest
+	srcAddr   ip.Addr
+	dstAddr   ip.Addr
+	ipVersion int
+	cg        *CalcGraph
+}
+
+func (e *explainer) explainEndpoint(key model.Key, ep interface{}, ingress bool) *EndpointExplanation {
+	result := &EndpointExplanation{
+		Endpoint:  key.String(),
+		Direction: "egress",
+		Steps:     []ExplainStep{},
+	}
+	if ingress {
+		result.Direction = "ingress"
+	}
+	logCxt := log.WithFields(log.Fields{"endpoint": key, "direction": result.Direction})
+
+	// Policies.  As in the dataplane, if there are any policies for this direction then a packet
+	// that doesn't match any of them is dropped.
+	policiesPresent := false
+	passed := false
+tierLoop:
+	for _, tier := range e.cg.policyResolver.tiersForEndpoint(key) {
+		for _, polKV := range tier.OrderedPolicies {
+			pol := polKV.Value
+			if pol.DoNotTrack || pol.PreDNAT {
+				// These are applied in the raw/mangle tables, only to host endpoints, and they
+				// are not part of the normal filter chain.
+				continue
+			}
+			if e.cg.ruleScanner.PolicyIsAuditOnly(polKV.Key) {
+				// Audit-only policies never change the verdict.
+				continue
+			}
+			rules := pol.OutboundRules
+			if ingress {
+				if !polKV.GovernsIngress() {
+					continue
+				}
+				rules = pol.InboundRules
+			} else if !polKV.GovernsEgress() {
+				continue
+			}
+			policiesPresent = true
+			for i := range rules {
+				if !e.ruleMatches(&rules[i]) {
+					continue
+				}
+				step := ExplainStep{
+					Tier:      tier.Name,
+					Policy:    polKV.Key.Name,
+					RuleIndex: i,
+					Action:    normaliseAction(rules[i].Action),
+				}
+				result.Steps = append(result.Steps, step)
+				switch step.Action {
+				case "allow", "deny":
+					result.Verdict = step.Action
+					result.Reason = step.String()
+					return result
+				case "pass":
+					passed = true
+					break tierLoop
+				}
+				// Log rules don't terminate evaluation.
+			}
+		}
+	}
+	if policiesPresent && !passed {
+		logCxt.Debug("No policy matched")
+		result.Verdict = VerdictDeny
+		result.Reason = "no policy matched (end of tier drop)"
+		return result
+	}
+
+	// Profiles.
+	var profileIDs []string
+	switch ep := ep.(type) {
+	case *model.WorkloadEndpoint:
+		profileIDs = ep.ProfileIDs
+	case *model.HostEndpoint:
+		profileIDs = ep.ProfileIDs
+	}
+	for _, profileID := range profileIDs {
+		profRules, known := e.cg.activeRulesCalculator.allProfileRules[profileID]
+		if !known {
+			logCxt.WithField("profile", profileID).Debug("Unknown profile, using dummy drop rules")
+			profRules = &DummyDropRules
+		}
+		rules := profRules.OutboundRules
+		if ingress {
+			rules = profRules.InboundRules
+		}
+		for i := range rules {
+			if !e.ruleMatches(&rules[i]) {
+				continue
+			}
+			step := ExplainStep{
+				Profile:   profileID,
+				RuleIndex: i,
+				Action:    normaliseAction(rules[i].Action),
+			}
+			result.Steps = append(result.Steps, step)
+			if step.Action == "allow" || step.Action == "deny" {
+				result.Verdict = step.Action
+				result.Reason = step.String()
+				return result
+			}
+		}
+	}
+	result.Verdict = VerdictDeny
+	if len(profileIDs) == 0 {
+		result.Reason = "no policy or profile applies to the endpoint"
+	} else {
+		result.Reason = "no profile matched"
+	}
+	return result
+}
+
+func normaliseAction(action string) string {
+	switch strings.ToLower(action) {
+	case "", "allow":
+		return "allow"
+	case "next-tier", "pass":
+		return "pass"
+	default:
+		return strings.ToLower(action)
+	}
+}
+
+// ruleMatches returns true if the rule matches the packet described by the request.  We convert
+// the rule to a ParsedRule so that selectors and named ports are replaced by the same IP set IDs
+// that the label index tracks.
+func (e *explainer) ruleMatches(rule *model.Rule) bool {
+	r, _ := ruleToParsedRule(rule)
+
+	if r.IPVersion != nil && *r.IPVersion != e.ipVersion {
+		return false
+	}
+	if r.Protocol != nil && !e.protocolMatches(*r.Protocol) {
+		return false
+	}
+	if r.NotProtocol != nil && e.protocolMatches(*r.NotProtocol) {
+		return false
+	}
+	if r.ICMPType != nil && (e.req.ICMPType == nil || *e.req.ICMPType != *r.ICMPType) {
+		return false
+	}
+	if r.ICMPCode != nil && (e.req.ICMPCode == nil || *e.req.ICMPCode != *r.ICMPCode) {
+		return false
+	}
+	if r.NotICMPType != nil && e.req.ICMPType != nil && *e.req.ICMPType == *r.NotICMPType &&
+		(r.NotICMPCode == nil || (e.req.ICMPCode != nil && *e.req.ICMPCode == *r.NotICMPCode)) {
+		return false
+	}
+
+	// Source.
+	if len(r.SrcNets) > 0 && !netsContain(r.SrcNets, e.req.SrcIP) {
+		return false
+	}
+	if netsContain(r.NotSrcNets, e.req.SrcIP) {
+		return false
+	}
+	if !e.allIPSetsContain(r.SrcIPSetIDs, e.srcAddr) || e.anyIPSetContains(r.NotSrcIPSetIDs, e.srcAddr, 0) {
+		return false
+	}
+	if (len(r.SrcPorts) > 0 || len(r.SrcNamedPortIPSetIDs) > 0) &&
+		!portsContain(r.SrcPorts, e.req.SrcPort) &&
+		!e.anyIPSetContains(r.SrcNamedPortIPSetIDs, e.srcAddr, e.req.SrcPort) {
+		return false
+	}
+	if portsContain(r.NotSrcPorts, e.req.SrcPort) ||
+		e.anyIPSetContains(r.NotSrcNamedPortIPSetIDs, e.srcAddr, e.req.SrcPort) {
+		return false
+	}
+
+	// Destination.
+	if len(r.DstNets) > 0 && !netsContain(r.DstNets, e.req.DstIP) {
+		return false
+	}
+	if netsContain(r.NotDstNets, e.req.DstIP) {
+		return false
+	}
+	if !e.allIPSetsContain(r.DstIPSetIDs, e.dstAddr) || e.anyIPSetContains(r.NotDstIPSetIDs, e.dstAddr, 0) {
+		return false
+	}
+	if (len(r.DstPorts) > 0 || len(r.DstNamedPortIPSetIDs) > 0) &&
+		!portsContain(r.DstPorts, e.req.DstPort) &&
+		!e.anyIPSetContains(r.DstNamedPortIPSetIDs, e.dstAddr, e.req.DstPort) {
+		return false
+	}
+	if portsContain(r.NotDstPorts, e.req.DstPort) ||
+		e.anyIPSetContains(r.NotDstNamedPortIPSetIDs, e.dstAddr, e.req.DstPort) {
+		return false
+	}
+
+	if r.HTTPMatch != nil {
+		log.WithField("rule", rule).Debug("Ignoring HTTP match, it is not enforced by Felix")
+	}
+	return true
+}
+
+func (e *explainer) protocolMatches(p numorstring.Protocol) bool {
+	num, ok := protocolNumber(p)
+	return ok && num == e.req.Protocol
+}
+
+// protocolNumber returns the IP protocol number for the given protocol, which may be specified by
+// number or by name.  Returns ok=false for names that Felix doesn't recognise.
+func protocolNumber(p numorstring.Protocol) (num uint8, ok bool) {
+	if p.Type == numorstring.NumOrStringNum {
+		return p.NumVal, true
+	}
+	switch strings.ToLower(p.StrVal) {
+	case "tcp":
+		return 6, true
+	case "udp":
+		return 17, true
+	case "icmp":
+		return 1, true
+	case "icmpv6":
+		return 58, true
+	case "sctp":
+		return 132, true
+	case "udplite":
+		return 136, true
+	}
+	return 0, false
+}
+
+// allIPSetsContain returns true if the address is in every one of the given (non-named-port) IP
+// sets.  IP sets that the index doesn't know about are treated as empty.
+func (e *explainer) allIPSetsContain(ipSetIDs []string, addr ip.Addr) bool {
+	for _, id := range ipSetIDs {
+		if contains, _ := e.cg.ipsetMemberIndex.IPSetContains(id, addr, labelindex.ProtocolNone, 0); !contains {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *explainer) anyIPSetContains(ipSetIDs []string, addr ip.Addr, port uint16) bool {
+	proto := labelindex.IPSetPortProtocol(e.req.Protocol)
+	for _, id := range ipSetIDs {
+		if contains, _ := e.cg.ipsetMemberIndex.IPSetContains(id, addr, proto, port); contains {
+			return true
+		}
+	}
+	return false
+}
+
+func netsContain(nets []*calinet.IPNet, addr net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func portsContain(ports []numorstring.Port, port uint16) bool {
+	for _, p := range ports {
+		if port >= p.MinPort && port <= p.MaxPort {
+			return true
+		}
+	}
+	return false
+}
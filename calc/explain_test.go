@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc_test
+
+import (
+	. "github.com/projectcalico/felix/calc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/config"
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	. "github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+)
+
+var _ = Describe("Policy explain", func() {
+	var cg *CalcGraph
+
+	update := func(key Key, value interface{}) {
+		cg.AllUpdDispatcher.OnUpdate(api.Update{
+			UpdateType: api.UpdateTypeKVNew,
+			KVPair:     KVPair{Key: key, Value: value},
+		})
+	}
+	tcpTo := func(src, dst string, port uint16) *ExplainRequest {
+		return &ExplainRequest{
+			SrcIP:    mustParseIP(src).IP,
+			DstIP:    mustParseIP(dst).IP,
+			Protocol: 6,
+			SrcPort:  54321,
+			DstPort:  port,
+		}
+	}
+
+	BeforeEach(func() {
+		eb := NewEventSequencer(nil)
+		eb.Callback = func(message interface{}) {}
+		conf := config.New()
+		conf.FelixHostname = localHostname
+		cg = NewCalculationGraph(eb, conf)
+
+		// localWlEp1 has IPs 10.0.0.1 and 10.0.0.2 and profiles prof-1, prof-2 and prof-missing.
+		update(localWlEpKey1, &localWlEp1)
+		update(ProfileRulesKey{ProfileKey: ProfileKey{Name: "prof-1"}}, &ProfileRules{
+			InboundRules:  []Rule{{Action: "allow", SrcNets: []*net.IPNet{mustParseCalicoIPNet("10.1.0.0/16")}}},
+			OutboundRules: []Rule{{Action: "allow", Protocol: &protoUDP}},
+		})
+		update(localWlEpKey2, &WorkloadEndpoint{
+			State:    "active",
+			Name:     "cali2",
+			IPv4Nets: []net.IPNet{mustParseNet("10.0.0.3/32")},
+			Labels:   map[string]string{"id": "loc-ep-2"},
+		})
+		cg.AllUpdDispatcher.OnStatusUpdated(api.InSync)
+	})
+
+	It("should fall through to profiles if there are no policies", func() {
+		exp, err := cg.Explain(tcpTo("10.1.2.3", "10.0.0.1", 80))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exp.Egress).To(BeNil())
+		Expect(exp.Ingress.Steps).To(Equal([]ExplainStep{{Profile: "prof-1", RuleIndex: 0, Action: "allow"}}))
+		Expect(exp.Verdict).To(Equal(VerdictAllow))
+	})
+
+	It("should use the dummy drop rules for a missing profile", func() {
+		exp, err := cg.Explain(tcpTo("10.0.0.1", "10.1.2.3", 80))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exp.Ingress).To(BeNil())
+		Expect(exp.Egress.Steps).To(Equal([]ExplainStep{{Profile: "prof-2", RuleIndex: 0, Action: "deny"}}))
+		Expect(exp.Verdict).To(Equal(VerdictDeny))
+	})
+
+	It("should return an error if neither IP is local", func() {
+		_, err := cg.Explain(tcpTo("10.1.2.3", "10.1.2.4", 80))
+		Expect(err).To(Equal(ErrNoLocalEndpoint))
+	})
+
+	Describe("with a policy", func() {
+		BeforeEach(func() {
+			update(PolicyKey{Name: "pol-1"}, &Policy{
+				Order:    &order10,
+				Selector: "id == 'loc-ep-1'",
+				InboundRules: []Rule{
+					{Action: "log", Protocol: &protoTCP},
+					{Action: "deny", Protocol: &protoTCP, DstPorts: []numorstring.Port{numorstring.SinglePort(22)}},
+					{Action: "allow", Protocol: &protoTCP, SrcSelector: "id == 'loc-ep-2'",
+						DstPorts: []numorstring.Port{numorstring.NamedPort("tcpport")}},
+					{Action: "pass", SrcNets: []*net.IPNet{mustParseCalicoIPNet("10.1.0.0/16")}},
+				},
+				Types: []string{"ingress"},
+			})
+		})
+
+		It("should report a deny rule", func() {
+			exp, err := cg.Explain(tcpTo("10.0.0.3", "10.0.0.1", 22))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exp.Ingress.Steps).To(Equal([]ExplainStep{
+				{Tier: "default", Policy: "pol-1", RuleIndex: 0, Action: "log"},
+				{Tier: "default", Policy: "pol-1", RuleIndex: 1, Action: "deny"},
+			}))
+			Expect(exp.Verdict).To(Equal(VerdictDeny))
+		})
+
+		It("should match selectors and named ports", func() {
+			exp, err := cg.Explain(tcpTo("10.0.0.3", "10.0.0.1", 8080))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exp.Ingress.Verdict).To(Equal(VerdictAllow))
+			Expect(exp.Ingress.Steps[1]).To(Equal(ExplainStep{Tier: "default", Policy: "pol-1", RuleIndex: 2, Action: "allow"}))
+			// ep2 has no policies or profiles so its egress is denied.
+			Expect(exp.Egress.Verdict).To(Equal(VerdictDeny))
+			Expect(exp.Verdict).To(Equal(VerdictDeny))
+		})
+
+		It("should drop at the end of the tier if nothing matches", func() {
+			exp, err := cg.Explain(tcpTo("10.0.0.3", "10.0.0.1", 8081))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exp.Ingress.Verdict).To(Equal(VerdictDeny))
+			Expect(exp.Ingress.Reason).To(ContainSubstring("no policy matched"))
+		})
+
+		It("should go to the profiles after a pass", func() {
+			exp, err := cg.Explain(tcpTo("10.1.2.3", "10.0.0.1", 80))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exp.Ingress.Steps).To(Equal([]ExplainStep{
+				{Tier: "default", Policy: "pol-1", RuleIndex: 0, Action: "log"},
+				{Tier: "default", Policy: "pol-1", RuleIndex: 3, Action: "pass"},
+				{Profile: "prof-1", RuleIndex: 0, Action: "allow"},
+			}))
+			Expect(exp.Verdict).To(Equal(VerdictAllow))
+		})
+
+		It("should ignore the policy for the other direction", func() {
+			exp, err := cg.Explain(&ExplainRequest{
+				SrcIP:    mustParseIP("10.0.0.1").IP,
+				DstIP:    mustParseIP("10.1.2.3").IP,
+				Protocol: 17,
+				DstPort:  53,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exp.Egress.Steps).To(Equal([]ExplainStep{{Profile: "prof-1", RuleIndex: 0, Action: "allow"}}))
+			Expect(exp.Verdict).To(Equal(VerdictAllow))
+		})
+	})
+})
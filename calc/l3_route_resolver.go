@@ -26,7 +26,6 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/backend/encap"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
 	cnet "github.com/projectcalico/libcalico-go/lib/net"
-	cresources "github.com/projectcalico/libcalico-go/lib/resources"
 	"github.com/projectcalico/libcalico-go/lib/set"
 
 	"github.com/projectcalico/felix/dispatcher"
@@ -34,7 +33,7 @@ import (
 	"github.com/projectcalico/felix/proto"
 )
 
-// L3RouteResolver is responsible for indexing (currently only IPv4 versions of):
+// L3RouteResolver is responsible for indexing (IPv4 and IPv6 versions of):
 //
 // - IPAM blocks
 // - IP pools
@@ -45,7 +44,7 @@ import (
 // - The relevant destination CIDR.
 // - The IP pool type that contains the CIDR (or none).
 // - Other metadata about the containing IP pool.
-// - Whether this (/32) CIDR is a host or not.
+// - Whether this (/32 or /128) CIDR is a host or not.
 // - For workload CIDRs, the IP and name of the host that contains the workload.
 //
 // The BPF dataplane use the above to form a map of IP space so it can look up whether a particular
@@ -67,16 +66,26 @@ type L3RouteResolver struct {
 	workloadIDToCIDRs      map[model.WorkloadEndpointKey][]cnet.IPNet
 	useNodeResourceUpdates bool
 	routeSource            string
+
+	// multipathEnabled enables ECMP routes to workload CIDRs that are on more than one remote node.
+	multipathEnabled bool
 }
 
 type l3rrNodeInfo struct {
+	// Addr and CIDR are the node's IPv4 address and subnet; they are zero if the node has no IPv4 address.
 	Addr ip.V4Addr
 	CIDR ip.V4CIDR
 
+	// V6Addr and V6CIDR are the node's IPv6 address and subnet; they are zero if the node has no IPv6
+	// address (which is always the case when node resource updates are disabled).
+	V6Addr ip.V6Addr
+	V6CIDR ip.V6CIDR
+
 	// Tunnel IP addresses
-	IPIPAddr      ip.Addr
-	VXLANAddr     ip.Addr
-	WireguardAddr ip.Addr
+	IPIPAddr        ip.Addr
+	VXLANAddr       ip.Addr
+	WireguardAddr   ip.Addr
+	WireguardV6Addr ip.Addr
 
 	Addresses []ip.Addr
 }
@@ -84,9 +93,12 @@ type l3rrNodeInfo struct {
 func (i l3rrNodeInfo) Equal(b l3rrNodeInfo) bool {
 	if i.Addr == b.Addr &&
 		i.CIDR == b.CIDR &&
+		i.V6Addr == b.V6Addr &&
+		i.V6CIDR == b.V6CIDR &&
 		i.IPIPAddr == b.IPIPAddr &&
 		i.VXLANAddr == b.VXLANAddr &&
-		i.WireguardAddr == b.WireguardAddr {
+		i.WireguardAddr == b.WireguardAddr &&
+		i.WireguardV6Addr == b.WireguardV6Addr {
 
 		if len(i.Addresses) != len(b.Addresses) {
 			return false
@@ -118,28 +130,69 @@ func (i l3rrNodeInfo) AddrAsCIDR() ip.V4CIDR {
 	return i.Addr.AsCIDR().(ip.V4CIDR)
 }
 
-func (i l3rrNodeInfo) AddresesV4AsCIDRs() []ip.V4CIDR {
-	addrs4 := make(map[ip.V4Addr]struct{})
+func (i l3rrNodeInfo) HasV4Addr() bool {
+	return i.Addr != ip.V4Addr{}
+}
 
-	addrs4[i.Addr] = struct{}{}
+func (i l3rrNodeInfo) HasV6Addr() bool {
+	return i.V6Addr != ip.V6Addr{}
+}
 
-	for _, a := range i.Addresses {
-		if a.Version() == 4 {
-			addrs4[a.(ip.V4Addr)] = struct{}{}
+// AddrForVersion returns the node's address of the given IP version, or nil if the node doesn't have one.
+func (i l3rrNodeInfo) AddrForVersion(version uint8) ip.Addr {
+	if version == 6 {
+		if i.HasV6Addr() {
+			return i.V6Addr
 		}
+		return nil
+	}
+	if i.HasV4Addr() {
+		return i.Addr
+	}
+	return nil
+}
+
+// SubnetContains returns true if the other node's address of the given IP version is in this node's subnet
+// of the same version.  Returns false if either node lacks an address of that version.
+func (i l3rrNodeInfo) SubnetContains(other l3rrNodeInfo, version uint8) bool {
+	if version == 6 {
+		return i.HasV6Addr() && other.HasV6Addr() && i.V6CIDR.ContainsV6(other.V6Addr)
+	}
+	return i.HasV4Addr() && other.HasV4Addr() && i.CIDR.ContainsV4(other.Addr)
+}
+
+// AddressesAsCIDRs returns the /32 and /128 CIDRs of all the node's addresses, without duplicates.
+func (i l3rrNodeInfo) AddressesAsCIDRs() []ip.CIDR {
+	addrs := make(map[ip.Addr]struct{})
+
+	if i.HasV4Addr() {
+		addrs[i.Addr] = struct{}{}
+	}
+	if i.HasV6Addr() {
+		addrs[i.V6Addr] = struct{}{}
 	}
 
-	cidrs := make([]ip.V4CIDR, len(addrs4))
+	for _, a := range i.Addresses {
+		addrs[a] = struct{}{}
+	}
+
+	cidrs := make([]ip.CIDR, len(addrs))
 	idx := 0
-	for a := range addrs4 {
-		cidrs[idx] = a.AsCIDR().(ip.V4CIDR)
+	for a := range addrs {
+		cidrs[idx] = a.AsCIDR()
 		idx++
 	}
 
 	return cidrs
 }
 
-func NewL3RouteResolver(hostname string, callbacks PipelineCallbacks, useNodeResourceUpdates bool, routeSource string) *L3RouteResolver {
+func NewL3RouteResolver(
+	hostname string,
+	callbacks PipelineCallbacks,
+	useNodeResourceUpdates bool,
+	routeSource string,
+	multipathEnabled bool,
+) *L3RouteResolver {
 	logrus.Info("Creating L3 route resolver")
 	return &L3RouteResolver{
 		myNodeName: hostname,
@@ -154,6 +207,7 @@ func NewL3RouteResolver(hostname string, callbacks PipelineCallbacks, useNodeRes
 		useNodeResourceUpdates: useNodeResourceUpdates,
 		routeSource:            routeSource,
 		nodeRoutes:             newNodeRoutes(),
+		multipathEnabled:       multipathEnabled,
 	}
 }
 
@@ -193,6 +247,7 @@ func (c *L3RouteResolver) OnWorkloadUpdate(update api.Update) (_ bool) {
 	var newCIDRs []cnet.IPNet
 	if update.Value != nil {
 		newWorkload := update.Value.(*model.WorkloadEndpoint)
+		// Routes derived from workload endpoints are IPv4-only for now; IPv6 routes come from IPAM blocks.
 		newCIDRs = newWorkload.IPv4Nets
 		logrus.WithField("workload", key).WithField("newCIDRs", newCIDRs).Debug("Workload update")
 	}
@@ -205,14 +260,14 @@ func (c *L3RouteResolver) OnWorkloadUpdate(update api.Update) (_ bool) {
 
 	// Incref the new CIDRs.
 	for _, newCIDR := range newCIDRs {
-		cidr := ip.CIDRFromCalicoNet(newCIDR).(ip.V4CIDR)
+		cidr := ip.CIDRFromCalicoNet(newCIDR)
 		c.trie.AddRef(cidr, key.Hostname, RefTypeWEP)
 		c.nodeRoutes.Add(nodenameRoute{key.Hostname, cidr})
 	}
 
 	// Decref the old.
 	for _, oldCIDR := range oldCIDRs {
-		cidr := ip.CIDRFromCalicoNet(oldCIDR).(ip.V4CIDR)
+		cidr := ip.CIDRFromCalicoNet(oldCIDR)
 		c.trie.RemoveRef(cidr, key.Hostname, RefTypeWEP)
 		c.nodeRoutes.Remove(nodenameRoute{key.Hostname, cidr})
 	}
@@ -241,7 +296,7 @@ func (c *L3RouteResolver) OnBlockUpdate(update api.Update) (_ bool) {
 		// We don't allow multiple blocks with the same CIDR, so no need to check
 		// for duplicates here. Look at the routes contributed by this block and determine if we
 		// need to send any updates.
-		newRoutes := c.v4RoutesFromBlock(update.Value.(*model.AllocationBlock))
+		newRoutes := c.routesFromBlock(update.Value.(*model.AllocationBlock))
 		logrus.WithField("numRoutes", len(newRoutes)).Debug("IPAM block update")
 		cachedRoutes, ok := c.blockToRoutes[key]
 		if !ok {
@@ -345,10 +400,7 @@ func (c *L3RouteResolver) OnResourceUpdate(update api.Update) (_ bool) {
 				CIDR: ip.CIDRFromCalicoNet(*caliNodeCIDR).(ip.V4CIDR),
 			}
 		} else {
-			ipv4, caliNodeCIDR := cresources.FindNodeAddress(node, apiv3.InternalIP)
-			if ipv4 == nil {
-				ipv4, caliNodeCIDR = cresources.FindNodeAddress(node, apiv3.ExternalIP)
-			}
+			ipv4, caliNodeCIDR := findNodeAddress(node, 4)
 			if ipv4 != nil && caliNodeCIDR != nil {
 				nodeInfo = &l3rrNodeInfo{
 					Addr: ip.FromCalicoIP(*ipv4).(ip.V4Addr),
@@ -357,10 +409,33 @@ func (c *L3RouteResolver) OnResourceUpdate(update api.Update) (_ bool) {
 			}
 		}
 
+		// Same again for the IPv6 address; a node may have either or both.
+		var ipv6 *cnet.IP
+		var caliNodeV6CIDR *cnet.IPNet
+		if node.Spec.BGP != nil && node.Spec.BGP.IPv6Address != "" {
+			var err error
+			ipv6, caliNodeV6CIDR, err = cnet.ParseCIDROrIP(node.Spec.BGP.IPv6Address)
+			if err != nil {
+				logrus.WithError(err).Panic("Failed to parse already-validated IP address")
+			}
+		} else {
+			ipv6, caliNodeV6CIDR = findNodeAddress(node, 6)
+		}
+		if ipv6 != nil && caliNodeV6CIDR != nil {
+			if nodeInfo == nil {
+				nodeInfo = &l3rrNodeInfo{}
+			}
+			nodeInfo.V6Addr = ip.FromCalicoIP(*ipv6).(ip.V6Addr)
+			nodeInfo.V6CIDR = ip.CIDRFromCalicoNet(*caliNodeV6CIDR).(ip.V6CIDR)
+		}
+
 		if nodeInfo != nil {
 			if node.Spec.Wireguard != nil && node.Spec.Wireguard.InterfaceIPv4Address != "" {
 				nodeInfo.WireguardAddr = ip.FromString(node.Spec.Wireguard.InterfaceIPv4Address)
 			}
+			if addr := wireguardIPv6InterfaceAddr(node); addr != nil {
+				nodeInfo.WireguardV6Addr = ip.FromCalicoIP(*addr)
+			}
 
 			if node.Spec.BGP != nil && node.Spec.BGP.IPv4IPIPTunnelAddr != "" {
 				nodeInfo.IPIPAddr = ip.FromString(node.Spec.BGP.IPv4IPIPTunnelAddr)
@@ -386,6 +461,25 @@ func (c *L3RouteResolver) OnResourceUpdate(update api.Update) (_ bool) {
 	return
 }
 
+// findNodeAddress returns the node's first InternalIP of the given IP version, falling back to its first
+// ExternalIP of that version.  Returns nil if the node has no such address.  Unlike
+// cresources.FindNodeAddress, it skips over addresses of the other IP version.
+func findNodeAddress(node *apiv3.Node, version int) (*cnet.IP, *cnet.IPNet) {
+	for _, addrType := range []string{apiv3.InternalIP, apiv3.ExternalIP} {
+		for _, a := range node.Spec.Addresses {
+			if a.Type != addrType {
+				continue
+			}
+			addr, cidr, err := cnet.ParseCIDROrIP(a.Address)
+			if err != nil || addr.Version() != version {
+				continue
+			}
+			return addr, cidr
+		}
+	}
+	return nil, nil
+}
+
 // OnHostIPUpdate gets called whenever a node IP address changes.
 func (c *L3RouteResolver) OnHostIPUpdate(update api.Update) (_ bool) {
 	// Queue up a flush.
@@ -421,14 +515,12 @@ func (c *L3RouteResolver) onNodeUpdate(nodeName string, newNodeInfo *l3rrNodeInf
 	}
 
 	if nodeName == c.myNodeName {
-		// Check if our CIDR has changed and if so recalculate the "same subnet" tracking.
-		var myNewCIDR ip.V4CIDR
-		var myNewCIDRKnown bool
+		// Check if our CIDRs have changed and if so recalculate the "same subnet" tracking.
+		var myNewNodeInfo l3rrNodeInfo
 		if newNodeInfo != nil {
-			myNewCIDR = newNodeInfo.CIDR
-			myNewCIDRKnown = true
+			myNewNodeInfo = *newNodeInfo
 		}
-		if oldNodeInfo.CIDR != myNewCIDR {
+		if oldNodeInfo.CIDR != myNewNodeInfo.CIDR || oldNodeInfo.V6CIDR != myNewNodeInfo.V6CIDR {
 			// This node's CIDR has changed; some routes may now have an incorrect value for same-subnet.
 			c.visitAllRoutes(func(r nodenameRoute) {
 				if r.nodeName == c.myNodeName {
@@ -438,9 +530,9 @@ func (c *L3RouteResolver) onNodeUpdate(nodeName string, newNodeInfo *l3rrNodeInf
 				if !known {
 					return // Don't know other node's CIDR so ignore for now.
 				}
-				otherNodesIPv4 := otherNodeInfo.Addr
-				wasSameSubnet := nodeExisted && oldNodeInfo.CIDR.ContainsV4(otherNodesIPv4)
-				nowSameSubnet := myNewCIDRKnown && myNewCIDR.ContainsV4(otherNodesIPv4)
+				version := r.dst.Version()
+				wasSameSubnet := nodeExisted && oldNodeInfo.SubnetContains(otherNodeInfo, version)
+				nowSameSubnet := newNodeInfo != nil && myNewNodeInfo.SubnetContains(otherNodeInfo, version)
 				if wasSameSubnet != nowSameSubnet {
 					logrus.WithField("route", r).Debug("Update to our subnet invalidated route")
 					c.trie.MarkCIDRDirty(r.dst)
@@ -452,37 +544,43 @@ func (c *L3RouteResolver) onNodeUpdate(nodeName string, newNodeInfo *l3rrNodeInf
 	// Process the tunnel addresses. These are reference counted, so handle adds followed by deletes to minimize churn.
 	if newNodeInfo != nil {
 		if newNodeInfo.IPIPAddr != nil {
-			c.trie.AddRef(newNodeInfo.IPIPAddr.AsCIDR().(ip.V4CIDR), nodeName, RefTypeIPIP)
+			c.trie.AddRef(newNodeInfo.IPIPAddr.AsCIDR(), nodeName, RefTypeIPIP)
 		}
 		if newNodeInfo.VXLANAddr != nil {
-			c.trie.AddRef(newNodeInfo.VXLANAddr.AsCIDR().(ip.V4CIDR), nodeName, RefTypeVXLAN)
+			c.trie.AddRef(newNodeInfo.VXLANAddr.AsCIDR(), nodeName, RefTypeVXLAN)
 		}
 		if newNodeInfo.WireguardAddr != nil {
-			c.trie.AddRef(newNodeInfo.WireguardAddr.AsCIDR().(ip.V4CIDR), nodeName, RefTypeWireguard)
+			c.trie.AddRef(newNodeInfo.WireguardAddr.AsCIDR(), nodeName, RefTypeWireguard)
+		}
+		if newNodeInfo.WireguardV6Addr != nil {
+			c.trie.AddRef(newNodeInfo.WireguardV6Addr.AsCIDR(), nodeName, RefTypeWireguard)
 		}
 	}
 	if nodeExisted {
 		if oldNodeInfo.IPIPAddr != nil {
-			c.trie.RemoveRef(oldNodeInfo.IPIPAddr.AsCIDR().(ip.V4CIDR), nodeName, RefTypeIPIP)
+			c.trie.RemoveRef(oldNodeInfo.IPIPAddr.AsCIDR(), nodeName, RefTypeIPIP)
 		}
 		if oldNodeInfo.VXLANAddr != nil {
-			c.trie.RemoveRef(oldNodeInfo.VXLANAddr.AsCIDR().(ip.V4CIDR), nodeName, RefTypeVXLAN)
+			c.trie.RemoveRef(oldNodeInfo.VXLANAddr.AsCIDR(), nodeName, RefTypeVXLAN)
 		}
 		if oldNodeInfo.WireguardAddr != nil {
-			c.trie.RemoveRef(oldNodeInfo.WireguardAddr.AsCIDR().(ip.V4CIDR), nodeName, RefTypeWireguard)
+			c.trie.RemoveRef(oldNodeInfo.WireguardAddr.AsCIDR(), nodeName, RefTypeWireguard)
+		}
+		if oldNodeInfo.WireguardV6Addr != nil {
+			c.trie.RemoveRef(oldNodeInfo.WireguardV6Addr.AsCIDR(), nodeName, RefTypeWireguard)
 		}
 	}
 
 	// Process the node CIDR and cache the node info.
 	if nodeExisted {
 		delete(c.nodeNameToNodeInfo, nodeName)
-		for _, a := range oldNodeInfo.AddresesV4AsCIDRs() {
+		for _, a := range oldNodeInfo.AddressesAsCIDRs() {
 			c.trie.RemoveHost(a, nodeName)
 		}
 	}
 	if newNodeInfo != nil {
 		c.nodeNameToNodeInfo[nodeName] = *newNodeInfo
-		for _, a := range newNodeInfo.AddresesV4AsCIDRs() {
+		for _, a := range newNodeInfo.AddressesAsCIDRs() {
 			c.trie.AddHost(a, nodeName)
 		}
 	}
@@ -497,9 +595,8 @@ func (c *L3RouteResolver) markAllNodeRoutesDirty(nodeName string) {
 }
 
 func (c *L3RouteResolver) visitAllRoutes(v func(route nodenameRoute)) {
-	c.trie.t.Visit(func(cidr ip.V4CIDR, data interface{}) bool {
+	c.trie.Visit(func(cidr ip.CIDR, ri RouteInfo) bool {
 		// Construct a nodenameRoute to pass to the visiting function.
-		ri := c.trie.t.Get(cidr).(RouteInfo)
 		nnr := nodenameRoute{dst: cidr}
 		if len(ri.Refs) > 0 {
 			// From a Ref.
@@ -526,29 +623,25 @@ func (c *L3RouteResolver) OnPoolUpdate(update api.Update) (_ bool) {
 	poolKey := k.String()
 	oldPool, oldPoolExists := c.allPools[poolKey]
 	oldPoolType := proto.IPPoolType_NONE
-	var poolCIDR ip.V4CIDR
+	var poolCIDR ip.CIDR
 	if oldPoolExists {
 		// Need explicit oldPoolExists check so that we don't pass a zero-struct to poolTypeForPool.
 		oldPoolType = c.poolTypeForPool(&oldPool)
-		poolCIDR = ip.CIDRFromCalicoNet(oldPool.CIDR).(ip.V4CIDR)
+		poolCIDR = ip.CIDRFromCalicoNet(oldPool.CIDR)
 	}
 	var newPool *model.IPPool
 	if update.Value != nil {
 		newPool = update.Value.(*model.IPPool)
-		if len(newPool.CIDR.IP.To4()) == 0 {
-			logrus.Debug("Ignoring IPv6 pool")
-			newPool = nil
-		}
 	}
 	newPoolType := c.poolTypeForPool(newPool)
 	logCxt := logrus.WithFields(logrus.Fields{"oldType": oldPoolType, "newType": newPoolType})
 	if newPool != nil && newPoolType != proto.IPPoolType_NONE {
 		logCxt.Info("Pool is active")
 		c.allPools[poolKey] = *newPool
-		poolCIDR = ip.CIDRFromCalicoNet(newPool.CIDR).(ip.V4CIDR)
+		poolCIDR = ip.CIDRFromCalicoNet(newPool.CIDR)
 		crossSubnet := newPool.IPIPMode == encap.CrossSubnet || newPool.VXLANMode == encap.CrossSubnet
 		c.trie.UpdatePool(poolCIDR, newPoolType, newPool.Masquerade, crossSubnet)
-	} else {
+	} else if oldPoolExists {
 		delete(c.allPools, poolKey)
 		c.trie.RemovePool(poolCIDR)
 	}
@@ -569,14 +662,9 @@ func (c *L3RouteResolver) poolTypeForPool(pool *model.IPPool) proto.IPPoolType {
 	return proto.IPPoolType_NO_ENCAP
 }
 
-// v4RoutesFromBlock returns a list of routes which should exist based on the provided
+// routesFromBlock returns a list of routes which should exist based on the provided
 // allocation block.
-func (c *L3RouteResolver) v4RoutesFromBlock(b *model.AllocationBlock) map[string]nodenameRoute {
-	if len(b.CIDR.IP.To4()) == 0 {
-		logrus.Debug("Ignoring IPv6 block")
-		return nil
-	}
-
+func (c *L3RouteResolver) routesFromBlock(b *model.AllocationBlock) map[string]nodenameRoute {
 	routes := make(map[string]nodenameRoute)
 	for _, alloc := range b.NonAffineAllocations() {
 		if alloc.Host == "" {
@@ -585,7 +673,7 @@ func (c *L3RouteResolver) v4RoutesFromBlock(b *model.AllocationBlock) map[string
 			continue
 		}
 		r := nodenameRoute{
-			dst:      ip.CIDRFromNetIP(alloc.Addr.IP).(ip.V4CIDR),
+			dst:      ip.CIDRFromNetIP(alloc.Addr.IP),
 			nodeName: alloc.Host,
 		}
 		routes[r.Key()] = r
@@ -595,7 +683,7 @@ func (c *L3RouteResolver) v4RoutesFromBlock(b *model.AllocationBlock) map[string
 	if host != "" {
 		logrus.WithField("host", host).Debug("Block has a host, including block-via-host route")
 		r := nodenameRoute{
-			dst:      ip.CIDRFromCalicoNet(b.CIDR).(ip.V4CIDR),
+			dst:      ip.CIDRFromCalicoNet(b.CIDR),
 			nodeName: host,
 		}
 		routes[r.Key()] = r
@@ -607,11 +695,11 @@ func (c *L3RouteResolver) v4RoutesFromBlock(b *model.AllocationBlock) map[string
 // flush() iterates over the CIDRs that are marked dirty in the trie and sends any route updates
 // that it finds.
 func (c *L3RouteResolver) flush() {
-	var buf []ip.V4TrieEntry
+	var buf []RouteInfo
 	c.trie.dirtyCIDRs.Iter(func(item interface{}) error {
 		logCxt := logrus.WithField("cidr", item)
 		logCxt.Debug("Flushing dirty route")
-		cidr := item.(ip.V4CIDR)
+		cidr := item.(ip.CIDR)
 
 		// We know the CIDR may be dirty, look up the path through the trie to the CIDR.  This will
 		// give us the information about the enclosing CIDRs.  For example, if we have:
@@ -620,7 +708,7 @@ func (c *L3RouteResolver) flush() {
 		// - IP          10.0.0.1/32 node y
 		// Then, we'll see the pool, block and IP in turn on the lookup path allowing us to collect the
 		// relevant information from each.
-		buf = c.trie.t.LookupPath(buf, cidr)
+		buf = c.trie.LookupPath(buf, cidr)
 
 		if len(buf) == 0 {
 			// CIDR is not in the trie.  Nothing to do.  Route removed before it had even been sent?
@@ -629,7 +717,7 @@ func (c *L3RouteResolver) flush() {
 		}
 
 		// Otherwise, check if the route is removed.
-		ri := buf[len(buf)-1].Data.(RouteInfo)
+		ri := buf[len(buf)-1]
 		if ri.WasSent && !ri.IsValidRoute() {
 			logCxt.Debug("CIDR was sent before but now needs to be removed.")
 			c.callbacks.OnRouteRemove(cidr.String())
@@ -637,111 +725,172 @@ func (c *L3RouteResolver) flush() {
 			return set.RemoveItem
 		}
 
-		rt := &proto.RouteUpdate{
-			Type:       proto.RouteType_CIDR_INFO,
-			IpPoolType: proto.IPPoolType_NONE,
-			Dst:        cidr.String(),
+		rt := c.routeFromLookupPath(cidr, buf)
+		logrus.WithField("route", rt).Debug("Sending route")
+		c.callbacks.OnRouteUpdate(rt)
+		c.trie.SetRouteSent(cidr, true)
+
+		return set.RemoveItem
+	})
+}
+
+// routeFromLookupPath calculates the route for the given CIDR from the RouteInfos along its lookup
+// path through the trie, as returned by LookupPath.
+func (c *L3RouteResolver) routeFromLookupPath(cidr ip.CIDR, buf []RouteInfo) *proto.RouteUpdate {
+	logCxt := logrus.WithField("cidr", cidr)
+	rt := &proto.RouteUpdate{
+		Type:       proto.RouteType_CIDR_INFO,
+		IpPoolType: proto.IPPoolType_NONE,
+		Dst:        cidr.String(),
+	}
+	poolAllowsCrossSubnet := false
+	for _, ri := range buf {
+		if ri.Pool.Type != proto.IPPoolType_NONE {
+			logCxt.WithField("type", ri.Pool.Type).Debug("Found containing IP pool.")
+			rt.IpPoolType = ri.Pool.Type
 		}
-		poolAllowsCrossSubnet := false
-		for _, entry := range buf {
-			ri := entry.Data.(RouteInfo)
-			if ri.Pool.Type != proto.IPPoolType_NONE {
-				logCxt.WithField("type", ri.Pool.Type).Debug("Found containing IP pool.")
-				rt.IpPoolType = ri.Pool.Type
-			}
-			if ri.Pool.NATOutgoing {
-				logCxt.Debug("NAT outgoing enabled on this CIDR.")
-				rt.NatOutgoing = true
+		if ri.Pool.NATOutgoing {
+			logCxt.Debug("NAT outgoing enabled on this CIDR.")
+			rt.NatOutgoing = true
+		}
+		if ri.Pool.CrossSubnet {
+			logCxt.Debug("Cross-subnet enabled on this CIDR.")
+			poolAllowsCrossSubnet = true
+		}
+		if ri.Block.NodeName != "" {
+			rt.DstNodeName = ri.Block.NodeName
+			if rt.DstNodeName == c.myNodeName {
+				logCxt.Debug("Local workload route.")
+				rt.Type = proto.RouteType_LOCAL_WORKLOAD
+			} else {
+				logCxt.Debug("Remote workload route.")
+				rt.Type = proto.RouteType_REMOTE_WORKLOAD
 			}
-			if ri.Pool.CrossSubnet {
-				logCxt.Debug("Cross-subnet enabled on this CIDR.")
-				poolAllowsCrossSubnet = true
+		}
+		if len(ri.Host.NodeNames) > 0 {
+			rt.DstNodeName = ri.Host.NodeNames[0]
+
+			if rt.DstNodeName == c.myNodeName {
+				logCxt.Debug("Local host route.")
+				rt.Type = proto.RouteType_LOCAL_HOST
+			} else {
+				logCxt.Debug("Remote host route.")
+				rt.Type = proto.RouteType_REMOTE_HOST
 			}
-			if ri.Block.NodeName != "" {
-				rt.DstNodeName = ri.Block.NodeName
-				if rt.DstNodeName == c.myNodeName {
-					logCxt.Debug("Local workload route.")
+		}
+
+		if len(ri.Refs) > 0 {
+			// At least one Ref exists with this IP. It may be on this node, or a remote node.
+			// In steady state we only ever expect a single workload Ref for this CIDR, or multiple tunnel Refs
+			// sharing the same CIDR. However, there are rare transient cases we must handle where we may have
+			// multiple workload, or workload and tunnel, or multiple node Refs with the same IP. Since this will be
+			// transient, we can always just use the first entry (and related tunnel entries).  The exception is
+			// when multipath is enabled: then, workloads on several nodes may share an IP on purpose and we give
+			// the route a next hop for each of the nodes.
+			rt.DstNodeName = ri.Refs[0].NodeName
+			if ri.Refs[0].RefType == RefTypeWEP {
+				// This is not a tunnel ref, so must be a workload.
+				if ri.Refs[0].NodeName == c.myNodeName {
 					rt.Type = proto.RouteType_LOCAL_WORKLOAD
+					rt.LocalWorkload = true
 				} else {
-					logCxt.Debug("Remote workload route.")
 					rt.Type = proto.RouteType_REMOTE_WORKLOAD
+					if c.multipathEnabled {
+						rt.NextHops = c.nextHopsFromRefs(ri.Refs, cidr.Version())
+					}
 				}
-			}
-			if len(ri.Host.NodeNames) > 0 {
-				rt.DstNodeName = ri.Host.NodeNames[0]
-
-				if rt.DstNodeName == c.myNodeName {
-					logCxt.Debug("Local host route.")
-					rt.Type = proto.RouteType_LOCAL_HOST
+			} else {
+				// This is a tunnel ref, set type and also store the tunnel type in the route. It is possible for
+				// multiple tunnels to have the same IP, so collate all tunnel types on the same node.
+				if ri.Refs[0].NodeName == c.myNodeName {
+					rt.Type = proto.RouteType_LOCAL_TUNNEL
 				} else {
-					logCxt.Debug("Remote host route.")
-					rt.Type = proto.RouteType_REMOTE_HOST
+					rt.Type = proto.RouteType_REMOTE_TUNNEL
 				}
-			}
 
-			if len(ri.Refs) > 0 {
-				// At least one Ref exists with this IP. It may be on this node, or a remote node.
-				// In steady state we only ever expect a single workload Ref for this CIDR, or multiple tunnel Refs
-				// sharing the same CIDR. However, there are rare transient cases we must handle where we may have
-				// multiple workload, or workload and tunnel, or multiple node Refs with the same IP. Since this will be
-				// transient, we can always just use the first entry (and related tunnel entries)
-				rt.DstNodeName = ri.Refs[0].NodeName
-				if ri.Refs[0].RefType == RefTypeWEP {
-					// This is not a tunnel ref, so must be a workload.
-					if ri.Refs[0].NodeName == c.myNodeName {
-						rt.Type = proto.RouteType_LOCAL_WORKLOAD
-						rt.LocalWorkload = true
-					} else {
-						rt.Type = proto.RouteType_REMOTE_WORKLOAD
-					}
-				} else {
-					// This is a tunnel ref, set type and also store the tunnel type in the route. It is possible for
-					// multiple tunnels to have the same IP, so collate all tunnel types on the same node.
-					if ri.Refs[0].NodeName == c.myNodeName {
-						rt.Type = proto.RouteType_LOCAL_TUNNEL
-					} else {
-						rt.Type = proto.RouteType_REMOTE_TUNNEL
+				rt.TunnelType = &proto.TunnelType{}
+				for _, ref := range ri.Refs {
+					if ref.NodeName != ri.Refs[0].NodeName {
+						// This reference is on a different node to entry 0, so don't include.
+						continue
 					}
 
-					rt.TunnelType = &proto.TunnelType{}
-					for _, ref := range ri.Refs {
-						if ref.NodeName != ri.Refs[0].NodeName {
-							// This reference is on a different node to entry 0, so don't include.
-							continue
-						}
-
-						switch ref.RefType {
-						case RefTypeIPIP:
-							rt.TunnelType.Ipip = true
-						case RefTypeVXLAN:
-							rt.TunnelType.Vxlan = true
-						case RefTypeWireguard:
-							rt.TunnelType.Wireguard = true
-						}
+					switch ref.RefType {
+					case RefTypeIPIP:
+						rt.TunnelType.Ipip = true
+					case RefTypeVXLAN:
+						rt.TunnelType.Vxlan = true
+					case RefTypeWireguard:
+						rt.TunnelType.Wireguard = true
 					}
 				}
 			}
 		}
+	}
 
-		if rt.DstNodeName != "" {
-			dstNodeInfo, exists := c.nodeNameToNodeInfo[rt.DstNodeName]
-			if exists {
-				rt.DstNodeIp = dstNodeInfo.Addr.String()
+	if rt.DstNodeName != "" {
+		dstNodeInfo, exists := c.nodeNameToNodeInfo[rt.DstNodeName]
+		if exists {
+			// Use the node's address of the same IP version as the route.
+			if addr := dstNodeInfo.AddrForVersion(cidr.Version()); addr != nil {
+				rt.DstNodeIp = addr.String()
 			}
 		}
-		rt.SameSubnet = poolAllowsCrossSubnet && c.nodeInOurSubnet(rt.DstNodeName)
+	}
+	rt.SameSubnet = poolAllowsCrossSubnet && c.nodeInOurSubnet(rt.DstNodeName, cidr.Version())
+	for _, hop := range rt.NextHops {
+		// The dataplane can only skip encapsulation for an ECMP route if it can do so for all of the next hops.
+		rt.SameSubnet = rt.SameSubnet && c.nodeInOurSubnet(hop.NodeName, cidr.Version())
+	}
 
-		logrus.WithField("route", rt).Debug("Sending route")
-		c.callbacks.OnRouteUpdate(rt)
-		c.trie.SetRouteSent(cidr, true)
+	return rt
+}
 
-		return set.RemoveItem
+// nextHopsFromRefs returns a next hop for each remote node that has a workload with the CIDR of the given Refs,
+// weighted by the number of such workloads on the node.  It returns nil if there are fewer than two such nodes,
+// since the route then only needs its usual DstNodeName and DstNodeIp.
+func (c *L3RouteResolver) nextHopsFromRefs(refs []Ref, version uint8) []*proto.RouteNextHop {
+	var hops []*proto.RouteNextHop
+	for _, ref := range refs {
+		if ref.RefType != RefTypeWEP || ref.NodeName == c.myNodeName {
+			continue
+		}
+		hop := &proto.RouteNextHop{
+			NodeName: ref.NodeName,
+			Weight:   int32(ref.RefCount),
+		}
+		if nodeInfo, exists := c.nodeNameToNodeInfo[ref.NodeName]; exists {
+			if addr := nodeInfo.AddrForVersion(version); addr != nil {
+				hop.NodeIp = addr.String()
+			}
+		}
+		hops = append(hops, hop)
+	}
+	if len(hops) < 2 {
+		return nil
+	}
+	return hops
+}
+
+// SentRoutes returns the routes that are currently active in the dataplane.  Intended for
+// diagnostics; it recalculates each route from scratch.
+func (c *L3RouteResolver) SentRoutes() []*proto.RouteUpdate {
+	var routes []*proto.RouteUpdate
+	var buf []RouteInfo
+	c.trie.Visit(func(cidr ip.CIDR, ri RouteInfo) bool {
+		if !ri.WasSent {
+			return true
+		}
+		buf = c.trie.LookupPath(buf, cidr)
+		routes = append(routes, c.routeFromLookupPath(cidr, buf))
+		return true
 	})
+	return routes
 }
 
-// nodeInOurSubnet returns true if the IP of the given node is known and it's in our subnet.
-// Return false if either the remote IP or our subnet is not known.
-func (c *L3RouteResolver) nodeInOurSubnet(name string) bool {
+// nodeInOurSubnet returns true if the IP of the given node (of the given IP version) is known and it's in
+// our subnet.  Return false if either the remote IP or our subnet is not known.
+func (c *L3RouteResolver) nodeInOurSubnet(name string, version uint8) bool {
 	localNodeInfo, exists := c.nodeNameToNodeInfo[c.myNodeName]
 	if !exists {
 		return false
@@ -752,13 +901,13 @@ func (c *L3RouteResolver) nodeInOurSubnet(name string) bool {
 		return false
 	}
 
-	return localNodeInfo.CIDR.ContainsV4(nodeInfo.Addr)
+	return localNodeInfo.SubnetContains(nodeInfo, version)
 }
 
 // nodenameRoute is the L3RouteResolver's internal representation of a route.
 type nodenameRoute struct {
 	nodeName string
-	dst      ip.V4CIDR
+	dst      ip.CIDR
 }
 
 func (r nodenameRoute) Key() string {
@@ -793,19 +942,28 @@ func (r nodenameRoute) String() string {
 //
 // The RouteTrie maintains a set of dirty CIDRs.  When an IPAM pool is updated, all the CIDRs under it are
 // marked dirty.
+//
+// IPv4 and IPv6 CIDRs are stored in separate tries; the methods take an ip.CIDR of either version and
+// dispatch to the appropriate one.
 type RouteTrie struct {
-	t          *ip.V4Trie
+	v4         *ip.V4Trie
+	v6         *ip.V6Trie
 	dirtyCIDRs set.Set
+
+	// Scratch buffers for LookupPath.
+	v4Buf []ip.V4TrieEntry
+	v6Buf []ip.V6TrieEntry
 }
 
 func NewRouteTrie() *RouteTrie {
 	return &RouteTrie{
-		t:          &ip.V4Trie{},
+		v4:         &ip.V4Trie{},
+		v6:         &ip.V6Trie{},
 		dirtyCIDRs: set.New(),
 	}
 }
 
-func (r *RouteTrie) UpdatePool(cidr ip.V4CIDR, poolType proto.IPPoolType, natOutgoing bool, crossSubnet bool) {
+func (r *RouteTrie) UpdatePool(cidr ip.CIDR, poolType proto.IPPoolType, natOutgoing bool, crossSubnet bool) {
 	logrus.WithFields(logrus.Fields{
 		"cidr":        cidr,
 		"poolType":    poolType,
@@ -823,35 +981,45 @@ func (r *RouteTrie) UpdatePool(cidr ip.V4CIDR, poolType proto.IPPoolType, natOut
 	r.markChildrenDirty(cidr)
 }
 
-func (r *RouteTrie) markChildrenDirty(cidr ip.V4CIDR) {
+func (r *RouteTrie) markChildrenDirty(cidr ip.CIDR) {
 	// TODO: avoid full scan to mark children dirty
-	r.t.Visit(func(c ip.V4CIDR, data interface{}) bool {
-		if cidr.ContainsV4(c.Addr().(ip.V4Addr)) {
-			r.MarkCIDRDirty(c)
-		}
-		return true
-	})
+	switch cidr := cidr.(type) {
+	case ip.V4CIDR:
+		r.v4.Visit(func(c ip.V4CIDR, data interface{}) bool {
+			if cidr.ContainsV4(c.Addr().(ip.V4Addr)) {
+				r.MarkCIDRDirty(c)
+			}
+			return true
+		})
+	case ip.V6CIDR:
+		r.v6.Visit(func(c ip.V6CIDR, data interface{}) bool {
+			if cidr.ContainsV6(c.Addr().(ip.V6Addr)) {
+				r.MarkCIDRDirty(c)
+			}
+			return true
+		})
+	}
 }
 
-func (r *RouteTrie) MarkCIDRDirty(cidr ip.V4CIDR) {
+func (r *RouteTrie) MarkCIDRDirty(cidr ip.CIDR) {
 	r.dirtyCIDRs.Add(cidr)
 }
 
-func (r *RouteTrie) RemovePool(cidr ip.V4CIDR) {
+func (r *RouteTrie) RemovePool(cidr ip.CIDR) {
 	r.UpdatePool(cidr, proto.IPPoolType_NONE, false, false)
 }
 
-func (r *RouteTrie) UpdateBlockRoute(cidr ip.V4CIDR, nodeName string) {
+func (r *RouteTrie) UpdateBlockRoute(cidr ip.CIDR, nodeName string) {
 	r.updateCIDR(cidr, func(ri *RouteInfo) {
 		ri.Block.NodeName = nodeName
 	})
 }
 
-func (r *RouteTrie) RemoveBlockRoute(cidr ip.V4CIDR) {
+func (r *RouteTrie) RemoveBlockRoute(cidr ip.CIDR) {
 	r.UpdateBlockRoute(cidr, "")
 }
 
-func (r *RouteTrie) AddHost(cidr ip.V4CIDR, nodeName string) {
+func (r *RouteTrie) AddHost(cidr ip.CIDR, nodeName string) {
 	r.updateCIDR(cidr, func(ri *RouteInfo) {
 		ri.Host.NodeNames = append(ri.Host.NodeNames, nodeName)
 		if len(ri.Host.NodeNames) > 1 {
@@ -865,7 +1033,7 @@ func (r *RouteTrie) AddHost(cidr ip.V4CIDR, nodeName string) {
 	})
 }
 
-func (r *RouteTrie) RemoveHost(cidr ip.V4CIDR, nodeName string) {
+func (r *RouteTrie) RemoveHost(cidr ip.CIDR, nodeName string) {
 	r.updateCIDR(cidr, func(ri *RouteInfo) {
 		var ns []string
 		for _, n := range ri.Host.NodeNames {
@@ -878,7 +1046,7 @@ func (r *RouteTrie) RemoveHost(cidr ip.V4CIDR, nodeName string) {
 	})
 }
 
-func (r *RouteTrie) AddRef(cidr ip.V4CIDR, nodename string, rt RefType) {
+func (r *RouteTrie) AddRef(cidr ip.CIDR, nodename string, rt RefType) {
 	r.updateCIDR(cidr, func(ri *RouteInfo) {
 		// Find the ref in the list for this nodename,
 		// if it exists. If it doesn't, we'll add it below.
@@ -906,7 +1074,7 @@ func (r *RouteTrie) AddRef(cidr ip.V4CIDR, nodename string, rt RefType) {
 	})
 }
 
-func (r *RouteTrie) RemoveRef(cidr ip.V4CIDR, nodename string, rt RefType) {
+func (r *RouteTrie) RemoveRef(cidr ip.CIDR, nodename string, rt RefType) {
 	r.updateCIDR(cidr, func(ri *RouteInfo) {
 		for i := range ri.Refs {
 			if ri.Refs[i].NodeName == nodename && ri.Refs[i].RefType == rt {
@@ -930,13 +1098,13 @@ func (r *RouteTrie) RemoveRef(cidr ip.V4CIDR, nodename string, rt RefType) {
 	})
 }
 
-func (r *RouteTrie) SetRouteSent(cidr ip.V4CIDR, sent bool) {
+func (r *RouteTrie) SetRouteSent(cidr ip.CIDR, sent bool) {
 	r.updateCIDR(cidr, func(ri *RouteInfo) {
 		ri.WasSent = sent
 	})
 }
 
-func (r RouteTrie) updateCIDR(cidr ip.V4CIDR, updateFn func(info *RouteInfo)) bool {
+func (r RouteTrie) updateCIDR(cidr ip.CIDR, updateFn func(info *RouteInfo)) bool {
 	// Get the RouteInfo for the given CIDR and take a copy so we can compare.
 	ri := r.Get(cidr)
 	riCopy := ri.Copy()
@@ -957,21 +1125,72 @@ func (r RouteTrie) updateCIDR(cidr ip.V4CIDR, updateFn func(info *RouteInfo)) bo
 	if ri.IsZero() {
 		// No longer have *anything* to track about this CIDR, clean it up.
 		logrus.WithField("cidr", cidr).Debug("RouteInfo is zero, cleaning up.")
-		r.t.Delete(cidr)
+		switch cidr := cidr.(type) {
+		case ip.V4CIDR:
+			r.v4.Delete(cidr)
+		case ip.V6CIDR:
+			r.v6.Delete(cidr)
+		}
 		return true
 	}
-	r.t.Update(cidr, ri)
+	switch cidr := cidr.(type) {
+	case ip.V4CIDR:
+		r.v4.Update(cidr, ri)
+	case ip.V6CIDR:
+		r.v6.Update(cidr, ri)
+	}
 	return true
 }
 
-func (r RouteTrie) Get(cidr ip.V4CIDR) RouteInfo {
-	ri := r.t.Get(cidr)
+func (r RouteTrie) Get(cidr ip.CIDR) RouteInfo {
+	var ri interface{}
+	switch cidr := cidr.(type) {
+	case ip.V4CIDR:
+		ri = r.v4.Get(cidr)
+	case ip.V6CIDR:
+		ri = r.v6.Get(cidr)
+	}
 	if ri == nil {
 		return RouteInfo{}
 	}
 	return ri.(RouteInfo)
 }
 
+// LookupPath returns the RouteInfo for each CIDR in the trie that encloses the given CIDR, outermost
+// first.  If buffer is non-nil, then it is used to store the entries.  If the CIDR is not in the trie
+// then an empty slice is returned.
+func (r *RouteTrie) LookupPath(buffer []RouteInfo, cidr ip.CIDR) []RouteInfo {
+	buffer = buffer[:0]
+	switch cidr := cidr.(type) {
+	case ip.V4CIDR:
+		r.v4Buf = r.v4.LookupPath(r.v4Buf, cidr)
+		for _, e := range r.v4Buf {
+			buffer = append(buffer, e.Data.(RouteInfo))
+		}
+	case ip.V6CIDR:
+		r.v6Buf = r.v6.LookupPath(r.v6Buf, cidr)
+		for _, e := range r.v6Buf {
+			buffer = append(buffer, e.Data.(RouteInfo))
+		}
+	}
+	return buffer
+}
+
+// Visit calls f for each CIDR in the trie, IPv4 CIDRs first.  Iteration stops if f returns false.
+func (r *RouteTrie) Visit(f func(cidr ip.CIDR, ri RouteInfo) bool) {
+	keepGoing := true
+	r.v4.Visit(func(cidr ip.V4CIDR, data interface{}) bool {
+		keepGoing = f(cidr, data.(RouteInfo))
+		return keepGoing
+	})
+	if !keepGoing {
+		return
+	}
+	r.v6.Visit(func(cidr ip.V6CIDR, data interface{}) bool {
+		return f(cidr, data.(RouteInfo))
+	})
+}
+
 type RouteInfo struct {
 	// Pool contains information extracted from the IP pool that has this CIDR.
 	Pool struct {
@@ -1056,18 +1275,18 @@ func (r RouteInfo) Equals(other RouteInfo) bool {
 // It uses a reference counter so that we can properly handle intermediate cases where
 // the same CIDR might appear twice.
 type nodeRoutes struct {
-	cache map[string]map[ip.V4CIDR]int
+	cache map[string]map[ip.CIDR]int
 }
 
 func newNodeRoutes() nodeRoutes {
 	return nodeRoutes{
-		cache: map[string]map[ip.V4CIDR]int{},
+		cache: map[string]map[ip.CIDR]int{},
 	}
 }
 
 func (nr *nodeRoutes) Add(r nodenameRoute) {
 	if _, ok := nr.cache[r.nodeName]; !ok {
-		nr.cache[r.nodeName] = map[ip.V4CIDR]int{r.dst: 0}
+		nr.cache[r.nodeName] = map[ip.CIDR]int{r.dst: 0}
 	}
 	nr.cache[r.nodeName][r.dst]++
 }
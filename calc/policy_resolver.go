@@ -164,6 +164,20 @@ func (pr *PolicyResolver) sendEndpointUpdate(endpointID interface{}) error {
 			nil, []tierInfo{})
 		return nil
 	}
+	applicableTiers := pr.tiersForEndpoint(endpointID)
+	log.Debugf("Endpoint tier update: %v -> %v", endpointID, applicableTiers)
+	pr.Callbacks.OnEndpointTierUpdate(endpointID.(model.Key),
+		endpoint, applicableTiers)
+	return nil
+}
+
+// tiersForEndpoint returns the tiers and policies that apply to the given endpoint, in the order
+// that they should be applied.  It refreshes the sort order if needed since the debug endpoints
+// call it outside of a flush.
+func (pr *PolicyResolver) tiersForEndpoint(endpointID interface{}) []tierInfo {
+	if pr.sortRequired {
+		pr.refreshSortOrder()
+	}
 	applicableTiers := []tierInfo{}
 	tier := pr.sortedTierData
 	tierMatches := false
@@ -184,8 +198,5 @@ func (pr *PolicyResolver) sendEndpointUpdate(endpointID interface{}) error {
 		log.Debugf("Tier %v matches %v", tier.Name, endpointID)
 		applicableTiers = append(applicableTiers, filteredTier)
 	}
-	log.Debugf("Endpoint tier update: %v -> %v", endpointID, applicableTiers)
-	pr.Callbacks.OnEndpointTierUpdate(endpointID.(model.Key),
-		endpoint, applicableTiers)
-	return nil
+	return applicableTiers
 }
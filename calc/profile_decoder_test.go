@@ -153,7 +153,7 @@ func (p *passthruCallbackRecorder) OnIPPoolRemove(model.IPPoolKey) {
 	Fail("IPPoolRemove received")
 }
 
-func (p *passthruCallbackRecorder) OnWireguardUpdate(string, *model.Wireguard) {
+func (p *passthruCallbackRecorder) OnWireguardUpdate(string, *model.Wireguard, *net.IP) {
 	Fail("OnWireguardUpdate received")
 }
 
@@ -0,0 +1,163 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc
+
+import (
+	"fmt"
+
+	"github.com/projectcalico/libcalico-go/lib/set"
+
+	"github.com/projectcalico/felix/proto"
+)
+
+// ReplayChecker tracks the state implied by the messages that the calculation graph sends to the
+// dataplane and checks that each message is consistent with that state; for example, that IP set
+// deltas only apply to known IP sets and that endpoints only reference active policies and
+// profiles, which are guarantees that the EventSequencer makes.  It is the sink for
+// "calico-felix replay" so, unlike the mock dataplane, it reports problems as errors rather than
+// failing a test.
+type ReplayChecker struct {
+	ipSets          map[string]set.Set
+	activePolicies  set.Set
+	activeProfiles  set.Set
+	endpointToPols  map[string][]proto.PolicyID
+	endpointToProfs map[string][]string
+	serviceAccounts set.Set
+	namespaces      set.Set
+	vteps           set.Set
+}
+
+func NewReplayChecker() *ReplayChecker {
+	return &ReplayChecker{
+		ipSets:          map[string]set.Set{},
+		activePolicies:  set.New(),
+		activeProfiles:  set.New(),
+		endpointToPols:  map[string][]proto.PolicyID{},
+		endpointToProfs: map[string][]string{},
+		serviceAccounts: set.New(),
+		namespaces:      set.New(),
+		vteps:           set.New(),
+	}
+}
+
+// OnEvent updates the tracked state with the given message.  It returns an error describing the
+// first inconsistency that it finds in the message, if any; the state is still updated so that
+// checking can continue with the next message.
+func (c *ReplayChecker) OnEvent(event interface{}) error {
+	switch event := event.(type) {
+	case nil:
+		return fmt.Errorf("nil event")
+	case *proto.IPSetUpdate:
+		members := set.New()
+		for _, m := range event.Members {
+			members.Add(m)
+		}
+		c.ipSets[event.Id] = members
+	case *proto.IPSetDeltaUpdate:
+		members, ok := c.ipSets[event.Id]
+		if !ok {
+			return fmt.Errorf("IP set delta to unknown IP set %v", event.Id)
+		}
+		var err error
+		for _, m := range event.AddedMembers {
+			if members.Contains(m) && err == nil {
+				err = fmt.Errorf("IP set %v already contained added member %v", event.Id, m)
+			}
+			members.Add(m)
+		}
+		for _, m := range event.RemovedMembers {
+			if !members.Contains(m) && err == nil {
+				err = fmt.Errorf("IP set %v did not contain removed member %v", event.Id, m)
+			}
+			members.Discard(m)
+		}
+		return err
+	case *proto.IPSetRemove:
+		if _, ok := c.ipSets[event.Id]; !ok {
+			return fmt.Errorf("IP set remove for unknown IP set %v", event.Id)
+		}
+		delete(c.ipSets, event.Id)
+	case *proto.ActivePolicyUpdate:
+		c.activePolicies.Add(*event.Id)
+	case *proto.ActivePolicyRemove:
+		c.activePolicies.Discard(*event.Id)
+		for ep, pols := range c.endpointToPols {
+			for _, p := range pols {
+				if p == *event.Id {
+					return fmt.Errorf("policy %v removed while still in use by endpoint %s", p, ep)
+				}
+			}
+		}
+	case *proto.ActiveProfileUpdate:
+		c.activeProfiles.Add(*event.Id)
+	case *proto.ActiveProfileRemove:
+		c.activeProfiles.Discard(*event.Id)
+		for ep, profs := range c.endpointToProfs {
+			for _, p := range profs {
+				if p == event.Id.Name {
+					return fmt.Errorf("profile %s removed while still in use by endpoint %s", p, ep)
+				}
+			}
+		}
+	case *proto.WorkloadEndpointUpdate:
+		id := fmt.Sprintf("%v", *event.Id)
+		var polIDs []proto.PolicyID
+		for _, tier := range event.Endpoint.Tiers {
+			for _, names := range [][]string{tier.IngressPolicies, tier.EgressPolicies} {
+				for _, name := range names {
+					polIDs = append(polIDs, proto.PolicyID{Tier: tier.Name, Name: name})
+				}
+			}
+		}
+		c.endpointToPols[id] = polIDs
+		c.endpointToProfs[id] = event.Endpoint.ProfileIds
+		for _, polID := range polIDs {
+			if !c.activePolicies.Contains(polID) {
+				return fmt.Errorf("workload endpoint %s references inactive policy %v", id, polID)
+			}
+		}
+		for _, name := range event.Endpoint.ProfileIds {
+			if !c.activeProfiles.Contains(proto.ProfileID{Name: name}) {
+				return fmt.Errorf("workload endpoint %s references inactive profile %s", id, name)
+			}
+		}
+	case *proto.WorkloadEndpointRemove:
+		id := fmt.Sprintf("%v", *event.Id)
+		delete(c.endpointToPols, id)
+		delete(c.endpointToProfs, id)
+	case *proto.ServiceAccountUpdate:
+		c.serviceAccounts.Add(*event.Id)
+	case *proto.ServiceAccountRemove:
+		if !c.serviceAccounts.Contains(*event.Id) {
+			return fmt.Errorf("remove for unknown service account %v", *event.Id)
+		}
+		c.serviceAccounts.Discard(*event.Id)
+	case *proto.NamespaceUpdate:
+		c.namespaces.Add(*event.Id)
+	case *proto.NamespaceRemove:
+		if !c.namespaces.Contains(*event.Id) {
+			return fmt.Errorf("remove for unknown namespace %v", *event.Id)
+		}
+		c.namespaces.Discard(*event.Id)
+	case *proto.VXLANTunnelEndpointUpdate:
+		c.vteps.Add(event.Node)
+	case *proto.VXLANTunnelEndpointRemove:
+		if !c.vteps.Contains(event.Node) {
+			return fmt.Errorf("remove for unknown VTEP %v", event.Node)
+		}
+		c.vteps.Discard(event.Node)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc_test
+
+import (
+	. "github.com/projectcalico/felix/calc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/proto"
+)
+
+var _ = Describe("ReplayChecker", func() {
+	var checker *ReplayChecker
+	polID := proto.PolicyID{Tier: "default", Name: "pol-1"}
+	wepID := proto.WorkloadEndpointID{OrchestratorId: "k8s", WorkloadId: "ns/pod", EndpointId: "eth0"}
+	wepUpdate := &proto.WorkloadEndpointUpdate{
+		Id: &wepID,
+		Endpoint: &proto.WorkloadEndpoint{
+			Tiers: []*proto.TierInfo{{Name: "default", IngressPolicies: []string{"pol-1"}}},
+		},
+	}
+
+	BeforeEach(func() {
+		checker = NewReplayChecker()
+	})
+
+	It("should accept a consistent sequence of messages", func() {
+		Expect(checker.OnEvent(&proto.IPSetUpdate{Id: "s:abcd", Members: []string{"10.0.0.1"}})).To(Succeed())
+		Expect(checker.OnEvent(&proto.IPSetDeltaUpdate{
+			Id:             "s:abcd",
+			AddedMembers:   []string{"10.0.0.2"},
+			RemovedMembers: []string{"10.0.0.1"},
+		})).To(Succeed())
+		Expect(checker.OnEvent(&proto.ActivePolicyUpdate{Id: &polID, Policy: &proto.Policy{}})).To(Succeed())
+		Expect(checker.OnEvent(wepUpdate)).To(Succeed())
+		Expect(checker.OnEvent(&proto.WorkloadEndpointRemove{Id: &wepID})).To(Succeed())
+		Expect(checker.OnEvent(&proto.ActivePolicyRemove{Id: &polID})).To(Succeed())
+		Expect(checker.OnEvent(&proto.IPSetRemove{Id: "s:abcd"})).To(Succeed())
+	})
+
+	It("should flag a delta to an unknown IP set", func() {
+		Expect(checker.OnEvent(&proto.IPSetDeltaUpdate{Id: "s:abcd"})).To(HaveOccurred())
+	})
+
+	It("should flag removal of a member that isn't in the IP set", func() {
+		Expect(checker.OnEvent(&proto.IPSetUpdate{Id: "s:abcd"})).To(Succeed())
+		Expect(checker.OnEvent(&proto.IPSetDeltaUpdate{
+			Id:             "s:abcd",
+			RemovedMembers: []string{"10.0.0.1"},
+		})).To(HaveOccurred())
+	})
+
+	It("should flag an endpoint that references an inactive policy", func() {
+		Expect(checker.OnEvent(wepUpdate)).To(HaveOccurred())
+	})
+
+	It("should flag removal of a policy that is still in use", func() {
+		Expect(checker.OnEvent(&proto.ActivePolicyUpdate{Id: &polID, Policy: &proto.Policy{}})).To(Succeed())
+		Expect(checker.OnEvent(wepUpdate)).To(Succeed())
+		Expect(checker.OnEvent(&proto.ActivePolicyRemove{Id: &polID})).To(HaveOccurred())
+	})
+})
@@ -67,6 +67,9 @@ var remoteWlEpKey1 = WorkloadEndpointKey{Hostname: remoteHostname, OrchestratorI
 // Same as remoteWlEpKey1 but on a different host.
 var remoteWlEpKey2 = WorkloadEndpointKey{Hostname: remoteHostname2, OrchestratorID: "orch", WorkloadID: "wl1", EndpointID: "ep1"}
 
+// A second workload on the same host as remoteWlEpKey2.
+var remoteWlEpKey3 = WorkloadEndpointKey{Hostname: remoteHostname2, OrchestratorID: "orch", WorkloadID: "wl2", EndpointID: "ep1"}
+
 var localWlEp1 = WorkloadEndpoint{
 	State:      "active",
 	Name:       "cali1",
@@ -588,6 +591,9 @@ var remoteHost2IP = mustParseIP("192.168.0.3")
 var localHostIPWithPrefix = "192.168.0.1/24"
 var remoteHostIPWithPrefix = "192.168.0.2/24"
 
+var remoteHostIPv6 = mustParseIP("dead:beef::2")
+var remoteHostIPv6WithPrefix = "dead:beef::2/96"
+
 var localHostVXLANTunnelConfigKey = HostConfigKey{
 	Hostname: localHostname,
 	Name:     "IPv4VXLANTunnelAddr",
@@ -601,6 +607,11 @@ var remoteHost2VXLANTunnelConfigKey = HostConfigKey{
 	Name:     "IPv4VXLANTunnelAddr",
 }
 
+var remoteHostVXLANV6TunnelConfigKey = HostConfigKey{
+	Hostname: remoteHostname,
+	Name:     "IPv6VXLANTunnelAddr",
+}
+
 var remoteHostVXLANTunnelMACConfigKey = HostConfigKey{
 	Hostname: remoteHostname,
 	Name:     "VXLANTunnelMACAddr",
@@ -626,11 +637,16 @@ var ipPoolWithIPIP = IPPool{
 }
 
 var v6IPPoolKey = IPPoolKey{
-	CIDR: mustParseNet("feed:beef::/64"),
+	CIDR: mustParseNet("feed:beef::/32"),
 }
 
 var v6IPPool = IPPool{
-	CIDR: mustParseNet("feed:beef::/64"),
+	CIDR: mustParseNet("feed:beef::/32"),
+}
+
+var v6IPPoolWithVXLAN = IPPool{
+	CIDR:      mustParseNet("feed:beef::/32"),
+	VXLANMode: encap.Always,
 }
 
 var ipPoolWithVXLAN = IPPool{
@@ -641,6 +657,8 @@ var ipPoolWithVXLAN = IPPool{
 
 var workloadIPs = "WorkloadIPs"
 
+var multipathEnabled = "true"
+
 var ipPoolWithVXLANSlash32 = IPPool{
 	CIDR:       mustParseNet("10.0.0.0/32"),
 	VXLANMode:  encap.Always,
@@ -772,4 +790,5 @@ var localHostVXLANTunnelIP = "10.0.0.0"
 var remoteHostVXLANTunnelIP = "10.0.1.0"
 var remoteHostVXLANTunnelIP2 = "10.0.1.1"
 var remoteHost2VXLANTunnelIP = "10.0.2.0"
+var remoteHostVXLANV6TunnelIP = "feed:beef:1::"
 var remoteHostVXLANTunnelMAC = "66:74:c5:72:3f:01"
@@ -0,0 +1,544 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	calinet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+	"github.com/projectcalico/libcalico-go/lib/set"
+)
+
+var (
+	gaugeNumShadowedRules = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "felix_calc_shadowed_rules",
+		Help: "Number of rules in active policies that can never match because an earlier rule covers them.",
+	})
+	gaugeNumUnreachablePolicies = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "felix_calc_unreachable_policies",
+		Help: "Number of active policies (per direction) that are never reached because of an earlier policy or tier.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(gaugeNumShadowedRules)
+	prometheus.MustRegister(gaugeNumUnreachablePolicies)
+}
+
+// RuleAnalysis holds the results of analysing the active policies for rules and policies that
+// have no effect.
+type RuleAnalysis struct {
+	ShadowedRules       []ShadowedRule      `json:"shadowedRules"`
+	UnreachablePolicies []UnreachablePolicy `json:"unreachablePolicies"`
+}
+
+// ShadowedRule is a rule that can never match because an earlier rule, either in the same policy
+// or in an earlier policy in the same tier, matches every packet that it would match.
+type ShadowedRule struct {
+	Tier      string `json:"tier"`
+	Policy    string `json:"policy"`
+	Direction string `json:"direction"`
+	RuleIndex int    `json:"ruleIndex"`
+
+	ShadowedByPolicy    string `json:"shadowedByPolicy"`
+	ShadowedByRuleIndex int    `json:"shadowedByRuleIndex"`
+}
+
+// UnreachablePolicy is a policy that is never evaluated (for the given direction) because an
+// earlier policy in the same tier matches all traffic, or because an earlier tier never passes
+// traffic on to later tiers.
+type UnreachablePolicy struct {
+	Tier      string `json:"tier"`
+	Policy    string `json:"policy"`
+	Direction string `json:"direction"`
+	Reason    string `json:"reason"`
+}
+
+// AnalyseRules looks for shadowed rules and unreachable policies.  Since which policies apply, and
+// in what order, depends on the endpoint, the policies are analysed in the context of each local
+// endpoint; a rule (or policy) is only reported if it has no effect for any of the local endpoints
+// that it applies to.
+//
+// The analysis is conservative: it only reports rules that are definitely covered by an earlier
+// rule, based on the rules' match criteria alone.  (For example, a rule that matches one selector
+// isn't considered to be covered by a rule that matches a different selector, even if the second
+// selector happens to match a superset of the endpoints.)  Only the normal filter policies are
+// analysed, not untracked or pre-DNAT policies, or profiles.
+//
+// Must be called from the goroutine that owns the graph.
+func (cg *CalcGraph) AnalyseRules() *RuleAnalysis {
+	a := &ruleAnalyser{
+		ruleScanner:        cg.ruleScanner,
+		parsedRules:        map[model.PolicyKey]*ParsedRules{},
+		analysedSequences:  set.New(),
+		shadowedRules:      map[analysedRuleID]ShadowedRule{},
+		reachableRules:     set.New(),
+		unreachablePols:    map[analysedPolicyID]UnreachablePolicy{},
+		reachablePolicyIDs: set.New(),
+	}
+
+	// Many endpoints tend to share the same sequence of policies so we only analyse each
+	// distinct sequence once.
+	for key := range cg.policyResolver.endpoints {
+		tiers := cg.policyResolver.tiersForEndpoint(key)
+		for _, direction := range []string{"ingress", "egress"} {
+			a.analyseSequence(tiers, direction)
+		}
+	}
+	return a.result()
+}
+
+type analysedRuleID struct {
+	tier, policy, direction string
+	index                   int
+}
+
+type analysedPolicyID struct {
+	tier, policy, direction string
+}
+
+type ruleAnalyser struct {
+	ruleScanner       *RuleScanner
+	parsedRules       map[model.PolicyKey]*ParsedRules
+	analysedSequences set.Set
+
+	// A rule is reported as shadowed if it was shadowed in every sequence that it appeared in
+	// (and wasn't itself in an unreachable policy).
+	shadowedRules  map[analysedRuleID]ShadowedRule
+	reachableRules set.Set
+
+	// Similarly, a policy is reported as unreachable if it was unreachable in every sequence.
+	unreachablePols    map[analysedPolicyID]UnreachablePolicy
+	reachablePolicyIDs set.Set
+}
+
+type coveringRule struct {
+	policy string
+	index  int
+	rule   *ParsedRule
+}
+
+func (a *ruleAnalyser) analyseSequence(tiers []tierInfo, direction string) {
+	var seqID strings.Builder
+	seqID.WriteString(direction)
+	for _, tier := range tiers {
+		seqID.WriteString("|" + tier.Name)
+		for _, polKV := range tier.OrderedPolicies {
+			seqID.WriteString("," + polKV.Key.Name)
+		}
+	}
+	if a.analysedSequences.Contains(seqID.String()) {
+		return
+	}
+	a.analysedSequences.Add(seqID.String())
+
+	// Mimic the dataplane: if a tier has any policies for this direction then traffic that
+	// doesn't hit a pass rule is allowed or dropped within the tier.
+	earlierTierTerminates := ""
+	for _, tier := range tiers {
+		var terminalRules []coveringRule
+		catchAllPolicy := ""
+		tierPasses := false
+		tierHasPolicies := false
+		for _, polKV := range tier.OrderedPolicies {
+			rules := a.rulesForDirection(polKV, direction)
+			if rules == nil {
+				continue
+			}
+			tierHasPolicies = true
+			polID := analysedPolicyID{tier: tier.Name, policy: polKV.Key.Name, direction: direction}
+
+			if earlierTierTerminates != "" {
+				a.recordUnreachable(polID, "tier "+earlierTierTerminates+" never passes traffic to later tiers")
+				continue
+			}
+			if catchAllPolicy != "" {
+				a.recordUnreachable(polID, "policy "+catchAllPolicy+" matches all traffic")
+				continue
+			}
+			a.reachablePolicyIDs.Add(polID)
+
+			for i, rule := range rules {
+				ruleID := analysedRuleID{tier: tier.Name, policy: polKV.Key.Name, direction: direction, index: i}
+				if by := findCoveringRule(terminalRules, rule); by != nil {
+					if _, ok := a.shadowedRules[ruleID]; !ok {
+						a.shadowedRules[ruleID] = ShadowedRule{
+							Tier:                tier.Name,
+							Policy:              polKV.Key.Name,
+							Direction:           direction,
+							RuleIndex:           i,
+							ShadowedByPolicy:    by.policy,
+							ShadowedByRuleIndex: by.index,
+						}
+					}
+					continue
+				}
+				a.reachableRules.Add(ruleID)
+
+				action := normaliseAction(rule.Action)
+				if action == "log" {
+					continue
+				}
+				terminalRules = append(terminalRules, coveringRule{policy: polKV.Key.Name, index: i, rule: rule})
+				if action == "pass" {
+					tierPasses = true
+				}
+				if catchAllPolicy == "" && ruleMatchesAll(rule) {
+					// Later rules in this policy will be reported as shadowed; later policies
+					// are unreachable.
+					catchAllPolicy = polKV.Key.Name
+				}
+			}
+		}
+		if tierHasPolicies && !tierPasses && earlierTierTerminates == "" {
+			earlierTierTerminates = tier.Name
+		}
+	}
+}
+
+// rulesForDirection returns the parsed rules of the policy for the given direction or nil if the
+// policy doesn't apply to that direction in the filter table.
+func (a *ruleAnalyser) rulesForDirection(polKV PolKV, direction string) []*ParsedRule {
+	pol := polKV.Value
+	if pol == nil || pol.DoNotTrack || pol.PreDNAT || a.ruleScanner.PolicyIsAuditOnly(polKV.Key) {
+		// Audit-only policies don't affect traffic so they can't shadow anything; their own rules
+		// are worth checking but only once they're enforced.
+		return nil
+	}
+	if direction == "ingress" && !polKV.GovernsIngress() || direction == "egress" && !polKV.GovernsEgress() {
+		return nil
+	}
+	parsed := a.parsedRules[polKV.Key]
+	if parsed == nil {
+		parsed = &ParsedRules{
+			InboundRules:  parseRules(pol.InboundRules),
+			OutboundRules: parseRules(pol.OutboundRules),
+		}
+		a.parsedRules[polKV.Key] = parsed
+	}
+	if direction == "ingress" {
+		return parsed.InboundRules
+	}
+	return parsed.OutboundRules
+}
+
+func parseRules(rules []model.Rule) []*ParsedRule {
+	// Return an empty, non-nil, slice for a policy with no rules; it still drops traffic.
+	parsed := make([]*ParsedRule, len(rules))
+	for i := range rules {
+		parsed[i], _ = ruleToParsedRule(&rules[i])
+	}
+	return parsed
+}
+
+func (a *ruleAnalyser) recordUnreachable(polID analysedPolicyID, reason string) {
+	if _, ok := a.unreachablePols[polID]; ok {
+		return
+	}
+	a.unreachablePols[polID] = UnreachablePolicy{
+		Tier:      polID.tier,
+		Policy:    polID.policy,
+		Direction: polID.direction,
+		Reason:    reason,
+	}
+}
+
+func (a *ruleAnalyser) result() *RuleAnalysis {
+	result := &RuleAnalysis{
+		ShadowedRules:       []ShadowedRule{},
+		UnreachablePolicies: []UnreachablePolicy{},
+	}
+	for id, sr := range a.shadowedRules {
+		if a.reachableRules.Contains(id) {
+			continue
+		}
+		result.ShadowedRules = append(result.ShadowedRules, sr)
+	}
+	sort.Slice(result.ShadowedRules, func(i, j int) bool {
+		x, y := result.ShadowedRules[i], result.ShadowedRules[j]
+		if x.Tier != y.Tier {
+			return x.Tier < y.Tier
+		}
+		if x.Policy != y.Policy {
+			return x.Policy < y.Policy
+		}
+		if x.Direction != y.Direction {
+			return x.Direction < y.Direction
+		}
+		return x.RuleIndex < y.RuleIndex
+	})
+	for id, up := range a.unreachablePols {
+		if a.reachablePolicyIDs.Contains(id) {
+			continue
+		}
+		result.UnreachablePolicies = append(result.UnreachablePolicies, up)
+	}
+	sort.Slice(result.UnreachablePolicies, func(i, j int) bool {
+		x, y := result.UnreachablePolicies[i], result.UnreachablePolicies[j]
+		if x.Tier != y.Tier {
+			return x.Tier < y.Tier
+		}
+		if x.Policy != y.Policy {
+			return x.Policy < y.Policy
+		}
+		return x.Direction < y.Direction
+	})
+	return result
+}
+
+func findCoveringRule(candidates []coveringRule, rule *ParsedRule) *coveringRule {
+	for i := range candidates {
+		if ruleCovers(candidates[i].rule, rule) {
+			return &candidates[i]
+		}
+	}
+	return nil
+}
+
+var emptyParsedRule = &ParsedRule{}
+
+// ruleMatchesAll returns true if the rule has no match criteria.
+func ruleMatchesAll(rule *ParsedRule) bool {
+	return ruleCovers(rule, emptyParsedRule)
+}
+
+// ruleCovers returns true if every packet that matches inner also matches outer.  It errs on the
+// side of returning false: criteria are only compared structurally so, for example, IP sets are
+// compared by ID rather than by their contents.
+func ruleCovers(outer, inner *ParsedRule) bool {
+	if outer.IPVersion != nil && (inner.IPVersion == nil || *inner.IPVersion != *outer.IPVersion) {
+		return false
+	}
+	if outer.Protocol != nil && (inner.Protocol == nil || !protocolsEqual(*outer.Protocol, *inner.Protocol)) {
+		return false
+	}
+	if outer.NotProtocol != nil {
+		excludedByInner := inner.NotProtocol != nil && protocolsEqual(*outer.NotProtocol, *inner.NotProtocol)
+		otherProtocol := inner.Protocol != nil && !protocolsEqual(*outer.NotProtocol, *inner.Protocol)
+		if !excludedByInner && !otherProtocol {
+			return false
+		}
+	}
+	if !intPtrCovers(outer.ICMPType, inner.ICMPType) || !intPtrCovers(outer.ICMPCode, inner.ICMPCode) {
+		return false
+	}
+	if (outer.NotICMPType != nil || outer.NotICMPCode != nil) &&
+		!(intPtrsEqual(outer.NotICMPType, inner.NotICMPType) && intPtrsEqual(outer.NotICMPCode, inner.NotICMPCode)) {
+		return false
+	}
+
+	// Source.
+	if !netsCover(outer.SrcNets, inner.SrcNets) || !allNetsWithin(outer.NotSrcNets, inner.NotSrcNets) {
+		return false
+	}
+	if !stringsSubset(outer.SrcIPSetIDs, inner.SrcIPSetIDs) || !stringsSubset(outer.NotSrcIPSetIDs, inner.NotSrcIPSetIDs) {
+		return false
+	}
+	if !portsCover(outer.SrcPorts, outer.SrcNamedPortIPSetIDs, inner.SrcPorts, inner.SrcNamedPortIPSetIDs) ||
+		!allPortsWithin(outer.NotSrcPorts, inner.NotSrcPorts) ||
+		!stringsSubset(outer.NotSrcNamedPortIPSetIDs, inner.NotSrcNamedPortIPSetIDs) {
+		return false
+	}
+
+	// Destination.
+	if !netsCover(outer.DstNets, inner.DstNets) || !allNetsWithin(outer.NotDstNets, inner.NotDstNets) {
+		return false
+	}
+	if !stringsSubset(outer.DstIPSetIDs, inner.DstIPSetIDs) || !stringsSubset(outer.NotDstIPSetIDs, inner.NotDstIPSetIDs) {
+		return false
+	}
+	if !portsCover(outer.DstPorts, outer.DstNamedPortIPSetIDs, inner.DstPorts, inner.DstNamedPortIPSetIDs) ||
+		!allPortsWithin(outer.NotDstPorts, inner.NotDstPorts) ||
+		!stringsSubset(outer.NotDstNamedPortIPSetIDs, inner.NotDstNamedPortIPSetIDs) {
+		return false
+	}
+
+	if outer.HTTPMatch != nil && !reflect.DeepEqual(outer.HTTPMatch, inner.HTTPMatch) {
+		return false
+	}
+	return true
+}
+
+func protocolsEqual(a, b numorstring.Protocol) bool {
+	aNum, aOK := protocolNumber(a)
+	bNum, bOK := protocolNumber(b)
+	if aOK && bOK {
+		return aNum == bNum
+	}
+	return strings.EqualFold(a.String(), b.String())
+}
+
+// intPtrCovers returns true if outer is unset (so it matches anything) or inner matches the same
+// value.
+func intPtrCovers(outer, inner *int) bool {
+	return outer == nil || (inner != nil && *inner == *outer)
+}
+
+func intPtrsEqual(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// netsCover returns true if every address that matches the inner list of CIDRs also matches the
+// outer list.  An empty list matches all addresses.
+func netsCover(outer, inner []*calinet.IPNet) bool {
+	if len(outer) == 0 {
+		return true
+	}
+	if len(inner) == 0 {
+		return false
+	}
+	return allNetsWithin(inner, outer)
+}
+
+// allNetsWithin returns true if every CIDR in nets is contained in one of the CIDRs in within.
+func allNetsWithin(nets, within []*calinet.IPNet) bool {
+	for _, n := range nets {
+		found := false
+		for _, w := range within {
+			if netWithin(n, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func netWithin(inner, outer *calinet.IPNet) bool {
+	innerOnes, innerBits := inner.Mask.Size()
+	outerOnes, outerBits := outer.Mask.Size()
+	return innerBits == outerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+// portsCover returns true if every port that matches the inner ports matches the outer ports.
+// As in the datamodel, numeric and named ports are ORed together and no ports at all matches any
+// port.
+func portsCover(outerPorts []numorstring.Port, outerNamed []string, innerPorts []numorstring.Port, innerNamed []string) bool {
+	if len(outerPorts) == 0 && len(outerNamed) == 0 {
+		return true
+	}
+	if len(innerPorts) == 0 && len(innerNamed) == 0 {
+		return false
+	}
+	return allPortsWithin(innerPorts, outerPorts) && stringsSubset(innerNamed, outerNamed)
+}
+
+// allPortsWithin returns true if every port range in ports is contained in one of the ranges in
+// within.
+func allPortsWithin(ports, within []numorstring.Port) bool {
+	for _, p := range ports {
+		found := false
+		for _, w := range within {
+			if p.MinPort >= w.MinPort && p.MaxPort <= w.MaxPort {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func stringsSubset(subset, superset []string) bool {
+	for _, s := range subset {
+		found := false
+		for _, t := range superset {
+			if s == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleAnalysisReporter updates the Prometheus gauges with the results of each analysis and logs
+// any findings that have changed since the previous analysis.
+type ruleAnalysisReporter struct {
+	lastShadowed    set.Set
+	lastUnreachable set.Set
+}
+
+func newRuleAnalysisReporter() *ruleAnalysisReporter {
+	return &ruleAnalysisReporter{
+		lastShadowed:    set.New(),
+		lastUnreachable: set.New(),
+	}
+}
+
+func (r *ruleAnalysisReporter) report(analysis *RuleAnalysis) {
+	gaugeNumShadowedRules.Set(float64(len(analysis.ShadowedRules)))
+	gaugeNumUnreachablePolicies.Set(float64(len(analysis.UnreachablePolicies)))
+
+	shadowed := set.New()
+	for _, sr := range analysis.ShadowedRules {
+		shadowed.Add(sr)
+		if !r.lastShadowed.Contains(sr) {
+			log.WithFields(log.Fields{
+				"tier":                sr.Tier,
+				"policy":              sr.Policy,
+				"direction":           sr.Direction,
+				"ruleIndex":           sr.RuleIndex,
+				"shadowedByPolicy":    sr.ShadowedByPolicy,
+				"shadowedByRuleIndex": sr.ShadowedByRuleIndex,
+			}).Info("Rule can never match, an earlier rule covers it.")
+		}
+	}
+	r.lastShadowed.Iter(func(item interface{}) error {
+		if !shadowed.Contains(item) {
+			log.WithField("rule", item).Info("Rule is no longer shadowed.")
+		}
+		return nil
+	})
+	r.lastShadowed = shadowed
+
+	unreachable := set.New()
+	for _, up := range analysis.UnreachablePolicies {
+		unreachable.Add(up)
+		if !r.lastUnreachable.Contains(up) {
+			log.WithFields(log.Fields{
+				"tier":      up.Tier,
+				"policy":    up.Policy,
+				"direction": up.Direction,
+				"reason":    up.Reason,
+			}).Info("Policy is never reached.")
+		}
+	}
+	r.lastUnreachable.Iter(func(item interface{}) error {
+		if !unreachable.Contains(item) {
+			log.WithField("policy", item).Info("Policy is no longer unreachable.")
+		}
+		return nil
+	})
+	r.lastUnreachable = unreachable
+}
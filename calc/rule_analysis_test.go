@@ -0,0 +1,229 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc_test
+
+import (
+	. "github.com/projectcalico/felix/calc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/config"
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	. "github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+)
+
+var _ = Describe("Rule analysis", func() {
+	var cg *CalcGraph
+
+	update := func(key Key, value interface{}) {
+		cg.AllUpdDispatcher.OnUpdate(api.Update{
+			UpdateType: api.UpdateTypeKVNew,
+			KVPair:     KVPair{Key: key, Value: value},
+		})
+	}
+	ingressPolicy := func(order float64, selector string, rules ...Rule) *Policy {
+		return &Policy{
+			Order:        &order,
+			Selector:     selector,
+			InboundRules: rules,
+			Types:        []string{"ingress"},
+		}
+	}
+
+	BeforeEach(func() {
+		eb := NewEventSequencer(nil)
+		eb.Callback = func(message interface{}) {}
+		conf := config.New()
+		conf.FelixHostname = localHostname
+		cg = NewCalculationGraph(eb, conf)
+
+		update(localWlEpKey1, &localWlEp1)
+		update(localWlEpKey2, &localWlEp2)
+		cg.AllUpdDispatcher.OnStatusUpdated(api.InSync)
+	})
+
+	It("should report nothing if there are no policies", func() {
+		Expect(cg.AnalyseRules()).To(Equal(&RuleAnalysis{
+			ShadowedRules:       []ShadowedRule{},
+			UnreachablePolicies: []UnreachablePolicy{},
+		}))
+	})
+
+	It("should report a rule that is covered by an earlier policy in the tier", func() {
+		update(PolicyKey{Name: "pol-1"}, ingressPolicy(10, "all()",
+			Rule{Action: "log"},
+			Rule{Action: "deny", Protocol: &protoTCP},
+		))
+		update(PolicyKey{Name: "pol-2"}, ingressPolicy(20, "all()",
+			Rule{Action: "allow", Protocol: &protoUDP},
+			Rule{Action: "allow", Protocol: &protoTCP, DstPorts: []numorstring.Port{numorstring.SinglePort(80)}},
+		))
+		Expect(cg.AnalyseRules().ShadowedRules).To(Equal([]ShadowedRule{{
+			Tier:                "default",
+			Policy:              "pol-2",
+			Direction:           "ingress",
+			RuleIndex:           1,
+			ShadowedByPolicy:    "pol-1",
+			ShadowedByRuleIndex: 1,
+		}}))
+	})
+
+	It("should only report a rule that is shadowed for every endpoint", func() {
+		update(PolicyKey{Name: "pol-1"}, ingressPolicy(10, "id == 'loc-ep-1'",
+			Rule{Action: "deny", Protocol: &protoTCP},
+		))
+		update(PolicyKey{Name: "pol-2"}, ingressPolicy(20, "all()",
+			Rule{Action: "allow", Protocol: &protoTCP},
+		))
+		Expect(cg.AnalyseRules().ShadowedRules).To(BeEmpty())
+
+		By("reporting the rule once the policy applies to both endpoints")
+		update(PolicyKey{Name: "pol-1"}, ingressPolicy(10, "all()",
+			Rule{Action: "deny", Protocol: &protoTCP},
+		))
+		Expect(cg.AnalyseRules().ShadowedRules).To(HaveLen(1))
+	})
+
+	It("should report policies after a policy that matches all traffic", func() {
+		update(PolicyKey{Name: "pol-1"}, ingressPolicy(10, "all()",
+			Rule{Action: "allow", Protocol: &protoTCP},
+			Rule{Action: "deny"},
+			Rule{Action: "allow", Protocol: &protoUDP},
+		))
+		update(PolicyKey{Name: "pol-2"}, ingressPolicy(20, "all()",
+			Rule{Action: "allow"},
+		))
+		analysis := cg.AnalyseRules()
+		Expect(analysis.ShadowedRules).To(Equal([]ShadowedRule{{
+			Tier:                "default",
+			Policy:              "pol-1",
+			Direction:           "ingress",
+			RuleIndex:           2,
+			ShadowedByPolicy:    "pol-1",
+			ShadowedByRuleIndex: 1,
+		}}))
+		Expect(analysis.UnreachablePolicies).To(Equal([]UnreachablePolicy{{
+			Tier:      "default",
+			Policy:    "pol-2",
+			Direction: "ingress",
+			Reason:    "policy pol-1 matches all traffic",
+		}}))
+	})
+
+	It("should ignore the other direction and untracked policies", func() {
+		update(PolicyKey{Name: "pol-1"}, &Policy{
+			Order:         &order10,
+			Selector:      "all()",
+			OutboundRules: []Rule{{Action: "deny"}},
+			Types:         []string{"egress"},
+		})
+		update(PolicyKey{Name: "pol-2"}, &Policy{
+			Order:        &order10,
+			Selector:     "all()",
+			InboundRules: []Rule{{Action: "deny"}},
+			DoNotTrack:   true,
+			Types:        []string{"ingress"},
+		})
+		update(PolicyKey{Name: "pol-3"}, ingressPolicy(20, "all()",
+			Rule{Action: "allow"},
+		))
+		Expect(cg.AnalyseRules()).To(Equal(&RuleAnalysis{
+			ShadowedRules:       []ShadowedRule{},
+			UnreachablePolicies: []UnreachablePolicy{},
+		}))
+	})
+
+	DescribeTable("rule coverage",
+		func(earlier, later Rule, expectShadowed bool) {
+			update(PolicyKey{Name: "pol-1"}, ingressPolicy(10, "all()", earlier, later))
+			if expectShadowed {
+				Expect(cg.AnalyseRules().ShadowedRules).To(HaveLen(1))
+			} else {
+				Expect(cg.AnalyseRules().ShadowedRules).To(BeEmpty())
+			}
+		},
+		Entry("identical rules",
+			Rule{Action: "allow", Protocol: &protoTCP},
+			Rule{Action: "deny", Protocol: &protoTCP},
+			true),
+		Entry("protocol by name and number",
+			Rule{Action: "allow", Protocol: &protoTCP},
+			Rule{Action: "allow", Protocol: protocolPtr(numorstring.ProtocolFromInt(6))},
+			true),
+		Entry("different protocols",
+			Rule{Action: "allow", Protocol: &protoTCP},
+			Rule{Action: "allow", Protocol: &protoUDP},
+			false),
+		Entry("earlier rule is more specific",
+			Rule{Action: "allow", Protocol: &protoTCP},
+			Rule{Action: "allow"},
+			false),
+		Entry("CIDR within earlier CIDR",
+			Rule{Action: "deny", SrcNets: []*net.IPNet{mustParseCalicoIPNet("10.0.0.0/8")}},
+			Rule{Action: "allow", SrcNets: []*net.IPNet{mustParseCalicoIPNet("10.1.0.0/16")}},
+			true),
+		Entry("CIDR partly outside earlier CIDR",
+			Rule{Action: "deny", SrcNets: []*net.IPNet{mustParseCalicoIPNet("10.1.0.0/16")}},
+			Rule{Action: "allow", SrcNets: []*net.IPNet{
+				mustParseCalicoIPNet("10.1.0.0/16"),
+				mustParseCalicoIPNet("10.2.0.0/16"),
+			}},
+			false),
+		Entry("port within earlier range",
+			Rule{Action: "allow", Protocol: &protoTCP, DstPorts: []numorstring.Port{mustPortRange(1000, 2000)}},
+			Rule{Action: "allow", Protocol: &protoTCP, DstPorts: []numorstring.Port{numorstring.SinglePort(1234)}},
+			true),
+		Entry("port outside earlier range",
+			Rule{Action: "allow", Protocol: &protoTCP, DstPorts: []numorstring.Port{mustPortRange(1000, 2000)}},
+			Rule{Action: "allow", Protocol: &protoTCP, DstPorts: []numorstring.Port{numorstring.SinglePort(80)}},
+			false),
+		Entry("selector also required by later rule",
+			Rule{Action: "allow", SrcSelector: "id == 'loc-ep-2'"},
+			Rule{Action: "allow", SrcSelector: "id == 'loc-ep-2'", Protocol: &protoTCP},
+			true),
+		Entry("different selectors",
+			Rule{Action: "allow", SrcSelector: "id == 'loc-ep-2'"},
+			Rule{Action: "allow", SrcSelector: "id == 'loc-ep-1'"},
+			false),
+		Entry("later rule excludes more",
+			Rule{Action: "allow", NotSrcNets: []*net.IPNet{mustParseCalicoIPNet("10.1.0.0/16")}},
+			Rule{Action: "allow", NotSrcNets: []*net.IPNet{mustParseCalicoIPNet("10.0.0.0/8")}},
+			true),
+		Entry("earlier rule excludes more",
+			Rule{Action: "allow", NotSrcNets: []*net.IPNet{mustParseCalicoIPNet("10.0.0.0/8")}},
+			Rule{Action: "allow", NotSrcNets: []*net.IPNet{mustParseCalicoIPNet("10.1.0.0/16")}},
+			false),
+		Entry("earlier log rule",
+			Rule{Action: "log"},
+			Rule{Action: "allow"},
+			false),
+	)
+})
+
+func protocolPtr(p numorstring.Protocol) *numorstring.Protocol {
+	return &p
+}
+
+func mustPortRange(min, max uint16) numorstring.Port {
+	p, err := numorstring.PortFromRange(min, max)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
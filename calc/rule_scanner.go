@@ -15,6 +15,8 @@ package calc
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -30,6 +32,12 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/hash"
 )
 
+// DstDomainsAnnotation is the rule annotation that restricts a rule to destinations that local
+// workloads have looked up, via DNS, under one of the listed domain names.  The value is a
+// comma-separated list of names; a name of the form "*.example.com" matches any subdomain of
+// example.com.
+const DstDomainsAnnotation = "felix.projectcalico.org/dst-domains"
+
 // AllSelector is a pre-calculated copy of the "all()" selector.
 var AllSelector selector.Selector
 
@@ -75,6 +83,8 @@ type RuleScanner struct {
 	rulesIDToUIDs multidict.IfaceToString
 	// uidsToRulesIDs maps from IP set UID to the set of policy/profile IDs that use it.
 	uidsToRulesIDs multidict.StringToIface
+	// auditOnlyPolicies contains the keys of the active policies that are audit-only.
+	auditOnlyPolicies set.Set
 
 	OnIPSetActive   func(ipSet *IPSetData)
 	OnIPSetInactive func(ipSet *IPSetData)
@@ -93,6 +103,9 @@ type IPSetData struct {
 	// NamedPort contains the name of the named port represented by this IP set or "" for a
 	// selector-only IP set
 	NamedPort string
+	// Domains, if non-empty, makes this a domain-based IP set, which holds the addresses that
+	// the listed domains resolve to.  The other fields are ignored for such a set.
+	Domains []string
 	// cachedUID holds the calculated unique ID of this IP set, or "" if it hasn't been calculated
 	// yet.
 	cachedUID string
@@ -100,6 +113,10 @@ type IPSetData struct {
 
 func (d *IPSetData) UniqueID() string {
 	if d.cachedUID == "" {
+		if len(d.Domains) > 0 {
+			d.cachedUID = hash.MakeUniqueID("d", strings.Join(d.Domains, ","))
+			return d.cachedUID
+		}
 		selID := d.Selector.UniqueID()
 		if d.NamedPortProtocol == labelindex.ProtocolNone {
 			d.cachedUID = selID
@@ -114,6 +131,9 @@ func (d *IPSetData) UniqueID() string {
 // DataplaneProtocolType returns the dataplane driver protocol type of this IP set.
 // One of the proto.IPSetUpdate_IPSetType constants.
 func (d *IPSetData) DataplaneProtocolType() proto.IPSetUpdate_IPSetType {
+	if len(d.Domains) > 0 {
+		return proto.IPSetUpdate_IP
+	}
 	if d.NamedPortProtocol != labelindex.ProtocolNone {
 		return proto.IPSetUpdate_IP_AND_PORT
 	}
@@ -122,9 +142,10 @@ func (d *IPSetData) DataplaneProtocolType() proto.IPSetUpdate_IPSetType {
 
 func NewRuleScanner() *RuleScanner {
 	calc := &RuleScanner{
-		ipSetsByUID:    make(map[string]*IPSetData),
-		rulesIDToUIDs:  multidict.NewIfaceToString(),
-		uidsToRulesIDs: multidict.NewStringToIface(),
+		ipSetsByUID:       make(map[string]*IPSetData),
+		rulesIDToUIDs:     multidict.NewIfaceToString(),
+		uidsToRulesIDs:    multidict.NewStringToIface(),
+		auditOnlyPolicies: set.New(),
 	}
 	return calc
 }
@@ -141,14 +162,27 @@ func (rs *RuleScanner) OnProfileInactive(key model.ProfileRulesKey) {
 
 func (rs *RuleScanner) OnPolicyActive(key model.PolicyKey, policy *model.Policy) {
 	parsedRules := rs.updateRules(key, policy.InboundRules, policy.OutboundRules, policy.DoNotTrack, policy.PreDNAT, policy.Namespace)
+	parsedRules.AuditOnly = proto.PolicyNameIsAuditOnly(key.Name)
+	if parsedRules.AuditOnly {
+		rs.auditOnlyPolicies.Add(key)
+	} else {
+		rs.auditOnlyPolicies.Discard(key)
+	}
 	rs.RulesUpdateCallbacks.OnPolicyActive(key, parsedRules)
 }
 
 func (rs *RuleScanner) OnPolicyInactive(key model.PolicyKey) {
 	rs.updateRules(key, nil, nil, false, false, "")
+	rs.auditOnlyPolicies.Discard(key)
 	rs.RulesUpdateCallbacks.OnPolicyInactive(key)
 }
 
+// PolicyIsAuditOnly returns true if the given active policy is audit-only; that is, if it was sent
+// to the dataplane with Policy.AuditOnly set.
+func (rs *RuleScanner) PolicyIsAuditOnly(key model.PolicyKey) bool {
+	return rs.auditOnlyPolicies.Contains(key)
+}
+
 func (rs *RuleScanner) updateRules(key interface{}, inbound, outbound []model.Rule, untracked, preDNAT bool, origNamespace string) (parsedRules *ParsedRules) {
 	log.Debugf("Scanning rules (%v in, %v out) for key %v",
 		len(inbound), len(outbound), key)
@@ -254,6 +288,10 @@ type ParsedRules struct {
 
 	// PreDNAT is true if these rules should be applied before any DNAT.
 	PreDNAT bool
+
+	// AuditOnly is true if these rules should only record what they would have done to a packet
+	// rather than enforcing their actions.
+	AuditOnly bool
 }
 
 // ParsedRule is like a backend.model.Rule, except the tag and selector matches and named ports are
@@ -368,6 +406,12 @@ func ruleToParsedRule(rule *model.Rule) (parsedRule *ParsedRule, allIPSets []*IP
 	notSrcSelIPSets := selectorsToIPSets(notSrcSels)
 	notDstSelIPSets := selectorsToIPSets(notDstSels)
 
+	// A domain-based rule is rendered as an extra destination IP set, which the dataplane fills
+	// in from DNS responses.
+	if domains := ruleDstDomains(rule); len(domains) > 0 {
+		dstSelIPSets = append(dstSelIPSets, &IPSetData{Domains: domains})
+	}
+
 	parsedRule = &ParsedRule{
 		Action: rule.Action,
 
@@ -463,6 +507,33 @@ func selectorsToIPSets(selectors []selector.Selector) []*IPSetData {
 	return ipSets
 }
 
+// ruleDstDomains returns the normalised, sorted list of domains from the rule's
+// DstDomainsAnnotation, or nil if the rule doesn't have one.
+func ruleDstDomains(rule *model.Rule) []string {
+	if rule.Metadata == nil {
+		return nil
+	}
+	value := rule.Metadata.Annotations[DstDomainsAnnotation]
+	if value == "" {
+		return nil
+	}
+	domainSet := set.New()
+	for _, d := range strings.Split(value, ",") {
+		d = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d == "" {
+			continue
+		}
+		domainSet.Add(d)
+	}
+	var domains []string
+	domainSet.Iter(func(item interface{}) error {
+		domains = append(domains, item.(string))
+		return nil
+	})
+	sort.Strings(domains)
+	return domains
+}
+
 func ipSetsToUIDs(ipSets []*IPSetData) []string {
 	var ids []string
 	for _, ipSet := range ipSets {
@@ -159,6 +159,31 @@ var _ = DescribeTable("RuleScanner rule conversion should generate correct Parse
 		model.Rule{Metadata: &model.RuleMetadata{Annotations: map[string]string{"key": "value"}}},
 		ParsedRule{Metadata: &model.RuleMetadata{Annotations: map[string]string{"key": "value"}}}),
 
+	// Domains.
+	Entry("dest domains",
+		model.Rule{Metadata: &model.RuleMetadata{Annotations: map[string]string{
+			DstDomainsAnnotation: "www.Example.com., *.example.org",
+		}}},
+		ParsedRule{
+			DstIPSetIDs: []string{hash.MakeUniqueID("d", "*.example.org,www.example.com")},
+			Metadata: &model.RuleMetadata{Annotations: map[string]string{
+				DstDomainsAnnotation: "www.Example.com., *.example.org",
+			}},
+		}),
+	Entry("dest selector and domains",
+		model.Rule{
+			DstSelector: sel1,
+			Metadata: &model.RuleMetadata{Annotations: map[string]string{
+				DstDomainsAnnotation: "example.com",
+			}},
+		},
+		ParsedRule{
+			DstIPSetIDs: []string{sel1ID, hash.MakeUniqueID("d", "example.com")},
+			Metadata: &model.RuleMetadata{Annotations: map[string]string{
+				DstDomainsAnnotation: "example.com",
+			}},
+		}),
+
 	// Tags/Selectors.
 	Entry("source tag", model.Rule{SrcTag: "tag1"}, ParsedRule{SrcIPSetIDs: []string{tag1ID}}),
 	Entry("dest tag", model.Rule{DstTag: "tag1"}, ParsedRule{DstIPSetIDs: []string{tag1ID}}),
@@ -351,10 +376,17 @@ func (ur *scanUpdateRecorder) OnProfileInactive(key model.ProfileRulesKey) {
 }
 
 func (ur *scanUpdateRecorder) ipSetActive(ipSet *IPSetData) {
+	if ipSet.Selector == nil {
+		// Domain IP set, which has no selector.
+		return
+	}
 	ur.activeSelectors.Add(ipSet.Selector.String())
 }
 
 func (ur *scanUpdateRecorder) ipSetInactive(ipSet *IPSetData) {
+	if ipSet.Selector == nil {
+		return
+	}
 	ur.activeSelectors.Discard(ipSet.Selector.String())
 }
 
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc
+
+import (
+	"sort"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+
+	"github.com/projectcalico/felix/proto"
+)
+
+// CalcGraphSnapshot is a point-in-time view of the calculation graph's state, for diagnostics.
+type CalcGraphSnapshot struct {
+	Endpoints      []EndpointSnapshot                 `json:"endpoints"`
+	ActivePolicies []string                           `json:"activePolicies"`
+	ActiveProfiles []string                           `json:"activeProfiles"`
+	IPSets         map[string]int                     `json:"ipSetMemberCounts"`
+	Routes         []*proto.RouteUpdate               `json:"routes"`
+	VTEPs          []*proto.VXLANTunnelEndpointUpdate `json:"vteps"`
+}
+
+type EndpointSnapshot struct {
+	Key   string         `json:"key"`
+	Tiers []TierSnapshot `json:"tiers"`
+}
+
+type TierSnapshot struct {
+	Name            string   `json:"name"`
+	IngressPolicies []string `json:"ingressPolicies"`
+	EgressPolicies  []string `json:"egressPolicies"`
+}
+
+// Snapshot returns a snapshot of the calculation graph's state.  It must be called from the
+// goroutine that owns the graph.  Routes and VTEPs are only included if the relevant resolvers
+// are enabled.
+func (cg *CalcGraph) Snapshot() *CalcGraphSnapshot {
+	snap := &CalcGraphSnapshot{
+		Endpoints:      []EndpointSnapshot{},
+		ActivePolicies: []string{},
+		ActiveProfiles: []string{},
+		IPSets:         cg.ipsetMemberIndex.IPSetMemberCounts(),
+		Routes:         []*proto.RouteUpdate{},
+		VTEPs:          []*proto.VXLANTunnelEndpointUpdate{},
+	}
+
+	for key := range cg.policyResolver.endpoints {
+		epSnap := EndpointSnapshot{Key: key.String(), Tiers: []TierSnapshot{}}
+		for _, tier := range cg.policyResolver.tiersForEndpoint(key) {
+			tierSnap := TierSnapshot{Name: tier.Name, IngressPolicies: []string{}, EgressPolicies: []string{}}
+			for _, polKV := range tier.OrderedPolicies {
+				if polKV.GovernsIngress() {
+					tierSnap.IngressPolicies = append(tierSnap.IngressPolicies, polKV.Key.Name)
+				}
+				if polKV.GovernsEgress() {
+					tierSnap.EgressPolicies = append(tierSnap.EgressPolicies, polKV.Key.Name)
+				}
+			}
+			epSnap.Tiers = append(epSnap.Tiers, tierSnap)
+		}
+		snap.Endpoints = append(snap.Endpoints, epSnap)
+	}
+	sort.Slice(snap.Endpoints, func(i, j int) bool {
+		return snap.Endpoints[i].Key < snap.Endpoints[j].Key
+	})
+
+	cg.activeRulesCalculator.policyIDToEndpointKeys.IterKeys(func(key interface{}) {
+		snap.ActivePolicies = append(snap.ActivePolicies, key.(model.PolicyKey).Name)
+	})
+	sort.Strings(snap.ActivePolicies)
+	cg.activeRulesCalculator.profileIDToEndpointKeys.IterKeys(func(key interface{}) {
+		snap.ActiveProfiles = append(snap.ActiveProfiles, key.(string))
+	})
+	sort.Strings(snap.ActiveProfiles)
+
+	if cg.l3RouteResolver != nil {
+		snap.Routes = append(snap.Routes, cg.l3RouteResolver.SentRoutes()...)
+	}
+	if cg.vxlanResolver != nil {
+		snap.VTEPs = append(snap.VTEPs, cg.vxlanResolver.SentVTEPs()...)
+	}
+	return snap
+}
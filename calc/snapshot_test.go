@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc_test
+
+import (
+	. "github.com/projectcalico/felix/calc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/config"
+)
+
+var _ = Describe("Calculation graph snapshot", func() {
+	It("should report endpoints, policies, profiles and IP sets", func() {
+		eb := NewEventSequencer(nil)
+		eb.Callback = func(message interface{}) {}
+		conf := config.New()
+		conf.FelixHostname = localHostname
+		cg := NewCalculationGraph(eb, conf)
+		cg.AllUpdDispatcher.OnUpdates(localEp1WithPolicy.KVDeltas(empty))
+
+		snap := cg.Snapshot()
+		Expect(snap.Endpoints).To(HaveLen(1))
+		Expect(snap.Endpoints[0].Tiers).To(Equal([]TierSnapshot{{
+			Name:            "default",
+			IngressPolicies: []string{"pol-1"},
+			EgressPolicies:  []string{"pol-1"},
+		}}))
+		Expect(snap.ActivePolicies).To(Equal([]string{"pol-1"}))
+		Expect(snap.ActiveProfiles).To(ConsistOf("prof-1", "prof-2", "prof-missing"))
+		Expect(snap.IPSets).To(HaveLen(len(localEp1WithPolicy.ExpectedIPSets)))
+		for id, members := range localEp1WithPolicy.ExpectedIPSets {
+			Expect(snap.IPSets).To(HaveKeyWithValue(id, members.Len()))
+		}
+		// Neither the route resolver nor the VXLAN resolver is enabled by default.
+		Expect(snap.Routes).To(BeEmpty())
+		Expect(snap.VTEPs).To(BeEmpty())
+	})
+})
@@ -226,7 +226,12 @@ func (s State) withVTEPs(vteps ...proto.VXLANTunnelEndpointUpdate) (newState Sta
 
 func (s State) withRoutes(routes ...proto.RouteUpdate) (newState State) {
 	newState = s.Copy()
-	newState.ExpectedRoutes = set.FromArray(routes)
+	// RouteUpdate has a repeated field, so it can't be a set item itself; store pointers, like the mock
+	// dataplane.
+	newState.ExpectedRoutes = set.New()
+	for i := range routes {
+		newState.ExpectedRoutes.Add(&routes[i])
+	}
 	return newState
 }
 
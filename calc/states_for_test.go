@@ -1099,6 +1099,57 @@ var vxlanWithWEPIPsAndWEPDuplicate = vxlanWithWEPIPsAndWEP.withKVUpdates(
 	},
 )
 
+// The WorkloadIPs states above, but with multipath enabled.
+var vxlanWithWEPIPsMultipath = vxlanWithWEPIPs.withKVUpdates(
+	KVPair{Key: GlobalConfigKey{Name: "RouteMultipathEnabled"}, Value: &multipathEnabled},
+).withName("VXLAN using WorkloadIPs with multipath")
+
+var vxlanWithWEPIPsAndWEPMultipath = vxlanWithWEPIPsAndWEP.withKVUpdates(
+	KVPair{Key: GlobalConfigKey{Name: "RouteMultipathEnabled"}, Value: &multipathEnabled},
+).withName("VXLAN using WorkloadIPs with multipath and a WEP")
+
+// With multipath enabled, the overlapping WEPs give an ECMP route with a next hop for each node.
+var vxlanWithWEPIPsAndECMPWEPs = vxlanWithWEPIPsAndWEPDuplicate.withKVUpdates(
+	KVPair{Key: GlobalConfigKey{Name: "RouteMultipathEnabled"}, Value: &multipathEnabled},
+).withName("VXLAN using WorkloadIPs with multipath and overlapping WEPs").withRoutes(
+	routeUpdateIPPoolVXLAN,
+	routeUpdateRemoteHost,
+	routeUpdateRemoteHost2,
+	proto.RouteUpdate{
+		Type:        proto.RouteType_REMOTE_WORKLOAD,
+		IpPoolType:  proto.IPPoolType_VXLAN,
+		Dst:         "10.0.0.5/32",
+		DstNodeName: remoteHostname,
+		DstNodeIp:   remoteHostIP.String(),
+		NatOutgoing: true,
+		NextHops: []*proto.RouteNextHop{
+			{NodeName: remoteHostname, NodeIp: remoteHostIP.String(), Weight: 1},
+			{NodeName: remoteHostname2, NodeIp: remoteHost2IP.String(), Weight: 1},
+		},
+	},
+)
+
+// Adds a second WEP with the same IP on remoteHost2, which doubles the weight of its next hop.
+var vxlanWithWEPIPsAndWeightedECMPWEPs = vxlanWithWEPIPsAndECMPWEPs.withKVUpdates(
+	KVPair{Key: remoteWlEpKey3, Value: &remoteWlEp1},
+).withName("VXLAN using WorkloadIPs with multipath and weighted overlapping WEPs").withRoutes(
+	routeUpdateIPPoolVXLAN,
+	routeUpdateRemoteHost,
+	routeUpdateRemoteHost2,
+	proto.RouteUpdate{
+		Type:        proto.RouteType_REMOTE_WORKLOAD,
+		IpPoolType:  proto.IPPoolType_VXLAN,
+		Dst:         "10.0.0.5/32",
+		DstNodeName: remoteHostname,
+		DstNodeIp:   remoteHostIP.String(),
+		NatOutgoing: true,
+		NextHops: []*proto.RouteNextHop{
+			{NodeName: remoteHostname, NodeIp: remoteHostIP.String(), Weight: 1},
+			{NodeName: remoteHostname2, NodeIp: remoteHost2IP.String(), Weight: 2},
+		},
+	},
+)
+
 // Minimal VXLAN set-up using Calico IPAM, all the data needed for a remote VTEP, a pool and a block.
 var vxlanWithBlock = empty.withKVUpdates(
 	KVPair{Key: ipPoolKey, Value: &ipPoolWithVXLAN},
@@ -1172,11 +1223,87 @@ var vxlanWithBlockNodeRes = vxlanWithBlock.withKVUpdates(
 		}}}},
 ).withName("VXLAN with node resource (node resources)")
 
-// As vxlanWithBlock but with some superfluous IPv6 resources (VXLAN is IPv4 only).
+// As vxlanWithBlock but with a non-VXLAN IPv6 pool and block.  The remote node has no IPv6 address
+// (it is configured via HostIP) so the block route has no node IP.
 var vxlanWithIPv6Resources = vxlanWithBlock.withKVUpdates(
 	KVPair{Key: v6IPPoolKey, Value: &v6IPPool},
 	KVPair{Key: remotev6IPAMBlockKey, Value: &remotev6IPAMBlock},
-).withName("VXLAN with IPv6")
+).withName("VXLAN with IPv6").withRoutes(append(vxlanWithBlockRoutes[0:len(vxlanWithBlockRoutes):len(vxlanWithBlockRoutes) /* force copy */],
+	proto.RouteUpdate{
+		Type:       proto.RouteType_CIDR_INFO,
+		IpPoolType: proto.IPPoolType_NO_ENCAP,
+		Dst:        "feed:beef::/32",
+	},
+	proto.RouteUpdate{
+		Type:        proto.RouteType_REMOTE_WORKLOAD,
+		IpPoolType:  proto.IPPoolType_NO_ENCAP,
+		Dst:         "feed:beef:1::/96",
+		DstNodeName: remoteHostname,
+	},
+)...)
+
+// IPv4 and IPv6 VXLAN set-up using node resources.  The remote node is dual-stack and has tunnel
+// addresses for both IP versions so its VTEP has both halves filled in.
+var vxlanV6WithBlockNodeRes = empty.withKVUpdates(
+	KVPair{Key: ipPoolKey, Value: &ipPoolWithVXLAN},
+	KVPair{Key: remoteIPAMBlockKey, Value: &remoteIPAMBlock},
+	KVPair{Key: v6IPPoolKey, Value: &v6IPPoolWithVXLAN},
+	KVPair{Key: remotev6IPAMBlockKey, Value: &remotev6IPAMBlock},
+	KVPair{Key: remoteNodeResKey, Value: &apiv3.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: remoteHostname,
+		},
+		Spec: apiv3.NodeSpec{BGP: &apiv3.NodeBGPSpec{
+			IPv4Address: remoteHostIPWithPrefix,
+			IPv6Address: remoteHostIPv6WithPrefix,
+		}}}},
+	KVPair{Key: remoteHostVXLANTunnelConfigKey, Value: remoteHostVXLANTunnelIP},
+	KVPair{Key: remoteHostVXLANV6TunnelConfigKey, Value: remoteHostVXLANV6TunnelIP},
+).withName("VXLAN IPv4 and IPv6 (node resources)").withVTEPs(
+	proto.VXLANTunnelEndpointUpdate{
+		Node:             remoteHostname,
+		Mac:              "66:3e:ca:a4:db:65",
+		Ipv4Addr:         remoteHostVXLANTunnelIP,
+		ParentDeviceIp:   remoteHostIP.String(),
+		MacV6:            "66:51:93:7c:e7:2b",
+		Ipv6Addr:         remoteHostVXLANV6TunnelIP,
+		ParentDeviceIpv6: remoteHostIPv6.String(),
+	},
+).withRoutes(vxlanV6WithBlockNodeResRoutes...)
+
+var vxlanV6WithBlockNodeResRoutes = append(vxlanWithBlockRoutes[0:len(vxlanWithBlockRoutes):len(vxlanWithBlockRoutes) /* force copy */],
+	proto.RouteUpdate{
+		Type:       proto.RouteType_CIDR_INFO,
+		IpPoolType: proto.IPPoolType_VXLAN,
+		Dst:        "feed:beef::/32",
+	},
+	proto.RouteUpdate{
+		Type:        proto.RouteType_REMOTE_HOST,
+		IpPoolType:  proto.IPPoolType_NONE,
+		Dst:         remoteHostIPv6.String() + "/128",
+		DstNodeName: remoteHostname,
+		DstNodeIp:   remoteHostIPv6.String(),
+	},
+	proto.RouteUpdate{
+		Type:        proto.RouteType_REMOTE_WORKLOAD,
+		IpPoolType:  proto.IPPoolType_VXLAN,
+		Dst:         "feed:beef:1::/96",
+		DstNodeName: remoteHostname,
+		DstNodeIp:   remoteHostIPv6.String(),
+	},
+)
+
+// As vxlanV6WithBlockNodeRes but with the IPv6 tunnel address removed; only the IPv4 half of the VTEP remains.
+var vxlanV6TunnelIPDeleteNodeRes = vxlanV6WithBlockNodeRes.withKVUpdates(
+	KVPair{Key: remoteHostVXLANV6TunnelConfigKey, Value: nil},
+).withName("VXLAN IPv6 tunnel IP deleted (node resources)").withVTEPs(
+	proto.VXLANTunnelEndpointUpdate{
+		Node:           remoteHostname,
+		Mac:            "66:3e:ca:a4:db:65",
+		Ipv4Addr:       remoteHostVXLANTunnelIP,
+		ParentDeviceIp: remoteHostIP.String(),
+	},
+)
 
 // Minimal VXLAN set-up with a MAC address override for the remote node.
 var vxlanWithMAC = vxlanWithBlock.withKVUpdates(
@@ -1796,9 +1923,19 @@ var nodesWithDifferentAddressTypes = nodesWithMoreIPs.withKVUpdates(
 				},
 			},
 		}}},
-).withRoutes(nodesWithMoreIPsRoutes...).
+).withRoutes(nodesWithDifferentAddressTypesRoutes...).
 	withName("routes for nodes with more IPs someof them unexpected/invalid")
 
+// The IPv6 address gets a host route too but, since it isn't the node's BGP address or an InternalIP, there's
+// no IPv6 node IP to go with it.
+var nodesWithDifferentAddressTypesRoutes = append(nodesWithMoreIPsRoutes[0:len(nodesWithMoreIPsRoutes):len(nodesWithMoreIPsRoutes) /* force copy */],
+	proto.RouteUpdate{
+		Type:        proto.RouteType_LOCAL_HOST,
+		Dst:         "feed:dead:beef::/128",
+		DstNodeName: localHostname,
+	},
+)
+
 var nodesWithMoreIPsRoutesDeletedExtras = append(vxlanWithBlockRoutes[0:len(vxlanWithBlockRoutes):len(vxlanWithBlockRoutes) /* force copy */],
 	proto.RouteUpdate{
 		Type:        proto.RouteType_LOCAL_HOST,
@@ -1879,6 +2016,21 @@ func (l StateList) RouteSource() string {
 	return "CalicoIPAM"
 }
 
+// RouteMultipathEnabled returns true if the states include a Felix configuration update that
+// enables multipath routes.
+func (l StateList) RouteMultipathEnabled() bool {
+	for _, s := range l {
+		for _, kv := range s.DatastoreState {
+			if resourceKey, ok := kv.Key.(GlobalConfigKey); ok && resourceKey.Name == "RouteMultipathEnabled" {
+				if kv.Value != nil {
+					return *kv.Value.(*string) == "true"
+				}
+			}
+		}
+	}
+	return false
+}
+
 // identity is a test expander that returns the test unaltered.
 func identity(baseTest StateList) (string, []StateList) {
 	return "in normal ordering", []StateList{baseTest}
@@ -0,0 +1,337 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/typha/pkg/syncproto"
+
+	"github.com/projectcalico/felix/config"
+	"github.com/projectcalico/felix/proto"
+)
+
+// The update log is a gzipped stream of gob-encoded values: a recordingHeader followed by any number
+// of recordedEvents.  Updates are serialized in the same way that Typha serializes them on the wire,
+// which keeps the log compact and means that we can decode any resource that Felix can receive.
+const updateLogVersion = 1
+
+// oldRecordingTimeFormat is the format of the modification time that is appended to the name of a
+// previous recording.  It sorts in time order.
+const oldRecordingTimeFormat = "20060102T150405.000000000Z"
+
+type recordingHeader struct {
+	Version int
+	// Config holds the raw config values of the recording Felix at the time the recording started.
+	// It always includes the hostname.
+	Config map[string]string
+}
+
+// recordedEvent holds either a batch of updates or a sync status change.  (We can't use a pointer to
+// signal the presence of the status because gob doesn't distinguish a pointer to a zero value from
+// nil.)
+type recordedEvent struct {
+	Updates   []syncproto.SerializedUpdate
+	HasStatus bool
+	Status    api.SyncStatus
+}
+
+// UpdateRecorder records the updates and sync status changes that are fed into the calculation graph
+// so that they can be replayed offline by ReplayUpdateLog.
+type UpdateRecorder struct {
+	file    *os.File
+	counter *countingWriter
+	gzipW   *gzip.Writer
+	encoder *gob.Encoder
+
+	// maxSize is the size of file at which we stop recording.  We don't roll over to a new file
+	// because a replay has to start from the beginning of the stream.
+	maxSize int64
+	full    bool
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewUpdateRecorder starts a new recording at the given path.  If there is already a recording
+// there, from a previous run of Felix, it is moved aside (see keepPreviousRecording) rather than
+// overwritten; if Felix is restarting after a crash, the previous recording is the interesting one.
+func NewUpdateRecorder(path string, conf *config.Config) (*UpdateRecorder, error) {
+	if err := keepPreviousRecording(path, conf.DebugCalcGraphRecordingMaxOldFiles); err != nil {
+		return nil, err
+	}
+	// O_EXCL so that we never overwrite a recording, even if something recreated the file.
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	counter := &countingWriter{w: file}
+	gzipW := gzip.NewWriter(counter)
+	r := &UpdateRecorder{
+		file:    file,
+		counter: counter,
+		gzipW:   gzipW,
+		encoder: gob.NewEncoder(gzipW),
+		maxSize: int64(conf.DebugCalcGraphRecordingMaxSizeMB) * 1024 * 1024,
+	}
+	// The hostname may have come from the kernel rather than from config, make sure that we
+	// replay with the same hostname.
+	rawConfig := map[string]string{"FelixHostname": conf.FelixHostname}
+	for k, v := range conf.RawValues() {
+		rawConfig[k] = v
+	}
+	err = r.write(&recordingHeader{
+		Version: updateLogVersion,
+		Config:  rawConfig,
+	})
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// keepPreviousRecording renames the recording at path, if there is one, to
+// "<path>.<modification time>".  It then deletes all but the maxOldFiles most recent of the
+// renamed recordings.
+func keepPreviousRecording(path string, maxOldFiles int) error {
+	info, err := os.Stat(path)
+	if err == nil {
+		oldPath := fmt.Sprintf("%s.%s", path, info.ModTime().UTC().Format(oldRecordingTimeFormat))
+		log.WithFields(log.Fields{
+			"file":    path,
+			"newName": oldPath,
+		}).Info("Keeping previous calculation graph recording")
+		if err := os.Rename(path, oldPath); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return deleteOldRecordings(path, maxOldFiles)
+}
+
+// deleteOldRecordings deletes all but the maxOldFiles most recent "<path>.<modification time>"
+// files.  Other files that happen to share the prefix are left alone.
+func deleteOldRecordings(path string, maxOldFiles int) error {
+	candidates, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return err
+	}
+	var oldPaths []string
+	for _, p := range candidates {
+		suffix := strings.TrimPrefix(p, path+".")
+		if _, err := time.Parse(oldRecordingTimeFormat, suffix); err != nil {
+			continue
+		}
+		oldPaths = append(oldPaths, p)
+	}
+	if len(oldPaths) <= maxOldFiles {
+		return nil
+	}
+	// The timestamp format sorts in time order so the oldest recordings come first.
+	sort.Strings(oldPaths)
+	for _, p := range oldPaths[:len(oldPaths)-maxOldFiles] {
+		log.WithField("file", p).Info("Deleting old calculation graph recording")
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *UpdateRecorder) RecordUpdates(updates []api.Update) {
+	event := recordedEvent{Updates: make([]syncproto.SerializedUpdate, 0, len(updates))}
+	for _, upd := range updates {
+		su, err := syncproto.SerializeUpdate(upd)
+		if err != nil {
+			log.WithError(err).WithField("key", upd.Key).Warn("Failed to serialize update for recording, skipping")
+			continue
+		}
+		event.Updates = append(event.Updates, su)
+	}
+	r.writeOrLog(&event)
+}
+
+func (r *UpdateRecorder) RecordStatus(status api.SyncStatus) {
+	r.writeOrLog(&recordedEvent{HasStatus: true, Status: status})
+}
+
+func (r *UpdateRecorder) writeOrLog(v interface{}) {
+	if r.full {
+		return
+	}
+	if err := r.write(v); err != nil {
+		log.WithError(err).Error("Failed to write to calculation graph update log")
+	}
+	if r.counter.n >= r.maxSize {
+		log.WithFields(log.Fields{
+			"file":    r.file.Name(),
+			"maxSize": r.maxSize,
+		}).Warn("Calculation graph recording reached its maximum size; no longer recording")
+		r.full = true
+	}
+}
+
+// write encodes the value and flushes it to the file.  We flush after every event so that the log
+// is usable even if Felix crashes, which is when it is most likely to be needed.
+func (r *UpdateRecorder) write(v interface{}) error {
+	if err := r.encoder.Encode(v); err != nil {
+		return err
+	}
+	return r.gzipW.Flush()
+}
+
+func (r *UpdateRecorder) Close() error {
+	if err := r.gzipW.Close(); err != nil {
+		_ = r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// UpdateLogReader reads back a log written by an UpdateRecorder.
+type UpdateLogReader struct {
+	file    *os.File
+	gzipR   *gzip.Reader
+	decoder *gob.Decoder
+	header  recordingHeader
+}
+
+func OpenUpdateLog(path string) (*UpdateLogReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	gzipR, err := gzip.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	r := &UpdateLogReader{
+		file:    file,
+		gzipR:   gzipR,
+		decoder: gob.NewDecoder(gzipR),
+	}
+	if err := r.decoder.Decode(&r.header); err != nil {
+		_ = r.Close()
+		return nil, fmt.Errorf("failed to read update log header: %w", err)
+	}
+	if r.header.Version != updateLogVersion {
+		_ = r.Close()
+		return nil, fmt.Errorf("unsupported update log version %d", r.header.Version)
+	}
+	return r, nil
+}
+
+// Config returns the raw config that was in use when the log was recorded.
+func (r *UpdateLogReader) Config() map[string]string {
+	return r.header.Config
+}
+
+// Next returns the next event from the log; either a batch of updates or a sync status.  It returns
+// io.EOF at the end of the log.  A log that was truncated (for example, because Felix was killed
+// before it could close the log) is treated as ending after the last complete event.
+func (r *UpdateLogReader) Next() (updates []api.Update, status *api.SyncStatus, err error) {
+	var event recordedEvent
+	err = r.decoder.Decode(&event)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		log.Warn("Update log was truncated, assuming it ends after the last complete event")
+		err = io.EOF
+	}
+	if err != nil {
+		return
+	}
+	for _, su := range event.Updates {
+		var upd api.Update
+		upd, err = su.ToUpdate()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse recorded update for key %v: %w", su.Key, err)
+		}
+		updates = append(updates, upd)
+	}
+	if event.HasStatus {
+		status = &event.Status
+	}
+	return
+}
+
+func (r *UpdateLogReader) Close() error {
+	_ = r.gzipR.Close()
+	return r.file.Close()
+}
+
+// ReplayUpdateLog feeds the events in the given log into a fresh calculation graph, which is
+// configured with the recorded config (applied as if it came from the config file), and passes each
+// message that the graph emits to the callback, in order.  The graph is flushed after each event.
+func ReplayUpdateLog(path string, callback func(event interface{})) error {
+	r, err := OpenUpdateLog(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	conf := config.New()
+	if _, err := conf.UpdateFrom(r.Config(), config.ConfigFile); err != nil {
+		return fmt.Errorf("failed to load recorded config: %w", err)
+	}
+	eventSequencer := NewEventSequencer(conf)
+	eventSequencer.Callback = callback
+	calcGraph := NewCalculationGraph(eventSequencer, conf)
+
+	beenInSync := false
+	for {
+		updates, status, err := r.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if status != nil {
+			calcGraph.AllUpdDispatcher.OnStatusUpdated(*status)
+		} else {
+			calcGraph.AllUpdDispatcher.OnUpdates(updates)
+		}
+		eventSequencer.Flush()
+		if status != nil && *status == api.InSync && !beenInSync {
+			// Mimic the AsyncCalcGraph, which sends InSync after the first flush.
+			beenInSync = true
+			callback(&proto.InSync{})
+		}
+	}
+}
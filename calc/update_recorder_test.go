@@ -0,0 +1,184 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc_test
+
+import (
+	. "github.com/projectcalico/felix/calc"
+
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/config"
+	"github.com/projectcalico/felix/dataplane/mock"
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+)
+
+var _ = Describe("Calculation graph update recording", func() {
+	var tmpDir, recordingFile string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "felix-recording")
+		Expect(err).NotTo(HaveOccurred())
+		recordingFile = filepath.Join(tmpDir, "calc-graph.rec")
+
+		conf := config.New()
+		conf.FelixHostname = localHostname
+		recorder, err := NewUpdateRecorder(recordingFile, conf)
+		Expect(err).NotTo(HaveOccurred())
+		recorder.RecordStatus(api.ResyncInProgress)
+		recorder.RecordUpdates(localEp1WithPolicy.KVDeltas(empty))
+		recorder.RecordStatus(api.InSync)
+		Expect(recorder.Close()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("should read back the recorded events", func() {
+		r, err := OpenUpdateLog(recordingFile)
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		Expect(r.Config()).To(HaveKeyWithValue("FelixHostname", localHostname))
+
+		updates, status, err := r.Next()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updates).To(BeEmpty())
+		Expect(*status).To(Equal(api.ResyncInProgress))
+
+		updates, status, err = r.Next()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(BeNil())
+		expectedUpdates := localEp1WithPolicy.KVDeltas(empty)
+		Expect(updates).To(HaveLen(len(expectedUpdates)))
+		for i, upd := range updates {
+			Expect(upd.Key).To(Equal(expectedUpdates[i].Key))
+			Expect(upd.UpdateType).To(Equal(expectedUpdates[i].UpdateType))
+		}
+
+		_, status, err = r.Next()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*status).To(Equal(api.InSync))
+
+		_, _, err = r.Next()
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("should replay the recording into a calculation graph", func() {
+		mockDataplane := mock.NewMockDataplane()
+		Expect(ReplayUpdateLog(recordingFile, mockDataplane.OnEvent)).To(Succeed())
+
+		Expect(mockDataplane.InSync()).To(BeTrue())
+		Expect(mockDataplane.ActivePolicies()).To(Equal(localEp1WithPolicy.ExpectedPolicyIDs))
+		Expect(mockDataplane.ActiveProfiles()).To(Equal(localEp1WithPolicy.ExpectedProfileIDs))
+		Expect(mockDataplane.EndpointToPolicyOrder()).To(Equal(localEp1WithPolicy.ExpectedEndpointPolicyOrder))
+	})
+
+	It("should keep the previous recording when a new one is started", func() {
+		conf := config.New()
+		conf.FelixHostname = "new-hostname"
+		recorder, err := NewUpdateRecorder(recordingFile, conf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Close()).To(Succeed())
+
+		oldRecordings, err := filepath.Glob(recordingFile + ".*")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(oldRecordings).To(HaveLen(1))
+		mockDataplane := mock.NewMockDataplane()
+		Expect(ReplayUpdateLog(oldRecordings[0], mockDataplane.OnEvent)).To(Succeed())
+		Expect(mockDataplane.ActivePolicies()).To(Equal(localEp1WithPolicy.ExpectedPolicyIDs))
+
+		r, err := OpenUpdateLog(recordingFile)
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		Expect(r.Config()).To(HaveKeyWithValue("FelixHostname", "new-hostname"))
+	})
+
+	It("should only keep the most recent previous recordings", func() {
+		for _, ts := range []string{
+			"20200101T000000.000000000Z",
+			"20200102T000000.000000000Z",
+			"20200103T000000.000000000Z",
+		} {
+			Expect(ioutil.WriteFile(recordingFile+"."+ts, nil, 0600)).To(Succeed())
+		}
+		Expect(ioutil.WriteFile(recordingFile+".notes", nil, 0600)).To(Succeed())
+
+		conf := config.New()
+		_, err := conf.UpdateFrom(map[string]string{"DebugCalcGraphRecordingMaxOldFiles": "2"}, config.ConfigFile)
+		Expect(err).NotTo(HaveOccurred())
+		recorder, err := NewUpdateRecorder(recordingFile, conf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Close()).To(Succeed())
+
+		oldRecordings, err := filepath.Glob(recordingFile + ".*")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(oldRecordings).To(HaveLen(3))
+		Expect(oldRecordings).To(ContainElement(recordingFile + ".20200103T000000.000000000Z"))
+		Expect(oldRecordings).To(ContainElement(recordingFile + ".notes"))
+		Expect(oldRecordings).NotTo(ContainElement(recordingFile + ".20200102T000000.000000000Z"))
+	})
+
+	It("should stop recording when the recording reaches its maximum size", func() {
+		conf := config.New()
+		_, err := conf.UpdateFrom(map[string]string{"DebugCalcGraphRecordingMaxSizeMB": "1"}, config.ConfigFile)
+		Expect(err).NotTo(HaveOccurred())
+		recorder, err := NewUpdateRecorder(recordingFile, conf)
+		Expect(err).NotTo(HaveOccurred())
+		const numBatches = 100000
+		for i := 0; i < numBatches; i++ {
+			recorder.RecordUpdates(localEp1WithPolicy.KVDeltas(empty))
+		}
+		Expect(recorder.Close()).To(Succeed())
+
+		info, err := os.Stat(recordingFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Size()).To(BeNumerically(">=", 1024*1024))
+		Expect(info.Size()).To(BeNumerically("<", 1024*1024+64*1024))
+
+		// The recording should still be readable up to the point where it stopped.
+		r, err := OpenUpdateLog(recordingFile)
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		numRead := 0
+		for {
+			_, _, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			numRead++
+		}
+		Expect(numRead).To(BeNumerically(">", 0))
+		Expect(numRead).To(BeNumerically("<", numBatches))
+	})
+
+	It("should replay a truncated recording up to the last complete event", func() {
+		data, err := ioutil.ReadFile(recordingFile)
+		Expect(err).NotTo(HaveOccurred())
+		// Chop off the gzip trailer, as if Felix had been killed.
+		Expect(ioutil.WriteFile(recordingFile, data[:len(data)-8], 0600)).To(Succeed())
+
+		mockDataplane := mock.NewMockDataplane()
+		Expect(ReplayUpdateLog(recordingFile, mockDataplane.OnEvent)).To(Succeed())
+		Expect(mockDataplane.InSync()).To(BeTrue())
+	})
+})
@@ -42,3 +42,8 @@ func mustParseIP(s string) net.IP {
 	ip := net2.ParseIP(s)
 	return net.IP{IP: ip}
 }
+
+func mustParseCalicoIPNet(n string) *net.IPNet {
+	cidr := mustParseNet(n)
+	return &cidr
+}
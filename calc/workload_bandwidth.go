@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calc
+
+import (
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/projectcalico/felix/proto"
+)
+
+// Workload endpoint labels that set bandwidth limits for the workload.  The values are Kubernetes quantities, for
+// example "10M".  Bandwidths are in bits per second and bursts are in bits.  Ingress is traffic to the workload and
+// egress is traffic from the workload.
+const (
+	IngressBandwidthLabel = "qos.projectcalico.org/ingressBandwidth"
+	IngressBurstLabel     = "qos.projectcalico.org/ingressBurst"
+	EgressBandwidthLabel  = "qos.projectcalico.org/egressBandwidth"
+	EgressBurstLabel      = "qos.projectcalico.org/egressBurst"
+)
+
+// setBandwidthLimits sets the bandwidth limit fields of the endpoint from the endpoint's labels.  Invalid values are
+// logged and ignored, which leaves that limit unset.
+func setBandwidthLimits(ep *proto.WorkloadEndpoint, labels map[string]string) {
+	ep.IngressBandwidth = bandwidthLabelValue(ep.Name, labels, IngressBandwidthLabel)
+	ep.IngressBurst = bandwidthLabelValue(ep.Name, labels, IngressBurstLabel)
+	ep.EgressBandwidth = bandwidthLabelValue(ep.Name, labels, EgressBandwidthLabel)
+	ep.EgressBurst = bandwidthLabelValue(ep.Name, labels, EgressBurstLabel)
+}
+
+func bandwidthLabelValue(epName string, labels map[string]string, label string) int64 {
+	value, ok := labels[label]
+	if !ok {
+		return 0
+	}
+	logCxt := log.WithFields(log.Fields{"endpoint": epName, "label": label, "value": value})
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		logCxt.WithError(err).Warn("Failed to parse bandwidth limit label, ignoring it")
+		return 0
+	}
+	if q.Sign() <= 0 {
+		logCxt.Warn("Bandwidth limit label is not positive, ignoring it")
+		return 0
+	}
+	return q.Value()
+}
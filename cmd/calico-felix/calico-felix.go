@@ -15,22 +15,49 @@
 package main
 
 import (
+	"fmt"
+	"net/url"
+	"os"
+
 	log "github.com/sirupsen/logrus"
 
 	docopt "github.com/docopt/docopt-go"
 
 	"github.com/projectcalico/felix/buildinfo"
+	"github.com/projectcalico/felix/calc"
 	"github.com/projectcalico/felix/daemon"
+	"github.com/projectcalico/felix/debugserver"
 )
 
 const usage = `Felix, the Calico per-host daemon.
 
 Usage:
   calico-felix [options]
+  calico-felix explain --src=<ip> --dst=<ip> [--protocol=<protocol>] [--sport=<port>] [--dport=<port>] [--icmp-type=<type>] [--icmp-code=<code>] [--debug-server=<addr>]
+  calico-felix replay <recording-file>
 
 Options:
   -c --config-file=<filename>  Config file to load [default: /etc/calico/felix.cfg].
   --version                    Print the version and exit.
+
+Explain options:
+  --src=<ip>                   Source IP of the packet.
+  --dst=<ip>                   Destination IP of the packet.
+  --protocol=<protocol>        Protocol name or number [default: tcp].
+  --sport=<port>               Source port.
+  --dport=<port>               Destination port.
+  --icmp-type=<type>           ICMP type.
+  --icmp-code=<code>           ICMP code.
+  --debug-server=<addr>        Address of Felix's debug server [default: localhost:9098].
+
+The explain command asks a running Felix (which must have DebugServerEnabled=true) to evaluate
+the given packet against its active policy and prints the tier, policy, rule index and action of
+each matching rule, along with the final verdict.
+
+The replay command feeds a recording made with DebugCalcGraphRecordingFile into a fresh
+calculation graph and prints each message that would have been sent to the dataplane.  Messages
+that are inconsistent with the earlier ones (for example, an endpoint that references a policy
+that was never sent) are flagged and cause a non-zero exit code.
 `
 
 // main is the entry point to the calico-felix binary.
@@ -45,8 +72,59 @@ func main() {
 		println(usage)
 		log.Fatalf("Failed to parse usage, exiting: %v", err)
 	}
+	if explain, _ := arguments["explain"].(bool); explain {
+		os.Exit(runExplain(arguments))
+	}
+	if replay, _ := arguments["replay"].(bool); replay {
+		os.Exit(runReplay(arguments["<recording-file>"].(string)))
+	}
 	configFile := arguments["--config-file"].(string)
 
 	// Execute felix.
 	daemon.Run(configFile, buildinfo.GitVersion, buildinfo.GitRevision, buildinfo.BuildDate)
 }
+
+func runExplain(arguments map[string]interface{}) int {
+	query := url.Values{}
+	for flag, param := range map[string]string{
+		"--src":       "src",
+		"--dst":       "dst",
+		"--protocol":  "protocol",
+		"--sport":     "sport",
+		"--dport":     "dport",
+		"--icmp-type": "icmptype",
+		"--icmp-code": "icmpcode",
+	} {
+		if v, ok := arguments[flag].(string); ok {
+			query.Set(param, v)
+		}
+	}
+	explanation, err := debugserver.QueryExplain(arguments["--debug-server"].(string), query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to explain packet: %v\n", err)
+		return 1
+	}
+	debugserver.WriteExplanation(os.Stdout, explanation)
+	return 0
+}
+
+func runReplay(recordingFile string) int {
+	checker := calc.NewReplayChecker()
+	numInconsistencies := 0
+	err := calc.ReplayUpdateLog(recordingFile, func(event interface{}) {
+		fmt.Printf("%T %v\n", event, event)
+		if err := checker.OnEvent(event); err != nil {
+			fmt.Printf("INCONSISTENT: %v\n", err)
+			numInconsistencies++
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to replay recording: %v\n", err)
+		return 1
+	}
+	if numInconsistencies > 0 {
+		fmt.Fprintf(os.Stderr, "Replay found %d inconsistent messages\n", numInconsistencies)
+		return 1
+	}
+	return 0
+}
@@ -108,12 +108,40 @@ type Config struct {
 	UseInternalDataplaneDriver bool   `config:"bool;true"`
 	DataplaneDriver            string `config:"file(must-exist,executable);calico-iptables-plugin;non-zero,die-on-fail,skip-default-validation"`
 
+	// DataplaneDryRunEnabled puts the internal dataplane driver in observe-only mode: it calculates its
+	// iptables, IP set, route, routing rule, sysctl, tunnel device, bandwidth and BPF updates as normal but,
+	// instead of writing them, it logs them, counts them in the felix_dry_run_changes metric and keeps the
+	// most recent DataplaneDryRunMaxChanges for the debug server's dataplane state.  WireGuard is not
+	// supported in dry-run mode; Felix refuses to start if both are enabled.
+	DataplaneDryRunEnabled    bool `config:"bool;false"`
+	DataplaneDryRunMaxChanges int  `config:"int;1000;non-zero"`
+
+	// DataplaneDriftAuditLogFile, if set, is a file to which Felix appends a JSON record for each
+	// iptables chain, IP set or route that a resync finds to have been changed by another process.
+	// Drift is always logged and counted in the felix_dataplane_drift_events metric, and the most
+	// recent DataplaneDriftMaxEvents are kept for the debug server's dataplane state.
+	DataplaneDriftAuditLogFile string `config:"string;"`
+	DataplaneDriftMaxEvents    int    `config:"int;100;non-zero"`
+
 	// Wireguard configuration
 	WireguardEnabled             bool   `config:"bool;false"`
 	WireguardListeningPort       int    `config:"int;51820"`
 	WireguardRoutingRulePriority int    `config:"int;99"`
 	WireguardInterfaceName       string `config:"iface-param;wireguard.cali;non-zero"`
 	WireguardMTU                 int    `config:"int;0"`
+	// WireguardInterfaceIPv6Addr is the IPv6 address to assign to the wireguard interface.  Unlike the IPv4
+	// address, which is allocated from IPAM, this is configured per-node; Felix publishes it on the Node resource
+	// so that other nodes route to it over wireguard.
+	WireguardInterfaceIPv6Addr net.IP `config:"ipv6;"`
+	// WireguardKeyRotationInterval is the interval at which Felix replaces the wireguard private key and publishes
+	// the new public key.  Zero disables rotation.  Rotation is not hitless: a wireguard device has a single private
+	// key so wireguard traffic to and from the node is dropped from the moment that the key is replaced until each
+	// peer has learned the new public key from the datastore, typically a few seconds.
+	WireguardKeyRotationInterval time.Duration `config:"seconds;0"`
+	// WireguardHostEncryptionEnabled extends wireguard encryption to host-networked traffic between nodes by
+	// routing the nodes' host addresses over wireguard.  It should be enabled on all nodes, since a node only accepts
+	// traffic from a peer's host addresses if it has this enabled too.
+	WireguardHostEncryptionEnabled bool `config:"bool;false"`
 
 	BPFEnabled                         bool           `config:"bool;false"`
 	BPFDisableUnprivileged             bool           `config:"bool;true"`
@@ -164,7 +192,7 @@ type Config struct {
 
 	Ipv6Support bool `config:"bool;true"`
 
-	IptablesBackend                    string            `config:"oneof(legacy,nft,auto);auto"`
+	IptablesBackend                    string            `config:"oneof(legacy,nft,nftables,auto);auto"`
 	RouteRefreshInterval               time.Duration     `config:"seconds;90"`
 	InterfaceRefreshInterval           time.Duration     `config:"seconds;90"`
 	DeviceRouteSourceAddress           net.IP            `config:"ipv4;"`
@@ -175,8 +203,10 @@ type Config struct {
 	IptablesLockFilePath               string            `config:"file;/run/xtables.lock"`
 	IptablesLockTimeoutSecs            time.Duration     `config:"seconds;0"`
 	IptablesLockProbeIntervalMillis    time.Duration     `config:"millis;50"`
+	IptablesPersistentRestoreEnabled   bool              `config:"bool;false"`
 	FeatureDetectOverride              map[string]string `config:"keyvaluelist;;"`
 	IpsetsRefreshInterval              time.Duration     `config:"seconds;10"`
+	IpsetsBackend                      string            `config:"oneof(netlink,exec,auto);auto"`
 	MaxIpsetSize                       int               `config:"int;1048576;non-zero"`
 	XDPRefreshInterval                 time.Duration     `config:"seconds;90"`
 
@@ -198,18 +228,28 @@ type Config struct {
 	IptablesMangleAllowAction   string `config:"oneof(ACCEPT,RETURN);ACCEPT;non-zero,die-on-fail"`
 	LogPrefix                   string `config:"string;calico-packet"`
 
+	// AuditLogRateLimit limits the kernel log messages that audit-only policies write, in
+	// iptables mode, to that many per second for each policy rule, with bursts of up to
+	// AuditLogRateLimitBurst.  Packets over the limit are still evaluated but not logged.  The
+	// kernel's limit match can't go above 10000 per second.
+	AuditLogRateLimit      int `config:"int(1,10000);10"`
+	AuditLogRateLimitBurst int `config:"int(1,10000);20"`
+
 	LogFilePath string `config:"file;/var/log/calico/felix.log;die-on-fail"`
 
 	LogSeverityFile   string `config:"oneof(DEBUG,INFO,WARNING,ERROR,FATAL);INFO"`
 	LogSeverityScreen string `config:"oneof(DEBUG,INFO,WARNING,ERROR,FATAL);INFO"`
 	LogSeveritySys    string `config:"oneof(DEBUG,INFO,WARNING,ERROR,FATAL);INFO"`
 
-	VXLANEnabled        bool   `config:"bool;false"`
-	VXLANPort           int    `config:"int;4789"`
-	VXLANVNI            int    `config:"int;4096"`
-	VXLANMTU            int    `config:"int;0"`
-	IPv4VXLANTunnelAddr net.IP `config:"ipv4;"`
-	VXLANTunnelMACAddr  string `config:"string;"`
+	VXLANEnabled         bool   `config:"bool;false"`
+	VXLANPort            int    `config:"int;4789"`
+	VXLANVNI             int    `config:"int;4096"`
+	VXLANMTU             int    `config:"int;0"`
+	VXLANMTUV6           int    `config:"int;0"`
+	IPv4VXLANTunnelAddr  net.IP `config:"ipv4;"`
+	IPv6VXLANTunnelAddr  net.IP `config:"ipv6;"`
+	VXLANTunnelMACAddr   string `config:"string;"`
+	VXLANTunnelMACAddrV6 string `config:"string;"`
 
 	IpInIpEnabled    bool   `config:"bool;false"`
 	IpInIpMtu        int    `config:"int;0"`
@@ -224,24 +264,85 @@ type Config struct {
 
 	ServiceLoopPrevention string `config:"oneof(Drop,Reject,Disabled);Drop"`
 
+	// DNSSnoopingEnabled enables domain-based egress rules.  When enabled, DNS queries from local
+	// workloads to DNSTrustedServers, and the responses to them, are copied to the given NFLOG
+	// group and Felix adds the resolved addresses to the IP sets of rules that match on those
+	// domains.  Only responses to queries that Felix has seen are used, and only the records for
+	// the queried name and its CNAMEs.  Addresses are kept for the TTL of the DNS record plus
+	// DNSExtraTTL.  DNSTrustedServers lists the addresses that workloads send their queries to;
+	// for a DNS service, that is its cluster IP.  If it is empty, no responses are snooped.
+	//
+	// There are two limitations.  Only DNS over UDP is snooped; responses over TCP, which
+	// resolvers fall back to for large responses, are not.  And NFLOG is asynchronous: the
+	// response continues on to the workload while Felix processes its copy, so the workload's
+	// first packets to a newly-resolved address may be dropped if they overtake the update to
+	// the IP set.  Clients normally recover by retrying.
+	DNSSnoopingEnabled    bool          `config:"bool;false"`
+	DNSSnoopingNFLOGGroup int           `config:"int(1,65535);3"`
+	DNSTrustedServers     []string      `config:"dual-stack-cidr-list;"`
+	DNSExtraTTL           time.Duration `config:"seconds;0"`
+
+	// FlowLogsEnabled enables per-flow logging.  Every FlowLogsFlushInterval, Felix writes a JSON
+	// record for each flow that was active during the interval to FlowLogsDestination, which is
+	// either a file path or "unix:<path>" for a Unix socket.  In iptables mode, policy verdicts
+	// are copied to FlowLogsNFLOGGroup so that each flow can be attributed to a policy rule.
+	FlowLogsEnabled       bool          `config:"bool;false"`
+	FlowLogsFlushInterval time.Duration `config:"seconds;300"`
+	FlowLogsDestination   string        `config:"string;/var/log/calico/flowlogs/flows.log;non-zero"`
+	FlowLogsNFLOGGroup    int           `config:"int(1,65535);4"`
+
+	// PolicyCountersEnabled enables Prometheus metrics that count the packets and bytes that hit
+	// each policy rule.  In iptables mode, the counters are read back when Felix resyncs with
+	// iptables so they lag by up to IptablesRefreshInterval.  PolicyCountersGranularity chooses
+	// between a series per rule and a series per policy.  Once there are PolicyCountersMaxSeries
+	// series, hits on further rules are added to a single overflow series instead.
+	PolicyCountersEnabled     bool   `config:"bool;false"`
+	PolicyCountersGranularity string `config:"oneof(Policy,Rule);Rule"`
+	PolicyCountersMaxSeries   int    `config:"int(1,1000000);1000"`
+
+	// DeniedPacketLogEnabled enables structured logging of denied packets.  In iptables mode,
+	// packets that are dropped by a deny rule, or because no policy or profile allowed them, are
+	// copied to DeniedPacketLogNFLOGGroup and Felix appends a JSON record for each one, naming the
+	// endpoints and the policy, to DeniedPacketLogFile.  At most DeniedPacketLogRateLimit records
+	// are written per second; each record counts the records that were dropped before it.  The
+	// kernel also limits the packets that each rule copies to Felix to that rate.
+	DeniedPacketLogEnabled    bool   `config:"bool;false"`
+	DeniedPacketLogNFLOGGroup int    `config:"int(1,65535);5"`
+	DeniedPacketLogFile       string `config:"string;/var/log/calico/denied/denied.log;non-zero"`
+	DeniedPacketLogRateLimit  int    `config:"int(1,10000);100"`
+
+	// WorkloadBandwidthLimitsEnabled enables per-workload bandwidth limits, which are set by the
+	// qos.projectcalico.org/{ingress,egress}{Bandwidth,Burst} labels of the workload endpoint.
+	// Traffic to the workload is shaped by a TBF qdisc on its interface; traffic from the
+	// workload is redirected to an IFB device and shaped there.  This does the same job as the
+	// CNI bandwidth plugin, which should not be used at the same time.  Egress limits are not
+	// supported in BPF mode.
+	WorkloadBandwidthLimitsEnabled bool `config:"bool;false"`
+
 	ReportingIntervalSecs time.Duration `config:"seconds;30"`
 	ReportingTTLSecs      time.Duration `config:"seconds;90"`
 
 	EndpointReportingEnabled   bool          `config:"bool;false"`
 	EndpointReportingDelaySecs time.Duration `config:"seconds;1"`
 
+	// RuleAnalysisInterval controls how often Felix analyses the active policies for rules that
+	// can never match and policies that can never be reached.  0 disables the periodic analysis;
+	// the analysis is still available on demand from the debug server.
+	RuleAnalysisInterval time.Duration `config:"seconds;0"`
+
 	IptablesMarkMask uint32 `config:"mark-bitmask;0xffff0000;non-zero,die-on-fail"`
 
 	DisableConntrackInvalidCheck bool `config:"bool;false"`
 
-	HealthEnabled                   bool   `config:"bool;false"`
-	HealthPort                      int    `config:"int(0,65535);9099"`
-	HealthHost                      string `config:"host-address;localhost"`
-	PrometheusMetricsEnabled        bool   `config:"bool;false"`
-	PrometheusMetricsHost           string `config:"host-address;"`
-	PrometheusMetricsPort           int    `config:"int(0,65535);9091"`
-	PrometheusGoMetricsEnabled      bool   `config:"bool;true"`
-	PrometheusProcessMetricsEnabled bool   `config:"bool;true"`
+	HealthEnabled                     bool   `config:"bool;false"`
+	HealthPort                        int    `config:"int(0,65535);9099"`
+	HealthHost                        string `config:"host-address;localhost"`
+	PrometheusMetricsEnabled          bool   `config:"bool;false"`
+	PrometheusMetricsHost             string `config:"host-address;"`
+	PrometheusMetricsPort             int    `config:"int(0,65535);9091"`
+	PrometheusGoMetricsEnabled        bool   `config:"bool;true"`
+	PrometheusProcessMetricsEnabled   bool   `config:"bool;true"`
+	PrometheusWireguardMetricsEnabled bool   `config:"bool;true"`
 
 	FailsafeInboundHostPorts  []ProtoPort `config:"port-list;tcp:22,udp:68,tcp:179,tcp:2379,tcp:2380,tcp:5473,tcp:6443,tcp:6666,tcp:6667;die-on-fail"`
 	FailsafeOutboundHostPorts []ProtoPort `config:"port-list;udp:53,udp:67,tcp:179,tcp:2379,tcp:2380,tcp:5473,tcp:6443,tcp:6666,tcp:6667;die-on-fail"`
@@ -250,6 +351,24 @@ type Config struct {
 	NATPortRange       numorstring.Port   `config:"portrange;"`
 	NATOutgoingAddress net.IP             `config:"ipv4;"`
 
+	// EgressPolicies gives selected workloads a stable source IP for traffic that leaves the
+	// cluster.  It is a comma-separated list of <selector>=<action> entries, for example
+	// "namespace:tenant-a=snat:192.0.2.10,pool:10.65.0.0/16=gateway:10.65.1.10"; the first entry
+	// that matches a workload applies.  The selector is namespace:<name>, for the workloads in a
+	// Kubernetes namespace, or pool:<CIDR>, for the workloads with an IP in an IP pool.  The
+	// action is snat:<IP>, to source NAT the traffic to the given IP, or gateway:<IP>, to route
+	// the traffic via the gateway workload with that IP, using a routing table from
+	// RouteTableRange and a routing rule with priority EgressRoutingRulePriority.  Egress
+	// policies apply to IPv4 traffic and are not supported in BPF mode.
+	//
+	// Felix always reserves EgressGatewayMaxPolicies routing tables from RouteTableRange for
+	// gateway policies, even if there are none, and owns all the routes and routing rules that
+	// use them, so that it can clean up after gateway policies that are removed or reordered.
+	// Gateway policies beyond that number are ignored.
+	EgressPolicies            []EgressPolicy `config:"egress-policy-list;;die-on-fail"`
+	EgressRoutingRulePriority int            `config:"int;100"`
+	EgressGatewayMaxPolicies  int            `config:"int(0,250);8"`
+
 	UsageReportingEnabled          bool          `config:"bool;true"`
 	UsageReportingInitialDelaySecs time.Duration `config:"seconds;300"`
 	UsageReportingIntervalSecs     time.Duration `config:"seconds;86400"`
@@ -267,10 +386,37 @@ type Config struct {
 	DebugPanicAfter                 time.Duration `config:"seconds;0"`
 	DebugSimulateDataRace           bool          `config:"bool;false"`
 
+	// DebugCalcGraphRecordingFile, if set, causes Felix to record every update that is fed into the
+	// calculation graph to the given file.  The recording can be replayed with "calico-felix replay".
+	// When Felix restarts, the previous recording is kept alongside, with its modification time
+	// appended to the name.
+	DebugCalcGraphRecordingFile string `config:"file;;local"`
+	// DebugCalcGraphRecordingMaxSizeMB limits the size of a recording.  Once the (compressed)
+	// recording reaches the limit, Felix stops recording; a replay needs the start of the stream
+	// so the recording is not rolled over.
+	DebugCalcGraphRecordingMaxSizeMB int `config:"int(1,100000);100;local"`
+	// DebugCalcGraphRecordingMaxOldFiles is the number of previous recordings to keep; older ones
+	// are deleted when a new recording is started.
+	DebugCalcGraphRecordingMaxOldFiles int `config:"int(0,1000);3;local"`
+
+	// DebugServerEnabled enables the debug HTTP server, which allows the current policy state to be
+	// queried; for example, by "calico-felix explain".
+	DebugServerEnabled bool   `config:"bool;false"`
+	DebugServerHost    string `config:"host-address;localhost"`
+	DebugServerPort    int    `config:"int(0,65535);9098"`
+	// DebugServerSocketPath, if set, causes the debug server to also listen on a Unix socket at
+	// the given path.
+	DebugServerSocketPath string `config:"file;;local"`
+
 	// Configure where Felix gets its routing information.
 	// - workloadIPs: use workload endpoints to construct routes.
 	// - calicoIPAM: use IPAM data to contruct routes.
 	RouteSource string `config:"oneof(WorkloadIPs,CalicoIPAM);CalicoIPAM"`
+	// RouteMultipathEnabled allows workloads on several nodes to share an IP when RouteSource is
+	// WorkloadIPs, for example for anycast services.  The route to a shared IP then has a next hop
+	// for each of the nodes (an ECMP route), weighted by the number of workloads on the node.  When
+	// disabled, Felix routes a shared IP to only one of the nodes.
+	RouteMultipathEnabled bool `config:"bool;false"`
 
 	RouteTableRange idalloc.IndexRange `config:"route-table-range;1-250;die-on-fail"`
 
@@ -336,6 +482,15 @@ type ProtoPort struct {
 	Port     uint16
 }
 
+// EgressPolicy is an entry of the EgressPolicies parameter.  Exactly one of Namespace and
+// PoolCIDR is set, and exactly one of SNATAddress and Gateway.
+type EgressPolicy struct {
+	Namespace   string
+	PoolCIDR    string
+	SNATAddress string
+	Gateway     string
+}
+
 // Load parses and merges the rawData from one particular source into this config object.
 // If there is a config value already loaded from a higher-priority source, then
 // the new value will be ignored (after validation).
@@ -591,6 +746,11 @@ func (config *Config) Validate() (err error) {
 		}
 	}
 
+	if config.DataplaneDryRunEnabled && config.WireguardEnabled {
+		err = errors.New("WireguardEnabled is not supported with DataplaneDryRunEnabled: the WireGuard " +
+			"device, routes, rules and key would still be written")
+	}
+
 	if err != nil {
 		config.Err = err
 	}
@@ -676,6 +836,8 @@ func loadParams() {
 				Msg: "invalid URL authority"}
 		case "ipv4":
 			param = &Ipv4Param{}
+		case "ipv6":
+			param = &Ipv6Param{}
 		case "endpoint-list":
 			param = &EndpointListParam{}
 		case "port-list":
@@ -705,10 +867,14 @@ func loadParams() {
 				Msg: "invalid string"}
 		case "cidr-list":
 			param = &CIDRListParam{}
+		case "dual-stack-cidr-list":
+			param = &DualStackCIDRListParam{}
 		case "route-table-range":
 			param = &RouteTableRangeParam{}
 		case "keyvaluelist":
 			param = &KeyValueListParam{}
+		case "egress-policy-list":
+			param = &EgressPolicyListParam{}
 		default:
 			log.Panicf("Unknown type of parameter: %v", kind)
 		}
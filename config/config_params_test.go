@@ -55,12 +55,50 @@ var _ = Describe("FelixConfig vs ConfigParams parity", func() {
 		// Moved to Node.
 		"IpInIpTunnelAddr",
 		"IPv4VXLANTunnelAddr",
+		"IPv6VXLANTunnelAddr",
 		"VXLANTunnelMACAddr",
+		"VXLANTunnelMACAddrV6",
 		"loadClientConfigFromEnvironment",
 
 		"loadClientConfigFromEnvironment",
 		"useNodeResourceUpdates",
 		"internalOverrides",
+
+		// Not yet in FelixConfigurationSpec; can only be set via the environment or config file.
+		"IpsetsBackend",
+		"RuleAnalysisInterval",
+		"DNSSnoopingEnabled",
+		"DNSSnoopingNFLOGGroup",
+		"DNSTrustedServers",
+		"DNSExtraTTL",
+		"FlowLogsEnabled",
+		"FlowLogsFlushInterval",
+		"FlowLogsDestination",
+		"FlowLogsNFLOGGroup",
+		"PolicyCountersEnabled",
+		"PolicyCountersGranularity",
+		"PolicyCountersMaxSeries",
+		"DeniedPacketLogEnabled",
+		"DeniedPacketLogNFLOGGroup",
+		"DeniedPacketLogFile",
+		"DeniedPacketLogRateLimit",
+		"AuditLogRateLimit",
+		"AuditLogRateLimitBurst",
+		"VXLANMTUV6",
+		"WireguardInterfaceIPv6Addr",
+		"WireguardKeyRotationInterval",
+		"PrometheusWireguardMetricsEnabled",
+		"WireguardHostEncryptionEnabled",
+		"WorkloadBandwidthLimitsEnabled",
+		"EgressPolicies",
+		"EgressRoutingRulePriority",
+		"EgressGatewayMaxPolicies",
+		"IptablesPersistentRestoreEnabled",
+		"DataplaneDryRunEnabled",
+		"DataplaneDryRunMaxChanges",
+		"DataplaneDriftAuditLogFile",
+		"DataplaneDriftMaxEvents",
+		"RouteMultipathEnabled",
 	}
 	cpFieldNameToFC := map[string]string{
 		"IpInIpEnabled":                      "IPIPEnabled",
@@ -307,6 +345,27 @@ var _ = DescribeTable("Config parsing",
 	Entry("IpInIpMtu", "IpInIpMtu", "1234", int(1234)),
 	Entry("IpInIpTunnelAddr", "IpInIpTunnelAddr",
 		"10.0.0.1", net.ParseIP("10.0.0.1")),
+	Entry("IPv6VXLANTunnelAddr", "IPv6VXLANTunnelAddr",
+		"fd00::1", net.ParseIP("fd00::1")),
+	Entry("WireguardInterfaceIPv6Addr", "WireguardInterfaceIPv6Addr",
+		"fd00:10::1", net.ParseIP("fd00:10::1")),
+	Entry("WireguardKeyRotationInterval", "WireguardKeyRotationInterval", "86400", 24*time.Hour),
+	Entry("WireguardHostEncryptionEnabled", "WireguardHostEncryptionEnabled", "true", true),
+	Entry("WorkloadBandwidthLimitsEnabled", "WorkloadBandwidthLimitsEnabled", "true", true),
+	Entry("EgressPolicies", "EgressPolicies",
+		"namespace:tenant-a=snat:192.0.2.10, pool:10.65.1.0/16=gateway:10.65.1.10",
+		[]config.EgressPolicy{
+			{Namespace: "tenant-a", SNATAddress: "192.0.2.10"},
+			{PoolCIDR: "10.65.0.0/16", Gateway: "10.65.1.10"},
+		}),
+	Entry("EgressPolicies bad selector", "EgressPolicies", "node:foo=snat:192.0.2.10",
+		[]config.EgressPolicy(nil), true),
+	Entry("EgressPolicies bad action", "EgressPolicies", "namespace:tenant-a=masquerade",
+		[]config.EgressPolicy(nil), true),
+	Entry("EgressPolicies IPv6 address", "EgressPolicies", "namespace:tenant-a=snat:fd00::1",
+		[]config.EgressPolicy(nil), true),
+	Entry("EgressRoutingRulePriority", "EgressRoutingRulePriority", "200", 200),
+	Entry("EgressGatewayMaxPolicies", "EgressGatewayMaxPolicies", "2", 2),
 
 	Entry("ReportingIntervalSecs", "ReportingIntervalSecs", "31", 31*time.Second),
 	Entry("ReportingTTLSecs", "ReportingTTLSecs", "91", 91*time.Second),
@@ -317,6 +376,8 @@ var _ = DescribeTable("Config parsing",
 		"yes", true),
 	Entry("EndpointReportingDelaySecs", "EndpointReportingDelaySecs",
 		"10", 10*time.Second),
+	Entry("RuleAnalysisInterval", "RuleAnalysisInterval", "300", 300*time.Second),
+	Entry("PolicyCountersGranularity", "PolicyCountersGranularity", "policy", "Policy"),
 
 	Entry("MaxIpsetSize", "MaxIpsetSize", "12345", int(12345)),
 	Entry("IptablesMarkMask", "IptablesMarkMask", "0xf0f0", uint32(0xf0f0)),
@@ -324,11 +385,18 @@ var _ = DescribeTable("Config parsing",
 	Entry("HealthEnabled", "HealthEnabled", "true", true),
 	Entry("HealthHost", "HealthHost", "127.0.0.1", "127.0.0.1"),
 	Entry("HealthPort", "HealthPort", "1234", int(1234)),
+	Entry("DebugCalcGraphRecordingMaxSizeMB", "DebugCalcGraphRecordingMaxSizeMB", "20", int(20)),
+	Entry("DebugCalcGraphRecordingMaxOldFiles", "DebugCalcGraphRecordingMaxOldFiles", "0", int(0)),
+	Entry("DebugServerEnabled", "DebugServerEnabled", "true", true),
+	Entry("DebugServerHost", "DebugServerHost", "127.0.0.1", "127.0.0.1"),
+	Entry("DebugServerPort", "DebugServerPort", "1234", int(1234)),
+	Entry("DebugServerSocketPath", "DebugServerSocketPath", "/var/run/calico/felix-debug.sock", "/var/run/calico/felix-debug.sock"),
 
 	Entry("PrometheusMetricsEnabled", "PrometheusMetricsEnabled", "true", true),
 	Entry("PrometheusMetricsHost", "PrometheusMetricsHost", "10.0.0.1", "10.0.0.1"),
 	Entry("PrometheusMetricsPort", "PrometheusMetricsPort", "1234", int(1234)),
 	Entry("PrometheusGoMetricsEnabled", "PrometheusGoMetricsEnabled", "false", false),
+	Entry("PrometheusWireguardMetricsEnabled", "PrometheusWireguardMetricsEnabled", "false", false),
 	Entry("PrometheusProcessMetricsEnabled", "PrometheusProcessMetricsEnabled", "false", false),
 
 	Entry("FailsafeInboundHostPorts old syntax", "FailsafeInboundHostPorts", "1,2,3,4",
@@ -685,6 +753,13 @@ var _ = DescribeTable("Config validation",
 	Entry("invalid RouteTableRange", map[string]string{
 		"RouteTableRange": "abcde",
 	}, false),
+	Entry("dry-run mode", map[string]string{
+		"DataplaneDryRunEnabled": "true",
+	}, true),
+	Entry("dry-run mode with WireGuard", map[string]string{
+		"DataplaneDryRunEnabled": "true",
+		"WireguardEnabled":       "true",
+	}, false),
 )
 
 var _ = DescribeTable("Config InterfaceExclude",
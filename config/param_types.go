@@ -299,6 +299,19 @@ func (p *Ipv4Param) Parse(raw string) (result interface{}, err error) {
 	return
 }
 
+type Ipv6Param struct {
+	Metadata
+}
+
+func (p *Ipv6Param) Parse(raw string) (result interface{}, err error) {
+	res := net.ParseIP(raw)
+	if res == nil || res.To4() != nil {
+		err = p.parseFailed(raw, "invalid IPv6 address")
+	}
+	result = res
+	return
+}
+
 type PortListParam struct {
 	Metadata
 }
@@ -521,6 +534,28 @@ func (c *CIDRListParam) Parse(raw string) (result interface{}, err error) {
 	return resultSlice, nil
 }
 
+// DualStackCIDRListParam is like CIDRListParam but it accepts IPv6 CIDRs and IPs too.
+type DualStackCIDRListParam struct {
+	Metadata
+}
+
+func (c *DualStackCIDRListParam) Parse(raw string) (result interface{}, err error) {
+	resultSlice := []string{}
+	for _, in := range strings.Split(raw, ",") {
+		val := strings.Trim(in, " ")
+		if len(val) == 0 {
+			continue
+		}
+		_, net, e := cnet.ParseCIDROrIP(val)
+		if e != nil {
+			err = c.parseFailed(in, "invalid CIDR or IP "+val)
+			return
+		}
+		resultSlice = append(resultSlice, net.String())
+	}
+	return resultSlice, nil
+}
+
 type RegionParam struct {
 	Metadata
 }
@@ -579,3 +614,67 @@ func (p *KeyValueListParam) Parse(raw string) (result interface{}, err error) {
 	result, err = stringutils.ParseKeyValueList(raw)
 	return
 }
+
+type EgressPolicyListParam struct {
+	Metadata
+}
+
+func (p *EgressPolicyListParam) Parse(raw string) (interface{}, error) {
+	var policies []EgressPolicy
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, p.parseFailed(raw, "invalid entry "+entry+", must be <selector>=<action>")
+		}
+		var policy EgressPolicy
+
+		kind, value := splitEgressPolicyTerm(parts[0])
+		switch kind {
+		case "namespace":
+			if errs := validation.IsDNS1123Label(value); len(errs) != 0 {
+				return nil, p.parseFailed(raw, "invalid namespace "+value)
+			}
+			policy.Namespace = value
+		case "pool":
+			ip, ipNet, err := cnet.ParseCIDROrIP(value)
+			if err != nil || ip.Version() != 4 {
+				return nil, p.parseFailed(raw, "invalid IPv4 pool CIDR "+value)
+			}
+			policy.PoolCIDR = ipNet.String()
+		default:
+			return nil, p.parseFailed(raw, "invalid selector "+parts[0]+", must be namespace:<name> or pool:<CIDR>")
+		}
+
+		kind, value = splitEgressPolicyTerm(parts[1])
+		switch kind {
+		case "snat":
+			addr := net.ParseIP(value)
+			if addr == nil || addr.To4() == nil {
+				return nil, p.parseFailed(raw, "invalid IPv4 SNAT address "+value)
+			}
+			policy.SNATAddress = addr.String()
+		case "gateway":
+			addr := net.ParseIP(value)
+			if addr == nil || addr.To4() == nil {
+				return nil, p.parseFailed(raw, "invalid IPv4 gateway address "+value)
+			}
+			policy.Gateway = addr.String()
+		default:
+			return nil, p.parseFailed(raw, "invalid action "+parts[1]+", must be snat:<IP> or gateway:<IP>")
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+func splitEgressPolicyTerm(term string) (kind, value string) {
+	parts := strings.SplitN(strings.TrimSpace(term), ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
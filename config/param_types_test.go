@@ -60,6 +60,24 @@ var _ = DescribeTable("CIDR list parameter parsing",
 	Entry("Reject IPv6", "aabc::1111/32", []string{}, false),
 )
 
+var _ = DescribeTable("Dual-stack CIDR list parameter parsing",
+	func(raw string, expected interface{}, expectSuccess bool) {
+		p := config.DualStackCIDRListParam{config.Metadata{
+			Name: "CIDRs",
+		}}
+		actual, err := p.Parse(raw)
+		if expectSuccess {
+			Expect(err).To(BeNil())
+			Expect(actual).To(Equal(expected))
+		} else {
+			Expect(err).NotTo(BeNil())
+		}
+	},
+	Entry("Empty", "", []string{}, true),
+	Entry("Mix of IPv4 and IPv6", "1.1.1.1, fd00::1/64", []string{"1.1.1.1/32", "fd00::/64"}, true),
+	Entry("Reject garbage", "1.1.1.1,foo", []string{}, false),
+)
+
 var _ = DescribeTable("KeyValue list parameter parsing",
 	func(raw string, expected map[string]string) {
 		p := config.KeyValueListParam{config.Metadata{
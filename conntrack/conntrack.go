@@ -1,4 +1,4 @@
-// Copyright (c) 2016-2017,2020 Tigera, Inc. All rights reserved.
+// Copyright (c) 2016-2017,2020-2021 Tigera, Inc. All rights reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -16,11 +16,16 @@ package conntrack
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net"
 	"os/exec"
+	"regexp"
+	"strconv"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/nfnetlink"
 )
 
 // For TCP/UDP, each conntrack entry holds two copies of the tuple
@@ -43,14 +48,58 @@ var deleteDirections = []string{
 
 const numRetries = 3
 
+// numDeletedRegexp extracts the number of deleted flows from the conntrack binary's summary line,
+// for example "conntrack v1.4.4 (conntrack-tools): 3 flow entries have been deleted."
+var numDeletedRegexp = regexp.MustCompile(`(\d+) flow entries`)
+
+// Conntrack removes conntrack flows.  It uses the kernel's ctnetlink API if it is available,
+// falling back to the conntrack binary if not.
 type Conntrack struct {
 	newCmd newCmd
+	// nl is non-nil if we're using ctnetlink instead of the conntrack binary.
+	nl NetlinkIface
+}
+
+// FlowFilter further restricts the flows that RemoveConntrackFlowsForIPs removes.  The zero
+// value matches all flows.
+type FlowFilter struct {
+	// Zone, if non-nil, matches only flows in the given conntrack zone.
+	Zone *uint16
+	// Mark and MarkMask, if MarkMask is non-zero, match only flows whose mark, masked with
+	// MarkMask, is equal to Mark.
+	Mark     uint32
+	MarkMask uint32
+}
+
+func (f FlowFilter) matches(flow *nfnetlink.ConntrackFlow) bool {
+	if f.Zone != nil && flow.Zone != *f.Zone {
+		return false
+	}
+	return flow.Mark&f.MarkMask == f.Mark&f.MarkMask
+}
+
+func (f FlowFilter) args() []string {
+	var args []string
+	if f.Zone != nil {
+		args = append(args, "--zone", strconv.Itoa(int(*f.Zone)))
+	}
+	if f.MarkMask != 0 {
+		args = append(args, "--mark", fmt.Sprintf("%#x/%#x", f.Mark, f.MarkMask))
+	}
+	return args
 }
 
 func New() *Conntrack {
-	return NewWithCmdShim(func(name string, arg ...string) CmdIface {
+	c := NewWithCmdShim(func(name string, arg ...string) CmdIface {
 		return (*cmdAdapter)(exec.Command(name, arg...))
 	})
+	conn, err := nfnetlink.Open(ctNetlinkTimeout)
+	if err != nil {
+		log.WithError(err).Info("Conntrack netlink API unavailable, using conntrack binary.")
+		return c
+	}
+	c.nl = conn
+	return c
 }
 
 type cmdAdapter exec.Cmd
@@ -70,6 +119,13 @@ func NewWithCmdShim(newCmd newCmd) *Conntrack {
 	}
 }
 
+// NewWithNetlinkShim is a test constructor that allows for shimming the ctnetlink connection.
+func NewWithNetlinkShim(nl NetlinkIface) *Conntrack {
+	return &Conntrack{
+		nl: nl,
+	}
+}
+
 type newCmd func(name string, arg ...string) CmdIface
 
 type CmdIface interface {
@@ -77,7 +133,15 @@ type CmdIface interface {
 	Run() error
 }
 
-func (c Conntrack) RemoveConntrackFlows(ipVersion uint8, ipAddr net.IP) {
+// RemoveConntrackFlows removes all conntrack flows to or from the given workload IP.
+func (c *Conntrack) RemoveConntrackFlows(ipVersion uint8, ipAddr net.IP) {
+	_, _ = c.RemoveConntrackFlowsForIPs(ipVersion, []net.IP{ipAddr}, FlowFilter{})
+}
+
+// RemoveConntrackFlowsForIPs removes the conntrack flows that match the filter and that were
+// either originated by, or replied to by, one of the given IPs.  It returns the number of flows
+// that were removed.  Errors are logged as well as returned.
+func (c *Conntrack) RemoveConntrackFlowsForIPs(ipVersion uint8, ipAddrs []net.IP, filter FlowFilter) (int, error) {
 	var family string
 	switch ipVersion {
 	case 4:
@@ -87,21 +151,52 @@ func (c Conntrack) RemoveConntrackFlows(ipVersion uint8, ipAddr net.IP) {
 	default:
 		log.WithField("version", ipVersion).Panic("Unknown IP version")
 	}
-	log.WithField("ip", ipAddr).Info("Removing conntrack flows")
+	logCxt := log.WithField("ips", ipAddrs)
+	logCxt.Info("Removing conntrack flows")
+
+	var numDeleted int
+	var err error
+	if c.nl != nil {
+		numDeleted, err = c.removeFlowsNetlink(ipVersion, ipAddrs, filter)
+	} else {
+		for _, ipAddr := range ipAddrs {
+			n, ipErr := c.removeFlowsExec(family, ipAddr, filter)
+			numDeleted += n
+			if ipErr != nil {
+				err = ipErr
+			}
+		}
+	}
+	if err != nil {
+		return numDeleted, err
+	}
+	logCxt.WithField("numDeleted", numDeleted).Debug("Successfully removed conntrack flows.")
+	return numDeleted, nil
+}
+
+func (c *Conntrack) removeFlowsExec(family string, ipAddr net.IP, filter FlowFilter) (int, error) {
+	numDeleted := 0
+	var lastErr error
 	for _, direction := range deleteDirections {
 		logCxt := log.WithFields(log.Fields{"ip": ipAddr, "direction": direction})
 		// Retry a few times because the conntrack command seems to fail at random.
 		for retry := 0; retry <= numRetries; retry += 1 {
-			cmd := c.newCmd("conntrack",
+			args := []string{
 				"--family", family,
 				"--delete", direction,
-				ipAddr.String())
+				ipAddr.String(),
+			}
+			cmd := c.newCmd("conntrack", append(args, filter.args()...)...)
 
 			// The conntrack tool generates quite a lot of output on stdout (one line per flow) so we
-			// only capture stderr (which is where it logs its errors).
+			// only capture stderr (which is where it logs its errors and the number of deleted flows).
 			var stderrBuf bytes.Buffer
 			cmd.SetStderr(&stderrBuf)
 			err := cmd.Run()
+			if m := numDeletedRegexp.FindSubmatch(stderrBuf.Bytes()); m != nil {
+				n, _ := strconv.Atoi(string(m[1]))
+				numDeleted += n
+			}
 			if err == nil {
 				logCxt.Debug("Successfully removed conntrack flows.")
 				break
@@ -114,9 +209,11 @@ func (c Conntrack) RemoveConntrackFlows(ipVersion uint8, ipAddr net.IP) {
 			}
 			if retry == numRetries {
 				logCxt.WithError(err).WithField("output", stderrBuf.String()).Error("Failed to remove conntrack flows after retries.")
+				lastErr = err
 			} else {
 				logCxt.WithError(err).WithField("output", stderrBuf.String()).Debug("Failed to remove conntrack flows, will retry...")
 			}
 		}
 	}
+	return numDeleted, lastErr
 }
@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conntrack
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/projectcalico/felix/nfnetlink"
+)
+
+// ctNetlinkTimeout is the receive timeout for the ctnetlink socket.
+const ctNetlinkTimeout = 10 * time.Second
+
+// NetlinkIface is the subset of nfnetlink.Conn that we use; shimmed for UT.
+type NetlinkIface interface {
+	Execute(msg *nfnetlink.Message) ([]nfnetlink.Message, error)
+	DumpFunc(msg *nfnetlink.Message, fn func(m *nfnetlink.Message) error) error
+}
+
+// removeFlowsNetlink deletes the matching flows using the kernel's ctnetlink API.  Unlike the
+// conntrack binary, which dumps the whole table for each IP and direction, we check every IP and
+// both directions in a single dump.
+func (c *Conntrack) removeFlowsNetlink(ipVersion uint8, ipAddrs []net.IP, filter FlowFilter) (int, error) {
+	family := uint8(unix.AF_INET)
+	if ipVersion == 6 {
+		family = unix.AF_INET6
+	}
+	addrs := map[string]bool{}
+	for _, addr := range ipAddrs {
+		addrs[addr.String()] = true
+	}
+
+	numDeleted := 0
+	var err error
+	for retry := 0; retry <= numRetries; retry++ {
+		// We can't delete flows from inside the dump callback because the connection is busy
+		// until the dump is finished.
+		var flows []nfnetlink.ConntrackFlow
+		err = c.nl.DumpFunc(nfnetlink.ConntrackDumpRequest(family), func(m *nfnetlink.Message) error {
+			flow, err := nfnetlink.ParseConntrackFlow(m)
+			if err != nil {
+				log.WithError(err).Warn("Failed to parse conntrack entry, ignoring.")
+				return nil
+			}
+			if !addrs[flow.Orig.Src.String()] && !addrs[flow.Reply.Src.String()] {
+				return nil
+			}
+			if !filter.matches(&flow) {
+				return nil
+			}
+			flows = append(flows, flow)
+			return nil
+		})
+		if err != nil && !errors.Is(err, nfnetlink.ErrDumpInterrupted) {
+			log.WithError(err).Debug("Failed to dump conntrack table, will retry...")
+			continue
+		}
+		// If the dump was interrupted, the flows that we did see are still worth deleting (the
+		// flow ID protects against deleting a newer flow) but we may have missed some, so go
+		// round again.
+		for i := range flows {
+			_, delErr := c.nl.Execute(nfnetlink.ConntrackDeleteRequest(family, &flows[i]))
+			if errors.Is(delErr, unix.ENOENT) {
+				// Flow already gone.
+				continue
+			}
+			if delErr != nil {
+				log.WithError(delErr).WithField("flow", flows[i]).Debug("Failed to delete conntrack flow")
+				err = delErr
+				continue
+			}
+			numDeleted++
+		}
+		if err == nil {
+			return numDeleted, nil
+		}
+	}
+	log.WithError(err).WithField("ips", ipAddrs).Error("Failed to remove conntrack flows after retries.")
+	return numDeleted, err
+}
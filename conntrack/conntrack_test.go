@@ -1,4 +1,4 @@
-// Copyright (c) 2017,2020 Tigera, Inc. All rights reserved.
+// Copyright (c) 2017,2020-2021 Tigera, Inc. All rights reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -21,8 +21,10 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
 
 	. "github.com/projectcalico/felix/conntrack"
+	"github.com/projectcalico/felix/nfnetlink"
 )
 
 var _ = Describe("Conntrack", func() {
@@ -99,16 +101,188 @@ var _ = Describe("Conntrack", func() {
 	})
 })
 
+var _ = Describe("Conntrack with a zone and mark filter", func() {
+	It("should pass the filter to the conntrack binary and count the deleted flows", func() {
+		cmdRec := &cmdRecorder{stderr: "conntrack v1.4.4 (conntrack-tools): 2 flow entries have been deleted."}
+		conntrack := NewWithCmdShim(cmdRec.newCmd)
+		zone := uint16(3)
+		n, err := conntrack.RemoveConntrackFlowsForIPs(4, []net.IP{net.ParseIP("10.0.0.1")},
+			FlowFilter{Zone: &zone, Mark: 0x100, MarkMask: 0xf00})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(4))
+		Expect(cmdRec.cmdArgs).To(Equal([][]string{
+			{"--family", "ipv4", "--delete", "--orig-src", "10.0.0.1", "--zone", "3", "--mark", "0x100/0xf00"},
+			{"--family", "ipv4", "--delete", "--reply-src", "10.0.0.1", "--zone", "3", "--mark", "0x100/0xf00"},
+		}))
+	})
+})
+
+var _ = Describe("Conntrack over netlink", func() {
+	var kernel *mockCTNetlink
+	var conntrack *Conntrack
+
+	BeforeEach(func() {
+		kernel = &mockCTNetlink{}
+		conntrack = NewWithNetlinkShim(kernel)
+	})
+
+	addFlow := func(flow nfnetlink.ConntrackFlow) {
+		flow.ID = uint32(len(kernel.flows) + 1)
+		flow.Orig.Proto = unix.IPPROTO_TCP
+		flow.Reply.Proto = unix.IPPROTO_TCP
+		kernel.flows = append(kernel.flows, flow)
+	}
+	tuple := func(src, dst string) nfnetlink.ConntrackTuple {
+		return nfnetlink.ConntrackTuple{Src: net.ParseIP(src).To4(), Dst: net.ParseIP(dst).To4()}
+	}
+	remainingIDs := func() []uint32 {
+		var ids []uint32
+		for _, f := range kernel.flows {
+			ids = append(ids, f.ID)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		// Originated by 10.0.0.1.
+		addFlow(nfnetlink.ConntrackFlow{Orig: tuple("10.0.0.1", "10.96.0.10"), Reply: tuple("10.0.1.5", "10.0.0.1")})
+		// Received by 10.0.0.2.
+		addFlow(nfnetlink.ConntrackFlow{Orig: tuple("10.0.0.9", "10.0.0.2"), Reply: tuple("10.0.0.2", "10.0.0.9"), Mark: 0x100})
+		// Unrelated.
+		addFlow(nfnetlink.ConntrackFlow{Orig: tuple("10.0.0.9", "10.0.0.3"), Reply: tuple("10.0.0.3", "10.0.0.9")})
+		// Received by 10.0.0.2 in another zone.
+		addFlow(nfnetlink.ConntrackFlow{Orig: tuple("10.0.0.9", "10.0.0.2"), Reply: tuple("10.0.0.2", "10.0.0.9"), Zone: 5})
+	})
+
+	It("should remove the flows of all the IPs in one dump", func() {
+		n, err := conntrack.RemoveConntrackFlowsForIPs(4,
+			[]net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, FlowFilter{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(3))
+		Expect(kernel.numDumps).To(Equal(1))
+		Expect(remainingIDs()).To(Equal([]uint32{3}))
+	})
+
+	It("should only remove the flows that match the filter", func() {
+		zone := uint16(0)
+		n, err := conntrack.RemoveConntrackFlowsForIPs(4, []net.IP{net.ParseIP("10.0.0.2")},
+			FlowFilter{Zone: &zone, Mark: 0x100, MarkMask: 0x100})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(1))
+		Expect(remainingIDs()).To(Equal([]uint32{1, 3, 4}))
+	})
+
+	It("should retry if the dump is interrupted", func() {
+		kernel.interruptNextDump = true
+		conntrack.RemoveConntrackFlows(4, net.ParseIP("10.0.0.1"))
+		Expect(kernel.numDumps).To(Equal(2))
+		Expect(remainingIDs()).To(Equal([]uint32{2, 3, 4}))
+	})
+
+	It("should ignore flows that have already gone", func() {
+		kernel.deleteErr = unix.ENOENT
+		n, err := conntrack.RemoveConntrackFlowsForIPs(4, []net.IP{net.ParseIP("10.0.0.1")}, FlowFilter{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(0))
+	})
+
+	It("should give up after a persistent error", func() {
+		kernel.deleteErr = unix.EPERM
+		_, err := conntrack.RemoveConntrackFlowsForIPs(4, []net.IP{net.ParseIP("10.0.0.1")}, FlowFilter{})
+		Expect(err).To(MatchError(unix.EPERM))
+		Expect(kernel.numDumps).To(Equal(4))
+	})
+})
+
+// Attribute types from the kernel's uapi/linux/netfilter/nfnetlink_conntrack.h.
+const (
+	ctaTupleOrig  = 1
+	ctaTupleReply = 2
+	ctaMark       = 8
+	ctaID         = 12
+	ctaZone       = 18
+
+	ctaTupleIP      = 1
+	ctaTupleProto   = 2
+	ctaIPv4Src      = 1
+	ctaIPv4Dst      = 2
+	ctaProtoNum     = 1
+	ctaProtoSrcPort = 2
+	ctaProtoDstPort = 3
+)
+
+// mockCTNetlink is a mock of the kernel's ctnetlink API, holding a list of IPv4 flows.
+type mockCTNetlink struct {
+	flows             []nfnetlink.ConntrackFlow
+	numDumps          int
+	interruptNextDump bool
+	deleteErr         error
+}
+
+func (k *mockCTNetlink) Execute(msg *nfnetlink.Message) ([]nfnetlink.Message, error) {
+	Expect(msg.Type).To(Equal(nfnetlink.MsgType(nfnetlink.SubsysCTNetlink, 2)), "Expected a delete")
+	Expect(msg.Family).To(Equal(uint8(unix.AF_INET)))
+	if k.deleteErr != nil {
+		return nil, k.deleteErr
+	}
+	req, err := nfnetlink.ParseConntrackFlow(msg)
+	Expect(err).NotTo(HaveOccurred())
+	for i, f := range k.flows {
+		if f.ID == req.ID {
+			Expect(req.Orig).To(Equal(f.Orig))
+			Expect(req.Zone).To(Equal(f.Zone))
+			k.flows = append(k.flows[:i], k.flows[i+1:]...)
+			return nil, nil
+		}
+	}
+	return nil, unix.ENOENT
+}
+
+func (k *mockCTNetlink) DumpFunc(msg *nfnetlink.Message, fn func(m *nfnetlink.Message) error) error {
+	Expect(msg.Type).To(Equal(nfnetlink.MsgType(nfnetlink.SubsysCTNetlink, 1)), "Expected a dump")
+	Expect(msg.Family).To(Equal(uint8(unix.AF_INET)))
+	k.numDumps++
+	for _, f := range k.flows {
+		var e nfnetlink.AttrEncoder
+		e.Nested(ctaTupleOrig, func(e *nfnetlink.AttrEncoder) { encodeTuple(e, f.Orig) })
+		e.Nested(ctaTupleReply, func(e *nfnetlink.AttrEncoder) { encodeTuple(e, f.Reply) })
+		e.Uint32BE(ctaMark, f.Mark)
+		e.Uint32BE(ctaID, f.ID)
+		if f.Zone != 0 {
+			e.Uint16BE(ctaZone, f.Zone)
+		}
+		Expect(fn(&nfnetlink.Message{Type: msg.Type, Family: msg.Family, Attrs: e.Encode()})).To(Succeed())
+		if k.interruptNextDump {
+			k.interruptNextDump = false
+			return nfnetlink.ErrDumpInterrupted
+		}
+	}
+	return nil
+}
+
+func encodeTuple(e *nfnetlink.AttrEncoder, t nfnetlink.ConntrackTuple) {
+	e.Nested(ctaTupleIP, func(e *nfnetlink.AttrEncoder) {
+		e.Bytes(ctaIPv4Src, t.Src.To4())
+		e.Bytes(ctaIPv4Dst, t.Dst.To4())
+	})
+	e.Nested(ctaTupleProto, func(e *nfnetlink.AttrEncoder) {
+		e.Uint8(ctaProtoNum, t.Proto)
+		e.Uint16BE(ctaProtoSrcPort, t.SrcPort)
+		e.Uint16BE(ctaProtoDstPort, t.DstPort)
+	})
+}
+
 type cmdRecorder struct {
 	commands        []*mockCmd
 	cmdArgs         [][]string
 	nextError       error
 	persistentError error
+	stderr          string
 }
 
 func (r *cmdRecorder) newCmd(name string, arg ...string) CmdIface {
 	Expect(name).To(Equal("conntrack"))
-	mc := &mockCmd{}
+	mc := &mockCmd{stderr: r.stderr}
 	if r.nextError != nil {
 		mc.err = r.nextError
 		r.nextError = nil
@@ -122,17 +296,19 @@ func (r *cmdRecorder) newCmd(name string, arg ...string) CmdIface {
 }
 
 type mockCmd struct {
-	err    error
-	stderr io.Writer
+	err       error
+	stderr    string
+	stderrOut io.Writer
 }
 
 func (m *mockCmd) SetStderr(w io.Writer) {
-	m.stderr = w
+	m.stderrOut = w
 }
 
 func (m *mockCmd) Run() error {
+	_, _ = m.stderrOut.Write([]byte(m.stderr))
 	if m.err != nil {
-		_, _ = m.stderr.Write([]byte(m.err.Error()))
+		_, _ = m.stderrOut.Write([]byte(m.err.Error()))
 	}
 	return m.err
 }
@@ -61,6 +61,7 @@ import (
 	"github.com/projectcalico/felix/config"
 	_ "github.com/projectcalico/felix/config"
 	dp "github.com/projectcalico/felix/dataplane"
+	"github.com/projectcalico/felix/debugserver"
 	"github.com/projectcalico/felix/jitter"
 	"github.com/projectcalico/felix/logutils"
 	"github.com/projectcalico/felix/policysync"
@@ -583,6 +584,25 @@ configRetry:
 		statsCollector.RegisterWith(asyncCalcGraph.CalcGraph)
 	}
 
+	if configParams.DebugServerEnabled {
+		debugServer := debugserver.New(
+			configParams.DebugServerHost,
+			configParams.DebugServerPort,
+			configParams.DebugServerSocketPath,
+		)
+		debugServer.RegisterExplainer(asyncCalcGraph)
+		debugServer.RegisterState("calcGraph", func() interface{} {
+			return asyncCalcGraph.Snapshot()
+		})
+		debugServer.RegisterState("ruleAnalysis", func() interface{} {
+			return asyncCalcGraph.AnalyseRules()
+		})
+		if reporter, ok := dpDriver.(dp.DebugStateReporter); ok {
+			debugServer.RegisterState("dataplane", reporter.DebugState)
+		}
+		debugServer.Start()
+	}
+
 	// Create the validator, which sits between the syncer and the
 	// calculation graph.
 	validator := calc.NewValidationFilter(asyncCalcGraph)
@@ -1041,7 +1061,7 @@ func (fc *DataplaneConnector) handleProcessStatusUpdate(ctx context.Context, msg
 	}
 }
 
-func (fc *DataplaneConnector) reconcileWireguardStatUpdate(dpPubKey string) error {
+func (fc *DataplaneConnector) reconcileWireguardStatUpdate(dpPubKey, dpIPv6InterfaceAddr string) error {
 	// In case of a recoverable failure (ErrorResourceUpdateConflict), retry update 3 times.
 	for iter := 0; iter < 3; iter++ {
 		// Read node resource from datastore and compare it with the publicKey from dataplane.
@@ -1066,11 +1086,21 @@ func (fc *DataplaneConnector) reconcileWireguardStatUpdate(dpPubKey string) erro
 			return err
 		}
 
-		// Check if the public-key needs to be updated.
+		// Check if the public-key or the IPv6 interface address need to be updated. The Node resource has no field
+		// for the IPv6 wireguard interface address so it is stored as an annotation.
 		storedPublicKey := node.Status.WireguardPublicKey
-		if storedPublicKey != dpPubKey {
+		storedIPv6InterfaceAddr := node.Annotations[calc.WireguardIPv6InterfaceAddrAnnotation]
+		if storedPublicKey != dpPubKey || storedIPv6InterfaceAddr != dpIPv6InterfaceAddr {
 			updateCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 			node.Status.WireguardPublicKey = dpPubKey
+			if dpIPv6InterfaceAddr != "" {
+				if node.Annotations == nil {
+					node.Annotations = map[string]string{}
+				}
+				node.Annotations[calc.WireguardIPv6InterfaceAddrAnnotation] = dpIPv6InterfaceAddr
+			} else {
+				delete(node.Annotations, calc.WireguardIPv6InterfaceAddrAnnotation)
+			}
 			_, err := fc.datastorev3.Nodes().Update(updateCtx, node, options.SetOptions{})
 			cancel()
 			if err != nil {
@@ -1084,7 +1114,8 @@ func (fc *DataplaneConnector) reconcileWireguardStatUpdate(dpPubKey string) erro
 				log.WithError(err).Info("Failed updating node resource")
 				return err
 			}
-			log.Debugf("Updated Wireguard public-key from %s to %s", storedPublicKey, dpPubKey)
+			log.Debugf("Updated Wireguard public-key from %s to %s, IPv6 interface address from %q to %q",
+				storedPublicKey, dpPubKey, storedIPv6InterfaceAddr, dpIPv6InterfaceAddr)
 		}
 		break
 	}
@@ -1109,7 +1140,7 @@ func (fc *DataplaneConnector) handleWireguardStatUpdateFromDataplane() {
 		}
 
 		// Try and reconcile the current wireguard status data.
-		err := fc.reconcileWireguardStatUpdate(current.PublicKey)
+		err := fc.reconcileWireguardStatUpdate(current.PublicKey, current.InterfaceIpv6Addr)
 		if err == nil {
 			current = nil
 			retryC = nil
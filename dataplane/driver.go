@@ -33,8 +33,11 @@ import (
 	extdataplane "github.com/projectcalico/felix/dataplane/external"
 	"github.com/projectcalico/felix/dataplane/inactive"
 	intdataplane "github.com/projectcalico/felix/dataplane/linux"
+	"github.com/projectcalico/felix/drift"
+	"github.com/projectcalico/felix/dryrun"
 	"github.com/projectcalico/felix/idalloc"
 	"github.com/projectcalico/felix/ifacemonitor"
+	"github.com/projectcalico/felix/ip"
 	"github.com/projectcalico/felix/ipsets"
 	"github.com/projectcalico/felix/logutils"
 	"github.com/projectcalico/felix/markbits"
@@ -163,6 +166,59 @@ func StartDataplaneDriver(configParams *config.Config,
 			log.WithError(err).Warning("Unable to assign table index for wireguard")
 		}
 
+		// Always allocate the egress gateway table indices (even when there are no gateway policies). This
+		// ensures we can tidy up routes and rules if gateway policies are removed or reordered.
+		var egressTableIndices []int
+		for i := 0; i < configParams.EgressGatewayMaxPolicies; i++ {
+			idx, err := routeTableIndexAllocator.GrabIndex()
+			if err != nil {
+				log.WithError(err).Warning("Unable to assign all table indices for egress gateway policies")
+				break
+			}
+			egressTableIndices = append(egressTableIndices, idx)
+		}
+		log.Debugf("Assigned egress gateway table indices: %v", egressTableIndices)
+
+		// Give each egress gateway policy one of the egress gateway tables.  A policy that can't have
+		// a table is skipped, which leaves its workloads' traffic with the normal source address.
+		var egressPolicies []intdataplane.EgressPolicy
+		var egressSNATEnabled bool
+		numGatewayPolicies := 0
+		for _, p := range configParams.EgressPolicies {
+			policy := intdataplane.EgressPolicy{Namespace: p.Namespace}
+			if p.PoolCIDR != "" {
+				policy.PoolCIDR = ip.MustParseCIDROrIP(p.PoolCIDR)
+			}
+			if p.SNATAddress != "" {
+				policy.SNATAddress = ip.FromString(p.SNATAddress)
+				egressSNATEnabled = true
+			} else {
+				policy.Gateway = ip.FromString(p.Gateway)
+				if numGatewayPolicies >= len(egressTableIndices) {
+					log.WithField("policy", policy).Error(
+						"Unable to assign table index for egress gateway policy, ignoring it")
+					continue
+				}
+				policy.RouteTableIndex = egressTableIndices[numGatewayPolicies]
+				numGatewayPolicies++
+			}
+			egressPolicies = append(egressPolicies, policy)
+		}
+
+		var dryRunRecorder *dryrun.Recorder
+		if configParams.DataplaneDryRunEnabled {
+			dryRunRecorder = dryrun.NewRecorder(configParams.DataplaneDryRunMaxChanges)
+		}
+
+		var driftReporter *drift.Reporter
+		if configParams.DataplaneDryRunEnabled {
+			// In dry-run mode, nothing is written so every resync would find the same "drift".
+			log.Info("Dataplane drift reporting disabled in dry-run mode.")
+		} else {
+			driftReporter = drift.NewReporter(configParams.DataplaneDriftMaxEvents,
+				configParams.DataplaneDriftAuditLogFile)
+		}
+
 		// If wireguard is enabled, update the failsafe ports to include the wireguard port.
 		failsafeInboundHostPorts := configParams.FailsafeInboundHostPorts
 		failsafeOutboundHostPorts := configParams.FailsafeOutboundHostPorts
@@ -259,10 +315,13 @@ func StartDataplaneDriver(configParams *config.Config,
 				AllowVXLANPacketsFromWorkloads: configParams.AllowVXLANPacketsFromWorkloads,
 				AllowIPIPPacketsFromWorkloads:  configParams.AllowIPIPPacketsFromWorkloads,
 
-				WireguardEnabled:       configParams.WireguardEnabled,
-				WireguardInterfaceName: configParams.WireguardInterfaceName,
+				WireguardEnabled:           configParams.WireguardEnabled,
+				WireguardInterfaceName:     configParams.WireguardInterfaceName,
+				WireguardInterfaceIPv6Addr: configParams.WireguardInterfaceIPv6Addr,
 
 				IptablesLogPrefix:         configParams.LogPrefix,
+				AuditLogRateLimit:         configParams.AuditLogRateLimit,
+				AuditLogRateLimitBurst:    configParams.AuditLogRateLimitBurst,
 				EndpointToHostAction:      configParams.DefaultEndpointToHostAction,
 				IptablesFilterAllowAction: configParams.IptablesFilterAllowAction,
 				IptablesMangleAllowAction: configParams.IptablesMangleAllowAction,
@@ -276,19 +335,34 @@ func StartDataplaneDriver(configParams *config.Config,
 				IptablesNATOutgoingInterfaceFilter: configParams.IptablesNATOutgoingInterfaceFilter,
 				NATOutgoingAddress:                 configParams.NATOutgoingAddress,
 				BPFEnabled:                         configParams.BPFEnabled,
+				EgressSNATEnabled:                  egressSNATEnabled && !configParams.BPFEnabled,
 				ServiceLoopPrevention:              configParams.ServiceLoopPrevention,
+				DNSSnoopingEnabled:                 configParams.DNSSnoopingEnabled,
+				DNSSnoopingNFLOGGroup:              uint16(configParams.DNSSnoopingNFLOGGroup),
+				DNSTrustedServers:                  configParams.DNSTrustedServers,
+				FlowLogsEnabled:                    configParams.FlowLogsEnabled,
+				FlowLogsNFLOGGroup:                 uint16(configParams.FlowLogsNFLOGGroup),
+				PolicyCountersEnabled:              configParams.PolicyCountersEnabled,
+				DeniedPacketLogEnabled:             configParams.DeniedPacketLogEnabled,
+				DeniedPacketLogNFLOGGroup:          uint16(configParams.DeniedPacketLogNFLOGGroup),
+				DeniedPacketLogRateLimit:           configParams.DeniedPacketLogRateLimit,
 			},
 			Wireguard: wireguard.Config{
-				Enabled:             wireguardEnabled,
-				ListeningPort:       configParams.WireguardListeningPort,
-				FirewallMark:        int(markWireguard),
-				RoutingRulePriority: configParams.WireguardRoutingRulePriority,
-				RoutingTableIndex:   wireguardTableIndex,
-				InterfaceName:       configParams.WireguardInterfaceName,
-				MTU:                 configParams.WireguardMTU,
+				Enabled:               wireguardEnabled,
+				ListeningPort:         configParams.WireguardListeningPort,
+				FirewallMark:          int(markWireguard),
+				RoutingRulePriority:   configParams.WireguardRoutingRulePriority,
+				RoutingTableIndex:     wireguardTableIndex,
+				InterfaceName:         configParams.WireguardInterfaceName,
+				MTU:                   configParams.WireguardMTU,
+				EnableIPv6:            configParams.Ipv6Support,
+				InterfaceIPv6Addr:     configParams.WireguardInterfaceIPv6Addr,
+				KeyRotationInterval:   configParams.WireguardKeyRotationInterval,
+				HostEncryptionEnabled: configParams.WireguardHostEncryptionEnabled,
 			},
 			IPIPMTU:                        configParams.IpInIpMtu,
 			VXLANMTU:                       configParams.VXLANMTU,
+			VXLANMTUV6:                     configParams.VXLANMTUV6,
 			VXLANPort:                      configParams.VXLANPort,
 			IptablesBackend:                configParams.IptablesBackend,
 			IptablesRefreshInterval:        configParams.IptablesRefreshInterval,
@@ -297,15 +371,30 @@ func StartDataplaneDriver(configParams *config.Config,
 			DeviceRouteProtocol:            configParams.DeviceRouteProtocol,
 			RemoveExternalRoutes:           configParams.RemoveExternalRoutes,
 			IPSetsRefreshInterval:          configParams.IpsetsRefreshInterval,
+			IPSetsBackend:                  configParams.IpsetsBackend,
 			IptablesPostWriteCheckInterval: configParams.IptablesPostWriteCheckIntervalSecs,
 			IptablesInsertMode:             configParams.ChainInsertMode,
 			IptablesLockFilePath:           configParams.IptablesLockFilePath,
 			IptablesLockTimeout:            configParams.IptablesLockTimeoutSecs,
 			IptablesLockProbeInterval:      configParams.IptablesLockProbeIntervalMillis,
+			IptablesPersistentRestore:      configParams.IptablesPersistentRestoreEnabled,
 			MaxIPSetSize:                   configParams.MaxIpsetSize,
 			IPv6Enabled:                    configParams.Ipv6Support,
 			StatusReportingInterval:        configParams.ReportingIntervalSecs,
 			XDPRefreshInterval:             configParams.XDPRefreshInterval,
+			DNSExtraTTL:                    configParams.DNSExtraTTL,
+			FlowLogsFlushInterval:          configParams.FlowLogsFlushInterval,
+			FlowLogsDestination:            configParams.FlowLogsDestination,
+			PolicyCountersGranularity:      configParams.PolicyCountersGranularity,
+			PolicyCountersMaxSeries:        configParams.PolicyCountersMaxSeries,
+			DeniedPacketLogFile:            configParams.DeniedPacketLogFile,
+			DeniedPacketLogRateLimit:       configParams.DeniedPacketLogRateLimit,
+			WireguardPeerMetricsEnabled:    configParams.PrometheusMetricsEnabled && configParams.PrometheusWireguardMetricsEnabled,
+			WorkloadBandwidthLimitsEnabled: configParams.WorkloadBandwidthLimitsEnabled,
+
+			EgressPolicies:            egressPolicies,
+			EgressRoutingRulePriority: configParams.EgressRoutingRulePriority,
+			EgressRouteTableIndices:   egressTableIndices,
 
 			NetlinkTimeout: configParams.NetlinkTimeoutSecs,
 
@@ -324,6 +413,8 @@ func StartDataplaneDriver(configParams *config.Config,
 			},
 			HealthAggregator:                   healthAggregator,
 			DebugSimulateDataplaneHangAfter:    configParams.DebugSimulateDataplaneHangAfter,
+			DryRun:                             dryRunRecorder,
+			DriftReporter:                      driftReporter,
 			ExternalNodesCidrs:                 configParams.ExternalNodesCIDRList,
 			SidecarAccelerationEnabled:         configParams.SidecarAccelerationEnabled,
 			BPFEnabled:                         configParams.BPFEnabled,
@@ -18,3 +18,9 @@ type DataplaneDriver interface {
 	SendMessage(msg interface{}) error
 	RecvMessage() (msg interface{}, err error)
 }
+
+// DebugStateReporter is implemented by dataplane drivers that can report their internal state
+// to the debug server.  The returned value must be JSON-serializable.
+type DebugStateReporter interface {
+	DebugState() interface{}
+}
@@ -0,0 +1,157 @@
+// +build !windows
+
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/projectcalico/felix/bpf"
+	"github.com/projectcalico/felix/bpf/polprog"
+	"github.com/projectcalico/felix/bpf/tc"
+	"github.com/projectcalico/felix/dryrun"
+)
+
+const bpfDryRunComponent = "bpf-endpoints"
+
+// dryRunBPFDataplane is the bpfDataplane that the BPF endpoint manager uses in observe-only mode.
+// It records the programs that the manager would attach and the policy programs that it would
+// load instead of touching the interfaces.
+//
+// Since nothing is attached, we hand out fake jump map FDs, one per attach point, so that we can
+// tell which attach point the manager's later policy program updates are for.
+type dryRunBPFDataplane struct {
+	recorder *dryrun.Recorder
+
+	lastFD            bpf.MapFD
+	attachPointToFD   map[string]bpf.MapFD
+	fdToAttachPoint   map[bpf.MapFD]string
+	fdToPolicy        map[bpf.MapFD]polprog.Rules
+	ifacesWithQdisc   map[string]bool
+	ifaceAcceptLocals map[string]bool
+}
+
+func newDryRunBPFDataplane(recorder *dryrun.Recorder) *dryRunBPFDataplane {
+	return &dryRunBPFDataplane{
+		recorder:          recorder,
+		attachPointToFD:   map[string]bpf.MapFD{},
+		fdToAttachPoint:   map[bpf.MapFD]string{},
+		fdToPolicy:        map[bpf.MapFD]polprog.Rules{},
+		ifacesWithQdisc:   map[string]bool{},
+		ifaceAcceptLocals: map[string]bool{},
+	}
+}
+
+func (d *dryRunBPFDataplane) record(format string, args ...interface{}) {
+	d.recorder.Record(bpfDryRunComponent, fmt.Sprintf(format, args...))
+}
+
+func (d *dryRunBPFDataplane) ensureStarted() {
+	// The real dataplane starts the jump map cleanup here, which we mustn't do.
+}
+
+func (d *dryRunBPFDataplane) ensureQdisc(iface string) error {
+	if d.ifacesWithQdisc[iface] {
+		return nil
+	}
+	d.ifacesWithQdisc[iface] = true
+	d.record("ensure clsact qdisc on %s", iface)
+	return nil
+}
+
+func (d *dryRunBPFDataplane) ensureProgramAttached(ap *tc.AttachPoint, polDirection PolDirection) (bpf.MapFD, error) {
+	key := fmt.Sprintf("%s %s", ap.Iface, ap.Hook)
+	if ap.IPv6 {
+		key += " ipv6"
+	}
+	if fd, ok := d.attachPointToFD[key]; ok {
+		return fd, nil
+	}
+	d.lastFD++
+	d.attachPointToFD[key] = d.lastFD
+	d.fdToAttachPoint[d.lastFD] = key
+	d.record("attach program %s (%s) to %s", ap.ProgramName(), ap.FileName(), key)
+	return d.lastFD, nil
+}
+
+func (d *dryRunBPFDataplane) updatePolicyProgram(jumpMapFD bpf.MapFD, rules polprog.Rules, ipv6 bool) error {
+	if old, ok := d.fdToPolicy[jumpMapFD]; ok && reflect.DeepEqual(old, rules) {
+		return nil
+	}
+	d.fdToPolicy[jumpMapFD] = rules
+	d.record("load policy program on %s: %s", d.fdToAttachPoint[jumpMapFD], describePolicyRules(&rules))
+	return nil
+}
+
+func (d *dryRunBPFDataplane) removePolicyProgram(jumpMapFD bpf.MapFD) error {
+	if _, ok := d.fdToPolicy[jumpMapFD]; !ok {
+		return nil
+	}
+	delete(d.fdToPolicy, jumpMapFD)
+	d.record("remove policy program from %s", d.fdToAttachPoint[jumpMapFD])
+	return nil
+}
+
+func (d *dryRunBPFDataplane) setAcceptLocal(iface string, val bool) error {
+	if old, ok := d.ifaceAcceptLocals[iface]; ok && old == val {
+		return nil
+	}
+	d.ifaceAcceptLocals[iface] = val
+	d.record("set accept_local on %s to %v", iface, val)
+	return nil
+}
+
+// describePolicyRules summarises the policies in a policy program, for example
+// "tiers=[default: ns1/pol1, ns1/pol2] profiles=[kns.ns1]".
+func describePolicyRules(rules *polprog.Rules) string {
+	var parts []string
+	describeTiers := func(name string, tiers []polprog.Tier) {
+		if len(tiers) == 0 {
+			return
+		}
+		var tierDescs []string
+		for _, t := range tiers {
+			var pols []string
+			for _, p := range t.Policies {
+				pols = append(pols, p.Name)
+			}
+			tierDescs = append(tierDescs, fmt.Sprintf("%s: %s", t.Name, strings.Join(pols, ", ")))
+		}
+		parts = append(parts, fmt.Sprintf("%s=[%s]", name, strings.Join(tierDescs, "; ")))
+	}
+	describeProfiles := func(name string, profiles []polprog.Profile) {
+		if len(profiles) == 0 {
+			return
+		}
+		var names []string
+		for _, p := range profiles {
+			names = append(names, p.Name)
+		}
+		parts = append(parts, fmt.Sprintf("%s=[%s]", name, strings.Join(names, ", ")))
+	}
+	describeTiers("tiers", rules.Tiers)
+	describeProfiles("profiles", rules.Profiles)
+	describeTiers("hostPreDNATTiers", rules.HostPreDnatTiers)
+	describeTiers("hostForwardTiers", rules.HostForwardTiers)
+	describeTiers("hostNormalTiers", rules.HostNormalTiers)
+	describeProfiles("hostProfiles", rules.HostProfiles)
+	if len(parts) == 0 {
+		return "default policy only"
+	}
+	return strings.Join(parts, " ")
+}
@@ -78,7 +78,7 @@ type bpfDataplane interface {
 	ensureStarted()
 	ensureProgramAttached(ap *tc.AttachPoint, polDirection PolDirection) (bpf.MapFD, error)
 	ensureQdisc(iface string) error
-	updatePolicyProgram(jumpMapFD bpf.MapFD, rules polprog.Rules) error
+	updatePolicyProgram(jumpMapFD bpf.MapFD, rules polprog.Rules, ipv6 bool) error
 	removePolicyProgram(jumpMapFD bpf.MapFD) error
 	setAcceptLocal(iface string, val bool) error
 }
@@ -100,6 +100,9 @@ type bpfInterfaceInfo struct {
 
 type bpfInterfaceState struct {
 	jumpMapFDs [2]bpf.MapFD
+	// jumpMapV6FDs holds the jump maps of the IPv6 programs, which are attached alongside the
+	// IPv4 programs when IPv6 is enabled.
+	jumpMapV6FDs [2]bpf.MapFD
 }
 
 type bpfEndpointManager struct {
@@ -134,9 +137,23 @@ type bpfEndpointManager struct {
 
 	ipSetMap bpf.Map
 	stateMap bpf.Map
+	auditMap bpf.Map
+	// ruleCountersMap, if non-nil, is the map in which the policy programs count the packets
+	// that match each rule.
+	ruleCountersMap bpf.Map
+
+	// IPv6 support.  If ipv6Enabled is set, we attach the IPv6 programs to each interface in
+	// addition to the IPv4 programs.  The IPv6 IP sets have their own IDs and map.
+	ipv6Enabled    bool
+	ipSetIDAllocV6 *idalloc.IDAllocator
+	ipSetMapV6     bpf.Map
+	stateMapV6     bpf.Map
 
 	ruleRenderer        bpfAllowChainRenderer
 	iptablesFilterTable iptablesTable
+	// iptablesFilterTableV6 is set once the IPv6 tables have been created.  Like the IPv4 table,
+	// it needs the workload allow chains.
+	iptablesFilterTableV6 iptablesTable
 
 	startupOnce      sync.Once
 	mapCleanupRunner *ratelimited.Runner
@@ -173,6 +190,11 @@ func newBPFEndpointManager(
 	bpfExtToServiceConnmark int,
 	ipSetMap bpf.Map,
 	stateMap bpf.Map,
+	auditMap bpf.Map,
+	ruleCountersMap bpf.Map,
+	ipSetIDAllocV6 *idalloc.IDAllocator,
+	ipSetMapV6 bpf.Map,
+	stateMapV6 bpf.Map,
 	iptablesRuleRenderer bpfAllowChainRenderer,
 	iptablesFilterTable iptablesTable,
 	livenessCallback func(),
@@ -203,6 +225,12 @@ func newBPFEndpointManager(
 		bpfExtToServiceConnmark: bpfExtToServiceConnmark,
 		ipSetMap:                ipSetMap,
 		stateMap:                stateMap,
+		auditMap:                auditMap,
+		ruleCountersMap:         ruleCountersMap,
+		ipv6Enabled:             ipSetMapV6 != nil,
+		ipSetIDAllocV6:          ipSetIDAllocV6,
+		ipSetMapV6:              ipSetMapV6,
+		stateMapV6:              stateMapV6,
 		ruleRenderer:            iptablesRuleRenderer,
 		iptablesFilterTable:     iptablesFilterTable,
 		mapCleanupRunner: ratelimited.NewRunner(jumpMapCleanupInterval, func(ctx context.Context) {
@@ -487,6 +515,9 @@ func (m *bpfEndpointManager) CompleteDeferredWork() error {
 	if m.happyWEPsDirty {
 		chains := m.ruleRenderer.WorkloadInterfaceAllowChains(m.happyWEPs)
 		m.iptablesFilterTable.UpdateChains(chains)
+		if m.iptablesFilterTableV6 != nil {
+			m.iptablesFilterTableV6.UpdateChains(chains)
+		}
 		m.happyWEPsDirty = false
 	}
 	bpfHappyEndpointsGauge.Set(float64(len(m.happyWEPs)))
@@ -533,13 +564,26 @@ func (m *bpfEndpointManager) applyProgramsToDirtyDataInterfaces() {
 			ingressWG.Add(1)
 			go func() {
 				defer ingressWG.Done()
-				ingressErr = m.attachDataIfaceProgram(iface, hepPtr, PolDirnIngress)
+				ingressErr = m.attachDataIfaceProgram(iface, hepPtr, PolDirnIngress, false)
 			}()
-			err = m.attachDataIfaceProgram(iface, hepPtr, PolDirnEgress)
+			err = m.attachDataIfaceProgram(iface, hepPtr, PolDirnEgress, false)
 			ingressWG.Wait()
 			if err == nil {
 				err = ingressErr
 			}
+			if err == nil && m.ipv6Enabled && iface != "tunl0" {
+				// IPIP only carries IPv4 so there's no IPv6 program for the tunnel device.
+				ingressWG.Add(1)
+				go func() {
+					defer ingressWG.Done()
+					ingressErr = m.attachDataIfaceProgram(iface, hepPtr, PolDirnIngress, true)
+				}()
+				err = m.attachDataIfaceProgram(iface, hepPtr, PolDirnEgress, true)
+				ingressWG.Wait()
+				if err == nil {
+					err = ingressErr
+				}
+			}
 			if err == nil {
 				// This is required to allow NodePort forwarding with
 				// encapsulation with the host's IP as the source address
@@ -672,13 +716,15 @@ func (m *bpfEndpointManager) applyPolicy(ifaceName string) error {
 		endpointID = iface.info.endpointID
 		if !ifaceUp {
 			log.WithField("iface", ifaceName).Debug("Interface is down/gone, closing jump maps.")
-			for i := range iface.dpState.jumpMapFDs {
-				if iface.dpState.jumpMapFDs[i] > 0 {
-					err := iface.dpState.jumpMapFDs[i].Close()
-					if err != nil {
-						log.WithError(err).Error("Failed to close jump map.")
+			for _, fds := range []*[2]bpf.MapFD{&iface.dpState.jumpMapFDs, &iface.dpState.jumpMapV6FDs} {
+				for i := range fds {
+					if fds[i] > 0 {
+						err := fds[i].Close()
+						if err != nil {
+							log.WithError(err).Error("Failed to close jump map.")
+						}
+						fds[i] = 0
 					}
-					iface.dpState.jumpMapFDs[i] = 0
 				}
 			}
 		}
@@ -719,11 +765,11 @@ func (m *bpfEndpointManager) applyPolicy(ifaceName string) error {
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		ingressErr = m.attachWorkloadProgram(ifaceName, wep, PolDirnIngress)
+		ingressErr = m.attachWorkloadProgram(ifaceName, wep, PolDirnIngress, false)
 	}()
 	go func() {
 		defer wg.Done()
-		egressErr = m.attachWorkloadProgram(ifaceName, wep, PolDirnEgress)
+		egressErr = m.attachWorkloadProgram(ifaceName, wep, PolDirnEgress, false)
 	}()
 	wg.Wait()
 
@@ -734,6 +780,26 @@ func (m *bpfEndpointManager) applyPolicy(ifaceName string) error {
 		return egressErr
 	}
 
+	if m.ipv6Enabled {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ingressErr = m.attachWorkloadProgram(ifaceName, wep, PolDirnIngress, true)
+		}()
+		go func() {
+			defer wg.Done()
+			egressErr = m.attachWorkloadProgram(ifaceName, wep, PolDirnEgress, true)
+		}()
+		wg.Wait()
+
+		if ingressErr != nil {
+			return ingressErr
+		}
+		if egressErr != nil {
+			return egressErr
+		}
+	}
+
 	applyTime := time.Since(startTime)
 	log.WithField("timeTaken", applyTime).Info("Finished applying BPF programs for workload")
 	return nil
@@ -741,8 +807,9 @@ func (m *bpfEndpointManager) applyPolicy(ifaceName string) error {
 
 var calicoRouterIP = net.IPv4(169, 254, 1, 1).To4()
 
-func (m *bpfEndpointManager) attachWorkloadProgram(ifaceName string, endpoint *proto.WorkloadEndpoint, polDirection PolDirection) error {
+func (m *bpfEndpointManager) attachWorkloadProgram(ifaceName string, endpoint *proto.WorkloadEndpoint, polDirection PolDirection, ipv6 bool) error {
 	ap := m.calculateTCAttachPoint(polDirection, ifaceName)
+	ap.IPv6 = ipv6
 	// Host side of the veth is always configured as 169.254.1.1.
 	ap.HostIP = calicoRouterIP
 	// * VXLAN MTU should be the host ifaces MTU -50, in order to allow space for VXLAN.
@@ -791,7 +858,7 @@ func (m *bpfEndpointManager) attachWorkloadProgram(ifaceName string, endpoint *p
 		rules.SuppressNormalHostPolicy = true
 	}
 
-	return m.dp.updatePolicyProgram(jumpMapFD, rules)
+	return m.dp.updatePolicyProgram(jumpMapFD, rules, ipv6)
 }
 
 func (m *bpfEndpointManager) addHostPolicy(rules *polprog.Rules, hostEndpoint *proto.HostEndpoint, polDirection PolDirection) {
@@ -826,8 +893,9 @@ func (m *bpfEndpointManager) ifaceIsUp(ifaceName string) (up bool) {
 	return
 }
 
-func (m *bpfEndpointManager) attachDataIfaceProgram(ifaceName string, ep *proto.HostEndpoint, polDirection PolDirection) error {
+func (m *bpfEndpointManager) attachDataIfaceProgram(ifaceName string, ep *proto.HostEndpoint, polDirection PolDirection, ipv6 bool) error {
 	ap := m.calculateTCAttachPoint(polDirection, ifaceName)
+	ap.IPv6 = ipv6
 	ap.HostIP = m.hostIP
 	ap.TunnelMTU = uint16(m.vxlanMTU)
 	ap.ExtToServiceConnmark = uint32(m.bpfExtToServiceConnmark)
@@ -849,7 +917,7 @@ func (m *bpfEndpointManager) attachDataIfaceProgram(ifaceName string, ep *proto.
 			ForHostInterface: true,
 		}
 		m.addHostPolicy(&rules, ep, polDirection)
-		return m.dp.updatePolicyProgram(jumpMapFD, rules)
+		return m.dp.updatePolicyProgram(jumpMapFD, rules, ipv6)
 	}
 
 	return m.dp.removePolicyProgram(jumpMapFD)
@@ -919,6 +987,7 @@ func (m *bpfEndpointManager) calculateTCAttachPoint(policyDirection PolDirection
 	ap.DSR = m.dsrEnabled
 	ap.LogLevel = m.bpfLogLevel
 	ap.VXLANPort = m.vxlanPort
+	ap.IPv6Enabled = m.ipv6Enabled && endpointType != tc.EpTypeTunnel
 
 	return ap
 }
@@ -951,8 +1020,9 @@ func (m *bpfEndpointManager) extractTiers(tier *proto.TierInfo, direction PolDir
 				prules = pol.OutboundRules
 			}
 			policy := polprog.Policy{
-				Name:  polName,
-				Rules: make([]polprog.Rule, len(prules)),
+				Name:      polName,
+				Rules:     make([]polprog.Rule, len(prules)),
+				AuditOnly: pol.AuditOnly,
 			}
 
 			for ri, r := range prules {
@@ -1244,7 +1314,7 @@ func (m *bpfEndpointManager) ensureQdisc(iface string) error {
 
 // Ensure TC program is attached to the specified interface and return its jump map FD.
 func (m *bpfEndpointManager) ensureProgramAttached(ap *tc.AttachPoint, polDirection PolDirection) (bpf.MapFD, error) {
-	jumpMapFD := m.getJumpMapFD(ap.Iface, polDirection)
+	jumpMapFD := m.getJumpMapFD(ap.Iface, polDirection, ap.IPv6)
 	if jumpMapFD != 0 {
 		if attached, err := ap.IsAttached(); err != nil {
 			return jumpMapFD, fmt.Errorf("failed to check if interface %s had BPF program; %w", ap.Iface, err)
@@ -1257,7 +1327,7 @@ func (m *bpfEndpointManager) ensureProgramAttached(ap *tc.AttachPoint, polDirect
 			if err != nil {
 				log.WithError(err).Warn("Failed to close jump map FD. Ignoring.")
 			}
-			m.setJumpMapFD(ap.Iface, polDirection, 0)
+			m.setJumpMapFD(ap.Iface, polDirection, ap.IPv6, 0)
 			jumpMapFD = 0 // Trigger program to be re-added below.
 		}
 	}
@@ -1273,34 +1343,52 @@ func (m *bpfEndpointManager) ensureProgramAttached(ap *tc.AttachPoint, polDirect
 		if err != nil {
 			return 0, fmt.Errorf("failed to look up jump map: %w", err)
 		}
-		m.setJumpMapFD(ap.Iface, polDirection, jumpMapFD)
+		m.setJumpMapFD(ap.Iface, polDirection, ap.IPv6, jumpMapFD)
 	}
 
 	return jumpMapFD, nil
 }
 
-func (m *bpfEndpointManager) getJumpMapFD(ifaceName string, direction PolDirection) (fd bpf.MapFD) {
+func (m *bpfEndpointManager) getJumpMapFD(ifaceName string, direction PolDirection, ipv6 bool) (fd bpf.MapFD) {
 	m.ifacesLock.Lock()
 	defer m.ifacesLock.Unlock()
 	m.withIface(ifaceName, func(iface *bpfInterface) bool {
-		fd = iface.dpState.jumpMapFDs[direction]
+		if ipv6 {
+			fd = iface.dpState.jumpMapV6FDs[direction]
+		} else {
+			fd = iface.dpState.jumpMapFDs[direction]
+		}
 		return false
 	})
 	return
 }
 
-func (m *bpfEndpointManager) setJumpMapFD(name string, direction PolDirection, fd bpf.MapFD) {
+func (m *bpfEndpointManager) setJumpMapFD(name string, direction PolDirection, ipv6 bool, fd bpf.MapFD) {
 	m.ifacesLock.Lock()
 	defer m.ifacesLock.Unlock()
 
 	m.withIface(name, func(iface *bpfInterface) bool {
-		iface.dpState.jumpMapFDs[direction] = fd
+		if ipv6 {
+			iface.dpState.jumpMapV6FDs[direction] = fd
+		} else {
+			iface.dpState.jumpMapFDs[direction] = fd
+		}
 		return false
 	})
 }
 
-func (m *bpfEndpointManager) updatePolicyProgram(jumpMapFD bpf.MapFD, rules polprog.Rules) error {
-	pg := polprog.NewBuilder(m.ipSetIDAlloc, m.ipSetMap.MapFD(), m.stateMap.MapFD(), jumpMapFD)
+func (m *bpfEndpointManager) updatePolicyProgram(jumpMapFD bpf.MapFD, rules polprog.Rules, ipv6 bool) error {
+	var opts []polprog.Option
+	if m.ruleCountersMap != nil {
+		opts = append(opts, polprog.WithRuleCounters(m.ruleCountersMap.MapFD()))
+	}
+	var pg *polprog.Builder
+	if ipv6 {
+		opts = append(opts, polprog.WithIPv6())
+		pg = polprog.NewBuilder(m.ipSetIDAllocV6, m.ipSetMapV6.MapFD(), m.stateMapV6.MapFD(), jumpMapFD, m.auditMap.MapFD(), opts...)
+	} else {
+		pg = polprog.NewBuilder(m.ipSetIDAlloc, m.ipSetMap.MapFD(), m.stateMap.MapFD(), jumpMapFD, m.auditMap.MapFD(), opts...)
+	}
 	insns, err := pg.Instructions(rules)
 	if err != nil {
 		return fmt.Errorf("failed to generate policy bytecode: %w", err)
@@ -1345,6 +1433,10 @@ func FindJumpMap(ap *tc.AttachPoint) (mapFD bpf.MapFD, err error) {
 
 	progName := ap.ProgramName()
 	for _, line := range strings.Split(out, "\n") {
+		if !ap.IPv6 && strings.Contains(line, progName+"_v6") {
+			// The IPv6 program's name has the IPv4 program's name as a prefix.
+			continue
+		}
 		if strings.Contains(line, progName) {
 			re := regexp.MustCompile(`id (\d+)`)
 			m := re.FindStringSubmatch(line)
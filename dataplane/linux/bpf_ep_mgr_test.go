@@ -25,6 +25,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/projectcalico/felix/bpf"
+	"github.com/projectcalico/felix/bpf/audit"
 	bpfipsets "github.com/projectcalico/felix/bpf/ipsets"
 	"github.com/projectcalico/felix/bpf/polprog"
 	"github.com/projectcalico/felix/bpf/state"
@@ -59,6 +60,9 @@ func (m *mockDataplane) ensureProgramAttached(ap *tc.AttachPoint, polDirection P
 	defer m.mutex.Unlock()
 	suffixes := []string{"-I", "-E"}
 	key := ap.Iface + suffixes[int(polDirection)]
+	if ap.IPv6 {
+		key += "-6"
+	}
 	if fd, exists := m.fds[key]; exists {
 		return bpf.MapFD(fd), nil
 	}
@@ -71,7 +75,7 @@ func (m *mockDataplane) ensureQdisc(iface string) error {
 	return nil
 }
 
-func (m *mockDataplane) updatePolicyProgram(jumpMapFD bpf.MapFD, rules polprog.Rules) error {
+func (m *mockDataplane) updatePolicyProgram(jumpMapFD bpf.MapFD, rules polprog.Rules, ipv6 bool) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	m.state[uint32(jumpMapFD)] = rules
@@ -124,9 +128,14 @@ var _ = Describe("BPF Endpoint Manager", func() {
 		bpfMapContext        *bpf.MapContext
 		ipSetsMap            bpf.Map
 		stateMap             bpf.Map
+		auditMap             bpf.Map
 		rrConfigNormal       rules.Config
 		ruleRenderer         rules.RuleRenderer
 		filterTableV4        iptablesTable
+		ipSetIDAllocatorV6   *idalloc.IDAllocator
+		ipSetsMapV6          bpf.Map
+		stateMapV6           bpf.Map
+		filterTableV6        iptablesTable
 	)
 
 	BeforeEach(func() {
@@ -142,6 +151,7 @@ var _ = Describe("BPF Endpoint Manager", func() {
 		}
 		ipSetsMap = bpfipsets.Map(bpfMapContext)
 		stateMap = state.Map(bpfMapContext)
+		auditMap = audit.Map(bpfMapContext)
 		rrConfigNormal = rules.Config{
 			IPIPEnabled:                 true,
 			IPIPTunnelAddress:           nil,
@@ -160,6 +170,10 @@ var _ = Describe("BPF Endpoint Manager", func() {
 		}
 		ruleRenderer = rules.NewRenderer(rrConfigNormal)
 		filterTableV4 = newMockTable("filter")
+		ipSetIDAllocatorV6 = nil
+		ipSetsMapV6 = nil
+		stateMapV6 = nil
+		filterTableV6 = nil
 	})
 
 	JustBeforeEach(func() {
@@ -178,11 +192,17 @@ var _ = Describe("BPF Endpoint Manager", func() {
 			0,
 			ipSetsMap,
 			stateMap,
+			auditMap,
+			nil,
+			ipSetIDAllocatorV6,
+			ipSetsMapV6,
+			stateMapV6,
 			ruleRenderer,
 			filterTableV4,
 			nil,
 		)
 		bpfEpMgr.dp = dp
+		bpfEpMgr.iptablesFilterTableV6 = filterTableV6
 	})
 
 	It("exists", func() {
@@ -320,6 +340,34 @@ var _ = Describe("BPF Endpoint Manager", func() {
 				Expect(caliE.SuppressNormalHostPolicy).To(BeFalse())
 			})
 		})
+
+		Context("with IPv6 enabled", func() {
+			BeforeEach(func() {
+				ipSetIDAllocatorV6 = idalloc.New()
+				ipSetsMapV6 = bpfipsets.MapV6(bpfMapContext)
+				stateMapV6 = state.MapV6(bpfMapContext)
+				filterTableV6 = newMockTable("filter")
+			})
+
+			It("attaches IPv6 programs with the same policy as the IPv4 programs", func() {
+				for _, key := range []string{"eth0-I", "eth0-E", "cali12345-I", "cali12345-E"} {
+					var v4Rules, v6Rules *polprog.Rules
+					Eventually(dp.setAndReturn(&v4Rules, key)).ShouldNot(BeNil())
+					Eventually(dp.setAndReturn(&v6Rules, key+"-6")).ShouldNot(BeNil())
+					Expect(*v6Rules).To(Equal(*v4Rules), "IPv6 rules differ for "+key)
+				}
+			})
+
+			It("updates the IPv6 workload allow chains", func() {
+				Expect(filterTableV6.(*mockTable).UpdateCalled).To(BeTrue())
+			})
+		})
+
+		It("does not attach IPv6 programs by default", func() {
+			Expect(dp.getRules("eth0-I")).NotTo(BeNil())
+			Expect(dp.getRules("eth0-I-6")).To(BeNil())
+			Expect(dp.getRules("cali12345-I-6")).To(BeNil())
+		})
 	})
 
 	Context("with eth0 up", func() {
@@ -0,0 +1,442 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/set"
+
+	"github.com/projectcalico/felix/bpf"
+	"github.com/projectcalico/felix/bpf/routes"
+	"github.com/projectcalico/felix/ifacemonitor"
+	"github.com/projectcalico/felix/ip"
+	"github.com/projectcalico/felix/proto"
+)
+
+// bpfRouteManagerV6 is the IPv6 equivalent of bpfRouteManager; it maintains the IPv6 BPF routes
+// map.  Since the IPv6 kube-proxy doesn't handle NodePorts, there are no callbacks to tell it
+// about the host IPs and routes.
+type bpfRouteManagerV6 struct {
+	resyncScheduled bool
+	routeMap        bpf.Map
+
+	// Cache of the input data, see bpfRouteManager for details.
+	cidrToRoute       map[ip.V6CIDR]proto.RouteUpdate
+	cidrToLocalIfaces map[ip.V6CIDR]set.Set
+	localIfaceToCIDRs map[string]set.Set
+	cidrToWEPIDs      map[ip.V6CIDR]set.Set
+	wepIDToWorkload   map[proto.WorkloadEndpointID]*proto.WorkloadEndpoint
+	ifaceNameToIdx    map[string]int
+	ifaceNameToWEPIDs map[string]set.Set
+	externalNodeCIDRs set.Set
+	dirtyCIDRs        set.Set
+
+	desiredRoutes map[routes.KeyV6]routes.ValueV6
+	dirtyRoutes   set.Set
+}
+
+func newBPFRouteManagerV6(externalCIDRs []string, mc *bpf.MapContext) *bpfRouteManagerV6 {
+	extCIDRs := set.New()
+	dirtyCIDRs := set.New()
+	for _, cidrStr := range externalCIDRs {
+		if !strings.Contains(cidrStr, ":") {
+			continue
+		}
+		cidr, err := ip.ParseCIDROrIP(cidrStr)
+		if err != nil {
+			log.WithError(err).WithField("cidr", cidrStr).Error(
+				"Failed to parse external node CIDR (which should have been validated already).")
+			continue
+		}
+		extCIDRs.Add(cidr)
+		dirtyCIDRs.Add(cidr)
+	}
+
+	return &bpfRouteManagerV6{
+		cidrToRoute:       map[ip.V6CIDR]proto.RouteUpdate{},
+		cidrToLocalIfaces: map[ip.V6CIDR]set.Set{},
+		localIfaceToCIDRs: map[string]set.Set{},
+		cidrToWEPIDs:      map[ip.V6CIDR]set.Set{},
+		wepIDToWorkload:   map[proto.WorkloadEndpointID]*proto.WorkloadEndpoint{},
+		ifaceNameToIdx:    map[string]int{},
+		ifaceNameToWEPIDs: map[string]set.Set{},
+		externalNodeCIDRs: extCIDRs,
+		dirtyCIDRs:        dirtyCIDRs,
+
+		desiredRoutes: map[routes.KeyV6]routes.ValueV6{},
+		routeMap:      routes.MapV6(mc),
+
+		dirtyRoutes:     set.New(),
+		resyncScheduled: true,
+	}
+}
+
+func (m *bpfRouteManagerV6) OnUpdate(msg interface{}) {
+	switch msg := msg.(type) {
+	case *ifaceUpdate:
+		m.onIfaceUpdate(msg)
+	case *ifaceAddrsUpdate:
+		m.onIfaceAddrsUpdate(msg)
+	case *proto.RouteUpdate:
+		m.onRouteUpdate(msg)
+	case *proto.RouteRemove:
+		m.onRouteRemove(msg)
+	case *proto.WorkloadEndpointUpdate:
+		m.removeWEP(msg.Id)
+		m.addWEP(msg)
+	case *proto.WorkloadEndpointRemove:
+		m.removeWEP(msg.Id)
+	}
+}
+
+func (m *bpfRouteManagerV6) CompleteDeferredWork() error {
+	err := m.routeMap.EnsureExists()
+	if err != nil {
+		log.WithError(err).Panic("Failed to create IPv6 route map")
+	}
+
+	startTime := time.Now()
+	m.recalculateRoutesForDirtyCIDRs()
+	if m.resyncScheduled {
+		m.resyncWithDataplane()
+		m.resyncScheduled = false
+	}
+	numDels, numAdds := m.applyUpdates()
+
+	if numDels > 0 || numAdds > 0 {
+		log.WithFields(log.Fields{
+			"timeTaken": time.Since(startTime),
+			"numAdds":   numAdds,
+			"numDels":   numDels,
+		}).Info("Completed updates to BPF IPv6 routes.")
+	}
+	return nil
+}
+
+func (m *bpfRouteManagerV6) recalculateRoutesForDirtyCIDRs() {
+	m.dirtyCIDRs.Iter(func(item interface{}) error {
+		cidr := item.(ip.V6CIDR)
+
+		dataplaneKey := routes.NewKeyV6(cidr)
+		newValue := m.calculateRoute(cidr)
+
+		oldValue, exists := m.desiredRoutes[dataplaneKey]
+		if newValue != nil {
+			if exists && oldValue == *newValue {
+				return set.RemoveItem
+			}
+			m.desiredRoutes[dataplaneKey] = *newValue
+		} else {
+			if !exists {
+				return set.RemoveItem
+			}
+			delete(m.desiredRoutes, dataplaneKey)
+		}
+		m.dirtyRoutes.Add(dataplaneKey)
+		return set.RemoveItem
+	})
+}
+
+// calculateRoute mirrors bpfRouteManager.calculateRoute.
+func (m *bpfRouteManagerV6) calculateRoute(cidr ip.V6CIDR) *routes.ValueV6 {
+	var flags routes.Flags
+
+	if _, ok := m.cidrToLocalIfaces[cidr]; ok {
+		flags |= routes.FlagsLocalHost
+	}
+	if m.externalNodeCIDRs.Contains(cidr) {
+		flags |= routes.FlagHost
+	}
+
+	cgRoute, cgRouteExists := m.cidrToRoute[cidr]
+	if cgRouteExists {
+		if cgRoute.SameSubnet {
+			flags |= routes.FlagSameSubnet
+		}
+		if cgRoute.IpPoolType != proto.IPPoolType_NONE {
+			flags |= routes.FlagInIPAMPool
+		}
+		if cgRoute.NatOutgoing {
+			flags |= routes.FlagNATOutgoing
+		}
+	}
+
+	var route *routes.ValueV6
+
+	switch cgRoute.Type {
+	case proto.RouteType_LOCAL_WORKLOAD:
+		if !cgRoute.LocalWorkload {
+			// Just the local IPAM block, not an actual workload.
+			return nil
+		}
+		if wepIDs, ok := m.cidrToWEPIDs[cidr]; ok {
+			bestWepScore := -1
+			var bestWepID proto.WorkloadEndpointID
+			wepIDs.Iter(func(item interface{}) error {
+				wepScore := 0
+				wepID := item.(proto.WorkloadEndpointID)
+				wep := m.wepIDToWorkload[wepID]
+				ifaceIdx, ok := m.ifaceNameToIdx[wep.Name]
+				if ok {
+					wepScore++
+				}
+				if wepScore > bestWepScore || wepScore == bestWepScore && wepID.String() > bestWepID.String() {
+					routeVal := routes.NewValueV6WithIfIndex(flags|routes.FlagsLocalWorkload, ifaceIdx)
+					route = &routeVal
+					bestWepID = wepID
+					bestWepScore = wepScore
+				}
+				return nil
+			})
+		}
+	case proto.RouteType_REMOTE_WORKLOAD, proto.RouteType_REMOTE_HOST:
+		if cgRoute.Type == proto.RouteType_REMOTE_WORKLOAD {
+			flags |= routes.FlagsRemoteWorkload
+		} else {
+			flags |= routes.FlagsRemoteHost
+		}
+		nodeIP, ok := ip.FromNetIP(net.ParseIP(cgRoute.DstNodeIp)).(ip.V6Addr)
+		if !ok {
+			// The node's IPv6 address isn't known (it may be an IPv4-only node); the IPv6 programs
+			// don't encap so they only need the flags.
+			routeVal := routes.NewValueV6(flags)
+			return &routeVal
+		}
+		routeVal := routes.NewValueV6WithNextHop(flags, nodeIP)
+		route = &routeVal
+	case proto.RouteType_LOCAL_HOST:
+		flags |= routes.FlagsLocalHost
+		fallthrough
+	default:
+		if flags != 0 {
+			routeVal := routes.NewValueV6(flags)
+			route = &routeVal
+		}
+	}
+
+	return route
+}
+
+func (m *bpfRouteManagerV6) applyUpdates() (numDels uint, numAdds uint) {
+	m.dirtyRoutes.Iter(func(item interface{}) error {
+		key := item.(routes.KeyV6)
+		value, present := m.desiredRoutes[key]
+		if !present {
+			numDels++
+			err := m.routeMap.Delete(key[:])
+			if err != nil {
+				log.WithFields(log.Fields{"key": key}).Error("Failed to delete from BPF map")
+				m.resyncScheduled = true
+				return nil
+			}
+			return set.RemoveItem
+		}
+
+		numAdds++
+		err := m.routeMap.Update(key[:], value[:])
+		if err != nil {
+			log.WithFields(log.Fields{"key": key}).Error("Failed to update BPF map")
+			m.resyncScheduled = true
+			return nil
+		}
+		return set.RemoveItem
+	})
+	return
+}
+
+func (m *bpfRouteManagerV6) resyncWithDataplane() {
+	log.Info("Doing full resync of BPF IPv6 routes map")
+
+	m.dirtyRoutes.Clear()
+	for k := range m.desiredRoutes {
+		m.dirtyRoutes.Add(k)
+	}
+
+	err := m.routeMap.Iter(func(k, v []byte) bpf.IteratorAction {
+		var key routes.KeyV6
+		var value routes.ValueV6
+		copy(key[:], k)
+		copy(value[:], v)
+
+		if desired, ok := m.desiredRoutes[key]; ok && desired == value {
+			m.dirtyRoutes.Discard(key)
+		} else if !ok {
+			m.dirtyRoutes.Add(key)
+		}
+		return bpf.IterNone
+	})
+	if err != nil {
+		log.WithError(err).Panic("Failed to scan BPF map.")
+	}
+}
+
+func (m *bpfRouteManagerV6) onIfaceUpdate(msg *ifaceUpdate) {
+	if msg.State == ifacemonitor.StateUp {
+		oldIdx, ok := m.ifaceNameToIdx[msg.Name]
+		if !ok || oldIdx != msg.Index {
+			m.ifaceNameToIdx[msg.Name] = msg.Index
+			m.onIfaceIdxChanged(msg.Name)
+		}
+	} else if _, ok := m.ifaceNameToIdx[msg.Name]; ok {
+		delete(m.ifaceNameToIdx, msg.Name)
+		m.onIfaceIdxChanged(msg.Name)
+	}
+}
+
+func (m *bpfRouteManagerV6) onIfaceIdxChanged(name string) {
+	wepIDs := m.ifaceNameToWEPIDs[name]
+	if wepIDs == nil {
+		return
+	}
+	wepIDs.Iter(func(item interface{}) error {
+		wep := m.wepIDToWorkload[item.(proto.WorkloadEndpointID)]
+		m.markCIDRsDirty(getV6WorkloadCIDRs(wep)...)
+		return nil
+	})
+}
+
+func (m *bpfRouteManagerV6) onIfaceAddrsUpdate(update *ifaceAddrsUpdate) {
+	newCIDRs := set.New()
+	if update.Addrs != nil {
+		update.Addrs.Iter(func(item interface{}) error {
+			cidr := ip.MustParseCIDROrIP(item.(string))
+			if v6CIDR, ok := cidr.(ip.V6CIDR); ok && cidr.Addr().AsNetIP().IsGlobalUnicast() {
+				newCIDRs.Add(v6CIDR)
+			}
+			return nil
+		})
+	}
+
+	cidrs := m.localIfaceToCIDRs[update.Name]
+	if cidrs != nil {
+		cidrs.Iter(func(item interface{}) error {
+			cidr := item.(ip.V6CIDR)
+			if newCIDRs.Contains(cidr) {
+				newCIDRs.Discard(cidr)
+				return nil
+			}
+			m.cidrToLocalIfaces[cidr].Discard(update.Name)
+			if m.cidrToLocalIfaces[cidr].Len() == 0 {
+				delete(m.cidrToLocalIfaces, cidr)
+			}
+			m.markCIDRsDirty(cidr)
+			return set.RemoveItem
+		})
+	}
+
+	newCIDRs.Iter(func(item interface{}) error {
+		cidr := item.(ip.V6CIDR)
+		ifaceNames := m.cidrToLocalIfaces[cidr]
+		if ifaceNames == nil {
+			ifaceNames = set.New()
+			m.cidrToLocalIfaces[cidr] = ifaceNames
+		}
+		ifaceNames.Add(update.Name)
+		if cidrs == nil {
+			cidrs = set.New()
+			m.localIfaceToCIDRs[update.Name] = cidrs
+		}
+		m.markCIDRsDirty(cidr)
+		cidrs.Add(cidr)
+		return nil
+	})
+}
+
+func (m *bpfRouteManagerV6) onRouteUpdate(update *proto.RouteUpdate) {
+	v6CIDR, ok := ip.MustParseCIDROrIP(update.Dst).(ip.V6CIDR)
+	if !ok {
+		return
+	}
+	if update.Type == proto.RouteType_REMOTE_TUNNEL || update.Type == proto.RouteType_LOCAL_TUNNEL {
+		m.onRouteRemove(&proto.RouteRemove{Dst: update.Dst})
+		return
+	}
+	if reflect.DeepEqual(m.cidrToRoute[v6CIDR], *update) {
+		return
+	}
+	m.cidrToRoute[v6CIDR] = *update
+	m.dirtyCIDRs.Add(v6CIDR)
+}
+
+func (m *bpfRouteManagerV6) onRouteRemove(update *proto.RouteRemove) {
+	v6CIDR, ok := ip.MustParseCIDROrIP(update.Dst).(ip.V6CIDR)
+	if !ok {
+		return
+	}
+	if _, ok := m.cidrToRoute[v6CIDR]; ok {
+		delete(m.cidrToRoute, v6CIDR)
+		m.dirtyCIDRs.Add(v6CIDR)
+	}
+}
+
+func (m *bpfRouteManagerV6) addWEP(update *proto.WorkloadEndpointUpdate) {
+	m.wepIDToWorkload[*update.Id] = update.Endpoint
+	newCIDRs := getV6WorkloadCIDRs(update.Endpoint)
+	for _, cidr := range newCIDRs {
+		wepIDs := m.cidrToWEPIDs[cidr]
+		if wepIDs == nil {
+			wepIDs = set.New()
+			m.cidrToWEPIDs[cidr] = wepIDs
+		}
+		wepIDs.Add(*update.Id)
+	}
+	m.markCIDRsDirty(newCIDRs...)
+	wepIDs := m.ifaceNameToWEPIDs[update.Endpoint.Name]
+	if wepIDs == nil {
+		wepIDs = set.New()
+		m.ifaceNameToWEPIDs[update.Endpoint.Name] = wepIDs
+	}
+	wepIDs.Add(*update.Id)
+}
+
+func (m *bpfRouteManagerV6) removeWEP(id *proto.WorkloadEndpointID) {
+	oldWEP := m.wepIDToWorkload[*id]
+	if oldWEP == nil {
+		return
+	}
+	delete(m.wepIDToWorkload, *id)
+	oldCIDRs := getV6WorkloadCIDRs(oldWEP)
+	for _, cidr := range oldCIDRs {
+		m.cidrToWEPIDs[cidr].Discard(*id)
+		if m.cidrToWEPIDs[cidr].Len() == 0 {
+			delete(m.cidrToWEPIDs, cidr)
+		}
+	}
+	m.markCIDRsDirty(oldCIDRs...)
+	m.ifaceNameToWEPIDs[oldWEP.Name].Discard(*id)
+	if m.ifaceNameToWEPIDs[oldWEP.Name].Len() == 0 {
+		delete(m.ifaceNameToWEPIDs, oldWEP.Name)
+	}
+}
+
+func getV6WorkloadCIDRs(wep *proto.WorkloadEndpoint) (cidrs []ip.V6CIDR) {
+	if wep == nil {
+		return
+	}
+	for _, addr := range wep.Ipv6Nets {
+		cidrs = append(cidrs, ip.MustParseCIDROrIP(addr).(ip.V6CIDR))
+	}
+	return
+}
+
+func (m *bpfRouteManagerV6) markCIDRsDirty(cidrs ...ip.V6CIDR) {
+	m.dirtyCIDRs.AddAll(cidrs)
+}
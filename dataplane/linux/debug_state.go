@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/projectcalico/felix/drift"
+	"github.com/projectcalico/felix/dryrun"
+)
+
+// DebugState is the dataplane state that we expose via the debug server.
+type DebugState struct {
+	// InSync is true if the most recent apply succeeded.
+	InSync    bool                 `json:"inSync"`
+	LastApply time.Time            `json:"lastApply"`
+	Managers  []ManagerApplyStatus `json:"managers"`
+
+	// DryRunChanges lists the most recent changes that weren't made because the dataplane is in
+	// dry-run mode.  NumDryRunChangesDropped counts the older changes that have been discarded.
+	DryRunChanges           []dryrun.Change `json:"dryRunChanges,omitempty"`
+	NumDryRunChangesDropped int             `json:"numDryRunChangesDropped,omitempty"`
+
+	// DriftEvents lists the most recent out-of-sync objects that resyncs have found and fixed.
+	// NumDriftEventsDropped counts the older events that have been discarded.
+	DriftEvents           []drift.Event `json:"driftEvents,omitempty"`
+	NumDriftEventsDropped int           `json:"numDriftEventsDropped,omitempty"`
+}
+
+// ManagerApplyStatus records when a manager last tried to, and last succeeded in, completing its
+// deferred work.
+type ManagerApplyStatus struct {
+	Manager     string    `json:"manager"`
+	LastAttempt time.Time `json:"lastAttempt"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// applyTracker is written by the main dataplane goroutine and read by the debug server so it
+// has its own lock.
+type applyTracker struct {
+	lock      sync.Mutex
+	inSync    bool
+	lastApply time.Time
+	managers  []ManagerApplyStatus
+}
+
+func (t *applyTracker) registerManager(mgr Manager) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.managers = append(t.managers, ManagerApplyStatus{Manager: fmt.Sprintf("%T", mgr)})
+}
+
+// recordManagerApply records the result of calling CompleteDeferredWork on the idx'th registered
+// manager.
+func (t *applyTracker) recordManagerApply(idx int, startTime time.Time, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	status := &t.managers[idx]
+	status.LastAttempt = startTime
+	if err != nil {
+		status.LastError = err.Error()
+	} else {
+		status.LastSuccess = startTime
+		status.LastError = ""
+	}
+}
+
+func (t *applyTracker) recordApply(startTime time.Time, inSync bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.lastApply = startTime
+	t.inSync = inSync
+}
+
+// DebugState returns a snapshot of the dataplane's apply status.  Safe to call from any goroutine.
+func (d *InternalDataplane) DebugState() interface{} {
+	d.applyTracker.lock.Lock()
+	defer d.applyTracker.lock.Unlock()
+	managers := make([]ManagerApplyStatus, len(d.applyTracker.managers))
+	copy(managers, d.applyTracker.managers)
+	state := &DebugState{
+		InSync:    d.applyTracker.inSync,
+		LastApply: d.applyTracker.lastApply,
+		Managers:  managers,
+	}
+	if d.config.DryRun != nil {
+		state.DryRunChanges, state.NumDryRunChangesDropped = d.config.DryRun.Changes()
+	}
+	state.DriftEvents, state.NumDriftEventsDropped = d.config.DriftReporter.Events()
+	return state
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"github.com/projectcalico/felix/denylog"
+)
+
+// deniedPacketLogManager passes the active policies, profiles and local endpoints to the
+// denied-packet logger, which uses them to name the policy and endpoints in each record.  Like
+// the flow logs manager, it doesn't program anything itself.
+type deniedPacketLogManager struct {
+	logger *denylog.Logger
+}
+
+func newDeniedPacketLogManager(logger *denylog.Logger) *deniedPacketLogManager {
+	return &deniedPacketLogManager{
+		logger: logger,
+	}
+}
+
+func (m *deniedPacketLogManager) OnUpdate(msg interface{}) {
+	m.logger.OnUpdate(msg)
+}
+
+func (m *deniedPacketLogManager) CompleteDeferredWork() error {
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/dnssnoop"
+	"github.com/projectcalico/felix/proto"
+	"github.com/projectcalico/libcalico-go/lib/set"
+)
+
+// domainIPSetsManager populates the IP sets that back domain-based rules.  The calculation graph
+// sends an IPSetUpdate that lists the set's domains but no members; the ipsets managers create
+// the empty set and this manager then fills it in with the addresses that snooped DNS responses
+// resolved those domains to.  It sends the same IPSetDeltaUpdates to the ipsets managers as the
+// calculation graph would, so the rest of the dataplane sees a normal IP set.
+//
+// Addresses expire when the DNS response's TTL (plus the configured grace period) runs out.
+type domainIPSetsManager struct {
+	ipSetsMgrs []Manager
+	extraTTL   time.Duration
+
+	// domainsBySetID maps from IP set ID to the domains whose addresses belong in the set.
+	domainsBySetID map[string][]string
+	// membersBySetID maps from IP set ID to the members that we've sent for that set.
+	membersBySetID map[string]set.Set
+	// expiryByName maps from domain name to address to the time at which we should forget
+	// the mapping.
+	expiryByName map[string]map[string]time.Time
+}
+
+func newDomainIPSetsManager(ipSetsMgrs []Manager, extraTTL time.Duration) *domainIPSetsManager {
+	return &domainIPSetsManager{
+		ipSetsMgrs:     ipSetsMgrs,
+		extraTTL:       extraTTL,
+		domainsBySetID: map[string][]string{},
+		membersBySetID: map[string]set.Set{},
+		expiryByName:   map[string]map[string]time.Time{},
+	}
+}
+
+func (m *domainIPSetsManager) OnUpdate(msg interface{}) {
+	switch msg := msg.(type) {
+	case *proto.IPSetUpdate:
+		if len(msg.Domains) == 0 {
+			return
+		}
+		log.WithFields(log.Fields{
+			"ipSetId": msg.Id,
+			"domains": msg.Domains,
+		}).Debug("Domain IP set update")
+		m.domainsBySetID[msg.Id] = msg.Domains
+		// The ipsets managers have just replaced the set with an empty one.
+		m.membersBySetID[msg.Id] = set.New()
+		m.updateSetMembers(msg.Id)
+	case *proto.IPSetRemove:
+		delete(m.domainsBySetID, msg.Id)
+		delete(m.membersBySetID, msg.Id)
+	}
+}
+
+func (m *domainIPSetsManager) CompleteDeferredWork() error {
+	// Nothing to do, we send our updates to the ipsets managers as soon as we calculate them.
+	return nil
+}
+
+// OnAddrRecords records the addresses from snooped DNS responses and updates any IP sets that
+// they belong in.  It returns true if any IP set changed.
+func (m *domainIPSetsManager) OnAddrRecords(records []dnssnoop.AddrRecord, now time.Time) bool {
+	changedNames := set.New()
+	for _, r := range records {
+		addrs := m.expiryByName[r.Name]
+		if addrs == nil {
+			addrs = map[string]time.Time{}
+			m.expiryByName[r.Name] = addrs
+		}
+		expiry := now.Add(r.TTL + m.extraTTL)
+		oldExpiry, known := addrs[r.Addr]
+		if expiry.After(oldExpiry) {
+			addrs[r.Addr] = expiry
+		}
+		if !known {
+			changedNames.Add(r.Name)
+		}
+	}
+	return m.updateSetsForNames(changedNames)
+}
+
+// ExpireAddrs removes any addresses whose TTL has run out.  It returns true if any IP set
+// changed.
+func (m *domainIPSetsManager) ExpireAddrs(now time.Time) bool {
+	changedNames := set.New()
+	for name, addrs := range m.expiryByName {
+		for addr, expiry := range addrs {
+			if expiry.After(now) {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"name": name,
+				"addr": addr,
+			}).Debug("DNS record expired")
+			delete(addrs, addr)
+			changedNames.Add(name)
+		}
+		if len(addrs) == 0 {
+			delete(m.expiryByName, name)
+		}
+	}
+	return m.updateSetsForNames(changedNames)
+}
+
+func (m *domainIPSetsManager) updateSetsForNames(names set.Set) bool {
+	if names.Len() == 0 {
+		return false
+	}
+	changed := false
+	for setID, domains := range m.domainsBySetID {
+		affected := false
+		names.Iter(func(item interface{}) error {
+			if anyDomainMatches(domains, item.(string)) {
+				affected = true
+				return set.StopIteration
+			}
+			return nil
+		})
+		if affected && m.updateSetMembers(setID) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// updateSetMembers recalculates the members of the given IP set and sends any changes to the
+// ipsets managers.  It returns true if the members changed.
+func (m *domainIPSetsManager) updateSetMembers(setID string) bool {
+	domains := m.domainsBySetID[setID]
+	newMembers := set.New()
+	for name, addrs := range m.expiryByName {
+		if !anyDomainMatches(domains, name) {
+			continue
+		}
+		for addr := range addrs {
+			newMembers.Add(addr)
+		}
+	}
+
+	oldMembers := m.membersBySetID[setID]
+	var added, removed []string
+	newMembers.Iter(func(item interface{}) error {
+		if !oldMembers.Contains(item) {
+			added = append(added, item.(string))
+		}
+		return nil
+	})
+	oldMembers.Iter(func(item interface{}) error {
+		if !newMembers.Contains(item) {
+			removed = append(removed, item.(string))
+		}
+		return nil
+	})
+	m.membersBySetID[setID] = newMembers
+	if len(added) == 0 && len(removed) == 0 {
+		return false
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	log.WithFields(log.Fields{
+		"ipSetId": setID,
+		"added":   added,
+		"removed": removed,
+	}).Debug("Domain IP set members changed")
+	// The ipsets layer filters out the members that don't match each manager's IP family.
+	update := &proto.IPSetDeltaUpdate{
+		Id:             setID,
+		AddedMembers:   added,
+		RemovedMembers: removed,
+	}
+	for _, mgr := range m.ipSetsMgrs {
+		mgr.OnUpdate(update)
+	}
+	return true
+}
+
+// anyDomainMatches returns true if the name matches one of the domains.  A domain of the form
+// "*.example.com" matches any subdomain of example.com, but not example.com itself.
+func anyDomainMatches(domains []string, name string) bool {
+	for _, d := range domains {
+		if strings.HasPrefix(d, "*.") {
+			if strings.HasSuffix(name, d[1:]) {
+				return true
+			}
+		} else if d == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/dnssnoop"
+	"github.com/projectcalico/felix/proto"
+	"github.com/projectcalico/libcalico-go/lib/set"
+)
+
+var _ = Describe("Domain IP sets manager", func() {
+	var (
+		domainMgr *domainIPSetsManager
+		ipsetsMgr *ipSetsManager
+		ipSets    *mockIPSets
+		now       time.Time
+	)
+
+	// sendUpdate mimics the main loop, which sends each update to all the managers in turn.
+	sendUpdate := func(msg interface{}) {
+		ipsetsMgr.OnUpdate(msg)
+		domainMgr.OnUpdate(msg)
+	}
+
+	BeforeEach(func() {
+		ipSets = newMockIPSets()
+		ipsetsMgr = newIPSetsManager(ipSets, 1024, newCallbacks())
+		domainMgr = newDomainIPSetsManager([]Manager{ipsetsMgr}, 10*time.Second)
+		now = time.Now()
+
+		sendUpdate(&proto.IPSetUpdate{
+			Id:      "d:exact",
+			Type:    proto.IPSetUpdate_IP,
+			Domains: []string{"www.example.com"},
+		})
+		sendUpdate(&proto.IPSetUpdate{
+			Id:      "d:wild",
+			Type:    proto.IPSetUpdate_IP,
+			Domains: []string{"*.example.com"},
+		})
+	})
+
+	It("should create empty sets", func() {
+		Expect(ipSets.Members["d:exact"]).To(Equal(set.New()))
+		Expect(ipSets.Members["d:wild"]).To(Equal(set.New()))
+	})
+
+	It("should ignore IP sets that don't have domains", func() {
+		sendUpdate(&proto.IPSetUpdate{
+			Id:      "s:sel",
+			Members: []string{"10.0.0.1"},
+		})
+		domainMgr.OnAddrRecords([]dnssnoop.AddrRecord{
+			{Name: "www.example.com", Addr: "10.0.0.9", TTL: time.Minute},
+		}, now)
+		Expect(ipSets.Members["s:sel"]).To(Equal(set.From("10.0.0.1")))
+	})
+
+	Describe("after receiving DNS records", func() {
+		var changed bool
+
+		BeforeEach(func() {
+			changed = domainMgr.OnAddrRecords([]dnssnoop.AddrRecord{
+				{Name: "www.example.com", Addr: "10.0.0.1", TTL: time.Minute},
+				{Name: "www.example.com", Addr: "fd00::1", TTL: 2 * time.Minute},
+				{Name: "api.example.com", Addr: "10.0.0.2", TTL: time.Minute},
+				{Name: "example.com", Addr: "10.0.0.3", TTL: time.Minute},
+				{Name: "www.example.org", Addr: "10.0.0.4", TTL: time.Minute},
+			}, now)
+		})
+
+		It("should populate the matching sets", func() {
+			Expect(changed).To(BeTrue())
+			Expect(ipSets.Members["d:exact"]).To(Equal(set.From("10.0.0.1", "fd00::1")))
+			Expect(ipSets.Members["d:wild"]).To(Equal(set.From("10.0.0.1", "fd00::1", "10.0.0.2")))
+		})
+
+		It("should report no change for a repeated response", func() {
+			Expect(domainMgr.OnAddrRecords([]dnssnoop.AddrRecord{
+				{Name: "www.example.com", Addr: "10.0.0.1", TTL: time.Minute},
+			}, now)).To(BeFalse())
+		})
+
+		It("should fill in a set that is created later", func() {
+			sendUpdate(&proto.IPSetUpdate{
+				Id:      "d:api",
+				Type:    proto.IPSetUpdate_IP,
+				Domains: []string{"api.example.com"},
+			})
+			Expect(ipSets.Members["d:api"]).To(Equal(set.From("10.0.0.2")))
+		})
+
+		It("should keep records until the TTL plus the extra TTL runs out", func() {
+			Expect(domainMgr.ExpireAddrs(now.Add(time.Minute))).To(BeFalse())
+			Expect(domainMgr.ExpireAddrs(now.Add(time.Minute + 10*time.Second))).To(BeTrue())
+			Expect(ipSets.Members["d:exact"]).To(Equal(set.From("fd00::1")))
+			Expect(ipSets.Members["d:wild"]).To(Equal(set.From("fd00::1")))
+		})
+
+		It("should extend the expiry when a record is refreshed", func() {
+			domainMgr.OnAddrRecords([]dnssnoop.AddrRecord{
+				{Name: "www.example.com", Addr: "10.0.0.1", TTL: time.Minute},
+			}, now.Add(time.Minute))
+			domainMgr.ExpireAddrs(now.Add(time.Minute + 10*time.Second))
+			Expect(ipSets.Members["d:exact"]).To(Equal(set.From("10.0.0.1", "fd00::1")))
+		})
+
+		It("should forget a removed set", func() {
+			sendUpdate(&proto.IPSetRemove{Id: "d:exact"})
+			Expect(ipSets.Members).NotTo(HaveKey("d:exact"))
+			domainMgr.ExpireAddrs(now.Add(time.Hour))
+			Expect(ipSets.Members).NotTo(HaveKey("d:exact"))
+			Expect(ipSets.Members["d:wild"]).To(Equal(set.New()))
+		})
+	})
+})
@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"fmt"
+
+	"github.com/projectcalico/felix/bandwidth"
+	"github.com/projectcalico/felix/dryrun"
+)
+
+const (
+	sysctlDryRunComponent    = "sysctl"
+	bandwidthDryRunComponent = "bandwidth"
+	// nodeDryRunComponent covers the one-off node configuration that the dataplane does when it
+	// starts: kernel modules, tunnel devices and the MTU file.
+	nodeDryRunComponent = "node"
+)
+
+// procSysWriterFor returns the function to use to write sysctls: writeProcSys or, in dry-run
+// mode, a function that records the write instead.
+func procSysWriterFor(recorder *dryrun.Recorder) procSysWriter {
+	if recorder == nil {
+		return writeProcSys
+	}
+	return func(path, value string) error {
+		recorder.Record(sysctlDryRunComponent, fmt.Sprintf("write %s to %s", value, path))
+		return nil
+	}
+}
+
+// dryRunBandwidthShaper is the bandwidthShaper that the endpoint manager uses in observe-only
+// mode.  It records the limits that would be set instead of programming the qdiscs and IFB
+// devices.
+type dryRunBandwidthShaper struct {
+	recorder *dryrun.Recorder
+}
+
+func (s *dryRunBandwidthShaper) SetLimits(ifaceName string, limits bandwidth.Limits) error {
+	s.recorder.Record(bandwidthDryRunComponent, fmt.Sprintf("set limits on %s: %+v", ifaceName, limits))
+	return nil
+}
+
+func (s *dryRunBandwidthShaper) CleanUpOrphans() error {
+	s.recorder.Record(bandwidthDryRunComponent, "clean up orphaned IFB devices")
+	return nil
+}
@@ -0,0 +1,512 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/ifacemonitor"
+	"github.com/projectcalico/felix/ip"
+	"github.com/projectcalico/felix/ipsets"
+	"github.com/projectcalico/felix/netlinkshim"
+	"github.com/projectcalico/felix/proto"
+	"github.com/projectcalico/felix/routerule"
+	"github.com/projectcalico/felix/routetable"
+	"github.com/projectcalico/felix/rules"
+)
+
+// EgressPolicy selects workloads by namespace or by IP pool and gives their traffic that leaves the
+// cluster either a fixed source address or a gateway workload to egress through.  Exactly one of
+// Namespace and PoolCIDR is set, and exactly one of SNATAddress and Gateway.  RouteTableIndex is the
+// routing table allocated for a gateway policy.
+type EgressPolicy struct {
+	Namespace       string
+	PoolCIDR        ip.CIDR
+	SNATAddress     ip.Addr
+	Gateway         ip.Addr
+	RouteTableIndex int
+}
+
+func (p EgressPolicy) String() string {
+	selector := "namespace:" + p.Namespace
+	if p.PoolCIDR != nil {
+		selector = "pool:" + p.PoolCIDR.String()
+	}
+	if p.Gateway != nil {
+		return fmt.Sprintf("%s=gateway:%s", selector, p.Gateway)
+	}
+	return fmt.Sprintf("%s=snat:%s", selector, p.SNATAddress)
+}
+
+// routeRules is the interface provided by the routerule module, which programs the routing rules
+// that send traffic from gateway-routed workloads to their policy's routing table.
+type routeRules interface {
+	SetRule(rule *routerule.Rule)
+	RemoveRule(rule *routerule.Rule)
+	QueueResync()
+	Apply() error
+}
+
+var errGatewayRoutesPending = errors.New("routes to egress gateways have changed, resolving them again")
+
+// egressManager implements egress policies for IPv4 workloads.  Each workload is selected by the
+// first policy that matches it.
+//
+// For an SNAT policy, the manager maintains an IP set of the selected workloads and the egress SNAT
+// chain source NATs their traffic that leaves the cluster to the policy's address.  The egress SNAT
+// chain comes before the NAT outgoing chain, so it takes precedence over the IP pool's NAT outgoing
+// setting.
+//
+// For a gateway policy, the manager adds a routing rule per selected workload that looks up the
+// policy's routing table.  The table has throw routes for the IP pools, so that traffic within the
+// cluster uses the main table, and a default route via the gateway.  The gateway's next hop and
+// interface are resolved from the main routing table, in the same way as the kernel would route
+// traffic to the gateway itself.  If the gateway can't be resolved, the default route is a blackhole
+// route so that the workloads' traffic doesn't leave the cluster with the wrong source address.
+type egressManager struct {
+	policies        []EgressPolicy
+	ipsetsDataplane ipsetsDataplane
+	natTable        iptablesTable
+	ruleRenderer    rules.RuleRenderer
+	routeRules      routeRules
+	rulePriority    int
+	maxIPSetSize    int
+
+	// routeTables holds the routing tables that are reserved for gateway policies, by table index.
+	// We own all of them, so a table that no policy uses is kept empty.
+	routeTables map[int]routeTable
+
+	newNetlinkHandle    func() (netlinkshim.Interface, error)
+	cachedNetlinkHandle netlinkshim.Interface
+
+	activeWlEndpoints map[proto.WorkloadEndpointID]*proto.WorkloadEndpoint
+	activePools       map[string]string
+
+	// Dataplane state: the source CIDR of each routing rule mapped to the routing table index, the
+	// interface of each gateway policy's default route and the gateway policies whose default route
+	// is a blackhole route.
+	activeRules       map[string]int
+	activeRouteIfaces map[int]string
+	blackholed        map[int]bool
+
+	chainProgrammed bool
+	dirty           bool
+	gatewaysDirty   bool
+	// gatewayRoutesPending is set when the routes to a gateway may have changed in this batch of
+	// updates.  Those routes are programmed after the manager has completed its deferred work, so
+	// the gateways are resolved once more on the next apply.
+	gatewayRoutesPending bool
+}
+
+func newEgressManager(
+	policies []EgressPolicy,
+	ipsetsDataplane ipsetsDataplane,
+	natTable iptablesTable,
+	ruleRenderer rules.RuleRenderer,
+	routeRules routeRules,
+	routeTables map[int]routeTable,
+	rulePriority int,
+	maxIPSetSize int,
+) *egressManager {
+	return newEgressManagerWithShims(
+		policies,
+		ipsetsDataplane,
+		natTable,
+		ruleRenderer,
+		routeRules,
+		routeTables,
+		rulePriority,
+		maxIPSetSize,
+		netlinkshim.NewRealNetlink,
+	)
+}
+
+func newEgressManagerWithShims(
+	policies []EgressPolicy,
+	ipsetsDataplane ipsetsDataplane,
+	natTable iptablesTable,
+	ruleRenderer rules.RuleRenderer,
+	routeRules routeRules,
+	routeTables map[int]routeTable,
+	rulePriority int,
+	maxIPSetSize int,
+	newNetlinkHandle func() (netlinkshim.Interface, error),
+) *egressManager {
+	return &egressManager{
+		policies:          policies,
+		ipsetsDataplane:   ipsetsDataplane,
+		natTable:          natTable,
+		ruleRenderer:      ruleRenderer,
+		routeRules:        routeRules,
+		routeTables:       routeTables,
+		rulePriority:      rulePriority,
+		maxIPSetSize:      maxIPSetSize,
+		newNetlinkHandle:  newNetlinkHandle,
+		activeWlEndpoints: map[proto.WorkloadEndpointID]*proto.WorkloadEndpoint{},
+		activePools:       map[string]string{},
+		activeRules:       map[string]int{},
+		activeRouteIfaces: map[int]string{},
+		blackholed:        map[int]bool{},
+		dirty:             true,
+		gatewaysDirty:     true,
+	}
+}
+
+func egressSNATIPSetID(policyIdx int) string {
+	return fmt.Sprintf("%s%d", rules.IPSetIDEgressSNATPrefix, policyIdx)
+}
+
+func (m *egressManager) OnUpdate(msg interface{}) {
+	switch msg := msg.(type) {
+	case *proto.WorkloadEndpointUpdate:
+		if old := m.activeWlEndpoints[*msg.Id]; old != nil {
+			m.onGatewayAddrsUpdate(old.Ipv4Nets)
+		}
+		m.onGatewayAddrsUpdate(msg.Endpoint.Ipv4Nets)
+		m.activeWlEndpoints[*msg.Id] = msg.Endpoint
+		m.dirty = true
+	case *proto.WorkloadEndpointRemove:
+		if old := m.activeWlEndpoints[*msg.Id]; old != nil {
+			m.onGatewayAddrsUpdate(old.Ipv4Nets)
+		}
+		delete(m.activeWlEndpoints, *msg.Id)
+		m.dirty = true
+	case *proto.IPAMPoolUpdate:
+		if strings.Contains(msg.Pool.Cidr, ":") {
+			return
+		}
+		m.activePools[msg.Id] = msg.Pool.Cidr
+		m.dirty = true
+	case *proto.IPAMPoolRemove:
+		if _, ok := m.activePools[msg.Id]; ok {
+			delete(m.activePools, msg.Id)
+			m.dirty = true
+		}
+	case *proto.RouteUpdate:
+		m.onGatewayAddrsUpdate([]string{msg.Dst})
+	case *proto.RouteRemove:
+		m.onGatewayAddrsUpdate([]string{msg.Dst})
+	case *ifaceUpdate:
+		if m.hasGatewayPolicies() {
+			m.gatewaysDirty = true
+			m.gatewayRoutesPending = true
+		}
+	}
+}
+
+func (m *egressManager) hasGatewayPolicies() bool {
+	for _, p := range m.policies {
+		if p.Gateway != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// onGatewayAddrsUpdate marks the gateways for resolution if any of the CIDRs contain a gateway.
+func (m *egressManager) onGatewayAddrsUpdate(cidrs []string) {
+	for _, cidr := range cidrs {
+		ipNet := ip.MustParseCIDROrIP(cidr).ToIPNet()
+		for _, p := range m.policies {
+			if p.Gateway != nil && ipNet.Contains(p.Gateway.AsNetIP()) {
+				log.WithFields(log.Fields{"cidr": cidr, "gateway": p.Gateway}).Debug(
+					"Update affects route to egress gateway")
+				m.gatewaysDirty = true
+				m.gatewayRoutesPending = true
+				return
+			}
+		}
+	}
+}
+
+// policyForEndpoint returns the index of the first policy that selects the workload, or -1 if no
+// policy selects it.  A gateway is never selected by its own policy.
+func (m *egressManager) policyForEndpoint(id proto.WorkloadEndpointID, ep *proto.WorkloadEndpoint) int {
+	namespace := strings.Split(id.WorkloadId, "/")[0]
+	for i, p := range m.policies {
+		if p.Namespace != "" && p.Namespace != namespace {
+			continue
+		}
+		selected := p.PoolCIDR == nil
+		isGateway := false
+		var poolNet net.IPNet
+		if p.PoolCIDR != nil {
+			poolNet = p.PoolCIDR.ToIPNet()
+		}
+		for _, cidr := range ep.Ipv4Nets {
+			addr := ip.MustParseCIDROrIP(cidr).Addr()
+			if p.PoolCIDR != nil && poolNet.Contains(addr.AsNetIP()) {
+				selected = true
+			}
+			if p.Gateway != nil && addr == p.Gateway {
+				isGateway = true
+			}
+		}
+		if selected && !isGateway {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *egressManager) CompleteDeferredWork() error {
+	if !m.chainProgrammed {
+		var snats []rules.EgressSNAT
+		for i, p := range m.policies {
+			if p.SNATAddress == nil {
+				continue
+			}
+			snats = append(snats, rules.EgressSNAT{IPSetID: egressSNATIPSetID(i), ToAddr: p.SNATAddress.String()})
+		}
+		m.natTable.UpdateChain(m.ruleRenderer.EgressSNATChain(snats))
+		m.chainProgrammed = true
+	}
+
+	if m.dirty {
+		m.updateWorkloads()
+		m.dirty = false
+	}
+
+	if m.gatewaysDirty {
+		if err := m.updateGatewayRoutes(); err != nil {
+			return err
+		}
+		m.gatewaysDirty = false
+	}
+
+	if m.gatewayRoutesPending {
+		m.gatewayRoutesPending = false
+		m.gatewaysDirty = true
+		return errGatewayRoutesPending
+	}
+	return nil
+}
+
+// updateWorkloads programs the SNAT IP sets, the routing rules and the throw routes for the IP pools.
+func (m *egressManager) updateWorkloads() {
+	snatMembers := map[int][]string{}
+	desiredRules := map[string]int{}
+	for id, ep := range m.activeWlEndpoints {
+		policyIdx := m.policyForEndpoint(id, ep)
+		if policyIdx < 0 {
+			continue
+		}
+		p := m.policies[policyIdx]
+		for _, cidr := range ep.Ipv4Nets {
+			if p.SNATAddress != nil {
+				snatMembers[policyIdx] = append(snatMembers[policyIdx], cidr)
+			} else {
+				desiredRules[ip.MustParseCIDROrIP(cidr).String()] = p.RouteTableIndex
+			}
+		}
+	}
+
+	for i, p := range m.policies {
+		if p.SNATAddress == nil {
+			continue
+		}
+		m.ipsetsDataplane.AddOrReplaceIPSet(ipsets.IPSetMetadata{
+			MaxSize: m.maxIPSetSize,
+			SetID:   egressSNATIPSetID(i),
+			Type:    ipsets.IPSetTypeHashNet,
+		}, snatMembers[i])
+	}
+
+	for cidr, tableIndex := range m.activeRules {
+		if desiredRules[cidr] != tableIndex {
+			log.WithFields(log.Fields{"cidr": cidr, "table": tableIndex}).Debug("Removing egress routing rule")
+			m.routeRules.RemoveRule(m.newRule(cidr, tableIndex))
+			delete(m.activeRules, cidr)
+		}
+	}
+	for cidr, tableIndex := range desiredRules {
+		if _, ok := m.activeRules[cidr]; !ok {
+			log.WithFields(log.Fields{"cidr": cidr, "table": tableIndex}).Debug("Adding egress routing rule")
+			m.routeRules.SetRule(m.newRule(cidr, tableIndex))
+			m.activeRules[cidr] = tableIndex
+		}
+	}
+
+	for i, p := range m.policies {
+		if p.Gateway == nil {
+			continue
+		}
+		m.routeTables[p.RouteTableIndex].SetRoutes(routetable.InterfaceNone, m.noIfaceRoutes(i))
+	}
+}
+
+// noIfaceRoutes returns the routes of a gateway policy's routing table that have no interface: throw
+// routes for the IP pools and the blackhole default route if the gateway can't be resolved.
+func (m *egressManager) noIfaceRoutes(policyIdx int) []routetable.Target {
+	var poolCIDRs []string
+	for _, cidr := range m.activePools {
+		poolCIDRs = append(poolCIDRs, cidr)
+	}
+	sort.Strings(poolCIDRs)
+
+	var targets []routetable.Target
+	for _, cidr := range poolCIDRs {
+		targets = append(targets, routetable.Target{
+			Type: routetable.TargetTypeThrow,
+			CIDR: ip.MustParseCIDROrIP(cidr),
+		})
+	}
+	if m.blackholed[policyIdx] {
+		targets = append(targets, routetable.Target{
+			Type: routetable.TargetTypeBlackhole,
+			CIDR: ip.MustParseCIDROrIP("0.0.0.0/0"),
+		})
+	}
+	return targets
+}
+
+func (m *egressManager) newRule(cidr string, tableIndex int) *routerule.Rule {
+	return routerule.NewRule(4, m.rulePriority).
+		MatchSrcAddress(ip.MustParseCIDROrIP(cidr).ToIPNet()).
+		GoToTable(tableIndex)
+}
+
+// updateGatewayRoutes resolves each gateway and programs the default route of its policy's routing
+// table.  A gateway that can't be resolved gets a blackhole default route instead, and an error is
+// returned so that resolution is retried.
+func (m *egressManager) updateGatewayRoutes() error {
+	var lastErr error
+	for i, p := range m.policies {
+		if p.Gateway == nil {
+			continue
+		}
+		logCxt := log.WithFields(log.Fields{"policy": p, "gateway": p.Gateway})
+		rt := m.routeTables[p.RouteTableIndex]
+		ifaceName, nextHop, err := m.resolveGateway(p.Gateway)
+		if oldIface, ok := m.activeRouteIfaces[i]; ok && (err != nil || oldIface != ifaceName) {
+			rt.SetRoutes(oldIface, nil)
+			delete(m.activeRouteIfaces, i)
+		}
+		if err != nil {
+			logCxt.WithError(err).Warn("Failed to resolve egress gateway, dropping traffic from its workloads")
+			lastErr = err
+		} else {
+			logCxt.WithFields(log.Fields{"iface": ifaceName, "nextHop": nextHop}).Debug("Resolved egress gateway")
+			rt.SetRoutes(ifaceName, []routetable.Target{{
+				Type: routetable.TargetTypeNoEncap,
+				CIDR: ip.MustParseCIDROrIP("0.0.0.0/0"),
+				GW:   nextHop,
+			}})
+			m.activeRouteIfaces[i] = ifaceName
+		}
+		if m.blackholed[i] != (err != nil) {
+			m.blackholed[i] = err != nil
+			rt.SetRoutes(routetable.InterfaceNone, m.noIfaceRoutes(i))
+		}
+	}
+	return lastErr
+}
+
+// resolveGateway looks up the route to the gateway in the main routing table and returns the
+// interface and next hop for traffic to the gateway.
+func (m *egressManager) resolveGateway(gateway ip.Addr) (ifaceName string, nextHop ip.Addr, err error) {
+	nl, err := m.getNetlink()
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() {
+		if err != nil {
+			m.closeNetlink()
+		}
+	}()
+
+	routes, err := nl.RouteGet(gateway.AsNetIP())
+	if err != nil {
+		return "", nil, err
+	}
+	if len(routes) == 0 {
+		return "", nil, fmt.Errorf("no route to egress gateway %s", gateway)
+	}
+	route := routes[0]
+
+	links, err := nl.LinkList()
+	if err != nil {
+		return "", nil, err
+	}
+	for _, link := range links {
+		if link.Attrs().Index == route.LinkIndex {
+			ifaceName = link.Attrs().Name
+		}
+	}
+	if ifaceName == "" {
+		return "", nil, fmt.Errorf("no interface with index %d for route to egress gateway %s",
+			route.LinkIndex, gateway)
+	}
+
+	nextHop = gateway
+	if route.Gw != nil && !route.Gw.Equal(net.IPv4zero) {
+		nextHop = ip.FromNetIP(route.Gw)
+	}
+	return ifaceName, nextHop, nil
+}
+
+func (m *egressManager) getNetlink() (netlinkshim.Interface, error) {
+	if m.cachedNetlinkHandle == nil {
+		nl, err := m.newNetlinkHandle()
+		if err != nil {
+			log.WithError(err).Error("Failed to connect to netlink")
+			return nil, err
+		}
+		m.cachedNetlinkHandle = nl
+	}
+	return m.cachedNetlinkHandle, nil
+}
+
+func (m *egressManager) closeNetlink() {
+	if m.cachedNetlinkHandle == nil {
+		return
+	}
+	m.cachedNetlinkHandle.Delete()
+	m.cachedNetlinkHandle = nil
+}
+
+func (m *egressManager) GetRouteTableSyncers() []routeTableSyncer {
+	var rts []routeTableSyncer
+	if m.routeRules != nil {
+		rts = append(rts, &egressRouteRulesSyncer{manager: m})
+	}
+	for _, rt := range m.routeTables {
+		rts = append(rts, rt)
+	}
+	return rts
+}
+
+// egressRouteRulesSyncer applies the egress routing rules along with the route tables.  A resync of
+// the route tables also resolves the gateways again.
+type egressRouteRulesSyncer struct {
+	manager *egressManager
+}
+
+func (s *egressRouteRulesSyncer) OnIfaceStateChanged(string, ifacemonitor.State) {}
+
+func (s *egressRouteRulesSyncer) QueueResync() {
+	s.manager.routeRules.QueueResync()
+	s.manager.gatewaysDirty = true
+}
+
+func (s *egressRouteRulesSyncer) Apply() error {
+	return s.manager.routeRules.Apply()
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+
+	"github.com/projectcalico/felix/ifacemonitor"
+	"github.com/projectcalico/felix/ip"
+	"github.com/projectcalico/felix/ipsets"
+	"github.com/projectcalico/felix/iptables"
+	mocknetlink "github.com/projectcalico/felix/netlinkshim/mocknetlink"
+	"github.com/projectcalico/felix/proto"
+	"github.com/projectcalico/felix/routerule"
+	"github.com/projectcalico/felix/routetable"
+	"github.com/projectcalico/felix/rules"
+	"github.com/projectcalico/libcalico-go/lib/set"
+)
+
+var _ = Describe("Egress manager", func() {
+	var (
+		egressMgr    *egressManager
+		natTable     *mockTable
+		ipSets       *mockIPSets
+		rr           *mockRouteRules
+		rt           *mockRouteTable
+		unusedRT     *mockRouteTable
+		nlDataplane  *mocknetlink.MockNetlinkDataplane
+		ruleRenderer rules.RuleRenderer
+	)
+
+	BeforeEach(func() {
+		ipSets = newMockIPSets()
+		natTable = newMockTable("nat")
+		rr = &mockRouteRules{rules: map[string]int{}}
+		rt = &mockRouteTable{
+			currentRoutes:   map[string][]routetable.Target{},
+			currentL2Routes: map[string][]routetable.L2Target{},
+		}
+		unusedRT = &mockRouteTable{
+			currentRoutes:   map[string][]routetable.Target{},
+			currentL2Routes: map[string][]routetable.L2Target{},
+		}
+		nlDataplane = mocknetlink.New()
+		nlDataplane.AddIface(5, "tunl0", true, true)
+		ruleRenderer = rules.NewRenderer(rules.Config{
+			IPSetConfigV4: ipsets.NewIPVersionConfig(
+				ipsets.IPFamilyV4,
+				"cali",
+				nil,
+				nil,
+			),
+			IptablesMarkPass:     0x1,
+			IptablesMarkAccept:   0x2,
+			IptablesMarkScratch0: 0x4,
+			IptablesMarkScratch1: 0x8,
+			IptablesMarkEndpoint: 0x11110000,
+		})
+		egressMgr = newEgressManagerWithShims(
+			[]EgressPolicy{
+				{Namespace: "tenant-a", SNATAddress: ip.FromString("192.0.2.10")},
+				{PoolCIDR: ip.MustParseCIDROrIP("10.65.0.0/16"), Gateway: ip.FromString("10.65.1.10"), RouteTableIndex: 10},
+			},
+			ipSets,
+			natTable,
+			ruleRenderer,
+			rr,
+			map[int]routeTable{10: rt, 11: unusedRT},
+			100,
+			1024,
+			nlDataplane.NewMockNetlink,
+		)
+		egressMgr.OnUpdate(&proto.IPAMPoolUpdate{Id: "pool-1", Pool: &proto.IPAMPool{Cidr: "10.65.0.0/16"}})
+		egressMgr.OnUpdate(&proto.IPAMPoolUpdate{Id: "pool-2", Pool: &proto.IPAMPool{Cidr: "10.66.0.0/16"}})
+	})
+
+	addWorkload := func(workloadID string, addrs ...string) {
+		egressMgr.OnUpdate(&proto.WorkloadEndpointUpdate{
+			Id:       &proto.WorkloadEndpointID{OrchestratorId: "k8s", WorkloadId: workloadID, EndpointId: "eth0"},
+			Endpoint: &proto.WorkloadEndpoint{Ipv4Nets: addrs},
+		})
+	}
+
+	addGatewayRoute := func() {
+		_, dst, _ := net.ParseCIDR("10.65.1.0/26")
+		nlDataplane.AddMockRoute(&netlink.Route{
+			LinkIndex: 5,
+			Dst:       dst,
+			Gw:        net.ParseIP("172.16.0.2"),
+		})
+	}
+
+	throwRoutes := []routetable.Target{
+		{Type: routetable.TargetTypeThrow, CIDR: ip.MustParseCIDROrIP("10.65.0.0/16")},
+		{Type: routetable.TargetTypeThrow, CIDR: ip.MustParseCIDROrIP("10.66.0.0/16")},
+	}
+
+	It("should source NAT the traffic of workloads with an SNAT policy", func() {
+		addGatewayRoute()
+		addWorkload("tenant-a/pod-1", "10.66.0.1/32")
+		addWorkload("tenant-b/pod-2", "10.66.0.2/32")
+		Expect(egressMgr.CompleteDeferredWork()).To(Succeed())
+
+		Expect(ipSets.Members["egress-snat-0"]).To(Equal(set.From("10.66.0.1/32")))
+		natTable.checkChains([][]*iptables.Chain{{{
+			Name: "cali-egress-snat",
+			Rules: []iptables.Rule{{
+				Match: iptables.Match().
+					SourceIPSet("cali40egress-snat-0").
+					NotDestIPSet("cali40all-ipam-pools"),
+				Action: iptables.SNATAction{ToAddr: "192.0.2.10"},
+			}},
+		}}})
+		Expect(rr.rules).To(BeEmpty())
+	})
+
+	It("should route the traffic of workloads with a gateway policy via the gateway", func() {
+		addGatewayRoute()
+		addWorkload("tenant-b/pod-1", "10.65.0.1/32")
+		addWorkload("tenant-b/gateway", "10.65.1.10/32")
+		// The first policy takes precedence.
+		addWorkload("tenant-a/pod-2", "10.65.0.2/32")
+		// Adding the gateway's workload may change the route to the gateway.
+		Expect(egressMgr.CompleteDeferredWork()).To(Equal(errGatewayRoutesPending))
+		Expect(egressMgr.CompleteDeferredWork()).To(Succeed())
+
+		Expect(rr.rules).To(Equal(map[string]int{"10.65.0.1/32": 10}))
+		Expect(ipSets.Members["egress-snat-0"]).To(Equal(set.From("10.65.0.2/32")))
+		rt.checkRoutes(routetable.InterfaceNone, throwRoutes)
+		rt.checkRoutes("tunl0", []routetable.Target{{
+			Type: routetable.TargetTypeNoEncap,
+			CIDR: ip.MustParseCIDROrIP("0.0.0.0/0"),
+			GW:   ip.FromString("172.16.0.2"),
+		}})
+
+		By("removing the workload")
+		egressMgr.OnUpdate(&proto.WorkloadEndpointRemove{
+			Id: &proto.WorkloadEndpointID{OrchestratorId: "k8s", WorkloadId: "tenant-b/pod-1", EndpointId: "eth0"},
+		})
+		Expect(egressMgr.CompleteDeferredWork()).To(Succeed())
+		Expect(rr.rules).To(BeEmpty())
+	})
+
+	It("should sync all the reserved tables but only program the ones that policies use", func() {
+		addGatewayRoute()
+		addWorkload("tenant-b/pod-1", "10.65.0.1/32")
+		Expect(egressMgr.CompleteDeferredWork()).To(Succeed())
+
+		Expect(egressMgr.GetRouteTableSyncers()).To(ConsistOf(
+			&egressRouteRulesSyncer{manager: egressMgr}, rt, unusedRT))
+		Expect(rt.currentRoutes).NotTo(BeEmpty())
+		Expect(unusedRT.currentRoutes).To(BeEmpty())
+	})
+
+	It("should sync the reserved tables and routing rules when there are no gateway policies", func() {
+		egressMgr = newEgressManagerWithShims(
+			nil,
+			ipSets,
+			natTable,
+			ruleRenderer,
+			rr,
+			map[int]routeTable{10: rt},
+			100,
+			1024,
+			nlDataplane.NewMockNetlink,
+		)
+		addWorkload("tenant-b/pod-1", "10.65.0.1/32")
+		egressMgr.OnUpdate(&ifaceUpdate{Name: "tunl0", State: ifacemonitor.StateUp, Index: 5})
+		Expect(egressMgr.CompleteDeferredWork()).To(Succeed())
+
+		// The syncers resync the tables and rules, which removes anything left behind by gateway
+		// policies that have since been removed.
+		Expect(egressMgr.GetRouteTableSyncers()).To(ConsistOf(&egressRouteRulesSyncer{manager: egressMgr}, rt))
+		Expect(rr.rules).To(BeEmpty())
+		Expect(rt.currentRoutes).To(BeEmpty())
+	})
+
+	It("should drop the traffic of workloads whose gateway can't be resolved", func() {
+		addWorkload("tenant-b/pod-1", "10.65.0.1/32")
+		Expect(egressMgr.CompleteDeferredWork()).NotTo(Succeed())
+		rt.checkRoutes(routetable.InterfaceNone, append(throwRoutes, routetable.Target{
+			Type: routetable.TargetTypeBlackhole,
+			CIDR: ip.MustParseCIDROrIP("0.0.0.0/0"),
+		}))
+		Expect(rr.rules).To(Equal(map[string]int{"10.65.0.1/32": 10}))
+
+		By("adding a route to the gateway")
+		addGatewayRoute()
+		egressMgr.OnUpdate(&proto.RouteUpdate{Type: proto.RouteType_REMOTE_WORKLOAD, Dst: "10.65.1.0/26"})
+		// The route from the update isn't programmed until after the manager's deferred work, so
+		// the manager asks to resolve the gateway again.
+		Expect(egressMgr.CompleteDeferredWork()).NotTo(Succeed())
+		Expect(egressMgr.CompleteDeferredWork()).To(Succeed())
+		rt.checkRoutes(routetable.InterfaceNone, throwRoutes)
+		Expect(rt.currentRoutes["tunl0"]).To(HaveLen(1))
+	})
+
+	It("should move the default route when the route to the gateway changes", func() {
+		addGatewayRoute()
+		Expect(egressMgr.CompleteDeferredWork()).To(Succeed())
+		Expect(rt.currentRoutes["tunl0"]).To(HaveLen(1))
+
+		nlDataplane.AddIface(6, "vxlan.calico", true, true)
+		_, dst, _ := net.ParseCIDR("10.65.1.0/26")
+		nlDataplane.RemoveMockRoute(&netlink.Route{LinkIndex: 5, Dst: dst})
+		nlDataplane.AddMockRoute(&netlink.Route{LinkIndex: 6, Dst: dst, Gw: net.ParseIP("10.65.1.0")})
+		egressMgr.OnUpdate(&ifaceUpdate{Name: "vxlan.calico", State: ifacemonitor.StateUp, Index: 6})
+		Expect(egressMgr.CompleteDeferredWork()).To(Equal(errGatewayRoutesPending))
+		Expect(egressMgr.CompleteDeferredWork()).To(Succeed())
+		Expect(rt.currentRoutes["tunl0"]).To(BeEmpty())
+		rt.checkRoutes("vxlan.calico", []routetable.Target{{
+			Type: routetable.TargetTypeNoEncap,
+			CIDR: ip.MustParseCIDROrIP("0.0.0.0/0"),
+			GW:   ip.FromString("10.65.1.0"),
+		}})
+	})
+})
+
+type mockRouteRules struct {
+	rules map[string]int
+}
+
+func (r *mockRouteRules) SetRule(rule *routerule.Rule) {
+	nlRule := rule.NetLinkRule()
+	r.rules[nlRule.Src.String()] = nlRule.Table
+}
+
+func (r *mockRouteRules) RemoveRule(rule *routerule.Rule) {
+	delete(r.rules, rule.NetLinkRule().Src.String())
+}
+
+func (r *mockRouteRules) QueueResync() {}
+
+func (r *mockRouteRules) Apply() error {
+	return nil
+}
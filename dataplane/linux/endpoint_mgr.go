@@ -25,6 +25,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/projectcalico/felix/bandwidth"
 	"github.com/projectcalico/felix/ifacemonitor"
 	"github.com/projectcalico/felix/ip"
 	"github.com/projectcalico/felix/iptables"
@@ -53,6 +54,12 @@ type hepListener interface {
 	OnHEPUpdate(hostIfaceToEpMap map[string]proto.HostEndpoint)
 }
 
+// bandwidthShaper is the interface provided by the bandwidth module used to program per-workload bandwidth limits.
+type bandwidthShaper interface {
+	SetLimits(ifaceName string, limits bandwidth.Limits) error
+	CleanUpOrphans() error
+}
+
 type endpointManagerCallbacks struct {
 	addInterface           *AddInterfaceFuncs
 	removeInterface        *RemoveInterfaceFuncs
@@ -145,6 +152,8 @@ type endpointManager struct {
 	writeProcSys procSysWriter
 	osStat       func(path string) (os.FileInfo, error)
 	epMarkMapper rules.EndpointMarkMapper
+	// bandwidthShaper programs workload bandwidth limits, nil if they are disabled.
+	bandwidthShaper bandwidthShaper
 
 	// Pending updates, cleared in CompleteDeferredWork as the data is copied to the activeXYZ
 	// fields.
@@ -167,6 +176,11 @@ type endpointManager struct {
 	// their configuration (sysctls etc.) refreshed.
 	wlIfaceNamesToReconfigure set.Set
 
+	// auditOnlyPolicies contains the names of the active policies that are audit-only, according
+	// to their Policy.AuditOnly field.  Endpoint chains don't drop packets that weren't accepted by
+	// a policy if all the policies are audit-only.
+	auditOnlyPolicies set.Set
+
 	// epIDsToUpdateStatus contains IDs of endpoints that we need to report status for.
 	// Mix of host and workload endpoint IDs.
 	epIDsToUpdateStatus set.Set
@@ -195,6 +209,9 @@ type endpointManager struct {
 
 	needToCheckDispatchChains     bool
 	needToCheckEndpointMarkChains bool
+	// bandwidthCleanupNeeded is set to true when there may be bandwidth limiting devices left
+	// over from workloads that no longer exist.
+	bandwidthCleanupNeeded bool
 
 	// Callbacks
 	OnEndpointStatusUpdate EndpointStatusUpdateCallback
@@ -218,8 +235,10 @@ func newEndpointManager(
 	kubeIPVSSupportEnabled bool,
 	wlInterfacePrefixes []string,
 	onWorkloadEndpointStatusUpdate EndpointStatusUpdateCallback,
+	procSysWriter procSysWriter,
 	bpfEnabled bool,
 	bpfEndpointManager hepListener,
+	bandwidthShaper bandwidthShaper,
 	callbacks *callbacks,
 ) *endpointManager {
 	return newEndpointManagerWithShims(
@@ -233,10 +252,11 @@ func newEndpointManager(
 		kubeIPVSSupportEnabled,
 		wlInterfacePrefixes,
 		onWorkloadEndpointStatusUpdate,
-		writeProcSys,
+		procSysWriter,
 		os.Stat,
 		bpfEnabled,
 		bpfEndpointManager,
+		bandwidthShaper,
 		callbacks,
 	)
 }
@@ -256,6 +276,7 @@ func newEndpointManagerWithShims(
 	osStat func(name string) (os.FileInfo, error),
 	bpfEnabled bool,
 	bpfEndpointManager hepListener,
+	bandwidthShaper bandwidthShaper,
 	callbacks *callbacks,
 ) *endpointManager {
 	wlIfacesPattern := "^(" + strings.Join(wlInterfacePrefixes, "|") + ").*"
@@ -277,9 +298,12 @@ func newEndpointManagerWithShims(
 		osStat:       osStat,
 		epMarkMapper: epMarkMapper,
 
+		bandwidthShaper: bandwidthShaper,
+
 		// Pending updates, we store these up as OnUpdate is called, then process them
 		// in CompleteDeferredWork and transfer the important data to the activeXYX fields.
 		pendingWlEpUpdates:  map[proto.WorkloadEndpointID]*proto.WorkloadEndpoint{},
+		auditOnlyPolicies:   set.New(),
 		pendingIfaceUpdates: map[string]ifacemonitor.State{},
 
 		activeUpIfaces: set.New(),
@@ -312,6 +336,7 @@ func newEndpointManagerWithShims(
 		activeEPMarkDispatchChains:     map[string]*iptables.Chain{},
 		needToCheckDispatchChains:      true, // Need to do start-of-day update.
 		needToCheckEndpointMarkChains:  true, // Need to do start-of-day update.
+		bandwidthCleanupNeeded:         bandwidthShaper != nil,
 
 		OnEndpointStatusUpdate: onWorkloadEndpointStatusUpdate,
 		callbacks:              newEndpointManagerCallbacks(callbacks, ipVersion),
@@ -325,6 +350,10 @@ func (m *endpointManager) OnUpdate(protoBufMsg interface{}) {
 		m.pendingWlEpUpdates[*msg.Id] = msg.Endpoint
 	case *proto.WorkloadEndpointRemove:
 		m.pendingWlEpUpdates[*msg.Id] = nil
+	case *proto.ActivePolicyUpdate:
+		m.onPolicyAuditOnlyUpdate(msg.Id.Name, msg.Policy.AuditOnly)
+	case *proto.ActivePolicyRemove:
+		m.auditOnlyPolicies.Discard(msg.Id.Name)
 	case *proto.HostEndpointUpdate:
 		log.WithField("msg", msg).Debug("Host endpoint update")
 		m.callbacks.InvokeUpdateHostEndpoint(*msg.Id)
@@ -355,6 +384,30 @@ func (m *endpointManager) OnUpdate(protoBufMsg interface{}) {
 	}
 }
 
+// onPolicyAuditOnlyUpdate records whether the given policy is audit-only.  Endpoints that reference
+// a new policy are always sent after the policy but, if an existing policy changes to or from
+// audit-only, we need to re-render all the endpoint chains.
+func (m *endpointManager) onPolicyAuditOnlyUpdate(name string, auditOnly bool) {
+	if auditOnly == m.auditOnlyPolicies.Contains(name) {
+		return
+	}
+	if auditOnly {
+		m.auditOnlyPolicies.Add(name)
+	} else {
+		m.auditOnlyPolicies.Discard(name)
+	}
+	log.WithFields(log.Fields{
+		"policy":    name,
+		"auditOnly": auditOnly,
+	}).Debug("Policy audit-only status changed, re-rendering endpoint chains.")
+	for id, workload := range m.activeWlEndpoints {
+		if _, ok := m.pendingWlEpUpdates[id]; !ok {
+			m.pendingWlEpUpdates[id] = workload
+		}
+	}
+	m.hostEndpointsDirty = true
+}
+
 func (m *endpointManager) ResolveUpdateBatch() error {
 	// Copy the pending interface state to the active set and mark any interfaces that have
 	// changed state for reconfiguration by resolveWorkload/HostEndpoints()
@@ -390,6 +443,14 @@ func (m *endpointManager) CompleteDeferredWork() error {
 
 	m.resolveWorkloadEndpoints()
 
+	if m.bandwidthCleanupNeeded {
+		if err := m.bandwidthShaper.CleanUpOrphans(); err != nil {
+			log.WithError(err).Warn("Failed to clean up workload bandwidth limiting devices, will retry")
+		} else {
+			m.bandwidthCleanupNeeded = false
+		}
+	}
+
 	if m.hostEndpointsDirty {
 		log.Debug("Host endpoints updated, resolving them.")
 		m.updateHostEndpoints()
@@ -543,6 +604,7 @@ func (m *endpointManager) resolveWorkloadEndpoints() {
 			// conntrack entries as a side-effect.
 			logCxt.Info("Workload removed, deleting old state.")
 			m.routeTable.SetRoutes(oldWorkload.Name, nil)
+			m.removeBandwidthLimits(oldWorkload.Name)
 			m.wlIfaceNamesToReconfigure.Discard(oldWorkload.Name)
 			delete(m.activeWlIfaceNameToID, oldWorkload.Name)
 		}
@@ -588,6 +650,7 @@ func (m *endpointManager) resolveWorkloadEndpoints() {
 						m.filterTable.RemoveChains(m.activeWlIDToChains[id])
 					}
 					m.routeTable.SetRoutes(oldWorkload.Name, nil)
+					m.removeBandwidthLimits(oldWorkload.Name)
 					m.wlIfaceNamesToReconfigure.Discard(oldWorkload.Name)
 					delete(m.activeWlIfaceNameToID, oldWorkload.Name)
 				}
@@ -605,6 +668,7 @@ func (m *endpointManager) resolveWorkloadEndpoints() {
 						ingressPolicyNames,
 						egressPolicyNames,
 						workload.ProfileIds,
+						m.auditOnlyPolicies,
 					)
 					m.filterTable.UpdateChains(chains)
 					m.activeWlIDToChains[id] = chains
@@ -941,6 +1005,7 @@ func (m *endpointManager) updateHostEndpoints() {
 				ingressForwardPolicyNames,
 				egressForwardPolicyNames,
 				hostEp.ProfileIds,
+				m.auditOnlyPolicies,
 			)
 
 			if !reflect.DeepEqual(filtChains, m.activeHostIfaceToFiltChains[ifaceName]) {
@@ -1198,7 +1263,40 @@ func (m *endpointManager) configureInterface(name string) error {
 			return err
 		}
 	}
-	return nil
+	return m.configureBandwidthLimits(name)
+}
+
+// configureBandwidthLimits programs the bandwidth limits of the workload that owns the given
+// interface.  Since it is called from configureInterface(), a failure is retried along with
+// the rest of the interface configuration.
+func (m *endpointManager) configureBandwidthLimits(name string) error {
+	if m.bandwidthShaper == nil {
+		return nil
+	}
+	var limits bandwidth.Limits
+	if id, ok := m.activeWlIfaceNameToID[name]; ok {
+		workload := m.activeWlEndpoints[id]
+		limits = bandwidth.Limits{
+			IngressRate:  uint64(workload.IngressBandwidth),
+			IngressBurst: uint64(workload.IngressBurst),
+			EgressRate:   uint64(workload.EgressBandwidth),
+			EgressBurst:  uint64(workload.EgressBurst),
+		}
+	}
+	return m.bandwidthShaper.SetLimits(name, limits)
+}
+
+// removeBandwidthLimits removes the bandwidth limits from an interface that no longer belongs
+// to an active workload.  If that fails, we fall back to cleaning up any left-over devices
+// once the interface has gone.
+func (m *endpointManager) removeBandwidthLimits(name string) {
+	if m.bandwidthShaper == nil {
+		return
+	}
+	if err := m.bandwidthShaper.SetLimits(name, bandwidth.Limits{}); err != nil {
+		log.WithError(err).WithField("ifaceName", name).Warn("Failed to remove workload bandwidth limits")
+		m.bandwidthCleanupNeeded = true
+	}
 }
 
 func writeProcSys(path, value string) error {
@@ -26,6 +26,7 @@ import (
 	. "github.com/onsi/gomega"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/projectcalico/felix/bandwidth"
 	"github.com/projectcalico/felix/ip"
 	"github.com/projectcalico/felix/ipsets"
 	"github.com/projectcalico/felix/iptables"
@@ -648,6 +649,7 @@ func endpointManagerTests(ipVersion uint8) func() {
 			mockProcSys     *testProcSys
 			statusReportRec *statusReportRecorder
 			hepListener     *testHEPListener
+			bwShaper        *testBandwidthShaper
 		)
 
 		BeforeEach(func() {
@@ -688,6 +690,7 @@ func endpointManagerTests(ipVersion uint8) func() {
 			mockProcSys = &testProcSys{state: map[string]string{}, pathsThatExist: map[string]bool{}}
 			statusReportRec = &statusReportRecorder{currentState: map[interface{}]string{}}
 			hepListener = &testHEPListener{}
+			bwShaper = &testBandwidthShaper{limits: map[string]bandwidth.Limits{}}
 			epMgr = newEndpointManagerWithShims(
 				rawTable,
 				mangleTable,
@@ -703,6 +706,7 @@ func endpointManagerTests(ipVersion uint8) func() {
 				mockProcSys.stat,
 				false,
 				hepListener,
+				bwShaper,
 				newCallbacks(),
 			)
 		})
@@ -711,6 +715,12 @@ func endpointManagerTests(ipVersion uint8) func() {
 			Expect(epMgr).ToNot(BeNil())
 		})
 
+		It("should clean up bandwidth limiting devices at start of day", func() {
+			Expect(epMgr.CompleteDeferredWork()).To(Succeed())
+			Expect(epMgr.CompleteDeferredWork()).To(Succeed())
+			Expect(bwShaper.numCleanUps).To(Equal(1))
+		})
+
 		configureHostEp := func(spec *hostEpSpec) func() {
 			tiers := []*proto.TierInfo{}
 			untrackedTiers := []*proto.TierInfo{}
@@ -1810,6 +1820,77 @@ func endpointManagerTests(ipVersion uint8) func() {
 						})
 					})
 
+					It("should not set bandwidth limits", func() {
+						Expect(bwShaper.limits).To(BeEmpty())
+					})
+
+					Context("with bandwidth limits added to the endpoint", func() {
+						JustBeforeEach(func() {
+							epMgr.OnUpdate(&proto.WorkloadEndpointUpdate{
+								Id: &wlEPID1,
+								Endpoint: &proto.WorkloadEndpoint{
+									State:            "active",
+									Mac:              "01:02:03:04:05:06",
+									Name:             "cali12345-ab",
+									ProfileIds:       []string{},
+									Tiers:            []*proto.TierInfo{},
+									Ipv4Nets:         []string{"10.0.240.2/24"},
+									Ipv6Nets:         []string{"2001:db8:2::2/128"},
+									IngressBandwidth: 10000000,
+									EgressBandwidth:  1000000,
+									EgressBurst:      800000,
+								},
+							})
+							err := epMgr.ResolveUpdateBatch()
+							Expect(err).ToNot(HaveOccurred())
+							err = epMgr.CompleteDeferredWork()
+							Expect(err).ToNot(HaveOccurred())
+						})
+
+						It("should set bandwidth limits", func() {
+							Expect(bwShaper.limits).To(Equal(map[string]bandwidth.Limits{
+								"cali12345-ab": {IngressRate: 10000000, EgressRate: 1000000, EgressBurst: 800000},
+							}))
+						})
+
+						Context("with the endpoint removed", func() {
+							JustBeforeEach(func() {
+								epMgr.OnUpdate(&proto.WorkloadEndpointRemove{
+									Id: &wlEPID1,
+								})
+								err := epMgr.ResolveUpdateBatch()
+								Expect(err).ToNot(HaveOccurred())
+								err = epMgr.CompleteDeferredWork()
+								Expect(err).ToNot(HaveOccurred())
+							})
+
+							It("should remove bandwidth limits", func() {
+								Expect(bwShaper.limits).To(BeEmpty())
+							})
+						})
+
+						Context("with a failure to set bandwidth limits", func() {
+							JustBeforeEach(func() {
+								bwShaper.fail = true
+								epMgr.OnUpdate(&ifaceUpdate{
+									Name:  "cali12345-ab",
+									State: "up",
+								})
+								err := epMgr.ResolveUpdateBatch()
+								Expect(err).ToNot(HaveOccurred())
+								err = epMgr.CompleteDeferredWork()
+								Expect(err).ToNot(HaveOccurred())
+							})
+
+							It("should retry", func() {
+								bwShaper.fail = false
+								bwShaper.limits = map[string]bandwidth.Limits{}
+								Expect(epMgr.CompleteDeferredWork()).To(Succeed())
+								Expect(bwShaper.limits).To(HaveKey("cali12345-ab"))
+							})
+						})
+					})
+
 					Context("with the endpoint removed", func() {
 						JustBeforeEach(func() {
 							epMgr.OnUpdate(&proto.WorkloadEndpointRemove{
@@ -1990,6 +2071,33 @@ func (t *testProcSys) checkState(expected map[string]string) {
 	Expect(t.state).To(Equal(expected))
 }
 
+type testBandwidthShaper struct {
+	limits      map[string]bandwidth.Limits
+	numCleanUps int
+	fail        bool
+}
+
+var (
+	bandwidthShaperFail = errors.New("mock bandwidth shaper failure")
+)
+
+func (t *testBandwidthShaper) SetLimits(ifaceName string, limits bandwidth.Limits) error {
+	if t.fail {
+		return bandwidthShaperFail
+	}
+	if limits == (bandwidth.Limits{}) {
+		delete(t.limits, ifaceName)
+	} else {
+		t.limits[ifaceName] = limits
+	}
+	return nil
+}
+
+func (t *testBandwidthShaper) CleanUpOrphans() error {
+	t.numCleanUps++
+	return nil
+}
+
 type testHEPListener struct {
 	state map[string]string
 }
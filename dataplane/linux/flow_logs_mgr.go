@@ -0,0 +1,40 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"github.com/projectcalico/felix/flowlogs"
+)
+
+// flowLogsManager passes the active policies, profiles and local endpoints to the flow logs
+// collector, which uses them to attribute flows to rules and endpoints.  It doesn't program
+// anything itself: the verdict NFLOG rules are rendered along with the policy chains.
+type flowLogsManager struct {
+	collector *flowlogs.Collector
+}
+
+func newFlowLogsManager(collector *flowlogs.Collector) *flowLogsManager {
+	return &flowLogsManager{
+		collector: collector,
+	}
+}
+
+func (m *flowLogsManager) OnUpdate(msg interface{}) {
+	m.collector.OnUpdate(msg)
+}
+
+func (m *flowLogsManager) CompleteDeferredWork() error {
+	return nil
+}
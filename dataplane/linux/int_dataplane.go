@@ -32,27 +32,39 @@ import (
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 	"k8s.io/client-go/kubernetes"
 
+	"github.com/projectcalico/felix/bandwidth"
 	"github.com/projectcalico/felix/bpf"
 	"github.com/projectcalico/felix/bpf/arp"
+	"github.com/projectcalico/felix/bpf/audit"
 	"github.com/projectcalico/felix/bpf/conntrack"
 	"github.com/projectcalico/felix/bpf/failsafes"
 	bpfipsets "github.com/projectcalico/felix/bpf/ipsets"
 	"github.com/projectcalico/felix/bpf/nat"
 	bpfproxy "github.com/projectcalico/felix/bpf/proxy"
 	"github.com/projectcalico/felix/bpf/routes"
+	"github.com/projectcalico/felix/bpf/rulecounters"
 	"github.com/projectcalico/felix/bpf/state"
 	"github.com/projectcalico/felix/bpf/tc"
+	"github.com/projectcalico/felix/denylog"
+	"github.com/projectcalico/felix/dnssnoop"
+	"github.com/projectcalico/felix/drift"
+	"github.com/projectcalico/felix/dryrun"
+	"github.com/projectcalico/felix/flowlogs"
 	"github.com/projectcalico/felix/idalloc"
 	"github.com/projectcalico/felix/ifacemonitor"
+	"github.com/projectcalico/felix/ip"
 	"github.com/projectcalico/felix/ipsets"
 	"github.com/projectcalico/felix/iptables"
 	"github.com/projectcalico/felix/jitter"
 	"github.com/projectcalico/felix/labelindex"
 	"github.com/projectcalico/felix/logutils"
+	"github.com/projectcalico/felix/netlinkshim"
 	"github.com/projectcalico/felix/proto"
+	"github.com/projectcalico/felix/routerule"
 	"github.com/projectcalico/felix/routetable"
 	"github.com/projectcalico/felix/rules"
 	"github.com/projectcalico/felix/throttle"
+	"github.com/projectcalico/felix/timeshim"
 	"github.com/projectcalico/felix/wireguard"
 	"github.com/projectcalico/libcalico-go/lib/health"
 	lclogutils "github.com/projectcalico/libcalico-go/lib/logutils"
@@ -120,12 +132,14 @@ type Config struct {
 	RuleRendererOverride rules.RuleRenderer
 	IPIPMTU              int
 	VXLANMTU             int
+	VXLANMTUV6           int
 	VXLANPort            int
 
 	MaxIPSetSize int
 
 	IptablesBackend                string
 	IPSetsRefreshInterval          time.Duration
+	IPSetsBackend                  string
 	RouteRefreshInterval           time.Duration
 	DeviceRouteSourceAddress       net.IP
 	DeviceRouteProtocol            int
@@ -136,10 +150,25 @@ type Config struct {
 	IptablesLockFilePath           string
 	IptablesLockTimeout            time.Duration
 	IptablesLockProbeInterval      time.Duration
+	IptablesPersistentRestore      bool
 	XDPRefreshInterval             time.Duration
+	DNSExtraTTL                    time.Duration
+	FlowLogsFlushInterval          time.Duration
+	FlowLogsDestination            string
+	PolicyCountersGranularity      string
+	PolicyCountersMaxSeries        int
+	DeniedPacketLogFile            string
+	DeniedPacketLogRateLimit       int
+	WireguardPeerMetricsEnabled    bool
+	WorkloadBandwidthLimitsEnabled bool
 
 	Wireguard wireguard.Config
 
+	EgressPolicies            []EgressPolicy
+	EgressRoutingRulePriority int
+	// EgressRouteTableIndices are the routing tables reserved for egress gateway policies.
+	EgressRouteTableIndices []int
+
 	NetlinkTimeout time.Duration
 
 	RulesConfig rules.Config
@@ -157,6 +186,16 @@ type Config struct {
 
 	DebugSimulateDataplaneHangAfter time.Duration
 
+	// DryRun, if non-nil, puts the dataplane in observe-only mode: the iptables tables, IP sets,
+	// route tables, routing rules, sysctls, bandwidth shaper and BPF managers calculate their
+	// changes as normal but pass them to the recorder instead of writing them.  The one-off node
+	// configuration (kernel modules, tunnel devices and the MTU file) is recorded and skipped.
+	// WireGuard is not supported in this mode.
+	DryRun *dryrun.Recorder
+	// DriftReporter, if non-nil, is told about each iptables chain, IP set or route that a resync
+	// finds to have been modified by something other than Felix.
+	DriftReporter *drift.Reporter
+
 	ExternalNodesCidrs []string
 
 	BPFEnabled                         bool
@@ -247,6 +286,7 @@ type InternalDataplane struct {
 
 	allManagers             []Manager
 	managersWithRouteTables []ManagerWithRouteTables
+	applyTracker            applyTracker
 	ruleRenderer            rules.RuleRenderer
 
 	// dataplaneNeedsSync is set if the dataplane is dirty in some way, i.e. we need to
@@ -280,6 +320,23 @@ type InternalDataplane struct {
 	ipsetsSourceV4    ipsetsSource
 	callbacks         *callbacks
 
+	// domainIPSetsMgr, if DNS snooping is enabled, populates the IP sets of domain-based rules
+	// from the address records that the DNS snooper sends on dnsRecordsC.
+	domainIPSetsMgr *domainIPSetsManager
+	dnsRecordsC     chan []dnssnoop.AddrRecord
+
+	// flowLogsCollector, if flow logs are enabled, aggregates the flows that the conntrack
+	// sources report and the policy verdicts that NFLOG reports.
+	flowLogsCollector *flowlogs.Collector
+
+	// policyCountersMgr, if policy counters are enabled, exports the packet and byte counters of
+	// policy rules as Prometheus metrics.
+	policyCountersMgr *policyCountersManager
+
+	// deniedPacketLogger, if the denied-packet log is enabled, turns the denied packets that
+	// NFLOG reports into log records.
+	deniedPacketLogger *denylog.Logger
+
 	loopSummarizer *logutils.Summarizer
 }
 
@@ -290,6 +347,14 @@ const (
 
 func NewIntDataplaneDriver(config Config) *InternalDataplane {
 	log.WithField("config", config).Info("Creating internal dataplane driver.")
+	if config.DryRun != nil {
+		log.Warn("Dataplane dry-run mode enabled: dataplane updates will be recorded instead of written.")
+		if config.Wireguard.Enabled {
+			// Config validation should prevent this; WireGuard writes its device, routes, rules and key
+			// directly.
+			log.Panic("WireGuard is not supported in dataplane dry-run mode")
+		}
+	}
 	ruleRenderer := config.RuleRendererOverride
 	if ruleRenderer == nil {
 		ruleRenderer = rules.NewRenderer(config.RulesConfig)
@@ -315,12 +380,18 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 			log.Debug("Defaulting VXLAN MTU based on host")
 			config.VXLANMTU = mtu - 50
 		}
+		if config.VXLANMTUV6 == 0 {
+			log.Debug("Defaulting IPv6 VXLAN MTU based on host")
+			config.VXLANMTUV6 = mtu - 70
+		}
 		if config.Wireguard.MTU == 0 {
 			log.Debug("Defaulting Wireguard MTU based on host")
 			config.Wireguard.MTU = mtu - 60
 		}
 	}
-	if err := writeMTUFile(config); err != nil {
+	if config.DryRun != nil {
+		config.DryRun.Record(nodeDryRunComponent, fmt.Sprintf("write pod MTU %d to MTU file", determinePodMTU(config)))
+	} else if err := writeMTUFile(config); err != nil {
 		log.WithError(err).Error("Failed to write MTU file, pod MTU may not be properly set")
 	}
 
@@ -349,6 +420,9 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 		PostWriteInterval:     config.IptablesPostWriteCheckInterval,
 		LockTimeout:           config.IptablesLockTimeout,
 		LockProbeInterval:     config.IptablesLockProbeInterval,
+		PersistentRestore:     config.IptablesPersistentRestore,
+		DryRun:                config.DryRun,
+		DriftReporter:         config.DriftReporter,
 		BackendMode:           backendMode,
 		LookPathOverride:      config.LookPathOverride,
 		OnStillAlive:          dp.reportHealth,
@@ -370,11 +444,19 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 		iptablesNATOptions.ExtraCleanupRegexPattern += "|" + rules.HistoricInsertedNATRuleRegex
 	}
 
+	// In iptables mode, the tables that hold policy chains read back the rule counters for the
+	// policy counters manager.  (The NAT tables don't hold any policy chains.)
+	iptablesOptions.ReadRuleCounters = config.RulesConfig.PolicyCountersEnabled && !config.BPFEnabled
+
 	featureDetector := iptables.NewFeatureDetector(config.FeatureDetectOverrides)
 	iptablesFeatures := featureDetector.GetFeatures()
 
 	var iptablesLock sync.Locker
-	if iptablesFeatures.RestoreSupportsLock {
+	if backendMode == iptables.BackendNftables {
+		log.Debug("Calico implementation of iptables lock disabled (native nftables transactions " +
+			"are atomic).")
+		iptablesLock = dummyLock{}
+	} else if iptablesFeatures.RestoreSupportsLock {
 		log.Debug("Calico implementation of iptables lock disabled (because detected version of " +
 			"iptables-restore will use its own implementation).")
 		iptablesLock = dummyLock{}
@@ -424,7 +506,8 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 		featureDetector,
 		iptablesOptions)
 	ipSetsConfigV4 := config.RulesConfig.IPSetConfigV4
-	ipSetsV4 := ipsets.NewIPSets(ipSetsConfigV4, dp.loopSummarizer)
+	ipSetsV4 := ipsets.NewIPSets(ipSetsConfigV4, dp.loopSummarizer, config.IPSetsBackend, config.DryRun,
+		config.DriftReporter)
 	dp.iptablesNATTables = append(dp.iptablesNATTables, natTableV4)
 	dp.iptablesRawTables = append(dp.iptablesRawTables, rawTableV4)
 	dp.iptablesMangleTables = append(dp.iptablesMangleTables, mangleTableV4)
@@ -434,7 +517,7 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 	if config.RulesConfig.VXLANEnabled {
 		routeTableVXLAN := routetable.New([]string{"^vxlan.calico$"}, 4, true, config.NetlinkTimeout,
 			config.DeviceRouteSourceAddress, config.DeviceRouteProtocol, true, 0,
-			dp.loopSummarizer)
+			dp.loopSummarizer, config.DryRun, config.DriftReporter)
 
 		vxlanManager := newVXLANManager(
 			ipSetsV4,
@@ -442,11 +525,18 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 			"vxlan.calico",
 			config,
 			dp.loopSummarizer,
+			4,
 		)
-		go vxlanManager.KeepVXLANDeviceInSync(config.VXLANMTU, 10*time.Second)
+		if config.DryRun != nil {
+			config.DryRun.Record(nodeDryRunComponent, "keep VXLAN device vxlan.calico in sync")
+		} else {
+			go vxlanManager.KeepVXLANDeviceInSync(config.VXLANMTU, 10*time.Second)
+		}
 		dp.RegisterManager(vxlanManager)
+	} else if config.DryRun != nil {
+		config.DryRun.Record(nodeDryRunComponent, "clean up VXLAN device vxlan.calico")
 	} else {
-		cleanUpVXLANDevice()
+		cleanUpVXLANDevice("vxlan.calico")
 	}
 
 	dp.endpointStatusCombiner = newEndpointStatusCombiner(dp.fromDataplane, config.IPv6Enabled)
@@ -512,11 +602,15 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 		// bpffs so there's nothing to clean up
 	}
 
+	// The domain IP sets manager sends its updates to the ipsets managers so it needs to know
+	// about all of them.
+	var domainIPSetsTargets []Manager
 	if !config.BPFEnabled {
 		// BPF mode disabled, create the iptables-only managers.
 		ipsetsManager := newIPSetsManager(ipSetsV4, config.MaxIPSetSize, callbacks)
 		dp.RegisterManager(ipsetsManager)
 		dp.ipsetsSourceV4 = ipsetsManager
+		domainIPSetsTargets = append(domainIPSetsTargets, ipsetsManager)
 		// TODO Connect host IP manager to BPF
 		dp.RegisterManager(newHostIPManager(
 			config.RulesConfig.WorkloadIfacePrefixes,
@@ -526,11 +620,15 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 		dp.RegisterManager(newPolicyManager(rawTableV4, mangleTableV4, filterTableV4, ruleRenderer, 4, callbacks))
 
 		// Clean up any leftover BPF state.
-		err := nat.RemoveConnectTimeLoadBalancer("")
-		if err != nil {
-			log.WithError(err).Info("Failed to remove BPF connect-time load balancer, ignoring.")
+		if config.DryRun != nil {
+			config.DryRun.Record(bpfDryRunComponent, "clean up connect-time load balancer, BPF programs and pins")
+		} else {
+			err := nat.RemoveConnectTimeLoadBalancer("")
+			if err != nil {
+				log.WithError(err).Info("Failed to remove BPF connect-time load balancer, ignoring.")
+			}
+			tc.CleanUpProgramsAndPins()
 		}
-		tc.CleanUpProgramsAndPins()
 	}
 
 	interfaceRegexes := make([]string, len(config.RulesConfig.WorkloadIfacePrefixes))
@@ -539,12 +637,28 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 	}
 	bpfMapContext := &bpf.MapContext{
 		RepinningEnabled: config.BPFMapRepin,
+		DryRun:           config.DryRun,
 	}
 
 	var (
 		bpfEndpointManager *bpfEndpointManager
+		ruleCountersMap    bpf.Map
 	)
 
+	if config.RulesConfig.FlowLogsEnabled {
+		dp.flowLogsCollector = flowlogs.NewCollector(config.FlowLogsFlushInterval, time.Now())
+		dp.RegisterManager(newFlowLogsManager(dp.flowLogsCollector))
+	}
+
+	if config.RulesConfig.DeniedPacketLogEnabled {
+		if config.BPFEnabled {
+			log.Warn("The denied-packet log isn't supported in BPF mode, ignoring.")
+		} else {
+			dp.deniedPacketLogger = denylog.NewLogger(config.DeniedPacketLogRateLimit, time.Now())
+			dp.RegisterManager(newDeniedPacketLogManager(dp.deniedPacketLogger))
+		}
+	}
+
 	if config.BPFEnabled {
 		log.Info("BPF enabled, starting BPF endpoint manager and map manager.")
 		// Register map managers first since they create the maps that will be used by the endpoint manager.
@@ -582,6 +696,20 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 			log.WithError(err).Panic("Failed to create ARP BPF map.")
 		}
 
+		auditMap := audit.Map(bpfMapContext)
+		err = auditMap.EnsureExists()
+		if err != nil {
+			log.WithError(err).Panic("Failed to create audit BPF map.")
+		}
+
+		if config.RulesConfig.PolicyCountersEnabled {
+			ruleCountersMap = rulecounters.Map(bpfMapContext)
+			err = ruleCountersMap.EnsureExists()
+			if err != nil {
+				log.WithError(err).Panic("Failed to create rule counters BPF map.")
+			}
+		}
+
 		// The failsafe manager sets up the failsafe port map.  It's important that it is registered before the
 		// endpoint managers so that the map is brought up to date before they run for the first time.
 		failsafesMap := failsafes.Map(bpfMapContext)
@@ -597,6 +725,43 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 		)
 		dp.RegisterManager(failsafeMgr)
 
+		// With IPv6 enabled, we attach the IPv6 programs alongside the IPv4 ones.  They have
+		// their own maps and the IPv6 IP sets have their own IDs.
+		var (
+			ipSetIDAllocatorV6 *idalloc.IDAllocator
+			ipSetsMapV6        bpf.Map
+			stateMapV6         bpf.Map
+		)
+		if config.IPv6Enabled {
+			ipSetIDAllocatorV6 = idalloc.New()
+			ipSetsMapV6 = bpfipsets.MapV6(bpfMapContext)
+			err = ipSetsMapV6.EnsureExists()
+			if err != nil {
+				log.WithError(err).Panic("Failed to create IPv6 ipsets BPF map.")
+			}
+			bpfIPSetsV6 := bpfipsets.NewBPFIPSets(
+				config.RulesConfig.IPSetConfigV6,
+				ipSetIDAllocatorV6,
+				ipSetsMapV6,
+				dp.loopSummarizer,
+			)
+			dp.ipSets = append(dp.ipSets, bpfIPSetsV6)
+			dp.RegisterManager(newIPSetsManager(bpfIPSetsV6, config.MaxIPSetSize, callbacks))
+			dp.RegisterManager(newBPFRouteManagerV6(config.ExternalNodesCidrs, bpfMapContext))
+
+			stateMapV6 = state.MapV6(bpfMapContext)
+			err = stateMapV6.EnsureExists()
+			if err != nil {
+				log.WithError(err).Panic("Failed to create IPv6 state BPF map.")
+			}
+
+			arpMapV6 := arp.MapV6(bpfMapContext)
+			err = arpMapV6.EnsureExists()
+			if err != nil {
+				log.WithError(err).Panic("Failed to create IPv6 neighbour BPF map.")
+			}
+		}
+
 		workloadIfaceRegex := regexp.MustCompile(strings.Join(interfaceRegexes, "|"))
 		bpfEndpointManager = newBPFEndpointManager(
 			config.BPFLogLevel,
@@ -612,10 +777,18 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 			config.BPFExtToServiceConnmark,
 			ipSetsMap,
 			stateMap,
+			auditMap,
+			ruleCountersMap,
+			ipSetIDAllocatorV6,
+			ipSetsMapV6,
+			stateMapV6,
 			ruleRenderer,
 			filterTableV4,
 			dp.reportHealth,
 		)
+		if config.DryRun != nil {
+			bpfEndpointManager.dp = newDryRunBPFDataplane(config.DryRun)
+		}
 		dp.RegisterManager(bpfEndpointManager)
 
 		// Pre-create the NAT maps so that later operations can assume access.
@@ -684,12 +857,67 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 			bpfRTMgr.setHostIPUpdatesCallBack(kp.OnHostIPsUpdate)
 			bpfRTMgr.setRoutesCallBacks(kp.OnRouteUpdate, kp.OnRouteDelete)
 			conntrackScanner.AddUnlocked(conntrack.NewStaleNATScanner(kp))
+			if dp.flowLogsCollector != nil {
+				// Added last so that it only sees the entries that the other scanners keep.
+				conntrackScanner.AddUnlocked(flowlogs.NewBPFConntrackScanner(dp.flowLogsCollector, timeshim.RealTime()))
+			}
 			conntrackScanner.Start()
 		} else {
 			log.Info("BPF enabled but no Kubernetes client available, unable to run kube-proxy module.")
 		}
 
-		if config.BPFConnTimeLBEnabled {
+		if config.IPv6Enabled {
+			frontendMapV6 := nat.FrontendMapV6(bpfMapContext)
+			err = frontendMapV6.EnsureExists()
+			if err != nil {
+				log.WithError(err).Panic("Failed to create IPv6 NAT frontend BPF map.")
+			}
+			backendMapV6 := nat.BackendMapV6(bpfMapContext)
+			err = backendMapV6.EnsureExists()
+			if err != nil {
+				log.WithError(err).Panic("Failed to create IPv6 NAT backend BPF map.")
+			}
+			ctMapV6 := conntrack.MapV6(bpfMapContext)
+			err = ctMapV6.EnsureExists()
+			if err != nil {
+				log.WithError(err).Panic("Failed to create IPv6 conntrack BPF map.")
+			}
+
+			conntrackScannerV6 := conntrack.NewScannerV6(ctMapV6,
+				conntrack.NewLivenessScanner(config.BPFConntrackTimeouts, config.BPFNodePortDSREnabled))
+			conntrackScannerV6.Scan()
+
+			if config.KubeClientSet != nil {
+				// The IPv6 services are handled by their own kube-proxy, which only does
+				// ClusterIPs, external IPs and load balancer IPs.
+				bpfproxyOptsV6 := []bpfproxy.Option{
+					bpfproxy.WithMinSyncPeriod(config.KubeProxyMinSyncPeriod),
+					bpfproxy.WithIPv6(),
+				}
+				if config.KubeProxyEndpointSlicesEnabled {
+					bpfproxyOptsV6 = append(bpfproxyOptsV6, bpfproxy.WithEndpointsSlices())
+				}
+				kpV6, err := bpfproxy.StartKubeProxy(
+					config.KubeClientSet,
+					config.Hostname,
+					frontendMapV6,
+					backendMapV6,
+					nil,
+					ctMapV6,
+					bpfproxyOptsV6...,
+				)
+				if err != nil {
+					log.WithError(err).Panic("Failed to start IPv6 kube-proxy.")
+				}
+				conntrackScannerV6.AddUnlocked(conntrack.NewStaleNATScanner(kpV6))
+				conntrackScannerV6.Start()
+			}
+		}
+
+		if config.DryRun != nil {
+			config.DryRun.Record(bpfDryRunComponent, fmt.Sprintf(
+				"install connect-time load balancer: %v", config.BPFConnTimeLBEnabled))
+		} else if config.BPFConnTimeLBEnabled {
 			// Activate the connect-time load balancer.
 			err = nat.InstallConnectTimeLoadBalancer(frontendMap, backendMap, routeMap, config.BPFCgroupV2, config.BPFLogLevel)
 			if err != nil {
@@ -706,7 +934,18 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 
 	routeTableV4 := routetable.New(interfaceRegexes, 4, false, config.NetlinkTimeout,
 		config.DeviceRouteSourceAddress, config.DeviceRouteProtocol, config.RemoveExternalRoutes, 0,
-		dp.loopSummarizer)
+		dp.loopSummarizer, config.DryRun, config.DriftReporter)
+
+	var bwShaper bandwidthShaper
+	if config.WorkloadBandwidthLimitsEnabled {
+		// Egress limits need an ingress qdisc on the workload interface, which clashes with the
+		// BPF programs.
+		if config.DryRun != nil {
+			bwShaper = &dryRunBandwidthShaper{recorder: config.DryRun}
+		} else {
+			bwShaper = bandwidth.New(!config.BPFEnabled)
+		}
+	}
 
 	epManager := newEndpointManager(
 		rawTableV4,
@@ -719,13 +958,55 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 		config.RulesConfig.KubeIPVSSupportEnabled,
 		config.RulesConfig.WorkloadIfacePrefixes,
 		dp.endpointStatusCombiner.OnEndpointStatusUpdate,
+		procSysWriterFor(config.DryRun),
 		config.BPFEnabled,
 		bpfEndpointManager,
+		bwShaper,
 		callbacks)
 	dp.RegisterManager(epManager)
 	dp.endpointsSourceV4 = epManager
 	dp.RegisterManager(newFloatingIPManager(natTableV4, ruleRenderer, 4))
 	dp.RegisterManager(newMasqManager(ipSetsV4, natTableV4, ruleRenderer, config.MaxIPSetSize, 4))
+	if config.BPFEnabled {
+		if len(config.EgressPolicies) > 0 {
+			log.Warn("Egress policies are not supported in BPF mode, ignoring them")
+		}
+	} else {
+		// Each gateway policy has its own routing table, which is only used by the routing rules
+		// of its workloads, so we own all the routes in it.  We manage all the reserved tables, and
+		// the routing rules that use them, whether or not a policy uses them so that the routes and
+		// rules of gateway policies that have been removed or reordered are cleaned up.
+		egressRouteTables := map[int]routeTable{}
+		egressTableIndices := set.New()
+		for _, idx := range config.EgressRouteTableIndices {
+			egressRouteTables[idx] = routetable.New([]string{"^.*$", routetable.InterfaceNone}, 4, false,
+				config.NetlinkTimeout, nil, config.DeviceRouteProtocol, true, idx,
+				dp.loopSummarizer, config.DryRun, config.DriftReporter)
+			egressTableIndices.Add(idx)
+		}
+		var egressRouteRules routeRules
+		if egressTableIndices.Len() > 0 {
+			rr, err := routerule.New(4, config.EgressRoutingRulePriority, egressTableIndices,
+				routerule.RulesMatchSrcFWMarkTable, routerule.RulesMatchSrcFWMarkTable,
+				config.NetlinkTimeout, func() (routerule.HandleIface, error) {
+					return netlinkshim.NewRealNetlink()
+				}, dp.loopSummarizer, config.DryRun)
+			if err != nil {
+				log.WithError(err).Panic("Failed to create egress gateway routing rules")
+			}
+			egressRouteRules = rr
+		}
+		dp.RegisterManager(newEgressManager(
+			config.EgressPolicies,
+			ipSetsV4,
+			natTableV4,
+			ruleRenderer,
+			egressRouteRules,
+			egressRouteTables,
+			config.EgressRoutingRulePriority,
+			config.MaxIPSetSize,
+		))
+	}
 	if config.RulesConfig.IPIPEnabled {
 		// Add a manger to keep the all-hosts IP set up to date.
 		dp.ipipManager = newIPIPManager(ipSetsV4, config.MaxIPSetSize, config.ExternalNodesCidrs)
@@ -733,19 +1014,28 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 	}
 
 	// Add a manager for wireguard configuration. This is added irrespective of whether wireguard is actually enabled
-	// because it may need to tidy up some of the routing rules when disabled.
-	cryptoRouteTableWireguard := wireguard.New(config.Hostname, &config.Wireguard, config.NetlinkTimeout,
-		config.DeviceRouteProtocol, func(publicKey wgtypes.Key) error {
-			if publicKey == zeroKey {
-				dp.fromDataplane <- &proto.WireguardStatusUpdate{PublicKey: ""}
-			} else {
-				dp.fromDataplane <- &proto.WireguardStatusUpdate{PublicKey: publicKey.String()}
-			}
-			return nil
-		},
-		dp.loopSummarizer)
-	dp.wireguardManager = newWireguardManager(cryptoRouteTableWireguard)
-	dp.RegisterManager(dp.wireguardManager) // IPv4-only
+	// because it may need to tidy up some of the routing rules when disabled.  In dry-run mode, WireGuard can't be
+	// enabled and the tidy up would write to the dataplane, so we don't add it.
+	if config.DryRun != nil {
+		config.DryRun.Record(nodeDryRunComponent, "clean up any WireGuard device, routes and rules")
+	} else {
+		cryptoRouteTableWireguard := wireguard.New(config.Hostname, &config.Wireguard, config.NetlinkTimeout,
+			config.DeviceRouteProtocol, func(publicKey wgtypes.Key, ipv6InterfaceAddr ip.Addr) error {
+				if publicKey == zeroKey {
+					dp.fromDataplane <- &proto.WireguardStatusUpdate{PublicKey: ""}
+				} else {
+					status := &proto.WireguardStatusUpdate{PublicKey: publicKey.String()}
+					if ipv6InterfaceAddr != nil {
+						status.InterfaceIpv6Addr = ipv6InterfaceAddr.String()
+					}
+					dp.fromDataplane <- status
+				}
+				return nil
+			},
+			dp.loopSummarizer)
+		dp.wireguardManager = newWireguardManager(cryptoRouteTableWireguard, config.Wireguard)
+		dp.RegisterManager(dp.wireguardManager) // Handles both IPv4 and IPv6.
+	}
 
 	dp.RegisterManager(newServiceLoopManager(filterTableV4, ruleRenderer, 4))
 
@@ -784,7 +1074,8 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 		)
 
 		ipSetsConfigV6 := config.RulesConfig.IPSetConfigV6
-		ipSetsV6 := ipsets.NewIPSets(ipSetsConfigV6, dp.loopSummarizer)
+		ipSetsV6 := ipsets.NewIPSets(ipSetsConfigV6, dp.loopSummarizer, config.IPSetsBackend, config.DryRun,
+			config.DriftReporter)
 		dp.ipSets = append(dp.ipSets, ipSetsV6)
 		dp.iptablesNATTables = append(dp.iptablesNATTables, natTableV6)
 		dp.iptablesRawTables = append(dp.iptablesRawTables, rawTableV6)
@@ -794,10 +1085,12 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 		routeTableV6 := routetable.New(
 			interfaceRegexes, 6, false, config.NetlinkTimeout,
 			config.DeviceRouteSourceAddress, config.DeviceRouteProtocol, config.RemoveExternalRoutes, 0,
-			dp.loopSummarizer)
+			dp.loopSummarizer, config.DryRun, config.DriftReporter)
 
 		if !config.BPFEnabled {
-			dp.RegisterManager(newIPSetsManager(ipSetsV6, config.MaxIPSetSize, callbacks))
+			ipsetsManagerV6 := newIPSetsManager(ipSetsV6, config.MaxIPSetSize, callbacks)
+			dp.RegisterManager(ipsetsManagerV6)
+			domainIPSetsTargets = append(domainIPSetsTargets, ipsetsManagerV6)
 			dp.RegisterManager(newHostIPManager(
 				config.RulesConfig.WorkloadIfacePrefixes,
 				rules.IPSetIDThisHostIPs,
@@ -816,12 +1109,78 @@ func NewIntDataplaneDriver(config Config) *InternalDataplane {
 			config.RulesConfig.KubeIPVSSupportEnabled,
 			config.RulesConfig.WorkloadIfacePrefixes,
 			dp.endpointStatusCombiner.OnEndpointStatusUpdate,
+			procSysWriterFor(config.DryRun),
 			config.BPFEnabled,
 			nil,
+			nil,
 			callbacks))
 		dp.RegisterManager(newFloatingIPManager(natTableV6, ruleRenderer, 6))
 		dp.RegisterManager(newMasqManager(ipSetsV6, natTableV6, ruleRenderer, config.MaxIPSetSize, 6))
 		dp.RegisterManager(newServiceLoopManager(filterTableV6, ruleRenderer, 6))
+
+		if config.RulesConfig.VXLANEnabled {
+			routeTableVXLANV6 := routetable.New([]string{"^vxlan-v6.calico$"}, 6, true, config.NetlinkTimeout,
+				nil, config.DeviceRouteProtocol, true, 0,
+				dp.loopSummarizer, config.DryRun, config.DriftReporter)
+
+			vxlanManagerV6 := newVXLANManager(
+				ipSetsV6,
+				routeTableVXLANV6,
+				"vxlan-v6.calico",
+				config,
+				dp.loopSummarizer,
+				6,
+			)
+			if config.DryRun != nil {
+				config.DryRun.Record(nodeDryRunComponent, "keep VXLAN device vxlan-v6.calico in sync")
+			} else {
+				go vxlanManagerV6.KeepVXLANDeviceInSync(config.VXLANMTUV6, 10*time.Second)
+			}
+			dp.RegisterManager(vxlanManagerV6)
+		} else if config.DryRun != nil {
+			config.DryRun.Record(nodeDryRunComponent, "clean up VXLAN device vxlan-v6.calico")
+		} else {
+			cleanUpVXLANDevice("vxlan-v6.calico")
+		}
+
+		if bpfEndpointManager != nil {
+			bpfEndpointManager.iptablesFilterTableV6 = filterTableV6
+		}
+	}
+
+	if config.RulesConfig.DNSSnoopingEnabled {
+		if config.BPFEnabled {
+			log.Warn("DNS snooping is not supported in BPF mode, domain-based rules will not match.")
+		} else {
+			if len(config.RulesConfig.DNSTrustedServers) == 0 {
+				log.Warn("DNS snooping is enabled but DNSTrustedServers is empty, domain-based rules will not match.")
+			}
+			// Registered after the ipsets managers so that they have created each domain IP
+			// set by the time we hear about it.
+			dp.domainIPSetsMgr = newDomainIPSetsManager(domainIPSetsTargets, config.DNSExtraTTL)
+			dp.RegisterManager(dp.domainIPSetsMgr)
+			dp.dnsRecordsC = make(chan []dnssnoop.AddrRecord, 100)
+		}
+	}
+
+	if config.RulesConfig.PolicyCountersEnabled {
+		var counterTables []ruleCountersSource
+		if !config.BPFEnabled {
+			for _, tables := range [][]*iptables.Table{
+				dp.iptablesFilterTables, dp.iptablesRawTables, dp.iptablesMangleTables,
+			} {
+				for _, t := range tables {
+					counterTables = append(counterTables, t)
+				}
+			}
+		}
+		dp.policyCountersMgr = newPolicyCountersManager(
+			config.PolicyCountersGranularity,
+			config.PolicyCountersMaxSeries,
+			counterTables,
+			ruleCountersMap,
+		)
+		dp.RegisterManager(dp.policyCountersMgr)
 	}
 
 	dp.allIptablesTables = append(dp.allIptablesTables, dp.iptablesMangleTables...)
@@ -913,6 +1272,7 @@ func determinePodMTU(config Config) int {
 	for _, s := range []mtuState{
 		{config.IPIPMTU, config.RulesConfig.IPIPEnabled},
 		{config.VXLANMTU, config.RulesConfig.VXLANEnabled},
+		{config.VXLANMTUV6, config.RulesConfig.VXLANEnabled && config.IPv6Enabled},
 		{config.Wireguard.MTU, config.Wireguard.Enabled},
 	} {
 		if s.enabled && s.mtu != 0 && (s.mtu < mtu || mtu == 0) {
@@ -931,10 +1291,10 @@ func determinePodMTU(config Config) int {
 	return mtu
 }
 
-func cleanUpVXLANDevice() {
+func cleanUpVXLANDevice(deviceName string) {
 	// If VXLAN is not enabled, check to see if there is a VXLAN device and delete it if there is.
-	log.Debug("Checking if we need to clean up the VXLAN device")
-	link, err := netlink.LinkByName("vxlan.calico")
+	log.WithField("device", deviceName).Debug("Checking if we need to clean up the VXLAN device")
+	link, err := netlink.LinkByName(deviceName)
 	if err != nil {
 		if _, ok := err.(netlink.LinkNotFoundError); ok {
 			log.Debug("VXLAN disabled and no VXLAN device found")
@@ -982,6 +1342,7 @@ func (d *InternalDataplane) RegisterManager(mgr Manager) {
 		d.managersWithRouteTables = append(d.managersWithRouteTables, mgr)
 	}
 	d.allManagers = append(d.allManagers, mgr)
+	d.applyTracker.registerManager(mgr)
 }
 
 func (d *InternalDataplane) Start() {
@@ -991,6 +1352,21 @@ func (d *InternalDataplane) Start() {
 	// Then, start the worker threads.
 	go d.loopUpdatingDataplane()
 	go d.loopReportingStatus()
+	if d.dnsRecordsC != nil {
+		dnssnoop.NewSnooper(d.config.RulesConfig.DNSSnoopingNFLOGGroup, d.dnsRecordsC).Start()
+	}
+	if d.flowLogsCollector != nil {
+		if !d.config.BPFEnabled {
+			// In BPF mode, the flows come from the BPF conntrack scanner instead.
+			flowlogs.NewVerdictReader(d.config.RulesConfig.FlowLogsNFLOGGroup, d.flowLogsCollector).Start()
+			flowlogs.NewConntrackReader(d.flowLogsCollector).Start()
+		}
+		d.flowLogsCollector.StartFlushing(flowlogs.NewWriter(d.config.FlowLogsDestination))
+	}
+	if d.deniedPacketLogger != nil {
+		denylog.NewReader(d.config.RulesConfig.DeniedPacketLogNFLOGGroup, d.deniedPacketLogger,
+			d.config.DeniedPacketLogFile).Start()
+	}
 	go d.ifaceMonitor.MonitorInterfaces()
 	go d.monitorHostMTU()
 }
@@ -1085,7 +1461,9 @@ func (d *InternalDataplane) doStaticDataplaneConfig() {
 		d.setUpIptablesNormal()
 	}
 
-	if d.config.RulesConfig.IPIPEnabled {
+	if d.config.RulesConfig.IPIPEnabled && d.config.DryRun != nil {
+		d.config.DryRun.Record(nodeDryRunComponent, "keep IPIP device tunl0 in sync")
+	} else if d.config.RulesConfig.IPIPEnabled {
 		log.Info("IPIP enabled, starting thread to keep tunnel configuration in sync.")
 		go d.ipipManager.KeepIPIPDeviceInSync(
 			d.config.IPIPMTU,
@@ -1166,55 +1544,44 @@ func (d *InternalDataplane) setUpIptablesBPF() {
 			})
 		}
 
-		if t.IPVersion == 6 {
-			for _, prefix := range d.config.RulesConfig.WorkloadIfacePrefixes {
-				// In BPF mode, we don't support IPv6 yet.  Drop it.
-				fwdRules = append(fwdRules, iptables.Rule{
+		// Let the BPF programs know if Linux conntrack knows about the flow.
+		fwdRules = append(fwdRules,
+			iptables.Rule{
+				Match: iptables.Match().
+					ConntrackState("ESTABLISHED,RELATED"),
+				Comment: []string{"Mark pre-established flows."},
+				Action: iptables.SetMaskedMarkAction{
+					Mark: tc.MarkLinuxConntrackEstablished,
+					Mask: tc.MarkLinuxConntrackEstablishedMask,
+				},
+			},
+		)
+		// The packet may be about to go to a local workload.  However, the local workload may not have a BPF
+		// program attached (yet).  To catch that case, we send the packet through a dispatch chain.  We only
+		// add interfaces to the dispatch chain if the BPF program is in place.
+		for _, prefix := range d.config.RulesConfig.WorkloadIfacePrefixes {
+			// Make sure iptables rules don't drop packets that we're about to process through BPF.
+			fwdRules = append(fwdRules,
+				iptables.Rule{
 					Match:   iptables.Match().OutInterface(prefix + "+"),
-					Action:  iptables.DropAction{},
-					Comment: []string{"To workload, drop IPv6."},
-				})
-			}
-		} else {
-			// Let the BPF programs know if Linux conntrack knows about the flow.
+					Action:  iptables.JumpAction{Target: rules.ChainToWorkloadDispatch},
+					Comment: []string{"To workload, check workload is known."},
+				},
+			)
+		}
+		// Need a final rule to accept traffic that is from a workload and going somewhere else.
+		// Otherwise, if iptables has a DROP policy on the forward chain, the packet will get dropped.
+		// This rule must come after the to-workload jump rules above to ensure that we don't accept too
+		// early before the destination is checked.
+		for _, prefix := range d.config.RulesConfig.WorkloadIfacePrefixes {
+			// Make sure iptables rules don't drop packets that we're about to process through BPF.
 			fwdRules = append(fwdRules,
 				iptables.Rule{
-					Match: iptables.Match().
-						ConntrackState("ESTABLISHED,RELATED"),
-					Comment: []string{"Mark pre-established flows."},
-					Action: iptables.SetMaskedMarkAction{
-						Mark: tc.MarkLinuxConntrackEstablished,
-						Mask: tc.MarkLinuxConntrackEstablishedMask,
-					},
+					Match:   iptables.Match().InInterface(prefix + "+"),
+					Action:  iptables.AcceptAction{},
+					Comment: []string{"To workload, mark has already been verified."},
 				},
 			)
-			// The packet may be about to go to a local workload.  However, the local workload may not have a BPF
-			// program attached (yet).  To catch that case, we send the packet through a dispatch chain.  We only
-			// add interfaces to the dispatch chain if the BPF program is in place.
-			for _, prefix := range d.config.RulesConfig.WorkloadIfacePrefixes {
-				// Make sure iptables rules don't drop packets that we're about to process through BPF.
-				fwdRules = append(fwdRules,
-					iptables.Rule{
-						Match:   iptables.Match().OutInterface(prefix + "+"),
-						Action:  iptables.JumpAction{Target: rules.ChainToWorkloadDispatch},
-						Comment: []string{"To workload, check workload is known."},
-					},
-				)
-			}
-			// Need a final rule to accept traffic that is from a workload and going somewhere else.
-			// Otherwise, if iptables has a DROP policy on the forward chain, the packet will get dropped.
-			// This rule must come after the to-workload jump rules above to ensure that we don't accept too
-			// early before the destination is checked.
-			for _, prefix := range d.config.RulesConfig.WorkloadIfacePrefixes {
-				// Make sure iptables rules don't drop packets that we're about to process through BPF.
-				fwdRules = append(fwdRules,
-					iptables.Rule{
-						Match:   iptables.Match().InInterface(prefix + "+"),
-						Action:  iptables.AcceptAction{},
-						Comment: []string{"To workload, mark has already been verified."},
-					},
-				)
-			}
 		}
 
 		t.InsertOrAppendRules("INPUT", inputRules)
@@ -1437,6 +1804,21 @@ func (d *InternalDataplane) loopUpdatingDataplane() {
 		xdpRefreshC = refreshTicker.C
 	}
 
+	var dnsExpiryC <-chan time.Time
+	if d.domainIPSetsMgr != nil {
+		dnsExpiryC = time.NewTicker(time.Second).C
+	}
+
+	var policyCountersC <-chan time.Time
+	if d.policyCountersMgr != nil {
+		policyCountersC = time.NewTicker(policyCountersRefreshInterval).C
+	}
+
+	var wireguardMetricsC <-chan time.Time
+	if d.config.Wireguard.Enabled && d.config.WireguardPeerMetricsEnabled {
+		wireguardMetricsC = time.NewTicker(wireguardMetricsRefreshInterval).C
+	}
+
 	// Fill the apply throttle leaky bucket.
 	throttleC := jitter.NewTicker(100*time.Millisecond, 10*time.Millisecond).C
 	beingThrottled := false
@@ -1549,6 +1931,18 @@ func (d *InternalDataplane) loopUpdatingDataplane() {
 			log.Debug("Refreshing XDP")
 			d.forceXDPRefresh = true
 			d.dataplaneNeedsSync = true
+		case records := <-d.dnsRecordsC:
+			if d.domainIPSetsMgr.OnAddrRecords(records, time.Now()) {
+				d.dataplaneNeedsSync = true
+			}
+		case <-dnsExpiryC:
+			if d.domainIPSetsMgr.ExpireAddrs(time.Now()) {
+				d.dataplaneNeedsSync = true
+			}
+		case <-policyCountersC:
+			d.policyCountersMgr.Refresh()
+		case <-wireguardMetricsC:
+			d.wireguardManager.RefreshMetrics()
 		case <-d.reschedC:
 			log.Debug("Reschedule kick received")
 			d.dataplaneNeedsSync = true
@@ -1618,12 +2012,17 @@ func (d *InternalDataplane) configureKernel() {
 	// conntrack without it being a kernel module, and so modprobe will fail.
 	// Log result at INFO level for troubleshooting, but otherwise ignore any
 	// failed modprobe calls.
-	mp := newModProbe(moduleConntrackSCTP, newRealCmd)
-	out, err := mp.Exec()
-	log.WithError(err).WithField("output", out).Infof("attempted to modprobe %s", moduleConntrackSCTP)
+	if d.config.DryRun != nil {
+		d.config.DryRun.Record(nodeDryRunComponent, "modprobe "+moduleConntrackSCTP)
+	} else {
+		mp := newModProbe(moduleConntrackSCTP, newRealCmd)
+		out, err := mp.Exec()
+		log.WithError(err).WithField("output", out).Infof("attempted to modprobe %s", moduleConntrackSCTP)
+	}
+	writeProcSys := procSysWriterFor(d.config.DryRun)
 
 	log.Info("Making sure IPv4 forwarding is enabled.")
-	err = writeProcSys("/proc/sys/net/ipv4/ip_forward", "1")
+	err := writeProcSys("/proc/sys/net/ipv4/ip_forward", "1")
 	if err != nil {
 		log.WithError(err).Error("Failed to set IPv4 forwarding sysctl")
 	}
@@ -1643,10 +2042,23 @@ func (d *InternalDataplane) configureKernel() {
 			log.WithError(err).Error("Failed to set unprivileged_bpf_disabled sysctl")
 		}
 	}
+	if d.config.RulesConfig.FlowLogsEnabled && !d.config.BPFEnabled {
+		// Flow logs need the per-flow counters and timestamps that conntrack only keeps
+		// when asked.
+		log.Info("Flow logs enabled, enabling conntrack accounting.")
+		err := writeProcSys("/proc/sys/net/netfilter/nf_conntrack_acct", "1")
+		if err != nil {
+			log.WithError(err).Error("Failed to set nf_conntrack_acct sysctl")
+		}
+		err = writeProcSys("/proc/sys/net/netfilter/nf_conntrack_timestamp", "1")
+		if err != nil {
+			log.WithError(err).Error("Failed to set nf_conntrack_timestamp sysctl")
+		}
+	}
 	if d.config.Wireguard.Enabled {
 		// wireguard module is available in linux kernel >= 5.6
 		mpwg := newModProbe(moduleWireguard, newRealCmd)
-		out, err = mpwg.Exec()
+		out, err := mpwg.Exec()
 		log.WithError(err).WithField("output", out).Infof("attempted to modprobe %s", moduleWireguard)
 	}
 }
@@ -1663,6 +2075,7 @@ func (d *InternalDataplane) apply() {
 
 	// Unset the needs-sync flag, we'll set it again if something fails.
 	d.dataplaneNeedsSync = false
+	applyStart := time.Now()
 
 	// First, give the managers a chance to resolve any state based on the preceding batch of
 	// updates.  In some cases, e.g. EndpointManager, this can result in an update to another
@@ -1681,8 +2094,10 @@ func (d *InternalDataplane) apply() {
 	}
 
 	// Now allow managers to complete the dataplane programming updates that they need.
-	for _, mgr := range d.allManagers {
+	for i, mgr := range d.allManagers {
+		mgrStart := time.Now()
 		err := mgr.CompleteDeferredWork()
+		d.applyTracker.recordManagerApply(i, mgrStart, err)
 		if err != nil {
 			log.WithField("manager", reflect.TypeOf(mgr).Name()).WithError(err).Debug(
 				"couldn't complete deferred work for manager, will try again later")
@@ -1807,6 +2222,7 @@ func (d *InternalDataplane) apply() {
 
 	// And publish and status updates.
 	d.endpointStatusCombiner.Apply()
+	d.applyTracker.recordApply(applyStart, !d.dataplaneNeedsSync)
 
 	// Set up any needed rescheduling kick.
 	if d.reschedC != nil {
@@ -0,0 +1,240 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/bpf"
+	"github.com/projectcalico/felix/bpf/rulecounters"
+	"github.com/projectcalico/felix/iptables"
+	"github.com/projectcalico/felix/ruleindex"
+)
+
+var policyCounterLabelNames = []string{"tier", "policy", "direction", "rule_id"}
+
+var (
+	policyRulePackets = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "felix_policy_rule_packets",
+		Help: "Number of packets that matched a policy rule.",
+	}, policyCounterLabelNames)
+	policyRuleBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "felix_policy_rule_bytes",
+		Help: "Number of bytes in the packets that matched a policy rule.",
+	}, policyCounterLabelNames)
+)
+
+func init() {
+	prometheus.MustRegister(policyRulePackets)
+	prometheus.MustRegister(policyRuleBytes)
+}
+
+const (
+	policyCountersGranularityPolicy = "Policy"
+
+	policyCountersRefreshInterval = 10 * time.Second
+)
+
+// policyCounterLabels holds the label values of one series of the policy rule metrics.  For
+// profiles, the tier is empty and the policy is "profile:<name>".  At "Policy" granularity, the
+// rule ID is empty.
+type policyCounterLabels struct {
+	tier      string
+	policy    string
+	direction string
+	ruleID    string
+}
+
+func (l policyCounterLabels) values() []string {
+	return []string{l.tier, l.policy, l.direction, l.ruleID}
+}
+
+// overflowLabels labels the series that collects the counts of rules that didn't get a series
+// of their own because we'd hit the limit on the number of series.
+var overflowLabels = policyCounterLabels{policy: "__overflow__"}
+
+type ruleCountersSource interface {
+	RuleCounters() map[string]iptables.RuleCounter
+}
+
+// policyCountersManager exports the packet and byte counters of policy rules as Prometheus
+// metrics.  In iptables mode, the counters come from the iptables tables, which read them back
+// when they resync; in BPF mode, they come from the rule counters map, which the policy programs
+// update.  The sources hold running totals so, each time we refresh, we add the increase since
+// the previous reading to the metrics.
+type policyCountersManager struct {
+	granularity string
+	maxSeries   int
+
+	tables       []ruleCountersSource
+	lastIptables []map[string]iptables.RuleCounter
+	bpfMap       bpf.Map
+	lastBPF      map[uint64]rulecounters.Counters
+
+	// rules maps from rule ID to the rule's policy or profile, from which we derive the labels
+	// of the series that the rule's counts are added to.
+	rules             *ruleindex.Index
+	ruleIDByCounterID map[uint64]string
+
+	// seriesRefs counts the rules that map to each series.  activeSeries contains the series
+	// that we've created; once there are maxSeries of those, other rules count towards the
+	// overflow series instead.
+	seriesRefs     map[policyCounterLabels]int
+	activeSeries   map[policyCounterLabels]bool
+	loggedOverflow bool
+}
+
+func newPolicyCountersManager(
+	granularity string,
+	maxSeries int,
+	tables []ruleCountersSource,
+	bpfMap bpf.Map,
+) *policyCountersManager {
+	m := &policyCountersManager{
+		granularity:       granularity,
+		maxSeries:         maxSeries,
+		tables:            tables,
+		bpfMap:            bpfMap,
+		lastBPF:           map[uint64]rulecounters.Counters{},
+		rules:             ruleindex.New(),
+		ruleIDByCounterID: map[uint64]string{},
+		seriesRefs:        map[policyCounterLabels]int{},
+		activeSeries:      map[policyCounterLabels]bool{},
+	}
+	for range tables {
+		m.lastIptables = append(m.lastIptables, map[string]iptables.RuleCounter{})
+	}
+	return m
+}
+
+// OnUpdate tracks the rules of the active policies and profiles.  We keep the previous readings
+// of rules that are still present after an update so that their counts carry on from where they
+// were.
+func (m *policyCountersManager) OnUpdate(msg interface{}) {
+	oldRules, newRules := m.rules.OnUpdate(msg)
+	for _, r := range newRules {
+		m.ruleIDByCounterID[rulecounters.CounterID(r.ID)] = r.ID
+		m.seriesRefs[m.labelsFor(r)]++
+	}
+	for _, r := range oldRules {
+		m.decrefSeries(m.labelsFor(r))
+		if _, ok := m.rules.Rule(r.ID); ok {
+			continue
+		}
+		delete(m.ruleIDByCounterID, rulecounters.CounterID(r.ID))
+		delete(m.lastBPF, rulecounters.CounterID(r.ID))
+		for _, last := range m.lastIptables {
+			delete(last, r.ID)
+		}
+	}
+}
+
+// labelsFor returns the labels of the series that the given rule's counts are added to.
+func (m *policyCountersManager) labelsFor(r ruleindex.Rule) policyCounterLabels {
+	labels := policyCounterLabels{tier: r.Tier, policy: r.Policy, direction: r.Direction}
+	if r.Profile != "" {
+		labels.policy = "profile:" + r.Profile
+	}
+	if m.granularity != policyCountersGranularityPolicy {
+		labels.ruleID = r.ID
+	}
+	return labels
+}
+
+func (m *policyCountersManager) CompleteDeferredWork() error {
+	return nil
+}
+
+func (m *policyCountersManager) decrefSeries(labels policyCounterLabels) {
+	m.seriesRefs[labels]--
+	if m.seriesRefs[labels] > 0 {
+		return
+	}
+	delete(m.seriesRefs, labels)
+	if m.activeSeries[labels] {
+		policyRulePackets.DeleteLabelValues(labels.values()...)
+		policyRuleBytes.DeleteLabelValues(labels.values()...)
+		delete(m.activeSeries, labels)
+	}
+}
+
+// Refresh reads the counters from each source and adds any increase to the metrics.
+func (m *policyCountersManager) Refresh() {
+	for i, t := range m.tables {
+		last := m.lastIptables[i]
+		for ruleID, c := range t.RuleCounters() {
+			if _, ok := m.rules.Rule(ruleID); !ok {
+				continue
+			}
+			prev := last[ruleID]
+			last[ruleID] = c
+			m.addCounts(ruleID, counterIncrease(prev.Packets, c.Packets), counterIncrease(prev.Bytes, c.Bytes))
+		}
+	}
+
+	if m.bpfMap == nil {
+		return
+	}
+	counters, err := rulecounters.ReadCounters(m.bpfMap, func(id uint64) bool {
+		_, ok := m.ruleIDByCounterID[id]
+		return ok
+	})
+	if err != nil {
+		log.WithError(err).Warn("Failed to read BPF rule counters.")
+		return
+	}
+	for id, c := range counters {
+		prev := m.lastBPF[id]
+		m.lastBPF[id] = c
+		m.addCounts(m.ruleIDByCounterID[id], counterIncrease(prev.Packets, c.Packets), counterIncrease(prev.Bytes, c.Bytes))
+	}
+}
+
+// counterIncrease returns the increase from prev to cur.  If cur is lower, the counter must have
+// been reset (for example, because its iptables chain was rewritten) so all of cur is new.
+func counterIncrease(prev, cur uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+func (m *policyCountersManager) addCounts(ruleID string, packets, bytes uint64) {
+	if packets == 0 && bytes == 0 {
+		return
+	}
+	rule, ok := m.rules.Rule(ruleID)
+	if !ok {
+		return
+	}
+	labels := m.labelsFor(rule)
+	if !m.activeSeries[labels] {
+		if len(m.activeSeries) >= m.maxSeries {
+			if !m.loggedOverflow {
+				log.WithField("maxSeries", m.maxSeries).Warn(
+					"Too many policy counter series, adding further counts to the overflow series.")
+				m.loggedOverflow = true
+			}
+			labels = overflowLabels
+		} else {
+			m.activeSeries[labels] = true
+		}
+	}
+	policyRulePackets.WithLabelValues(labels.values()...).Add(float64(packets))
+	policyRuleBytes.WithLabelValues(labels.values()...).Add(float64(bytes))
+}
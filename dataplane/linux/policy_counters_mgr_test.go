@@ -0,0 +1,139 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/projectcalico/felix/iptables"
+	"github.com/projectcalico/felix/proto"
+)
+
+type mockRuleCountersSource struct {
+	counters map[string]iptables.RuleCounter
+}
+
+func (s *mockRuleCountersSource) RuleCounters() map[string]iptables.RuleCounter {
+	return s.counters
+}
+
+var _ = Describe("Policy counters manager", func() {
+	var (
+		mgr    *policyCountersManager
+		source *mockRuleCountersSource
+	)
+
+	policyUpdate := &proto.ActivePolicyUpdate{
+		Id: &proto.PolicyID{Tier: "default", Name: "pol"},
+		Policy: &proto.Policy{
+			InboundRules:  []*proto.Rule{{Action: "allow", RuleId: "rule-in"}},
+			OutboundRules: []*proto.Rule{{Action: "deny", RuleId: "rule-out"}, {Action: "allow", RuleId: "rule-out-2"}},
+		},
+	}
+
+	packets := func(tier, policy, direction, ruleID string) float64 {
+		return testutil.ToFloat64(policyRulePackets.WithLabelValues(tier, policy, direction, ruleID))
+	}
+	bytes := func(tier, policy, direction, ruleID string) float64 {
+		return testutil.ToFloat64(policyRuleBytes.WithLabelValues(tier, policy, direction, ruleID))
+	}
+
+	BeforeEach(func() {
+		policyRulePackets.Reset()
+		policyRuleBytes.Reset()
+		source = &mockRuleCountersSource{counters: map[string]iptables.RuleCounter{}}
+	})
+
+	Describe("at rule granularity", func() {
+		BeforeEach(func() {
+			mgr = newPolicyCountersManager("Rule", 10, []ruleCountersSource{source}, nil)
+			mgr.OnUpdate(policyUpdate)
+			mgr.OnUpdate(&proto.ActiveProfileUpdate{
+				Id:      &proto.ProfileID{Name: "prof"},
+				Profile: &proto.Profile{InboundRules: []*proto.Rule{{Action: "allow", RuleId: "rule-prof"}}},
+			})
+		})
+
+		It("should export the counters of each rule", func() {
+			source.counters["rule-in"] = iptables.RuleCounter{Packets: 10, Bytes: 1000}
+			source.counters["rule-prof"] = iptables.RuleCounter{Packets: 1, Bytes: 60}
+			mgr.Refresh()
+			Expect(packets("default", "pol", "ingress", "rule-in")).To(Equal(10.0))
+			Expect(bytes("default", "pol", "ingress", "rule-in")).To(Equal(1000.0))
+			Expect(packets("", "profile:prof", "ingress", "rule-prof")).To(Equal(1.0))
+		})
+
+		It("should add the increase since the previous reading", func() {
+			source.counters["rule-in"] = iptables.RuleCounter{Packets: 10, Bytes: 1000}
+			mgr.Refresh()
+			source.counters["rule-in"] = iptables.RuleCounter{Packets: 15, Bytes: 1500}
+			mgr.Refresh()
+			Expect(packets("default", "pol", "ingress", "rule-in")).To(Equal(15.0))
+			Expect(bytes("default", "pol", "ingress", "rule-in")).To(Equal(1500.0))
+		})
+
+		It("should handle counters that were reset", func() {
+			source.counters["rule-in"] = iptables.RuleCounter{Packets: 10, Bytes: 1000}
+			mgr.Refresh()
+			source.counters["rule-in"] = iptables.RuleCounter{Packets: 3, Bytes: 300}
+			mgr.Refresh()
+			Expect(packets("default", "pol", "ingress", "rule-in")).To(Equal(13.0))
+		})
+
+		It("should carry on counting rules that survive a policy update", func() {
+			source.counters["rule-in"] = iptables.RuleCounter{Packets: 10, Bytes: 1000}
+			mgr.Refresh()
+			mgr.OnUpdate(policyUpdate)
+			mgr.Refresh()
+			Expect(packets("default", "pol", "ingress", "rule-in")).To(Equal(10.0))
+		})
+
+		It("should delete the series of removed policies", func() {
+			source.counters["rule-in"] = iptables.RuleCounter{Packets: 10, Bytes: 1000}
+			mgr.Refresh()
+			Expect(testutil.CollectAndCount(policyRulePackets)).To(Equal(1))
+			mgr.OnUpdate(&proto.ActivePolicyRemove{Id: &proto.PolicyID{Tier: "default", Name: "pol"}})
+			Expect(testutil.CollectAndCount(policyRulePackets)).To(Equal(0))
+
+			// Counters for rules that we don't know about are ignored.
+			mgr.Refresh()
+			Expect(testutil.CollectAndCount(policyRulePackets)).To(Equal(0))
+		})
+	})
+
+	It("should aggregate by policy at policy granularity", func() {
+		mgr = newPolicyCountersManager("Policy", 10, []ruleCountersSource{source}, nil)
+		mgr.OnUpdate(policyUpdate)
+		source.counters["rule-out"] = iptables.RuleCounter{Packets: 2, Bytes: 200}
+		source.counters["rule-out-2"] = iptables.RuleCounter{Packets: 3, Bytes: 300}
+		mgr.Refresh()
+		Expect(packets("default", "pol", "egress", "")).To(Equal(5.0))
+		Expect(bytes("default", "pol", "egress", "")).To(Equal(500.0))
+	})
+
+	It("should send further counts to the overflow series once it hits the limit", func() {
+		mgr = newPolicyCountersManager("Rule", 2, []ruleCountersSource{source}, nil)
+		mgr.OnUpdate(policyUpdate)
+		source.counters["rule-in"] = iptables.RuleCounter{Packets: 1}
+		source.counters["rule-out"] = iptables.RuleCounter{Packets: 2}
+		mgr.Refresh()
+		source.counters["rule-out-2"] = iptables.RuleCounter{Packets: 3}
+		mgr.Refresh()
+		Expect(testutil.CollectAndCount(policyRulePackets)).To(Equal(3))
+		Expect(packets("", "__overflow__", "", "")).To(Equal(3.0))
+	})
+})
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -68,6 +69,9 @@ type vxlanManager struct {
 	vxlanDevice string
 	vxlanID     int
 	vxlanPort   int
+	// ipVersion is the IP version of the overlay that this manager programs.  Each manager
+	// only handles routes of its own IP version and the matching half of each VTEP.
+	ipVersion uint8
 
 	// Indicates if configuration has changed since the last apply.
 	routesDirty       bool
@@ -89,6 +93,7 @@ func newVXLANManager(
 	deviceName string,
 	dpConfig Config,
 	opRecorder logutils.OpRecorder,
+	ipVersion uint8,
 ) *vxlanManager {
 	nlHandle, _ := netlink.NewHandle()
 
@@ -98,11 +103,12 @@ func newVXLANManager(
 		deviceName,
 		dpConfig,
 		nlHandle,
+		ipVersion,
 		func(interfaceRegexes []string, ipVersion uint8, vxlan bool, netlinkTimeout time.Duration,
 			deviceRouteSourceAddress net.IP, deviceRouteProtocol int, removeExternalRoutes bool) routeTable {
 			return routetable.New(interfaceRegexes, ipVersion, vxlan, netlinkTimeout,
 				deviceRouteSourceAddress, deviceRouteProtocol, removeExternalRoutes, 0,
-				opRecorder)
+				opRecorder, dpConfig.DryRun, dpConfig.DriftReporter)
 		},
 	)
 }
@@ -113,6 +119,7 @@ func newVXLANManagerWithShims(
 	deviceName string,
 	dpConfig Config,
 	nlHandle netlinkHandle,
+	ipVersion uint8,
 	noEncapRTConstruct func(interfacePrefixes []string, ipVersion uint8, vxlan bool, netlinkTimeout time.Duration,
 		deviceRouteSourceAddress net.IP, deviceRouteProtocol int, removeExternalRoutes bool) routeTable,
 ) *vxlanManager {
@@ -120,6 +127,13 @@ func newVXLANManagerWithShims(
 	if dpConfig.DeviceRouteProtocol != syscall.RTPROT_BOOT {
 		noEncapProtocol = dpConfig.DeviceRouteProtocol
 	}
+	// Only the external node CIDRs of our IP version can go in our IP set.
+	var externalNodeCIDRs []string
+	for _, cidr := range dpConfig.ExternalNodesCidrs {
+		if strings.Contains(cidr, ":") == (ipVersion == 6) {
+			externalNodeCIDRs = append(externalNodeCIDRs, cidr)
+		}
+	}
 	return &vxlanManager{
 		ipsetsDataplane: ipsetsDataplane,
 		ipSetMetadata: ipsets.IPSetMetadata{
@@ -134,7 +148,8 @@ func newVXLANManagerWithShims(
 		vxlanDevice:        deviceName,
 		vxlanID:            dpConfig.RulesConfig.VXLANVNI,
 		vxlanPort:          dpConfig.RulesConfig.VXLANPort,
-		externalNodeCIDRs:  dpConfig.ExternalNodesCidrs,
+		ipVersion:          ipVersion,
+		externalNodeCIDRs:  externalNodeCIDRs,
 		routesDirty:        true,
 		vtepsDirty:         true,
 		dpConfig:           dpConfig,
@@ -150,7 +165,8 @@ func (m *vxlanManager) OnUpdate(protoBufMsg interface{}) {
 		// In case the route changes type to one we no longer care about...
 		m.deleteRoute(msg.Dst)
 
-		if msg.Type == proto.RouteType_REMOTE_WORKLOAD && msg.IpPoolType == proto.IPPoolType_VXLAN {
+		if msg.Type == proto.RouteType_REMOTE_WORKLOAD && msg.IpPoolType == proto.IPPoolType_VXLAN &&
+			m.isOurIPVersion(msg.Dst) {
 			logrus.WithField("msg", msg).Debug("VXLAN data plane received route update")
 			m.routesByDest[msg.Dst] = msg
 			m.routesDirty = true
@@ -178,6 +194,65 @@ func (m *vxlanManager) OnUpdate(protoBufMsg interface{}) {
 	}
 }
 
+// isOurIPVersion returns true if the given address or CIDR has the same IP version as this manager.
+func (m *vxlanManager) isOurIPVersion(addr string) bool {
+	return strings.Contains(addr, ":") == (m.ipVersion == 6)
+}
+
+// vtepMAC returns the VTEP's MAC address for this manager's IP version.
+func (m *vxlanManager) vtepMAC(vtep *proto.VXLANTunnelEndpointUpdate) string {
+	if m.ipVersion == 6 {
+		return vtep.MacV6
+	}
+	return vtep.Mac
+}
+
+// vtepTunnelAddr returns the VTEP's tunnel address for this manager's IP version, or "" if the
+// VTEP doesn't have one.
+func (m *vxlanManager) vtepTunnelAddr(vtep *proto.VXLANTunnelEndpointUpdate) string {
+	if m.ipVersion == 6 {
+		return vtep.Ipv6Addr
+	}
+	return vtep.Ipv4Addr
+}
+
+// vtepParentDeviceIP returns the address of the VTEP's parent device for this manager's IP version.
+func (m *vxlanManager) vtepParentDeviceIP(vtep *proto.VXLANTunnelEndpointUpdate) string {
+	if m.ipVersion == 6 {
+		return vtep.ParentDeviceIpv6
+	}
+	return vtep.ParentDeviceIp
+}
+
+// nextHopsForRoute returns the ECMP next hops for the route: the remote VTEPs' tunnel addresses if
+// encap is true, otherwise the remote nodes' IPs.  Next hops whose gateway isn't known yet are
+// skipped; the caller should fall back to a single-path route if fewer than two remain.
+func (m *vxlanManager) nextHopsForRoute(r *proto.RouteUpdate, encap bool) []routetable.NextHop {
+	var hops []routetable.NextHop
+	for _, hop := range r.NextHops {
+		var gw string
+		if encap {
+			if vtep, ok := m.vtepsByNode[hop.NodeName]; ok {
+				gw = m.vtepTunnelAddr(vtep)
+			}
+		} else {
+			gw = hop.NodeIp
+		}
+		if gw == "" {
+			logrus.WithFields(logrus.Fields{
+				"route": r,
+				"node":  hop.NodeName,
+			}).Debug("Next hop has no known gateway, skipping")
+			continue
+		}
+		hops = append(hops, routetable.NextHop{
+			GW:     ip.FromString(gw),
+			Weight: int(hop.Weight),
+		})
+	}
+	return hops
+}
+
 func (m *vxlanManager) deleteRoute(dst string) {
 	_, exists := m.routesByDest[dst]
 	if exists {
@@ -245,7 +320,11 @@ func (m *vxlanManager) CompleteDeferredWork() error {
 		// known VTEPs.
 		var l2routes []routetable.L2Target
 		for _, u := range m.vtepsByNode {
-			mac, err := net.ParseMAC(u.Mac)
+			if m.vtepTunnelAddr(u) == "" {
+				// VTEP only has an address of the other IP version.
+				continue
+			}
+			mac, err := net.ParseMAC(m.vtepMAC(u))
 			if err != nil {
 				// Don't block programming of other VTEPs if somehow we receive one with a bad mac.
 				logrus.WithError(err).Warn("Failed to parse VTEP mac address")
@@ -253,10 +332,10 @@ func (m *vxlanManager) CompleteDeferredWork() error {
 			}
 			l2routes = append(l2routes, routetable.L2Target{
 				VTEPMAC: mac,
-				GW:      ip.FromString(u.Ipv4Addr),
-				IP:      ip.FromString(u.ParentDeviceIp),
+				GW:      ip.FromString(m.vtepTunnelAddr(u)),
+				IP:      ip.FromString(m.vtepParentDeviceIP(u)),
 			})
-			allowedVXLANSources = append(allowedVXLANSources, u.ParentDeviceIp)
+			allowedVXLANSources = append(allowedVXLANSources, m.vtepParentDeviceIP(u))
 		}
 		logrus.WithField("l2routes", l2routes).Debug("VXLAN manager sending L2 updates")
 		m.routeTable.SetL2Routes(m.vxlanDevice, l2routes)
@@ -286,7 +365,11 @@ func (m *vxlanManager) CompleteDeferredWork() error {
 				defaultRoute := routetable.Target{
 					Type: routetable.TargetTypeNoEncap,
 					CIDR: cidr,
-					GW:   ip.FromString(r.DstNodeIp),
+				}
+				if hops := m.nextHopsForRoute(r, false); len(hops) > 1 {
+					defaultRoute.MultiPath = hops
+				} else {
+					defaultRoute.GW = ip.FromString(r.DstNodeIp)
 				}
 
 				noEncapRoutes = append(noEncapRoutes, defaultRoute)
@@ -294,7 +377,7 @@ func (m *vxlanManager) CompleteDeferredWork() error {
 			} else {
 				// Extract the gateway addr for this route based on its remote VTEP.
 				vtep, ok := m.vtepsByNode[r.DstNodeName]
-				if !ok {
+				if !ok || m.vtepTunnelAddr(vtep) == "" {
 					// When the VTEP arrives, it'll set routesDirty=true so this loop will execute again.
 					logCtx.Debug("Dataplane has route with no corresponding VTEP")
 					continue
@@ -303,7 +386,11 @@ func (m *vxlanManager) CompleteDeferredWork() error {
 				vxlanRoute := routetable.Target{
 					Type: routetable.TargetTypeVXLAN,
 					CIDR: cidr,
-					GW:   ip.FromString(vtep.Ipv4Addr),
+				}
+				if hops := m.nextHopsForRoute(r, true); len(hops) > 1 {
+					vxlanRoute.MultiPath = hops
+				} else {
+					vxlanRoute.GW = ip.FromString(m.vtepTunnelAddr(vtep))
 				}
 
 				vxlanRoutes = append(vxlanRoutes, vxlanRoute)
@@ -340,11 +427,11 @@ func (m *vxlanManager) CompleteDeferredWork() error {
 // KeepVXLANDeviceInSync is a goroutine that configures the VXLAN tunnel device, then periodically
 // checks that it is still correctly configured.
 func (m *vxlanManager) KeepVXLANDeviceInSync(mtu int, wait time.Duration) {
-	logrus.WithField("mtu", mtu).Info("VXLAN tunnel device thread started.")
+	logrus.WithFields(logrus.Fields{"mtu": mtu, "ipVersion": m.ipVersion}).Info("VXLAN tunnel device thread started.")
 	logNextSuccess := true
 	for {
 		localVTEP := m.getLocalVTEP()
-		if localVTEP == nil {
+		if localVTEP == nil || m.vtepTunnelAddr(localVTEP) == "" {
 			logrus.Debug("Missing local VTEP information, retrying...")
 			time.Sleep(1 * time.Second)
 			continue
@@ -356,7 +443,12 @@ func (m *vxlanManager) KeepVXLANDeviceInSync(mtu int, wait time.Duration) {
 			continue
 		} else {
 			if m.getNoEncapRouteTable() == nil {
-				noEncapRouteTable := m.noEncapRTConstruct([]string{"^" + parent.Attrs().Name + "$"}, 4, false, m.dpConfig.NetlinkTimeout, m.dpConfig.DeviceRouteSourceAddress,
+				// The configured device route source address is an IPv4 address.
+				var srcAddr net.IP
+				if m.ipVersion == 4 {
+					srcAddr = m.dpConfig.DeviceRouteSourceAddress
+				}
+				noEncapRouteTable := m.noEncapRTConstruct([]string{"^" + parent.Attrs().Name + "$"}, m.ipVersion, false, m.dpConfig.NetlinkTimeout, srcAddr,
 					m.noEncapProtocol, false)
 				m.setNoEncapRouteTable(noEncapRouteTable)
 			}
@@ -380,23 +472,37 @@ func (m *vxlanManager) KeepVXLANDeviceInSync(mtu int, wait time.Duration) {
 // getParentInterface returns the parent interface for the given local VTEP based on IP address. This link returned is nil
 // if, and only if, an error occurred
 func (m *vxlanManager) getParentInterface(localVTEP *proto.VXLANTunnelEndpointUpdate) (netlink.Link, error) {
+	if localVTEP == nil {
+		return nil, errors.New("local VTEP not yet known")
+	}
+	parentDeviceIP := m.vtepParentDeviceIP(localVTEP)
+	if parentDeviceIP == "" {
+		return nil, fmt.Errorf("local VTEP has no IPv%d parent device address", m.ipVersion)
+	}
 	links, err := m.nlHandle.LinkList()
 	if err != nil {
 		return nil, err
 	}
 	for _, link := range links {
-		addrs, err := m.nlHandle.AddrList(link, netlink.FAMILY_V4)
+		addrs, err := m.nlHandle.AddrList(link, m.netlinkFamily())
 		if err != nil {
 			return nil, err
 		}
 		for _, addr := range addrs {
-			if addr.IPNet.IP.String() == localVTEP.ParentDeviceIp {
+			if addr.IPNet.IP.Equal(net.ParseIP(parentDeviceIP)) {
 				logrus.Debugf("Found parent interface: %s", link)
 				return link, nil
 			}
 		}
 	}
-	return nil, fmt.Errorf("Unable to find parent interface with address %s", localVTEP.ParentDeviceIp)
+	return nil, fmt.Errorf("Unable to find parent interface with address %s", parentDeviceIP)
+}
+
+func (m *vxlanManager) netlinkFamily() int {
+	if m.ipVersion == 6 {
+		return netlink.FAMILY_V6
+	}
+	return netlink.FAMILY_V4
 }
 
 // configureVXLANDevice ensures the VXLAN tunnel device is up and configured correctly.
@@ -407,7 +513,7 @@ func (m *vxlanManager) configureVXLANDevice(mtu int, localVTEP *proto.VXLANTunne
 	if err != nil {
 		return err
 	}
-	mac, err := net.ParseMAC(localVTEP.Mac)
+	mac, err := net.ParseMAC(m.vtepMAC(localVTEP))
 	if err != nil {
 		return err
 	}
@@ -419,7 +525,7 @@ func (m *vxlanManager) configureVXLANDevice(mtu int, localVTEP *proto.VXLANTunne
 		VxlanId:      m.vxlanID,
 		Port:         m.vxlanPort,
 		VtepDevIndex: parent.Attrs().Index,
-		SrcAddr:      ip.FromString(localVTEP.ParentDeviceIp).AsNetIP(),
+		SrcAddr:      ip.FromString(m.vtepParentDeviceIP(localVTEP)).AsNetIP(),
 	}
 
 	// Try to get the device.
@@ -474,7 +580,7 @@ func (m *vxlanManager) configureVXLANDevice(mtu int, localVTEP *proto.VXLANTunne
 	}
 
 	// Make sure the IP address is configured.
-	if err := m.ensureV4AddressOnLink(localVTEP.Ipv4Addr, link); err != nil {
+	if err := m.ensureAddressOnLink(m.vtepTunnelAddr(localVTEP), link); err != nil {
 		return fmt.Errorf("failed to ensure address of interface: %s", err)
 	}
 
@@ -486,15 +592,20 @@ func (m *vxlanManager) configureVXLANDevice(mtu int, localVTEP *proto.VXLANTunne
 	return nil
 }
 
-// ensureV4AddressOnLink ensures that the provided IPv4 address is configured on the provided Link. If there are other addresses,
-// this function will remove them, ensuring that the desired IPv4 address is the _only_ address on the Link.
-func (m *vxlanManager) ensureV4AddressOnLink(ipStr string, link netlink.Link) error {
-	_, net, err := net.ParseCIDR(ipStr + "/32")
+// ensureAddressOnLink ensures that the provided address, of the manager's IP version, is configured on the provided Link.
+// If there are other addresses, this function will remove them, ensuring that the desired address is the _only_ address
+// on the Link (apart from the kernel's IPv6 link-local address).
+func (m *vxlanManager) ensureAddressOnLink(ipStr string, link netlink.Link) error {
+	suffix := "/32"
+	if m.ipVersion == 6 {
+		suffix = "/128"
+	}
+	_, net, err := net.ParseCIDR(ipStr + suffix)
 	if err != nil {
 		return err
 	}
 	addr := netlink.Addr{IPNet: net}
-	existingAddrs, err := m.nlHandle.AddrList(link, netlink.FAMILY_V4)
+	existingAddrs, err := m.nlHandle.AddrList(link, m.netlinkFamily())
 	if err != nil {
 		return err
 	}
@@ -506,6 +617,10 @@ func (m *vxlanManager) ensureV4AddressOnLink(ipStr string, link netlink.Link) er
 			addrPresent = true
 			continue
 		}
+		if m.ipVersion == 6 && existing.IPNet.IP.IsLinkLocalUnicast() {
+			// The kernel adds a link-local address to the device when it comes up; leave it alone.
+			continue
+		}
 		logrus.WithFields(logrus.Fields{"address": existing, "link": link.Attrs().Name}).Warn("Removing unwanted IP from VXLAN device")
 		if err := m.nlHandle.AddrDel(link, &existing); err != nil {
 			return fmt.Errorf("failed to remove IP address %s", existing)
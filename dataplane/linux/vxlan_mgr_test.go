@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/projectcalico/felix/rules"
+	"github.com/projectcalico/libcalico-go/lib/set"
 
 	"github.com/projectcalico/felix/ip"
 
@@ -55,6 +56,13 @@ func (m *mockVXLANDataplane) LinkSetUp(link netlink.Link) error {
 }
 
 func (m *mockVXLANDataplane) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
+	if family == netlink.FAMILY_V6 {
+		return []netlink.Addr{{
+			IPNet: &net.IPNet{
+				IP: net.ParseIP("fc00:0:0:2::2"),
+			},
+		}}, nil
+	}
 	l := []netlink.Addr{{
 		IPNet: &net.IPNet{
 			IP: net.IPv4(172, 0, 0, 2),
@@ -114,6 +122,7 @@ var _ = Describe("VXLANManager", func() {
 			&mockVXLANDataplane{
 				links: []netlink.Link{&mockLink{attrs: netlink.LinkAttrs{Name: "eth0"}}},
 			},
+			4,
 			func(interfacePrefixes []string, ipVersion uint8, vxlan bool, netlinkTimeout time.Duration,
 				deviceRouteSourceAddress net.IP, deviceRouteProtocol int, removeExternalRoutes bool) routeTable {
 				return prt
@@ -223,4 +232,201 @@ var _ = Describe("VXLANManager", func() {
 		Expect(manager.routesDirty).To(BeFalse())
 		Expect(prt.currentRoutes["eth0"]).To(HaveLen(1))
 	})
+
+	It("programs an ECMP route via the remote VTEPs", func() {
+		manager.OnUpdate(&proto.VXLANTunnelEndpointUpdate{
+			Node:           "node1",
+			Mac:            "00:0a:74:9d:68:16",
+			Ipv4Addr:       "10.0.0.0",
+			ParentDeviceIp: "172.0.0.2",
+		})
+		manager.OnUpdate(&proto.VXLANTunnelEndpointUpdate{
+			Node:           "node2",
+			Mac:            "00:0a:95:9d:68:16",
+			Ipv4Addr:       "10.0.80.0",
+			ParentDeviceIp: "172.0.12.1",
+		})
+		manager.OnUpdate(&proto.VXLANTunnelEndpointUpdate{
+			Node:           "node3",
+			Mac:            "00:0a:95:9d:68:17",
+			Ipv4Addr:       "10.0.81.0",
+			ParentDeviceIp: "172.0.12.2",
+		})
+
+		manager.noEncapRouteTable = prt
+
+		err := manager.configureVXLANDevice(50, manager.getLocalVTEP())
+		Expect(err).NotTo(HaveOccurred())
+
+		manager.OnUpdate(&proto.RouteUpdate{
+			Type:        proto.RouteType_REMOTE_WORKLOAD,
+			IpPoolType:  proto.IPPoolType_VXLAN,
+			Dst:         "172.0.0.1/26",
+			DstNodeName: "node2",
+			DstNodeIp:   "172.8.8.8",
+			NextHops: []*proto.RouteNextHop{
+				{NodeName: "node2", NodeIp: "172.8.8.8", Weight: 1},
+				{NodeName: "node3", NodeIp: "172.8.8.9", Weight: 2},
+			},
+		})
+
+		err = manager.CompleteDeferredWork()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rt.currentRoutes["vxlan.calico"]).To(Equal([]routetable.Target{{
+			Type: routetable.TargetTypeVXLAN,
+			CIDR: ip.MustParseCIDROrIP("172.0.0.1/26"),
+			MultiPath: []routetable.NextHop{
+				{GW: ip.FromString("10.0.80.0"), Weight: 1},
+				{GW: ip.FromString("10.0.81.0"), Weight: 2},
+			},
+		}}))
+	})
+
+	It("ignores IPv6 routes", func() {
+		manager.OnUpdate(&proto.RouteUpdate{
+			Type:        proto.RouteType_REMOTE_WORKLOAD,
+			IpPoolType:  proto.IPPoolType_VXLAN,
+			Dst:         "fc00:10:10::/122",
+			DstNodeName: "node2",
+			DstNodeIp:   "fc00:0:0:12::1",
+		})
+		Expect(manager.routesByDest).To(BeEmpty())
+	})
+})
+
+var _ = Describe("VXLANManager (IPv6)", func() {
+	var manager *vxlanManager
+	var rt *mockRouteTable
+	var prt *mockRouteTable
+	var ipSets *mockIPSets
+	var prtIPVersion uint8
+
+	BeforeEach(func() {
+		rt = &mockRouteTable{
+			currentRoutes:   map[string][]routetable.Target{},
+			currentL2Routes: map[string][]routetable.L2Target{},
+		}
+		prt = &mockRouteTable{
+			currentRoutes:   map[string][]routetable.Target{},
+			currentL2Routes: map[string][]routetable.L2Target{},
+		}
+		ipSets = newMockIPSets()
+
+		manager = newVXLANManagerWithShims(
+			ipSets,
+			rt,
+			"vxlan-v6.calico",
+			Config{
+				MaxIPSetSize:       5,
+				Hostname:           "node1",
+				ExternalNodesCidrs: []string{"10.0.0.0/24", "fc00:0:0:99::/64"},
+				RulesConfig: rules.Config{
+					VXLANVNI:  1,
+					VXLANPort: 20,
+				},
+			},
+			&mockVXLANDataplane{
+				links: []netlink.Link{&mockLink{attrs: netlink.LinkAttrs{Name: "eth0"}}},
+			},
+			6,
+			func(interfacePrefixes []string, ipVersion uint8, vxlan bool, netlinkTimeout time.Duration,
+				deviceRouteSourceAddress net.IP, deviceRouteProtocol int, removeExternalRoutes bool) routeTable {
+				prtIPVersion = ipVersion
+				return prt
+			},
+		)
+
+		manager.OnUpdate(&proto.VXLANTunnelEndpointUpdate{
+			Node:             "node1",
+			Mac:              "00:0a:74:9d:68:16",
+			Ipv4Addr:         "10.0.0.0",
+			ParentDeviceIp:   "172.0.0.2",
+			MacV6:            "00:0a:74:9d:68:26",
+			Ipv6Addr:         "fc00:10:10::",
+			ParentDeviceIpv6: "fc00:0:0:2::2",
+		})
+		manager.OnUpdate(&proto.VXLANTunnelEndpointUpdate{
+			Node:             "node2",
+			MacV6:            "00:0a:95:9d:68:26",
+			Ipv6Addr:         "fc00:10:10:1::",
+			ParentDeviceIpv6: "fc00:0:0:12::1",
+		})
+		// node3 only has an IPv4 VTEP.
+		manager.OnUpdate(&proto.VXLANTunnelEndpointUpdate{
+			Node:           "node3",
+			Mac:            "00:0a:95:9d:68:36",
+			Ipv4Addr:       "10.0.90.0",
+			ParentDeviceIp: "172.0.13.1",
+		})
+	})
+
+	It("configures the device with the IPv6 half of the local VTEP", func() {
+		go manager.KeepVXLANDeviceInSync(1400, 1*time.Second)
+		Eventually(manager.getNoEncapRouteTable).ShouldNot(BeNil())
+		Expect(prtIPVersion).To(Equal(uint8(6)))
+
+		parent, err := manager.getLocalVTEPParent()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parent.Attrs().Name).To(Equal("eth0"))
+	})
+
+	It("programs IPv6 routes and VTEPs only", func() {
+		manager.noEncapRouteTable = prt
+
+		manager.OnUpdate(&proto.RouteUpdate{
+			Type:        proto.RouteType_REMOTE_WORKLOAD,
+			IpPoolType:  proto.IPPoolType_VXLAN,
+			Dst:         "fc00:10:10:1::/122",
+			DstNodeName: "node2",
+			DstNodeIp:   "fc00:0:0:12::1",
+		})
+		manager.OnUpdate(&proto.RouteUpdate{
+			Type:        proto.RouteType_REMOTE_WORKLOAD,
+			IpPoolType:  proto.IPPoolType_VXLAN,
+			Dst:         "fc00:10:10:2::/122",
+			DstNodeName: "node2",
+			DstNodeIp:   "fc00:0:0:12::1",
+			SameSubnet:  true,
+		})
+		manager.OnUpdate(&proto.RouteUpdate{
+			Type:        proto.RouteType_REMOTE_WORKLOAD,
+			IpPoolType:  proto.IPPoolType_VXLAN,
+			Dst:         "172.0.1.0/26",
+			DstNodeName: "node3",
+			DstNodeIp:   "172.0.13.1",
+		})
+		// No IPv6 VTEP for node3 so this route can't be programmed.
+		manager.OnUpdate(&proto.RouteUpdate{
+			Type:        proto.RouteType_REMOTE_WORKLOAD,
+			IpPoolType:  proto.IPPoolType_VXLAN,
+			Dst:         "fc00:10:10:3::/122",
+			DstNodeName: "node3",
+		})
+
+		err := manager.CompleteDeferredWork()
+		Expect(err).NotTo(HaveOccurred())
+
+		rt.checkRoutes("vxlan-v6.calico", []routetable.Target{{
+			Type: routetable.TargetTypeVXLAN,
+			CIDR: ip.MustParseCIDROrIP("fc00:10:10:1::/122"),
+			GW:   ip.FromString("fc00:10:10:1::"),
+		}})
+		prt.checkRoutes("eth0", []routetable.Target{{
+			Type: routetable.TargetTypeNoEncap,
+			CIDR: ip.MustParseCIDROrIP("fc00:10:10:2::/122"),
+			GW:   ip.FromString("fc00:0:0:12::1"),
+		}})
+
+		mac, err := net.ParseMAC("00:0a:95:9d:68:26")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rt.currentL2Routes["vxlan-v6.calico"]).To(Equal([]routetable.L2Target{{
+			VTEPMAC: mac,
+			GW:      ip.FromString("fc00:10:10:1::"),
+			IP:      ip.FromString("fc00:0:0:12::1"),
+		}}))
+
+		Expect(ipSets.Members[rules.IPSetIDAllVXLANSourceNets]).To(Equal(
+			set.From("fc00:0:0:99::/64", "fc00:0:0:12::1")))
+	})
 })
@@ -15,6 +15,8 @@
 package intdataplane
 
 import (
+	"time"
+
 	log "github.com/sirupsen/logrus"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 
@@ -37,15 +39,23 @@ import (
 type wireguardManager struct {
 	// Our dependencies.
 	wireguardRouteTable *wireguard.Wireguard
+
+	// Whether host addresses are routed over wireguard.
+	hostEncryptionEnabled bool
 }
 
+// wireguardMetricsRefreshInterval is the interval at which the per-peer wireguard metrics are refreshed.
+const wireguardMetricsRefreshInterval = 10 * time.Second
+
 type WireguardStatusUpdateCallback func(ipVersion uint8, id interface{}, status string)
 
 func newWireguardManager(
 	wireguardRouteTable *wireguard.Wireguard,
+	config wireguard.Config,
 ) *wireguardManager {
 	return &wireguardManager{
-		wireguardRouteTable: wireguardRouteTable,
+		wireguardRouteTable:   wireguardRouteTable,
+		hostEncryptionEnabled: config.HostEncryptionEnabled,
 	}
 }
 
@@ -75,6 +85,17 @@ func (m *wireguardManager) OnUpdate(protoBufMsg interface{}) {
 			// to and from these addresses when both nodes support wireguard).
 			log.Debug("RouteUpdate is a tunnel update")
 			m.wireguardRouteTable.RouteUpdate(msg.DstNodeName, cidr)
+		case proto.RouteType_LOCAL_HOST, proto.RouteType_REMOTE_HOST:
+			if !m.hostEncryptionEnabled {
+				log.Debug("RouteUpdate is a host update and host encryption is disabled, treating as a deletion")
+				m.wireguardRouteTable.RouteRemove(cidr)
+				return
+			}
+			// CIDR is for a host address. With host encryption enabled we treat host addresses like workloads, so
+			// that host-networked traffic between wireguard nodes is encrypted. The wireguard UDP traffic to the
+			// peers' host addresses is marked, and the routing rule excludes it from the wireguard table.
+			log.Debug("RouteUpdate is a host update")
+			m.wireguardRouteTable.RouteUpdate(msg.DstNodeName, cidr)
 		default:
 			// It is not a workload CIDR - treat this as a route deletion.
 			log.Debug("RouteUpdate is not a workload update, treating as a deletion")
@@ -87,7 +108,7 @@ func (m *wireguardManager) OnUpdate(protoBufMsg interface{}) {
 			log.Errorf("error parsing RouteUpdate CIDR: %s", msg.Dst)
 			return
 		}
-		log.Debugf("Route removal for IPv4 CIDR: %s", cidr)
+		log.Debugf("Route removal for CIDR: %s", cidr)
 		m.wireguardRouteTable.RouteRemove(cidr)
 	case *proto.WireguardEndpointUpdate:
 		log.WithField("msg", msg).Debug("WireguardEndpointUpdate update")
@@ -95,24 +116,36 @@ func (m *wireguardManager) OnUpdate(protoBufMsg interface{}) {
 		if err != nil {
 			log.WithError(err).Errorf("error parsing wireguard public key %s for node %s", msg.PublicKey, msg.Hostname)
 		}
-		var ifaceAddr ip.Addr
-		if msg.InterfaceIpv4Addr != "" {
-			addr := ip.FromString(msg.InterfaceIpv4Addr)
-			if addr == nil {
-				// Unable to parse the wireguard interface address. We can still enable wireguard without this, so treat as
-				// an update with no interface address.
-				log.WithError(err).Errorf("error parsing wireguard interface address %s for node %s", msg.InterfaceIpv4Addr, msg.Hostname)
-			} else if addr.Version() == 4 {
-				ifaceAddr = addr
-			}
-		}
-		m.wireguardRouteTable.EndpointWireguardUpdate(msg.Hostname, key, ifaceAddr)
+		ipv4IfaceAddr := parseWireguardInterfaceAddr(msg.Hostname, msg.InterfaceIpv4Addr, 4)
+		ipv6IfaceAddr := parseWireguardInterfaceAddr(msg.Hostname, msg.InterfaceIpv6Addr, 6)
+		m.wireguardRouteTable.EndpointWireguardUpdate(msg.Hostname, key, ipv4IfaceAddr, ipv6IfaceAddr)
 	case *proto.WireguardEndpointRemove:
 		log.WithField("msg", msg).Debug("WireguardEndpointRemove update")
 		m.wireguardRouteTable.EndpointWireguardRemove(msg.Hostname)
 	}
 }
 
+// parseWireguardInterfaceAddr parses a wireguard interface address of the required IP version. Unable to parse the
+// address is not fatal: we can still enable wireguard without it, so treat it as an update with no interface address.
+func parseWireguardInterfaceAddr(hostname, addrStr string, ipVersion uint8) ip.Addr {
+	if addrStr == "" {
+		return nil
+	}
+	addr := ip.FromString(addrStr)
+	if addr == nil {
+		log.Errorf("error parsing wireguard interface address %s for node %s", addrStr, hostname)
+		return nil
+	} else if addr.Version() != ipVersion {
+		return nil
+	}
+	return addr
+}
+
+// RefreshMetrics updates the per-peer wireguard metrics from the wireguard device.
+func (m *wireguardManager) RefreshMetrics() {
+	m.wireguardRouteTable.UpdatePeerMetrics()
+}
+
 func (m *wireguardManager) CompleteDeferredWork() error {
 	// Dataplane programming is handled through the routetable interface.
 	return nil
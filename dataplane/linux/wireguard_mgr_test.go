@@ -0,0 +1,147 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intdataplane
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/projectcalico/felix/ifacemonitor"
+	"github.com/projectcalico/felix/ip"
+	"github.com/projectcalico/felix/logutils"
+	mocknetlink "github.com/projectcalico/felix/netlinkshim/mocknetlink"
+	"github.com/projectcalico/felix/proto"
+	"github.com/projectcalico/felix/timeshim/mocktime"
+	"github.com/projectcalico/felix/wireguard"
+)
+
+var _ = Describe("Wireguard manager", func() {
+	const (
+		ifaceName = "wireguard-mgr"
+		peer      = "peer-node"
+	)
+	var (
+		wgDataplane, rtDataplane, rrDataplane *mocknetlink.MockNetlinkDataplane
+		wg                                    *wireguard.Wireguard
+		peerKey                               wgtypes.Key
+	)
+
+	newManager := func(hostEncryptionEnabled bool) *wireguardManager {
+		wgDataplane = mocknetlink.New()
+		rtDataplane = mocknetlink.New()
+		rrDataplane = mocknetlink.New()
+		config := wireguard.Config{
+			Enabled:               true,
+			ListeningPort:         1000,
+			FirewallMark:          10,
+			RoutingRulePriority:   98,
+			RoutingTableIndex:     99,
+			InterfaceName:         ifaceName,
+			MTU:                   2000,
+			HostEncryptionEnabled: hostEncryptionEnabled,
+		}
+		wg = wireguard.NewWithShims(
+			"this-node",
+			&config,
+			rtDataplane.NewMockNetlink,
+			rrDataplane.NewMockNetlink,
+			wgDataplane.NewMockNetlink,
+			wgDataplane.NewMockWireguard,
+			10*time.Second,
+			mocktime.New(),
+			syscall.RTPROT_BOOT,
+			func(wgtypes.Key, ip.Addr) error { return nil },
+			logutils.NewSummarizer("test loop"),
+		)
+		Expect(wg.Apply()).NotTo(HaveOccurred())
+		wgDataplane.SetIface(ifaceName, true, true)
+		wg.OnIfaceStateChanged(ifaceName, ifacemonitor.StateUp)
+		Expect(wg.Apply()).NotTo(HaveOccurred())
+		// Update the mock routing table dataplane so that it knows about the wireguard interface.
+		rtDataplane.NameToLink[ifaceName] = wgDataplane.NameToLink[ifaceName]
+		return newWireguardManager(wg, config)
+	}
+
+	sendPeerUpdates := func(m *wireguardManager) {
+		key, err := wgtypes.GeneratePrivateKey()
+		Expect(err).NotTo(HaveOccurred())
+		peerKey = key.PublicKey()
+		m.OnUpdate(&proto.HostMetadataUpdate{Hostname: peer, Ipv4Addr: "10.0.0.2"})
+		m.OnUpdate(&proto.WireguardEndpointUpdate{Hostname: peer, PublicKey: peerKey.String()})
+		m.OnUpdate(&proto.RouteUpdate{
+			Type:        proto.RouteType_REMOTE_WORKLOAD,
+			Dst:         "192.168.1.0/26",
+			DstNodeName: peer,
+		})
+		m.OnUpdate(&proto.RouteUpdate{
+			Type:        proto.RouteType_REMOTE_HOST,
+			Dst:         "10.0.0.2/32",
+			DstNodeName: peer,
+		})
+		m.OnUpdate(&proto.RouteUpdate{
+			Type:        proto.RouteType_LOCAL_HOST,
+			Dst:         "10.0.0.1/32",
+			DstNodeName: "this-node",
+		})
+		Expect(wg.Apply()).NotTo(HaveOccurred())
+	}
+
+	routeKey := func(cidr string) string {
+		return fmt.Sprintf("%d-%d-%s", 99, wgDataplane.NameToLink[ifaceName].LinkAttrs.Index, cidr)
+	}
+	throwRouteKey := func(cidr string) string {
+		return fmt.Sprintf("%d-%d-%s", 99, 0, cidr)
+	}
+
+	peerAllowedIPs := func() []net.IPNet {
+		link := wgDataplane.NameToLink[ifaceName]
+		Expect(link.WireguardPeers).To(HaveKey(peerKey))
+		return link.WireguardPeers[peerKey].AllowedIPs
+	}
+
+	It("should only route workload addresses when host encryption is disabled", func() {
+		m := newManager(false)
+		sendPeerUpdates(m)
+		Expect(peerAllowedIPs()).To(ConsistOf(ip.MustParseCIDROrIP("192.168.1.0/26").ToIPNet()))
+		Expect(rtDataplane.RouteKeyToRoute).To(HaveKey(routeKey("192.168.1.0/26")))
+		Expect(rtDataplane.RouteKeyToRoute).NotTo(HaveKey(routeKey("10.0.0.2/32")))
+	})
+
+	It("should route host addresses over wireguard when host encryption is enabled", func() {
+		m := newManager(true)
+		sendPeerUpdates(m)
+		Expect(peerAllowedIPs()).To(ConsistOf(
+			ip.MustParseCIDROrIP("192.168.1.0/26").ToIPNet(),
+			ip.MustParseCIDROrIP("10.0.0.2/32").ToIPNet(),
+		))
+		Expect(rtDataplane.RouteKeyToRoute).To(HaveKey(routeKey("10.0.0.2/32")))
+
+		// Our own host address is a throw route so that it uses the main table.
+		Expect(rtDataplane.RouteKeyToRoute).To(HaveKey(throwRouteKey("10.0.0.1/32")))
+		Expect(rtDataplane.RouteKeyToRoute[throwRouteKey("10.0.0.1/32")].Type).To(Equal(syscall.RTN_THROW))
+
+		By("removing the host route")
+		m.OnUpdate(&proto.RouteRemove{Dst: "10.0.0.2/32"})
+		Expect(wg.Apply()).NotTo(HaveOccurred())
+		Expect(peerAllowedIPs()).To(ConsistOf(ip.MustParseCIDROrIP("192.168.1.0/26").ToIPNet()))
+		Expect(rtDataplane.RouteKeyToRoute).NotTo(HaveKey(routeKey("10.0.0.2/32")))
+	})
+})
@@ -399,16 +399,18 @@ func (d *MockDataplane) OnEvent(event interface{}) {
 		delete(d.namespaces, id)
 	case *proto.RouteUpdate:
 		d.activeRoutes.Iter(func(item interface{}) error {
-			r := item.(proto.RouteUpdate)
+			r := item.(*proto.RouteUpdate)
 			if event.Dst == r.Dst {
 				return set.RemoveItem
 			}
 			return nil
 		})
-		d.activeRoutes.Add(*event)
+		// RouteUpdate has a repeated field, so it can't be a set item itself; store a copy.
+		r := *event
+		d.activeRoutes.Add(&r)
 	case *proto.RouteRemove:
 		d.activeRoutes.Iter(func(item interface{}) error {
-			r := item.(proto.RouteUpdate)
+			r := item.(*proto.RouteUpdate)
 			if event.Dst == r.Dst {
 				return set.RemoveItem
 			}
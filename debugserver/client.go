@@ -0,0 +1,66 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debugserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/projectcalico/felix/calc"
+)
+
+// QueryExplain sends an /explain request to the debug server at the given host:port address.
+func QueryExplain(addr string, query url.Values) (*calc.Explanation, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	u := url.URL{Scheme: "http", Host: addr, Path: "/explain", RawQuery: query.Encode()}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("debug server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var explanation calc.Explanation
+	if err := json.Unmarshal(body, &explanation); err != nil {
+		return nil, fmt.Errorf("failed to parse debug server response: %w", err)
+	}
+	return &explanation, nil
+}
+
+// WriteExplanation writes a human-readable rendering of the explanation to w.
+func WriteExplanation(w io.Writer, explanation *calc.Explanation) {
+	for _, epExp := range []*calc.EndpointExplanation{explanation.Egress, explanation.Ingress} {
+		if epExp == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s policy for %s:\n", strings.Title(epExp.Direction), epExp.Endpoint)
+		for _, step := range epExp.Steps {
+			fmt.Fprintf(w, "  %s\n", step)
+		}
+		fmt.Fprintf(w, "  => %s (%s)\n", epExp.Verdict, epExp.Reason)
+	}
+	fmt.Fprintf(w, "Verdict: %s\n", explanation.Verdict)
+}
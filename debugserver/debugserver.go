@@ -0,0 +1,232 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package debugserver implements Felix's debug HTTP server, which exposes read-only views of
+// Felix's internal state for troubleshooting.
+package debugserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/calc"
+)
+
+// Explainer is implemented by the AsyncCalcGraph.
+type Explainer interface {
+	Explain(req *calc.ExplainRequest) (*calc.Explanation, error)
+}
+
+// StateFunc returns a JSON-serializable snapshot of some component's state.  It may be called
+// concurrently from multiple goroutines.
+type StateFunc func() interface{}
+
+type Server struct {
+	host       string
+	port       int
+	socketPath string
+	mux        *http.ServeMux
+
+	stateLock sync.Mutex
+	stateFns  map[string]StateFunc
+}
+
+// New creates a debug server.  If socketPath is non-empty then the server also listens on a Unix
+// socket at that path.
+func New(host string, port int, socketPath string) *Server {
+	s := &Server{
+		host:       host,
+		port:       port,
+		socketPath: socketPath,
+		mux:        http.NewServeMux(),
+		stateFns:   map[string]StateFunc{},
+	}
+	s.mux.HandleFunc("/state", s.handleAllState)
+	return s
+}
+
+// RegisterState adds a state snapshot, which is served at /state/<name> and also included in
+// the combined output at /state.
+func (s *Server) RegisterState(name string, fn StateFunc) {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+	s.stateFns[name] = fn
+	s.mux.HandleFunc("/state/"+name, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, fn())
+	})
+}
+
+func (s *Server) handleAllState(w http.ResponseWriter, r *http.Request) {
+	s.stateLock.Lock()
+	fns := make(map[string]StateFunc, len(s.stateFns))
+	for name, fn := range s.stateFns {
+		fns[name] = fn
+	}
+	s.stateLock.Unlock()
+
+	state := map[string]interface{}{}
+	for name, fn := range fns {
+		state[name] = fn()
+	}
+	writeJSON(w, state)
+}
+
+// RegisterExplainer adds the /explain endpoint, which evaluates a packet against the current
+// policy.
+func (s *Server) RegisterExplainer(explainer Explainer) {
+	s.mux.HandleFunc("/explain", func(w http.ResponseWriter, r *http.Request) {
+		req, err := ParseExplainQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		explanation, err := explainer.Explain(req)
+		if err == calc.ErrNoLocalEndpoint {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, explanation)
+	})
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Start starts the server in a background goroutine.  If the server fails, it is restarted.
+func (s *Server) Start() {
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	log.WithField("addr", addr).Info("Starting debug server")
+	go s.serveForever("tcp", addr)
+	if s.socketPath != "" {
+		log.WithField("path", s.socketPath).Info("Starting debug server on Unix socket")
+		go s.serveForever("unix", s.socketPath)
+	}
+}
+
+func (s *Server) serveForever(network, addr string) {
+	for {
+		err := s.serve(network, addr)
+		log.WithError(err).WithField("addr", addr).Error("Debug server failed, trying to restart it...")
+		time.Sleep(1 * time.Second)
+	}
+}
+
+func (s *Server) serve(network, addr string) error {
+	if network == "unix" {
+		// Clean up the socket from any previous run.
+		if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	if network == "unix" {
+		// The state that we expose isn't secret but there's no need for anyone other than
+		// root to see it.
+		if err := os.Chmod(addr, 0600); err != nil {
+			_ = l.Close()
+			return err
+		}
+	}
+	return http.Serve(l, s.mux)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.WithError(err).Warn("Failed to write debug server response")
+	}
+}
+
+var protocolNumbers = map[string]uint8{
+	"icmp":    1,
+	"tcp":     6,
+	"udp":     17,
+	"icmpv6":  58,
+	"sctp":    132,
+	"udplite": 136,
+}
+
+// ParseExplainQuery converts the query parameters of an /explain request into an ExplainRequest.
+// The supported parameters are src, dst, protocol (name or number; defaults to tcp), sport,
+// dport, icmptype and icmpcode.
+func ParseExplainQuery(q url.Values) (*calc.ExplainRequest, error) {
+	req := &calc.ExplainRequest{
+		SrcIP: net.ParseIP(q.Get("src")),
+		DstIP: net.ParseIP(q.Get("dst")),
+	}
+	if req.SrcIP == nil {
+		return nil, fmt.Errorf("invalid or missing src IP %q", q.Get("src"))
+	}
+	if req.DstIP == nil {
+		return nil, fmt.Errorf("invalid or missing dst IP %q", q.Get("dst"))
+	}
+
+	protoStr := strings.ToLower(q.Get("protocol"))
+	if protoStr == "" {
+		protoStr = "tcp"
+	}
+	if num, ok := protocolNumbers[protoStr]; ok {
+		req.Protocol = num
+	} else if num, err := strconv.ParseUint(protoStr, 10, 8); err == nil {
+		req.Protocol = uint8(num)
+	} else {
+		return nil, fmt.Errorf("invalid protocol %q", protoStr)
+	}
+
+	for _, p := range []struct {
+		name string
+		dest *uint16
+	}{{"sport", &req.SrcPort}, {"dport", &req.DstPort}} {
+		if s := q.Get(p.name); s != "" {
+			port, err := strconv.ParseUint(s, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", p.name, s)
+			}
+			*p.dest = uint16(port)
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dest **int
+	}{{"icmptype", &req.ICMPType}, {"icmpcode", &req.ICMPCode}} {
+		if s := q.Get(p.name); s != "" {
+			v, err := strconv.ParseUint(s, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", p.name, s)
+			}
+			i := int(v)
+			*p.dest = &i
+		}
+	}
+	return req, nil
+}
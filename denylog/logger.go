@@ -0,0 +1,197 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package denylog writes a structured JSON record for each packet that the policy chains deny.
+//
+// The iptables deny rules (and the drops at the end of the endpoint chains) copy the packets
+// that they drop to an NFLOG group, with a prefix that carries the ID of the rule.  The Logger
+// decodes the packets, maps the rule ID back to its policy or profile and the addresses back to
+// the local workload endpoints, and rate limits the records so that a flood of denied packets
+// can't swamp the disk.
+package denylog
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/flowlogs"
+	"github.com/projectcalico/felix/ruleindex"
+)
+
+// denyPrefix is the prefix of the NFLOG messages for denied packets.  It must match the prefix
+// that the rules package renders.
+const denyPrefix = "D|"
+
+const ActionDeny = "deny"
+
+var counterRecordsSuppressed = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "felix_denied_packet_log_records_suppressed",
+	Help: "Number of denied-packet log records that were dropped by rate limiting.",
+})
+
+func init() {
+	prometheus.MustRegister(counterRecordsSuppressed)
+}
+
+// Record describes one denied packet.
+type Record struct {
+	Time time.Time `json:"time"`
+
+	Proto   uint8  `json:"proto"`
+	SrcIP   string `json:"src_ip"`
+	DstIP   string `json:"dst_ip"`
+	SrcPort uint16 `json:"src_port"`
+	DstPort uint16 `json:"dst_port"`
+
+	// SrcEndpoint and DstEndpoint name the local workload endpoints at either end, if any.  For
+	// Kubernetes pods, the namespace is split out into SrcNamespace/DstNamespace.
+	SrcEndpoint  string `json:"src_endpoint,omitempty"`
+	SrcNamespace string `json:"src_namespace,omitempty"`
+	DstEndpoint  string `json:"dst_endpoint,omitempty"`
+	DstNamespace string `json:"dst_namespace,omitempty"`
+
+	// Tier and Policy, or Profile, identify the rule that denied the packet.  They are all empty
+	// if the packet was dropped because no policy or profile allowed it, or if the rule's policy
+	// has been removed since.
+	Tier      string `json:"tier,omitempty"`
+	Policy    string `json:"policy,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	Direction string `json:"direction,omitempty"`
+	RuleID    string `json:"rule_id,omitempty"`
+	Action    string `json:"action"`
+
+	// Suppressed is the number of records that were dropped by rate limiting since the
+	// previous record.
+	Suppressed uint64 `json:"suppressed,omitempty"`
+}
+
+type endpointInfo struct {
+	name      string
+	namespace string
+}
+
+// Logger turns denied-packet NFLOG messages into Records.  It is safe for concurrent use.
+type Logger struct {
+	lock sync.Mutex
+
+	// rules attributes denied packets to rules and addresses to endpoints.
+	rules *ruleindex.Index
+
+	// The rate limiter is a token bucket that holds up to one second's worth of records.
+	ratePerSec float64
+	tokens     float64
+	lastRefill time.Time
+	suppressed uint64
+}
+
+func NewLogger(ratePerSec int, now time.Time) *Logger {
+	return &Logger{
+		rules:      ruleindex.New(),
+		ratePerSec: float64(ratePerSec),
+		tokens:     float64(ratePerSec),
+		lastRefill: now,
+	}
+}
+
+// OnUpdate processes a message from the calculation graph, picking out the policies, profiles
+// and endpoints.
+func (l *Logger) OnUpdate(msg interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.rules.OnUpdate(msg)
+}
+
+// endpointInfoFor returns the name and namespace of the local workload endpoint with the given
+// IP, if any.  Kubernetes workload IDs have the form "<namespace>/<pod name>".
+func (l *Logger) endpointInfoFor(ip string) endpointInfo {
+	id, ok := l.rules.Endpoint(ip)
+	if !ok {
+		return endpointInfo{}
+	}
+	if id.OrchestratorId == "k8s" {
+		if parts := strings.SplitN(id.WorkloadId, "/", 2); len(parts) == 2 {
+			return endpointInfo{namespace: parts[0], name: parts[1]}
+		}
+	}
+	return endpointInfo{name: id.WorkloadId}
+}
+
+// OnDeniedPacket returns the Record for a denied packet with the given flow key and NFLOG
+// prefix.  It returns false if the prefix isn't a deny prefix or if the record was dropped by
+// rate limiting.
+func (l *Logger) OnDeniedPacket(key flowlogs.FlowKey, prefix string, now time.Time) (Record, bool) {
+	if !strings.HasPrefix(prefix, denyPrefix) {
+		log.WithField("prefix", prefix).Debug("Ignoring NFLOG message with unknown prefix.")
+		return Record{}, false
+	}
+	ruleID := prefix[len(denyPrefix):]
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !l.takeToken(now) {
+		l.suppressed++
+		counterRecordsSuppressed.Inc()
+		return Record{}, false
+	}
+
+	src := l.endpointInfoFor(key.SrcIP)
+	dst := l.endpointInfoFor(key.DstIP)
+	rule, _ := l.rules.Rule(ruleID)
+	r := Record{
+		Time: now,
+
+		Proto:   key.Proto,
+		SrcIP:   key.SrcIP,
+		DstIP:   key.DstIP,
+		SrcPort: key.SrcPort,
+		DstPort: key.DstPort,
+
+		SrcEndpoint:  src.name,
+		SrcNamespace: src.namespace,
+		DstEndpoint:  dst.name,
+		DstNamespace: dst.namespace,
+
+		Tier:      rule.Tier,
+		Policy:    rule.Policy,
+		Profile:   rule.Profile,
+		Direction: rule.Direction,
+		RuleID:    ruleID,
+		Action:    ActionDeny,
+
+		Suppressed: l.suppressed,
+	}
+	l.suppressed = 0
+	return r, true
+}
+
+func (l *Logger) takeToken(now time.Time) bool {
+	if elapsed := now.Sub(l.lastRefill); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.ratePerSec
+		if l.tokens > l.ratePerSec {
+			l.tokens = l.ratePerSec
+		}
+		l.lastRefill = now
+	}
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package denylog_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/projectcalico/felix/denylog"
+	"github.com/projectcalico/felix/flowlogs"
+	"github.com/projectcalico/felix/proto"
+)
+
+var _ = Describe("Denied packet logger", func() {
+	var (
+		l     *Logger
+		start time.Time
+		key   flowlogs.FlowKey
+	)
+
+	BeforeEach(func() {
+		start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		l = NewLogger(2, start)
+		key = flowlogs.FlowKey{Proto: 6, SrcIP: "10.0.0.1", DstIP: "10.0.0.2", SrcPort: 40000, DstPort: 80}
+
+		l.OnUpdate(&proto.ActivePolicyUpdate{
+			Id: &proto.PolicyID{Tier: "default", Name: "deny-web"},
+			Policy: &proto.Policy{
+				InboundRules: []*proto.Rule{
+					{Action: "allow", RuleId: "rule-allow"},
+					{Action: "deny", RuleId: "rule-deny"},
+				},
+			},
+		})
+		l.OnUpdate(&proto.ActiveProfileUpdate{
+			Id: &proto.ProfileID{Name: "prof"},
+			Profile: &proto.Profile{
+				OutboundRules: []*proto.Rule{{Action: "deny", RuleId: "rule-prof"}},
+			},
+		})
+		l.OnUpdate(&proto.WorkloadEndpointUpdate{
+			Id: &proto.WorkloadEndpointID{
+				OrchestratorId: "k8s",
+				WorkloadId:     "default/client",
+				EndpointId:     "eth0",
+			},
+			Endpoint: &proto.WorkloadEndpoint{Ipv4Nets: []string{"10.0.0.1/32"}},
+		})
+		l.OnUpdate(&proto.WorkloadEndpointUpdate{
+			Id: &proto.WorkloadEndpointID{
+				OrchestratorId: "openstack",
+				WorkloadId:     "vm1",
+				EndpointId:     "eth0",
+			},
+			Endpoint: &proto.WorkloadEndpoint{Ipv4Nets: []string{"10.0.0.2/32"}},
+		})
+	})
+
+	It("should attribute a packet denied by a policy rule", func() {
+		r, ok := l.OnDeniedPacket(key, "D|rule-deny", start)
+		Expect(ok).To(BeTrue())
+		Expect(r).To(Equal(Record{
+			Time:         start,
+			Proto:        6,
+			SrcIP:        "10.0.0.1",
+			DstIP:        "10.0.0.2",
+			SrcPort:      40000,
+			DstPort:      80,
+			SrcEndpoint:  "client",
+			SrcNamespace: "default",
+			DstEndpoint:  "vm1",
+			Tier:         "default",
+			Policy:       "deny-web",
+			Direction:    "ingress",
+			RuleID:       "rule-deny",
+			Action:       ActionDeny,
+		}))
+	})
+
+	It("should attribute a packet denied by a profile rule", func() {
+		r, ok := l.OnDeniedPacket(key, "D|rule-prof", start)
+		Expect(ok).To(BeTrue())
+		Expect(r.Profile).To(Equal("prof"))
+		Expect(r.Direction).To(Equal("egress"))
+		Expect(r.Policy).To(BeEmpty())
+	})
+
+	It("should record default denies without a rule", func() {
+		r, ok := l.OnDeniedPacket(key, "D|", start)
+		Expect(ok).To(BeTrue())
+		Expect(r.RuleID).To(BeEmpty())
+		Expect(r.Policy).To(BeEmpty())
+		Expect(r.Action).To(Equal(ActionDeny))
+	})
+
+	It("should keep the rule ID of removed policies", func() {
+		l.OnUpdate(&proto.ActivePolicyRemove{Id: &proto.PolicyID{Tier: "default", Name: "deny-web"}})
+		r, ok := l.OnDeniedPacket(key, "D|rule-deny", start)
+		Expect(ok).To(BeTrue())
+		Expect(r.RuleID).To(Equal("rule-deny"))
+		Expect(r.Policy).To(BeEmpty())
+	})
+
+	It("should forget removed endpoints", func() {
+		l.OnUpdate(&proto.WorkloadEndpointRemove{
+			Id: &proto.WorkloadEndpointID{
+				OrchestratorId: "k8s",
+				WorkloadId:     "default/client",
+				EndpointId:     "eth0",
+			},
+		})
+		r, ok := l.OnDeniedPacket(key, "D|rule-deny", start)
+		Expect(ok).To(BeTrue())
+		Expect(r.SrcEndpoint).To(BeEmpty())
+		Expect(r.SrcNamespace).To(BeEmpty())
+	})
+
+	It("should ignore NFLOG messages with other prefixes", func() {
+		_, ok := l.OnDeniedPacket(key, "A|rule-allow", start)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should rate limit and report the number of suppressed records", func() {
+		for i := 0; i < 2; i++ {
+			_, ok := l.OnDeniedPacket(key, "D|rule-deny", start)
+			Expect(ok).To(BeTrue())
+		}
+		for i := 0; i < 3; i++ {
+			_, ok := l.OnDeniedPacket(key, "D|rule-deny", start.Add(100*time.Millisecond))
+			Expect(ok).To(BeFalse())
+		}
+
+		r, ok := l.OnDeniedPacket(key, "D|rule-deny", start.Add(600*time.Millisecond))
+		Expect(ok).To(BeTrue())
+		Expect(r.Suppressed).To(BeNumerically("==", 3))
+		r, ok = l.OnDeniedPacket(key, "D|rule-deny", start.Add(5*time.Second))
+		Expect(ok).To(BeTrue())
+		Expect(r.Suppressed).To(BeZero())
+	})
+})
+
+var _ = Describe("Denied packet log file", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "denylog")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	It("should append JSON lines, creating the directory", func() {
+		path := filepath.Join(dir, "sub", "denied.log")
+		records := []Record{
+			{SrcIP: "10.0.0.1", Action: ActionDeny, RuleID: "a"},
+			{SrcIP: "10.0.0.2", Action: ActionDeny, RuleID: "b"},
+		}
+		Expect(WriteRecords(path, records[:1])).To(Succeed())
+		Expect(WriteRecords(path, records[1:])).To(Succeed())
+		Expect(WriteRecords(path, nil)).To(Succeed())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		var decoded []Record
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var r Record
+			Expect(json.Unmarshal([]byte(line), &r)).To(Succeed())
+			decoded = append(decoded, r)
+		}
+		Expect(decoded).To(Equal(records))
+	})
+})
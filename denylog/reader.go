@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package denylog
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/flowlogs"
+	"github.com/projectcalico/felix/nfnetlink"
+)
+
+// copyRange is the amount of each packet that we ask the kernel to copy to us.  It covers the IP
+// header (with options) and the start of the L4 header.
+const copyRange = 128
+
+// Reader reads the denied packets that the policy chains send to an NFLOG group and appends
+// their Records to a file as JSON lines.
+type Reader struct {
+	group  uint16
+	logger *Logger
+	path   string
+}
+
+func NewReader(group uint16, logger *Logger, path string) *Reader {
+	return &Reader{
+		group:  group,
+		logger: logger,
+		path:   path,
+	}
+}
+
+// Start starts the reader's background goroutine.
+func (r *Reader) Start() {
+	go nfnetlink.LoopReadingNflog(r.group, copyRange, "denied packets", r.onPackets)
+}
+
+func (r *Reader) onPackets(pkts []nfnetlink.NflogPacket) {
+	now := time.Now()
+	var records []Record
+	for _, pkt := range pkts {
+		key, _, err := flowlogs.FlowKeyFromPacket(pkt.Payload)
+		if err != nil {
+			log.WithError(err).Debug("Failed to parse denied packet, ignoring.")
+			continue
+		}
+		if rec, ok := r.logger.OnDeniedPacket(key, pkt.Prefix, now); ok {
+			records = append(records, rec)
+		}
+	}
+	if err := WriteRecords(r.path, records); err != nil {
+		log.WithError(err).WithField("numRecords", len(records)).Warn(
+			"Failed to write denied-packet logs, dropping records.")
+	}
+}
+
+// WriteRecords appends the records to the file at path as JSON lines, creating the file and its
+// directory if needed.  The file is reopened for each batch so that it can be rotated by moving
+// it aside.
+func WriteRecords(path string, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(f)
+	enc := json.NewEncoder(buf)
+	for i := range records {
+		if err := enc.Encode(&records[i]); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnssnoop
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	ipProtoUDP = 17
+
+	ipv6HeaderLen = 40
+	udpHeaderLen  = 8
+
+	// maxCNAMEChain limits how far we follow a chain of CNAMEs within a single response.
+	maxCNAMEChain = 10
+)
+
+// AddrRecord records that a DNS response resolved Name to Addr.
+type AddrRecord struct {
+	// Name is the name that was looked up, lower-case and without the trailing dot.  If the
+	// response included CNAMEs, there is a record for each name in the chain.
+	Name string
+	// Addr is the IPv4 or IPv6 address, in its canonical string form.
+	Addr string
+	// TTL is the time for which the response allows the record to be cached.  For a name that
+	// was resolved via CNAMEs, it is the lowest TTL along the chain.
+	TTL time.Duration
+}
+
+// Message is a parsed DNS query or response.
+type Message struct {
+	ID       uint16
+	Response bool
+	// Question is the name in the message's first question, normalised like AddrRecord.Name.
+	Question string
+	// Records are the address records that a successful response gives for Question, either
+	// directly or via CNAMEs.  Answers for other names are ignored.
+	Records []AddrRecord
+}
+
+// Packet is a DNS message along with the addresses and ports of the UDP packet that carried it.
+type Packet struct {
+	Message
+	SrcIP, DstIP     net.IP
+	SrcPort, DstPort uint16
+}
+
+// ParsePacket parses an IP packet that carries a DNS message over UDP.
+func ParsePacket(pkt []byte) (*Packet, error) {
+	p, payload, err := parseUDP(pkt)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := ParseMessage(payload)
+	if err != nil {
+		return nil, err
+	}
+	p.Message = *msg
+	return p, nil
+}
+
+// parseUDP parses the headers of the given IPv4 or IPv6 UDP packet and returns its payload.  We
+// only handle the IPv6 packets that have no extension headers, which covers DNS in practice.
+func parseUDP(pkt []byte) (*Packet, []byte, error) {
+	if len(pkt) < 1 {
+		return nil, nil, errors.New("empty packet")
+	}
+	p := &Packet{}
+	var hdrLen int
+	switch pkt[0] >> 4 {
+	case 4:
+		if len(pkt) < 20 {
+			return nil, nil, errors.New("truncated IPv4 header")
+		}
+		hdrLen = int(pkt[0]&0xf) * 4
+		if pkt[9] != ipProtoUDP {
+			return nil, nil, errors.New("not a UDP packet")
+		}
+		p.SrcIP = append(net.IP(nil), pkt[12:16]...)
+		p.DstIP = append(net.IP(nil), pkt[16:20]...)
+	case 6:
+		if len(pkt) < ipv6HeaderLen {
+			return nil, nil, errors.New("truncated IPv6 header")
+		}
+		hdrLen = ipv6HeaderLen
+		if pkt[6] != ipProtoUDP {
+			return nil, nil, errors.New("not a UDP packet")
+		}
+		p.SrcIP = append(net.IP(nil), pkt[8:24]...)
+		p.DstIP = append(net.IP(nil), pkt[24:40]...)
+	default:
+		return nil, nil, errors.New("unknown IP version")
+	}
+	if len(pkt) < hdrLen+udpHeaderLen {
+		return nil, nil, errors.New("truncated UDP header")
+	}
+	p.SrcPort = binary.BigEndian.Uint16(pkt[hdrLen:])
+	p.DstPort = binary.BigEndian.Uint16(pkt[hdrLen+2:])
+	return p, pkt[hdrLen+udpHeaderLen:], nil
+}
+
+// ParseMessage parses a DNS message.  Failed lookups and queries (rather than responses) have
+// no records.
+func ParseMessage(msg []byte) (*Message, error) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(msg)
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{ID: hdr.ID, Response: hdr.Response}
+	q, err := p.Question()
+	if err == dnsmessage.ErrSectionDone {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.Question = normaliseName(q.Name.String())
+	if !hdr.Response || hdr.RCode != dnsmessage.RCodeSuccess {
+		return m, nil
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, err
+	}
+
+	// answer is the target of a CNAME or the address of an A/AAAA record, with its TTL.
+	type answer struct {
+		value string
+		ttl   time.Duration
+	}
+	cnames := map[string]answer{}
+	addrs := map[string][]answer{}
+	for {
+		rh, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := normaliseName(rh.Name.String())
+		ttl := time.Duration(rh.TTL) * time.Second
+		switch rh.Type {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return nil, err
+			}
+			addrs[name] = append(addrs[name], answer{net.IP(r.A[:]).String(), ttl})
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return nil, err
+			}
+			addrs[name] = append(addrs[name], answer{net.IP(r.AAAA[:]).String(), ttl})
+		case dnsmessage.TypeCNAME:
+			r, err := p.CNAMEResource()
+			if err != nil {
+				return nil, err
+			}
+			cnames[name] = answer{normaliseName(r.CNAME.String()), ttl}
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Follow the chain of CNAMEs from the question to the name that has the addresses.  Other
+	// answers weren't asked for so a response that includes them may be trying to poison us.
+	chain := []answer{{value: m.Question}}
+	for len(chain) <= maxCNAMEChain {
+		next, ok := cnames[chain[len(chain)-1].value]
+		if !ok {
+			break
+		}
+		chain = append(chain, next)
+	}
+	// Each name in the chain resolves to the addresses at the end of the chain, with the lowest
+	// TTL from that name onwards.
+	for _, a := range addrs[chain[len(chain)-1].value] {
+		ttl := a.ttl
+		for i := len(chain) - 1; i >= 0; i-- {
+			m.Records = append(m.Records, AddrRecord{Name: chain[i].value, Addr: a.value, TTL: ttl})
+			// chain[i].ttl is the TTL of the CNAME record that points to chain[i], which applies
+			// to the names before it.
+			if chain[i].ttl < ttl {
+				ttl = chain[i].ttl
+			}
+		}
+	}
+	return m, nil
+}
+
+func normaliseName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
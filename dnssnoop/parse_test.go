@@ -0,0 +1,277 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnssnoop_test
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/dns/dnsmessage"
+
+	. "github.com/projectcalico/felix/dnssnoop"
+)
+
+var _ = Describe("DNS response parsing", func() {
+	var (
+		server *fakeDNSServer
+		query  []byte
+	)
+
+	BeforeEach(func() {
+		server = startFakeDNSServer()
+		query = buildQuery("www.example.com.")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should extract A and AAAA records", func() {
+		server.answers = []dnsmessage.Resource{
+			aRecord("www.example.com.", 300, "10.0.0.1"),
+			aaaaRecord("www.example.com.", 60, "fd00::1"),
+		}
+		resp := server.exchange(query)
+
+		p, err := ParsePacket(ipv4UDPPacket(serverIP, clientIP, 53, clientPort, resp))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Response).To(BeTrue())
+		Expect(p.ID).To(Equal(uint16(1234)))
+		Expect(p.Question).To(Equal("www.example.com"))
+		Expect(p.Records).To(ConsistOf(
+			AddrRecord{Name: "www.example.com", Addr: "10.0.0.1", TTL: 300 * time.Second},
+			AddrRecord{Name: "www.example.com", Addr: "fd00::1", TTL: 60 * time.Second},
+		))
+	})
+
+	It("should extract the addresses and ports of the packet", func() {
+		p, err := ParsePacket(ipv4UDPPacket(clientIP, serverIP, clientPort, 53, query))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.SrcIP.String()).To(Equal(clientIP))
+		Expect(p.DstIP.String()).To(Equal(serverIP))
+		Expect(p.SrcPort).To(Equal(uint16(clientPort)))
+		Expect(p.DstPort).To(Equal(uint16(53)))
+
+		p, err = ParsePacket(ipv6UDPPacket("fd00::53", "fd00::2", 53, clientPort, query))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.SrcIP.String()).To(Equal("fd00::53"))
+		Expect(p.DstIP.String()).To(Equal("fd00::2"))
+		Expect(p.SrcPort).To(Equal(uint16(53)))
+		Expect(p.DstPort).To(Equal(uint16(clientPort)))
+	})
+
+	It("should resolve CNAME chains using the lowest TTL", func() {
+		server.answers = []dnsmessage.Resource{
+			cnameRecord("WWW.Example.com.", 30, "cdn.example.net."),
+			cnameRecord("cdn.example.net.", 600, "edge.example.net."),
+			aRecord("edge.example.net.", 120, "10.0.0.2"),
+		}
+		resp := server.exchange(query)
+
+		p, err := ParsePacket(ipv6UDPPacket("fd00::53", "fd00::2", 53, clientPort, resp))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Records).To(ConsistOf(
+			AddrRecord{Name: "edge.example.net", Addr: "10.0.0.2", TTL: 120 * time.Second},
+			AddrRecord{Name: "cdn.example.net", Addr: "10.0.0.2", TTL: 120 * time.Second},
+			AddrRecord{Name: "www.example.com", Addr: "10.0.0.2", TTL: 30 * time.Second},
+		))
+	})
+
+	It("should ignore answers for names that weren't asked about", func() {
+		server.answers = []dnsmessage.Resource{
+			aRecord("www.example.com.", 300, "10.0.0.1"),
+			aRecord("bank.example.org.", 300, "10.0.0.66"),
+			cnameRecord("login.example.org.", 300, "www.example.com."),
+		}
+		resp := server.exchange(query)
+
+		p, err := ParsePacket(ipv4UDPPacket(serverIP, clientIP, 53, clientPort, resp))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Records).To(ConsistOf(
+			AddrRecord{Name: "www.example.com", Addr: "10.0.0.1", TTL: 300 * time.Second},
+		))
+	})
+
+	It("should ignore failed lookups", func() {
+		server.rcode = dnsmessage.RCodeNameError
+		resp := server.exchange(query)
+
+		p, err := ParsePacket(ipv4UDPPacket(serverIP, clientIP, 53, clientPort, resp))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Response).To(BeTrue())
+		Expect(p.Records).To(BeEmpty())
+	})
+
+	It("should parse queries without records", func() {
+		m, err := ParseMessage(query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Response).To(BeFalse())
+		Expect(m.Question).To(Equal("www.example.com"))
+		Expect(m.Records).To(BeEmpty())
+	})
+
+	It("should reject non-UDP packets", func() {
+		pkt := ipv4UDPPacket(serverIP, clientIP, 53, clientPort, server.exchange(query))
+		pkt[9] = 6
+		_, err := ParsePacket(pkt)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject truncated packets", func() {
+		_, err := ParsePacket(ipv4UDPPacket(serverIP, clientIP, 53, clientPort, nil)[:24])
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+// fakeDNSServer answers every query on a local UDP socket with a fixed set of answers.
+type fakeDNSServer struct {
+	conn    net.PacketConn
+	answers []dnsmessage.Resource
+	rcode   dnsmessage.RCode
+}
+
+func startFakeDNSServer() *fakeDNSServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	return &fakeDNSServer{conn: conn}
+}
+
+// exchange sends the query to the server, which answers it on its own goroutine, and returns
+// the raw response.
+func (s *fakeDNSServer) exchange(query []byte) []byte {
+	go s.serveOne()
+
+	client, err := net.Dial("udp", s.conn.LocalAddr().String())
+	Expect(err).NotTo(HaveOccurred())
+	defer client.Close()
+	Expect(client.SetDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+	_, err = client.Write(query)
+	Expect(err).NotTo(HaveOccurred())
+	buf := make([]byte, 4096)
+	n, err := client.Read(buf)
+	Expect(err).NotTo(HaveOccurred())
+	return buf[:n]
+}
+
+func (s *fakeDNSServer) serveOne() {
+	defer GinkgoRecover()
+	buf := make([]byte, 4096)
+	n, addr, err := s.conn.ReadFrom(buf)
+	Expect(err).NotTo(HaveOccurred())
+
+	var p dnsmessage.Parser
+	hdr, err := p.Start(buf[:n])
+	Expect(err).NotTo(HaveOccurred())
+	q, err := p.Question()
+	Expect(err).NotTo(HaveOccurred())
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: hdr.ID, Response: true, RCode: s.rcode})
+	Expect(b.StartQuestions()).To(Succeed())
+	Expect(b.Question(q)).To(Succeed())
+	Expect(b.StartAnswers()).To(Succeed())
+	for _, a := range s.answers {
+		switch body := a.Body.(type) {
+		case *dnsmessage.AResource:
+			Expect(b.AResource(a.Header, *body)).To(Succeed())
+		case *dnsmessage.AAAAResource:
+			Expect(b.AAAAResource(a.Header, *body)).To(Succeed())
+		case *dnsmessage.CNAMEResource:
+			Expect(b.CNAMEResource(a.Header, *body)).To(Succeed())
+		}
+	}
+	resp, err := b.Finish()
+	Expect(err).NotTo(HaveOccurred())
+	_, err = s.conn.WriteTo(resp, addr)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func (s *fakeDNSServer) Close() {
+	_ = s.conn.Close()
+}
+
+func buildQuery(name string) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 1234, RecursionDesired: true})
+	Expect(b.StartQuestions()).To(Succeed())
+	Expect(b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	})).To(Succeed())
+	msg, err := b.Finish()
+	Expect(err).NotTo(HaveOccurred())
+	return msg
+}
+
+func rrHeader(name string, rrType dnsmessage.Type, ttl uint32) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  rrType,
+		Class: dnsmessage.ClassINET,
+		TTL:   ttl,
+	}
+}
+
+func aRecord(name string, ttl uint32, addr string) dnsmessage.Resource {
+	var r dnsmessage.AResource
+	copy(r.A[:], net.ParseIP(addr).To4())
+	return dnsmessage.Resource{Header: rrHeader(name, dnsmessage.TypeA, ttl), Body: &r}
+}
+
+func aaaaRecord(name string, ttl uint32, addr string) dnsmessage.Resource {
+	var r dnsmessage.AAAAResource
+	copy(r.AAAA[:], net.ParseIP(addr).To16())
+	return dnsmessage.Resource{Header: rrHeader(name, dnsmessage.TypeAAAA, ttl), Body: &r}
+}
+
+func cnameRecord(name string, ttl uint32, target string) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: rrHeader(name, dnsmessage.TypeCNAME, ttl),
+		Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(target)},
+	}
+}
+
+const (
+	clientIP   = "10.65.0.2"
+	clientPort = 34567
+	serverIP   = "10.96.0.10"
+)
+
+// ipv4UDPPacket wraps the payload in minimal IPv4 and UDP headers, as NFLOG would deliver it.
+// Only the fields that the parser looks at are filled in.
+func ipv4UDPPacket(src, dst string, srcPort, dstPort uint16, payload []byte) []byte {
+	pkt := make([]byte, 20+8, 20+8+len(payload))
+	pkt[0] = 0x45
+	pkt[9] = 17
+	copy(pkt[12:16], net.ParseIP(src).To4())
+	copy(pkt[16:20], net.ParseIP(dst).To4())
+	binary.BigEndian.PutUint16(pkt[20:], srcPort)
+	binary.BigEndian.PutUint16(pkt[22:], dstPort)
+	return append(pkt, payload...)
+}
+
+// ipv6UDPPacket wraps the payload in minimal IPv6 and UDP headers.
+func ipv6UDPPacket(src, dst string, srcPort, dstPort uint16, payload []byte) []byte {
+	pkt := make([]byte, 40+8, 40+8+len(payload))
+	pkt[0] = 0x60
+	pkt[6] = 17
+	copy(pkt[8:24], net.ParseIP(src).To16())
+	copy(pkt[24:40], net.ParseIP(dst).To16())
+	binary.BigEndian.PutUint16(pkt[40:], srcPort)
+	binary.BigEndian.PutUint16(pkt[42:], dstPort)
+	return append(pkt, payload...)
+}
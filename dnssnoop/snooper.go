@@ -0,0 +1,116 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnssnoop
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/nfnetlink"
+)
+
+const (
+	// copyRange is the amount of each packet that we ask the kernel to copy to us.  It covers
+	// the largest DNS-over-UDP response that a resolver will send with EDNS, plus headers,
+	// while keeping each message well inside the netlink socket's receive buffer.
+	copyRange = 16384
+
+	// queryTimeout is how long we wait for the response to a query.  Resolvers give up and
+	// retry well before this.
+	queryTimeout = 10 * time.Second
+)
+
+// Snooper listens for the DNS queries and responses that the dataplane copies to an NFLOG group
+// and sends the address records in the responses to its output channel.  The dataplane only
+// copies queries from workloads to trusted DNS servers and the replies to them; on top of that,
+// the snooper only accepts a response if it matches a query that it has seen, by client address
+// and port, DNS ID and question.  That stops a workload from injecting records by sending fake
+// responses to itself or to another workload.
+//
+// NFLOG is asynchronous: the response continues on to the workload while we parse our copy of
+// it.  The workload's first packets to a newly-resolved address may therefore race with the
+// update to the IP sets.
+type Snooper struct {
+	group   uint16
+	records chan<- []AddrRecord
+
+	// pendingQueries maps the queries that we've seen to the time at which we stop waiting
+	// for their responses.
+	pendingQueries map[queryKey]time.Time
+	time           func() time.Time
+}
+
+// queryKey identifies a query and its response, from the client's point of view.
+type queryKey struct {
+	clientIP   string
+	clientPort uint16
+	id         uint16
+	question   string
+}
+
+func NewSnooper(group uint16, records chan<- []AddrRecord) *Snooper {
+	return newSnooperWithShims(group, records, time.Now)
+}
+
+func newSnooperWithShims(group uint16, records chan<- []AddrRecord, now func() time.Time) *Snooper {
+	return &Snooper{
+		group:          group,
+		records:        records,
+		pendingQueries: map[queryKey]time.Time{},
+		time:           now,
+	}
+}
+
+// Start starts the snooper's background goroutine.
+func (s *Snooper) Start() {
+	go nfnetlink.LoopReadingNflog(s.group, copyRange, "DNS responses", s.onPackets)
+}
+
+// onPackets processes a batch of packets from the NFLOG group and sends the records from any
+// expected responses to the output channel.
+func (s *Snooper) onPackets(pkts []nfnetlink.NflogPacket) {
+	now := s.time()
+	for k, deadline := range s.pendingQueries {
+		if now.After(deadline) {
+			delete(s.pendingQueries, k)
+		}
+	}
+
+	var records []AddrRecord
+	for _, pkt := range pkts {
+		p, err := ParsePacket(pkt.Payload)
+		if err != nil {
+			log.WithError(err).Debug("Failed to parse DNS packet, ignoring.")
+			continue
+		}
+		if !p.Response {
+			key := queryKey{p.SrcIP.String(), p.SrcPort, p.ID, p.Question}
+			s.pendingQueries[key] = now.Add(queryTimeout)
+			continue
+		}
+		key := queryKey{p.DstIP.String(), p.DstPort, p.ID, p.Question}
+		if _, ok := s.pendingQueries[key]; !ok {
+			log.WithField("key", key).Debug("DNS response doesn't match a query, ignoring.")
+			continue
+		}
+		delete(s.pendingQueries, key)
+		records = append(records, p.Records...)
+	}
+	if len(records) > 0 {
+		log.WithField("records", records).Debug("Snooped DNS records.")
+		s.records <- records
+	}
+}
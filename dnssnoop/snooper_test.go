@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnssnoop
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/projectcalico/felix/nfnetlink"
+)
+
+var _ = Describe("Snooper", func() {
+	var (
+		snooper *Snooper
+		records chan []AddrRecord
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		records = make(chan []AddrRecord, 10)
+		now = time.Now()
+		snooper = newSnooperWithShims(3, records, func() time.Time { return now })
+	})
+
+	const (
+		client = "10.65.0.2"
+		server = "10.96.0.10"
+	)
+	expectedRecords := []AddrRecord{{Name: "www.example.com", Addr: "10.0.0.1", TTL: 300 * time.Second}}
+
+	It("should accept a response to a query that it has seen", func() {
+		snooper.onPackets([]nfnetlink.NflogPacket{
+			udpPacket(client, server, 34567, 53, dnsMessage(1234, false)),
+		})
+		snooper.onPackets([]nfnetlink.NflogPacket{
+			udpPacket(server, client, 53, 34567, dnsMessage(1234, true)),
+		})
+		Expect(records).To(Receive(Equal(expectedRecords)))
+	})
+
+	It("should ignore a response without a query", func() {
+		snooper.onPackets([]nfnetlink.NflogPacket{
+			udpPacket(server, client, 53, 34567, dnsMessage(1234, true)),
+		})
+		Expect(records).NotTo(Receive())
+	})
+
+	It("should ignore a response with the wrong ID or port", func() {
+		snooper.onPackets([]nfnetlink.NflogPacket{
+			udpPacket(client, server, 34567, 53, dnsMessage(1234, false)),
+			udpPacket(server, client, 53, 34567, dnsMessage(4321, true)),
+			udpPacket(server, client, 53, 34568, dnsMessage(1234, true)),
+		})
+		Expect(records).NotTo(Receive())
+	})
+
+	It("should only accept one response to each query", func() {
+		snooper.onPackets([]nfnetlink.NflogPacket{
+			udpPacket(client, server, 34567, 53, dnsMessage(1234, false)),
+			udpPacket(server, client, 53, 34567, dnsMessage(1234, true)),
+		})
+		Expect(records).To(Receive(Equal(expectedRecords)))
+		snooper.onPackets([]nfnetlink.NflogPacket{
+			udpPacket(server, client, 53, 34567, dnsMessage(1234, true)),
+		})
+		Expect(records).NotTo(Receive())
+	})
+
+	It("should forget queries that time out", func() {
+		snooper.onPackets([]nfnetlink.NflogPacket{
+			udpPacket(client, server, 34567, 53, dnsMessage(1234, false)),
+		})
+		now = now.Add(queryTimeout + time.Second)
+		snooper.onPackets([]nfnetlink.NflogPacket{
+			udpPacket(server, client, 53, 34567, dnsMessage(1234, true)),
+		})
+		Expect(records).NotTo(Receive())
+		Expect(snooper.pendingQueries).To(BeEmpty())
+	})
+})
+
+// dnsMessage returns a query for www.example.com or a response to it with a single A record.
+func dnsMessage(id uint16, response bool) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, Response: response})
+	Expect(b.StartQuestions()).To(Succeed())
+	Expect(b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName("www.example.com."),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	})).To(Succeed())
+	if response {
+		Expect(b.StartAnswers()).To(Succeed())
+		Expect(b.AResource(dnsmessage.ResourceHeader{
+			Name:  dnsmessage.MustNewName("www.example.com."),
+			Type:  dnsmessage.TypeA,
+			Class: dnsmessage.ClassINET,
+			TTL:   300,
+		}, dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}})).To(Succeed())
+	}
+	msg, err := b.Finish()
+	Expect(err).NotTo(HaveOccurred())
+	return msg
+}
+
+// udpPacket wraps the payload in minimal IPv4 and UDP headers, as NFLOG would deliver it.
+func udpPacket(src, dst string, srcPort, dstPort uint16, payload []byte) nfnetlink.NflogPacket {
+	pkt := make([]byte, 20+8, 20+8+len(payload))
+	pkt[0] = 0x45
+	pkt[9] = ipProtoUDP
+	copy(pkt[12:16], net.ParseIP(src).To4())
+	copy(pkt[16:20], net.ParseIP(dst).To4())
+	binary.BigEndian.PutUint16(pkt[20:], srcPort)
+	binary.BigEndian.PutUint16(pkt[22:], dstPort)
+	return nfnetlink.NflogPacket{Payload: append(pkt, payload...)}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drift reports dataplane drift: state that Felix programmed, or expected to find, that has
+// been changed by something else.  The iptables, IP set and route table components find drift when
+// they resync with the dataplane; they fix it as before but they also pass each problem that they
+// find to a *Reporter, which logs it, counts it, keeps the most recent events for the debug server
+// and, optionally, appends it to an audit log.
+//
+// A nil *Reporter discards the events, which is convenient for tests.
+package drift
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	SubsystemIptables   = "iptables"
+	SubsystemIPSets     = "ipsets"
+	SubsystemRouteTable = "routetable"
+)
+
+const (
+	// ResyncStartup is the reason for a component's first resync, when it has yet to program
+	// anything.
+	ResyncStartup = "startup"
+	// ResyncUpdateFailure is the reason for a resync that follows a failed update, when the
+	// dataplane is likely to be partly updated.
+	ResyncUpdateFailure = "update failure"
+)
+
+// IsExpected returns true if a resync for the given reason is expected to find differences
+// between the dataplane and Felix's view of it, so they aren't drift and shouldn't be reported.
+func IsExpected(resyncReason string) bool {
+	return resyncReason == ResyncStartup || resyncReason == ResyncUpdateFailure
+}
+
+// DefaultMaxEvents is the number of recent events that a Reporter keeps by default.
+const DefaultMaxEvents = 100
+
+var (
+	countEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "felix_dataplane_drift_events",
+		Help: "Number of out-of-sync dataplane objects found and corrected during resyncs.",
+	}, []string{"subsystem"})
+	countAuditLogErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "felix_dataplane_drift_audit_log_errors",
+		Help: "Number of failures to write to the dataplane drift audit log.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(countEvents, countAuditLogErrors)
+}
+
+// Event describes one out-of-sync object.
+type Event struct {
+	Time time.Time `json:"time"`
+	// Subsystem is one of the Subsystem... constants.
+	Subsystem string `json:"subsystem"`
+	// Component identifies the instance of the subsystem, for example "filter-v4" for the IPv4
+	// filter table or "routetable-v4-table-200".
+	Component string `json:"component"`
+	// Object is the chain, IP set or route that was out of sync.
+	Object string `json:"object"`
+	// Problem describes what was wrong with the object, for example "unexpected rules".
+	Problem string `json:"problem"`
+	// Resync is the reason for the resync that found the problem, for example "refresh timer".
+	Resync string `json:"resync"`
+}
+
+// Reporter collects drift events.  It is safe to use from multiple goroutines.
+type Reporter struct {
+	lock         sync.Mutex
+	maxEvents    int
+	events       []Event
+	numDropped   int
+	auditLogFile string
+}
+
+// NewReporter creates a Reporter that keeps up to maxEvents recent events and, if auditLogFile is
+// non-empty, appends each event to that file as a JSON line.  If maxEvents is not positive,
+// DefaultMaxEvents is used.
+func NewReporter(maxEvents int, auditLogFile string) *Reporter {
+	if maxEvents <= 0 {
+		maxEvents = DefaultMaxEvents
+	}
+	return &Reporter{
+		maxEvents:    maxEvents,
+		auditLogFile: auditLogFile,
+	}
+}
+
+// Report records an event.  The Time field is filled in if it is zero.
+func (r *Reporter) Report(event Event) {
+	if r == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	log.WithFields(log.Fields{
+		"subsystem": event.Subsystem,
+		"component": event.Component,
+		"object":    event.Object,
+		"problem":   event.Problem,
+		"resync":    event.Resync,
+	}).Info("Dataplane drift detected")
+	countEvents.WithLabelValues(event.Subsystem).Inc()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.events) >= r.maxEvents {
+		// Drop the oldest event.  Copy rather than re-slicing so that the backing array doesn't
+		// grow without bound.
+		copy(r.events, r.events[1:])
+		r.events = r.events[:len(r.events)-1]
+		r.numDropped++
+	}
+	r.events = append(r.events, event)
+
+	if r.auditLogFile != "" {
+		if err := appendToAuditLog(r.auditLogFile, &event); err != nil {
+			log.WithError(err).WithField("file", r.auditLogFile).Warn(
+				"Failed to write to dataplane drift audit log.")
+			countAuditLogErrors.Inc()
+		}
+	}
+}
+
+// Events returns a copy of the recent events, oldest first, and the number of older events that
+// have been discarded.
+func (r *Reporter) Events() (events []Event, numDropped int) {
+	if r == nil {
+		return nil, 0
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	events = make([]Event, len(r.events))
+	copy(events, r.events)
+	return events, r.numDropped
+}
+
+// appendToAuditLog appends the event to the file at path as a JSON line, creating the file and its
+// directory if needed.  The file is reopened for each event so that it can be rotated by moving it
+// aside; drift should be rare so the cost doesn't matter.
+func appendToAuditLog(path string, event *Event) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(f)
+	if err := json.NewEncoder(buf).Encode(event); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
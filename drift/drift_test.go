@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drift_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/drift"
+)
+
+var _ = Describe("Reporter", func() {
+	It("should keep the most recent events", func() {
+		r := drift.NewReporter(2, "")
+		r.Report(drift.Event{Subsystem: drift.SubsystemIptables, Object: "cali-FORWARD"})
+		r.Report(drift.Event{Subsystem: drift.SubsystemIPSets, Object: "cali40all-hosts"})
+		r.Report(drift.Event{Subsystem: drift.SubsystemRouteTable, Object: "10.0.0.1/32"})
+
+		events, numDropped := r.Events()
+		Expect(numDropped).To(Equal(1))
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Object).To(Equal("cali40all-hosts"))
+		Expect(events[0].Time.IsZero()).To(BeFalse())
+		Expect(events[1].Object).To(Equal("10.0.0.1/32"))
+	})
+
+	It("should ignore events if nil", func() {
+		var r *drift.Reporter
+		r.Report(drift.Event{Subsystem: drift.SubsystemIptables})
+		events, numDropped := r.Events()
+		Expect(events).To(BeEmpty())
+		Expect(numDropped).To(BeZero())
+	})
+
+	It("should treat startup and update failure resyncs as expected to find differences", func() {
+		Expect(drift.IsExpected(drift.ResyncStartup)).To(BeTrue())
+		Expect(drift.IsExpected(drift.ResyncUpdateFailure)).To(BeTrue())
+		Expect(drift.IsExpected("refresh")).To(BeFalse())
+	})
+
+	Describe("with an audit log", func() {
+		var dir, path string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "drift")
+			Expect(err).NotTo(HaveOccurred())
+			path = filepath.Join(dir, "audit", "drift.log")
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(dir)
+		})
+
+		It("should append each event as a JSON line", func() {
+			r := drift.NewReporter(0, path)
+			r.Report(drift.Event{
+				Subsystem: drift.SubsystemIptables,
+				Component: "filter-v4",
+				Object:    "cali-FORWARD",
+				Problem:   "unexpected rules",
+				Resync:    "refresh timer",
+			})
+			r.Report(drift.Event{
+				Subsystem: drift.SubsystemIPSets,
+				Component: "ipsets-v4",
+				Object:    "cali40all-hosts",
+				Problem:   "missing member 10.0.0.1",
+				Resync:    "refresh",
+			})
+
+			data, err := ioutil.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			Expect(lines).To(HaveLen(2))
+			var event drift.Event
+			Expect(json.Unmarshal([]byte(lines[1]), &event)).To(Succeed())
+			Expect(event.Subsystem).To(Equal(drift.SubsystemIPSets))
+			Expect(event.Component).To(Equal("ipsets-v4"))
+			Expect(event.Object).To(Equal("cali40all-hosts"))
+			Expect(event.Problem).To(Equal("missing member 10.0.0.1"))
+			Expect(event.Resync).To(Equal("refresh"))
+		})
+	})
+})
@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dryrun supports Felix's observe-only mode, where the dataplane components work out
+// what they would change but don't write to the kernel.
+//
+// Each component that supports dry-run takes a *Recorder; if the recorder is nil, the component
+// writes to the dataplane as normal.  Otherwise, it passes each change that it would have made
+// to the recorder instead, which logs it, counts it and keeps the most recent changes for the
+// debug server.
+package dryrun
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultMaxChanges is the number of recent changes that a Recorder keeps by default.
+const DefaultMaxChanges = 1000
+
+var countChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "felix_dry_run_changes",
+	Help: "Number of dataplane changes that were skipped because Felix is in dry-run mode.",
+}, []string{"component"})
+
+func init() {
+	prometheus.MustRegister(countChanges)
+}
+
+// Change is a dataplane change that was skipped.
+type Change struct {
+	Time time.Time `json:"time"`
+	// Component is the part of the dataplane that would have been changed, for example
+	// "iptables-filter-v4" or "routetable-v4".
+	Component string `json:"component"`
+	// Change describes the write in the component's own terms, for example the input that would
+	// have been passed to iptables-restore.
+	Change string `json:"change"`
+}
+
+// Recorder collects the changes that were skipped.  It is safe to use from multiple goroutines.
+type Recorder struct {
+	lock       sync.Mutex
+	maxChanges int
+	changes    []Change
+	numDropped int
+}
+
+// NewRecorder creates a Recorder that keeps up to maxChanges recent changes.  If maxChanges is
+// not positive, DefaultMaxChanges is used.
+func NewRecorder(maxChanges int) *Recorder {
+	if maxChanges <= 0 {
+		maxChanges = DefaultMaxChanges
+	}
+	return &Recorder{
+		maxChanges: maxChanges,
+	}
+}
+
+// Record logs and stores a change that the given component would have made.
+func (r *Recorder) Record(component, change string) {
+	log.WithField("component", component).Infof("Dry-run: skipping dataplane change:\n%s", change)
+	countChanges.WithLabelValues(component).Inc()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.changes) >= r.maxChanges {
+		// Drop the oldest change.  Copy rather than re-slicing so that the backing array doesn't
+		// grow without bound.
+		copy(r.changes, r.changes[1:])
+		r.changes = r.changes[:len(r.changes)-1]
+		r.numDropped++
+	}
+	r.changes = append(r.changes, Change{
+		Time:      time.Now(),
+		Component: component,
+		Change:    change,
+	})
+}
+
+// Changes returns a copy of the recent changes, oldest first, and the number of older changes
+// that have been discarded.
+func (r *Recorder) Changes() (changes []Change, numDropped int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	changes = make([]Change, len(r.changes))
+	copy(changes, r.changes)
+	return changes, r.numDropped
+}
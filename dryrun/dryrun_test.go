@@ -0,0 +1,48 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dryrun_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/projectcalico/felix/dryrun"
+)
+
+var _ = Describe("Recorder", func() {
+	It("should keep the most recent changes", func() {
+		r := NewRecorder(2)
+		r.Record("iptables-filter-v4", "change 1")
+		r.Record("routetable-v4", "change 2")
+		r.Record("ipsets-v4", "change 3")
+
+		changes, numDropped := r.Changes()
+		Expect(numDropped).To(Equal(1))
+		Expect(changes).To(HaveLen(2))
+		Expect(changes[0].Component).To(Equal("routetable-v4"))
+		Expect(changes[0].Change).To(Equal("change 2"))
+		Expect(changes[1].Component).To(Equal("ipsets-v4"))
+		Expect(changes[1].Change).To(Equal("change 3"))
+	})
+
+	It("should return a copy of the changes", func() {
+		r := NewRecorder(0)
+		r.Record("ipsets-v4", "change 1")
+		changes, _ := r.Changes()
+		changes[0].Change = "modified"
+		changes, _ = r.Changes()
+		Expect(changes[0].Change).To(Equal("change 1"))
+	})
+})
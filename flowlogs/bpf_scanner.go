@@ -0,0 +1,105 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowlogs
+
+import (
+	"time"
+
+	"github.com/projectcalico/felix/bpf/conntrack"
+	"github.com/projectcalico/felix/timeshim"
+)
+
+// BPFConntrackScanner is a conntrack.EntryScanner that feeds the flows in the BPF conntrack map
+// into a Collector.
+//
+// The BPF conntrack map doesn't hold packet or byte counts, and the BPF programs don't report
+// which rule allowed a flow, so the records for BPF flows have zero counts and no policies.
+// Since the BPF programs only create conntrack entries for allowed flows, their action is always
+// "allow".  A flow is reported as active in an interval if its entry's last-seen time moved, and
+// as ended once its entry is removed from the map.
+type BPFConntrackScanner struct {
+	collector *Collector
+	time      timeshim.Interface
+
+	// Go and kernel times at the start of the current iteration, used to convert the kernel
+	// timestamps in the map.
+	iterNow   time.Time
+	iterKTime int64
+
+	// lastSeenByKey maps from flow key to the entry's last-seen time, for each flow that we saw
+	// in the previous iteration.  seenThisIter collects the same for the current iteration.
+	lastSeenByKey map[FlowKey]int64
+	seenThisIter  map[FlowKey]int64
+}
+
+var _ conntrack.EntryScannerSynced = (*BPFConntrackScanner)(nil)
+
+func NewBPFConntrackScanner(collector *Collector, timeShim timeshim.Interface) *BPFConntrackScanner {
+	return &BPFConntrackScanner{
+		collector:     collector,
+		time:          timeShim,
+		lastSeenByKey: map[FlowKey]int64{},
+	}
+}
+
+func (s *BPFConntrackScanner) IterationStart() {
+	s.iterNow = s.time.Now()
+	s.iterKTime = s.time.KTimeNanos()
+	s.seenThisIter = map[FlowKey]int64{}
+}
+
+func (s *BPFConntrackScanner) Check(k conntrack.Key, v conntrack.Value, _ conntrack.EntryGet) conntrack.ScanVerdict {
+	switch v.Type() {
+	case conntrack.TypeNormal, conntrack.TypeNATReverse:
+		// For NATted flows, the reverse entry's key has the backend as one of its
+		// endpoints, matching what we report in iptables mode.
+	default:
+		return conntrack.ScanVerdictOK
+	}
+
+	key := FlowKey{
+		Proto:   k.Proto(),
+		SrcIP:   k.AddrA().String(),
+		DstIP:   k.AddrB().String(),
+		SrcPort: k.PortA(),
+		DstPort: k.PortB(),
+	}
+	data := v.Data()
+	if !data.A2B.Opener && data.B2A.Opener {
+		key.SrcIP, key.DstIP = key.DstIP, key.SrcIP
+		key.SrcPort, key.DstPort = key.DstPort, key.SrcPort
+	}
+
+	lastSeen := v.LastSeen()
+	prevLastSeen, known := s.lastSeenByKey[key]
+	s.seenThisIter[key] = lastSeen
+	s.collector.OnSeen(key, s.kTimeToTime(v.Created()), !known || lastSeen != prevLastSeen, s.iterNow)
+
+	return conntrack.ScanVerdictOK
+}
+
+func (s *BPFConntrackScanner) IterationEnd() {
+	for key := range s.lastSeenByKey {
+		if _, ok := s.seenThisIter[key]; !ok {
+			s.collector.OnFlowEnded(key, s.iterNow)
+		}
+	}
+	s.lastSeenByKey = s.seenThisIter
+	s.seenThisIter = nil
+}
+
+func (s *BPFConntrackScanner) kTimeToTime(kTime int64) time.Time {
+	return s.iterNow.Add(-time.Duration(s.iterKTime - kTime))
+}
@@ -0,0 +1,375 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flowlogs aggregates per-flow statistics and policy verdicts from the dataplane and
+// periodically writes them out as flow log records.
+//
+// The Collector is fed from three directions:
+//
+//   - the dataplane driver passes it the active policies, profiles and local endpoints so that
+//     it can attribute verdicts to rules and addresses to endpoints;
+//   - verdict sources report which rule allowed or denied the first packet of a flow (in
+//     iptables mode, from NFLOG messages emitted by the policy chains);
+//   - counter sources report the flows that exist and their packet/byte counts (conntrack
+//     events and dumps in iptables mode, conntrack map scans in BPF mode).
+package flowlogs
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/felix/ruleindex"
+)
+
+const (
+	// Prefixes of the NFLOG messages that carry verdicts.  These must match the prefixes that
+	// the rules package renders.
+	allowPrefix = "A|"
+	denyPrefix  = "D|"
+
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+
+	DirectionIngress = ruleindex.DirectionIngress
+	DirectionEgress  = ruleindex.DirectionEgress
+)
+
+// FlowKey identifies a flow.  For flows to a service, the destination is the backend that
+// the connection was load balanced to.
+type FlowKey struct {
+	Proto   uint8
+	SrcIP   string
+	DstIP   string
+	SrcPort uint16
+	DstPort uint16
+}
+
+// Counters holds cumulative packet and byte counts for a flow.  "Out" is the direction from
+// the flow's source to its destination, "In" is the reply direction.
+type Counters struct {
+	PacketsOut uint64
+	BytesOut   uint64
+	PacketsIn  uint64
+	BytesIn    uint64
+}
+
+// RuleInfo identifies the policy or profile rule that gave a verdict.
+type RuleInfo struct {
+	Tier        string            `json:"tier,omitempty"`
+	Policy      string            `json:"policy,omitempty"`
+	Profile     string            `json:"profile,omitempty"`
+	Direction   string            `json:"direction"`
+	Index       int               `json:"rule_index"`
+	RuleID      string            `json:"rule_id"`
+	Action      string            `json:"action"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Record is a flow log record, covering one flow over one flush interval.  The packet and byte
+// counts are for the interval only.
+type Record struct {
+	IntervalStart time.Time  `json:"interval_start"`
+	IntervalEnd   time.Time  `json:"interval_end"`
+	FlowStart     time.Time  `json:"flow_start"`
+	FlowEnd       *time.Time `json:"flow_end,omitempty"`
+
+	Proto   uint8  `json:"proto"`
+	SrcIP   string `json:"src_ip"`
+	DstIP   string `json:"dst_ip"`
+	SrcPort uint16 `json:"src_port"`
+	DstPort uint16 `json:"dst_port"`
+
+	// SrcEndpoint and DstEndpoint name the local workload endpoints at either end of the flow,
+	// if any.
+	SrcEndpoint string `json:"src_endpoint,omitempty"`
+	DstEndpoint string `json:"dst_endpoint,omitempty"`
+
+	PacketsOut uint64 `json:"packets_out"`
+	BytesOut   uint64 `json:"bytes_out"`
+	PacketsIn  uint64 `json:"packets_in"`
+	BytesIn    uint64 `json:"bytes_in"`
+
+	// Action is "deny" if any policy denied the flow and "allow" otherwise.
+	Action string `json:"action"`
+	// Policies lists the rules that gave a verdict on the flow, if known.  A deny with no rule
+	// ID is recorded as a RuleInfo with only the Action set: the packet was dropped because no
+	// policy or profile allowed it.
+	Policies []RuleInfo `json:"policies,omitempty"`
+}
+
+type flow struct {
+	start      time.Time
+	end        time.Time
+	lastUpdate time.Time
+	counters   Counters
+	// reported holds the counters as of the last record that we wrote for this flow.
+	reported Counters
+	// active is set if anything happened to the flow since the last flush.
+	active bool
+	denied bool
+	// hits holds the rules that gave a verdict on the flow.  Only the first packet of an
+	// allowed flow reaches the policy chains so we keep them for the life of the flow and
+	// include them in every record.
+	hits []RuleInfo
+}
+
+// Collector aggregates flow information and produces a Record for each flow that was active
+// in each interval.  It is safe for concurrent use.
+type Collector struct {
+	lock sync.Mutex
+
+	// rules attributes verdicts to rules and addresses to endpoints.
+	rules *ruleindex.Index
+
+	flows         map[FlowKey]*flow
+	interval      time.Duration
+	intervalStart time.Time
+	// staleTimeout is how long we keep a flow that no source has mentioned.
+	staleTimeout time.Duration
+}
+
+func NewCollector(interval time.Duration, now time.Time) *Collector {
+	return &Collector{
+		rules:         ruleindex.New(),
+		flows:         map[FlowKey]*flow{},
+		interval:      interval,
+		intervalStart: now,
+		staleTimeout:  2 * interval,
+	}
+}
+
+// OnUpdate processes a message from the calculation graph, picking out the policies, profiles
+// and endpoints.
+func (c *Collector) OnUpdate(msg interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.rules.OnUpdate(msg)
+}
+
+// ruleInfo returns the attribution of a rule for the flow logs.
+func ruleInfo(r ruleindex.Rule) RuleInfo {
+	return RuleInfo{
+		Tier:        r.Tier,
+		Policy:      r.Policy,
+		Profile:     r.Profile,
+		Direction:   r.Direction,
+		Index:       r.Index,
+		RuleID:      r.ID,
+		Action:      ruleAction(r.Action),
+		Annotations: r.Annotations,
+	}
+}
+
+func ruleAction(action string) string {
+	switch action {
+	case "", "allow":
+		return ActionAllow
+	case "next-tier":
+		return "pass"
+	}
+	return action
+}
+
+// OnVerdict records a policy verdict, as encoded in the prefix of a verdict NFLOG message, for
+// a packet of length pktLen.  Since only the first packet of an allowed flow reaches the policy
+// chains, only denied packets are counted here; the conntrack counters cover the rest.
+func (c *Collector) OnVerdict(key FlowKey, prefix string, pktLen int, now time.Time) {
+	var action, ruleID string
+	switch {
+	case strings.HasPrefix(prefix, allowPrefix):
+		action = ActionAllow
+		ruleID = prefix[len(allowPrefix):]
+	case strings.HasPrefix(prefix, denyPrefix):
+		action = ActionDeny
+		ruleID = prefix[len(denyPrefix):]
+	default:
+		log.WithField("prefix", prefix).Debug("Ignoring NFLOG message with unknown prefix.")
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f := c.getOrCreateFlow(key, now, now)
+	f.lastUpdate = now
+	f.active = true
+	hit := RuleInfo{Action: action}
+	if ruleID != "" {
+		if rule, ok := c.rules.Rule(ruleID); ok {
+			hit = ruleInfo(rule)
+		} else {
+			// The policy may have been removed since the packet was logged.
+			hit.RuleID = ruleID
+		}
+	}
+	if action == ActionDeny {
+		f.denied = true
+		f.counters.PacketsOut++
+		f.counters.BytesOut += uint64(pktLen)
+	}
+	for _, h := range f.hits {
+		if h.RuleID == hit.RuleID && h.Action == hit.Action {
+			return
+		}
+	}
+	f.hits = append(f.hits, hit)
+}
+
+// OnCounters records the current state of a flow, as reported by conntrack.  The counters are
+// cumulative since the start of the flow.  If the flow has ended, end should be set to the time
+// that it ended.
+func (c *Collector) OnCounters(key FlowKey, counters Counters, start, end, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f := c.getOrCreateFlow(key, start, now)
+	f.lastUpdate = now
+	if counters != f.counters {
+		f.active = true
+		f.counters = counters
+	}
+	if !end.IsZero() && f.end.IsZero() {
+		f.end = end
+		f.active = true
+	}
+}
+
+// OnSeen records that a flow for which we have no counters still exists and whether it has seen
+// traffic since the last time it was reported.
+func (c *Collector) OnSeen(key FlowKey, start time.Time, active bool, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f := c.getOrCreateFlow(key, start, now)
+	f.lastUpdate = now
+	if active {
+		f.active = true
+	}
+}
+
+// OnFlowEnded records that a flow has ended without a final update to its counters.
+func (c *Collector) OnFlowEnded(key FlowKey, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f := c.flows[key]
+	if f == nil || !f.end.IsZero() {
+		return
+	}
+	f.end = now
+	f.active = true
+}
+
+func (c *Collector) getOrCreateFlow(key FlowKey, start, now time.Time) *flow {
+	f := c.flows[key]
+	if f != nil && !f.end.IsZero() {
+		// The 5-tuple has been reused since the old flow ended.  We've still to report the
+		// old flow but we lose its final interval in favour of the new one.
+		f = nil
+	}
+	if f == nil {
+		if start.IsZero() {
+			start = now
+		}
+		f = &flow{start: start, active: true}
+		c.flows[key] = f
+	}
+	return f
+}
+
+// StartFlushing starts a background goroutine that flushes the records to the writer once per
+// interval.
+func (c *Collector) StartFlushing(w *Writer) {
+	go func() {
+		for now := range time.NewTicker(c.interval).C {
+			records := c.Flush(now)
+			if err := w.Write(records); err != nil {
+				log.WithError(err).WithField("numRecords", len(records)).Warn(
+					"Failed to write flow logs, dropping records.")
+			}
+		}
+	}()
+}
+
+// Flush returns a Record for each flow that was active since the last flush and forgets about
+// flows that have ended or gone stale.
+func (c *Collector) Flush(now time.Time) []Record {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var records []Record
+	for key, f := range c.flows {
+		if f.active {
+			records = append(records, c.makeRecord(key, f, now))
+			f.reported = f.counters
+			f.active = false
+		}
+		if !f.end.IsZero() || now.Sub(f.lastUpdate) > c.staleTimeout {
+			delete(c.flows, key)
+		}
+	}
+	c.intervalStart = now
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].FlowStart.Before(records[j].FlowStart)
+	})
+	return records
+}
+
+func (c *Collector) makeRecord(key FlowKey, f *flow, now time.Time) Record {
+	r := Record{
+		IntervalStart: c.intervalStart,
+		IntervalEnd:   now,
+		FlowStart:     f.start,
+
+		Proto:   key.Proto,
+		SrcIP:   key.SrcIP,
+		DstIP:   key.DstIP,
+		SrcPort: key.SrcPort,
+		DstPort: key.DstPort,
+
+		SrcEndpoint: c.endpointName(key.SrcIP),
+		DstEndpoint: c.endpointName(key.DstIP),
+
+		PacketsOut: f.counters.PacketsOut - f.reported.PacketsOut,
+		BytesOut:   f.counters.BytesOut - f.reported.BytesOut,
+		PacketsIn:  f.counters.PacketsIn - f.reported.PacketsIn,
+		BytesIn:    f.counters.BytesIn - f.reported.BytesIn,
+
+		Action:   ActionAllow,
+		Policies: append([]RuleInfo(nil), f.hits...),
+	}
+	if !f.end.IsZero() {
+		end := f.end
+		r.FlowEnd = &end
+	}
+	if f.denied {
+		r.Action = ActionDeny
+	}
+	return r
+}
+
+// endpointName returns the workload ID of the local workload endpoint with the given IP, or ""
+// if there isn't one.
+func (c *Collector) endpointName(ip string) string {
+	id, ok := c.rules.Endpoint(ip)
+	if !ok {
+		return ""
+	}
+	return id.WorkloadId
+}
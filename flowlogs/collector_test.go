@@ -0,0 +1,215 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowlogs_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/projectcalico/felix/flowlogs"
+	"github.com/projectcalico/felix/proto"
+)
+
+var _ = Describe("Flow log collector", func() {
+	const interval = time.Minute
+
+	var (
+		c     *Collector
+		start time.Time
+		key   FlowKey
+	)
+
+	BeforeEach(func() {
+		start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		c = NewCollector(interval, start)
+		key = FlowKey{Proto: 6, SrcIP: "10.0.0.1", DstIP: "10.0.0.2", SrcPort: 40000, DstPort: 80}
+
+		c.OnUpdate(&proto.ActivePolicyUpdate{
+			Id: &proto.PolicyID{Tier: "default", Name: "allow-web"},
+			Policy: &proto.Policy{
+				InboundRules: []*proto.Rule{
+					{Action: "deny", RuleId: "rule-deny"},
+					{
+						Action:   "allow",
+						RuleId:   "rule-allow",
+						Metadata: &proto.RuleMetadata{Annotations: map[string]string{"owner": "web"}},
+					},
+				},
+			},
+		})
+		c.OnUpdate(&proto.ActiveProfileUpdate{
+			Id: &proto.ProfileID{Name: "prof"},
+			Profile: &proto.Profile{
+				OutboundRules: []*proto.Rule{{Action: "allow", RuleId: "rule-prof"}},
+			},
+		})
+		c.OnUpdate(&proto.WorkloadEndpointUpdate{
+			Id: &proto.WorkloadEndpointID{
+				OrchestratorId: "k8s",
+				WorkloadId:     "default/client",
+				EndpointId:     "eth0",
+			},
+			Endpoint: &proto.WorkloadEndpoint{Ipv4Nets: []string{"10.0.0.1/32"}},
+		})
+	})
+
+	It("should produce nothing if there were no flows", func() {
+		Expect(c.Flush(start.Add(interval))).To(BeEmpty())
+	})
+
+	It("should attribute an allowed flow and report its counters", func() {
+		c.OnVerdict(key, "A|rule-allow", 60, start.Add(time.Second))
+		c.OnCounters(key, Counters{PacketsOut: 5, BytesOut: 500, PacketsIn: 4, BytesIn: 4000},
+			start.Add(time.Second), time.Time{}, start.Add(10*time.Second))
+
+		Expect(c.Flush(start.Add(interval))).To(Equal([]Record{{
+			IntervalStart: start,
+			IntervalEnd:   start.Add(interval),
+			FlowStart:     start.Add(time.Second),
+			Proto:         6,
+			SrcIP:         "10.0.0.1",
+			DstIP:         "10.0.0.2",
+			SrcPort:       40000,
+			DstPort:       80,
+			SrcEndpoint:   "default/client",
+			PacketsOut:    5,
+			BytesOut:      500,
+			PacketsIn:     4,
+			BytesIn:       4000,
+			Action:        ActionAllow,
+			Policies: []RuleInfo{{
+				Tier:        "default",
+				Policy:      "allow-web",
+				Direction:   DirectionIngress,
+				Index:       1,
+				RuleID:      "rule-allow",
+				Action:      ActionAllow,
+				Annotations: map[string]string{"owner": "web"},
+			}},
+		}}))
+	})
+
+	It("should report the counters per interval", func() {
+		c.OnCounters(key, Counters{PacketsOut: 5, BytesOut: 500}, start, time.Time{}, start.Add(time.Second))
+		c.Flush(start.Add(interval))
+
+		c.OnCounters(key, Counters{PacketsOut: 7, BytesOut: 700}, start, time.Time{}, start.Add(interval+time.Second))
+		records := c.Flush(start.Add(2 * interval))
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].IntervalStart).To(Equal(start.Add(interval)))
+		Expect(records[0].PacketsOut).To(BeNumerically("==", 2))
+		Expect(records[0].BytesOut).To(BeNumerically("==", 200))
+		Expect(records[0].Policies).To(BeEmpty())
+	})
+
+	It("should keep the policies for the life of the flow", func() {
+		c.OnVerdict(key, "A|rule-allow", 60, start.Add(time.Second))
+		c.OnCounters(key, Counters{PacketsOut: 5}, start, time.Time{}, start.Add(time.Second))
+		Expect(c.Flush(start.Add(interval))[0].Policies).To(HaveLen(1))
+
+		c.OnCounters(key, Counters{PacketsOut: 7}, start, time.Time{}, start.Add(interval+time.Second))
+		records := c.Flush(start.Add(2 * interval))
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Action).To(Equal(ActionAllow))
+		Expect(records[0].Policies).To(HaveLen(1))
+		Expect(records[0].Policies[0].RuleID).To(Equal("rule-allow"))
+	})
+
+	It("should skip idle flows and forget them once they go stale", func() {
+		c.OnCounters(key, Counters{PacketsOut: 5}, start, time.Time{}, start.Add(time.Second))
+		c.Flush(start.Add(interval))
+		c.OnCounters(key, Counters{PacketsOut: 5}, start, time.Time{}, start.Add(interval+time.Second))
+		Expect(c.Flush(start.Add(2 * interval))).To(BeEmpty())
+		Expect(c.Flush(start.Add(4 * interval))).To(BeEmpty())
+
+		// A flow with the same key is now new.
+		c.OnCounters(key, Counters{PacketsOut: 5}, time.Time{}, time.Time{}, start.Add(4*interval+time.Second))
+		records := c.Flush(start.Add(5 * interval))
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].FlowStart).To(Equal(start.Add(4*interval + time.Second)))
+		Expect(records[0].PacketsOut).To(BeNumerically("==", 5))
+	})
+
+	It("should report the end of a flow once", func() {
+		end := start.Add(30 * time.Second)
+		c.OnCounters(key, Counters{PacketsOut: 3}, start, end, end)
+		records := c.Flush(start.Add(interval))
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].FlowEnd).To(Equal(&end))
+		Expect(c.Flush(start.Add(2 * interval))).To(BeEmpty())
+	})
+
+	It("should count denied packets and attribute them", func() {
+		c.OnVerdict(key, "D|rule-deny", 60, start.Add(time.Second))
+		c.OnVerdict(key, "D|rule-deny", 60, start.Add(2*time.Second))
+
+		records := c.Flush(start.Add(interval))
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Action).To(Equal(ActionDeny))
+		Expect(records[0].PacketsOut).To(BeNumerically("==", 2))
+		Expect(records[0].BytesOut).To(BeNumerically("==", 120))
+		Expect(records[0].Policies).To(HaveLen(1))
+		Expect(records[0].Policies[0].RuleID).To(Equal("rule-deny"))
+		Expect(records[0].Policies[0].Index).To(Equal(0))
+	})
+
+	It("should record default denies without a rule", func() {
+		c.OnVerdict(key, "D|", 60, start.Add(time.Second))
+		records := c.Flush(start.Add(interval))
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Policies).To(Equal([]RuleInfo{{Action: ActionDeny}}))
+	})
+
+	It("should attribute profile rules", func() {
+		c.OnVerdict(key, "A|rule-prof", 60, start.Add(time.Second))
+		records := c.Flush(start.Add(interval))
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Policies).To(Equal([]RuleInfo{{
+			Profile:   "prof",
+			Direction: DirectionEgress,
+			RuleID:    "rule-prof",
+			Action:    ActionAllow,
+		}}))
+	})
+
+	It("should keep the rule ID of removed policies", func() {
+		c.OnUpdate(&proto.ActivePolicyRemove{Id: &proto.PolicyID{Tier: "default", Name: "allow-web"}})
+		c.OnVerdict(key, "A|rule-allow", 60, start.Add(time.Second))
+		records := c.Flush(start.Add(interval))
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Policies).To(Equal([]RuleInfo{{RuleID: "rule-allow", Action: ActionAllow}}))
+	})
+
+	It("should forget removed endpoints", func() {
+		c.OnUpdate(&proto.WorkloadEndpointRemove{
+			Id: &proto.WorkloadEndpointID{
+				OrchestratorId: "k8s",
+				WorkloadId:     "default/client",
+				EndpointId:     "eth0",
+			},
+		})
+		c.OnCounters(key, Counters{PacketsOut: 1}, start, time.Time{}, start.Add(time.Second))
+		records := c.Flush(start.Add(interval))
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].SrcEndpoint).To(BeEmpty())
+	})
+
+	It("should ignore NFLOG messages with other prefixes", func() {
+		c.OnVerdict(key, "calico-packet", 60, start.Add(time.Second))
+		Expect(c.Flush(start.Add(interval))).To(BeEmpty())
+	})
+})
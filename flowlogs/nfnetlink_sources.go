@@ -0,0 +1,175 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowlogs
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/projectcalico/felix/nfnetlink"
+)
+
+const (
+	// verdictCopyRange is the amount of each packet that we ask the kernel to copy to us.  It
+	// covers the IP header (with options) and the start of the L4 header, which is all we
+	// need for the flow key.
+	verdictCopyRange = 128
+
+	// conntrackDumpInterval is how often we dump the conntrack table to pick up the counters of
+	// long-lived flows.  It matches the BPF conntrack scanner's period.
+	conntrackDumpInterval = 10 * time.Second
+
+	retryInterval = 5 * time.Second
+)
+
+// VerdictReader feeds the verdicts that the iptables policy chains send to an NFLOG group into
+// a Collector.
+type VerdictReader struct {
+	group     uint16
+	collector *Collector
+}
+
+func NewVerdictReader(group uint16, collector *Collector) *VerdictReader {
+	return &VerdictReader{
+		group:     group,
+		collector: collector,
+	}
+}
+
+// Start starts the reader's background goroutine.
+func (r *VerdictReader) Start() {
+	go nfnetlink.LoopReadingNflog(r.group, verdictCopyRange, "policy verdicts", r.onPackets)
+}
+
+func (r *VerdictReader) onPackets(pkts []nfnetlink.NflogPacket) {
+	now := time.Now()
+	for _, pkt := range pkts {
+		key, pktLen, err := FlowKeyFromPacket(pkt.Payload)
+		if err != nil {
+			log.WithError(err).Debug("Failed to parse logged packet, ignoring.")
+			continue
+		}
+		r.collector.OnVerdict(key, pkt.Prefix, pktLen, now)
+	}
+}
+
+// ConntrackReader feeds the kernel's conntrack entries into a Collector.  It listens for
+// conntrack events, so that it sees short-lived flows and the final counters of every flow, and
+// periodically dumps the table to update the counters of long-lived flows.
+//
+// The kernel only maintains counters if net.netfilter.nf_conntrack_acct is enabled.
+type ConntrackReader struct {
+	collector *Collector
+}
+
+func NewConntrackReader(collector *Collector) *ConntrackReader {
+	return &ConntrackReader{
+		collector: collector,
+	}
+}
+
+// Start starts the reader's background goroutines.
+func (r *ConntrackReader) Start() {
+	go r.loopReadingEvents()
+	go r.loopDumping()
+}
+
+func (r *ConntrackReader) loopReadingEvents() {
+	for {
+		l, err := nfnetlink.ListenConntrackEvents()
+		if err != nil {
+			log.WithError(err).Error("Failed to listen for conntrack events, will retry.")
+			time.Sleep(retryInterval)
+			continue
+		}
+		log.Info("Listening for conntrack events.")
+		err = r.readEvents(l)
+		log.WithError(err).Warn("Failed to read conntrack events, reconnecting.")
+		_ = l.Close()
+	}
+}
+
+func (r *ConntrackReader) readEvents(l *nfnetlink.ConntrackEventListener) error {
+	for {
+		events, err := l.Read()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for i := range events {
+			flow := &events[i].Flow
+			switch events[i].Type {
+			case nfnetlink.ConntrackEventNew:
+				r.onFlow(flow, time.Time{}, now)
+			case nfnetlink.ConntrackEventDestroy:
+				end := flow.Stop
+				if end.IsZero() {
+					end = now
+				}
+				r.onFlow(flow, end, now)
+			}
+		}
+	}
+}
+
+func (r *ConntrackReader) loopDumping() {
+	for range time.NewTicker(conntrackDumpInterval).C {
+		if err := r.dump(); err != nil {
+			log.WithError(err).Warn("Failed to dump conntrack table.")
+		}
+	}
+}
+
+func (r *ConntrackReader) dump() error {
+	conn, err := nfnetlink.Open(10 * time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	now := time.Now()
+	for _, family := range []uint8{unix.AF_INET, unix.AF_INET6} {
+		err := nfnetlink.DumpConntrack(conn, family, func(flow *nfnetlink.ConntrackFlow) {
+			r.onFlow(flow, time.Time{}, now)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ConntrackReader) onFlow(flow *nfnetlink.ConntrackFlow, end, now time.Time) {
+	r.collector.OnCounters(ConntrackFlowKey(flow), Counters{
+		PacketsOut: flow.OrigCounters.Packets,
+		BytesOut:   flow.OrigCounters.Bytes,
+		PacketsIn:  flow.ReplyCounters.Packets,
+		BytesIn:    flow.ReplyCounters.Bytes,
+	}, flow.Start, end, now)
+}
+
+// ConntrackFlowKey returns the flow key for a conntrack entry.  The destination is taken from
+// the reply tuple so that, for a DNATted flow, it is the backend, as seen by the policy chains.
+// Similarly, the source is taken from the original tuple, before any SNAT.
+func ConntrackFlowKey(flow *nfnetlink.ConntrackFlow) FlowKey {
+	return FlowKey{
+		Proto:   flow.Orig.Proto,
+		SrcIP:   flow.Orig.Src.String(),
+		DstIP:   flow.Reply.Src.String(),
+		SrcPort: flow.Orig.SrcPort,
+		DstPort: flow.Reply.SrcPort,
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowlogs
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+const (
+	ipProtoTCP  = 6
+	ipProtoUDP  = 17
+	ipProtoSCTP = 132
+
+	ipv6HeaderLen = 40
+)
+
+// FlowKeyFromPacket extracts the flow key and the full length of an IPv4 or IPv6 packet from its
+// (possibly truncated) headers.  Ports are only filled in for TCP, UDP and SCTP.  As for DNS
+// snooping, we don't walk IPv6 extension headers; such packets get a key without ports.
+func FlowKeyFromPacket(pkt []byte) (FlowKey, int, error) {
+	var key FlowKey
+	if len(pkt) < 1 {
+		return key, 0, errors.New("empty packet")
+	}
+	var hdrLen, pktLen int
+	switch pkt[0] >> 4 {
+	case 4:
+		if len(pkt) < 20 {
+			return key, 0, errors.New("truncated IPv4 header")
+		}
+		hdrLen = int(pkt[0]&0xf) * 4
+		pktLen = int(binary.BigEndian.Uint16(pkt[2:4]))
+		key.Proto = pkt[9]
+		key.SrcIP = net.IP(pkt[12:16]).String()
+		key.DstIP = net.IP(pkt[16:20]).String()
+	case 6:
+		if len(pkt) < ipv6HeaderLen {
+			return key, 0, errors.New("truncated IPv6 header")
+		}
+		hdrLen = ipv6HeaderLen
+		pktLen = ipv6HeaderLen + int(binary.BigEndian.Uint16(pkt[4:6]))
+		key.Proto = pkt[6]
+		key.SrcIP = net.IP(pkt[8:24]).String()
+		key.DstIP = net.IP(pkt[24:40]).String()
+	default:
+		return key, 0, errors.New("unknown IP version")
+	}
+
+	switch key.Proto {
+	case ipProtoTCP, ipProtoUDP, ipProtoSCTP:
+		if len(pkt) < hdrLen+4 {
+			return key, 0, errors.New("truncated L4 header")
+		}
+		key.SrcPort = binary.BigEndian.Uint16(pkt[hdrLen : hdrLen+2])
+		key.DstPort = binary.BigEndian.Uint16(pkt[hdrLen+2 : hdrLen+4])
+	}
+	return key, pktLen, nil
+}
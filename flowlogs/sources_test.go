@@ -0,0 +1,168 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowlogs_test
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/bpf/conntrack"
+	. "github.com/projectcalico/felix/flowlogs"
+	"github.com/projectcalico/felix/nfnetlink"
+	"github.com/projectcalico/felix/timeshim/mocktime"
+)
+
+var _ = Describe("Flow keys from packets", func() {
+	It("should parse an IPv4 TCP packet", func() {
+		pkt := make([]byte, 24)
+		pkt[0] = 0x45
+		pkt[2], pkt[3] = 0x01, 0x00 // Total length 256.
+		pkt[9] = 6
+		copy(pkt[12:16], net.ParseIP("10.0.0.1").To4())
+		copy(pkt[16:20], net.ParseIP("10.0.0.2").To4())
+		pkt[20], pkt[21] = 0x9c, 0x40 // 40000
+		pkt[22], pkt[23] = 0x00, 0x50 // 80
+
+		key, pktLen, err := FlowKeyFromPacket(pkt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key).To(Equal(FlowKey{Proto: 6, SrcIP: "10.0.0.1", DstIP: "10.0.0.2", SrcPort: 40000, DstPort: 80}))
+		Expect(pktLen).To(Equal(256))
+	})
+
+	It("should parse an IPv6 UDP packet", func() {
+		pkt := make([]byte, 44)
+		pkt[0] = 0x60
+		pkt[5] = 16 // Payload length.
+		pkt[6] = 17
+		copy(pkt[8:24], net.ParseIP("fd00::1"))
+		copy(pkt[24:40], net.ParseIP("fd00::2"))
+		pkt[41] = 53
+		pkt[43] = 54
+
+		key, pktLen, err := FlowKeyFromPacket(pkt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key).To(Equal(FlowKey{Proto: 17, SrcIP: "fd00::1", DstIP: "fd00::2", SrcPort: 53, DstPort: 54}))
+		Expect(pktLen).To(Equal(56))
+	})
+
+	It("should leave the ports out for ICMP", func() {
+		pkt := make([]byte, 28)
+		pkt[0] = 0x45
+		pkt[9] = 1
+		key, _, err := FlowKeyFromPacket(pkt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.SrcPort).To(BeZero())
+		Expect(key.DstPort).To(BeZero())
+	})
+
+	It("should reject truncated packets", func() {
+		pkt := make([]byte, 22)
+		pkt[0] = 0x45
+		pkt[9] = 6
+		_, _, err := FlowKeyFromPacket(pkt)
+		Expect(err).To(HaveOccurred())
+		_, _, err = FlowKeyFromPacket(pkt[:10])
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Flow keys from conntrack", func() {
+	It("should use the backend as the destination of a DNATted flow", func() {
+		key := ConntrackFlowKey(&nfnetlink.ConntrackFlow{
+			Orig: nfnetlink.ConntrackTuple{
+				Src: net.ParseIP("10.0.0.1"), Dst: net.ParseIP("10.96.0.10"),
+				Proto: 17, SrcPort: 40000, DstPort: 53,
+			},
+			Reply: nfnetlink.ConntrackTuple{
+				Src: net.ParseIP("10.0.0.2"), Dst: net.ParseIP("10.0.0.1"),
+				Proto: 17, SrcPort: 5353, DstPort: 40000,
+			},
+		})
+		Expect(key).To(Equal(FlowKey{Proto: 17, SrcIP: "10.0.0.1", DstIP: "10.0.0.2", SrcPort: 40000, DstPort: 5353}))
+	})
+})
+
+var _ = Describe("BPF conntrack scanner", func() {
+	var (
+		c        *Collector
+		mockTime *mocktime.MockTime
+		scanner  *BPFConntrackScanner
+		ctKey    conntrack.Key
+	)
+
+	scan := func(entries map[conntrack.Key]conntrack.Value) {
+		scanner.IterationStart()
+		for k, v := range entries {
+			Expect(scanner.Check(k, v, nil)).To(Equal(conntrack.ScanVerdictOK))
+		}
+		scanner.IterationEnd()
+	}
+
+	BeforeEach(func() {
+		mockTime = mocktime.New()
+		c = NewCollector(time.Minute, mockTime.Now())
+		scanner = NewBPFConntrackScanner(c, mockTime)
+		ctKey = conntrack.NewKey(6, net.ParseIP("10.0.0.2").To4(), 80, net.ParseIP("10.0.0.1").To4(), 40000)
+	})
+
+	It("should report flows in the direction that they were opened", func() {
+		created := mocktime.StartKTime - 5*time.Second
+		scan(map[conntrack.Key]conntrack.Value{
+			ctKey: conntrack.NewValueNormal(created, created, 0, conntrack.Leg{}, conntrack.Leg{}),
+		})
+		records := c.Flush(mockTime.Now().Add(time.Minute))
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].FlowStart).To(Equal(mocktime.StartTime.Add(-5 * time.Second)))
+		Expect(records[0].Action).To(Equal(ActionAllow))
+	})
+
+	It("should report flows as ended once they leave the map", func() {
+		created := mocktime.StartKTime - 5*time.Second
+		scan(map[conntrack.Key]conntrack.Value{
+			ctKey: conntrack.NewValueNormal(created, created, 0, conntrack.Leg{Opener: true}, conntrack.Leg{}),
+		})
+		mockTime.IncrementTime(10 * time.Second)
+		scan(nil)
+
+		records := c.Flush(mockTime.Now())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].SrcIP).To(Equal("10.0.0.2"))
+		Expect(records[0].SrcPort).To(BeNumerically("==", 80))
+		Expect(records[0].FlowEnd).NotTo(BeNil())
+		Expect(*records[0].FlowEnd).To(Equal(mockTime.Now()))
+	})
+
+	It("should only report a flow in later intervals if it saw traffic", func() {
+		created := mocktime.StartKTime - 5*time.Second
+		entries := map[conntrack.Key]conntrack.Value{
+			ctKey: conntrack.NewValueNormal(created, created, 0, conntrack.Leg{}, conntrack.Leg{}),
+		}
+		scan(entries)
+		c.Flush(mockTime.Now())
+
+		mockTime.IncrementTime(10 * time.Second)
+		scan(entries)
+		Expect(c.Flush(mockTime.Now())).To(BeEmpty())
+
+		mockTime.IncrementTime(10 * time.Second)
+		entries[ctKey] = conntrack.NewValueNormal(created, mocktime.StartKTime+15*time.Second, 0,
+			conntrack.Leg{}, conntrack.Leg{})
+		scan(entries)
+		Expect(c.Flush(mockTime.Now())).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowlogs
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const unixSocketPrefix = "unix:"
+
+// Writer writes flow log records as JSON lines to a file or a Unix socket.  It opens the
+// destination afresh for each batch of records and closes it afterwards so that, for example, a
+// log file can be rotated without telling Felix.
+type Writer struct {
+	destination string
+}
+
+// NewWriter returns a Writer for the given destination, which is either a file path or, with a
+// "unix:" prefix, the path of a Unix stream socket.  The destination isn't opened until the first
+// write.
+func NewWriter(destination string) *Writer {
+	return &Writer{destination: destination}
+}
+
+func (w *Writer) Write(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	out, err := w.open()
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(out)
+	enc := json.NewEncoder(buf)
+	for i := range records {
+		if err := enc.Encode(&records[i]); err != nil {
+			_ = out.Close()
+			return err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (w *Writer) open() (io.WriteCloser, error) {
+	if strings.HasPrefix(w.destination, unixSocketPrefix) {
+		return net.Dial("unix", strings.TrimPrefix(w.destination, unixSocketPrefix))
+	}
+	if err := os.MkdirAll(filepath.Dir(w.destination), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(w.destination, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowlogs_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/projectcalico/felix/flowlogs"
+)
+
+var _ = Describe("Flow log writer", func() {
+	var (
+		dir     string
+		records []Record
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "flowlogs")
+		Expect(err).NotTo(HaveOccurred())
+		records = []Record{
+			{Proto: 6, SrcIP: "10.0.0.1", DstIP: "10.0.0.2", Action: ActionAllow},
+			{Proto: 17, SrcIP: "10.0.0.1", DstIP: "10.0.0.3", Action: ActionDeny},
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	decodeLines := func(data string) []Record {
+		var decoded []Record
+		for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+			var r Record
+			Expect(json.Unmarshal([]byte(line), &r)).To(Succeed())
+			decoded = append(decoded, r)
+		}
+		return decoded
+	}
+
+	It("should append JSON lines to a file, creating its directory", func() {
+		path := filepath.Join(dir, "sub", "flows.log")
+		w := NewWriter(path)
+		Expect(w.Write(records[:1])).To(Succeed())
+		Expect(w.Write(records[1:])).To(Succeed())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decodeLines(string(data))).To(Equal(records))
+	})
+
+	It("should reopen the file for each write so that it can be rotated", func() {
+		path := filepath.Join(dir, "flows.log")
+		w := NewWriter(path)
+		Expect(w.Write(records[:1])).To(Succeed())
+		Expect(os.Rename(path, path+".1")).To(Succeed())
+		Expect(w.Write(records[1:])).To(Succeed())
+
+		data, err := ioutil.ReadFile(path + ".1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decodeLines(string(data))).To(Equal(records[:1]))
+		data, err = ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decodeLines(string(data))).To(Equal(records[1:]))
+	})
+
+	It("should write JSON lines to a Unix socket", func() {
+		path := filepath.Join(dir, "flows.sock")
+		l, err := net.Listen("unix", path)
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+
+		w := NewWriter("unix:" + path)
+		Expect(w.Write(records)).To(Succeed())
+
+		conn, err := l.Accept()
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		Expect(decodeLines(strings.Join(lines, "\n"))).To(Equal(records))
+	})
+
+	It("should return an error if the socket isn't there", func() {
+		w := NewWriter("unix:" + filepath.Join(dir, "missing.sock"))
+		Expect(w.Write(records)).NotTo(Succeed())
+	})
+})
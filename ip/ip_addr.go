@@ -101,6 +101,15 @@ func (a V6Addr) AsCIDR() CIDR {
 	}
 }
 
+// NthBit returns the nth bit of the address, counting from 1 at the most significant bit.
+func (a V6Addr) NthBit(n uint) int {
+	return int(a[(n-1)/8]>>(7-(n-1)%8)) & 1
+}
+
+func (a V6Addr) asUint64s() (hi, lo uint64) {
+	return binary.BigEndian.Uint64(a[:8]), binary.BigEndian.Uint64(a[8:])
+}
+
 func (a V6Addr) String() string {
 	return a.AsNetIP().String()
 }
@@ -173,6 +182,10 @@ func (c V6CIDR) ToIPNet() net.IPNet {
 	}
 }
 
+func (c V6CIDR) ContainsV6(addr V6Addr) bool {
+	return v6CommonPrefixLen(c.addr, addr) >= c.prefix
+}
+
 func (c V6CIDR) String() string {
 	return fmt.Sprintf("%s/%v", c.addr.String(), c.prefix)
 }
@@ -266,6 +266,138 @@ var _ = Describe("V4Trie tests", func() {
 	)
 })
 
+var _ = DescribeTable("V6CommonPrefix",
+	func(a, b, expected string) {
+		aCIDR := ip.MustParseCIDROrIP(a).(ip.V6CIDR)
+		bCIDR := ip.MustParseCIDROrIP(b).(ip.V6CIDR)
+		expCIDR := ip.MustParseCIDROrIP(expected).(ip.V6CIDR)
+
+		Expect(ip.V6CommonPrefix(aCIDR, bCIDR)).To(Equal(expCIDR))
+		Expect(ip.V6CommonPrefix(bCIDR, aCIDR)).To(Equal(expCIDR))
+	},
+	// Zero cases.
+	cpEntry("::/0", "::/0", "::/0"),
+	cpEntry("::/0", "feed::/16", "::/0"),
+
+	// One contained in the other.
+	cpEntry("feed:beef::/32", "feed:beef:1::/48", "feed:beef::/32"),
+	cpEntry("feed:beef::1:0/112", "feed:beef::1:1/128", "feed:beef::1:0/112"),
+
+	// Disjoint, in the high and low halves of the address.
+	cpEntry("feed:beef::/32", "feed:beee::/32", "feed:beee::/31"),
+	cpEntry("feed::2/128", "feed::3/128", "feed::2/127"),
+	cpEntry("feed::1:0:0:0/80", "feed::/80", "feed::/79"),
+	cpEntry("feed:0:0:1::/64", "feed::/64", "feed::/63"),
+)
+
+var _ = Describe("V6Trie tests", func() {
+	var trie *ip.V6Trie
+
+	BeforeEach(func() {
+		trie = &ip.V6Trie{}
+	})
+
+	update := func(cidr string) {
+		trie.Update(ip.MustParseCIDROrIP(cidr).(ip.V6CIDR), "data:"+cidr)
+	}
+
+	remove := func(cidr string) {
+		trie.Delete(ip.MustParseCIDROrIP(cidr).(ip.V6CIDR))
+	}
+
+	contents := func() []string {
+		var s []string
+		for _, t := range trie.ToSlice() {
+			cidrStr := t.CIDR.String()
+			Expect(t.Data).To(Equal("data:"+cidrStr), "Trie returned entry with unexpected data")
+			s = append(s, cidrStr)
+		}
+		return s
+	}
+
+	lookup := func(cidr string) []string {
+		var s []string
+		for _, t := range trie.LookupPath(nil, ip.MustParseCIDROrIP(cidr).(ip.V6CIDR)) {
+			cidrStr := t.CIDR.String()
+			Expect(t.Data).To(Equal("data:"+cidrStr), "Trie returned entry with unexpected data")
+			s = append(s, cidrStr)
+		}
+		return s
+	}
+
+	It("should allow inserting a single CIDR", func() {
+		update("feed:beef::/32")
+		Expect(contents()).To(ConsistOf("feed:beef::/32"))
+	})
+
+	It("should ignore deletes for outside the trie", func() {
+		update("feed:beef::/32")
+		remove("feed:beee::/32")
+		Expect(contents()).To(ConsistOf("feed:beef::/32"))
+	})
+
+	It("should look up the enclosing CIDRs", func() {
+		update("feed:beef::/32")
+		update("feed:beef::/96")
+		update("feed:beef::1/128")
+		update("feed:beee::/32")
+		Expect(lookup("feed:beef::1/128")).To(Equal([]string{"feed:beef::/32", "feed:beef::/96", "feed:beef::1/128"}))
+		Expect(lookup("feed:beef::2/128")).To(BeEmpty())
+	})
+
+	It("should do a longest prefix match", func() {
+		update("feed:beef::/32")
+		update("feed:beef::/96")
+		cidr, data := trie.LPM(ip.MustParseCIDROrIP("feed:beef::1:2/128").(ip.V6CIDR))
+		Expect(cidr.String()).To(Equal("feed:beef::/96"))
+		Expect(data).To(Equal("data:feed:beef::/96"))
+		_, data = trie.LPM(ip.MustParseCIDROrIP("feed:beee::1/128").(ip.V6CIDR))
+		Expect(data).To(BeNil())
+	})
+
+	pEntry := func(cidrs ...string) TableEntry {
+		return Entry(fmt.Sprint(cidrs), cidrs)
+	}
+	DescribeTable("permutation tests",
+		func(cidrs []string) {
+			// See the V4Trie permutation tests for how the doubled input is interpreted.
+			cidrs = append(cidrs, cidrs...)
+			permute(cidrs, func(cidrs []string) {
+				expected := set.New()
+				for _, c := range cidrs {
+					if expected.Contains(c) {
+						expected.Discard(c)
+						remove(c)
+					} else {
+						expected.Add(c)
+						update(c)
+					}
+					var expSlice []string
+					expected.Iter(func(item interface{}) error {
+						cidr := item.(string)
+						expSlice = append(expSlice, cidr)
+
+						path := lookup(cidr)
+						for _, c := range path {
+							Expect(expected.Contains(c)).To(BeTrue(), fmt.Sprintf(
+								"Trie returned a path (%v) including a CIDR that wasn't supposed to be in the trie (%v)", path, c))
+						}
+
+						return nil
+					})
+					Expect(contents()).To(ConsistOf(expSlice),
+						fmt.Sprintf("Trie had incorrect contents with this sequence of CIDRs: %s", cidrs))
+				}
+			})
+		},
+		pEntry("::/0"),
+		pEntry("::/0", "feed::/16", "beef::/16"),
+		pEntry("feed::1/128", "feed::2/128", "feed::3/128"),
+		pEntry("::/0", "8000::/1", "::/1"),
+		pEntry("feed::/16", "feed::/96", "feed::1:0:0:0/80"),
+	)
+})
+
 // Based on the blog post at https://yourbasic.org/golang/generate-permutation-slice-string/ (CC-BY-3.0)
 // permute calls f with each permutation of a.
 func permute(a []string, f func([]string)) {
@@ -0,0 +1,380 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ip
+
+import (
+	"encoding/binary"
+	"log"
+	"math/bits"
+)
+
+// V6Trie is the IPv6 equivalent of V4Trie.
+type V6Trie struct {
+	root *V6Node
+}
+
+type V6Node struct {
+	cidr     V6CIDR
+	children [2]*V6Node
+	data     interface{}
+}
+
+func (t *V6Trie) Delete(cidr V6CIDR) {
+	if t.root == nil {
+		// Trie is empty.
+		return
+	}
+	if V6CommonPrefix(t.root.cidr, cidr) != t.root.cidr {
+		// Trie does not contain prefix.
+		return
+	}
+	t.root = deleteInternalV6(t.root, cidr)
+}
+
+func deleteInternalV6(n *V6Node, cidr V6CIDR) *V6Node {
+	if !n.cidr.ContainsV6(cidr.addr) {
+		// Not in trie.
+		return n
+	}
+
+	if cidr == n.cidr {
+		// Found the node.  If either child is nil then this was just an intermediate node
+		// and it no longer has any data in it so we replace it by its remaining child.
+		if n.children[0] == nil {
+			return n.children[1]
+		} else if n.children[1] == nil {
+			return n.children[0]
+		} else {
+			// Intermediate node but it has two children so it is still required.
+			n.data = nil
+			return n
+		}
+	}
+
+	// If we get here, then this node is a parent of the CIDR we're looking for.
+	// Figure out which child to recurse on.
+	childIdx := cidr.addr.NthBit(uint(n.cidr.prefix + 1))
+	oldChild := n.children[childIdx]
+	if oldChild == nil {
+		return n
+	}
+	newChild := deleteInternalV6(oldChild, cidr)
+	n.children[childIdx] = newChild
+	if newChild == nil {
+		// One of our children has been deleted completely, check if this node is an intermediate node
+		// that needs to be cleaned up.
+		if n.data == nil {
+			return n.children[1-childIdx]
+		}
+	}
+	return n
+}
+
+type V6TrieEntry struct {
+	CIDR V6CIDR
+	Data interface{}
+}
+
+func (t *V6Trie) Get(cidr V6CIDR) interface{} {
+	return t.root.get(cidr)
+}
+
+// LookupPath looks up the given CIDR in the trie.  It returns a slice containing a V6TrieEntry for each
+// CIDR in the trie that encloses the given CIDR.  If buffer is non-nil, then it is used to store the entries;
+// if it is too short append() is used to extend it and the updated slice is returned.
+//
+// If the CIDR is not in the trie then an empty slice is returned.
+func (t *V6Trie) LookupPath(buffer []V6TrieEntry, cidr V6CIDR) []V6TrieEntry {
+	return t.root.lookupPath(buffer[:0], cidr)
+}
+
+// LPM does a longest prefix match on the trie
+func (t *V6Trie) LPM(cidr V6CIDR) (V6CIDR, interface{}) {
+	n := t.root
+	var match *V6Node
+
+	for {
+		if n == nil {
+			break
+		}
+
+		if !n.cidr.ContainsV6(cidr.addr) {
+			break
+		}
+
+		if n.data != nil {
+			match = n
+		}
+
+		if cidr == n.cidr {
+			break
+		}
+
+		childIdx := cidr.addr.NthBit(uint(n.cidr.prefix + 1))
+		n = n.children[childIdx]
+	}
+
+	if match == nil || match.data == nil {
+		return V6CIDR{}, nil
+	}
+	return match.cidr, match.data
+}
+
+func (n *V6Node) lookupPath(buffer []V6TrieEntry, cidr V6CIDR) []V6TrieEntry {
+	if n == nil {
+		return buffer[:0]
+	}
+
+	if !n.cidr.ContainsV6(cidr.addr) {
+		// Not in trie.
+		return nil
+	}
+
+	if n.data != nil {
+		buffer = append(buffer, V6TrieEntry{CIDR: n.cidr, Data: n.data})
+	}
+
+	if cidr == n.cidr {
+		if n.data == nil {
+			// CIDR is an intermediate node with no data so CIDR isn't actually in the trie.
+			return nil
+		}
+		return buffer
+	}
+
+	childIdx := cidr.addr.NthBit(uint(n.cidr.prefix + 1))
+	child := n.children[childIdx]
+	return child.lookupPath(buffer, cidr)
+}
+
+func (n *V6Node) get(cidr V6CIDR) interface{} {
+	if n == nil {
+		return nil
+	}
+
+	if !n.cidr.ContainsV6(cidr.addr) {
+		// Not in trie.
+		return nil
+	}
+
+	if cidr == n.cidr {
+		if n.data == nil {
+			// CIDR is an intermediate node with no data so CIDR isn't actually in the trie.
+			return nil
+		}
+		return n.data
+	}
+
+	childIdx := cidr.addr.NthBit(uint(n.cidr.prefix + 1))
+	child := n.children[childIdx]
+	return child.get(cidr)
+}
+
+func (t *V6Trie) CoveredBy(cidr V6CIDR) bool {
+	return V6CommonPrefix(t.root.cidr, cidr) == cidr
+}
+
+func (t *V6Trie) Covers(cidr V6CIDR) bool {
+	return t.root.covers(cidr)
+}
+
+func (n *V6Node) covers(cidr V6CIDR) bool {
+	if n == nil {
+		return false
+	}
+
+	if V6CommonPrefix(n.cidr, cidr) != n.cidr {
+		// Not in trie.
+		return false
+	}
+
+	if n.data != nil {
+		return true
+	}
+
+	childIdx := cidr.addr.NthBit(uint(n.cidr.prefix + 1))
+	child := n.children[childIdx]
+	return child.covers(cidr)
+}
+
+func (t *V6Trie) Intersects(cidr V6CIDR) bool {
+	return t.root.intersects(cidr)
+}
+
+func (n *V6Node) intersects(cidr V6CIDR) bool {
+	if n == nil {
+		return false
+	}
+
+	common := V6CommonPrefix(n.cidr, cidr)
+
+	if common == cidr {
+		// This node's CIDR is contained within the target CIDR so we must have
+		// some value that is inside the target CIDR.
+		return true
+	}
+
+	if common != n.cidr {
+		// The CIDRs are disjoint.
+		return false
+	}
+
+	childIdx := cidr.addr.NthBit(uint(n.cidr.prefix + 1))
+	child := n.children[childIdx]
+	return child.intersects(cidr)
+}
+
+func (n *V6Node) appendTo(s []V6TrieEntry) []V6TrieEntry {
+	if n == nil {
+		return s
+	}
+	if n.data != nil {
+		s = append(s, V6TrieEntry{
+			CIDR: n.cidr,
+			Data: n.data,
+		})
+	}
+	s = n.children[0].appendTo(s)
+	s = n.children[1].appendTo(s)
+	return s
+}
+
+func (n *V6Node) visit(f func(cidr V6CIDR, data interface{}) bool) bool {
+	if n == nil {
+		return true
+	}
+
+	if n.data != nil {
+		keepGoing := f(n.cidr, n.data)
+		if !keepGoing {
+			return false
+		}
+	}
+	keepGoing := n.children[0].visit(f)
+	if !keepGoing {
+		return false
+	}
+	return n.children[1].visit(f)
+}
+
+func (t *V6Trie) ToSlice() []V6TrieEntry {
+	return t.root.appendTo(nil)
+}
+
+func (t *V6Trie) Visit(f func(cidr V6CIDR, data interface{}) bool) {
+	t.root.visit(f)
+}
+
+func (t *V6Trie) Update(cidr V6CIDR, value interface{}) {
+	if value == nil {
+		log.Panic("Can't store nil in a V6Trie")
+	}
+	parentsPtr := &t.root
+	thisNode := t.root
+
+	for {
+		if thisNode == nil {
+			// We've run off the end of the tree, create new child to hold this data.
+			newNode := &V6Node{
+				cidr: cidr,
+				data: value,
+			}
+			*parentsPtr = newNode
+			return
+		}
+
+		if thisNode.cidr == cidr {
+			// Found a node with exactly this CIDR, just update the data.
+			thisNode.data = value
+			return
+		}
+
+		// See V4Trie.Update for a description of the three cases.
+		commonPrefix := V6CommonPrefix(cidr, thisNode.cidr)
+
+		if commonPrefix.prefix == thisNode.cidr.prefix {
+			// Common is this node's CIDR so this node is parent of the new CIDR. Figure out which child to recurse on.
+			childIdx := cidr.addr.NthBit(uint(commonPrefix.prefix + 1))
+			parentsPtr = &thisNode.children[childIdx]
+			thisNode = thisNode.children[childIdx]
+			continue
+		}
+
+		if commonPrefix.prefix == cidr.prefix {
+			// Common is new CIDR so this node is a child of the new CIDR. Insert new node.
+			newNode := &V6Node{
+				cidr: cidr,
+				data: value,
+			}
+			childIdx := thisNode.cidr.addr.NthBit(uint(commonPrefix.prefix + 1))
+			newNode.children[childIdx] = thisNode
+			*parentsPtr = newNode
+			return
+		}
+
+		// Neither CIDR contains the other.  Create an internal node with this node and new CIDR as children.
+		newInternalNode := &V6Node{
+			cidr: commonPrefix,
+		}
+		childIdx := thisNode.cidr.addr.NthBit(uint(commonPrefix.prefix + 1))
+		newInternalNode.children[childIdx] = thisNode
+		newInternalNode.children[1-childIdx] = &V6Node{
+			cidr: cidr,
+			data: value,
+		}
+		*parentsPtr = newInternalNode
+		return
+	}
+}
+
+func V6CommonPrefix(a, b V6CIDR) V6CIDR {
+	var result V6CIDR
+	var maxLen uint8
+	if b.prefix < a.prefix {
+		maxLen = b.prefix
+	} else {
+		maxLen = a.prefix
+	}
+
+	commonPrefixLen := v6CommonPrefixLen(a.addr, b.addr)
+	if commonPrefixLen > maxLen {
+		result.prefix = maxLen
+	} else {
+		result.prefix = commonPrefixLen
+	}
+
+	aHi, aLo := a.addr.asUint64s()
+	var maskHi, maskLo uint64
+	if result.prefix >= 64 {
+		maskHi = 0xffffffffffffffff
+		maskLo = ^(uint64(0xffffffffffffffff) >> (result.prefix - 64))
+	} else {
+		maskHi = ^(uint64(0xffffffffffffffff) >> result.prefix)
+	}
+	binary.BigEndian.PutUint64(result.addr[:8], aHi&maskHi)
+	binary.BigEndian.PutUint64(result.addr[8:], aLo&maskLo)
+
+	return result
+}
+
+// v6CommonPrefixLen returns the number of leading bits that a and b have in common.
+func v6CommonPrefixLen(a, b V6Addr) uint8 {
+	aHi, aLo := a.asUint64s()
+	bHi, bLo := b.asUint64s()
+	if aHi != bHi {
+		return uint8(bits.LeadingZeros64(aHi ^ bHi))
+	}
+	return uint8(64 + bits.LeadingZeros64(aLo^bLo))
+}
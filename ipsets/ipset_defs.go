@@ -56,6 +56,18 @@ var (
 		Name: "felix_exec_time_micros",
 		Help: "Summary of time taken to fork/exec child processes",
 	})
+	summaryVecIPSetBackendOpTime = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Name: "felix_ipset_backend_op_time_seconds",
+		Help: "Summary of time taken to resync, update or delete IP sets, by backend (netlink or exec).",
+	}, []string{"backend", "operation"})
+	countVecIPSetBackendErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "felix_ipset_backend_errors",
+		Help: "Number of failed IP set resyncs, updates or deletions, by backend (netlink or exec).",
+	}, []string{"backend", "operation"})
+	countNumIPSetNetlinkFallbacks = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "felix_ipset_netlink_fallbacks",
+		Help: "Number of times that Felix fell back from netlink to the ipset binary.",
+	})
 )
 
 func init() {
@@ -65,8 +77,22 @@ func init() {
 	prometheus.MustRegister(countNumIPSetErrors)
 	prometheus.MustRegister(countNumIPSetLinesExecuted)
 	prometheus.MustRegister(summaryExecStart)
+	prometheus.MustRegister(summaryVecIPSetBackendOpTime)
+	prometheus.MustRegister(countVecIPSetBackendErrors)
+	prometheus.MustRegister(countNumIPSetNetlinkFallbacks)
 }
 
+// Values for the IP sets backend, which controls how we program IP sets.
+const (
+	// BackendNetlink programs IP sets using the kernel's netlink API, falling back to the ipset
+	// binary if netlink is unavailable.
+	BackendNetlink = "netlink"
+	// BackendExec programs IP sets by running the ipset binary.
+	BackendExec = "exec"
+	// BackendAuto is currently equivalent to BackendNetlink.
+	BackendAuto = "auto"
+)
+
 const MaxIPSetNameLength = 31
 
 // IPSetType constants for the different kinds of IP set.
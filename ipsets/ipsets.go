@@ -27,6 +27,8 @@ import (
 
 	"github.com/projectcalico/libcalico-go/lib/set"
 
+	"github.com/projectcalico/felix/drift"
+	"github.com/projectcalico/felix/dryrun"
 	"github.com/projectcalico/felix/logutils"
 )
 
@@ -43,6 +45,9 @@ type IPSets struct {
 	// dirtyIPSetIDs contains IDs of IP sets that need updating.
 	dirtyIPSetIDs  set.Set // <string>
 	resyncRequired bool
+	// resyncReason is the reason for the pending (or most recent) resync, which we include in
+	// any drift that the resync finds.
+	resyncReason string
 
 	// pendingTempIPSetDeletions contains names of temporary IP sets that need to be deleted.  We use it to
 	// attempt an early deletion of temporary IP sets, if possible.
@@ -52,6 +57,8 @@ type IPSets struct {
 
 	// Factory for command objects; shimmed for UT mocking.
 	newCmd cmdFactory
+	// nl is non-nil if we're programming IP sets over netlink instead of using the ipset binary.
+	nl *ipsetNetlink
 
 	// Shim for time.Sleep()
 	sleep func(time.Duration)
@@ -71,15 +78,51 @@ type IPSets struct {
 	stderrCopy bytes.Buffer
 
 	opReporter logutils.OpRecorder
+
+	// dryRun, if non-nil, receives the updates instead of the dataplane.
+	dryRun *dryrun.Recorder
+	// driftReporter receives the out-of-sync IP sets that we find when we resync.
+	driftReporter *drift.Reporter
 }
 
-func NewIPSets(ipVersionConfig *IPVersionConfig, recorder logutils.OpRecorder) *IPSets {
-	return NewIPSetsWithShims(
+// NewIPSets creates an IPSets for the given IP version.  The backend parameter selects how
+// the IP sets are programmed: BackendNetlink and BackendAuto use the kernel's netlink API if it is
+// available, falling back to the ipset binary (as used by BackendExec) if not.  If dryRun is
+// non-nil, the IP sets still read the dataplane but they pass their updates, rendered as
+// 'ipset restore' input, to the recorder instead of writing them.  If driftReporter is non-nil,
+// it is told about each IP set that a resync finds to have been modified by something else.
+func NewIPSets(
+	ipVersionConfig *IPVersionConfig,
+	recorder logutils.OpRecorder,
+	backend string,
+	dryRun *dryrun.Recorder,
+	driftReporter *drift.Reporter,
+) *IPSets {
+	s := NewIPSetsWithShims(
 		ipVersionConfig,
 		recorder,
 		newRealCmd,
 		time.Sleep,
 	)
+	s.dryRun = dryRun
+	s.driftReporter = driftReporter
+	if backend == BackendExec {
+		return s
+	}
+	nl, err := openIPSetNetlink(ipVersionConfig.Family)
+	if err != nil {
+		logCxt := s.logCxt.WithError(err)
+		if backend == BackendNetlink {
+			logCxt.Warning("IP sets netlink API unavailable, falling back to ipset binary.")
+		} else {
+			logCxt.Info("IP sets netlink API unavailable, using ipset binary.")
+		}
+		countNumIPSetNetlinkFallbacks.Inc()
+		return s
+	}
+	s.logCxt.Info("Programming IP sets over netlink.")
+	s.nl = nl
+	return s
 }
 
 // NewIPSetsWithShims is an internal test constructor.
@@ -103,6 +146,7 @@ func NewIPSetsWithShims(
 		sleep:                     sleep,
 		existingIPSetNames:        set.New(),
 		resyncRequired:            true,
+		resyncReason:              drift.ResyncStartup,
 
 		gaugeNumIpsets: gaugeVecNumCalicoIpsets.WithLabelValues(familyStr),
 
@@ -228,6 +272,13 @@ func (s *IPSets) RemoveMembers(setID string, removedMembers []string) {
 // QueueResync forces a resync with the dataplane on the next ApplyUpdates() call.
 func (s *IPSets) QueueResync() {
 	s.logCxt.Debug("Asked to resync with the dataplane on next update.")
+	s.queueResync("refresh")
+}
+
+func (s *IPSets) queueResync(reason string) {
+	if !s.resyncRequired {
+		s.resyncReason = reason
+	}
 	s.resyncRequired = true
 }
 
@@ -297,6 +348,26 @@ func (s *IPSets) GetMembers(setID string) (set.Set, error) {
 }
 
 func (s *IPSets) ApplyUpdates() {
+	success := s.tryApplyUpdates()
+	if !success && s.nl != nil {
+		// Rather than giving up, give the ipset binary a chance; it may cope with whatever
+		// the kernel didn't like about our netlink messages.
+		s.logCxt.Warning("Failed to update IP sets over netlink after multiple retries, " +
+			"falling back to ipset binary.")
+		s.fallBackToExec()
+		s.queueResync("netlink fallback")
+		success = s.tryApplyUpdates()
+	}
+	if !success {
+		s.dumpIPSetsToLog()
+		s.logCxt.Panic("Failed to update IP sets after multiple retries.")
+	}
+	gaugeNumTotalIpsets.Set(float64(s.existingIPSetNames.Len()))
+}
+
+// tryApplyUpdates does the resync (if needed) and updates, with retries.  It returns false if it
+// fails to do so after multiple retries.
+func (s *IPSets) tryApplyUpdates() bool {
 	success := false
 	retryDelay := 1 * time.Millisecond
 	backOff := func() {
@@ -337,7 +408,7 @@ func (s *IPSets) ApplyUpdates() {
 			// While failed deletions don't cause immediate problems, update failures may mean that our iptables
 			// updates fail.  We need to do an immediate resync.
 			s.logCxt.WithError(err).Warning("Failed to update IP sets. Marking dataplane for resync.")
-			s.resyncRequired = true
+			s.queueResync(drift.ResyncUpdateFailure)
 			countNumIPSetErrors.Inc()
 			backOff()
 			continue
@@ -346,11 +417,35 @@ func (s *IPSets) ApplyUpdates() {
 		success = true
 		break
 	}
-	if !success {
-		s.dumpIPSetsToLog()
-		s.logCxt.Panic("Failed to update IP sets after multiple retries.")
+	return success
+}
+
+// fallBackToExec permanently switches from netlink to the ipset binary.
+func (s *IPSets) fallBackToExec() {
+	if s.nl == nil {
+		return
+	}
+	if err := s.nl.Close(); err != nil {
+		s.logCxt.WithError(err).Warning("Failed to close IP sets netlink socket")
+	}
+	s.nl = nil
+	countNumIPSetNetlinkFallbacks.Inc()
+}
+
+// backendName returns the name of the backend in use, for use as a metric label.
+func (s *IPSets) backendName() string {
+	if s.nl != nil {
+		return BackendNetlink
+	}
+	return BackendExec
+}
+
+func (s *IPSets) recordBackendOp(op string, startTime time.Time, err error) {
+	backend := s.backendName()
+	summaryVecIPSetBackendOpTime.WithLabelValues(backend, op).Observe(time.Since(startTime).Seconds())
+	if err != nil {
+		countVecIPSetBackendErrors.WithLabelValues(backend, op).Inc()
 	}
-	gaugeNumTotalIpsets.Set(float64(s.existingIPSetNames.Len()))
 }
 
 // tryResync attempts to bring our state into sync with the dataplane.  It scans the contents of the
@@ -359,12 +454,28 @@ func (s *IPSets) tryResync() (numProblems int, err error) {
 	// Log the time spent as we exit the function.
 	resyncStart := time.Now()
 	defer func() {
+		s.recordBackendOp("resync", resyncStart, err)
 		s.logCxt.WithFields(log.Fields{
 			"resyncDuration":          time.Since(resyncStart),
 			"numInconsistenciesFound": numProblems,
 		}).Debug("Finished IPSets resync")
 	}()
 
+	if s.nl != nil {
+		numProblems, err = s.resyncFromNetlink()
+	} else {
+		numProblems, err = s.resyncFromIPSetList()
+	}
+	if err != nil {
+		return
+	}
+	s.queueLeftOverIPSetDeletions()
+	return
+}
+
+// resyncFromIPSetList scans the output of 'ipset list' and queues up updates to any of our IP
+// sets that are out-of-sync.  It also refreshes existingIPSetNames.
+func (s *IPSets) resyncFromIPSetList() (numProblems int, err error) {
 	// Start an 'ipset list' child process, which will emit output of the following form:
 	//
 	// 	Name: test-100
@@ -474,88 +585,7 @@ func (s *IPSets) tryResync() (numProblems int, err error) {
 
 			// If we get here, we've read all the members of the IP set.  Compare them
 			// with what we expect and queue up any fixes.
-			numMissing := 0
-			ipSet.members.Iter(func(item interface{}) error {
-				m := item.(ipSetMember)
-				if dataplaneMembers.Contains(m) {
-					// Mainline (correct) case, member is in memory and in the
-					// dataplane.
-					dataplaneMembers.Discard(m)
-					return nil
-				}
-
-				logCxt := logCxt.WithField("member", m.String())
-				numProblems++
-				if ipSet.pendingDeletions.Contains(m) {
-					// We were trying to delete this item anyway, record that
-					// it's already gone.  We commonly hit this case when we're
-					// doing a retry after a failure and we're not sure which
-					// deltas got applied.
-					logCxt.Debug("Resync found member missing from " +
-						"dataplane. (Already queued for deletion.)")
-					ipSet.pendingDeletions.Discard(m)
-					return set.RemoveItem
-				}
-
-				// The item should be in the dataplane but it's not, queue up an
-				// add to add it back in.
-				if numMissing == 0 {
-					logCxt.Warning("Resync found member missing from " +
-						"dataplane. Queueing up an add to reinstate it. " +
-						"Further inconsistencies will be logged at DEBUG.")
-				} else {
-					logCxt.Debug("Found another member missing")
-				}
-				numMissing++
-				s.dirtyIPSetIDs.Add(ipSet.SetID)
-				ipSet.pendingAdds.Add(m)
-				return set.RemoveItem
-			})
-			if numMissing > 0 {
-				logCxt.WithField("numMissing", numMissing).Warn(
-					"Resync found members missing from dataplane.")
-			}
-
-			// Now look for any members which are in the dataplane but are not expected.
-			// We removed the members we were expecting above so dataplaneMembers now
-			// contains only unexpected members.
-			numExtras := 0
-			dataplaneMembers.Iter(func(item interface{}) error {
-				m := item.(ipSetMember)
-				logCxt := logCxt.WithField("member", m.String())
-
-				// Record that this member really is in the dataplane.
-				ipSet.members.Add(m)
-				numProblems++
-
-				if ipSet.pendingAdds.Contains(m) {
-					// We were trying to add this item anyway, record that
-					// it's already there.  We commonly hit this case when we're
-					// doing a retry after a failure and we're not sure which
-					// deltas got applied.
-					logCxt.Debug("Resync found unexpected member in " +
-						"dataplane. (Was about to add it anyway.)")
-					ipSet.pendingAdds.Discard(m)
-					return nil
-				}
-
-				// We weren't planning on adding this member, queue up a deletion.
-				if numExtras == 0 {
-					logCxt.Warning("Resync found unexpected member in " +
-						"dataplane. Queueing it for removal.  Further " +
-						"inconsistencies will be logged at DEBUG.")
-				} else {
-					logCxt.Debug("Found another extra member.")
-				}
-				numExtras++
-				s.dirtyIPSetIDs.Add(ipSet.SetID)
-				ipSet.pendingDeletions.Add(m)
-				return nil
-			})
-			if numExtras > 0 {
-				logCxt.WithField("numExtras", numExtras).Warn(
-					"Resync found extra members in dataplane.")
-			}
+			numProblems += s.resyncIPSetMembers(ipSet, dataplaneMembers)
 		}
 	}
 	closeErr := out.Close()
@@ -576,6 +606,129 @@ func (s *IPSets) tryResync() (numProblems int, err error) {
 		return
 	}
 
+	return
+}
+
+// resyncIPSetMembers compares the members of one of our IP sets, as read back from the dataplane,
+// with what we expect and queues up any fixes.  It returns the number of inconsistencies found.
+func (s *IPSets) resyncIPSetMembers(ipSet *ipSet, dataplaneMembers set.Set) (numProblems int) {
+	logCxt := s.logCxt.WithField("setID", ipSet.SetID)
+	numMissing := 0
+	var firstMissing, firstExtra ipSetMember
+	ipSet.members.Iter(func(item interface{}) error {
+		m := item.(ipSetMember)
+		if dataplaneMembers.Contains(m) {
+			// Mainline (correct) case, member is in memory and in the
+			// dataplane.
+			dataplaneMembers.Discard(m)
+			return nil
+		}
+
+		logCxt := logCxt.WithField("member", m.String())
+		numProblems++
+		if ipSet.pendingDeletions.Contains(m) {
+			// We were trying to delete this item anyway, record that
+			// it's already gone.  We commonly hit this case when we're
+			// doing a retry after a failure and we're not sure which
+			// deltas got applied.
+			logCxt.Debug("Resync found member missing from " +
+				"dataplane. (Already queued for deletion.)")
+			ipSet.pendingDeletions.Discard(m)
+			return set.RemoveItem
+		}
+
+		// The item should be in the dataplane but it's not, queue up an
+		// add to add it back in.
+		if numMissing == 0 {
+			logCxt.Warning("Resync found member missing from " +
+				"dataplane. Queueing up an add to reinstate it. " +
+				"Further inconsistencies will be logged at DEBUG.")
+			firstMissing = m
+		} else {
+			logCxt.Debug("Found another member missing")
+		}
+		numMissing++
+		s.dirtyIPSetIDs.Add(ipSet.SetID)
+		ipSet.pendingAdds.Add(m)
+		return set.RemoveItem
+	})
+	if numMissing > 0 {
+		logCxt.WithField("numMissing", numMissing).Warn(
+			"Resync found members missing from dataplane.")
+		s.reportDrift(ipSet.MainIPSetName, describeMembers(numMissing, "missing member", firstMissing))
+	}
+
+	// Now look for any members which are in the dataplane but are not expected.
+	// We removed the members we were expecting above so dataplaneMembers now
+	// contains only unexpected members.
+	numExtras := 0
+	dataplaneMembers.Iter(func(item interface{}) error {
+		m := item.(ipSetMember)
+		logCxt := logCxt.WithField("member", m.String())
+
+		// Record that this member really is in the dataplane.
+		ipSet.members.Add(m)
+		numProblems++
+
+		if ipSet.pendingAdds.Contains(m) {
+			// We were trying to add this item anyway, record that
+			// it's already there.  We commonly hit this case when we're
+			// doing a retry after a failure and we're not sure which
+			// deltas got applied.
+			logCxt.Debug("Resync found unexpected member in " +
+				"dataplane. (Was about to add it anyway.)")
+			ipSet.pendingAdds.Discard(m)
+			return nil
+		}
+
+		// We weren't planning on adding this member, queue up a deletion.
+		if numExtras == 0 {
+			logCxt.Warning("Resync found unexpected member in " +
+				"dataplane. Queueing it for removal.  Further " +
+				"inconsistencies will be logged at DEBUG.")
+			firstExtra = m
+		} else {
+			logCxt.Debug("Found another extra member.")
+		}
+		numExtras++
+		s.dirtyIPSetIDs.Add(ipSet.SetID)
+		ipSet.pendingDeletions.Add(m)
+		return nil
+	})
+	if numExtras > 0 {
+		logCxt.WithField("numExtras", numExtras).Warn(
+			"Resync found extra members in dataplane.")
+		s.reportDrift(ipSet.MainIPSetName, describeMembers(numExtras, "unexpected member", firstExtra))
+	}
+	return
+}
+
+// describeMembers describes a drift problem affecting num members, giving the first as an example,
+// for example "2 missing members, including 10.0.0.1".
+func describeMembers(num int, problem string, example ipSetMember) string {
+	if num == 1 {
+		return fmt.Sprintf("%s %v", problem, example)
+	}
+	return fmt.Sprintf("%d %ss, including %v", num, problem, example)
+}
+
+// reportDrift tells the drift reporter that the resync found a problem with the given IP set.
+func (s *IPSets) reportDrift(setName, problem string) {
+	if drift.IsExpected(s.resyncReason) {
+		return
+	}
+	s.driftReporter.Report(drift.Event{
+		Subsystem: drift.SubsystemIPSets,
+		Component: s.componentName(),
+		Object:    setName,
+		Problem:   problem,
+		Resync:    s.resyncReason,
+	})
+}
+
+// queueLeftOverIPSetDeletions queues up the deletion of any IP sets in existingIPSetNames that
+// look like ours but that we no longer want.
+func (s *IPSets) queueLeftOverIPSetDeletions() {
 	// Scan for IP sets that need to be cleaned up.  Create a whitelist containing the IP sets
 	// that we expect to be there.
 	expectedIPSets := set.New()
@@ -615,10 +768,38 @@ func (s *IPSets) tryResync() (numProblems int, err error) {
 		}
 		s.logCxt.WithField("setName", setName).Info(
 			"Resync found left-over Calico IP set. Queueing deletion.")
+		if !s.IPVersionConfig.IsTempIPSetName(setName) {
+			s.reportDrift(setName, "unexpected IP set")
+		}
 		s.pendingIPSetDeletions.Add(setName)
 		return nil
 	})
+}
 
+// resyncFromNetlink dumps the IP sets over netlink and queues up updates to any of our IP sets
+// that are out-of-sync.  It also refreshes existingIPSetNames.
+func (s *IPSets) resyncFromNetlink() (numProblems int, err error) {
+	dataplaneSets, err := s.nl.listIPSets(func(setName string) (IPSetType, bool) {
+		ipSet := s.mainIPSetNameToIPSet[setName]
+		if ipSet == nil || ipSet.members == nil {
+			// Either this is not one of our IP sets, or it's one that we're about to
+			// rewrite.  Either way, we don't care about its members.
+			return "", false
+		}
+		return ipSet.Type, true
+	})
+	if err != nil {
+		s.logCxt.WithError(err).Error("Failed to list IP sets over netlink.")
+		return
+	}
+	s.existingIPSetNames.Clear()
+	for setName, dataplaneMembers := range dataplaneSets {
+		s.existingIPSetNames.Add(setName)
+		if dataplaneMembers == nil {
+			continue
+		}
+		numProblems += s.resyncIPSetMembers(s.mainIPSetNameToIPSet[setName], dataplaneMembers)
+	}
 	return
 }
 
@@ -633,6 +814,24 @@ func (s *IPSets) tryUpdates() error {
 
 	s.opReporter.RecordOperation(fmt.Sprint("update-ipsets-", s.IPVersionConfig.Family.Version()))
 
+	if s.dryRun != nil {
+		s.recordDryRunUpdates()
+		return nil
+	}
+
+	startTime := time.Now()
+	var err error
+	if s.nl != nil {
+		err = s.tryUpdatesNetlink()
+	} else {
+		err = s.tryUpdatesIPSetRestore()
+	}
+	s.recordBackendOp("update", startTime, err)
+	return err
+}
+
+// tryUpdatesIPSetRestore writes the pending updates to the dataplane using 'ipset restore'.
+func (s *IPSets) tryUpdatesIPSetRestore() error {
 	// Set up an ipset restore session.
 	countNumIPSetCalls.Inc()
 	cmd := s.newCmd("ipset", "restore")
@@ -701,27 +900,119 @@ func (s *IPSets) tryUpdates() error {
 	// If we get here, the writes were successful, reset the IP sets delta tracking now the
 	// dataplane should be in sync.  If we bail out above, then the resync logic will kick in
 	// and figure out how much of our update succeeded.
+	s.dirtyIPSetIDs.Iter(func(item interface{}) error {
+		s.markIPSetInSync(s.ipSetIDToIPSet[item.(string)])
+		return set.RemoveItem
+	})
+
+	return nil
+}
+
+// recordDryRunUpdates passes the pending updates to the dry-run recorder, in the same form that we
+// would send to 'ipset restore', and then treats them as written.
+func (s *IPSets) recordDryRunUpdates() {
+	var buf bytes.Buffer
 	s.dirtyIPSetIDs.Iter(func(item interface{}) error {
 		ipSet := s.ipSetIDToIPSet[item.(string)]
-		if ipSet.pendingReplace != nil {
-			ipSet.members = ipSet.pendingReplace
-			ipSet.pendingReplace = nil
+		// Writing to a buffer can't fail.
+		_ = s.writeUpdates(ipSet, &buf)
+		s.markIPSetInSync(ipSet)
+		return set.RemoveItem
+	})
+	if buf.Len() > 0 {
+		s.dryRun.Record(s.componentName(), buf.String())
+	}
+}
 
-			// Doing a rewrite creates the main IP set.
-			s.existingIPSetNames.Add(ipSet.MainIPSetName)
-		} else {
-			ipSet.pendingAdds.Iter(func(m interface{}) error {
-				ipSet.members.Add(m)
-				return set.RemoveItem
-			})
-			ipSet.pendingDeletions.Iter(func(m interface{}) error {
-				ipSet.members.Discard(m)
-				return set.RemoveItem
-			})
+// componentName returns the name that we use for this IPSets in dry-run changes and drift events.
+func (s *IPSets) componentName() string {
+	return fmt.Sprint("ipsets-v", s.IPVersionConfig.Family.Version())
+}
+
+// markIPSetInSync updates our record of the IP set's dataplane state after its pending updates
+// have been successfully written.
+func (s *IPSets) markIPSetInSync(ipSet *ipSet) {
+	if ipSet.pendingReplace != nil {
+		ipSet.members = ipSet.pendingReplace
+		ipSet.pendingReplace = nil
+
+		// Doing a rewrite creates the main IP set.
+		s.existingIPSetNames.Add(ipSet.MainIPSetName)
+	} else {
+		ipSet.pendingAdds.Iter(func(m interface{}) error {
+			ipSet.members.Add(m)
+			return set.RemoveItem
+		})
+		ipSet.pendingDeletions.Iter(func(m interface{}) error {
+			ipSet.members.Discard(m)
+			return set.RemoveItem
+		})
+	}
+}
+
+// tryUpdatesNetlink writes the pending updates to the dataplane over netlink.  Unlike the
+// 'ipset restore' path, each IP set is marked as in-sync as soon as its updates succeed so that a
+// failure part way through only requires the remaining IP sets to be retried.
+func (s *IPSets) tryUpdatesNetlink() error {
+	var err error
+	s.dirtyIPSetIDs.Iter(func(item interface{}) error {
+		ipSet := s.ipSetIDToIPSet[item.(string)]
+		err = s.writeUpdatesNetlink(ipSet)
+		if err != nil {
+			s.logCxt.WithError(err).WithField("setID", ipSet.SetID).Warning(
+				"Failed to update IP set over netlink, IP sets may be out-of-sync.")
+			return set.StopIteration
 		}
+		s.markIPSetInSync(ipSet)
 		return set.RemoveItem
 	})
+	return err
+}
+
+func (s *IPSets) writeUpdatesNetlink(ipSet *ipSet) error {
+	logCxt := s.logCxt.WithField("setID", ipSet.SetID)
+	mainSetName := ipSet.MainIPSetName
+	if ipSet.pendingReplace == nil {
+		if ipSet.pendingAdds.Len() == 0 && ipSet.pendingDeletions.Len() == 0 {
+			logCxt.Debug("Skipping delta write, IP set not dirty.")
+			return nil
+		}
+		logCxt.WithFields(log.Fields{
+			"numDeltaAdds":    ipSet.pendingAdds.Len(),
+			"numDeltaDeletes": ipSet.pendingDeletions.Len(),
+		}).Info("Applying deltas to IP set")
+		if err := s.nl.delMembers(mainSetName, ipSet.pendingDeletions); err != nil {
+			return err
+		}
+		return s.nl.addMembers(mainSetName, ipSet.pendingAdds)
+	}
 
+	// Full rewrite, using the same create-temp-then-swap approach as writeFullRewrite.
+	logCxt.WithField("numMembersInPendingReplace", ipSet.pendingReplace.Len()).Info(
+		"Doing full IP set rewrite")
+	if !s.existingIPSetNames.Contains(mainSetName) {
+		logCxt.Debug("Pre-creating main IP set")
+		if err := s.nl.create(mainSetName, ipSet.Type, ipSet.MaxSize); err != nil {
+			return err
+		}
+		s.existingIPSetNames.Add(mainSetName)
+	}
+	tempSetName := s.nextFreeTempIPSetName()
+	if err := s.nl.create(tempSetName, ipSet.Type, ipSet.MaxSize); err != nil {
+		return err
+	}
+	// From here on, if we fail, we leak the temporary IP set until the next resync finds it.
+	s.existingIPSetNames.Add(tempSetName)
+	if err := s.nl.addMembers(tempSetName, ipSet.pendingReplace); err != nil {
+		return err
+	}
+	if err := s.nl.swap(mainSetName, tempSetName); err != nil {
+		return err
+	}
+	if err := s.nl.destroy(tempSetName); err != nil {
+		return err
+	}
+	s.existingIPSetNames.Discard(tempSetName)
 	return nil
 }
 
@@ -904,15 +1195,30 @@ func (s *IPSets) tryTempIPSetDeletions() {
 	})
 }
 
-func (s *IPSets) deleteIPSet(setName string) error {
+func (s *IPSets) deleteIPSet(setName string) (err error) {
 	s.logCxt.WithField("setName", setName).Info("Deleting IP set.")
-	cmd := s.newCmd("ipset", "destroy", string(setName))
-	if output, err := cmd.CombinedOutput(); err != nil {
-		s.logCxt.WithError(err).WithFields(log.Fields{
-			"setName": setName,
-			"output":  string(output),
-		}).Warn("Failed to delete IP set, may be out-of-sync.")
-		return err
+	startTime := time.Now()
+	defer func() {
+		s.recordBackendOp("delete", startTime, err)
+	}()
+	if s.dryRun != nil {
+		s.dryRun.Record(s.componentName(), "destroy "+setName)
+	} else if s.nl != nil {
+		if err = s.nl.destroy(setName); err != nil {
+			s.logCxt.WithError(err).WithField("setName", setName).Warn(
+				"Failed to delete IP set, may be out-of-sync.")
+			return err
+		}
+	} else {
+		cmd := s.newCmd("ipset", "destroy", string(setName))
+		var output []byte
+		if output, err = cmd.CombinedOutput(); err != nil {
+			s.logCxt.WithError(err).WithFields(log.Fields{
+				"setName": setName,
+				"output":  string(output),
+			}).Warn("Failed to delete IP set, may be out-of-sync.")
+			return err
+		}
 	}
 	// Success, update the cache.
 	s.logCxt.WithField("setName", setName).Info("Deleted IP set")
@@ -0,0 +1,480 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsets
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/projectcalico/libcalico-go/lib/set"
+
+	"github.com/projectcalico/felix/ip"
+	"github.com/projectcalico/felix/labelindex"
+	"github.com/projectcalico/felix/nfnetlink"
+)
+
+// Constants from the kernel's uapi/linux/netfilter/ipset/ip_set.h.
+const (
+	// We speak the oldest protocol version that current kernels still accept; it has everything
+	// that we need.
+	ipsetProtocol = 6
+
+	ipsetCmdProtocol = 1
+	ipsetCmdCreate   = 2
+	ipsetCmdDestroy  = 3
+	ipsetCmdSwap     = 6
+	ipsetCmdList     = 7
+	ipsetCmdAdd      = 9
+	ipsetCmdDel      = 10
+	ipsetCmdType     = 13
+
+	// Command-level attributes.
+	ipsetAttrProtocol    = 1
+	ipsetAttrSetName     = 2
+	ipsetAttrTypeName    = 3
+	ipsetAttrSetName2    = ipsetAttrTypeName
+	ipsetAttrRevision    = 4
+	ipsetAttrFamily      = 5
+	ipsetAttrData        = 7
+	ipsetAttrADT         = 8
+	ipsetAttrProtocolMin = 10
+
+	// Create/add/del/test data attributes.
+	ipsetAttrIP      = 1
+	ipsetAttrCIDR    = 3
+	ipsetAttrPort    = 4
+	ipsetAttrProto   = 7
+	ipsetAttrMaxElem = 19
+
+	// IP address attributes, nested inside ipsetAttrIP.
+	ipsetAttrIPAddrIPv4 = 1
+	ipsetAttrIPAddrIPv6 = 2
+
+	// ipset-specific error codes, returned as negative errnos.
+	ipsetErrPrivate = 4096
+)
+
+var ipsetErrorStrings = map[unix.Errno]string{
+	ipsetErrPrivate + 1:  "kernel error received: ipset protocol error",
+	ipsetErrPrivate + 2:  "kernel error received: set type not supported",
+	ipsetErrPrivate + 3:  "kernel error received: maximal number of sets reached",
+	ipsetErrPrivate + 4:  "set cannot be destroyed: it is in use by a kernel component",
+	ipsetErrPrivate + 5:  "the second set does not exist",
+	ipsetErrPrivate + 6:  "the sets cannot be swapped: their type does not match",
+	ipsetErrPrivate + 7:  "set with the same name already exists",
+	ipsetErrPrivate + 8:  "the value of the CIDR parameter of the IP address is invalid",
+	ipsetErrPrivate + 9:  "the value of the netmask parameter is invalid",
+	ipsetErrPrivate + 10: "the protocol family not supported by the set type",
+	ipsetErrPrivate + 11: "timeout cannot be used: set was created without timeout support",
+	ipsetErrPrivate + 12: "set cannot be renamed: it is in use by another system",
+	ipsetErrPrivate + 13: "an IPv4 address is expected",
+	ipsetErrPrivate + 14: "an IPv6 address is expected",
+}
+
+const (
+	// ipsetNetlinkTimeout is the receive timeout for the ipset netlink socket.
+	ipsetNetlinkTimeout = 10 * time.Second
+	// ipsetNetlinkMaxMembersPerMsg limits the number of members that we add/remove in a single
+	// message.  The kernel echoes the whole request back if it fails, so we keep messages well
+	// within the netlink receive buffer size.
+	ipsetNetlinkMaxMembersPerMsg = 256
+)
+
+// ipsetNetlinkConn is the subset of nfnetlink.Conn that we use; shimmed for UT.
+type ipsetNetlinkConn interface {
+	Execute(msg *nfnetlink.Message) ([]nfnetlink.Message, error)
+	DumpFunc(msg *nfnetlink.Message, fn func(m *nfnetlink.Message) error) error
+	Close() error
+}
+
+// ipsetNetlinkError wraps an error returned by the kernel with the operation that triggered it.
+type ipsetNetlinkError struct {
+	Op      string
+	SetName string
+	Err     error
+}
+
+func (e *ipsetNetlinkError) Error() string {
+	errStr := e.Err.Error()
+	var errno unix.Errno
+	if errors.As(e.Err, &errno) {
+		if s, ok := ipsetErrorStrings[errno]; ok {
+			errStr = s
+		}
+	}
+	return fmt.Sprintf("ipset %s %s failed: %s", e.Op, e.SetName, errStr)
+}
+
+func (e *ipsetNetlinkError) Unwrap() error {
+	return e.Err
+}
+
+// ipsetNetlink programs IP sets of a single IP family using the kernel's NFNL_SUBSYS_IPSET
+// netlink API; it offers the same operations that we use the ipset binary for.
+type ipsetNetlink struct {
+	family IPFamily
+	conn   ipsetNetlinkConn
+
+	// revisions caches the latest revision of each set type that the kernel supports.
+	revisions map[IPSetType]uint8
+}
+
+// openIPSetNetlink opens a netlink socket and checks that the kernel speaks a version of the
+// ipset protocol that we understand.
+func openIPSetNetlink(family IPFamily) (*ipsetNetlink, error) {
+	conn, err := nfnetlink.Open(ipsetNetlinkTimeout)
+	if err != nil {
+		return nil, err
+	}
+	n := newIPSetNetlink(family, conn)
+	if err := n.checkProtocol(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return n, nil
+}
+
+func newIPSetNetlink(family IPFamily, conn ipsetNetlinkConn) *ipsetNetlink {
+	return &ipsetNetlink{
+		family:    family,
+		conn:      conn,
+		revisions: map[IPSetType]uint8{},
+	}
+}
+
+func (n *ipsetNetlink) Close() error {
+	return n.conn.Close()
+}
+
+func (n *ipsetNetlink) nfproto() uint8 {
+	if n.family == IPFamilyV6 {
+		return unix.NFPROTO_IPV6
+	}
+	return unix.NFPROTO_IPV4
+}
+
+func (n *ipsetNetlink) newMessage(cmd uint8, flags uint16, fill func(e *nfnetlink.AttrEncoder)) *nfnetlink.Message {
+	var e nfnetlink.AttrEncoder
+	e.Uint8(ipsetAttrProtocol, ipsetProtocol)
+	if fill != nil {
+		fill(&e)
+	}
+	return &nfnetlink.Message{
+		Type:  nfnetlink.MsgType(nfnetlink.SubsysIPSet, cmd),
+		Flags: flags,
+		// The ipset tool always uses AF_INET here; the real family is in the attributes.
+		Family: unix.AF_INET,
+		Attrs:  e.Encode(),
+	}
+}
+
+func (n *ipsetNetlink) execute(op, setName string, msg *nfnetlink.Message) ([]nfnetlink.Message, error) {
+	replies, err := n.conn.Execute(msg)
+	if err != nil {
+		return nil, &ipsetNetlinkError{Op: op, SetName: setName, Err: err}
+	}
+	return replies, nil
+}
+
+// checkProtocol verifies that the kernel supports our version of the protocol.
+func (n *ipsetNetlink) checkProtocol() error {
+	replies, err := n.execute("protocol", "", n.newMessage(ipsetCmdProtocol, 0, nil))
+	if err != nil {
+		return err
+	}
+	for _, r := range replies {
+		attrs, err := nfnetlink.ParseAttrMap(r.Attrs)
+		if err != nil {
+			return err
+		}
+		kernelMax, ok := attrs[ipsetAttrProtocol]
+		if !ok || len(kernelMax) < 1 {
+			continue
+		}
+		kernelMin := kernelMax
+		if v, ok := attrs[ipsetAttrProtocolMin]; ok && len(v) >= 1 {
+			kernelMin = v
+		}
+		if kernelMax[0] < ipsetProtocol || kernelMin[0] > ipsetProtocol {
+			return fmt.Errorf("kernel ipset protocol versions %d-%d do not include version %d",
+				kernelMin[0], kernelMax[0], ipsetProtocol)
+		}
+		log.WithField("kernelVersion", kernelMax[0]).Debug("Kernel supports our ipset protocol version")
+		return nil
+	}
+	return errors.New("no protocol version in kernel's ipset protocol response")
+}
+
+// typeRevision returns the latest revision of the given set type that the kernel supports.  The
+// ipset tool does the same negotiation (limited to the revisions that it knows about); since we
+// only use the basic features of each type, any revision will do.
+func (n *ipsetNetlink) typeRevision(t IPSetType) (uint8, error) {
+	if rev, ok := n.revisions[t]; ok {
+		return rev, nil
+	}
+	msg := n.newMessage(ipsetCmdType, 0, func(e *nfnetlink.AttrEncoder) {
+		e.String(ipsetAttrTypeName, string(t))
+		e.Uint8(ipsetAttrFamily, n.nfproto())
+	})
+	replies, err := n.execute("type", string(t), msg)
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range replies {
+		attrs, err := nfnetlink.ParseAttrMap(r.Attrs)
+		if err != nil {
+			return 0, err
+		}
+		if rev, ok := attrs[ipsetAttrRevision]; ok && len(rev) >= 1 {
+			n.revisions[t] = rev[0]
+			return rev[0], nil
+		}
+	}
+	return 0, fmt.Errorf("kernel did not report a revision for IP set type %s", t)
+}
+
+// create creates a new IP set; like "ipset create" without "-exist", it fails if the set already
+// exists.
+func (n *ipsetNetlink) create(setName string, t IPSetType, maxSize int) error {
+	rev, err := n.typeRevision(t)
+	if err != nil {
+		return err
+	}
+	msg := n.newMessage(ipsetCmdCreate, nfnetlink.FlagExcl, func(e *nfnetlink.AttrEncoder) {
+		e.String(ipsetAttrSetName, setName)
+		e.String(ipsetAttrTypeName, string(t))
+		e.Uint8(ipsetAttrRevision, rev)
+		e.Uint8(ipsetAttrFamily, n.nfproto())
+		e.Nested(ipsetAttrData, func(e *nfnetlink.AttrEncoder) {
+			e.Uint32BE(ipsetAttrMaxElem|nfnetlink.AttrNetByteOrder, uint32(maxSize))
+		})
+	})
+	_, err = n.execute("create", setName, msg)
+	return err
+}
+
+func (n *ipsetNetlink) destroy(setName string) error {
+	msg := n.newMessage(ipsetCmdDestroy, 0, func(e *nfnetlink.AttrEncoder) {
+		e.String(ipsetAttrSetName, setName)
+	})
+	_, err := n.execute("destroy", setName, msg)
+	return err
+}
+
+func (n *ipsetNetlink) swap(setName, otherSetName string) error {
+	msg := n.newMessage(ipsetCmdSwap, 0, func(e *nfnetlink.AttrEncoder) {
+		e.String(ipsetAttrSetName, setName)
+		e.String(ipsetAttrSetName2, otherSetName)
+	})
+	_, err := n.execute("swap", setName, msg)
+	return err
+}
+
+// addMembers adds the given set of ipSetMembers to the IP set.  Members that are already
+// present are ignored.
+func (n *ipsetNetlink) addMembers(setName string, members set.Set) error {
+	return n.modifyMembers(ipsetCmdAdd, "add", setName, members)
+}
+
+// delMembers removes the given set of ipSetMembers from the IP set.  Members that are already
+// absent are ignored (like "ipset del --exist").
+func (n *ipsetNetlink) delMembers(setName string, members set.Set) error {
+	return n.modifyMembers(ipsetCmdDel, "del", setName, members)
+}
+
+func (n *ipsetNetlink) modifyMembers(cmd uint8, op, setName string, members set.Set) error {
+	var err error
+	var batch []ipSetMember
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		// Omitting NLM_F_EXCL is the equivalent of the ipset tool's -exist flag.
+		msg := n.newMessage(cmd, 0, func(e *nfnetlink.AttrEncoder) {
+			e.String(ipsetAttrSetName, setName)
+			e.Nested(ipsetAttrADT, func(e *nfnetlink.AttrEncoder) {
+				for _, m := range batch {
+					if err != nil {
+						return
+					}
+					e.Nested(ipsetAttrData, func(e *nfnetlink.AttrEncoder) {
+						err = encodeIPSetMember(e, m)
+					})
+				}
+			})
+		})
+		if err == nil {
+			_, err = n.execute(op, setName, msg)
+		}
+		if err == nil {
+			countNumIPSetLinesExecuted.Add(float64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+	members.Iter(func(item interface{}) error {
+		batch = append(batch, item.(ipSetMember))
+		if len(batch) >= ipsetNetlinkMaxMembersPerMsg {
+			flush()
+			if err != nil {
+				return set.StopIteration
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		flush()
+	}
+	return err
+}
+
+// listIPSets dumps all the IP sets in the kernel.  It returns a map containing the name of every
+// IP set.  For sets where decodeAs returns true, the value is the set of members in their
+// canonical form (as returned by IPSetType.CanonicaliseMember); for other sets, it is nil.
+func (n *ipsetNetlink) listIPSets(decodeAs func(setName string) (IPSetType, bool)) (map[string]set.Set, error) {
+	sets := map[string]set.Set{}
+	msg := n.newMessage(ipsetCmdList, 0, nil)
+	err := n.conn.DumpFunc(msg, func(m *nfnetlink.Message) error {
+		attrs, err := nfnetlink.ParseAttrs(m.Attrs)
+		if err != nil {
+			return err
+		}
+		// A large IP set is split over several messages, each of which has the set name
+		// followed by some of its members.
+		var setName string
+		var members set.Set
+		var setType IPSetType
+		for _, a := range attrs {
+			switch a.Type {
+			case ipsetAttrSetName:
+				setName = a.String()
+				var known, decode bool
+				setType, decode = decodeAs(setName)
+				members, known = sets[setName]
+				if !known {
+					if decode {
+						members = set.New()
+					}
+					sets[setName] = members
+				}
+			case ipsetAttrADT:
+				if members == nil {
+					continue
+				}
+				entries, err := nfnetlink.ParseAttrs(a.Value)
+				if err != nil {
+					return err
+				}
+				for _, entry := range entries {
+					if entry.Type != ipsetAttrData {
+						continue
+					}
+					member, err := decodeIPSetMember(setType, entry.Value)
+					if err != nil {
+						return fmt.Errorf("failed to decode member of IP set %s: %w", setName, err)
+					}
+					members.Add(member)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, &ipsetNetlinkError{Op: "list", Err: err}
+	}
+	return sets, nil
+}
+
+func encodeIPSetMember(e *nfnetlink.AttrEncoder, member ipSetMember) error {
+	switch m := member.(type) {
+	case ip.Addr:
+		encodeIPSetAddr(e, m)
+	case V4IPPort:
+		encodeIPSetAddr(e, m.IP)
+		e.Uint16BE(ipsetAttrPort|nfnetlink.AttrNetByteOrder, m.Port)
+		e.Uint8(ipsetAttrProto, uint8(m.Protocol))
+	case V6IPPort:
+		encodeIPSetAddr(e, m.IP)
+		e.Uint16BE(ipsetAttrPort|nfnetlink.AttrNetByteOrder, m.Port)
+		e.Uint8(ipsetAttrProto, uint8(m.Protocol))
+	case ip.CIDR:
+		encodeIPSetAddr(e, m.Addr())
+		e.Uint8(ipsetAttrCIDR, m.Prefix())
+	default:
+		return fmt.Errorf("unexpected IP set member type %T", member)
+	}
+	return nil
+}
+
+func encodeIPSetAddr(e *nfnetlink.AttrEncoder, addr ip.Addr) {
+	e.Nested(ipsetAttrIP, func(e *nfnetlink.AttrEncoder) {
+		switch a := addr.(type) {
+		case ip.V4Addr:
+			e.Bytes(ipsetAttrIPAddrIPv4|nfnetlink.AttrNetByteOrder, a[:])
+		case ip.V6Addr:
+			e.Bytes(ipsetAttrIPAddrIPv6|nfnetlink.AttrNetByteOrder, a[:])
+		}
+	})
+}
+
+// decodeIPSetMember converts the data attributes of an IP set entry to the same canonical form
+// that IPSetType.CanonicaliseMember would return for its textual representation.
+func decodeIPSetMember(t IPSetType, data []byte) (ipSetMember, error) {
+	attrs, err := nfnetlink.ParseAttrMap(data)
+	if err != nil {
+		return nil, err
+	}
+	ipAttrs, err := nfnetlink.ParseAttrMap(attrs[ipsetAttrIP])
+	if err != nil {
+		return nil, err
+	}
+	var addr ip.Addr
+	if v, ok := ipAttrs[ipsetAttrIPAddrIPv4]; ok && len(v) == 4 {
+		var a ip.V4Addr
+		copy(a[:], v)
+		addr = a
+	} else if v, ok := ipAttrs[ipsetAttrIPAddrIPv6]; ok && len(v) == 16 {
+		var a ip.V6Addr
+		copy(a[:], v)
+		addr = a
+	} else {
+		return nil, errors.New("entry has no IP address")
+	}
+
+	switch t {
+	case IPSetTypeHashIP:
+		return addr, nil
+	case IPSetTypeHashIPPort:
+		port := nfnetlink.AttrUint16BE(attrs[ipsetAttrPort])
+		var proto labelindex.IPSetPortProtocol
+		if v := attrs[ipsetAttrProto]; len(v) >= 1 {
+			proto = labelindex.IPSetPortProtocol(v[0])
+		}
+		if v4, ok := addr.(ip.V4Addr); ok {
+			return V4IPPort{IP: v4, Port: port, Protocol: proto}, nil
+		}
+		return V6IPPort{IP: addr.(ip.V6Addr), Port: port, Protocol: proto}, nil
+	case IPSetTypeHashNet:
+		prefixLen := len(addr.AsNetIP()) * 8
+		if v := attrs[ipsetAttrCIDR]; len(v) >= 1 {
+			prefixLen = int(v[0])
+		}
+		return ip.CIDRFromAddrAndPrefix(addr, prefixLen), nil
+	}
+	return nil, fmt.Errorf("unknown IP set type %q", t)
+}
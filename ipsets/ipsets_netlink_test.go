@@ -0,0 +1,401 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsets
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+
+	"github.com/projectcalico/felix/drift"
+	"github.com/projectcalico/felix/dryrun"
+	"github.com/projectcalico/felix/logutils"
+	"github.com/projectcalico/felix/nfnetlink"
+)
+
+var _ = DescribeTable("netlink IP set member encoding",
+	func(t IPSetType, member string) {
+		canon := t.CanonicaliseMember(member)
+		var e nfnetlink.AttrEncoder
+		Expect(encodeIPSetMember(&e, canon)).To(Succeed())
+		decoded, err := decodeIPSetMember(t, e.Encode())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(Equal(canon))
+	},
+	Entry("IPv4 hash:ip", IPSetTypeHashIP, "10.0.0.1"),
+	Entry("IPv6 hash:ip", IPSetTypeHashIP, "feed:beef::1"),
+	Entry("IPv4 hash:ip,port", IPSetTypeHashIPPort, "10.0.0.1,tcp:8080"),
+	Entry("IPv6 hash:ip,port", IPSetTypeHashIPPort, "feed::1,sctp:1234"),
+	Entry("IPv4 hash:net", IPSetTypeHashNet, "10.0.0.0/16"),
+	Entry("IPv4 hash:net single IP", IPSetTypeHashNet, "10.0.0.1"),
+	Entry("IPv6 hash:net", IPSetTypeHashNet, "feed:beef::/64"),
+)
+
+var _ = Describe("IP sets over netlink", func() {
+	var kernel *mockIPSetKernel
+	var ipsets *IPSets
+
+	meta := IPSetMetadata{
+		MaxSize: 1234,
+		SetID:   "s:qMt7iLlGDhvLnCjM0l9nzxbabcd",
+		Type:    IPSetTypeHashIP,
+	}
+	v4VersionConf := NewIPVersionConfig(IPFamilyV4, "cali", nil, nil)
+	mainName := v4VersionConf.NameForMainIPSet(meta.SetID)
+
+	apply := func() {
+		ipsets.ApplyUpdates()
+		ipsets.ApplyDeletions()
+	}
+
+	BeforeEach(func() {
+		kernel = newMockIPSetKernel()
+		ipsets = NewIPSetsWithShims(
+			v4VersionConf,
+			logutils.NewSummarizer("test loop"),
+			func(name string, arg ...string) CmdIface {
+				Fail("Unexpected exec of " + name)
+				return nil
+			},
+			func(time.Duration) {},
+		)
+		ipsets.nl = newIPSetNetlink(IPFamilyV4, kernel)
+		Expect(ipsets.nl.checkProtocol()).To(Succeed())
+	})
+
+	It("should create an IP set with a full rewrite", func() {
+		ipsets.AddOrReplaceIPSet(meta, []string{"10.0.0.1", "10.0.0.2"})
+		apply()
+		Expect(kernel.setNames()).To(ConsistOf(mainName))
+		Expect(kernel.members(mainName)).To(ConsistOf("10.0.0.1", "10.0.0.2"))
+		Expect(kernel.sets[mainName].maxElem).To(Equal(uint32(1234)))
+	})
+
+	It("should rewrite an existing IP set via a temporary IP set", func() {
+		ipsets.AddOrReplaceIPSet(meta, []string{"10.0.0.1"})
+		apply()
+		ipsets.AddOrReplaceIPSet(meta, []string{"10.0.0.2"})
+		apply()
+		Expect(kernel.setNames()).To(ConsistOf(mainName))
+		Expect(kernel.members(mainName)).To(ConsistOf("10.0.0.2"))
+		Expect(kernel.numSwaps).To(Equal(2))
+	})
+
+	It("should apply deltas without a rewrite", func() {
+		ipsets.AddOrReplaceIPSet(meta, []string{"10.0.0.1", "10.0.0.2"})
+		apply()
+		ipsets.AddMembers(meta.SetID, []string{"10.0.0.3"})
+		ipsets.RemoveMembers(meta.SetID, []string{"10.0.0.1"})
+		apply()
+		Expect(kernel.members(mainName)).To(ConsistOf("10.0.0.2", "10.0.0.3"))
+		Expect(kernel.numSwaps).To(Equal(1))
+	})
+
+	It("should split large updates over several messages", func() {
+		var members []string
+		for i := 0; i < ipsetNetlinkMaxMembersPerMsg+10; i++ {
+			members = append(members, v4Addr(i))
+		}
+		ipsets.AddOrReplaceIPSet(meta, members)
+		apply()
+		Expect(kernel.members(mainName)).To(HaveLen(ipsetNetlinkMaxMembersPerMsg + 10))
+		Expect(kernel.numAddMsgs).To(Equal(2))
+	})
+
+	It("should fix up the dataplane on resync", func() {
+		ipsets.AddOrReplaceIPSet(meta, []string{"10.0.0.1", "10.0.0.2"})
+		apply()
+
+		// Remove one of our members, add an unexpected one and leak some sets.
+		kernel.sets[mainName].del("10.0.0.1")
+		kernel.sets[mainName].add("10.0.0.3")
+		kernel.addSet("cali40s:unknown", "10.0.0.1")
+		kernel.addSet("cali4t0")
+		kernel.addSet("not-ours", "10.0.0.1")
+
+		ipsets.QueueResync()
+		apply()
+
+		Expect(kernel.setNames()).To(ConsistOf(mainName, "not-ours"))
+		Expect(kernel.members(mainName)).To(ConsistOf("10.0.0.1", "10.0.0.2"))
+	})
+
+	It("should not report differences found by the startup resync", func() {
+		reporter := drift.NewReporter(0, "")
+		ipsets.driftReporter = reporter
+		kernel.addSet("cali40s:unknown", "10.0.0.1")
+		ipsets.AddOrReplaceIPSet(meta, []string{"10.0.0.1"})
+		apply()
+
+		Expect(kernel.setNames()).To(ConsistOf(mainName))
+		events, _ := reporter.Events()
+		Expect(events).To(BeEmpty())
+	})
+
+	It("should report drift found by a resync", func() {
+		reporter := drift.NewReporter(0, "")
+		ipsets.driftReporter = reporter
+		ipsets.AddOrReplaceIPSet(meta, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"})
+		apply()
+
+		kernel.sets[mainName].del("10.0.0.1")
+		kernel.sets[mainName].del("10.0.0.2")
+		kernel.sets[mainName].add("10.0.0.4")
+		kernel.addSet("cali40s:unknown", "10.0.0.1")
+		kernel.addSet("cali4t0")
+
+		ipsets.QueueResync()
+		apply()
+
+		events, _ := reporter.Events()
+		var problems []string
+		for _, e := range events {
+			Expect(e.Subsystem).To(Equal(drift.SubsystemIPSets))
+			Expect(e.Component).To(Equal("ipsets-v4"))
+			Expect(e.Resync).To(Equal("refresh"))
+			problems = append(problems, e.Object+": "+e.Problem)
+		}
+		Expect(problems).To(ConsistOf(
+			MatchRegexp(`^`+regexp.QuoteMeta(mainName)+`: 2 missing members, including 10\.0\.0\.[12]$`),
+			mainName+": unexpected member 10.0.0.4",
+			"cali40s:unknown: unexpected IP set",
+		))
+	})
+
+	It("should delete a removed IP set", func() {
+		ipsets.AddOrReplaceIPSet(meta, []string{"10.0.0.1"})
+		apply()
+		ipsets.RemoveIPSet(meta.SetID)
+		apply()
+		Expect(kernel.setNames()).To(BeEmpty())
+	})
+
+	It("should recover from a failed update with a resync", func() {
+		ipsets.AddOrReplaceIPSet(meta, []string{"10.0.0.1"})
+		kernel.failNextCmd[ipsetCmdSwap] = unix.Errno(ipsetErrPrivate + 6)
+		apply()
+		Expect(kernel.setNames()).To(ConsistOf(mainName))
+		Expect(kernel.members(mainName)).To(ConsistOf("10.0.0.1"))
+	})
+
+	It("should record the updates instead of writing them in dry-run mode", func() {
+		recorder := dryrun.NewRecorder(0)
+		ipsets.dryRun = recorder
+		kernel.addSet("cali40s:unknown", "10.0.0.1")
+
+		ipsets.AddOrReplaceIPSet(meta, []string{"10.0.0.1"})
+		apply()
+		ipsets.AddMembers(meta.SetID, []string{"10.0.0.2"})
+		apply()
+
+		Expect(kernel.setNames()).To(ConsistOf("cali40s:unknown"))
+		changes, _ := recorder.Changes()
+		var recorded []string
+		for _, c := range changes {
+			Expect(c.Component).To(Equal("ipsets-v4"))
+			recorded = append(recorded, c.Change)
+		}
+		tempName := v4VersionConf.NameForTempIPSet(0)
+		Expect(recorded).To(Equal([]string{
+			fmt.Sprintf("create %s hash:ip family inet maxelem 1234\n", mainName) +
+				fmt.Sprintf("create %s hash:ip family inet maxelem 1234\n", tempName) +
+				fmt.Sprintf("add %s 10.0.0.1\n", tempName) +
+				fmt.Sprintf("swap %s %s\n", mainName, tempName) +
+				fmt.Sprintf("destroy %s\n", tempName),
+			"destroy cali40s:unknown",
+			fmt.Sprintf("add %s 10.0.0.2\n", mainName),
+		}))
+	})
+})
+
+func v4Addr(i int) string {
+	return fmt.Sprintf("10.1.%d.%d", i>>8, i&0xff)
+}
+
+// mockIPSetKernel simulates the kernel's ipset netlink API for hash:ip sets.
+type mockIPSetKernel struct {
+	sets        map[string]*mockKernelSet
+	failNextCmd map[uint8]error
+	numSwaps    int
+	numAddMsgs  int
+}
+
+type mockKernelSet struct {
+	typ     string
+	maxElem uint32
+	// members maps from IP address to the raw data attributes that we were sent.
+	members map[string][]byte
+}
+
+func newMockIPSetKernel() *mockIPSetKernel {
+	return &mockIPSetKernel{
+		sets:        map[string]*mockKernelSet{},
+		failNextCmd: map[uint8]error{},
+	}
+}
+
+func (k *mockIPSetKernel) addSet(name string, members ...string) {
+	s := &mockKernelSet{typ: string(IPSetTypeHashIP), members: map[string][]byte{}}
+	for _, m := range members {
+		s.add(m)
+	}
+	k.sets[name] = s
+}
+
+func (k *mockIPSetKernel) setNames() []string {
+	var names []string
+	for n := range k.sets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (k *mockIPSetKernel) members(name string) []string {
+	var members []string
+	for m := range k.sets[name].members {
+		members = append(members, m)
+	}
+	return members
+}
+
+func (s *mockKernelSet) add(member string) {
+	var e nfnetlink.AttrEncoder
+	Expect(encodeIPSetMember(&e, IPSetTypeHashIP.CanonicaliseMember(member))).To(Succeed())
+	s.members[member] = e.Encode()
+}
+
+func (s *mockKernelSet) del(member string) {
+	delete(s.members, member)
+}
+
+func (k *mockIPSetKernel) Execute(msg *nfnetlink.Message) ([]nfnetlink.Message, error) {
+	Expect(msg.Subsys()).To(Equal(uint8(nfnetlink.SubsysIPSet)))
+	attrs, err := nfnetlink.ParseAttrMap(msg.Attrs)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(attrs[ipsetAttrProtocol]).To(Equal([]byte{ipsetProtocol}))
+	if err, ok := k.failNextCmd[msg.Msg()]; ok {
+		delete(k.failNextCmd, msg.Msg())
+		return nil, err
+	}
+	name := nfnetlink.AttrString(attrs[ipsetAttrSetName])
+
+	reply := func(fill func(e *nfnetlink.AttrEncoder)) []nfnetlink.Message {
+		var e nfnetlink.AttrEncoder
+		fill(&e)
+		return []nfnetlink.Message{{Type: msg.Type, Attrs: e.Encode()}}
+	}
+
+	switch msg.Msg() {
+	case ipsetCmdProtocol:
+		return reply(func(e *nfnetlink.AttrEncoder) {
+			e.Uint8(ipsetAttrProtocol, 7)
+			e.Uint8(ipsetAttrProtocolMin, 6)
+		}), nil
+	case ipsetCmdType:
+		return reply(func(e *nfnetlink.AttrEncoder) {
+			e.Uint8(ipsetAttrRevision, 4)
+		}), nil
+	case ipsetCmdCreate:
+		Expect(msg.Flags & nfnetlink.FlagExcl).NotTo(BeZero())
+		Expect(attrs[ipsetAttrRevision]).To(Equal([]byte{4}))
+		Expect(attrs[ipsetAttrFamily]).To(Equal([]byte{unix.NFPROTO_IPV4}))
+		if _, ok := k.sets[name]; ok {
+			return nil, unix.Errno(ipsetErrPrivate + 7)
+		}
+		data, err := nfnetlink.ParseAttrMap(attrs[ipsetAttrData])
+		Expect(err).NotTo(HaveOccurred())
+		k.sets[name] = &mockKernelSet{
+			typ:     nfnetlink.AttrString(attrs[ipsetAttrTypeName]),
+			maxElem: nfnetlink.AttrUint32BE(data[ipsetAttrMaxElem]),
+			members: map[string][]byte{},
+		}
+	case ipsetCmdDestroy:
+		if _, ok := k.sets[name]; !ok {
+			return nil, unix.ENOENT
+		}
+		delete(k.sets, name)
+	case ipsetCmdSwap:
+		other := nfnetlink.AttrString(attrs[ipsetAttrSetName2])
+		if k.sets[name] == nil || k.sets[other] == nil {
+			return nil, unix.ENOENT
+		}
+		k.sets[name], k.sets[other] = k.sets[other], k.sets[name]
+		k.numSwaps++
+	case ipsetCmdAdd, ipsetCmdDel:
+		s := k.sets[name]
+		if s == nil {
+			return nil, unix.ENOENT
+		}
+		if msg.Msg() == ipsetCmdAdd {
+			k.numAddMsgs++
+		}
+		entries, err := nfnetlink.ParseAttrs(attrs[ipsetAttrADT])
+		Expect(err).NotTo(HaveOccurred())
+		for _, entry := range entries {
+			member, err := decodeIPSetMember(IPSetTypeHashIP, entry.Value)
+			Expect(err).NotTo(HaveOccurred())
+			if msg.Msg() == ipsetCmdAdd {
+				s.members[member.String()] = entry.Value
+			} else {
+				delete(s.members, member.String())
+			}
+		}
+	default:
+		Fail("Unexpected ipset command")
+	}
+	return nil, nil
+}
+
+func (k *mockIPSetKernel) DumpFunc(msg *nfnetlink.Message, fn func(m *nfnetlink.Message) error) error {
+	Expect(msg.Msg()).To(Equal(uint8(ipsetCmdList)))
+	for _, name := range k.setNames() {
+		s := k.sets[name]
+		var members []string
+		for m := range s.members {
+			members = append(members, m)
+		}
+		sort.Strings(members)
+		// Split each set over several messages, like the kernel does for large sets.
+		for first := true; first || len(members) > 0; first = false {
+			var e nfnetlink.AttrEncoder
+			e.Uint8(ipsetAttrProtocol, 7)
+			e.String(ipsetAttrSetName, name)
+			if first {
+				e.String(ipsetAttrTypeName, s.typ)
+			}
+			e.Nested(ipsetAttrADT, func(e *nfnetlink.AttrEncoder) {
+				for i := 0; i < 2 && len(members) > 0; i++ {
+					e.Bytes(ipsetAttrData|nfnetlink.AttrNested, s.members[members[0]])
+					members = members[1:]
+				}
+			})
+			if err := fn(&nfnetlink.Message{Type: msg.Type, Attrs: e.Encode()}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (k *mockIPSetKernel) Close() error {
+	return nil
+}
@@ -111,6 +111,25 @@ func (g LogAction) String() string {
 	return "Log"
 }
 
+// NflogAction copies the packet to the given NFLOG group for a userspace listener.  The number of
+// bytes copied is controlled by the listener.
+type NflogAction struct {
+	Group     uint16
+	Prefix    string
+	TypeNflog struct{}
+}
+
+func (n NflogAction) ToFragment(features *Features) string {
+	if n.Prefix == "" {
+		return fmt.Sprintf("--jump NFLOG --nflog-group %d", n.Group)
+	}
+	return fmt.Sprintf(`--jump NFLOG --nflog-group %d --nflog-prefix "%s"`, n.Group, n.Prefix)
+}
+
+func (n NflogAction) String() string {
+	return fmt.Sprintf("Nflog:%d", n.Group)
+}
+
 type AcceptAction struct {
 	TypeAccept struct{}
 }
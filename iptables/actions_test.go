@@ -31,6 +31,8 @@ var _ = DescribeTable("Actions",
 	Entry("DropAction", Features{}, DropAction{}, "--jump DROP"),
 	Entry("AcceptAction", Features{}, AcceptAction{}, "--jump ACCEPT"),
 	Entry("LogAction", Features{}, LogAction{Prefix: "prefix"}, `--jump LOG --log-prefix "prefix: " --log-level 5`),
+	Entry("NflogAction", Features{}, NflogAction{Group: 3}, "--jump NFLOG --nflog-group 3"),
+	Entry("NflogAction with prefix", Features{}, NflogAction{Group: 3, Prefix: "dns"}, `--jump NFLOG --nflog-group 3 --nflog-prefix "dns"`),
 	Entry("DNATAction", Features{}, DNATAction{DestAddr: "10.0.0.1", DestPort: 8081}, "--jump DNAT --to-destination 10.0.0.1:8081"),
 	Entry("SNATAction", Features{}, SNATAction{ToAddr: "10.0.0.1"}, "--jump SNAT --to-source 10.0.0.1"),
 	Entry("SNATAction fully random", Features{SNATFullyRandom: true}, SNATAction{ToAddr: "10.0.0.1"}, "--jump SNAT --to-source 10.0.0.1 --random-fully"),
@@ -0,0 +1,130 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iptables_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/drift"
+	. "github.com/projectcalico/felix/iptables"
+	"github.com/projectcalico/felix/logutils"
+	"github.com/projectcalico/felix/rules"
+)
+
+var _ = Describe("Table drift reporting", func() {
+	var dataplane *mockDataplane
+	var table *Table
+	var reporter *drift.Reporter
+	BeforeEach(func() {
+		dataplane = newMockDataplane("filter", map[string][]string{
+			"FORWARD": {},
+			"INPUT":   {},
+			"OUTPUT":  {},
+			// Left over from a previous run.
+			"cali-stale": {},
+		}, "legacy")
+		featureDetector := NewFeatureDetector(nil)
+		featureDetector.NewCmd = dataplane.newCmd
+		featureDetector.GetKernelVersionReader = dataplane.getKernelVersionReader
+		reporter = drift.NewReporter(0, "")
+		table = NewTable(
+			"filter",
+			4,
+			rules.RuleHashPrefix,
+			&mockMutex{},
+			featureDetector,
+			TableOptions{
+				HistoricChainPrefixes: rules.AllHistoricChainNamePrefixes,
+				NewCmdOverride:        dataplane.newCmd,
+				SleepOverride:         dataplane.sleep,
+				NowOverride:           dataplane.now,
+				BackendMode:           "legacy",
+				LookPathOverride:      lookPathNoLegacy,
+				OpRecorder:            logutils.NewSummarizer("test loop"),
+				RefreshInterval:       30 * time.Second,
+				DriftReporter:         reporter,
+			},
+		)
+		table.InsertOrAppendRules("FORWARD", []Rule{
+			{Action: JumpAction{Target: "cali-foobar"}},
+		})
+		table.UpdateChain(&Chain{Name: "cali-foobar", Rules: []Rule{
+			{Action: AcceptAction{}},
+			{Action: DropAction{}},
+		}})
+		table.Apply()
+	})
+
+	It("should not report the differences found by the startup resync", func() {
+		events, _ := reporter.Events()
+		Expect(events).To(BeEmpty())
+		Expect(dataplane.Chains).NotTo(HaveKey("cali-stale"))
+	})
+
+	It("should report nothing if the dataplane is in sync", func() {
+		dataplane.AdvanceTimeBy(31 * time.Second)
+		table.Apply()
+		events, _ := reporter.Events()
+		Expect(events).To(BeEmpty())
+	})
+
+	Describe("after another process modifies the table", func() {
+		BeforeEach(func() {
+			dataplane.Chains["cali-foobar"] = dataplane.Chains["cali-foobar"][:1]
+			dataplane.Chains["FORWARD"] = append(dataplane.Chains["FORWARD"], "-j cali-foobar")
+			dataplane.Chains["cali-unexpected"] = []string{}
+			dataplane.AdvanceTimeBy(31 * time.Second)
+			table.Apply()
+		})
+
+		It("should report each out-of-sync chain", func() {
+			events, _ := reporter.Events()
+			for i := range events {
+				events[i].Time = time.Time{}
+			}
+			Expect(events).To(ConsistOf(
+				drift.Event{
+					Subsystem: drift.SubsystemIptables,
+					Component: "filter-v4",
+					Object:    "cali-foobar",
+					Problem:   "rules out of sync (expected 2 rules, found 1)",
+					Resync:    "refresh timer",
+				},
+				drift.Event{
+					Subsystem: drift.SubsystemIptables,
+					Component: "filter-v4",
+					Object:    "FORWARD",
+					Problem:   "inserted rules out of sync",
+					Resync:    "refresh timer",
+				},
+				drift.Event{
+					Subsystem: drift.SubsystemIptables,
+					Component: "filter-v4",
+					Object:    "cali-unexpected",
+					Problem:   "unexpected chain",
+					Resync:    "refresh timer",
+				},
+			))
+		})
+
+		It("should still fix the chains", func() {
+			Expect(dataplane.Chains["cali-foobar"]).To(HaveLen(2))
+			Expect(dataplane.Chains).NotTo(HaveKey("cali-unexpected"))
+		})
+	})
+})
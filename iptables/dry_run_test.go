@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iptables_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/dryrun"
+	. "github.com/projectcalico/felix/iptables"
+	"github.com/projectcalico/felix/logutils"
+	"github.com/projectcalico/felix/rules"
+)
+
+var _ = Describe("Table in dry-run mode", func() {
+	var dataplane *mockDataplane
+	var table *Table
+	var recorder *dryrun.Recorder
+	BeforeEach(func() {
+		dataplane = newMockDataplane("filter", map[string][]string{
+			"FORWARD": {},
+			"INPUT":   {},
+			"OUTPUT":  {},
+		}, "legacy")
+		featureDetector := NewFeatureDetector(nil)
+		featureDetector.NewCmd = dataplane.newCmd
+		featureDetector.GetKernelVersionReader = dataplane.getKernelVersionReader
+		recorder = dryrun.NewRecorder(0)
+		table = NewTable(
+			"filter",
+			4,
+			rules.RuleHashPrefix,
+			&mockMutex{},
+			featureDetector,
+			TableOptions{
+				HistoricChainPrefixes: rules.AllHistoricChainNamePrefixes,
+				NewCmdOverride:        dataplane.newCmd,
+				SleepOverride:         dataplane.sleep,
+				NowOverride:           dataplane.now,
+				BackendMode:           "legacy",
+				LookPathOverride:      lookPathNoLegacy,
+				OpRecorder:            logutils.NewSummarizer("test loop"),
+				DryRun:                recorder,
+			},
+		)
+	})
+
+	It("should record the update instead of running iptables-restore", func() {
+		table.InsertOrAppendRules("FORWARD", []Rule{
+			{Action: JumpAction{Target: "cali-foobar"}},
+		})
+		table.UpdateChain(&Chain{Name: "cali-foobar", Rules: []Rule{{Action: AcceptAction{}}}})
+		table.Apply()
+
+		Expect(dataplane.Chains["FORWARD"]).To(BeEmpty())
+		Expect(dataplane.Chains).NotTo(HaveKey("cali-foobar"))
+		for _, cmd := range dataplane.Cmds {
+			_, isRestore := cmd.(*restoreCmd)
+			Expect(isRestore).To(BeFalse(), "dry-run table should not run iptables-restore")
+		}
+
+		changes, _ := recorder.Changes()
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Component).To(Equal("iptables-filter-v4"))
+		Expect(changes[0].Change).To(ContainSubstring(":cali-foobar - -"))
+		Expect(changes[0].Change).To(ContainSubstring("-A cali-foobar"))
+		Expect(changes[0].Change).To(ContainSubstring("--jump cali-foobar"))
+	})
+})
@@ -188,12 +188,21 @@ func countRulesInIptableOutput(in []byte) int {
 	return count
 }
 
+// BackendNftables is the backend mode that programs nftables directly over netlink, instead of
+// going via the iptables binaries.  Unlike "nft" (iptables-nft), it is never auto-detected.
+const BackendNftables = "nftables"
+
 // GetIptablesBackend attempts to detect the iptables backend being used where Felix is running.
 // This code is duplicating the detection method found at
 // https://github.com/kubernetes/kubernetes/blob/623b6978866b5d3790d17ff13601ef9e7e4f4bf0/build/debian-iptables/iptables-wrapper#L28
 // If there is a specifiedBackend then it is used but if it does not match the detected
 // backend then a warning is logged.
 func DetectBackend(lookPath func(file string) (string, error), newCmd cmdFactory, specifiedBackend string) string {
+	if strings.ToLower(specifiedBackend) == BackendNftables {
+		// No need to look for iptables binaries, which may not even be installed.
+		log.Info("Native nftables backend specified, skipping iptables backend detection")
+		return BackendNftables
+	}
 	ip6LgcySave := findBestBinary(lookPath, 6, "legacy", "save")
 	ip4LgcySave := findBestBinary(lookPath, 4, "legacy", "save")
 	ip6l, _ := newCmd(ip6LgcySave).Output()
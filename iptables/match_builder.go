@@ -156,6 +156,17 @@ func (m MatchCriteria) NotConntrackState(stateNames string) MatchCriteria {
 	return append(m, fmt.Sprintf("-m conntrack ! --ctstate %s", stateNames))
 }
 
+// ConntrackReply matches packets that are travelling in the reply direction of their connection.
+func (m MatchCriteria) ConntrackReply() MatchCriteria {
+	return append(m, "-m conntrack --ctdir REPLY")
+}
+
+// ConntrackOrigDestNet matches packets whose connection was originally addressed to the given
+// CIDR; that is, before any DNAT.
+func (m MatchCriteria) ConntrackOrigDestNet(net string) MatchCriteria {
+	return append(m, fmt.Sprintf("-m conntrack --ctorigdst %s", net))
+}
+
 func (m MatchCriteria) Protocol(name string) MatchCriteria {
 	return append(m, fmt.Sprintf("-p %s", name))
 }
@@ -292,6 +303,12 @@ func (m MatchCriteria) NotICMPV6TypeAndCode(t, c uint8) MatchCriteria {
 	return append(m, fmt.Sprintf("-m icmp6 ! --icmpv6-type %d/%d", t, c))
 }
 
+// Limit matches packets at up to ratePerSecond packets per second on average, allowing bursts of up
+// to burst packets.  Packets over the limit don't match.
+func (m MatchCriteria) Limit(ratePerSecond, burst int) MatchCriteria {
+	return append(m, fmt.Sprintf("-m limit --limit %d/second --limit-burst %d", ratePerSecond, burst))
+}
+
 // VXLANVNI matches on the VNI contained within the VXLAN header.  It assumes that this is indeed a VXLAN
 // packet; i.e. it should be used with a protocol==UDP and port==VXLAN port match.
 //
@@ -59,6 +59,8 @@ var _ = DescribeTable("MatchBuilder",
 	Entry("NotMarkMatchesWithMask", Match().NotMarkMatchesWithMask(0x400a, 0xf00f), "-m mark ! --mark 0x400a/0xf00f"),
 	// Conntrack.
 	Entry("ConntrackState", Match().ConntrackState("INVALID"), "-m conntrack --ctstate INVALID"),
+	Entry("ConntrackReply", Match().ConntrackReply(), "-m conntrack --ctdir REPLY"),
+	Entry("ConntrackOrigDestNet", Match().ConntrackOrigDestNet("10.96.0.10"), "-m conntrack --ctorigdst 10.96.0.10"),
 	// Interfaces.
 	Entry("InInterface", Match().InInterface("tap1234abcd"), "--in-interface tap1234abcd"),
 	Entry("OutInterface", Match().OutInterface("tap1234abcd"), "--out-interface tap1234abcd"),
@@ -68,6 +70,8 @@ var _ = DescribeTable("MatchBuilder",
 	Entry("NotSrcAddrType limit iface", Match().NotSrcAddrType(AddrTypeLocal, true), "-m addrtype ! --src-type LOCAL --limit-iface-out"),
 	Entry("NotSrcAddrType no limit iface", Match().NotSrcAddrType(AddrTypeLocal, false), "-m addrtype ! --src-type LOCAL"),
 	Entry("DestAddrType no limit iface", Match().DestAddrType(AddrTypeLocal), "-m addrtype --dst-type LOCAL"),
+	// Rate limit.
+	Entry("Limit", Match().Limit(10, 20), "-m limit --limit 10/second --limit-burst 20"),
 	// Protocol.
 	Entry("Protocol", Match().Protocol("tcp"), "-p tcp"),
 	Entry("NotProtocol", Match().NotProtocol("tcp"), "! -p tcp"),
@@ -0,0 +1,261 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iptables
+
+// Constants from the kernel's uapi/linux/netfilter/nf_tables.h and friends.  We only define the
+// subset that we need to program the rules that Felix renders.
+
+const (
+	// Values for the nfgenmsg family field.
+	nfprotoIPv4 = 2
+	nfprotoIPv6 = 10
+
+	// nftables message types.
+	nftMsgNewTable = 0
+	nftMsgGetTable = 1
+	nftMsgDelTable = 2
+	nftMsgNewChain = 3
+	nftMsgGetChain = 4
+	nftMsgDelChain = 5
+	nftMsgNewRule  = 6
+	nftMsgGetRule  = 7
+	nftMsgDelRule  = 8
+
+	// Table attributes.
+	nftaTableName = 1
+
+	// Chain attributes.
+	nftaChainTable  = 1
+	nftaChainHandle = 2
+	nftaChainName   = 3
+	nftaChainHook   = 4
+	nftaChainPolicy = 5
+	nftaChainType   = 7
+
+	nftaHookHookNum  = 1
+	nftaHookPriority = 2
+
+	// Rule attributes.
+	nftaRuleTable       = 1
+	nftaRuleChain       = 2
+	nftaRuleHandle      = 3
+	nftaRuleExpressions = 4
+	nftaRuleCompat      = 5
+	nftaRulePosition    = 6
+	nftaRuleUserdata    = 7
+
+	nftaRuleCompatProto = 1
+	nftaRuleCompatFlags = 2
+	nftRuleCompatFInv   = 1 << 1
+
+	nftaListElem = 1
+	nftaExprName = 1
+	nftaExprData = 2
+
+	// Data attributes, used for constant values and verdicts.
+	nftaDataValue   = 1
+	nftaDataVerdict = 2
+
+	nftaVerdictCode  = 1
+	nftaVerdictChain = 2
+
+	// Registers.  We only need two general purpose registers.
+	nftRegVerdict = 0
+	nftReg1       = 1
+	nftReg2       = 2
+
+	// Verdict codes; the negative ones are stored as two's complement u32s.
+	nfDrop       = 0
+	nfAccept     = 1
+	nftJump      = 0xfffffffd // -3
+	nftGoto      = 0xfffffffc // -4
+	nftReturnVal = 0xfffffffb // -5
+
+	// cmp expression.
+	nftaCmpSreg = 1
+	nftaCmpOp   = 2
+	nftaCmpData = 3
+	nftCmpEq    = 0
+	nftCmpNeq   = 1
+
+	// payload expression.
+	nftaPayloadDreg   = 1
+	nftaPayloadBase   = 2
+	nftaPayloadOffset = 3
+	nftaPayloadLen    = 4
+
+	nftPayloadNetworkHeader   = 1
+	nftPayloadTransportHeader = 2
+
+	// bitwise expression.
+	nftaBitwiseSreg = 1
+	nftaBitwiseDreg = 2
+	nftaBitwiseLen  = 3
+	nftaBitwiseMask = 4
+	nftaBitwiseXor  = 5
+
+	// immediate expression.
+	nftaImmediateDreg = 1
+	nftaImmediateData = 2
+
+	// meta expression.
+	nftaMetaDreg = 1
+	nftaMetaKey  = 2
+	nftaMetaSreg = 3
+
+	nftMetaMark    = 3
+	nftMetaIIFName = 6
+	nftMetaOIFName = 7
+	nftMetaL4Proto = 16
+
+	// ct expression.
+	nftaCtDreg      = 1
+	nftaCtKey       = 2
+	nftaCtDirection = 3
+
+	nftCtState     = 0
+	nftCtDirection = 1
+	nftCtDstIP     = 20
+	nftCtDstIP6    = 22
+
+	ipCtDirOriginal = 0
+	ipCtDirReply    = 1
+
+	// Conntrack state bits, as used by the ct state key.
+	nfCtStateInvalid     = 1 << 0
+	nfCtStateEstablished = 1 << 1
+	nfCtStateRelated     = 1 << 2
+	nfCtStateNew         = 1 << 3
+	nfCtStateUntracked   = 1 << 6
+
+	// fib expression.
+	nftaFibDreg   = 1
+	nftaFibResult = 2
+	nftaFibFlags  = 3
+
+	nftFibResultAddrType = 3
+	nftFibFSaddr         = 1 << 0
+	nftFibFDaddr         = 1 << 1
+	nftFibFOif           = 1 << 4
+
+	rtnLocal = 2
+
+	// log expression.
+	nftaLogGroup  = 1
+	nftaLogPrefix = 2
+	nftaLogLevel  = 5
+
+	// limit expression.
+	nftaLimitRate  = 1
+	nftaLimitUnit  = 2
+	nftaLimitBurst = 3
+	nftaLimitType  = 4
+
+	nftLimitPkts = 0
+
+	// reject expression.
+	nftaRejectType     = 1
+	nftaRejectICMPCode = 2
+
+	nftRejectICMPUnreach  = 0
+	icmpPortUnreachable   = 3
+	icmpv6PortUnreachable = 4
+
+	// nat expression.
+	nftaNatType        = 1
+	nftaNatFamily      = 2
+	nftaNatRegAddrMin  = 3
+	nftaNatRegAddrMax  = 4
+	nftaNatRegProtoMin = 5
+	nftaNatRegProtoMax = 6
+	nftaNatFlags       = 7
+
+	nftNatSNAT = 0
+	nftNatDNAT = 1
+
+	// masq expression.
+	nftaMasqFlags       = 1
+	nftaMasqRegProtoMin = 2
+	nftaMasqRegProtoMax = 3
+
+	nfNatRangeMapIPs          = 1 << 0
+	nfNatRangeProtoSpecified  = 1 << 1
+	nfNatRangeProtoRandomFull = 1 << 4
+
+	// Compat (xtables) match and target expressions, used for matches that have no native
+	// nftables equivalent that the kernel supports (or that would need sets/maps to express).
+	nftaMatchName = 1
+	nftaMatchRev  = 2
+	nftaMatchInfo = 3
+
+	nftaTargetName = 1
+	nftaTargetRev  = 2
+	nftaTargetInfo = 3
+
+	// Netfilter hook numbers.
+	nfInetPreRouting  = 0
+	nfInetLocalIn     = 1
+	nfInetForward     = 2
+	nfInetLocalOut    = 3
+	nfInetPostRouting = 4
+
+	// Maximum length of rule userdata that the kernel will accept.
+	nftUserdataMaxLen = 256
+	// libnftnl userdata TLV type used for rule comments.  Using the same encoding means that
+	// "nft list ruleset" shows our hash comments.
+	nftUdataRuleComment = 0
+
+	ifNameSize = 16
+)
+
+// nftTablePrefix is prepended to the iptables table names to get the names of the nftables
+// tables that we program.  We use our own tables, rather than the "filter"/"nat"/etc. tables
+// that iptables-nft uses so that we don't confuse other iptables-nft users with rules that
+// they can't parse.
+const nftTablePrefix = "calico-"
+
+type nftBaseChain struct {
+	hook     uint32
+	priority int32
+	typ      string
+}
+
+// nftBaseChains maps from iptables table and chain to the equivalent nftables base chain
+// configuration.  Priorities match those that iptables itself registers so that our chains run
+// at the same point in the pipeline as the iptables equivalents.
+var nftBaseChains = map[string]map[string]nftBaseChain{
+	"filter": {
+		"INPUT":   {hook: nfInetLocalIn, priority: 0, typ: "filter"},
+		"FORWARD": {hook: nfInetForward, priority: 0, typ: "filter"},
+		"OUTPUT":  {hook: nfInetLocalOut, priority: 0, typ: "filter"},
+	},
+	"nat": {
+		"PREROUTING":  {hook: nfInetPreRouting, priority: -100, typ: "nat"},
+		"INPUT":       {hook: nfInetLocalIn, priority: 100, typ: "nat"},
+		"OUTPUT":      {hook: nfInetLocalOut, priority: -100, typ: "nat"},
+		"POSTROUTING": {hook: nfInetPostRouting, priority: 100, typ: "nat"},
+	},
+	"mangle": {
+		"PREROUTING":  {hook: nfInetPreRouting, priority: -150, typ: "filter"},
+		"INPUT":       {hook: nfInetLocalIn, priority: -150, typ: "filter"},
+		"FORWARD":     {hook: nfInetForward, priority: -150, typ: "filter"},
+		"OUTPUT":      {hook: nfInetLocalOut, priority: -150, typ: "route"},
+		"POSTROUTING": {hook: nfInetPostRouting, priority: -150, typ: "filter"},
+	},
+	"raw": {
+		"PREROUTING": {hook: nfInetPreRouting, priority: -300, typ: "filter"},
+		"OUTPUT":     {hook: nfInetLocalOut, priority: -300, typ: "filter"},
+	},
+}
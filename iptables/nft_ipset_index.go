@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iptables
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// The xtables "set" match refers to IP sets by their kernel index rather than by name.  The
+// iptables binary looks up the index using the ipset getsockopt() API; we do the same.
+const (
+	soIPSet            = 83
+	ipSetOpVersion     = 0x100
+	ipSetOpGetByName   = 0x6
+	ipSetMaxNameLength = 32
+	ipSetInvalidID     = 0xffff
+)
+
+// lookUpIPSetIndex returns the kernel's index for the named IP set.
+func lookUpIPSetIndex(name string) (uint16, error) {
+	if len(name) >= ipSetMaxNameLength {
+		return 0, fmt.Errorf("IP set name %q too long", name)
+	}
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.IPPROTO_RAW)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open socket for IP set lookup: %w", err)
+	}
+	defer unix.Close(fd)
+
+	// struct ip_set_req_version { unsigned op; unsigned version; }
+	req := make([]byte, 8)
+	nl.NativeEndian().PutUint32(req[0:4], ipSetOpVersion)
+	if err := getsockoptIPSet(fd, req); err != nil {
+		return 0, fmt.Errorf("failed to query IP set protocol version: %w", err)
+	}
+	version := nl.NativeEndian().Uint32(req[4:8])
+
+	// struct ip_set_req_get_set { unsigned op; unsigned version; union { char name[32]; u16 index; } }
+	req = make([]byte, 8+ipSetMaxNameLength)
+	nl.NativeEndian().PutUint32(req[0:4], ipSetOpGetByName)
+	nl.NativeEndian().PutUint32(req[4:8], version)
+	copy(req[8:], name)
+	if err := getsockoptIPSet(fd, req); err != nil {
+		return 0, fmt.Errorf("failed to look up IP set: %w", err)
+	}
+	idx := nl.NativeEndian().Uint16(req[8:10])
+	if idx == ipSetInvalidID {
+		return 0, fmt.Errorf("IP set %q does not exist", name)
+	}
+	return idx, nil
+}
+
+func getsockoptIPSet(fd int, buf []byte) error {
+	l := uint32(len(buf))
+	_, _, errno := unix.Syscall6(
+		unix.SYS_GETSOCKOPT,
+		uintptr(fd),
+		unix.SOL_IP,
+		soIPSet,
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&l)),
+		0,
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,1067 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iptables
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink/nl"
+
+	"github.com/projectcalico/felix/nfnetlink"
+)
+
+// nftExpr is a single nftables expression: the expression type name plus its encoded attributes.
+type nftExpr struct {
+	name string
+	data []byte
+}
+
+// nftRule is the nftables equivalent of a single iptables rule spec.
+type nftRule struct {
+	exprs []nftExpr
+	// comment is the first comment on the rule; we store it in the rule's userdata so that we can
+	// recover our hash when reading back the dataplane.
+	comment string
+	// compatProto is the protocol match (if any) that we pass to xtables compat matches, some
+	// of which (such as multiport) refuse to load unless the rule has a protocol match.
+	compatProto    uint8
+	compatProtoInv bool
+}
+
+// nftRuleTranslator converts the rule fragments that Felix renders for iptables-restore into
+// nftables expressions.  It handles exactly the set of matches and actions that MatchCriteria
+// and the Action implementations in this package can produce.  Where nftables has a native
+// equivalent we use it, otherwise we fall back to the xtables compat expressions, which load the
+// same kernel modules that iptables would.
+type nftRuleTranslator struct {
+	ipVersion uint8
+	// lookUpIPSetIndex returns the kernel's index for the named IP set, which is what the "set"
+	// match refers to.
+	lookUpIPSetIndex func(name string) (uint16, error)
+}
+
+var errNftUnsupported = errors.New("not supported by the nftables backend")
+
+type ruleTokens struct {
+	toks []string
+	pos  int
+}
+
+func (t *ruleTokens) done() bool {
+	return t.pos >= len(t.toks)
+}
+
+func (t *ruleTokens) peek() string {
+	if t.done() {
+		return ""
+	}
+	return t.toks[t.pos]
+}
+
+func (t *ruleTokens) next() (string, error) {
+	if t.done() {
+		return "", errors.New("unexpected end of rule")
+	}
+	tok := t.toks[t.pos]
+	t.pos++
+	return tok, nil
+}
+
+// splitRuleSpec splits an iptables-restore line into tokens, respecting double quotes.
+func splitRuleSpec(line string) ([]string, error) {
+	var toks []string
+	var cur strings.Builder
+	inQuotes := false
+	inToken := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			inToken = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if inToken {
+				toks = append(toks, cur.String())
+				cur.Reset()
+				inToken = false
+			}
+		default:
+			cur.WriteRune(r)
+			inToken = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if inToken {
+		toks = append(toks, cur.String())
+	}
+	return toks, nil
+}
+
+// Translate converts the rule spec (i.e. the part of the line after "-A <chain>") into an
+// nftRule.
+func (tr *nftRuleTranslator) Translate(spec []string) (*nftRule, error) {
+	rule := &nftRule{}
+	toks := &ruleTokens{toks: spec}
+	invert := false
+	haveVerdict := false
+	for !toks.done() {
+		tok, _ := toks.next()
+		if tok == "!" {
+			invert = true
+			continue
+		}
+		if invert && !canInvert(tok) {
+			return nil, fmt.Errorf("unexpected negation of %q", tok)
+		}
+		var err error
+		switch tok {
+		case "-m", "--match":
+			// Module names are implied by the options that follow.
+			_, err = toks.next()
+		case "--comment":
+			var c string
+			c, err = toks.next()
+			if rule.comment == "" {
+				rule.comment = c
+			}
+		case "-p", "--protocol":
+			err = tr.translateProtocol(rule, toks, invert)
+		case "-s", "--source", "-d", "--destination":
+			err = tr.translateNet(rule, tok, toks, invert)
+		case "-i", "--in-interface", "-o", "--out-interface":
+			err = tr.translateIface(rule, tok, toks, invert)
+		case "--mark":
+			err = tr.translateMarkMatch(rule, toks, invert)
+		case "--ctstate":
+			err = tr.translateCtState(rule, toks, invert)
+		case "--ctdir":
+			err = tr.translateCtDir(rule, toks)
+		case "--ctorigdst":
+			err = tr.translateCtOrigDst(rule, toks, invert)
+		case "--src-type", "--dst-type":
+			err = tr.translateAddrType(rule, tok, toks, invert)
+		case "--match-set":
+			err = tr.translateSetMatch(rule, toks, invert)
+		case "--source-ports", "--destination-ports", "--sports", "--dports":
+			err = tr.translateMultiport(rule, tok, toks, invert)
+		case "--icmp-type", "--icmpv6-type":
+			err = tr.translateICMP(rule, toks, invert)
+		case "--invert", "--validmark", "--accept-local", "--loose":
+			toks.pos--
+			err = tr.translateRPFilter(rule, toks)
+		case "--ipvs":
+			rule.exprs = append(rule.exprs, tr.ipvsMatch(invert))
+		case "--limit":
+			err = tr.translateLimit(rule, toks)
+		case "-g", "--goto", "-j", "--jump":
+			err = tr.translateAction(rule, tok, toks)
+			haveVerdict = true
+		case "--u32":
+			err = fmt.Errorf("u32 match %w", errNftUnsupported)
+		default:
+			err = fmt.Errorf("unknown rule fragment %q", tok)
+		}
+		if err != nil {
+			return nil, err
+		}
+		invert = false
+		if haveVerdict && !toks.done() {
+			return nil, fmt.Errorf("unexpected trailing fragments after action: %v", toks.toks[toks.pos:])
+		}
+	}
+	if !haveVerdict {
+		// Rule with no action, which iptables allows (it just increments the counters).
+		rule.exprs = append(rule.exprs, counterExpr())
+	}
+	return rule, nil
+}
+
+func canInvert(tok string) bool {
+	switch tok {
+	case "-p", "--protocol", "-s", "--source", "-d", "--destination",
+		"-i", "--in-interface", "-o", "--out-interface", "--mark", "--ctstate", "--ctorigdst",
+		"--src-type", "--dst-type", "--match-set", "--source-ports", "--destination-ports",
+		"--sports", "--dports", "--icmp-type", "--icmpv6-type", "--ipvs":
+		return true
+	}
+	return false
+}
+
+var protocolNameToNumber = map[string]uint8{
+	"icmp":      1,
+	"ipencap":   4,
+	"ipip":      4,
+	"tcp":       6,
+	"udp":       17,
+	"gre":       47,
+	"esp":       50,
+	"ah":        51,
+	"icmpv6":    58,
+	"ipv6-icmp": 58,
+	"sctp":      132,
+	"udplite":   136,
+}
+
+func (tr *nftRuleTranslator) translateProtocol(rule *nftRule, toks *ruleTokens, invert bool) error {
+	p, err := toks.next()
+	if err != nil {
+		return err
+	}
+	num, ok := protocolNameToNumber[strings.ToLower(p)]
+	if !ok {
+		n, err := strconv.ParseUint(p, 10, 8)
+		if err != nil {
+			return fmt.Errorf("unknown protocol %q", p)
+		}
+		num = uint8(n)
+	}
+	rule.compatProto = num
+	rule.compatProtoInv = invert
+	rule.exprs = append(rule.exprs,
+		metaLoad(nftMetaL4Proto, nftReg1),
+		cmp(nftReg1, invert, []byte{num}),
+	)
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateNet(rule *nftRule, opt string, toks *ruleTokens, invert bool) error {
+	s, err := toks.next()
+	if err != nil {
+		return err
+	}
+	src := opt == "-s" || opt == "--source"
+	return tr.appendCIDRMatch(rule, s, invert, func(addrLen uint32) nftExpr {
+		var offset uint32
+		if tr.ipVersion == 4 {
+			offset = 16
+			if src {
+				offset = 12
+			}
+		} else {
+			offset = 24
+			if src {
+				offset = 8
+			}
+		}
+		return payloadLoad(nftPayloadNetworkHeader, offset, addrLen, nftReg1)
+	})
+}
+
+// appendCIDRMatch appends the expressions that match the address that load loads into register 1
+// against the CIDR s.
+func (tr *nftRuleTranslator) appendCIDRMatch(rule *nftRule, s string, invert bool, load func(addrLen uint32) nftExpr) error {
+	if !strings.Contains(s, "/") {
+		if tr.ipVersion == 4 {
+			s += "/32"
+		} else {
+			s += "/128"
+		}
+	}
+	_, cidr, err := net.ParseCIDR(s)
+	if err != nil {
+		return err
+	}
+	var addr []byte
+	if tr.ipVersion == 4 {
+		addr = cidr.IP.To4()
+	} else {
+		addr = cidr.IP.To16()
+	}
+	if addr == nil {
+		return fmt.Errorf("CIDR %q doesn't match IP version %d", s, tr.ipVersion)
+	}
+	ones, bits := cidr.Mask.Size()
+	if ones == 0 {
+		// Matches everything; there's no need to load the address.
+		if invert {
+			return fmt.Errorf("negated match on %q %w", s, errNftUnsupported)
+		}
+		return nil
+	}
+	rule.exprs = append(rule.exprs, load(uint32(len(addr))))
+	if ones != bits {
+		rule.exprs = append(rule.exprs, bitwise(nftReg1, cidr.Mask, make([]byte, len(addr))))
+	}
+	rule.exprs = append(rule.exprs, cmp(nftReg1, invert, addr))
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateIface(rule *nftRule, opt string, toks *ruleTokens, invert bool) error {
+	name, err := toks.next()
+	if err != nil {
+		return err
+	}
+	key := uint32(nftMetaIIFName)
+	if opt == "-o" || opt == "--out-interface" {
+		key = nftMetaOIFName
+	}
+	var data []byte
+	if strings.HasSuffix(name, "+") {
+		// Wildcard; compare only the prefix.
+		data = []byte(strings.TrimSuffix(name, "+"))
+	} else {
+		// Exact match, include the terminating NUL.
+		data = append([]byte(name), 0)
+	}
+	if len(data) > ifNameSize {
+		return fmt.Errorf("interface name %q too long", name)
+	}
+	if len(data) == 0 {
+		// Bare "+" matches any interface.
+		if invert {
+			return fmt.Errorf("negated match on %q %w", name, errNftUnsupported)
+		}
+		return nil
+	}
+	rule.exprs = append(rule.exprs, metaLoad(key, nftReg1), cmp(nftReg1, invert, data))
+	return nil
+}
+
+// parseValueMask parses a "value/mask" pair as rendered by the mark match and MARK/CONNMARK
+// targets.  A missing mask means all bits.
+func parseValueMask(s string) (value, mask uint32, err error) {
+	parts := strings.SplitN(s, "/", 2)
+	v, err := strconv.ParseUint(parts[0], 0, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse mark %q: %w", s, err)
+	}
+	m := uint64(0xffffffff)
+	if len(parts) == 2 {
+		m, err = strconv.ParseUint(parts[1], 0, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse mask %q: %w", s, err)
+		}
+	}
+	return uint32(v), uint32(m), nil
+}
+
+func (tr *nftRuleTranslator) translateMarkMatch(rule *nftRule, toks *ruleTokens, invert bool) error {
+	s, err := toks.next()
+	if err != nil {
+		return err
+	}
+	value, mask, err := parseValueMask(s)
+	if err != nil {
+		return err
+	}
+	rule.exprs = append(rule.exprs, metaLoad(nftMetaMark, nftReg1))
+	if mask != 0xffffffff {
+		rule.exprs = append(rule.exprs, bitwise(nftReg1, nativeUint32(mask), nativeUint32(0)))
+	}
+	rule.exprs = append(rule.exprs, cmp(nftReg1, invert, nativeUint32(value)))
+	return nil
+}
+
+var ctStateBits = map[string]uint32{
+	"INVALID":     nfCtStateInvalid,
+	"ESTABLISHED": nfCtStateEstablished,
+	"RELATED":     nfCtStateRelated,
+	"NEW":         nfCtStateNew,
+	"UNTRACKED":   nfCtStateUntracked,
+}
+
+func (tr *nftRuleTranslator) translateCtState(rule *nftRule, toks *ruleTokens, invert bool) error {
+	s, err := toks.next()
+	if err != nil {
+		return err
+	}
+	var bits uint32
+	for _, st := range strings.Split(s, ",") {
+		b, ok := ctStateBits[strings.ToUpper(st)]
+		if !ok {
+			return fmt.Errorf("unknown conntrack state %q", st)
+		}
+		bits |= b
+	}
+	// Match if any of the state bits are set: (state & bits) != 0.
+	rule.exprs = append(rule.exprs,
+		ctLoad(nftCtState, nftReg1),
+		bitwise(nftReg1, nativeUint32(bits), nativeUint32(0)),
+		cmp(nftReg1, !invert, nativeUint32(0)),
+	)
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateCtDir(rule *nftRule, toks *ruleTokens) error {
+	d, err := toks.next()
+	if err != nil {
+		return err
+	}
+	var dir byte
+	switch d {
+	case "ORIGINAL":
+		dir = ipCtDirOriginal
+	case "REPLY":
+		dir = ipCtDirReply
+	default:
+		return fmt.Errorf("unknown conntrack direction %q", d)
+	}
+	rule.exprs = append(rule.exprs,
+		ctLoad(nftCtDirection, nftReg1),
+		cmp(nftReg1, false, []byte{dir}),
+	)
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateCtOrigDst(rule *nftRule, toks *ruleTokens, invert bool) error {
+	s, err := toks.next()
+	if err != nil {
+		return err
+	}
+	key := uint32(nftCtDstIP)
+	if tr.ipVersion == 6 {
+		key = nftCtDstIP6
+	}
+	return tr.appendCIDRMatch(rule, s, invert, func(uint32) nftExpr {
+		return ctLoadDir(key, ipCtDirOriginal, nftReg1)
+	})
+}
+
+func (tr *nftRuleTranslator) translateAddrType(rule *nftRule, opt string, toks *ruleTokens, invert bool) error {
+	t, err := toks.next()
+	if err != nil {
+		return err
+	}
+	if t != "LOCAL" {
+		return fmt.Errorf("address type %q %w", t, errNftUnsupported)
+	}
+	flags := uint32(nftFibFDaddr)
+	if opt == "--src-type" {
+		flags = nftFibFSaddr
+	}
+	if toks.peek() == "--limit-iface-out" {
+		_, _ = toks.next()
+		flags |= nftFibFOif
+	}
+	var e nfnetlink.AttrEncoder
+	e.Uint32BE(nftaFibDreg, nftReg1)
+	e.Uint32BE(nftaFibResult, nftFibResultAddrType)
+	e.Uint32BE(nftaFibFlags, flags)
+	rule.exprs = append(rule.exprs,
+		nftExpr{name: "fib", data: e.Encode()},
+		cmp(nftReg1, invert, nativeUint32(rtnLocal)),
+	)
+	return nil
+}
+
+// limitUnitSeconds maps the units that iptables accepts for --limit to their length in seconds.
+var limitUnitSeconds = map[string]uint64{
+	"second": 1,
+	"minute": 60,
+	"hour":   60 * 60,
+	"day":    24 * 60 * 60,
+}
+
+func (tr *nftRuleTranslator) translateLimit(rule *nftRule, toks *ruleTokens) error {
+	s, err := toks.next()
+	if err != nil {
+		return err
+	}
+	parts := strings.SplitN(s, "/", 2)
+	rate, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse limit %q: %w", s, err)
+	}
+	unit := uint64(1)
+	if len(parts) == 2 {
+		// iptables accepts any prefix of the unit name, as in "10/sec".
+		unit = 0
+		for name, secs := range limitUnitSeconds {
+			if parts[1] != "" && strings.HasPrefix(name, parts[1]) {
+				unit = secs
+			}
+		}
+		if unit == 0 {
+			return fmt.Errorf("unknown limit unit in %q", s)
+		}
+	}
+	burst := uint64(5) // The iptables default.
+	if toks.peek() == "--limit-burst" {
+		_, _ = toks.next()
+		b, err := toks.next()
+		if err != nil {
+			return err
+		}
+		burst, err = strconv.ParseUint(b, 10, 32)
+		if err != nil {
+			return fmt.Errorf("failed to parse limit burst %q: %w", b, err)
+		}
+	}
+	var e nfnetlink.AttrEncoder
+	e.Uint64BE(nftaLimitRate, rate)
+	e.Uint64BE(nftaLimitUnit, unit)
+	e.Uint32BE(nftaLimitBurst, uint32(burst))
+	e.Uint32BE(nftaLimitType, nftLimitPkts)
+	rule.exprs = append(rule.exprs, nftExpr{name: "limit", data: e.Encode()})
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateSetMatch(rule *nftRule, toks *ruleTokens, invert bool) error {
+	name, err := toks.next()
+	if err != nil {
+		return err
+	}
+	dirs, err := toks.next()
+	if err != nil {
+		return err
+	}
+	if tr.lookUpIPSetIndex == nil {
+		return fmt.Errorf("IP set match %w: no IP set lookup function", errNftUnsupported)
+	}
+	idx, err := tr.lookUpIPSetIndex(name)
+	if err != nil {
+		return fmt.Errorf("failed to look up IP set %q: %w", name, err)
+	}
+	// struct xt_set_info_match_v1 { u16 index; u8 dim; u8 flags; }
+	var dim, flags uint8
+	for _, d := range strings.Split(dirs, ",") {
+		dim++
+		switch d {
+		case "src":
+			flags |= 1 << dim
+		case "dst":
+		default:
+			return fmt.Errorf("unknown IP set direction %q", d)
+		}
+	}
+	if invert {
+		flags |= 1 // IPSET_INV_MATCH
+	}
+	info := make([]byte, 4)
+	nl.NativeEndian().PutUint16(info[0:2], idx)
+	info[2] = dim
+	info[3] = flags
+	rule.exprs = append(rule.exprs, compatMatch("set", 1, info))
+	return nil
+}
+
+const xtMultiPorts = 15
+
+func (tr *nftRuleTranslator) translateMultiport(rule *nftRule, opt string, toks *ruleTokens, invert bool) error {
+	s, err := toks.next()
+	if err != nil {
+		return err
+	}
+	// struct xt_multiport_v1 { u8 flags; u8 count; u16 ports[15]; u8 pflags[15]; u8 invert; }
+	info := make([]byte, 48)
+	if opt == "--destination-ports" || opt == "--dports" {
+		info[0] = 1 // XT_MULTIPORT_DESTINATION
+	}
+	count := 0
+	for _, p := range strings.Split(s, ",") {
+		parts := strings.SplitN(p, ":", 2)
+		for i, part := range parts {
+			if count >= xtMultiPorts {
+				return fmt.Errorf("too many ports in %q", s)
+			}
+			port, err := strconv.ParseUint(part, 10, 16)
+			if err != nil {
+				return fmt.Errorf("failed to parse port %q: %w", part, err)
+			}
+			nl.NativeEndian().PutUint16(info[2+2*count:], uint16(port))
+			if len(parts) == 2 && i == 0 {
+				// Start of a range.
+				info[32+count] = 1
+			}
+			count++
+		}
+	}
+	info[1] = uint8(count)
+	if invert {
+		info[47] = 1
+	}
+	rule.exprs = append(rule.exprs, compatMatch("multiport", 1, info))
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateICMP(rule *nftRule, toks *ruleTokens, invert bool) error {
+	s, err := toks.next()
+	if err != nil {
+		return err
+	}
+	var data []byte
+	for _, part := range strings.SplitN(s, "/", 2) {
+		v, err := strconv.ParseUint(part, 10, 8)
+		if err != nil {
+			return fmt.Errorf("failed to parse ICMP type/code %q: %w", s, err)
+		}
+		data = append(data, uint8(v))
+	}
+	// Type and code are adjacent so we can match both with a single comparison, which is
+	// needed to get the right semantics for a negated match.
+	rule.exprs = append(rule.exprs,
+		payloadLoad(nftPayloadTransportHeader, 0, uint32(len(data)), nftReg1),
+		cmp(nftReg1, invert, data),
+	)
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateRPFilter(rule *nftRule, toks *ruleTokens) error {
+	var flags uint8
+loop:
+	for !toks.done() {
+		switch toks.peek() {
+		case "--loose":
+			flags |= 1 << 0
+		case "--validmark":
+			flags |= 1 << 1
+		case "--accept-local":
+			flags |= 1 << 2
+		case "--invert":
+			flags |= 1 << 3
+		default:
+			break loop
+		}
+		toks.pos++
+	}
+	rule.exprs = append(rule.exprs, compatMatch("rpfilter", 0, []byte{flags}))
+	return nil
+}
+
+func (tr *nftRuleTranslator) ipvsMatch(invert bool) nftExpr {
+	// struct xt_ipvs_mtinfo { nf_inet_addr vaddr, vmask; be16 vport; u8 l4proto, fwd_method;
+	//                         be16 vportctl; u8 invert, bitmask; }
+	info := make([]byte, 40)
+	const xtIPVSIPVSProperty = 1
+	info[39] = xtIPVSIPVSProperty
+	if invert {
+		info[38] = xtIPVSIPVSProperty
+	}
+	return compatMatch("ipvs", 1, info)
+}
+
+func (tr *nftRuleTranslator) translateAction(rule *nftRule, opt string, toks *ruleTokens) error {
+	target, err := toks.next()
+	if err != nil {
+		return err
+	}
+	if opt == "-g" || opt == "--goto" {
+		rule.exprs = append(rule.exprs, counterExpr(), verdict(nftGoto, target))
+		return nil
+	}
+	// All our rules get a counter so that they can be monitored in the same way as iptables
+	// rules.
+	rule.exprs = append(rule.exprs, counterExpr())
+	switch target {
+	case "ACCEPT":
+		rule.exprs = append(rule.exprs, verdict(nfAccept, ""))
+	case "DROP":
+		rule.exprs = append(rule.exprs, verdict(nfDrop, ""))
+	case "RETURN":
+		rule.exprs = append(rule.exprs, verdict(nftReturnVal, ""))
+	case "REJECT":
+		var e nfnetlink.AttrEncoder
+		e.Uint32BE(nftaRejectType, nftRejectICMPUnreach)
+		if tr.ipVersion == 4 {
+			e.Uint8(nftaRejectICMPCode, icmpPortUnreachable)
+		} else {
+			e.Uint8(nftaRejectICMPCode, icmpv6PortUnreachable)
+		}
+		rule.exprs = append(rule.exprs, nftExpr{name: "reject", data: e.Encode()})
+	case "LOG":
+		return tr.translateLog(rule, toks)
+	case "NFLOG":
+		return tr.translateNflog(rule, toks)
+	case "DNAT", "SNAT":
+		return tr.translateNAT(rule, target, toks)
+	case "MASQUERADE":
+		return tr.translateMasq(rule, toks)
+	case "MARK":
+		return tr.translateSetMark(rule, toks)
+	case "NOTRACK":
+		rule.exprs = append(rule.exprs, nftExpr{name: "notrack"})
+	case "CONNMARK":
+		return tr.translateConnmark(rule, toks)
+	default:
+		if strings.ToUpper(target) == target && !strings.Contains(target, "-") {
+			// Looks like an xtables target that we don't know about.
+			return fmt.Errorf("target %q %w", target, errNftUnsupported)
+		}
+		rule.exprs = append(rule.exprs, verdict(nftJump, target))
+	}
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateLog(rule *nftRule, toks *ruleTokens) error {
+	var e nfnetlink.AttrEncoder
+	for !toks.done() {
+		opt, _ := toks.next()
+		val, err := toks.next()
+		if err != nil {
+			return err
+		}
+		switch opt {
+		case "--log-prefix":
+			e.String(nftaLogPrefix, val)
+		case "--log-level":
+			level, err := strconv.ParseUint(val, 10, 32)
+			if err != nil {
+				return fmt.Errorf("failed to parse log level %q: %w", val, err)
+			}
+			e.Uint32BE(nftaLogLevel, uint32(level))
+		default:
+			return fmt.Errorf("unknown LOG option %q", opt)
+		}
+	}
+	rule.exprs = append(rule.exprs, nftExpr{name: "log", data: e.Encode()})
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateNflog(rule *nftRule, toks *ruleTokens) error {
+	var e nfnetlink.AttrEncoder
+	for !toks.done() {
+		opt, _ := toks.next()
+		val, err := toks.next()
+		if err != nil {
+			return err
+		}
+		switch opt {
+		case "--nflog-group":
+			group, err := strconv.ParseUint(val, 10, 16)
+			if err != nil {
+				return fmt.Errorf("failed to parse NFLOG group %q: %w", val, err)
+			}
+			e.Uint16BE(nftaLogGroup, uint16(group))
+		case "--nflog-prefix":
+			e.String(nftaLogPrefix, val)
+		default:
+			return fmt.Errorf("unknown NFLOG option %q", opt)
+		}
+	}
+	rule.exprs = append(rule.exprs, nftExpr{name: "log", data: e.Encode()})
+	return nil
+}
+
+func (tr *nftRuleTranslator) family() uint32 {
+	if tr.ipVersion == 4 {
+		return nfprotoIPv4
+	}
+	return nfprotoIPv6
+}
+
+func (tr *nftRuleTranslator) translateNAT(rule *nftRule, target string, toks *ruleTokens) error {
+	var addrPort string
+	randomFully := false
+	for !toks.done() {
+		opt, _ := toks.next()
+		switch opt {
+		case "--to-destination", "--to-source":
+			var err error
+			addrPort, err = toks.next()
+			if err != nil {
+				return err
+			}
+		case "--random-fully":
+			randomFully = true
+		default:
+			return fmt.Errorf("unknown %s option %q", target, opt)
+		}
+	}
+	addrStr, portStr := addrPort, ""
+	if host, port, err := net.SplitHostPort(addrPort); err == nil {
+		addrStr, portStr = host, port
+	}
+	addrStr = strings.Trim(addrStr, "[]")
+	ip := net.ParseIP(addrStr)
+	if ip == nil {
+		return fmt.Errorf("failed to parse NAT address %q", addrPort)
+	}
+	var addr []byte
+	if tr.ipVersion == 4 {
+		addr = ip.To4()
+	} else {
+		addr = ip.To16()
+	}
+	if addr == nil {
+		return fmt.Errorf("NAT address %q doesn't match IP version %d", addrPort, tr.ipVersion)
+	}
+
+	var e nfnetlink.AttrEncoder
+	natType := uint32(nftNatSNAT)
+	if target == "DNAT" {
+		natType = nftNatDNAT
+	}
+	e.Uint32BE(nftaNatType, natType)
+	e.Uint32BE(nftaNatFamily, tr.family())
+	rule.exprs = append(rule.exprs, immediateData(nftReg1, addr))
+	e.Uint32BE(nftaNatRegAddrMin, nftReg1)
+	if portStr != "" {
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return fmt.Errorf("failed to parse NAT port %q: %w", portStr, err)
+		}
+		rule.exprs = append(rule.exprs, immediateData(nftReg2, bigEndianUint16(uint16(port))))
+		e.Uint32BE(nftaNatRegProtoMin, nftReg2)
+	}
+	if randomFully {
+		e.Uint32BE(nftaNatFlags, nfNatRangeProtoRandomFull)
+	}
+	rule.exprs = append(rule.exprs, nftExpr{name: "nat", data: e.Encode()})
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateMasq(rule *nftRule, toks *ruleTokens) error {
+	var e nfnetlink.AttrEncoder
+	var flags uint32
+	for !toks.done() {
+		opt, _ := toks.next()
+		switch opt {
+		case "--to-ports":
+			ports, err := toks.next()
+			if err != nil {
+				return err
+			}
+			parts := strings.SplitN(ports, "-", 2)
+			for i, p := range parts {
+				port, err := strconv.ParseUint(p, 10, 16)
+				if err != nil {
+					return fmt.Errorf("failed to parse MASQUERADE port %q: %w", p, err)
+				}
+				reg := uint32(nftReg1 + i)
+				rule.exprs = append(rule.exprs, immediateData(reg, bigEndianUint16(uint16(port))))
+			}
+			e.Uint32BE(nftaMasqRegProtoMin, nftReg1)
+			if len(parts) == 2 {
+				e.Uint32BE(nftaMasqRegProtoMax, nftReg2)
+			}
+		case "--random-fully":
+			flags |= nfNatRangeProtoRandomFull
+		default:
+			return fmt.Errorf("unknown MASQUERADE option %q", opt)
+		}
+	}
+	if flags != 0 {
+		e.Uint32BE(nftaMasqFlags, flags)
+	}
+	rule.exprs = append(rule.exprs, nftExpr{name: "masq", data: e.Encode()})
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateSetMark(rule *nftRule, toks *ruleTokens) error {
+	opt, err := toks.next()
+	if err != nil {
+		return err
+	}
+	if opt != "--set-mark" {
+		return fmt.Errorf("unknown MARK option %q", opt)
+	}
+	s, err := toks.next()
+	if err != nil {
+		return err
+	}
+	value, mask, err := parseValueMask(s)
+	if err != nil {
+		return err
+	}
+	// mark = (mark & ^mask) ^ value, which is what the MARK target does.
+	var e nfnetlink.AttrEncoder
+	e.Uint32BE(nftaMetaKey, nftMetaMark)
+	e.Uint32BE(nftaMetaSreg, nftReg1)
+	rule.exprs = append(rule.exprs,
+		metaLoad(nftMetaMark, nftReg1),
+		bitwise(nftReg1, nativeUint32(^mask), nativeUint32(value)),
+		nftExpr{name: "meta", data: e.Encode()},
+	)
+	return nil
+}
+
+func (tr *nftRuleTranslator) translateConnmark(rule *nftRule, toks *ruleTokens) error {
+	// struct xt_connmark_tginfo1 { u32 ctmark, ctmask, nfmask; u8 mode; }
+	const (
+		modeSet     = 0
+		modeSave    = 1
+		modeRestore = 2
+	)
+	var ctmark, ctmask, nfmask uint32
+	var mode uint8
+	var haveMode bool
+	for !toks.done() {
+		opt, _ := toks.next()
+		switch opt {
+		case "--save-mark", "--restore-mark":
+			mode = modeSave
+			if opt == "--restore-mark" {
+				mode = modeRestore
+			}
+			haveMode = true
+			ctmask, nfmask = 0xffffffff, 0xffffffff
+		case "--mark", "--mask":
+			s, err := toks.next()
+			if err != nil {
+				return err
+			}
+			m, err := strconv.ParseUint(s, 0, 32)
+			if err != nil {
+				return fmt.Errorf("failed to parse CONNMARK mask %q: %w", s, err)
+			}
+			ctmask, nfmask = uint32(m), uint32(m)
+		case "--set-mark":
+			s, err := toks.next()
+			if err != nil {
+				return err
+			}
+			value, mask, err := parseValueMask(s)
+			if err != nil {
+				return err
+			}
+			mode = modeSet
+			haveMode = true
+			ctmark, ctmask = value, value|mask
+		default:
+			return fmt.Errorf("unknown CONNMARK option %q", opt)
+		}
+	}
+	if !haveMode {
+		return errors.New("CONNMARK target missing mode")
+	}
+	info := make([]byte, 13)
+	nl.NativeEndian().PutUint32(info[0:4], ctmark)
+	nl.NativeEndian().PutUint32(info[4:8], ctmask)
+	nl.NativeEndian().PutUint32(info[8:12], nfmask)
+	info[12] = mode
+	rule.exprs = append(rule.exprs, compatTarget("CONNMARK", 1, info))
+	return nil
+}
+
+// Expression constructors.
+
+func metaLoad(key uint32, dreg uint32) nftExpr {
+	var e nfnetlink.AttrEncoder
+	e.Uint32BE(nftaMetaKey, key)
+	e.Uint32BE(nftaMetaDreg, dreg)
+	return nftExpr{name: "meta", data: e.Encode()}
+}
+
+func ctLoad(key uint32, dreg uint32) nftExpr {
+	var e nfnetlink.AttrEncoder
+	e.Uint32BE(nftaCtKey, key)
+	e.Uint32BE(nftaCtDreg, dreg)
+	return nftExpr{name: "ct", data: e.Encode()}
+}
+
+// ctLoadDir loads a conntrack key that has a value for each direction of the connection.
+func ctLoadDir(key uint32, dir uint8, dreg uint32) nftExpr {
+	var e nfnetlink.AttrEncoder
+	e.Uint32BE(nftaCtKey, key)
+	e.Uint8(nftaCtDirection, dir)
+	e.Uint32BE(nftaCtDreg, dreg)
+	return nftExpr{name: "ct", data: e.Encode()}
+}
+
+func payloadLoad(base, offset, length, dreg uint32) nftExpr {
+	var e nfnetlink.AttrEncoder
+	e.Uint32BE(nftaPayloadDreg, dreg)
+	e.Uint32BE(nftaPayloadBase, base)
+	e.Uint32BE(nftaPayloadOffset, offset)
+	e.Uint32BE(nftaPayloadLen, length)
+	return nftExpr{name: "payload", data: e.Encode()}
+}
+
+func bitwise(reg uint32, mask, xor []byte) nftExpr {
+	var e nfnetlink.AttrEncoder
+	e.Uint32BE(nftaBitwiseSreg, reg)
+	e.Uint32BE(nftaBitwiseDreg, reg)
+	e.Uint32BE(nftaBitwiseLen, uint32(len(mask)))
+	e.Nested(nftaBitwiseMask, func(e *nfnetlink.AttrEncoder) {
+		e.Bytes(nftaDataValue, mask)
+	})
+	e.Nested(nftaBitwiseXor, func(e *nfnetlink.AttrEncoder) {
+		e.Bytes(nftaDataValue, xor)
+	})
+	return nftExpr{name: "bitwise", data: e.Encode()}
+}
+
+func cmp(sreg uint32, invert bool, data []byte) nftExpr {
+	var e nfnetlink.AttrEncoder
+	op := uint32(nftCmpEq)
+	if invert {
+		op = nftCmpNeq
+	}
+	e.Uint32BE(nftaCmpSreg, sreg)
+	e.Uint32BE(nftaCmpOp, op)
+	e.Nested(nftaCmpData, func(e *nfnetlink.AttrEncoder) {
+		e.Bytes(nftaDataValue, data)
+	})
+	return nftExpr{name: "cmp", data: e.Encode()}
+}
+
+func immediateData(dreg uint32, data []byte) nftExpr {
+	var e nfnetlink.AttrEncoder
+	e.Uint32BE(nftaImmediateDreg, dreg)
+	e.Nested(nftaImmediateData, func(e *nfnetlink.AttrEncoder) {
+		e.Bytes(nftaDataValue, data)
+	})
+	return nftExpr{name: "immediate", data: e.Encode()}
+}
+
+func verdict(code uint32, chain string) nftExpr {
+	var e nfnetlink.AttrEncoder
+	e.Uint32BE(nftaImmediateDreg, nftRegVerdict)
+	e.Nested(nftaImmediateData, func(e *nfnetlink.AttrEncoder) {
+		e.Nested(nftaDataVerdict, func(e *nfnetlink.AttrEncoder) {
+			e.Uint32BE(nftaVerdictCode, code)
+			if chain != "" {
+				e.String(nftaVerdictChain, chain)
+			}
+		})
+	})
+	return nftExpr{name: "immediate", data: e.Encode()}
+}
+
+func counterExpr() nftExpr {
+	return nftExpr{name: "counter"}
+}
+
+// xtAlign pads compat match/target info to the alignment that the xtables core expects.
+func xtAlign(info []byte) []byte {
+	for len(info)%8 != 0 {
+		info = append(info, 0)
+	}
+	return info
+}
+
+func compatMatch(name string, rev uint32, info []byte) nftExpr {
+	var e nfnetlink.AttrEncoder
+	e.String(nftaMatchName, name)
+	e.Uint32BE(nftaMatchRev, rev)
+	e.Bytes(nftaMatchInfo, xtAlign(info))
+	return nftExpr{name: "match", data: e.Encode()}
+}
+
+func compatTarget(name string, rev uint32, info []byte) nftExpr {
+	var e nfnetlink.AttrEncoder
+	e.String(nftaTargetName, name)
+	e.Uint32BE(nftaTargetRev, rev)
+	e.Bytes(nftaTargetInfo, xtAlign(info))
+	return nftExpr{name: "target", data: e.Encode()}
+}
+
+func nativeUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	nl.NativeEndian().PutUint32(b, v)
+	return b
+}
+
+func bigEndianUint16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
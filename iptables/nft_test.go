@@ -0,0 +1,409 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iptables
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/nfnetlink"
+)
+
+// exprNames decodes the expression names from a NEWRULE message.
+func exprNames(msg *nfnetlink.Message) []string {
+	attrs, err := nfnetlink.ParseAttrMap(msg.Attrs)
+	Expect(err).NotTo(HaveOccurred())
+	elems, err := nfnetlink.ParseAttrs(attrs[nftaRuleExpressions])
+	Expect(err).NotTo(HaveOccurred())
+	var names []string
+	for _, elem := range elems {
+		exprAttrs, err := nfnetlink.ParseAttrMap(elem.Value)
+		Expect(err).NotTo(HaveOccurred())
+		names = append(names, nfnetlink.AttrString(exprAttrs[nftaExprName]))
+	}
+	return names
+}
+
+func translatedExprNames(rule *nftRule) []string {
+	var names []string
+	for _, e := range rule.exprs {
+		names = append(names, e.name)
+	}
+	return names
+}
+
+var _ = Describe("nftables rule translation", func() {
+	var tr *nftRuleTranslator
+	var lookedUpSets []string
+
+	BeforeEach(func() {
+		lookedUpSets = nil
+		tr = &nftRuleTranslator{
+			ipVersion: 4,
+			lookUpIPSetIndex: func(name string) (uint16, error) {
+				lookedUpSets = append(lookedUpSets, name)
+				return 7, nil
+			},
+		}
+	})
+
+	translate := func(line string) (*nftRule, error) {
+		toks, err := splitRuleSpec(line)
+		Expect(err).NotTo(HaveOccurred())
+		return tr.Translate(toks)
+	}
+
+	It("should split quoted fragments", func() {
+		toks, err := splitRuleSpec(`-A cali-INPUT -m comment --comment "cali:abcd" --jump LOG --log-prefix "calico-drop: " --log-level 5`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(toks).To(Equal([]string{
+			"-A", "cali-INPUT", "-m", "comment", "--comment", "cali:abcd",
+			"--jump", "LOG", "--log-prefix", "calico-drop: ", "--log-level", "5",
+		}))
+	})
+
+	DescribeTable("rendered rules should translate to the expected expressions",
+		func(rule Rule, expectedExprs []string) {
+			line := rule.RenderAppend("cali-foo", `-m comment --comment "cali:abcd"`, &Features{})
+			toks, err := splitRuleSpec(line)
+			Expect(err).NotTo(HaveOccurred())
+			nftRule, err := tr.Translate(toks[2:])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(translatedExprNames(nftRule)).To(Equal(expectedExprs))
+			Expect(nftRule.comment).To(Equal("cali:abcd"))
+		},
+		Entry("accept", Rule{Action: AcceptAction{}},
+			[]string{"counter", "immediate"}),
+		Entry("protocol and ports", Rule{
+			Match:  Match().Protocol("tcp").DestPorts(80, 443),
+			Action: DropAction{},
+		}, []string{"meta", "cmp", "match", "counter", "immediate"}),
+		Entry("mark with mask", Rule{
+			Match:  Match().MarkMatchesWithMask(0x10, 0x30),
+			Action: ReturnAction{},
+		}, []string{"meta", "bitwise", "cmp", "counter", "immediate"}),
+		Entry("single mark bit clear", Rule{
+			Match:  Match().MarkClear(0x100),
+			Action: JumpAction{Target: "cali-bar"},
+		}, []string{"meta", "bitwise", "cmp", "counter", "immediate"}),
+		Entry("source CIDR", Rule{
+			Match:  Match().SourceNet("10.0.0.0/8"),
+			Action: GotoAction{Target: "cali-bar"},
+		}, []string{"payload", "bitwise", "cmp", "counter", "immediate"}),
+		Entry("host destination", Rule{
+			Match:  Match().NotDestNet("10.0.0.1"),
+			Action: AcceptAction{},
+		}, []string{"payload", "cmp", "counter", "immediate"}),
+		Entry("interface wildcard", Rule{
+			Match:  Match().InInterface("cali+").OutInterface("eth0"),
+			Action: AcceptAction{},
+		}, []string{"meta", "cmp", "meta", "cmp", "counter", "immediate"}),
+		Entry("conntrack state", Rule{
+			Match:  Match().ConntrackState("RELATED,ESTABLISHED"),
+			Action: AcceptAction{},
+		}, []string{"ct", "bitwise", "cmp", "counter", "immediate"}),
+		Entry("conntrack reply to an original destination", Rule{
+			Match:  Match().ConntrackReply().ConntrackOrigDestNet("10.96.0.0/24"),
+			Action: AcceptAction{},
+		}, []string{"ct", "cmp", "ct", "bitwise", "cmp", "counter", "immediate"}),
+		Entry("addrtype", Rule{
+			Match:  Match().NotSrcAddrType(AddrTypeLocal, true).DestAddrType(AddrTypeLocal),
+			Action: AcceptAction{},
+		}, []string{"fib", "cmp", "fib", "cmp", "counter", "immediate"}),
+		Entry("IP set", Rule{
+			Match:  Match().SourceIPSet("cali40s:abcd"),
+			Action: AcceptAction{},
+		}, []string{"match", "counter", "immediate"}),
+		Entry("ICMP type and code", Rule{
+			Match:  Match().Protocol("icmp").NotICMPTypeAndCode(8, 0),
+			Action: AcceptAction{},
+		}, []string{"meta", "cmp", "payload", "cmp", "counter", "immediate"}),
+		Entry("rpfilter", Rule{
+			Match:  Match().RPFCheckFailed(false),
+			Action: DropAction{},
+		}, []string{"match", "counter", "immediate"}),
+		Entry("ipvs", Rule{
+			Match:  Match().IPVSConnection(),
+			Action: AcceptAction{},
+		}, []string{"match", "counter", "immediate"}),
+		Entry("reject", Rule{Action: RejectAction{}},
+			[]string{"counter", "reject"}),
+		Entry("log", Rule{Action: LogAction{Prefix: "calico-drop"}},
+			[]string{"counter", "log"}),
+		Entry("rate-limited log", Rule{
+			Match:  Match().Limit(10, 20),
+			Action: LogAction{Prefix: "calico-audit"},
+		}, []string{"limit", "counter", "log"}),
+		Entry("nflog", Rule{
+			Match:  Match().Protocol("udp").SourcePorts(53),
+			Action: NflogAction{Group: 3, Prefix: "dns"},
+		}, []string{"meta", "cmp", "match", "counter", "log"}),
+		Entry("DNAT with port", Rule{Action: DNATAction{DestAddr: "10.0.0.1", DestPort: 8080}},
+			[]string{"counter", "immediate", "immediate", "nat"}),
+		Entry("SNAT", Rule{Action: SNATAction{ToAddr: "10.0.0.1"}},
+			[]string{"counter", "immediate", "nat"}),
+		Entry("masquerade with ports", Rule{Action: MasqAction{ToPorts: "1000-2000"}},
+			[]string{"counter", "immediate", "immediate", "masq"}),
+		Entry("set mark", Rule{Action: SetMaskedMarkAction{Mark: 0x1, Mask: 0xf}},
+			[]string{"counter", "meta", "bitwise", "meta"}),
+		Entry("notrack", Rule{Action: NoTrackAction{}},
+			[]string{"counter", "notrack"}),
+		Entry("connmark", Rule{Action: RestoreConnMarkAction{RestoreMask: 0xff}},
+			[]string{"counter", "target"}),
+	)
+
+	It("should look up IP set indexes", func() {
+		_, err := translate(`-m set ! --match-set cali40s:abcd dst,dst --jump DROP`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lookedUpSets).To(Equal([]string{"cali40s:abcd"}))
+	})
+
+	It("should encode the protocol for compat matches", func() {
+		rule, err := translate(`-p udp -m multiport --source-ports 53,1000:2000 --jump ACCEPT`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rule.compatProto).To(Equal(uint8(17)))
+		Expect(rule.compatProtoInv).To(BeFalse())
+	})
+
+	It("should reject u32 matches", func() {
+		_, err := translate(`-m u32 --u32 "0>>22&0x3C@12>>8=0x1234" --jump DROP`)
+		Expect(errors.Is(err, errNftUnsupported)).To(BeTrue())
+	})
+
+	It("should accept the abbreviated limit units that iptables-save writes", func() {
+		rule, err := translate(`-m limit --limit 10/sec --limit-burst 20 --jump LOG`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(translatedExprNames(rule)).To(Equal([]string{"limit", "counter", "log"}))
+		_, err = translate(`-m limit --limit 10/fortnight --jump LOG`)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject unknown fragments", func() {
+		_, err := translate(`-m foo --foo bar --jump DROP`)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject an IPv6 CIDR in an IPv4 rule", func() {
+		_, err := translate(`--source fd00::/64 --jump DROP`)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+type mockNftConn struct {
+	chains  []nfnetlink.Message
+	rules   []nfnetlink.Message
+	batches [][]*nfnetlink.Message
+	closed  bool
+	failErr error
+}
+
+func (m *mockNftConn) Dump(msg *nfnetlink.Message) ([]nfnetlink.Message, error) {
+	switch msg.Msg() {
+	case nftMsgGetChain:
+		return m.chains, nil
+	case nftMsgGetRule:
+		return m.rules, nil
+	}
+	return nil, errors.New("unexpected dump")
+}
+
+func (m *mockNftConn) ExecuteBatch(subsys uint16, msgs []*nfnetlink.Message) error {
+	if m.failErr != nil {
+		return m.failErr
+	}
+	m.batches = append(m.batches, msgs)
+	return nil
+}
+
+func (m *mockNftConn) Close() error {
+	m.closed = true
+	return nil
+}
+
+func (m *mockNftConn) addChain(table, name string) {
+	var e nfnetlink.AttrEncoder
+	e.String(nftaChainTable, table)
+	e.String(nftaChainName, name)
+	m.chains = append(m.chains, nfnetlink.Message{Attrs: e.Encode()})
+}
+
+func (m *mockNftConn) addRule(table, chain string, handle uint64, comment string) {
+	var e nfnetlink.AttrEncoder
+	e.String(nftaRuleTable, table)
+	e.String(nftaRuleChain, chain)
+	e.Uint64BE(nftaRuleHandle, handle)
+	if comment != "" {
+		e.Bytes(nftaRuleUserdata, encodeRuleComment(comment))
+	}
+	m.rules = append(m.rules, nfnetlink.Message{Attrs: e.Encode()})
+}
+
+var _ = Describe("nftables pseudo-commands", func() {
+	var conn *mockNftConn
+	var dp *nftablesDataplane
+
+	BeforeEach(func() {
+		conn = &mockNftConn{}
+		conn.addChain("calico-filter", "INPUT")
+		conn.addChain("calico-filter", "cali-foo")
+		conn.addChain("other", "INPUT")
+		conn.addRule("calico-filter", "INPUT", 3, "")
+		conn.addRule("calico-filter", "INPUT", 4, "cali:hook")
+		conn.addRule("calico-filter", "cali-foo", 5, "cali:aaaa")
+		conn.addRule("calico-filter", "cali-foo", 6, "cali:bbbb")
+		conn.addRule("other", "INPUT", 7, "cali:other")
+		dp = newNftablesDataplane(4, func() (nftConn, error) { return conn, nil }, nil)
+	})
+
+	It("should render the table in iptables-save format", func() {
+		out, err := dp.newCmd(nftablesSaveCmd, "-t", "filter").Output()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal(strings.Join([]string{
+			"*filter",
+			":INPUT - [0:0]",
+			":cali-foo - [0:0]",
+			"-A INPUT",
+			`-A INPUT -m comment --comment "cali:hook"`,
+			`-A cali-foo -m comment --comment "cali:aaaa"`,
+			`-A cali-foo -m comment --comment "cali:bbbb"`,
+			"COMMIT",
+			"",
+		}, "\n")))
+	})
+
+	It("should support reading the save output via a pipe", func() {
+		cmd := dp.newCmd(nftablesSaveCmd, "-t", "filter")
+		pipe, err := cmd.StdoutPipe()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cmd.Start()).To(Succeed())
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(pipe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cmd.Wait()).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring(`-A cali-foo -m comment --comment "cali:bbbb"`))
+	})
+
+	runRestore := func(lines ...string) error {
+		cmd := dp.newCmd(nftablesRestoreCmd, "--noflush", "--verbose")
+		cmd.SetStdin(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+		var stderr bytes.Buffer
+		cmd.SetStderr(&stderr)
+		return cmd.Run()
+	}
+
+	// ourMsgs strips off the table and base chain creation messages that start every batch.
+	ourMsgs := func(batch []*nfnetlink.Message) []*nfnetlink.Message {
+		Expect(batch[0].Msg()).To(Equal(uint8(nftMsgNewTable)))
+		for i := 1; i <= len(tableToKernelChains["filter"]); i++ {
+			Expect(batch[i].Msg()).To(Equal(uint8(nftMsgNewChain)))
+		}
+		return batch[1+len(tableToKernelChains["filter"]):]
+	}
+
+	attrsOf := func(msg *nfnetlink.Message) map[uint16][]byte {
+		attrs, err := nfnetlink.ParseAttrMap(msg.Attrs)
+		Expect(err).NotTo(HaveOccurred())
+		return attrs
+	}
+
+	It("should map rule numbers to handles", func() {
+		Expect(runRestore(
+			"*filter",
+			`-R cali-foo 2 -m comment --comment "cali:cccc" --jump ACCEPT`,
+			"-D cali-foo 1",
+			"COMMIT",
+		)).To(Succeed())
+		Expect(conn.batches).To(HaveLen(1))
+		msgs := ourMsgs(conn.batches[0])
+		Expect(msgs).To(HaveLen(2))
+
+		Expect(msgs[0].Msg()).To(Equal(uint8(nftMsgNewRule)))
+		Expect(msgs[0].Flags & nfnetlink.FlagReplace).NotTo(BeZero())
+		Expect(nfnetlink.AttrUint64BE(attrsOf(msgs[0])[nftaRuleHandle])).To(Equal(uint64(6)))
+		Expect(decodeRuleComment(attrsOf(msgs[0])[nftaRuleUserdata])).To(Equal("cali:cccc"))
+		Expect(exprNames(msgs[0])).To(Equal([]string{"counter", "immediate"}))
+
+		Expect(msgs[1].Msg()).To(Equal(uint8(nftMsgDelRule)))
+		Expect(nfnetlink.AttrUint64BE(attrsOf(msgs[1])[nftaRuleHandle])).To(Equal(uint64(5)))
+	})
+
+	It("should delete by value using the hash comment", func() {
+		Expect(runRestore(
+			"*filter",
+			`-D INPUT -m comment --comment "cali:hook"`,
+			`-I INPUT -m comment --comment "cali:hook2" --jump cali-foo`,
+			"COMMIT",
+		)).To(Succeed())
+		msgs := ourMsgs(conn.batches[0])
+		Expect(msgs).To(HaveLen(2))
+		Expect(msgs[0].Msg()).To(Equal(uint8(nftMsgDelRule)))
+		Expect(nfnetlink.AttrUint64BE(attrsOf(msgs[0])[nftaRuleHandle])).To(Equal(uint64(4)))
+		Expect(msgs[1].Msg()).To(Equal(uint8(nftMsgNewRule)))
+		Expect(msgs[1].Flags & nfnetlink.FlagAppend).To(BeZero())
+	})
+
+	It("should create and flush chains for forward references", func() {
+		Expect(runRestore(
+			"*filter",
+			":cali-foo - -",
+			":cali-new - -",
+			`-A cali-new -m comment --comment "cali:dddd" --jump cali-foo`,
+			"--delete-chain cali-foo",
+			"COMMIT",
+		)).To(Succeed())
+		msgs := ourMsgs(conn.batches[0])
+		Expect(msgs).To(HaveLen(4))
+		Expect(msgs[0].Msg()).To(Equal(uint8(nftMsgDelRule)))
+		Expect(attrsOf(msgs[0])).NotTo(HaveKey(uint16(nftaRuleHandle)))
+		Expect(msgs[1].Msg()).To(Equal(uint8(nftMsgNewChain)))
+		Expect(msgs[2].Msg()).To(Equal(uint8(nftMsgNewRule)))
+		Expect(msgs[2].Flags & nfnetlink.FlagAppend).NotTo(BeZero())
+		Expect(msgs[3].Msg()).To(Equal(uint8(nftMsgDelChain)))
+	})
+
+	It("should refuse to modify rules added in the same transaction", func() {
+		err := runRestore(
+			"*filter",
+			`-A cali-foo -m comment --comment "cali:eeee" --jump DROP`,
+			"-D cali-foo 3",
+			"COMMIT",
+		)
+		Expect(err).To(HaveOccurred())
+		Expect(conn.batches).To(BeEmpty())
+		Expect(conn.closed).To(BeTrue())
+	})
+
+	It("should return an error if the batch fails", func() {
+		conn.failErr = errors.New("EINVAL")
+		err := runRestore("*filter", `-A cali-foo --jump DROP`, "COMMIT")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject input with no COMMIT", func() {
+		err := runRestore("*filter", `-A cali-foo --jump DROP`)
+		Expect(err).To(HaveOccurred())
+		Expect(conn.batches).To(BeEmpty())
+	})
+
+	It("should reject unknown tables", func() {
+		err := runRestore("*security", "COMMIT")
+		Expect(errors.Is(err, errNftUnsupported)).To(BeTrue())
+	})
+})
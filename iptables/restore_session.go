@@ -0,0 +1,148 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iptables
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// restoreSessionMarkerPrefix starts the comment line that we write after each transaction.
+	restoreSessionMarkerPrefix = "# calico-restore-session-commit"
+	// restoreSessionTimeout is how long we wait for a transaction on top of the xtables lock timeout.
+	restoreSessionTimeout = 30 * time.Second
+	// maxRestoreSessionFailures is the number of consecutive session failures, where a one-shot
+	// iptables-restore of the same update succeeded, after which we stop using sessions.
+	maxRestoreSessionFailures = 3
+)
+
+var errRestoreSessionTimeout = errors.New("timed out waiting for iptables-restore to commit")
+
+// restoreSession is a long-lived iptables-restore --noflush process.  Each write of a restore
+// input to its stdin is applied as its own transaction: iptables-restore commits a table when it
+// reads the table's COMMIT line and releases the xtables lock straight afterwards, so the idle
+// process doesn't block other iptables users.
+//
+// To find out when a transaction has been committed, we follow it with a numbered marker comment.
+// In verbose mode, iptables-restore echoes comment lines to stdout as it reads them, which happens
+// after it has committed the preceding lines.  If a commit fails, iptables-restore exits instead,
+// which closes its stdout.
+type restoreSession struct {
+	cmd     CmdIface
+	stdin   *io.PipeWriter
+	stdout  *bufio.Reader
+	stderr  bytes.Buffer
+	timeout time.Duration
+	seqNo   uint64
+	logCxt  *log.Entry
+}
+
+func startRestoreSession(
+	newCmd cmdFactory,
+	restoreCmd string,
+	args []string,
+	timeout time.Duration,
+	logCxt *log.Entry,
+) (*restoreSession, error) {
+	cmd := newCmd(restoreCmd, args...)
+	stdinReader, stdinWriter := io.Pipe()
+	s := &restoreSession{
+		cmd:     cmd,
+		stdin:   stdinWriter,
+		timeout: timeout,
+		logCxt:  logCxt,
+	}
+	cmd.SetStdin(stdinReader)
+	// Only read after the process has exited, once Wait() has finished copying stderr.
+	cmd.SetStderr(&s.stderr)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	s.stdout = bufio.NewReader(stdout)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	logCxt.WithField("cmd", restoreCmd).Info("Started persistent iptables-restore session")
+	return s, nil
+}
+
+// Apply writes the input to iptables-restore and waits for it to be committed.  After an error,
+// the session must be closed.
+func (s *restoreSession) Apply(input []byte) error {
+	s.seqNo++
+	marker := fmt.Sprintf("%s %d\n", restoreSessionMarkerPrefix, s.seqNo)
+
+	// Write and read concurrently, in case iptables-restore fills its stdout pipe before it has
+	// read all of its input.
+	writeErrC := make(chan error, 1)
+	go func() {
+		_, err := s.stdin.Write(input)
+		if err == nil {
+			_, err = io.WriteString(s.stdin, marker)
+		}
+		writeErrC <- err
+	}()
+	readErrC := make(chan error, 1)
+	go func() {
+		readErrC <- s.waitForMarker(marker)
+	}()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-readErrC:
+		if err != nil {
+			return err
+		}
+		// iptables-restore has read the marker so the write has finished.
+		return <-writeErrC
+	case <-timer.C:
+		return errRestoreSessionTimeout
+	}
+}
+
+func (s *restoreSession) waitForMarker(marker string) error {
+	for {
+		line, err := s.stdout.ReadString('\n')
+		if line == marker {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("iptables-restore exited: %w", err)
+		}
+		s.logCxt.WithField("line", line).Debug("Output from iptables-restore session")
+	}
+}
+
+// Close stops the iptables-restore process and returns what it wrote to stderr.
+func (s *restoreSession) Close() string {
+	// Closing stdin makes an idle iptables-restore exit; it also unblocks a pending write.
+	_ = s.stdin.Close()
+	if err := s.cmd.Kill(); err != nil {
+		s.logCxt.WithError(err).Debug("Failed to kill iptables-restore session, it may have exited already")
+	}
+	if err := s.cmd.Wait(); err != nil {
+		s.logCxt.WithError(err).Debug("iptables-restore session exited with an error")
+	}
+	return s.stderr.String()
+}
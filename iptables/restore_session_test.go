@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iptables_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/projectcalico/felix/iptables"
+	"github.com/projectcalico/felix/logutils"
+	"github.com/projectcalico/felix/rules"
+)
+
+var _ = Describe("Table with persistent iptables-restore (nft)", func() {
+	describePersistentRestoreTests("nft")
+})
+var _ = Describe("Table with persistent iptables-restore (legacy)", func() {
+	describePersistentRestoreTests("legacy")
+})
+
+func describePersistentRestoreTests(dataplaneMode string) {
+	var dataplane *mockDataplane
+	var table *Table
+	BeforeEach(func() {
+		dataplane = newMockDataplane("filter", map[string][]string{
+			"FORWARD": {},
+			"INPUT":   {},
+			"OUTPUT":  {},
+		}, dataplaneMode)
+		featureDetector := NewFeatureDetector(nil)
+		featureDetector.NewCmd = dataplane.newCmd
+		featureDetector.GetKernelVersionReader = dataplane.getKernelVersionReader
+		table = NewTable(
+			"filter",
+			4,
+			rules.RuleHashPrefix,
+			&mockMutex{},
+			featureDetector,
+			TableOptions{
+				HistoricChainPrefixes: rules.AllHistoricChainNamePrefixes,
+				NewCmdOverride:        dataplane.newCmd,
+				SleepOverride:         dataplane.sleep,
+				NowOverride:           dataplane.now,
+				BackendMode:           dataplaneMode,
+				LookPathOverride:      lookPathNoLegacy,
+				OpRecorder:            logutils.NewSummarizer("test loop"),
+				PersistentRestore:     true,
+			},
+		)
+		table.InsertOrAppendRules("FORWARD", []Rule{
+			{Action: JumpAction{Target: "cali-foobar"}},
+		})
+	})
+
+	// numRestores returns the number of persistent sessions or one-shot iptables-restore calls.
+	numRestores := func(sessions bool) int {
+		n := 0
+		for _, cmd := range dataplane.Cmds {
+			if restore, ok := cmd.(*restoreCmd); ok && restore.Started == sessions {
+				n++
+			}
+		}
+		return n
+	}
+
+	applyChain := func(action Action) {
+		table.UpdateChain(&Chain{Name: "cali-foobar", Rules: []Rule{{Action: action}}})
+		table.Apply()
+		Expect(dataplane.Chains["cali-foobar"]).To(HaveLen(1))
+		Expect(dataplane.Chains["cali-foobar"][0]).To(HaveSuffix(action.ToFragment(nil)))
+	}
+
+	It("should apply each update in the same iptables-restore session", func() {
+		applyChain(AcceptAction{})
+		applyChain(DropAction{})
+		applyChain(ReturnAction{})
+		Expect(dataplane.Chains["FORWARD"]).To(HaveLen(1))
+		Expect(numRestores(true)).To(Equal(1))
+		Expect(numRestores(false)).To(Equal(0))
+	})
+
+	It("should fall back to a one-shot iptables-restore and then restart the session", func() {
+		dataplane.FailNextRestore = true
+		applyChain(AcceptAction{})
+		Expect(numRestores(true)).To(Equal(1))
+		Expect(numRestores(false)).To(Equal(1))
+
+		applyChain(DropAction{})
+		applyChain(ReturnAction{})
+		Expect(numRestores(true)).To(Equal(2))
+		Expect(numRestores(false)).To(Equal(1))
+	})
+
+	It("should stop using sessions after repeated failures", func() {
+		for _, action := range []Action{AcceptAction{}, DropAction{}, ReturnAction{}} {
+			dataplane.FailNextRestore = true
+			applyChain(action)
+		}
+		Expect(numRestores(true)).To(Equal(3))
+
+		applyChain(AcceptAction{})
+		Expect(numRestores(true)).To(Equal(3))
+		Expect(numRestores(false)).To(Equal(4))
+	})
+}
@@ -35,6 +35,17 @@ type Rule struct {
 	Match   MatchCriteria
 	Action  Action
 	Comment []string
+
+	// PolicyRuleID, if set, is the ID of the policy rule that this rule implements.  It isn't
+	// rendered; the Table uses it to attribute the rule's counters when ReadRuleCounters is
+	// enabled.  Should only be set on one iptables rule per policy rule and chain.
+	PolicyRuleID string
+}
+
+// RuleCounter holds the packet and byte counters of a rule, as read back from the dataplane.
+type RuleCounter struct {
+	Packets uint64
+	Bytes   uint64
 }
 
 func (r Rule) RenderAppend(chainName, prefixFragment string, features *Features) string {
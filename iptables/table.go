@@ -23,6 +23,7 @@ import (
 	"os/exec"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -32,6 +33,8 @@ import (
 
 	"github.com/projectcalico/libcalico-go/lib/set"
 
+	"github.com/projectcalico/felix/drift"
+	"github.com/projectcalico/felix/dryrun"
 	"github.com/projectcalico/felix/logutils"
 )
 
@@ -54,6 +57,9 @@ var (
 	chainCreateRegexp = regexp.MustCompile(`^:(\S+)`)
 	// appendRegexp matches an iptables-save output line for an append operation.
 	appendRegexp = regexp.MustCompile(`^-A (\S+)`)
+	// counterPrefixRegexp matches the "[packets:bytes] " prefix that iptables-save -c adds to
+	// each rule.  It captures the packet and byte counts.
+	counterPrefixRegexp = regexp.MustCompile(`^\[(\d+):(\d+)\] `)
 	// nftErrorRegexp matches a particular error emitted if iptables-nft is run on a system that
 	// uses nft features that iptables-nft doesn't understand.
 	nftErrorRegexp = regexp.MustCompile(`^# Table .* is incompatible, use 'nft' tool.`)
@@ -213,6 +219,9 @@ type Table struct {
 	dirtyChains    set.Set
 
 	inSyncWithDataPlane bool
+	// resyncReason is the reason that we last invalidated our cache of the dataplane, which we
+	// include in any drift that the resync finds.
+	resyncReason string
 
 	// chainToDataplaneHashes contains the rule hashes that we think are in the dataplane.
 	// it is updated when we write to the dataplane but it can also be read back and compared
@@ -223,6 +232,14 @@ type Table struct {
 	// to slices of rules in that chain.
 	chainToFullRules map[string][]string
 
+	// readRuleCounters is true if we should ask iptables-save for the rule counters when we
+	// resync.  chainToDataplaneCounters then holds the counters from the most recent read, in
+	// the same layout as chainToDataplaneHashes, and ruleCounters holds the totals for each
+	// policy rule ID, calculated from our in-sync chains.
+	readRuleCounters         bool
+	chainToDataplaneCounters map[string][]RuleCounter
+	ruleCounters             map[string]RuleCounter
+
 	// hashCommentPrefix holds the prefix that we prepend to our rule-tracking hashes.
 	hashCommentPrefix string
 	// hashCommentRegexp matches the rule-tracking comment, capturing the rule hash.
@@ -259,6 +276,19 @@ type Table struct {
 	// implementation.
 	lockProbeInterval time.Duration
 
+	// persistentRestore is true if we apply updates through a long-lived iptables-restore process.
+	// It is disabled if the session keeps failing when one-shot iptables-restore calls succeed.
+	persistentRestore bool
+	restoreSession    *restoreSession
+	// numRestoreSessionFailures counts the consecutive updates that failed in the session but
+	// succeeded with a one-shot iptables-restore.
+	numRestoreSessionFailures int
+
+	// dryRun, if non-nil, receives the updates instead of iptables-restore.
+	dryRun *dryrun.Recorder
+	// driftReporter receives the out-of-sync chains that we find when we resync.
+	driftReporter *drift.Reporter
+
 	logCxt *log.Entry
 
 	gaugeNumChains        prometheus.Gauge
@@ -292,6 +322,10 @@ type TableOptions struct {
 	LockTimeout time.Duration
 	// LockProbeInterval is the probe interval to use for iptables-restore's native xtables lock.
 	LockProbeInterval time.Duration
+	// PersistentRestore, if true, makes the table keep a long-lived iptables-restore process and
+	// apply each update as a transaction on its stdin, rather than running iptables-restore for
+	// each update.  Not supported by the native nftables backend.
+	PersistentRestore bool
 
 	// NewCmdOverride for tests, if non-nil, factory to use instead of the real exec.Command()
 	NewCmdOverride cmdFactory
@@ -305,6 +339,16 @@ type TableOptions struct {
 	OnStillAlive func()
 	// OpRecorder to tell when we do resyncs etc.
 	OpRecorder logutils.OpRecorder
+	// ReadRuleCounters, if true, makes the table read back the packet and byte counters of
+	// rules that have a PolicyRuleID each time it resyncs with the dataplane.  Not supported
+	// by the native nftables backend.
+	ReadRuleCounters bool
+	// DryRun, if non-nil, puts the table in observe-only mode: it still reads the dataplane and
+	// calculates its updates but it passes them to the recorder instead of iptables-restore.
+	DryRun *dryrun.Recorder
+	// DriftReporter, if non-nil, is told about each chain that a resync finds to be out of sync
+	// because something else has modified it.
+	DriftReporter *drift.Reporter
 }
 
 func NewTable(
@@ -396,6 +440,8 @@ func NewTable(
 		dirtyChains:            set.New(),
 		chainToDataplaneHashes: map[string][]string{},
 		chainToFullRules:       map[string][]string{},
+		readRuleCounters:       options.ReadRuleCounters,
+		ruleCounters:           map[string]RuleCounter{},
 		logCxt: log.WithFields(log.Fields{
 			"ipVersion": ipVersion,
 			"table":     name,
@@ -421,6 +467,10 @@ func NewTable(
 		lockTimeout:       options.LockTimeout,
 		lockProbeInterval: options.LockProbeInterval,
 
+		dryRun:        options.DryRun,
+		driftReporter: options.DriftReporter,
+		resyncReason:  drift.ResyncStartup,
+
 		newCmd:    newCmd,
 		timeSleep: sleep,
 		timeNow:   now,
@@ -448,8 +498,22 @@ func NewTable(
 		table.nftablesMode = true
 	}
 
+	if iptablesVariant == BackendNftables {
+		// Native nftables mode: program the kernel directly over netlink rather than
+		// forking iptables-restore/save.  The in-process implementation doesn't suffer
+		// from the iptables-nft rule-replacement bug so we don't set nftablesMode.
+		log.Info("Using native nftables backend.")
+		table.iptablesRestoreCmd = nftablesRestoreCmd
+		table.iptablesSaveCmd = nftablesSaveCmd
+		if options.NewCmdOverride == nil {
+			table.newCmd = newNftablesCmdFactory(ipVersion)
+		}
+		return table
+	}
+
 	table.iptablesRestoreCmd = findBestBinary(table.lookPath, ipVersion, iptablesVariant, "restore")
 	table.iptablesSaveCmd = findBestBinary(table.lookPath, ipVersion, iptablesVariant, "save")
+	table.persistentRestore = options.PersistentRestore
 
 	return table
 }
@@ -633,6 +697,7 @@ func (t *Table) loadDataplaneState() {
 				if dataplaneHasInserts {
 					logCxt.WithField("actualRuleIDs", dpHashes).Warn(
 						"Chain had unexpected inserts, marking for resync")
+					t.reportDrift(chainName, "unexpected inserted rules")
 					t.dirtyInsertAppend.Add(chainName)
 				}
 				continue
@@ -650,12 +715,19 @@ func (t *Table) loadDataplaneState() {
 					"expectedRuleIDs": expectedHashes,
 					"actualRuleIDs":   dpHashes,
 				}).Warn("Detected out-of-sync inserts, marking for resync")
+				t.reportDrift(chainName, "inserted rules out of sync")
 				t.dirtyInsertAppend.Add(chainName)
 			}
 		} else {
 			// One of our chains, should match exactly.
 			if !reflect.DeepEqual(dpHashes, expectedHashes) {
 				logCxt.Warn("Detected out-of-sync Calico chain, marking for resync")
+				if _, ok := dataplaneHashes[chainName]; !ok {
+					t.reportDrift(chainName, "chain missing")
+				} else {
+					t.reportDrift(chainName, fmt.Sprintf("rules out of sync (expected %d rules, found %d)",
+						len(expectedHashes), len(dpHashes)))
+				}
 				t.dirtyChains.Add(chainName)
 			}
 		}
@@ -683,6 +755,7 @@ func (t *Table) loadDataplaneState() {
 			for _, hash := range dataplaneHashes {
 				if hash != "" {
 					logCxt.Info("Found unexpected insert, marking for cleanup")
+					t.reportDrift(chainName, "unexpected inserted rules")
 					t.dirtyInsertAppend.Add(chainName)
 					break
 				}
@@ -691,6 +764,7 @@ func (t *Table) loadDataplaneState() {
 		}
 		// Chain exists in dataplane but not in memory, mark as dirty so we'll clean it up.
 		logCxt.Info("Found unexpected chain, marking for cleanup")
+		t.reportDrift(chainName, "unexpected chain")
 		t.dirtyChains.Add(chainName)
 	}
 
@@ -698,6 +772,44 @@ func (t *Table) loadDataplaneState() {
 	t.chainToDataplaneHashes = dataplaneHashes
 	t.chainToFullRules = dataplaneRules
 	t.inSyncWithDataPlane = true
+
+	if t.readRuleCounters {
+		t.updateRuleCounters()
+	}
+}
+
+// updateRuleCounters recalculates the per-policy-rule counters from the counters that we just
+// read from the dataplane.  Only chains that are in sync are included since, for those, the
+// rules in the dataplane line up with the rules in our cache.  The counters for a rule whose chain
+// is out of sync drop out until the chain has been rewritten and re-read.
+func (t *Table) updateRuleCounters() {
+	ruleCounters := map[string]RuleCounter{}
+	for chainName, chain := range t.chainNameToChain {
+		if t.dirtyChains.Contains(chainName) {
+			continue
+		}
+		counters := t.chainToDataplaneCounters[chainName]
+		if len(counters) != len(chain.Rules) {
+			continue
+		}
+		for i, rule := range chain.Rules {
+			if rule.PolicyRuleID == "" {
+				continue
+			}
+			c := ruleCounters[rule.PolicyRuleID]
+			c.Packets += counters[i].Packets
+			c.Bytes += counters[i].Bytes
+			ruleCounters[rule.PolicyRuleID] = c
+		}
+	}
+	t.ruleCounters = ruleCounters
+}
+
+// RuleCounters returns the total packet and byte counters for each policy rule ID, as of the most
+// recent resync with the dataplane.  Empty unless the table was created with ReadRuleCounters.
+// The counters of a rule are reset when its chain is rewritten.
+func (t *Table) RuleCounters() map[string]RuleCounter {
+	return t.ruleCounters
 }
 
 // expectedHashesForInsertAppendChain calculates the expected hashes for a whole top-level chain
@@ -778,7 +890,11 @@ func (t *Table) getHashesAndRulesFromDataplane() (hashes map[string][]string, ru
 // attemptToGetHashesAndRulesFromDataplane starts an iptables-save subprocess and feeds its output to
 // readHashesAndRulesFrom() via a pipe.  It handles the various error cases.
 func (t *Table) attemptToGetHashesAndRulesFromDataplane() (hashes map[string][]string, rules map[string][]string, err error) {
-	cmd := t.newCmd(t.iptablesSaveCmd, "-t", t.Name)
+	args := []string{"-t", t.Name}
+	if t.readRuleCounters {
+		args = append(args, "-c")
+	}
+	cmd := t.newCmd(t.iptablesSaveCmd, args...)
 	countNumSaveCalls.Inc()
 
 	stdout, err := cmd.StdoutPipe()
@@ -828,6 +944,7 @@ func (t *Table) attemptToGetHashesAndRulesFromDataplane() (hashes map[string][]s
 func (t *Table) readHashesAndRulesFrom(r io.ReadCloser) (hashes map[string][]string, rules map[string][]string, err error) {
 	hashes = map[string][]string{}
 	rules = map[string][]string{}
+	counters := map[string][]RuleCounter{}
 	scanner := bufio.NewScanner(r)
 
 	// Keep track of whether the non-Calico chain has inserts. If the chain does not have inserts, we'll remove the
@@ -871,6 +988,16 @@ func (t *Table) readHashesAndRulesFrom(r io.ReadCloser) (hashes map[string][]str
 			continue
 		}
 
+		// If we asked for counters, the rules are prefixed with "[packets:bytes] ".  Strip
+		// the prefix so that the rest of the parsing (and the full rules that we store) are
+		// the same either way.
+		var counter RuleCounter
+		if captures := counterPrefixRegexp.FindSubmatch(line); captures != nil {
+			counter.Packets, _ = strconv.ParseUint(string(captures[1]), 10, 64)
+			counter.Bytes, _ = strconv.ParseUint(string(captures[2]), 10, 64)
+			line = line[len(captures[0]):]
+		}
+
 		// Look for append lines, such as "-A chain-name -m foo --foo bar"; these are the
 		// actual rules.
 		captures = appendRegexp.FindSubmatch(line)
@@ -902,6 +1029,7 @@ func (t *Table) readHashesAndRulesFrom(r io.ReadCloser) (hashes map[string][]str
 			chainHasCalicoRule.Add(chainName)
 		}
 		hashes[chainName] = append(hashes[chainName], hash)
+		counters[chainName] = append(counters[chainName], counter)
 
 		// Not our chain so cache the full rule in case we need to generate deletes later on.
 		// After scanning the input, we prune any chains of full rules that do not contain inserts.
@@ -930,6 +1058,7 @@ func (t *Table) readHashesAndRulesFrom(r io.ReadCloser) (hashes map[string][]str
 	}
 	t.logCxt.Debugf("Read hashes from dataplane: %#v", hashes)
 	t.logCxt.Debugf("Read rules from dataplane: %#v", rules)
+	t.chainToDataplaneCounters = counters
 	return hashes, rules, nil
 }
 
@@ -941,6 +1070,21 @@ func (t *Table) InvalidateDataplaneCache(reason string) {
 	}
 	logCxt.Debug("Invalidating dataplane cache")
 	t.inSyncWithDataPlane = false
+	t.resyncReason = reason
+}
+
+// reportDrift tells the drift reporter that the resync found a problem with the given chain.
+func (t *Table) reportDrift(chainName, problem string) {
+	if drift.IsExpected(t.resyncReason) {
+		return
+	}
+	t.driftReporter.Report(drift.Event{
+		Subsystem: drift.SubsystemIptables,
+		Component: fmt.Sprintf("%v-v%d", t.Name, t.IPVersion),
+		Object:    chainName,
+		Problem:   problem,
+		Resync:    t.resyncReason,
+	})
 }
 
 func (t *Table) Apply() (rescheduleAfter time.Duration) {
@@ -1277,7 +1421,6 @@ func (t *Table) applyUpdates() error {
 			t.logCxt.WithField("iptablesInput", inputStr).Debug("Writing to iptables")
 		}
 
-		var outputBuf, errBuf bytes.Buffer
 		args := []string{"--noflush", "--verbose"}
 		if features.RestoreSupportsLock {
 			// Versions of iptables-restore that support the xtables lock also make it impossible to disable.  Make
@@ -1302,27 +1445,22 @@ func (t *Table) applyUpdates() error {
 				"probeIntervalMicros": intervalStr,
 			}).Debug("Using native iptables-restore xtables lock.")
 		}
-		cmd := t.newCmd(t.iptablesRestoreCmd, args...)
-		cmd.SetStdin(bytes.NewReader(inputBytes))
-		cmd.SetStdout(&outputBuf)
-		cmd.SetStderr(&errBuf)
-		countNumRestoreCalls.Inc()
-		// Note: calicoXtablesLock will be a dummy lock if our xtables lock is disabled (i.e. if iptables-restore
-		// supports the xtables lock itself, or if our implementation is disabled by config.
-		t.calicoXtablesLock.Lock()
-		err := cmd.Run()
-		t.calicoXtablesLock.Unlock()
+		var err error
+		if t.dryRun != nil {
+			// Observe-only mode: report what we would have written and carry on as if it had
+			// succeeded so that we only report each change once.
+			t.dryRun.Record(fmt.Sprintf("iptables-%v-v%d", t.Name, t.IPVersion), string(inputBytes))
+		} else {
+			countNumRestoreCalls.Inc()
+			// Note: calicoXtablesLock will be a dummy lock if our xtables lock is disabled (i.e. if iptables-restore
+			// supports the xtables lock itself, or if our implementation is disabled by config.
+			t.calicoXtablesLock.Lock()
+			err = t.execRestore(inputBytes, args)
+			t.calicoXtablesLock.Unlock()
+		}
 		if err != nil {
-			// To log out the input, we must convert to string here since, after we return, the buffer can be re-used
-			// (and the logger may convert to string on a background thread).
-			inputStr := string(inputBytes)
-			t.logCxt.WithFields(log.Fields{
-				"output":      outputBuf.String(),
-				"errorOutput": errBuf.String(),
-				"error":       err,
-				"input":       inputStr,
-			}).Warn("Failed to execute ip(6)tables-restore command")
 			t.inSyncWithDataPlane = false
+			t.resyncReason = drift.ResyncUpdateFailure
 			countNumRestoreErrors.Inc()
 			return err
 		}
@@ -1349,6 +1487,77 @@ func (t *Table) applyUpdates() error {
 	return nil
 }
 
+// execRestore applies the input with iptables-restore.  If persistent restore is enabled, it uses the
+// restore session, starting one if needed, and falls back to a one-shot iptables-restore if the
+// session fails.
+func (t *Table) execRestore(inputBytes []byte, args []string) error {
+	if !t.persistentRestore {
+		return t.execOneShotRestore(inputBytes, args)
+	}
+
+	err := t.execRestoreInSession(inputBytes, args)
+	if err == nil {
+		t.numRestoreSessionFailures = 0
+		return nil
+	}
+	t.logCxt.WithError(err).Warn(
+		"Failed to apply update in iptables-restore session, falling back to one-shot iptables-restore")
+	err = t.execOneShotRestore(inputBytes, args)
+	if err != nil {
+		// The update itself failed so the session wasn't to blame.
+		return err
+	}
+	t.numRestoreSessionFailures++
+	if t.numRestoreSessionFailures >= maxRestoreSessionFailures {
+		t.logCxt.WithField("numFailures", t.numRestoreSessionFailures).Warn(
+			"iptables-restore session keeps failing, disabling persistent iptables-restore")
+		t.persistentRestore = false
+	}
+	return nil
+}
+
+func (t *Table) execRestoreInSession(inputBytes []byte, args []string) error {
+	if t.restoreSession == nil {
+		timeout := restoreSessionTimeout + t.lockTimeout
+		session, err := startRestoreSession(t.newCmd, t.iptablesRestoreCmd, args, timeout, t.logCxt)
+		if err != nil {
+			return err
+		}
+		t.restoreSession = session
+	}
+	err := t.restoreSession.Apply(inputBytes)
+	if err != nil {
+		errorOutput := t.restoreSession.Close()
+		t.restoreSession = nil
+		t.logCxt.WithFields(log.Fields{
+			"errorOutput": errorOutput,
+			"error":       err,
+		}).Debug("iptables-restore session failed")
+	}
+	return err
+}
+
+func (t *Table) execOneShotRestore(inputBytes []byte, args []string) error {
+	var outputBuf, errBuf bytes.Buffer
+	cmd := t.newCmd(t.iptablesRestoreCmd, args...)
+	cmd.SetStdin(bytes.NewReader(inputBytes))
+	cmd.SetStdout(&outputBuf)
+	cmd.SetStderr(&errBuf)
+	err := cmd.Run()
+	if err != nil {
+		// To log out the input, we must convert to string here since, after we return, the buffer can be re-used
+		// (and the logger may convert to string on a background thread).
+		inputStr := string(inputBytes)
+		t.logCxt.WithFields(log.Fields{
+			"output":      outputBuf.String(),
+			"errorOutput": errBuf.String(),
+			"error":       err,
+			"input":       inputStr,
+		}).Warn("Failed to execute ip(6)tables-restore command")
+	}
+	return err
+}
+
 // desiredStateOfChain returns the given chain, if and only if it exists in the cache and it is referenced by some
 // other chain.  If the chain doesn't exist or it is not referenced, returns nil and false.
 func (t *Table) desiredStateOfChain(chainName string) (chain *Chain, present bool) {
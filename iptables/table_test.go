@@ -1649,6 +1649,75 @@ func describeInsertAndNonCalicoChainTests(dataplaneMode string) {
 	})
 }
 
+var _ = Describe("Table with rule counters", func() {
+	var dataplane *mockDataplane
+	var table *Table
+	BeforeEach(func() {
+		dataplane = newMockDataplane("filter", map[string][]string{
+			"FORWARD": {},
+			"INPUT":   {},
+			"OUTPUT":  {},
+		}, "legacy")
+		featureDetector := NewFeatureDetector(nil)
+		featureDetector.NewCmd = dataplane.newCmd
+		featureDetector.GetKernelVersionReader = dataplane.getKernelVersionReader
+		table = NewTable(
+			"filter",
+			4,
+			rules.RuleHashPrefix,
+			&mockMutex{},
+			featureDetector,
+			TableOptions{
+				HistoricChainPrefixes: rules.AllHistoricChainNamePrefixes,
+				NewCmdOverride:        dataplane.newCmd,
+				SleepOverride:         dataplane.sleep,
+				NowOverride:           dataplane.now,
+				LookPathOverride:      lookPathNoLegacy,
+				OpRecorder:            logutils.NewSummarizer("test loop"),
+				ReadRuleCounters:      true,
+			},
+		)
+		table.InsertOrAppendRules("FORWARD", []Rule{
+			{Action: JumpAction{Target: "cali-pi-foo"}},
+		})
+		table.UpdateChain(&Chain{
+			Name: "cali-pi-foo",
+			Rules: []Rule{
+				{Action: SetMarkAction{Mark: 0x10}},
+				{Match: Match().MarkSingleBitSet(0x10), Action: AcceptAction{}, PolicyRuleID: "rule-1"},
+				{Action: DropAction{}, PolicyRuleID: "rule-2"},
+			},
+		})
+		table.Apply()
+	})
+
+	It("should not have counters for chains that were out of sync when read", func() {
+		Expect(table.RuleCounters()).To(BeEmpty())
+	})
+
+	It("should attribute the counters of in-sync chains after a resync", func() {
+		table.InvalidateDataplaneCache("test")
+		table.Apply()
+		Expect(dataplane.Chains["cali-pi-foo"]).To(HaveLen(3))
+		Expect(table.RuleCounters()).To(Equal(map[string]RuleCounter{
+			"rule-1": {Packets: 2, Bytes: 200},
+			"rule-2": {Packets: 3, Bytes: 300},
+		}))
+	})
+
+	It("should drop the counters of a chain that's being rewritten", func() {
+		table.InvalidateDataplaneCache("test")
+		table.Apply()
+		table.UpdateChain(&Chain{
+			Name:  "cali-pi-foo",
+			Rules: []Rule{{Action: DropAction{}, PolicyRuleID: "rule-2"}},
+		})
+		table.InvalidateDataplaneCache("test")
+		table.Apply()
+		Expect(table.RuleCounters()).To(BeEmpty())
+	})
+})
+
 type mockMutex struct {
 	Held     bool
 	WasTaken bool
@@ -15,6 +15,7 @@
 package iptables_test
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
@@ -118,9 +119,14 @@ func (d *mockDataplane) newCmd(name string, arg ...string) CmdIface {
 	case "iptables-save", "ip6tables-save",
 		"iptables-legacy-save", "ip6tables-legacy-save",
 		"iptables-nft-save", "ip6tables-nft-save":
+		counters := len(arg) == 3 && arg[2] == "-c"
+		if counters {
+			arg = arg[:2]
+		}
 		Expect(arg).To(Equal([]string{"-t", d.Table}))
 		cmd = &saveCmd{
 			Dataplane: d,
+			Counters:  counters,
 		}
 	case "iptables":
 		Expect(arg).To(Equal([]string{"--version"}))
@@ -175,18 +181,17 @@ type restoreCmd struct {
 	CapturedStdin string
 	Stdout        io.Writer
 	Stderr        io.Writer
+
+	// Persistent session state: the write end of the stdout pipe, and the exit error, which is
+	// set before done is closed.
+	Started      bool
+	stdoutWriter *io.PipeWriter
+	done         chan struct{}
+	exitErr      error
 }
 
 func (d *restoreCmd) SetStdin(r io.Reader) {
-
-	var buf bytes.Buffer
-	_, err := io.Copy(&buf, r)
-	if err != nil {
-		panic(err)
-	}
-
-	d.Stdin = &buf
-	d.CapturedStdin = buf.String()
+	d.Stdin = r
 }
 
 func (d *restoreCmd) SetStdout(w io.Writer) {
@@ -203,21 +208,64 @@ func (d *restoreCmd) Output() ([]byte, error) {
 }
 
 func (d *restoreCmd) StdoutPipe() (io.ReadCloser, error) {
-	Fail("Not implemented")
-	return nil, errors.New("Not implemented")
+	r, w := io.Pipe()
+	d.stdoutWriter = w
+	return r, nil
 }
 
+// Start simulates a persistent iptables-restore session.  It applies the input up to each comment
+// line as a transaction and then echoes the comment, like iptables-restore --verbose.  It exits if
+// a transaction fails.
 func (d *restoreCmd) Start() error {
-	Fail("Not implemented")
-	return errors.New("Not implemented")
+	if d.Dataplane.FailNextStart {
+		d.Dataplane.FailNextStart = false
+		return errors.New("dummy error")
+	}
+	Expect(d.stdoutWriter).NotTo(BeNil(), "Start() called without StdoutPipe()")
+	d.Started = true
+	d.done = make(chan struct{})
+	go func() {
+		defer GinkgoRecover()
+		defer close(d.done)
+		defer d.stdoutWriter.Close()
+
+		reader := bufio.NewReader(d.Stdin)
+		var txn strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				// Stdin closed; a real iptables-restore would also commit any trailing
+				// transaction but we never send one.
+				Expect(txn.String()).To(BeEmpty(), "Session closed with uncommitted input")
+				return
+			}
+			if !strings.HasPrefix(line, "#") {
+				txn.WriteString(line)
+				continue
+			}
+			d.CapturedStdin += txn.String()
+			d.exitErr = d.Dataplane.applyRestoreInput(txn.String())
+			txn.Reset()
+			if d.exitErr != nil {
+				return
+			}
+			_, err = io.WriteString(d.stdoutWriter, line)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}()
+	return nil
 }
 
 func (d *restoreCmd) Wait() error {
-	Fail("Not implemented")
-	return errors.New("Not implemented")
+	Expect(d.Started).To(BeTrue(), "Wait() called without Start()")
+	<-d.done
+	return d.exitErr
 }
 
 func (d *restoreCmd) Kill() error {
+	if stdin, ok := d.Stdin.(*io.PipeReader); ok {
+		_ = stdin.Close()
+	}
 	return nil
 }
 
@@ -231,19 +279,22 @@ func (d *restoreCmd) Run() error {
 	var buf bytes.Buffer
 	_, err := buf.ReadFrom(d.Stdin)
 	Expect(err).NotTo(HaveOccurred())
-	input := buf.String()
+	d.CapturedStdin = buf.String()
+	return d.Dataplane.applyRestoreInput(d.CapturedStdin)
+}
 
-	if d.Dataplane.OnPreRestore != nil {
+func (d *mockDataplane) applyRestoreInput(input string) error {
+	if d.OnPreRestore != nil {
 		log.Warn("OnPreRestore set, calling it")
-		d.Dataplane.OnPreRestore()
-		d.Dataplane.OnPreRestore = nil
+		d.OnPreRestore()
+		d.OnPreRestore = nil
 	}
-	if d.Dataplane.FailNextRestore {
+	if d.FailNextRestore {
 		log.Warn("Simulating an iptables-restore failure")
-		d.Dataplane.FailNextRestore = false
+		d.FailNextRestore = false
 		return errors.New("Simulated failure")
 	}
-	if d.Dataplane.FailAllRestores {
+	if d.FailAllRestores {
 		log.Warn("Simulating an iptables-restore failure")
 		return errors.New("Simulated failure")
 	}
@@ -266,12 +317,12 @@ func (d *restoreCmd) Run() error {
 		if strings.HasPrefix(line, "*") {
 			// Start of a table.
 			if tableSeen {
-				Expect(d.Dataplane.NftablesMode).To(BeTrue(), "Only nft mode should use more than one transaction")
+				Expect(d.NftablesMode).To(BeTrue(), "Only nft mode should use more than one transaction")
 				// We've already had one transaction, check that it was committed
 				Expect(commitSeen).To(BeTrue())
 				commitSeen = false
 			}
-			Expect(line[1:]).To(Equal(d.Dataplane.Table))
+			Expect(line[1:]).To(Equal(d.Table))
 			tableSeen = true
 			continue
 		}
@@ -282,7 +333,7 @@ func (d *restoreCmd) Run() error {
 			continue
 		}
 
-		chains := d.Dataplane.Chains
+		chains := d.Chains
 
 		if strings.HasPrefix(line, ":") {
 			// Chain forward-ref, creates and flushes the chain as needed.
@@ -290,7 +341,7 @@ func (d *restoreCmd) Run() error {
 			chainName := parts[0]
 			Expect(parts[1:]).To(Equal([]string{"-", "-"}))
 			chains[chainName] = []string{}
-			d.Dataplane.FlushedChains.Add(chainName)
+			d.FlushedChains.Add(chainName)
 			continue
 		}
 
@@ -300,14 +351,14 @@ func (d *restoreCmd) Run() error {
 		switch action {
 		case "-A", "--append":
 			chainName = parts[1]
-			if strings.HasPrefix(chainName, "cali") && d.Dataplane.NftablesMode {
-				Expect(d.Dataplane.FlushedChains.Contains(chainName)).To(BeTrue(),
+			if strings.HasPrefix(chainName, "cali") && d.NftablesMode {
+				Expect(d.FlushedChains.Contains(chainName)).To(BeTrue(),
 					"In nft mode, it's not safe to modify chain without flushing")
 			}
 			rest := strings.Join(parts[2:], " ")
 			Expect(chains[chainName]).NotTo(BeNil(), "Append to unknown chain: "+chainName)
 			chains[chainName] = append(chains[chainName], rest)
-			d.Dataplane.ChainMods.Add(chainMod{name: chainName, ruleNum: len(chains[chainName])})
+			d.ChainMods.Add(chainMod{name: chainName, ruleNum: len(chains[chainName])})
 		case "-I", "--insert":
 			chainName = parts[1]
 			rest := strings.Join(parts[2:], " ")
@@ -321,17 +372,17 @@ func (d *restoreCmd) Run() error {
 				chain = append(chain, "")
 				copy(chain[ruleIdx+1:], chain[ruleIdx:])
 				chain[ruleIdx] = rest
-				d.Dataplane.ChainMods.Add(chainMod{name: chainName, ruleNum: lineNum})
+				d.ChainMods.Add(chainMod{name: chainName, ruleNum: lineNum})
 			} else {
 				// Otherwise insert at the top.
 				for i := len(chain) - 1; i > 0; i-- {
 					chain[i] = chain[i-1]
 				}
 				chain[0] = rest
-				d.Dataplane.ChainMods.Add(chainMod{name: chainName, ruleNum: 1})
+				d.ChainMods.Add(chainMod{name: chainName, ruleNum: 1})
 			}
 		case "-R", "--replace":
-			Expect(d.Dataplane.NftablesMode).To(BeFalse(), "Replace shouldn't be used in nft mode")
+			Expect(d.NftablesMode).To(BeFalse(), "Replace shouldn't be used in nft mode")
 			chainName = parts[1]
 			ruleNum, err := strconv.Atoi(parts[2]) // 1-indexed position of rule.
 			Expect(err).NotTo(HaveOccurred())
@@ -340,7 +391,7 @@ func (d *restoreCmd) Run() error {
 			chain := chains[chainName]
 			Expect(len(chain)).To(BeNumerically(">", ruleIdx), "Replace of non-existent rule")
 			chain[ruleIdx] = rest
-			d.Dataplane.ChainMods.Add(chainMod{name: chainName, ruleNum: ruleNum})
+			d.ChainMods.Add(chainMod{name: chainName, ruleNum: ruleNum})
 		case "-D", "--delete":
 			chainName = parts[1]
 
@@ -357,7 +408,7 @@ func (d *restoreCmd) Run() error {
 					chain[i] = chain[i+1]
 				}
 				chains[chainName] = chain[:len(chain)-1]
-				d.Dataplane.ChainMods.Add(chainMod{name: chainName, ruleNum: ruleNum})
+				d.ChainMods.Add(chainMod{name: chainName, ruleNum: ruleNum})
 			} else {
 				// Otherwise, treat this as a delete by full rule.
 
@@ -378,7 +429,7 @@ func (d *restoreCmd) Run() error {
 
 				Expect(found).To(BeTrue(), "Delete of non-existent rule")
 				chains[chainName] = newChain
-				d.Dataplane.ChainMods.Add(chainMod{name: chainName, ruleNum: i})
+				d.ChainMods.Add(chainMod{name: chainName, ruleNum: i})
 
 			}
 		case "-X", "--delete-chain":
@@ -386,7 +437,7 @@ func (d *restoreCmd) Run() error {
 			Expect(parts).To(HaveLen(2), "--delete-chain only has one argument")
 			Expect(chains[chainName]).To(Equal([]string{}), "Only empty chains can be deleted")
 			delete(chains, chainName)
-			d.Dataplane.DeletedChains.Add(chainName)
+			d.DeletedChains.Add(chainName)
 		default:
 			Fail("Unknown action: " + action)
 		}
@@ -409,6 +460,7 @@ func prependLine(src []string, line string) []string {
 
 type saveCmd struct {
 	Dataplane  *mockDataplane
+	Counters   bool
 	stdoutPipe *closableBuffer
 }
 
@@ -468,7 +520,11 @@ func (d *saveCmd) Output() ([]byte, error) {
 	}
 
 	for chainName, chain := range d.Dataplane.Chains {
-		for _, rule := range chain {
+		for i, rule := range chain {
+			if d.Counters {
+				// Fake counters that depend on the position of the rule in the chain.
+				buf.WriteString(fmt.Sprintf("[%d:%d] ", i+1, (i+1)*100))
+			}
 			buf.WriteString(fmt.Sprintf("-A %s %s\n", chainName, rule))
 		}
 	}
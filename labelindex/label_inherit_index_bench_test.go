@@ -0,0 +1,149 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labelindex_test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	. "github.com/projectcalico/felix/labelindex"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/selector"
+)
+
+func BenchmarkInheritIndexEndpointUpdate10Sels(b *testing.B) {
+	benchmarkInheritIndexEndpointUpdates(b, 10)
+}
+func BenchmarkInheritIndexEndpointUpdate100Sels(b *testing.B) {
+	benchmarkInheritIndexEndpointUpdates(b, 100)
+}
+func BenchmarkInheritIndexEndpointUpdate1000Sels(b *testing.B) {
+	benchmarkInheritIndexEndpointUpdates(b, 1000)
+}
+func BenchmarkInheritIndexEndpointUpdate10000Sels(b *testing.B) {
+	benchmarkInheritIndexEndpointUpdates(b, 10000)
+}
+
+func BenchmarkInheritIndexSelectorUpdate100Endpoints(b *testing.B) {
+	benchmarkInheritIndexSelectorUpdates(b, 100)
+}
+func BenchmarkInheritIndexSelectorUpdate1000Endpoints(b *testing.B) {
+	benchmarkInheritIndexSelectorUpdates(b, 1000)
+}
+func BenchmarkInheritIndexSelectorUpdate20000Endpoints(b *testing.B) {
+	benchmarkInheritIndexSelectorUpdates(b, 20000)
+}
+
+// benchmarkInheritIndexEndpointUpdates measures the cost of adding an endpoint when there are
+// numSels selectors, each of which matches a subset of the endpoints, plus a handful that can't
+// be indexed.
+func benchmarkInheritIndexEndpointUpdates(b *testing.B, numSels int) {
+	logLevel := logrus.GetLevel()
+	logrus.SetLevel(logrus.InfoLevel)
+	defer logrus.SetLevel(logLevel)
+
+	idx, numMatches := newBenchInheritIndex()
+	for i := 0; i < numSels; i++ {
+		idx.UpdateSelector(fmt.Sprintf("sel-%d", i), mustParseBenchSelector(
+			fmt.Sprintf(`app == "app-%d" && has(role)`, i)))
+	}
+	idx.UpdateSelector("not-app", mustParseBenchSelector(`!has(app)`))
+	idx.UpdateSelector("all", mustParseBenchSelector(`all()`))
+
+	updates := make([]api.Update, b.N)
+	for n := 0; n < b.N; n++ {
+		updates[n] = benchWorkloadUpdate(n, numSels)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		idx.OnUpdate(updates[n])
+	}
+
+	runtime.KeepAlive(*numMatches)
+}
+
+// benchmarkInheritIndexSelectorUpdates measures the cost of adding a selector when there are
+// numEndpoints endpoints.
+func benchmarkInheritIndexSelectorUpdates(b *testing.B, numEndpoints int) {
+	logLevel := logrus.GetLevel()
+	logrus.SetLevel(logrus.InfoLevel)
+	defer logrus.SetLevel(logLevel)
+
+	const numApps = 100
+	idx, numMatches := newBenchInheritIndex()
+	for n := 0; n < numEndpoints; n++ {
+		idx.OnUpdate(benchWorkloadUpdate(n, numApps))
+	}
+
+	sels := make([]selector.Selector, b.N)
+	for n := 0; n < b.N; n++ {
+		sels[n] = mustParseBenchSelector(fmt.Sprintf(`app == "app-%d" || tier == "tier-%d"`, n%numApps, n))
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		idx.UpdateSelector(fmt.Sprintf("sel-%d", n), sels[n])
+	}
+
+	runtime.KeepAlive(*numMatches)
+}
+
+func newBenchInheritIndex() (*InheritIndex, *int) {
+	numMatches := 0
+	idx := NewInheritIndex(
+		func(selId, labelId interface{}) {
+			numMatches++
+		},
+		func(selId, labelId interface{}) {
+			numMatches--
+		},
+	)
+	idx.UpdateParentLabels("default", map[string]string{"role": "frontend"})
+	return idx, &numMatches
+}
+
+func benchWorkloadUpdate(n, numApps int) api.Update {
+	return api.Update{
+		KVPair: model.KVPair{
+			Key: model.WorkloadEndpointKey{
+				Hostname:       "host",
+				OrchestratorID: "k8s",
+				WorkloadID:     fmt.Sprintf("wep-%d", n),
+				EndpointID:     "eth0",
+			},
+			Value: &model.WorkloadEndpoint{
+				Labels: map[string]string{
+					"app":  fmt.Sprintf("app-%d", n%numApps),
+					"name": fmt.Sprintf("wep-%d", n),
+				},
+				ProfileIDs: []string{"default"},
+			},
+		},
+	}
+}
+
+func mustParseBenchSelector(s string) selector.Selector {
+	sel, err := selector.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return sel
+}
@@ -202,4 +202,91 @@ var _ = Describe("Index", func() {
 			}))
 		})
 	})
+
+	Context("with a mix of indexable and unindexable selectors", func() {
+		mustParse := func(s string) selector.Selector {
+			sel, err := selector.Parse(s)
+			Expect(err).NotTo(HaveOccurred())
+			return sel
+		}
+
+		BeforeEach(func() {
+			idx.UpdateSelector("or", mustParse(`a == "a1" || has(tag1)`))
+			idx.UpdateSelector("and", mustParse(`a == "a1" && c != "d"`))
+			idx.UpdateSelector("not", mustParse(`!has(a)`))
+			idx.UpdateSelector("all", mustParse(`all()`))
+			idx.UpdateSelector("in", mustParse(`b in {"b1", "b2"}`))
+		})
+
+		It("should match the item's own labels", func() {
+			idx.UpdateLabels("l1", map[string]string{"a": "a1"}, nil)
+			Expect(updates).To(ConsistOf(
+				update{"start", "l1", "or"},
+				update{"start", "l1", "and"},
+				update{"start", "l1", "all"},
+			))
+			updates = updates[:0]
+
+			idx.UpdateLabels("l1", map[string]string{"a": "a1", "c": "d"}, nil)
+			Expect(updates).To(Equal([]update{{"stop", "l1", "and"}}))
+			updates = updates[:0]
+
+			idx.UpdateLabels("l1", map[string]string{"b": "b2"}, nil)
+			Expect(updates).To(ConsistOf(
+				update{"stop", "l1", "or"},
+				update{"start", "l1", "not"},
+				update{"start", "l1", "in"},
+			))
+		})
+
+		It("should match inherited labels and tags", func() {
+			idx.UpdateLabels("l1", nil, []string{"prof1"})
+			Expect(updates).To(ConsistOf(
+				update{"start", "l1", "not"},
+				update{"start", "l1", "all"},
+			))
+			updates = updates[:0]
+
+			By("matching a parent tag")
+			idx.UpdateParentTags("prof1", []string{"tag1"})
+			Expect(updates).To(Equal([]update{{"start", "l1", "or"}}))
+			updates = updates[:0]
+
+			By("matching a parent label")
+			idx.UpdateParentLabels("prof1", map[string]string{"a": "a1"})
+			Expect(updates).To(ConsistOf(
+				update{"start", "l1", "and"},
+				update{"stop", "l1", "not"},
+			))
+			updates = updates[:0]
+
+			By("matching parent labels when a selector is added")
+			idx.UpdateSelector("new", mustParse(`has(tag1) && a == "a1"`))
+			Expect(updates).To(Equal([]update{{"start", "l1", "new"}}))
+			updates = updates[:0]
+
+			By("letting the item's own labels override the parent's")
+			idx.UpdateLabels("l1", map[string]string{"a": "a2"}, []string{"prof1"})
+			Expect(updates).To(ConsistOf(
+				update{"stop", "l1", "and"},
+				update{"stop", "l1", "new"},
+			))
+			updates = updates[:0]
+
+			By("removing the parent tags")
+			idx.DeleteParentTags("prof1")
+			Expect(updates).To(Equal([]update{{"stop", "l1", "or"}}))
+		})
+
+		It("should keep a parent's empty label when a tag with the same name is removed", func() {
+			idx.UpdateParentLabels("prof1", map[string]string{"tag1": ""})
+			idx.UpdateParentTags("prof1", []string{"tag1"})
+			idx.UpdateParentTags("prof1", nil)
+			idx.UpdateLabels("l1", nil, []string{"prof1"})
+			updates = updates[:0]
+
+			idx.UpdateSelector("has-tag", mustParse(`has(tag1)`))
+			Expect(updates).To(Equal([]update{{"start", "l1", "has-tag"}}))
+		})
+	})
 })
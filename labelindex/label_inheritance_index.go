@@ -45,6 +45,15 @@
 //         "d": "prof-d",  // Profile label "wins" over profile tag with same name.
 //         "tag-x": "",    // Profile tag inherited as empty label.
 //     }
+//
+// Indexed evaluation
+//
+// Rather than evaluating every selector against every item, the InheritIndex keeps inverted
+// indexes that map label names and values to the items/parents that have them, and to the
+// selectors that require them.  When a selector or item changes, only the candidates from those
+// indexes (plus any existing matches, which may need to stop) are evaluated.  Selectors that can
+// match items without a particular label, such as "all()" or "!has(a)", can't be indexed and are
+// still evaluated against every item.
 package labelindex
 
 import (
@@ -108,6 +117,17 @@ type InheritIndex struct {
 	selIdsByLabelId map[interface{}]set.Set
 	labelIdsBySelId map[interface{}]set.Set
 
+	// Inverted indexes, used to narrow down the selectors that need to be evaluated against an
+	// item and vice versa.  Items are indexed by their own labels and parents by their labels
+	// and (separately, since a parent may have both a tag and an empty label with the same name)
+	// their tags.  Selectors are indexed by their labelRestrictions.
+	itemIDsByLabel         *labelValueIndex
+	parentIDsByLabel       *labelValueIndex
+	parentIDsByTag         *labelValueIndex
+	selIDsByRestriction    *labelValueIndex
+	restrictionsBySelID    map[interface{}][]labelRestriction
+	unindexableSelectorIDs set.Set
+
 	// Callback functions
 	OnMatchStarted MatchCallback
 	OnMatchStopped MatchCallback
@@ -125,6 +145,13 @@ func NewInheritIndex(onMatchStarted, onMatchStopped MatchCallback) *InheritIndex
 		selIdsByLabelId: map[interface{}]set.Set{},
 		labelIdsBySelId: map[interface{}]set.Set{},
 
+		itemIDsByLabel:         newLabelValueIndex(),
+		parentIDsByLabel:       newLabelValueIndex(),
+		parentIDsByTag:         newLabelValueIndex(),
+		selIDsByRestriction:    newLabelValueIndex(),
+		restrictionsBySelID:    map[interface{}][]labelRestriction{},
+		unindexableSelectorIDs: set.New(),
+
 		// Callback functions
 		OnMatchStarted: onMatchStarted,
 		OnMatchStopped: onMatchStopped,
@@ -193,10 +220,33 @@ func (idx *InheritIndex) UpdateSelector(id interface{}, sel selector.Selector) {
 		return
 	}
 	log.WithField("selID", id).Info("Updating selector")
-	idx.scanAllLabels(id, sel)
+	idx.unindexSelector(id)
+	idx.indexSelector(id, sel)
+	idx.scanCandidateLabels(id, sel)
 	idx.selectorsById[id] = sel
 }
 
+func (idx *InheritIndex) indexSelector(id interface{}, sel selector.Selector) {
+	restrictions, ok := selectorLabelRestrictions(sel)
+	if !ok {
+		log.WithField("selID", id).Debug("Selector can't be indexed, it will be evaluated against all labels")
+		idx.unindexableSelectorIDs.Add(id)
+		return
+	}
+	for _, r := range restrictions {
+		idx.selIDsByRestriction.add(r, id)
+	}
+	idx.restrictionsBySelID[id] = restrictions
+}
+
+func (idx *InheritIndex) unindexSelector(id interface{}) {
+	idx.unindexableSelectorIDs.Discard(id)
+	for _, r := range idx.restrictionsBySelID[id] {
+		idx.selIDsByRestriction.discard(r, id)
+	}
+	delete(idx.restrictionsBySelID, id)
+}
+
 func (idx *InheritIndex) DeleteSelector(id interface{}) {
 	log.Infof("Deleting selector %v", id)
 	matchSet := idx.labelIdsBySelId[id]
@@ -207,6 +257,7 @@ func (idx *InheritIndex) DeleteSelector(id interface{}) {
 			return nil
 		})
 	}
+	idx.unindexSelector(id)
 	delete(idx.selectorsById, id)
 }
 
@@ -224,6 +275,7 @@ func (idx *InheritIndex) UpdateLabels(id interface{}, labels map[string]string,
 			log.Debug("No change to labels or parentIDs, ignoring.")
 			return
 		}
+		idx.itemIDsByLabel.discardLabels(oldLabels, id)
 	}
 	newItemData := &itemData{}
 	if len(labels) > 0 {
@@ -237,6 +289,7 @@ func (idx *InheritIndex) UpdateLabels(id interface{}, labels map[string]string,
 		newItemData.parents = parents
 	}
 	idx.itemDataByID[id] = newItemData
+	idx.itemIDsByLabel.addLabels(newItemData.labels, id)
 
 	idx.onItemParentsUpdate(id, oldParents, newItemData.parents)
 
@@ -251,6 +304,7 @@ func (idx *InheritIndex) DeleteLabels(id interface{}) {
 	var oldParents []*parentData
 	if oldItemData != nil {
 		oldParents = oldItemData.parents
+		idx.itemIDsByLabel.discardLabels(oldItemData.labels, id)
 	}
 	delete(idx.itemDataByID, id)
 	idx.onItemParentsUpdate(id, oldParents, nil)
@@ -315,7 +369,9 @@ func (idx *InheritIndex) onItemParentsUpdate(id interface{}, oldParents, newPare
 
 func (idx *InheritIndex) UpdateParentLabels(parentID string, labels map[string]string) {
 	parent := idx.getOrCreateParent(parentID)
+	idx.parentIDsByLabel.discardLabels(parent.labels, parentID)
 	parent.labels = labels
+	idx.parentIDsByLabel.addLabels(parent.labels, parentID)
 	idx.flushChildren(parentID)
 }
 
@@ -324,6 +380,7 @@ func (idx *InheritIndex) DeleteParentLabels(parentID string) {
 	if parent == nil {
 		return
 	}
+	idx.parentIDsByLabel.discardLabels(parent.labels, parentID)
 	parent.labels = nil
 	idx.discardParentIfEmpty(parentID)
 	idx.flushChildren(parentID)
@@ -331,7 +388,9 @@ func (idx *InheritIndex) DeleteParentLabels(parentID string) {
 
 func (idx *InheritIndex) UpdateParentTags(parentID string, tags []string) {
 	parent := idx.getOrCreateParent(parentID)
+	idx.parentIDsByTag.discardLabels(tagsToLabels(parent.tags), parentID)
 	parent.tags = tags
+	idx.parentIDsByTag.addLabels(tagsToLabels(parent.tags), parentID)
 	idx.flushChildren(parentID)
 }
 
@@ -340,6 +399,7 @@ func (idx *InheritIndex) DeleteParentTags(parentID string) {
 	if parentData == nil {
 		return
 	}
+	idx.parentIDsByTag.discardLabels(tagsToLabels(parentData.tags), parentID)
 	parentData.tags = nil
 	idx.discardParentIfEmpty(parentID)
 	idx.flushChildren(parentID)
@@ -375,12 +435,66 @@ func (idx *InheritIndex) flushUpdates() {
 		} else {
 			// Item updated/created, re-evaluate labels.
 			log.Debugf("Flushing update of item %v", itemID)
-			idx.scanAllSelectors(itemID)
+			idx.scanCandidateSelectors(itemID)
 		}
 		return set.RemoveItem
 	})
 }
 
+// tagsToLabels converts a parent's tags to the equivalent labels, each with an empty value.
+func tagsToLabels(tags []string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		labels[tag] = ""
+	}
+	return labels
+}
+
+// scanCandidateLabels evaluates the selector against every item that might match it: the items
+// that satisfy one of the selector's restrictions (either directly or via a parent), along with
+// the items that currently match the selector, in case they need to stop matching.
+func (idx *InheritIndex) scanCandidateLabels(selId interface{}, sel selector.Selector) {
+	if idx.unindexableSelectorIDs.Contains(selId) {
+		idx.scanAllLabels(selId, sel)
+		return
+	}
+	candidates := set.New()
+	addChildren := func(parentID interface{}) {
+		parent := idx.parentDataByParentID[parentID.(string)]
+		if parent == nil || parent.itemIDs == nil {
+			return
+		}
+		parent.itemIDs.Iter(func(itemID interface{}) error {
+			candidates.Add(itemID)
+			return nil
+		})
+	}
+	if matches := idx.labelIdsBySelId[selId]; matches != nil {
+		matches.Iter(func(labelId interface{}) error {
+			candidates.Add(labelId)
+			return nil
+		})
+	}
+	for _, r := range idx.restrictionsBySelID[selId] {
+		idx.itemIDsByLabel.iterIDsSatisfying(r, candidates.Add)
+		idx.parentIDsByLabel.iterIDsSatisfying(r, addChildren)
+		idx.parentIDsByTag.iterIDsSatisfying(r, addChildren)
+	}
+	log.Debugf("Scanning %v candidate labels (of %v) against selector %v",
+		candidates.Len(), len(idx.itemDataByID), selId)
+	candidates.Iter(func(labelId interface{}) error {
+		labels, ok := idx.itemDataByID[labelId]
+		if !ok {
+			return nil
+		}
+		idx.updateMatches(selId, sel, labelId, labels)
+		return nil
+	})
+}
+
 func (idx *InheritIndex) scanAllLabels(selId interface{}, sel selector.Selector) {
 	log.Debugf("Scanning all (%v) labels against selector %v",
 		len(idx.itemDataByID), selId)
@@ -389,13 +503,41 @@ func (idx *InheritIndex) scanAllLabels(selId interface{}, sel selector.Selector)
 	}
 }
 
-func (idx *InheritIndex) scanAllSelectors(labelId interface{}) {
-	log.Debugf("Scanning all (%v) selectors against labels %v",
-		len(idx.selectorsById), labelId)
+// scanCandidateSelectors evaluates every selector that might match the given item against it:
+// the unindexable selectors, the selectors with a restriction that is satisfied by one of the
+// item's labels (own or inherited) and the selectors that currently match the item, in case they
+// need to stop matching.
+func (idx *InheritIndex) scanCandidateSelectors(labelId interface{}) {
 	labels := idx.itemDataByID[labelId]
-	for selId, sel := range idx.selectorsById {
-		idx.updateMatches(selId, sel, labelId, labels)
+	candidates := idx.unindexableSelectorIDs.Copy()
+	if matches := idx.selIdsByLabelId[labelId]; matches != nil {
+		matches.Iter(func(selId interface{}) error {
+			candidates.Add(selId)
+			return nil
+		})
+	}
+	addCandidatesForLabels := func(labelMap map[string]string) {
+		for k, v := range labelMap {
+			idx.selIDsByRestriction.iterIDsMatchingLabel(k, v, candidates.Add)
+		}
+	}
+	addCandidatesForLabels(labels.labels)
+	for _, parent := range labels.parents {
+		addCandidatesForLabels(parent.labels)
+		for _, tag := range parent.tags {
+			idx.selIDsByRestriction.iterIDsMatchingLabel(tag, "", candidates.Add)
+		}
 	}
+	log.Debugf("Scanning %v candidate selectors (of %v) against labels %v",
+		candidates.Len(), len(idx.selectorsById), labelId)
+	candidates.Iter(func(selId interface{}) error {
+		sel, ok := idx.selectorsById[selId]
+		if !ok {
+			return nil
+		}
+		idx.updateMatches(selId, sel, labelId, labels)
+		return nil
+	})
 }
 
 func (idx *InheritIndex) updateMatches(
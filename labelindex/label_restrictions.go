@@ -0,0 +1,259 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labelindex
+
+import (
+	"github.com/projectcalico/libcalico-go/lib/selector"
+	"github.com/projectcalico/libcalico-go/lib/selector/parser"
+	"github.com/projectcalico/libcalico-go/lib/set"
+)
+
+// labelRestriction is a label that a set of labels must have in order to match a selector.  If
+// anyValue is true, the label may have any value; otherwise, it must have the given value.
+type labelRestriction struct {
+	name     string
+	value    string
+	anyValue bool
+}
+
+// selectorLabelRestrictions analyses the given selector and returns a list of restrictions such
+// that any set of labels that matches the selector satisfies at least one of the restrictions.
+// That allows the InheritIndex to skip evaluating the selector against label sets that satisfy
+// none of them.
+//
+// Returns ok=false if the selector can match label sets that satisfy no such restriction, for
+// example "all()" or "!has(a)"; such selectors must be evaluated against every set of labels.
+func selectorLabelRestrictions(sel selector.Selector) (restrictions []labelRestriction, ok bool) {
+	// The parser doesn't expose the root of the selector's AST directly but it does support
+	// visitors, which are called for every node.  Find the root by looking for the node that isn't
+	// the child of any other node so that we don't depend on the order of the traversal.
+	parsed, isParsed := sel.(parser.Selector)
+	if !isParsed {
+		// Not a selector from the parser; we can't analyse it so it has to be evaluated against
+		// every set of labels.
+		return nil, false
+	}
+	v := &nodeCollector{children: map[interface{}]bool{}}
+	parsed.AcceptVisitor(v)
+	var root interface{}
+	for _, n := range v.nodes {
+		if v.children[n] {
+			continue
+		}
+		if root != nil {
+			return nil, false
+		}
+		root = n
+	}
+	if root == nil {
+		return nil, false
+	}
+	return nodeLabelRestrictions(root)
+}
+
+type nodeCollector struct {
+	nodes    []interface{}
+	children map[interface{}]bool
+}
+
+func (v *nodeCollector) Visit(n interface{}) {
+	v.nodes = append(v.nodes, n)
+	switch n := n.(type) {
+	case *parser.AndNode:
+		for _, op := range n.Operands {
+			v.children[op] = true
+		}
+	case *parser.OrNode:
+		for _, op := range n.Operands {
+			v.children[op] = true
+		}
+	case *parser.NotNode:
+		v.children[n.Operand] = true
+	}
+}
+
+func nodeLabelRestrictions(n interface{}) ([]labelRestriction, bool) {
+	switch n := n.(type) {
+	case *parser.LabelEqValueNode:
+		return []labelRestriction{{name: n.LabelName, value: n.Value}}, true
+	case *parser.LabelInSetNode:
+		return []labelRestriction{{name: n.LabelName, anyValue: true}}, true
+	case *parser.HasNode:
+		return []labelRestriction{{name: n.LabelName, anyValue: true}}, true
+	case *parser.AndNode:
+		// A match must satisfy every operand so we're free to use the restrictions of any one
+		// of them.  Pick the most selective.
+		var best []labelRestriction
+		found := false
+		for _, op := range n.Operands {
+			r, ok := nodeLabelRestrictions(op)
+			if !ok {
+				continue
+			}
+			if !found || restrictionsCost(r) < restrictionsCost(best) {
+				best = r
+				found = true
+			}
+		}
+		return best, found
+	case *parser.OrNode:
+		// A match need only satisfy one operand so we need the restrictions of all of them.
+		var all []labelRestriction
+		for _, op := range n.Operands {
+			r, ok := nodeLabelRestrictions(op)
+			if !ok {
+				return nil, false
+			}
+			all = append(all, r...)
+		}
+		return all, true
+	}
+	// "all()", negations and any other operators can match label sets that don't have a
+	// particular label.
+	return nil, false
+}
+
+// restrictionsCost gives a rough measure of how many label sets are likely to satisfy the given
+// restrictions.  Restrictions on the label's value are assumed to be much more selective than
+// those that only require the label to be present.
+func restrictionsCost(restrictions []labelRestriction) int {
+	cost := 0
+	for _, r := range restrictions {
+		if r.anyValue {
+			cost += 10
+		} else {
+			cost++
+		}
+	}
+	return cost
+}
+
+// labelValueIndex is an inverted index from label name and value to IDs.  It's used both to index
+// items/parents by the labels they have and to index selectors by their labelRestrictions.
+type labelValueIndex struct {
+	idsByValueByName  map[string]map[string]set.Set
+	anyValueIDsByName map[string]set.Set
+}
+
+func newLabelValueIndex() *labelValueIndex {
+	return &labelValueIndex{
+		idsByValueByName:  map[string]map[string]set.Set{},
+		anyValueIDsByName: map[string]set.Set{},
+	}
+}
+
+func (i *labelValueIndex) add(r labelRestriction, id interface{}) {
+	if r.anyValue {
+		ids := i.anyValueIDsByName[r.name]
+		if ids == nil {
+			ids = set.New()
+			i.anyValueIDsByName[r.name] = ids
+		}
+		ids.Add(id)
+		return
+	}
+	idsByValue := i.idsByValueByName[r.name]
+	if idsByValue == nil {
+		idsByValue = map[string]set.Set{}
+		i.idsByValueByName[r.name] = idsByValue
+	}
+	ids := idsByValue[r.value]
+	if ids == nil {
+		ids = set.New()
+		idsByValue[r.value] = ids
+	}
+	ids.Add(id)
+}
+
+func (i *labelValueIndex) discard(r labelRestriction, id interface{}) {
+	if r.anyValue {
+		ids := i.anyValueIDsByName[r.name]
+		if ids == nil {
+			return
+		}
+		ids.Discard(id)
+		if ids.Len() == 0 {
+			delete(i.anyValueIDsByName, r.name)
+		}
+		return
+	}
+	idsByValue := i.idsByValueByName[r.name]
+	if idsByValue == nil {
+		return
+	}
+	ids := idsByValue[r.value]
+	if ids == nil {
+		return
+	}
+	ids.Discard(id)
+	if ids.Len() == 0 {
+		delete(idsByValue, r.value)
+		if len(idsByValue) == 0 {
+			delete(i.idsByValueByName, r.name)
+		}
+	}
+}
+
+// addLabels adds the ID under each of the given labels.
+func (i *labelValueIndex) addLabels(labels map[string]string, id interface{}) {
+	for k, v := range labels {
+		i.add(labelRestriction{name: k, value: v}, id)
+	}
+}
+
+// discardLabels removes the ID from under each of the given labels.
+func (i *labelValueIndex) discardLabels(labels map[string]string, id interface{}) {
+	for k, v := range labels {
+		i.discard(labelRestriction{name: k, value: v}, id)
+	}
+}
+
+// iterIDsMatchingLabel calls f with the IDs that were added with a restriction that is satisfied
+// by the given label.
+func (i *labelValueIndex) iterIDsMatchingLabel(name, value string, f func(id interface{})) {
+	if ids := i.idsByValueByName[name][value]; ids != nil {
+		ids.Iter(func(id interface{}) error {
+			f(id)
+			return nil
+		})
+	}
+	if ids := i.anyValueIDsByName[name]; ids != nil {
+		ids.Iter(func(id interface{}) error {
+			f(id)
+			return nil
+		})
+	}
+}
+
+// iterIDsSatisfying calls f with the IDs that were added with a label (or restriction) that
+// satisfies the given restriction.
+func (i *labelValueIndex) iterIDsSatisfying(r labelRestriction, f func(id interface{})) {
+	visit := func(id interface{}) error {
+		f(id)
+		return nil
+	}
+	if !r.anyValue {
+		if ids := i.idsByValueByName[r.name][r.value]; ids != nil {
+			ids.Iter(visit)
+		}
+		return
+	}
+	for _, ids := range i.idsByValueByName[r.name] {
+		ids.Iter(visit)
+	}
+	if ids := i.anyValueIDsByName[r.name]; ids != nil {
+		ids.Iter(visit)
+	}
+}
@@ -433,6 +433,45 @@ func (idx *SelectorAndNamedPortIndex) DeleteIPSet(id string) {
 	delete(idx.ipSetDataByID, id)
 }
 
+// IPSetContains checks whether the given address (and, for named port IP sets, protocol and port)
+// is a member of the IP set with the given ID.  known is false if the IP set isn't active.  The
+// check is a linear scan over the IP set's members so it is only suitable for diagnostics.
+func (idx *SelectorAndNamedPortIndex) IPSetContains(
+	ipSetID string,
+	addr ip.Addr,
+	proto IPSetPortProtocol,
+	port uint16,
+) (contains, known bool) {
+	ipSetData := idx.ipSetDataByID[ipSetID]
+	if ipSetData == nil {
+		return false, false
+	}
+	netIP := addr.AsNetIP()
+	for member := range ipSetData.memberToRefCount {
+		if member.CIDR.Version() != addr.Version() {
+			continue
+		}
+		if ipSetData.namedPortProtocol != ProtocolNone &&
+			(member.Protocol != proto || member.PortNumber != port) {
+			continue
+		}
+		ipNet := member.CIDR.ToIPNet()
+		if ipNet.Contains(netIP) {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// IPSetMemberCounts returns the number of members of each active IP set, indexed by IP set ID.
+func (idx *SelectorAndNamedPortIndex) IPSetMemberCounts() map[string]int {
+	counts := make(map[string]int, len(idx.ipSetDataByID))
+	for id, ipSetData := range idx.ipSetDataByID {
+		counts[id] = len(ipSetData.memberToRefCount)
+	}
+	return counts
+}
+
 func (idx *SelectorAndNamedPortIndex) UpdateEndpointOrSet(
 	id interface{},
 	labels map[string]string,
@@ -22,6 +22,7 @@ import (
 
 	"net"
 
+	"github.com/projectcalico/felix/ip"
 	"github.com/projectcalico/libcalico-go/lib/backend/api"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
 	calinet "github.com/projectcalico/libcalico-go/lib/net"
@@ -109,6 +110,46 @@ var _ = Describe("SelectorAndNamedPortIndex", func() {
 			Expect(set).To(HaveLen(1))
 		})
 	})
+
+	Describe("IP set queries", func() {
+		BeforeEach(func() {
+			uut.OnUpdate(api.Update{
+				KVPair: model.KVPair{
+					Key: model.NetworkSetKey{Name: "blinky"},
+					Value: &model.NetworkSet{
+						Nets: []calinet.IPNet{
+							{IPNet: net.IPNet{
+								IP:   net.IP{192, 168, 0, 0},
+								Mask: net.IPMask{255, 255, 0, 0},
+							}},
+						},
+						Labels: map[string]string{"villain": "ghost"},
+					},
+				},
+			})
+			s, err := selector.Parse("villain == 'ghost'")
+			Expect(err).ToNot(HaveOccurred())
+			uut.UpdateIPSet("villains", s, ProtocolNone, "")
+		})
+
+		It("should match addresses inside the CIDR", func() {
+			contains, known := uut.IPSetContains("villains", ip.FromString("192.168.4.10"), ProtocolTCP, 80)
+			Expect(known).To(BeTrue())
+			Expect(contains).To(BeTrue())
+		})
+		It("should not match addresses outside the CIDR", func() {
+			contains, known := uut.IPSetContains("villains", ip.FromString("10.0.0.1"), ProtocolTCP, 80)
+			Expect(known).To(BeTrue())
+			Expect(contains).To(BeFalse())
+		})
+		It("should count the members", func() {
+			Expect(uut.IPSetMemberCounts()).To(Equal(map[string]int{"villains": 1}))
+		})
+		It("should report unknown IP sets", func() {
+			_, known := uut.IPSetContains("heroes", ip.FromString("192.168.4.10"), ProtocolTCP, 80)
+			Expect(known).To(BeFalse())
+		})
+	})
 })
 
 type testRecorder struct {
@@ -31,6 +31,7 @@ import (
 	"golang.org/x/sys/unix"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 
+	"github.com/projectcalico/felix/conntrack"
 	"github.com/projectcalico/felix/ip"
 	"github.com/projectcalico/felix/netlinkshim"
 	"github.com/projectcalico/libcalico-go/lib/set"
@@ -38,8 +39,10 @@ import (
 
 func New() *MockNetlinkDataplane {
 	dp := &MockNetlinkDataplane{
-		NameToLink:      map[string]*MockLink{},
-		RouteKeyToRoute: map[string]netlink.Route{},
+		NameToLink:         map[string]*MockLink{},
+		RouteKeyToRoute:    map[string]netlink.Route{},
+		LinkIndexToQdiscs:  map[int]map[uint32]netlink.Qdisc{},
+		LinkIndexToFilters: map[int][]netlink.Filter{},
 		Rules: []netlink.Rule{
 			{
 				Priority: 0,
@@ -98,6 +101,12 @@ const (
 	FailNextWireguardClose
 	FailNextWireguardDeviceByName
 	FailNextWireguardConfigureDevice
+	FailNextQdiscList
+	FailNextQdiscReplace
+	FailNextQdiscDel
+	FailNextFilterList
+	FailNextFilterAdd
+	FailNextRouteGet
 	FailNone FailFlags = 0
 )
 
@@ -191,6 +200,24 @@ func (f FailFlags) String() string {
 	if f&FailNextWireguardConfigureDevice != 0 {
 		parts = append(parts, "FailNextWireguardConfigureDevice")
 	}
+	if f&FailNextQdiscList != 0 {
+		parts = append(parts, "FailNextQdiscList")
+	}
+	if f&FailNextQdiscReplace != 0 {
+		parts = append(parts, "FailNextQdiscReplace")
+	}
+	if f&FailNextQdiscDel != 0 {
+		parts = append(parts, "FailNextQdiscDel")
+	}
+	if f&FailNextFilterList != 0 {
+		parts = append(parts, "FailNextFilterList")
+	}
+	if f&FailNextFilterAdd != 0 {
+		parts = append(parts, "FailNextFilterAdd")
+	}
+	if f&FailNextRouteGet != 0 {
+		parts = append(parts, "FailNextRouteGet")
+	}
 	if f == 0 {
 		parts = append(parts, "FailNone")
 	}
@@ -213,6 +240,11 @@ type MockNetlinkDataplane struct {
 	DeletedRouteKeys set.Set
 	UpdatedRouteKeys set.Set
 
+	// Traffic control state, keyed on link index and then on the parent handle of the qdisc.  Deleting a qdisc
+	// deletes its filters.
+	LinkIndexToQdiscs  map[int]map[uint32]netlink.Qdisc
+	LinkIndexToFilters map[int][]netlink.Filter
+
 	NumNewNetlinkCalls     int
 	NetlinkOpen            bool
 	NumNewWireguardCalls   int
@@ -235,6 +267,7 @@ type MockNetlinkDataplane struct {
 
 	mutex                   sync.Mutex
 	deletedConntrackEntries set.Set
+	numConntrackBatches     int
 	ConntrackSleep          time.Duration
 }
 
@@ -258,6 +291,7 @@ func (d *MockNetlinkDataplane) ResetDeltas() {
 	d.DeletedRules = nil
 	d.WireguardConfigUpdated = false
 	d.deletedConntrackEntries = set.New()
+	d.numConntrackBatches = 0
 }
 
 // ----- Mock dataplane management functions for test code -----
@@ -275,6 +309,14 @@ func (d *MockNetlinkDataplane) GetDeletedConntrackEntries() []net.IP {
 	return cpy
 }
 
+// GetNumConntrackDeletionBatches returns the number of calls made to delete conntrack entries.
+func (d *MockNetlinkDataplane) GetNumConntrackDeletionBatches() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.numConntrackBatches
+}
+
 func (d *MockNetlinkDataplane) AddIface(idx int, name string, up bool, running bool) *MockLink {
 	t := "unknown"
 	if strings.Contains(name, "wireguard") {
@@ -424,6 +466,8 @@ func (d *MockNetlinkDataplane) LinkDel(link netlink.Link) error {
 		return NotFoundError
 	}
 
+	delete(d.LinkIndexToQdiscs, d.NameToLink[link.Attrs().Name].LinkAttrs.Index)
+	delete(d.LinkIndexToFilters, d.NameToLink[link.Attrs().Name].LinkAttrs.Index)
 	delete(d.NameToLink, link.Attrs().Name)
 	d.DeletedLinks.Add(link.Attrs().Name)
 	return nil
@@ -476,7 +520,14 @@ func (d *MockNetlinkDataplane) AddrList(link netlink.Link, family int) ([]netlin
 		return nil, SimulatedError
 	}
 	if link, ok := d.NameToLink[link.Attrs().Name]; ok {
-		return link.Addrs, nil
+		var addrs []netlink.Addr
+		for _, addr := range link.Addrs {
+			if addr.IPNet != nil && !familyMatches(family, addr.IP) {
+				continue
+			}
+			addrs = append(addrs, addr)
+		}
+		return addrs, nil
 	}
 	return nil, NotFoundError
 }
@@ -546,7 +597,14 @@ func (d *MockNetlinkDataplane) RuleList(family int) ([]netlink.Rule, error) {
 		return nil, SimulatedError
 	}
 
-	return d.Rules, nil
+	var rules []netlink.Rule
+	for _, rule := range d.Rules {
+		if family != netlink.FAMILY_ALL && rule.Family != 0 && rule.Family != family {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
 }
 
 func (d *MockNetlinkDataplane) RuleAdd(rule *netlink.Rule) error {
@@ -602,6 +660,100 @@ func (d *MockNetlinkDataplane) RuleDel(rule *netlink.Rule) error {
 	return nil
 }
 
+func (d *MockNetlinkDataplane) QdiscList(link netlink.Link) ([]netlink.Qdisc, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer GinkgoRecover()
+
+	Expect(d.NetlinkOpen).To(BeTrue())
+	if d.shouldFail(FailNextQdiscList) {
+		return nil, SimulatedError
+	}
+	var qdiscs []netlink.Qdisc
+	for _, qdisc := range d.LinkIndexToQdiscs[link.Attrs().Index] {
+		qdiscs = append(qdiscs, qdisc)
+	}
+	return qdiscs, nil
+}
+
+func (d *MockNetlinkDataplane) QdiscReplace(qdisc netlink.Qdisc) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer GinkgoRecover()
+
+	Expect(d.NetlinkOpen).To(BeTrue())
+	if d.shouldFail(FailNextQdiscReplace) {
+		return SimulatedError
+	}
+	attrs := qdisc.Attrs()
+	if d.LinkIndexToQdiscs[attrs.LinkIndex] == nil {
+		d.LinkIndexToQdiscs[attrs.LinkIndex] = map[uint32]netlink.Qdisc{}
+	}
+	d.LinkIndexToQdiscs[attrs.LinkIndex][attrs.Parent] = qdisc
+	return nil
+}
+
+func (d *MockNetlinkDataplane) QdiscDel(qdisc netlink.Qdisc) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer GinkgoRecover()
+
+	Expect(d.NetlinkOpen).To(BeTrue())
+	if d.shouldFail(FailNextQdiscDel) {
+		return SimulatedError
+	}
+	attrs := qdisc.Attrs()
+	if _, ok := d.LinkIndexToQdiscs[attrs.LinkIndex][attrs.Parent]; !ok {
+		return NotFoundError
+	}
+	delete(d.LinkIndexToQdiscs[attrs.LinkIndex], attrs.Parent)
+	var filters []netlink.Filter
+	for _, filter := range d.LinkIndexToFilters[attrs.LinkIndex] {
+		if filter.Attrs().Parent != attrs.Handle {
+			filters = append(filters, filter)
+		}
+	}
+	d.LinkIndexToFilters[attrs.LinkIndex] = filters
+	return nil
+}
+
+func (d *MockNetlinkDataplane) FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer GinkgoRecover()
+
+	Expect(d.NetlinkOpen).To(BeTrue())
+	if d.shouldFail(FailNextFilterList) {
+		return nil, SimulatedError
+	}
+	var filters []netlink.Filter
+	for _, filter := range d.LinkIndexToFilters[link.Attrs().Index] {
+		if filter.Attrs().Parent == parent {
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+func (d *MockNetlinkDataplane) FilterAdd(filter netlink.Filter) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer GinkgoRecover()
+
+	Expect(d.NetlinkOpen).To(BeTrue())
+	if d.shouldFail(FailNextFilterAdd) {
+		return SimulatedError
+	}
+	attrs := filter.Attrs()
+	for _, qdisc := range d.LinkIndexToQdiscs[attrs.LinkIndex] {
+		if qdisc.Attrs().Handle == attrs.Parent {
+			d.LinkIndexToFilters[attrs.LinkIndex] = append(d.LinkIndexToFilters[attrs.LinkIndex], filter)
+			return nil
+		}
+	}
+	return NotFoundError
+}
+
 func (d *MockNetlinkDataplane) RouteListFiltered(family int, filter *netlink.Route, filterMask uint64) ([]netlink.Route, error) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
@@ -633,11 +785,59 @@ func (d *MockNetlinkDataplane) RouteListFiltered(family int, filter *netlink.Rou
 			log.Debugf("Does not match table %d", filter.Table)
 			continue
 		}
+		if route.Dst != nil && !familyMatches(family, route.Dst.IP) {
+			log.Debug("Does not match family")
+			continue
+		}
 		routes = append(routes, route)
 	}
 	return routes, nil
 }
 
+// RouteGet mimics a kernel route lookup by returning the longest prefix match for the destination
+// in the main table.
+func (d *MockNetlinkDataplane) RouteGet(destination net.IP) ([]netlink.Route, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer GinkgoRecover()
+
+	Expect(d.NetlinkOpen).To(BeTrue())
+	if d.shouldFail(FailNextRouteGet) {
+		return nil, SimulatedError
+	}
+	var best *netlink.Route
+	bestLen := -1
+	for _, route := range d.RouteKeyToRoute {
+		if route.Table != 0 && route.Table != unix.RT_TABLE_MAIN {
+			continue
+		}
+		if route.Type == unix.RTN_THROW || route.Type == unix.RTN_BLACKHOLE {
+			continue
+		}
+		prefixLen := 0
+		if route.Dst != nil {
+			if !route.Dst.Contains(destination) {
+				continue
+			}
+			prefixLen, _ = route.Dst.Mask.Size()
+		}
+		if prefixLen > bestLen {
+			r := route
+			r.Table = unix.RT_TABLE_MAIN
+			best = &r
+			bestLen = prefixLen
+		}
+	}
+	if best == nil {
+		return nil, unix.ENETUNREACH
+	}
+	if v4 := destination.To4(); v4 != nil {
+		destination = v4
+	}
+	best.Dst = &net.IPNet{IP: destination, Mask: net.CIDRMask(len(destination)*8, len(destination)*8)}
+	return []netlink.Route{*best}, nil
+}
+
 func (d *MockNetlinkDataplane) AddMockRoute(route *netlink.Route) {
 	key := KeyForRoute(route)
 	r := *route
@@ -723,16 +923,20 @@ func (d *MockNetlinkDataplane) HasStaticArpEntry(cidr ip.CIDR, destMAC net.Hardw
 	return d.addedArpEntries.Contains(getArpKey(cidr, destMAC, ifaceName))
 }
 
-func (d *MockNetlinkDataplane) RemoveConntrackFlows(ipVersion uint8, ipAddr net.IP) {
+func (d *MockNetlinkDataplane) RemoveConntrackFlowsForIPs(ipVersion uint8, ipAddrs []net.IP, filter conntrack.FlowFilter) (int, error) {
 	log.WithFields(log.Fields{
 		"ipVersion": ipVersion,
-		"ipAddr":    ipAddr,
+		"ipAddrs":   ipAddrs,
 		"sleepTime": d.ConntrackSleep,
 	}).Info("Mock dataplane: Removing conntrack flows")
 	d.mutex.Lock()
-	d.deletedConntrackEntries.Add(ip.FromNetIP(ipAddr))
+	for _, ipAddr := range ipAddrs {
+		d.deletedConntrackEntries.Add(ip.FromNetIP(ipAddr))
+	}
+	d.numConntrackBatches++
 	d.mutex.Unlock()
 	time.Sleep(d.ConntrackSleep)
+	return 0, nil
 }
 
 // ----- Internals -----
@@ -748,6 +952,17 @@ func (d *MockNetlinkDataplane) shouldFail(flag FailFlags) bool {
 	return flagPresent
 }
 
+// familyMatches returns true if the netlink family is FAMILY_ALL or matches the IP's family.
+func familyMatches(family int, ip net.IP) bool {
+	switch family {
+	case netlink.FAMILY_V4:
+		return ip.To4() != nil
+	case netlink.FAMILY_V6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
 func KeyForRoute(route *netlink.Route) string {
 	table := route.Table
 	if table == 0 {
@@ -15,6 +15,7 @@
 package netlinkshim
 
 import (
+	"net"
 	"syscall"
 	"time"
 
@@ -32,12 +33,18 @@ type Interface interface {
 	RouteListFiltered(family int, filter *netlink.Route, filterMask uint64) ([]netlink.Route, error)
 	RouteAdd(route *netlink.Route) error
 	RouteDel(route *netlink.Route) error
+	RouteGet(destination net.IP) ([]netlink.Route, error)
 	AddrList(link netlink.Link, family int) ([]netlink.Addr, error)
 	AddrAdd(link netlink.Link, addr *netlink.Addr) error
 	AddrDel(link netlink.Link, addr *netlink.Addr) error
 	RuleList(family int) ([]netlink.Rule, error)
 	RuleAdd(rule *netlink.Rule) error
 	RuleDel(rule *netlink.Rule) error
+	QdiscList(link netlink.Link) ([]netlink.Qdisc, error)
+	QdiscReplace(qdisc netlink.Qdisc) error
+	QdiscDel(qdisc netlink.Qdisc) error
+	FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error)
+	FilterAdd(filter netlink.Filter) error
 	Delete()
 }
 
@@ -0,0 +1,181 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nfnetlink
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	sizeofAttrHdr = 4
+
+	// AttrNested is set in the type of an attribute that contains further attributes.
+	AttrNested = 0x8000
+	// AttrNetByteOrder is set in the type of an attribute whose value is in network byte order.
+	AttrNetByteOrder = 0x4000
+	attrTypeMask     = ^uint16(AttrNested | AttrNetByteOrder)
+)
+
+// AttrEncoder builds up a sequence of netlink attributes.  The zero value is ready to use.
+// Integer values in nfnetlink messages are conventionally big-endian, hence the BE suffixes.
+type AttrEncoder struct {
+	buf []byte
+}
+
+// Bytes appends an attribute with the given raw value.
+func (e *AttrEncoder) Bytes(typ uint16, value []byte) {
+	l := sizeofAttrHdr + len(value)
+	hdr := make([]byte, sizeofAttrHdr)
+	binary.LittleEndian.PutUint16(hdr[0:2], uint16(l))
+	binary.LittleEndian.PutUint16(hdr[2:4], typ)
+	e.buf = append(e.buf, hdr...)
+	e.buf = append(e.buf, value...)
+	e.pad()
+}
+
+// String appends a NUL-terminated string attribute.
+func (e *AttrEncoder) String(typ uint16, s string) {
+	e.Bytes(typ, append([]byte(s), 0))
+}
+
+// Uint8 appends a single-byte attribute.
+func (e *AttrEncoder) Uint8(typ uint16, v uint8) {
+	e.Bytes(typ, []byte{v})
+}
+
+// Uint16BE appends a big-endian 16-bit attribute.
+func (e *AttrEncoder) Uint16BE(typ uint16, v uint16) {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	e.Bytes(typ, b)
+}
+
+// Uint32BE appends a big-endian 32-bit attribute.
+func (e *AttrEncoder) Uint32BE(typ uint16, v uint32) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	e.Bytes(typ, b)
+}
+
+// Uint64BE appends a big-endian 64-bit attribute.
+func (e *AttrEncoder) Uint64BE(typ uint16, v uint64) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	e.Bytes(typ, b)
+}
+
+// Nested appends a nested attribute, whose content is filled in by the given function.
+func (e *AttrEncoder) Nested(typ uint16, fill func(e *AttrEncoder)) {
+	var inner AttrEncoder
+	fill(&inner)
+	e.Bytes(typ|AttrNested, inner.buf)
+}
+
+// Encode returns the encoded attributes.
+func (e *AttrEncoder) Encode() []byte {
+	return e.buf
+}
+
+func (e *AttrEncoder) pad() {
+	for len(e.buf)%4 != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+// Attr is a decoded netlink attribute.  The Type has the nested/byte order flags masked off.
+type Attr struct {
+	Type  uint16
+	Value []byte
+}
+
+// ParseAttrs decodes a sequence of attributes.
+func ParseAttrs(b []byte) ([]Attr, error) {
+	var attrs []Attr
+	for len(b) >= sizeofAttrHdr {
+		l := int(binary.LittleEndian.Uint16(b[0:2]))
+		typ := binary.LittleEndian.Uint16(b[2:4])
+		if l < sizeofAttrHdr || l > len(b) {
+			return nil, fmt.Errorf("malformed netlink attribute (length %d, buffer %d)", l, len(b))
+		}
+		attrs = append(attrs, Attr{
+			Type:  typ & attrTypeMask,
+			Value: b[sizeofAttrHdr:l],
+		})
+		if align(l) >= len(b) {
+			break
+		}
+		b = b[align(l):]
+	}
+	return attrs, nil
+}
+
+// ParseAttrMap decodes a sequence of attributes into a map, keyed on attribute type.  If an
+// attribute type is repeated, the last value wins; use ParseAttrs for lists.
+func ParseAttrMap(b []byte) (map[uint16][]byte, error) {
+	attrs, err := ParseAttrs(b)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[uint16][]byte, len(attrs))
+	for _, a := range attrs {
+		m[a.Type] = a.Value
+	}
+	return m, nil
+}
+
+// String decodes a (possibly NUL-terminated) string value.
+func (a Attr) String() string {
+	return AttrString(a.Value)
+}
+
+// Uint32BE decodes a big-endian 32-bit value.
+func (a Attr) Uint32BE() uint32 {
+	return AttrUint32BE(a.Value)
+}
+
+// AttrString decodes a (possibly NUL-terminated) string attribute value.
+func AttrString(v []byte) string {
+	for i, c := range v {
+		if c == 0 {
+			return string(v[:i])
+		}
+	}
+	return string(v)
+}
+
+// AttrUint16BE decodes a big-endian 16-bit attribute value, returning 0 if it's too short.
+func AttrUint16BE(v []byte) uint16 {
+	if len(v) < 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(v)
+}
+
+// AttrUint32BE decodes a big-endian 32-bit attribute value, returning 0 if it's too short.
+func AttrUint32BE(v []byte) uint32 {
+	if len(v) < 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(v)
+}
+
+// AttrUint64BE decodes a big-endian 64-bit attribute value, returning 0 if it's too short.
+func AttrUint64BE(v []byte) uint64 {
+	if len(v) < 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
@@ -0,0 +1,363 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nfnetlink
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ctnlMsgNew    = 0
+	ctnlMsgGet    = 1
+	ctnlMsgDelete = 2
+
+	// Top-level conntrack attributes.
+	ctaTupleOrig     = 1
+	ctaTupleReply    = 2
+	ctaMark          = 8
+	ctaCountersOrig  = 9
+	ctaCountersReply = 10
+	ctaID            = 12
+	ctaZone          = 18
+	ctaTimestamp     = 20
+
+	// Tuple attributes.
+	ctaTupleIP    = 1
+	ctaTupleProto = 2
+
+	ctaIPv4Src = 1
+	ctaIPv4Dst = 2
+	ctaIPv6Src = 3
+	ctaIPv6Dst = 4
+
+	ctaProtoNum        = 1
+	ctaProtoSrcPort    = 2
+	ctaProtoDstPort    = 3
+	ctaProtoICMPID     = 4
+	ctaProtoICMPType   = 5
+	ctaProtoICMPCode   = 6
+	ctaProtoICMPv6ID   = 7
+	ctaProtoICMPv6Type = 8
+	ctaProtoICMPv6Code = 9
+
+	ctaCountersPackets = 1
+	ctaCountersBytes   = 2
+
+	ctaTimestampStart = 1
+	ctaTimestampStop  = 2
+
+	// Conntrack multicast groups.
+	nfnlGroupConntrackNew     = 1
+	nfnlGroupConntrackDestroy = 3
+)
+
+// ConntrackTuple is one direction of a conntrack entry.  For ICMP and ICMPv6, the kernel tracks
+// the ICMP ID, type and code instead of the ports.
+type ConntrackTuple struct {
+	Src     net.IP
+	Dst     net.IP
+	Proto   uint8
+	SrcPort uint16
+	DstPort uint16
+
+	ICMPID   uint16
+	ICMPType uint8
+	ICMPCode uint8
+}
+
+// ConntrackCounters holds the packet and byte counts for one direction of a conntrack entry.
+// The kernel only maintains them if net.netfilter.nf_conntrack_acct is enabled.
+type ConntrackCounters struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// ConntrackFlow is a decoded conntrack entry.
+type ConntrackFlow struct {
+	ID    uint32
+	Mark  uint32
+	Zone  uint16
+	Orig  ConntrackTuple
+	Reply ConntrackTuple
+
+	OrigCounters  ConntrackCounters
+	ReplyCounters ConntrackCounters
+
+	// Start and Stop are only filled in if net.netfilter.nf_conntrack_timestamp is enabled.
+	// Stop is only set once the entry has been destroyed.
+	Start time.Time
+	Stop  time.Time
+}
+
+// ConntrackEventType is the type of a ConntrackEvent.
+type ConntrackEventType int
+
+const (
+	ConntrackEventNew ConntrackEventType = iota
+	ConntrackEventDestroy
+)
+
+// ConntrackEvent is a notification from the kernel that a conntrack entry was created or
+// destroyed.
+type ConntrackEvent struct {
+	Type ConntrackEventType
+	Flow ConntrackFlow
+}
+
+// ConntrackEventListener receives conntrack entry creation and destruction events.
+type ConntrackEventListener struct {
+	conn *Conn
+}
+
+// ListenConntrackEvents subscribes to conntrack creation and destruction events.  As for
+// ListenNflog, the socket has a short receive timeout so that Read returns periodically.
+func ListenConntrackEvents() (*ConntrackEventListener, error) {
+	groups := uint32(1<<(nfnlGroupConntrackNew-1) | 1<<(nfnlGroupConntrackDestroy-1))
+	conn, err := OpenSubscribed(time.Second, groups)
+	if err != nil {
+		return nil, err
+	}
+	return &ConntrackEventListener{conn: conn}, nil
+}
+
+// Read waits for the next batch of events.  It returns an empty batch if no events arrive within
+// the socket's timeout.
+func (l *ConntrackEventListener) Read() ([]ConntrackEvent, error) {
+	msgs, err := l.conn.receiveAsync()
+	if err != nil {
+		return nil, err
+	}
+	var events []ConntrackEvent
+	for _, m := range msgs {
+		var typ ConntrackEventType
+		switch m.Type {
+		case MsgType(SubsysCTNetlink, ctnlMsgNew):
+			typ = ConntrackEventNew
+		case MsgType(SubsysCTNetlink, ctnlMsgDelete):
+			typ = ConntrackEventDestroy
+		default:
+			continue
+		}
+		flow, err := ParseConntrackFlow(&m.Message)
+		if err != nil {
+			log.WithError(err).Warn("Failed to parse conntrack event, ignoring.")
+			continue
+		}
+		events = append(events, ConntrackEvent{Type: typ, Flow: flow})
+	}
+	return events, nil
+}
+
+// Close closes the socket.
+func (l *ConntrackEventListener) Close() error {
+	return l.conn.Close()
+}
+
+// DumpConntrack calls fn for each conntrack entry of the given family (AF_INET or AF_INET6).
+func DumpConntrack(conn *Conn, family uint8, fn func(flow *ConntrackFlow)) error {
+	return conn.DumpFunc(ConntrackDumpRequest(family), func(m *Message) error {
+		flow, err := ParseConntrackFlow(m)
+		if err != nil {
+			log.WithError(err).Warn("Failed to parse conntrack entry, ignoring.")
+			return nil
+		}
+		fn(&flow)
+		return nil
+	})
+}
+
+// ConntrackDumpRequest returns a request to dump the conntrack entries of the given family.
+func ConntrackDumpRequest(family uint8) *Message {
+	return &Message{
+		Type:   MsgType(SubsysCTNetlink, ctnlMsgGet),
+		Family: family,
+	}
+}
+
+// ConntrackDeleteRequest returns a request to delete the given conntrack entry.  The entry is
+// identified by its original tuple and zone; if the flow's ID is set, the kernel also checks it
+// so that we don't delete a newer entry that happens to have the same tuple.
+func ConntrackDeleteRequest(family uint8, flow *ConntrackFlow) *Message {
+	var e AttrEncoder
+	e.Nested(ctaTupleOrig, func(e *AttrEncoder) {
+		encodeConntrackTuple(e, &flow.Orig)
+	})
+	if flow.Zone != 0 {
+		e.Uint16BE(ctaZone, flow.Zone)
+	}
+	if flow.ID != 0 {
+		e.Uint32BE(ctaID, flow.ID)
+	}
+	return &Message{
+		Type:   MsgType(SubsysCTNetlink, ctnlMsgDelete),
+		Family: family,
+		Attrs:  e.Encode(),
+	}
+}
+
+func encodeConntrackTuple(e *AttrEncoder, t *ConntrackTuple) {
+	e.Nested(ctaTupleIP, func(e *AttrEncoder) {
+		if src4, dst4 := t.Src.To4(), t.Dst.To4(); src4 != nil && dst4 != nil {
+			e.Bytes(ctaIPv4Src, src4)
+			e.Bytes(ctaIPv4Dst, dst4)
+		} else {
+			e.Bytes(ctaIPv6Src, t.Src.To16())
+			e.Bytes(ctaIPv6Dst, t.Dst.To16())
+		}
+	})
+	e.Nested(ctaTupleProto, func(e *AttrEncoder) {
+		e.Uint8(ctaProtoNum, t.Proto)
+		switch t.Proto {
+		case unix.IPPROTO_ICMP:
+			e.Uint16BE(ctaProtoICMPID, t.ICMPID)
+			e.Uint8(ctaProtoICMPType, t.ICMPType)
+			e.Uint8(ctaProtoICMPCode, t.ICMPCode)
+		case unix.IPPROTO_ICMPV6:
+			e.Uint16BE(ctaProtoICMPv6ID, t.ICMPID)
+			e.Uint8(ctaProtoICMPv6Type, t.ICMPType)
+			e.Uint8(ctaProtoICMPv6Code, t.ICMPCode)
+		default:
+			e.Uint16BE(ctaProtoSrcPort, t.SrcPort)
+			e.Uint16BE(ctaProtoDstPort, t.DstPort)
+		}
+	})
+}
+
+// ParseConntrackFlow decodes a conntrack entry from a ctnetlink message.
+func ParseConntrackFlow(m *Message) (ConntrackFlow, error) {
+	var flow ConntrackFlow
+	attrs, err := ParseAttrs(m.Attrs)
+	if err != nil {
+		return flow, err
+	}
+	for _, a := range attrs {
+		switch a.Type {
+		case ctaTupleOrig:
+			flow.Orig, err = parseConntrackTuple(a.Value)
+		case ctaTupleReply:
+			flow.Reply, err = parseConntrackTuple(a.Value)
+		case ctaCountersOrig:
+			flow.OrigCounters, err = parseConntrackCounters(a.Value)
+		case ctaCountersReply:
+			flow.ReplyCounters, err = parseConntrackCounters(a.Value)
+		case ctaMark:
+			flow.Mark = a.Uint32BE()
+		case ctaID:
+			flow.ID = a.Uint32BE()
+		case ctaZone:
+			flow.Zone = AttrUint16BE(a.Value)
+		case ctaTimestamp:
+			err = parseConntrackTimestamp(a.Value, &flow)
+		}
+		if err != nil {
+			return flow, err
+		}
+	}
+	if flow.Orig.Src == nil {
+		return flow, errors.New("conntrack entry has no original tuple")
+	}
+	return flow, nil
+}
+
+func parseConntrackTuple(b []byte) (ConntrackTuple, error) {
+	var t ConntrackTuple
+	attrs, err := ParseAttrs(b)
+	if err != nil {
+		return t, err
+	}
+	for _, a := range attrs {
+		switch a.Type {
+		case ctaTupleIP:
+			ipAttrs, err := ParseAttrs(a.Value)
+			if err != nil {
+				return t, err
+			}
+			for _, ia := range ipAttrs {
+				switch ia.Type {
+				case ctaIPv4Src, ctaIPv6Src:
+					t.Src = net.IP(append([]byte(nil), ia.Value...))
+				case ctaIPv4Dst, ctaIPv6Dst:
+					t.Dst = net.IP(append([]byte(nil), ia.Value...))
+				}
+			}
+		case ctaTupleProto:
+			protoAttrs, err := ParseAttrs(a.Value)
+			if err != nil {
+				return t, err
+			}
+			for _, pa := range protoAttrs {
+				switch pa.Type {
+				case ctaProtoNum:
+					if len(pa.Value) > 0 {
+						t.Proto = pa.Value[0]
+					}
+				case ctaProtoSrcPort:
+					t.SrcPort = AttrUint16BE(pa.Value)
+				case ctaProtoDstPort:
+					t.DstPort = AttrUint16BE(pa.Value)
+				case ctaProtoICMPID, ctaProtoICMPv6ID:
+					t.ICMPID = AttrUint16BE(pa.Value)
+				case ctaProtoICMPType, ctaProtoICMPv6Type:
+					if len(pa.Value) > 0 {
+						t.ICMPType = pa.Value[0]
+					}
+				case ctaProtoICMPCode, ctaProtoICMPv6Code:
+					if len(pa.Value) > 0 {
+						t.ICMPCode = pa.Value[0]
+					}
+				}
+			}
+		}
+	}
+	return t, nil
+}
+
+func parseConntrackCounters(b []byte) (ConntrackCounters, error) {
+	var c ConntrackCounters
+	attrs, err := ParseAttrs(b)
+	if err != nil {
+		return c, err
+	}
+	for _, a := range attrs {
+		switch a.Type {
+		case ctaCountersPackets:
+			c.Packets = AttrUint64BE(a.Value)
+		case ctaCountersBytes:
+			c.Bytes = AttrUint64BE(a.Value)
+		}
+	}
+	return c, nil
+}
+
+func parseConntrackTimestamp(b []byte, flow *ConntrackFlow) error {
+	attrs, err := ParseAttrs(b)
+	if err != nil {
+		return err
+	}
+	for _, a := range attrs {
+		switch a.Type {
+		case ctaTimestampStart:
+			flow.Start = time.Unix(0, int64(AttrUint64BE(a.Value)))
+		case ctaTimestampStop:
+			flow.Stop = time.Unix(0, int64(AttrUint64BE(a.Value)))
+		}
+	}
+	return nil
+}
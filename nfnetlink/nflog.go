@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nfnetlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	SubsysULOG = 4
+
+	nfulnlMsgPacket = 0
+	nfulnlMsgConfig = 1
+
+	// Config attributes.
+	nfulaCfgCmd     = 1
+	nfulaCfgMode    = 2
+	nfulaCfgTimeout = 4
+	nfulaCfgQThresh = 5
+
+	nfulnlCfgCmdBind   = 1
+	nfulnlCfgCmdUnbind = 2
+
+	nfulnlCopyPacket = 2
+	sizeofNfulnlMode = 6
+
+	// Packet attributes.
+	nfulaPacketHdr  = 1
+	nfulaMark       = 2
+	nfulaIfIndexIn  = 4
+	nfulaIfIndexOut = 5
+	nfulaPayload    = 9
+	nfulaPrefix     = 10
+
+	sizeofNfulnlHdr = 4
+)
+
+// NflogPacket is a packet that was sent to an NFLOG group.
+type NflogPacket struct {
+	// Family is the address family of the packet, AF_INET or AF_INET6.
+	Family   uint8
+	Prefix   string
+	Mark     uint32
+	InIndex  uint32
+	OutIndex uint32
+	// Payload holds the packet, starting at the IP header.  It may have been truncated to the
+	// copy range that was passed to ListenNflog.
+	Payload []byte
+}
+
+// NflogListener receives packets from a single NFLOG group.
+type NflogListener struct {
+	conn  *Conn
+	group uint16
+}
+
+// ListenNflog binds to the given NFLOG group and asks the kernel to copy up to copyRange bytes of
+// each packet.  Only one listener can bind to a given group at a time.
+//
+// The socket has a short receive timeout so that Read returns periodically, even if there are no
+// packets, to give the caller a chance to shut down.
+func ListenNflog(group uint16, copyRange uint32) (*NflogListener, error) {
+	conn, err := Open(time.Second)
+	if err != nil {
+		return nil, err
+	}
+	l := &NflogListener{conn: conn, group: group}
+
+	var cmd AttrEncoder
+	cmd.Bytes(nfulaCfgCmd, []byte{nfulnlCfgCmdBind})
+	if _, err := conn.Execute(l.configMsg(cmd.Encode())); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to bind to NFLOG group %d: %w", group, err)
+	}
+
+	var cfg AttrEncoder
+	mode := make([]byte, sizeofNfulnlMode)
+	binary.BigEndian.PutUint32(mode[0:4], copyRange)
+	mode[4] = nfulnlCopyPacket
+	cfg.Bytes(nfulaCfgMode, mode)
+	// Deliver packets as soon as possible rather than batching them up; our callers care about
+	// latency more than throughput.
+	cfg.Uint32BE(nfulaCfgQThresh, 1)
+	cfg.Uint32BE(nfulaCfgTimeout, 0)
+	if _, err := conn.Execute(l.configMsg(cfg.Encode())); err != nil {
+		_ = l.Close()
+		return nil, fmt.Errorf("failed to configure NFLOG group %d: %w", group, err)
+	}
+	return l, nil
+}
+
+func (l *NflogListener) configMsg(attrs []byte) *Message {
+	return &Message{
+		Type:   MsgType(SubsysULOG, nfulnlMsgConfig),
+		Family: unix.AF_UNSPEC,
+		ResID:  l.group,
+		Attrs:  attrs,
+	}
+}
+
+// Read waits for the next batch of packets.  It returns an empty batch if no packets arrive
+// within the socket's timeout.  If the kernel had to drop packets because we weren't reading
+// them quickly enough, it logs a warning and carries on.
+func (l *NflogListener) Read() ([]NflogPacket, error) {
+	msgs, err := l.conn.receiveAsync()
+	if err != nil {
+		return nil, err
+	}
+	var pkts []NflogPacket
+	for _, m := range msgs {
+		if m.Type != MsgType(SubsysULOG, nfulnlMsgPacket) || m.ResID != l.group {
+			continue
+		}
+		pkt, err := parseNflogPacket(&m.Message)
+		if err != nil {
+			log.WithError(err).Warn("Failed to parse NFLOG packet, ignoring.")
+			continue
+		}
+		pkts = append(pkts, pkt)
+	}
+	return pkts, nil
+}
+
+func parseNflogPacket(m *Message) (NflogPacket, error) {
+	pkt := NflogPacket{Family: m.Family}
+	attrs, err := ParseAttrs(m.Attrs)
+	if err != nil {
+		return pkt, err
+	}
+	for _, a := range attrs {
+		switch a.Type {
+		case nfulaPacketHdr:
+			if len(a.Value) < sizeofNfulnlHdr {
+				return pkt, errors.New("truncated NFLOG packet header")
+			}
+		case nfulaMark:
+			pkt.Mark = AttrUint32BE(a.Value)
+		case nfulaIfIndexIn:
+			pkt.InIndex = AttrUint32BE(a.Value)
+		case nfulaIfIndexOut:
+			pkt.OutIndex = AttrUint32BE(a.Value)
+		case nfulaPayload:
+			pkt.Payload = a.Value
+		case nfulaPrefix:
+			pkt.Prefix = AttrString(a.Value)
+		}
+	}
+	return pkt, nil
+}
+
+// Close unbinds from the NFLOG group and closes the socket.
+func (l *NflogListener) Close() error {
+	var cmd AttrEncoder
+	cmd.Bytes(nfulaCfgCmd, []byte{nfulnlCfgCmdUnbind})
+	if _, err := l.conn.Execute(l.configMsg(cmd.Encode())); err != nil {
+		log.WithError(err).WithField("group", l.group).Warn("Failed to unbind from NFLOG group.")
+	}
+	return l.conn.Close()
+}
+
+// nflogRetryInterval is how long LoopReadingNflog waits before retrying after it fails to bind.
+const nflogRetryInterval = 5 * time.Second
+
+// LoopReadingNflog binds to the given NFLOG group and passes each batch of packets that it reads
+// to onPackets, which may be called with an empty batch.  If it fails to bind, or reading fails,
+// it logs the error and reconnects; it never returns.  description describes the packets, for
+// logging; for example, "denied packets".
+func LoopReadingNflog(group uint16, copyRange uint32, description string, onPackets func([]NflogPacket)) {
+	logCxt := log.WithField("group", group)
+	for {
+		l, err := ListenNflog(group, copyRange)
+		if err != nil {
+			logCxt.WithError(err).Errorf("Failed to listen for %s, will retry.", description)
+			time.Sleep(nflogRetryInterval)
+			continue
+		}
+		logCxt.Infof("Listening for %s.", description)
+		for {
+			var pkts []NflogPacket
+			pkts, err = l.Read()
+			if err != nil {
+				break
+			}
+			onPackets(pkts)
+		}
+		logCxt.WithError(err).Warnf("Failed to read %s, reconnecting.", description)
+		_ = l.Close()
+	}
+}
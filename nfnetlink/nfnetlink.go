@@ -0,0 +1,467 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nfnetlink provides a minimal client for the kernel's netfilter netlink (nfnetlink)
+// interface.  It deals only with framing: sending requests, dumps and atomic batches, and
+// decoding the replies.  The subsystem-specific message and attribute layouts (nftables, ipset,
+// conntrack, etc.) are left to the callers.
+package nfnetlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Subsystem IDs, as used in the top byte of the netlink message type.
+	SubsysCTNetlink = 1
+	SubsysIPSet     = 6
+	SubsysNFTables  = 10
+
+	// Batch delimiter message types, used to frame atomic nftables transactions.
+	MsgBatchBegin = 0x10
+	MsgBatchEnd   = 0x11
+
+	// NFGenVersion is the only defined value of the nfgenmsg version field.
+	NFGenVersion = 0
+
+	sizeofNfgenmsg = 4
+
+	// Message flags that aren't present in all versions of x/sys/unix.
+	flagReplace = 0x100
+	flagExcl    = 0x200
+	flagCreate  = 0x400
+	flagAppend  = 0x800
+
+	// flagDumpIntr is set by the kernel on a dump message if the dump was interrupted by a
+	// concurrent modification, in which case the dump is inconsistent and must be retried.
+	flagDumpIntr = 0x10
+
+	defaultRecvBufSize = 1 << 16
+	defaultSocketBuf   = 4 * 1024 * 1024
+)
+
+// Exported aliases for the message flags that callers need to set on individual requests.
+const (
+	FlagReplace = flagReplace
+	FlagExcl    = flagExcl
+	FlagCreate  = flagCreate
+	FlagAppend  = flagAppend
+)
+
+// ErrDumpInterrupted is returned from Dump if the kernel signalled that the dump was
+// inconsistent due to a concurrent update.
+var ErrDumpInterrupted = errors.New("netlink dump interrupted by concurrent update")
+
+// MsgType returns the netlink message type for the given nfnetlink subsystem and message.
+func MsgType(subsys uint8, msg uint8) uint16 {
+	return uint16(subsys)<<8 | uint16(msg)
+}
+
+// Message is a single nfnetlink message: the netlink header fields that callers care about,
+// the nfgenmsg header and the (already-encoded) attributes.
+type Message struct {
+	Type   uint16
+	Flags  uint16
+	Seq    uint32
+	Family uint8
+	ResID  uint16
+	Attrs  []byte
+}
+
+// Subsys returns the subsystem ID part of the message type.
+func (m *Message) Subsys() uint8 {
+	return uint8(m.Type >> 8)
+}
+
+// Msg returns the subsystem-specific part of the message type.
+func (m *Message) Msg() uint8 {
+	return uint8(m.Type & 0xff)
+}
+
+func (m *Message) appendTo(buf []byte, seq uint32, pid uint32) []byte {
+	length := unix.SizeofNlMsghdr + sizeofNfgenmsg + len(m.Attrs)
+	start := len(buf)
+	buf = append(buf, make([]byte, align(length))...)
+	b := buf[start:]
+	binary.LittleEndian.PutUint32(b[0:4], uint32(length))
+	binary.LittleEndian.PutUint16(b[4:6], m.Type)
+	binary.LittleEndian.PutUint16(b[6:8], m.Flags)
+	binary.LittleEndian.PutUint32(b[8:12], seq)
+	binary.LittleEndian.PutUint32(b[12:16], pid)
+	b[16] = m.Family
+	b[17] = NFGenVersion
+	binary.BigEndian.PutUint16(b[18:20], m.ResID)
+	copy(b[20:], m.Attrs)
+	return buf
+}
+
+// Conn is a NETLINK_NETFILTER socket.  It is safe for concurrent use; requests are serialised.
+type Conn struct {
+	lock sync.Mutex
+	fd   int
+	pid  uint32
+	seq  uint32
+	buf  []byte
+}
+
+// Open opens a new nfnetlink socket.  If timeout is non-zero, it is used as the receive timeout
+// for the socket so that a misbehaving kernel can't block us forever.
+func Open(timeout time.Duration) (*Conn, error) {
+	return open(timeout, 0)
+}
+
+// OpenSubscribed opens a new nfnetlink socket that is subscribed to the given multicast groups,
+// expressed as a bitmask with bit (n-1) set for group n.
+func OpenSubscribed(timeout time.Duration, groups uint32) (*Conn, error) {
+	return open(timeout, groups)
+}
+
+func open(timeout time.Duration, groups uint32) (*Conn, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netfilter netlink socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: groups}); err != nil {
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("failed to bind netfilter netlink socket: %w", err)
+	}
+	sa, err := unix.Getsockname(fd)
+	if err != nil {
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("failed to read netfilter netlink socket address: %w", err)
+	}
+	nlSA, ok := sa.(*unix.SockaddrNetlink)
+	if !ok {
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("unexpected netlink socket address type %T", sa)
+	}
+	if timeout > 0 {
+		tv := unix.NsecToTimeval(timeout.Nanoseconds())
+		if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+			_ = unix.Close(fd)
+			return nil, fmt.Errorf("failed to set netlink socket timeout: %w", err)
+		}
+	}
+	// Large batches generate one ack per message so make sure we've got room to queue them.
+	// SO_RCVBUFFORCE requires CAP_NET_ADMIN, fall back to the unprivileged version.
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_RCVBUFFORCE, defaultSocketBuf); err != nil {
+		log.WithError(err).Debug("Failed to force netlink socket buffer size, falling back to SO_RCVBUF")
+		_ = unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_RCVBUF, defaultSocketBuf)
+	}
+	return &Conn{
+		fd:  fd,
+		pid: nlSA.Pid,
+		seq: uint32(time.Now().Unix()),
+		buf: make([]byte, defaultRecvBufSize),
+	}, nil
+}
+
+// Close closes the underlying socket.
+func (c *Conn) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.fd < 0 {
+		return nil
+	}
+	err := unix.Close(c.fd)
+	c.fd = -1
+	return err
+}
+
+func (c *Conn) nextSeq() uint32 {
+	c.seq++
+	return c.seq
+}
+
+// Execute sends a single request and waits for the kernel to acknowledge it.  Any reply
+// messages other than the ack are returned.
+func (c *Conn) Execute(msg *Message) ([]Message, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	seq := c.nextSeq()
+	req := *msg
+	req.Flags |= unix.NLM_F_REQUEST | unix.NLM_F_ACK
+	if err := c.send(req.appendTo(nil, seq, c.pid)); err != nil {
+		return nil, err
+	}
+	var replies []Message
+	for {
+		msgs, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			if m.seq != seq {
+				log.WithField("seq", m.seq).Debug("Ignoring netlink message with stale sequence number")
+				continue
+			}
+			if m.typ == unix.NLMSG_ERROR {
+				// Error or ack (errno 0); either way, it's the last message for this request.
+				return replies, m.err
+			}
+			if m.typ == unix.NLMSG_DONE {
+				return replies, nil
+			}
+			replies = append(replies, m.Message)
+		}
+	}
+}
+
+// Dump sends a dump request and returns all the messages that the kernel sent in response.
+func (c *Conn) Dump(msg *Message) ([]Message, error) {
+	var replies []Message
+	err := c.DumpFunc(msg, func(m *Message) error {
+		replies = append(replies, *m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replies, nil
+}
+
+// DumpFunc sends a dump request and calls fn for each message that the kernel sends in
+// response, avoiding the need to buffer very large dumps.  If fn returns an error, the rest of
+// the dump is drained and discarded, and the error is returned.  Note: ErrDumpInterrupted is
+// only detected at the end of the dump so callers must be prepared to discard what they've
+// seen if DumpFunc returns an error.
+func (c *Conn) DumpFunc(msg *Message, fn func(m *Message) error) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	seq := c.nextSeq()
+	req := *msg
+	req.Flags |= unix.NLM_F_REQUEST | unix.NLM_F_DUMP
+	if err := c.send(req.appendTo(nil, seq, c.pid)); err != nil {
+		return err
+	}
+	interrupted := false
+	var fnErr error
+	for {
+		msgs, err := c.receive()
+		if err != nil {
+			return err
+		}
+		for i := range msgs {
+			m := &msgs[i]
+			if m.seq != seq {
+				log.WithField("seq", m.seq).Debug("Ignoring netlink message with stale sequence number")
+				continue
+			}
+			if m.Flags&flagDumpIntr != 0 {
+				interrupted = true
+			}
+			switch m.typ {
+			case unix.NLMSG_ERROR:
+				return m.err
+			case unix.NLMSG_DONE:
+				if fnErr != nil {
+					return fnErr
+				}
+				if interrupted {
+					return ErrDumpInterrupted
+				}
+				return nil
+			}
+			if fnErr == nil {
+				fnErr = fn(&m.Message)
+			}
+		}
+	}
+}
+
+// ExecuteBatch sends the given messages to the kernel as a single atomic transaction for the
+// given subsystem.  Either all the messages are applied, or none of them are.  Every message is
+// sent with NLM_F_ACK so that we can pinpoint the message that failed.  Returns a *BatchError
+// if the kernel rejected any of the messages.
+func (c *Conn) ExecuteBatch(subsys uint16, msgs []*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var buf []byte
+	begin := Message{
+		Type:  MsgBatchBegin,
+		Flags: unix.NLM_F_REQUEST,
+		ResID: subsys,
+	}
+	buf = begin.appendTo(buf, c.nextSeq(), c.pid)
+	seqToIdx := map[uint32]int{}
+	for i, m := range msgs {
+		req := *m
+		req.Flags |= unix.NLM_F_REQUEST | unix.NLM_F_ACK
+		seq := c.nextSeq()
+		seqToIdx[seq] = i
+		buf = req.appendTo(buf, seq, c.pid)
+	}
+	end := Message{
+		Type:  MsgBatchEnd,
+		Flags: unix.NLM_F_REQUEST,
+		ResID: subsys,
+	}
+	buf = end.appendTo(buf, c.nextSeq(), c.pid)
+
+	if err := c.send(buf); err != nil {
+		return err
+	}
+
+	// The kernel sends one ack (or error) per message.  If any message fails, the whole batch
+	// is aborted and the remaining messages may not be acked at all, so stop at the first error.
+	numOutstanding := len(msgs)
+	for numOutstanding > 0 {
+		replies, err := c.receive()
+		if err != nil {
+			return err
+		}
+		for _, m := range replies {
+			idx, ok := seqToIdx[m.seq]
+			if !ok || m.typ != unix.NLMSG_ERROR {
+				continue
+			}
+			delete(seqToIdx, m.seq)
+			numOutstanding--
+			if m.err != nil {
+				return &BatchError{Index: idx, Err: m.err}
+			}
+		}
+	}
+	return nil
+}
+
+// BatchError is returned from ExecuteBatch when the kernel rejects one of the messages in the
+// batch.  Index is the index of the failing message in the input slice.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("netlink batch message %d failed: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+func (c *Conn) send(buf []byte) error {
+	if c.fd < 0 {
+		return errors.New("netlink socket is closed")
+	}
+	err := unix.Sendto(c.fd, buf, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+	if err != nil {
+		return fmt.Errorf("failed to send netlink message: %w", err)
+	}
+	return nil
+}
+
+type rawMessage struct {
+	Message
+	typ uint16
+	seq uint32
+	err error
+}
+
+func (c *Conn) receive() ([]rawMessage, error) {
+	if c.fd < 0 {
+		return nil, errors.New("netlink socket is closed")
+	}
+	for {
+		n, from, err := unix.Recvfrom(c.fd, c.buf, 0)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from netlink socket: %w", err)
+		}
+		if sa, ok := from.(*unix.SockaddrNetlink); ok && sa.Pid != 0 {
+			// Not from the kernel.
+			continue
+		}
+		return parseMessages(c.buf[:n])
+	}
+}
+
+// receiveAsync reads the next batch of messages that the kernel sent of its own accord, such as
+// multicast events.  It returns an empty batch if the socket's receive timeout expires.  If the
+// kernel had to drop messages because we weren't reading them quickly enough, it logs a warning
+// and carries on.
+func (c *Conn) receiveAsync() ([]rawMessage, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	msgs, err := c.receive()
+	if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EWOULDBLOCK) {
+		return nil, nil
+	}
+	if errors.Is(err, unix.ENOBUFS) {
+		log.Warn("Netlink socket overrun, some messages were dropped.")
+		return nil, nil
+	}
+	return msgs, err
+}
+
+func parseMessages(b []byte) ([]rawMessage, error) {
+	var msgs []rawMessage
+	for len(b) >= unix.SizeofNlMsghdr {
+		length := binary.LittleEndian.Uint32(b[0:4])
+		if length < unix.SizeofNlMsghdr || int(length) > len(b) {
+			return nil, fmt.Errorf("malformed netlink message (length %d, buffer %d)", length, len(b))
+		}
+		m := rawMessage{
+			typ: binary.LittleEndian.Uint16(b[4:6]),
+			seq: binary.LittleEndian.Uint32(b[8:12]),
+		}
+		m.Type = m.typ
+		m.Flags = binary.LittleEndian.Uint16(b[6:8])
+		m.Seq = m.seq
+		payload := b[unix.SizeofNlMsghdr:length]
+		switch m.typ {
+		case unix.NLMSG_ERROR:
+			if len(payload) < 4 {
+				return nil, errors.New("truncated netlink error message")
+			}
+			errno := int32(binary.LittleEndian.Uint32(payload[0:4]))
+			if errno != 0 {
+				m.err = unix.Errno(-errno)
+			}
+		case unix.NLMSG_DONE, unix.NLMSG_NOOP:
+		default:
+			if len(payload) >= sizeofNfgenmsg {
+				m.Family = payload[0]
+				m.ResID = binary.BigEndian.Uint16(payload[2:4])
+				m.Attrs = append([]byte(nil), payload[sizeofNfgenmsg:]...)
+			}
+		}
+		msgs = append(msgs, m)
+		if align(int(length)) >= len(b) {
+			break
+		}
+		b = b[align(int(length)):]
+	}
+	return msgs, nil
+}
+
+func align(l int) int {
+	return (l + 3) &^ 3
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nfnetlink
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("Attribute encoding", func() {
+	It("should pad attributes to 4 bytes", func() {
+		var e AttrEncoder
+		e.String(1, "abc")
+		e.Uint8(2, 7)
+		Expect(e.Encode()).To(Equal([]byte{
+			8, 0, 1, 0, 'a', 'b', 'c', 0,
+			5, 0, 2, 0, 7, 0, 0, 0,
+		}))
+	})
+
+	It("should encode integers big-endian", func() {
+		var e AttrEncoder
+		e.Uint16BE(1, 0x0102)
+		e.Uint32BE(2, 0x01020304)
+		Expect(e.Encode()).To(Equal([]byte{
+			6, 0, 1, 0, 1, 2, 0, 0,
+			8, 0, 2, 0, 1, 2, 3, 4,
+		}))
+	})
+
+	It("should round-trip nested attributes", func() {
+		var e AttrEncoder
+		e.Nested(3, func(e *AttrEncoder) {
+			e.String(1, "cali-INPUT")
+			e.Uint64BE(2, 12345)
+		})
+		e.Uint32BE(4, 42)
+
+		attrs, err := ParseAttrs(e.Encode())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attrs).To(HaveLen(2))
+		Expect(attrs[0].Type).To(Equal(uint16(3)), "nested flag should be masked off")
+		Expect(attrs[1].Uint32BE()).To(Equal(uint32(42)))
+
+		inner, err := ParseAttrMap(attrs[0].Value)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(AttrString(inner[1])).To(Equal("cali-INPUT"))
+		Expect(AttrUint64BE(inner[2])).To(Equal(uint64(12345)))
+	})
+
+	It("should reject a truncated attribute", func() {
+		_, err := ParseAttrs([]byte{12, 0, 1, 0, 1, 2})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Message framing", func() {
+	It("should encode the netlink and nfgenmsg headers", func() {
+		m := Message{
+			Type:   MsgType(SubsysNFTables, 6),
+			Flags:  unix.NLM_F_REQUEST,
+			Family: unix.AF_INET,
+			ResID:  0x1234,
+			Attrs:  []byte{5, 0, 1, 0, 9, 0, 0, 0},
+		}
+		b := m.appendTo(nil, 77, 99)
+		Expect(b).To(HaveLen(16 + 4 + 8))
+		Expect(binary.LittleEndian.Uint32(b[0:4])).To(Equal(uint32(28)))
+		Expect(binary.LittleEndian.Uint16(b[4:6])).To(Equal(uint16(0x0a06)))
+		Expect(binary.LittleEndian.Uint32(b[8:12])).To(Equal(uint32(77)))
+		Expect(binary.LittleEndian.Uint32(b[12:16])).To(Equal(uint32(99)))
+		Expect(b[16]).To(Equal(uint8(unix.AF_INET)))
+		Expect(b[18:20]).To(Equal([]byte{0x12, 0x34}))
+	})
+
+	It("should parse a batch of replies including errors", func() {
+		data := Message{Type: MsgType(SubsysNFTables, 3), Family: 2, Attrs: []byte{5, 0, 1, 0, 1, 0, 0, 0}}
+		b := data.appendTo(nil, 1, 0)
+		errMsg := make([]byte, 16+4+16)
+		binary.LittleEndian.PutUint32(errMsg[0:4], uint32(len(errMsg)))
+		binary.LittleEndian.PutUint16(errMsg[4:6], unix.NLMSG_ERROR)
+		binary.LittleEndian.PutUint32(errMsg[8:12], 2)
+		binary.LittleEndian.PutUint32(errMsg[16:20], uint32(0xffffffff-uint32(unix.ENOENT)+1))
+		b = append(b, errMsg...)
+
+		msgs, err := parseMessages(b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msgs).To(HaveLen(2))
+		Expect(msgs[0].Msg()).To(Equal(uint8(3)))
+		Expect(msgs[0].Subsys()).To(Equal(uint8(SubsysNFTables)))
+		Expect(msgs[0].Attrs).To(Equal([]byte{5, 0, 1, 0, 1, 0, 0, 0}))
+		Expect(msgs[1].seq).To(Equal(uint32(2)))
+		Expect(msgs[1].err).To(Equal(unix.ENOENT))
+	})
+})
+
+var _ = Describe("NFLOG packet parsing", func() {
+	It("should extract the packet fields", func() {
+		var e AttrEncoder
+		e.Bytes(nfulaPacketHdr, []byte{0x08, 0x00, 4, 0})
+		e.Uint32BE(nfulaMark, 0x10)
+		e.Uint32BE(nfulaIfIndexOut, 7)
+		e.String(nfulaPrefix, "dns")
+		e.Bytes(nfulaPayload, []byte{0x45, 0, 0, 20})
+		pkt, err := parseNflogPacket(&Message{
+			Type:   MsgType(SubsysULOG, nfulnlMsgPacket),
+			Family: unix.AF_INET,
+			ResID:  3,
+			Attrs:  e.Encode(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkt).To(Equal(NflogPacket{
+			Family:   unix.AF_INET,
+			Prefix:   "dns",
+			Mark:     0x10,
+			OutIndex: 7,
+			Payload:  []byte{0x45, 0, 0, 20},
+		}))
+	})
+
+	It("should reject a truncated packet header", func() {
+		var e AttrEncoder
+		e.Bytes(nfulaPacketHdr, []byte{0x08})
+		_, err := parseNflogPacket(&Message{Attrs: e.Encode()})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("conntrack entry parsing", func() {
+	encodeTuple := func(e *AttrEncoder, src, dst net.IP, sport, dport uint16) {
+		e.Nested(ctaTupleIP, func(e *AttrEncoder) {
+			e.Bytes(ctaIPv4Src, src.To4())
+			e.Bytes(ctaIPv4Dst, dst.To4())
+		})
+		e.Nested(ctaTupleProto, func(e *AttrEncoder) {
+			e.Uint8(ctaProtoNum, 6)
+			e.Uint16BE(ctaProtoSrcPort, sport)
+			e.Uint16BE(ctaProtoDstPort, dport)
+		})
+	}
+
+	It("should extract the tuples, counters and timestamps", func() {
+		var e AttrEncoder
+		e.Nested(ctaTupleOrig, func(e *AttrEncoder) {
+			encodeTuple(e, net.ParseIP("10.0.0.1"), net.ParseIP("10.96.0.10"), 34567, 80)
+		})
+		e.Nested(ctaTupleReply, func(e *AttrEncoder) {
+			encodeTuple(e, net.ParseIP("10.0.1.5"), net.ParseIP("10.0.0.1"), 8080, 34567)
+		})
+		e.Nested(ctaCountersOrig, func(e *AttrEncoder) {
+			e.Uint64BE(ctaCountersPackets, 3)
+			e.Uint64BE(ctaCountersBytes, 180)
+		})
+		e.Nested(ctaCountersReply, func(e *AttrEncoder) {
+			e.Uint64BE(ctaCountersPackets, 2)
+			e.Uint64BE(ctaCountersBytes, 1200)
+		})
+		e.Uint32BE(ctaMark, 0x100)
+		e.Uint32BE(ctaID, 42)
+		e.Nested(ctaTimestamp, func(e *AttrEncoder) {
+			e.Uint64BE(ctaTimestampStart, 1000000000)
+			e.Uint64BE(ctaTimestampStop, 3000000000)
+		})
+
+		flow, err := ParseConntrackFlow(&Message{
+			Type:   MsgType(SubsysCTNetlink, ctnlMsgDelete),
+			Family: unix.AF_INET,
+			Attrs:  e.Encode(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(flow).To(Equal(ConntrackFlow{
+			ID:   42,
+			Mark: 0x100,
+			Orig: ConntrackTuple{
+				Src: net.ParseIP("10.0.0.1").To4(), Dst: net.ParseIP("10.96.0.10").To4(),
+				Proto: 6, SrcPort: 34567, DstPort: 80,
+			},
+			Reply: ConntrackTuple{
+				Src: net.ParseIP("10.0.1.5").To4(), Dst: net.ParseIP("10.0.0.1").To4(),
+				Proto: 6, SrcPort: 8080, DstPort: 34567,
+			},
+			OrigCounters:  ConntrackCounters{Packets: 3, Bytes: 180},
+			ReplyCounters: ConntrackCounters{Packets: 2, Bytes: 1200},
+			Start:         time.Unix(1, 0),
+			Stop:          time.Unix(3, 0),
+		}))
+	})
+
+	It("should encode a delete request that identifies the entry", func() {
+		flow := ConntrackFlow{
+			ID:   7,
+			Zone: 3,
+			Orig: ConntrackTuple{
+				Src: net.ParseIP("fd00::1"), Dst: net.ParseIP("fd00::2"),
+				Proto: unix.IPPROTO_ICMPV6, ICMPID: 1234, ICMPType: 128,
+			},
+		}
+		msg := ConntrackDeleteRequest(unix.AF_INET6, &flow)
+		Expect(msg.Type).To(Equal(MsgType(SubsysCTNetlink, ctnlMsgDelete)))
+		Expect(msg.Family).To(Equal(uint8(unix.AF_INET6)))
+		parsed, err := ParseConntrackFlow(msg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed).To(Equal(flow))
+	})
+
+	It("should reject an entry without an original tuple", func() {
+		var e AttrEncoder
+		e.Uint32BE(ctaID, 42)
+		_, err := ParseConntrackFlow(&Message{Attrs: e.Encode()})
+		Expect(err).To(HaveOccurred())
+	})
+})
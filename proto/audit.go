@@ -0,0 +1,37 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proto
+
+import "strings"
+
+// AuditOnlyPolicyNamePrefix marks a policy as audit-only (also known as "staged").  An audit-only
+// policy is rendered into the dataplane like any other but, rather than enforcing its rules, it
+// only records what it would have done.  For a namespaced policy, the prefix goes on the name
+// part, as in "namespace/staged:name".
+//
+// The name is the only place that we can carry the flag: the policy resources have no field for
+// it and the datastore drops the policy's annotations when it converts the policy for Felix.
+//
+// Since audit-only policies never accept or drop traffic, an endpoint (iptables) or tier (BPF)
+// whose policies are all audit-only doesn't end with the usual default drop.  Traffic carries on
+// to the profiles or to the next tier, as if the audit-only policies weren't there.
+const AuditOnlyPolicyNamePrefix = "staged:"
+
+// PolicyNameIsAuditOnly returns true if the policy with the given name is audit-only.  Only the
+// calculation graph should use this, to fill in Policy.AuditOnly; everything downstream of it
+// should use that field.
+func PolicyNameIsAuditOnly(name string) bool {
+	return strings.HasPrefix(name[strings.LastIndex(name, "/")+1:], AuditOnlyPolicyNamePrefix)
+}
@@ -61,6 +61,7 @@
 		NamespaceID
 		TunnelType
 		RouteUpdate
+		RouteNextHop
 		RouteRemove
 		VXLANTunnelEndpointUpdate
 		VXLANTunnelEndpointRemove
@@ -1442,6 +1443,9 @@ type IPSetUpdate struct {
 	Id      string                `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	Members []string              `protobuf:"bytes,2,rep,name=members" json:"members,omitempty"`
 	Type    IPSetUpdate_IPSetType `protobuf:"varint,3,opt,name=type,proto3,enum=felix.IPSetUpdate_IPSetType" json:"type,omitempty"`
+	// For IP sets that are populated by the dataplane from snooped DNS responses, the domain
+	// names whose addresses should be in the set.  Members is empty for such sets.
+	Domains []string `protobuf:"bytes,4,rep,name=domains" json:"domains,omitempty"`
 }
 
 func (m *IPSetUpdate) Reset()                    { *m = IPSetUpdate{} }
@@ -1470,6 +1474,13 @@ func (m *IPSetUpdate) GetType() IPSetUpdate_IPSetType {
 	return IPSetUpdate_IP
 }
 
+func (m *IPSetUpdate) GetDomains() []string {
+	if m != nil {
+		return m.Domains
+	}
+	return nil
+}
+
 type IPSetDeltaUpdate struct {
 	Id             string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	AddedMembers   []string `protobuf:"bytes,2,rep,name=added_members,json=addedMembers" json:"added_members,omitempty"`
@@ -1670,6 +1681,9 @@ type Policy struct {
 	OutboundRules []*Rule `protobuf:"bytes,2,rep,name=outbound_rules,json=outboundRules" json:"outbound_rules,omitempty"`
 	Untracked     bool    `protobuf:"varint,3,opt,name=untracked,proto3" json:"untracked,omitempty"`
 	PreDnat       bool    `protobuf:"varint,4,opt,name=pre_dnat,json=preDnat,proto3" json:"pre_dnat,omitempty"`
+	// If true, the policy should only record what it would have done rather than enforcing
+	// its rules.
+	AuditOnly bool `protobuf:"varint,6,opt,name=audit_only,json=auditOnly,proto3" json:"audit_only,omitempty"`
 }
 
 func (m *Policy) Reset()                    { *m = Policy{} }
@@ -1712,6 +1726,13 @@ func (m *Policy) GetPreDnat() bool {
 	return false
 }
 
+func (m *Policy) GetAuditOnly() bool {
+	if m != nil {
+		return m.AuditOnly
+	}
+	return false
+}
+
 type Rule struct {
 	Action    string    `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
 	IpVersion IPVersion `protobuf:"varint,2,opt,name=ip_version,json=ipVersion,proto3,enum=felix.IPVersion" json:"ip_version,omitempty"`
@@ -2573,15 +2594,19 @@ func (m *WorkloadEndpointUpdate) GetEndpoint() *WorkloadEndpoint {
 }
 
 type WorkloadEndpoint struct {
-	State      string      `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
-	Name       string      `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Mac        string      `protobuf:"bytes,3,opt,name=mac,proto3" json:"mac,omitempty"`
-	ProfileIds []string    `protobuf:"bytes,4,rep,name=profile_ids,json=profileIds" json:"profile_ids,omitempty"`
-	Ipv4Nets   []string    `protobuf:"bytes,5,rep,name=ipv4_nets,json=ipv4Nets" json:"ipv4_nets,omitempty"`
-	Ipv6Nets   []string    `protobuf:"bytes,6,rep,name=ipv6_nets,json=ipv6Nets" json:"ipv6_nets,omitempty"`
-	Tiers      []*TierInfo `protobuf:"bytes,7,rep,name=tiers" json:"tiers,omitempty"`
-	Ipv4Nat    []*NatInfo  `protobuf:"bytes,8,rep,name=ipv4_nat,json=ipv4Nat" json:"ipv4_nat,omitempty"`
-	Ipv6Nat    []*NatInfo  `protobuf:"bytes,9,rep,name=ipv6_nat,json=ipv6Nat" json:"ipv6_nat,omitempty"`
+	State            string      `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	Name             string      `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Mac              string      `protobuf:"bytes,3,opt,name=mac,proto3" json:"mac,omitempty"`
+	ProfileIds       []string    `protobuf:"bytes,4,rep,name=profile_ids,json=profileIds" json:"profile_ids,omitempty"`
+	Ipv4Nets         []string    `protobuf:"bytes,5,rep,name=ipv4_nets,json=ipv4Nets" json:"ipv4_nets,omitempty"`
+	Ipv6Nets         []string    `protobuf:"bytes,6,rep,name=ipv6_nets,json=ipv6Nets" json:"ipv6_nets,omitempty"`
+	Tiers            []*TierInfo `protobuf:"bytes,7,rep,name=tiers" json:"tiers,omitempty"`
+	Ipv4Nat          []*NatInfo  `protobuf:"bytes,8,rep,name=ipv4_nat,json=ipv4Nat" json:"ipv4_nat,omitempty"`
+	Ipv6Nat          []*NatInfo  `protobuf:"bytes,9,rep,name=ipv6_nat,json=ipv6Nat" json:"ipv6_nat,omitempty"`
+	IngressBandwidth int64       `protobuf:"varint,10,opt,name=ingress_bandwidth,json=ingressBandwidth,proto3" json:"ingress_bandwidth,omitempty"`
+	IngressBurst     int64       `protobuf:"varint,11,opt,name=ingress_burst,json=ingressBurst,proto3" json:"ingress_burst,omitempty"`
+	EgressBandwidth  int64       `protobuf:"varint,12,opt,name=egress_bandwidth,json=egressBandwidth,proto3" json:"egress_bandwidth,omitempty"`
+	EgressBurst      int64       `protobuf:"varint,13,opt,name=egress_burst,json=egressBurst,proto3" json:"egress_burst,omitempty"`
 }
 
 func (m *WorkloadEndpoint) Reset()                    { *m = WorkloadEndpoint{} }
@@ -2652,6 +2677,34 @@ func (m *WorkloadEndpoint) GetIpv6Nat() []*NatInfo {
 	return nil
 }
 
+func (m *WorkloadEndpoint) GetIngressBandwidth() int64 {
+	if m != nil {
+		return m.IngressBandwidth
+	}
+	return 0
+}
+
+func (m *WorkloadEndpoint) GetIngressBurst() int64 {
+	if m != nil {
+		return m.IngressBurst
+	}
+	return 0
+}
+
+func (m *WorkloadEndpoint) GetEgressBandwidth() int64 {
+	if m != nil {
+		return m.EgressBandwidth
+	}
+	return 0
+}
+
+func (m *WorkloadEndpoint) GetEgressBurst() int64 {
+	if m != nil {
+		return m.EgressBurst
+	}
+	return 0
+}
+
 type WorkloadEndpointRemove struct {
 	Id *WorkloadEndpointID `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 }
@@ -2987,6 +3040,8 @@ func (m *WorkloadEndpointStatusRemove) GetId() *WorkloadEndpointID {
 type WireguardStatusUpdate struct {
 	// Wireguard public-key set on the interface.
 	PublicKey string `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	// IPv6 address of the wireguard interface, if one is configured.
+	InterfaceIpv6Addr string `protobuf:"bytes,2,opt,name=interface_ipv6_addr,json=interfaceIpv6Addr,proto3" json:"interface_ipv6_addr,omitempty"`
 }
 
 func (m *WireguardStatusUpdate) Reset()         { *m = WireguardStatusUpdate{} }
@@ -3003,6 +3058,13 @@ func (m *WireguardStatusUpdate) GetPublicKey() string {
 	return ""
 }
 
+func (m *WireguardStatusUpdate) GetInterfaceIpv6Addr() string {
+	if m != nil {
+		return m.InterfaceIpv6Addr
+	}
+	return ""
+}
+
 type HostMetadataUpdate struct {
 	Hostname string `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
 	Ipv4Addr string `protobuf:"bytes,2,opt,name=ipv4_addr,json=ipv4Addr,proto3" json:"ipv4_addr,omitempty"`
@@ -3283,6 +3345,9 @@ type RouteUpdate struct {
 	NatOutgoing   bool        `protobuf:"varint,8,opt,name=nat_outgoing,json=natOutgoing,proto3" json:"nat_outgoing,omitempty"`
 	LocalWorkload bool        `protobuf:"varint,9,opt,name=local_workload,json=localWorkload,proto3" json:"local_workload,omitempty"`
 	TunnelType    *TunnelType `protobuf:"bytes,10,opt,name=tunnel_type,json=tunnelType" json:"tunnel_type,omitempty"`
+	// For an ECMP route, all of the next hops for this destination, including the one in
+	// dst_node_name/dst_node_ip.  Empty for a route with a single next hop.
+	NextHops []*RouteNextHop `protobuf:"bytes,11,rep,name=next_hops,json=nextHops" json:"next_hops,omitempty"`
 }
 
 func (m *RouteUpdate) Reset()                    { *m = RouteUpdate{} }
@@ -3353,6 +3418,49 @@ func (m *RouteUpdate) GetTunnelType() *TunnelType {
 	return nil
 }
 
+func (m *RouteUpdate) GetNextHops() []*RouteNextHop {
+	if m != nil {
+		return m.NextHops
+	}
+	return nil
+}
+
+// One of the next hops of an ECMP route.
+type RouteNextHop struct {
+	// The name of the node holding the destination.
+	NodeName string `protobuf:"bytes,1,opt,name=node_name,json=nodeName,proto3" json:"node_name,omitempty"`
+	// IP of the node holding the destination.
+	NodeIp string `protobuf:"bytes,2,opt,name=node_ip,json=nodeIp,proto3" json:"node_ip,omitempty"`
+	// Relative share of the traffic to send via this next hop.
+	Weight int32 `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"`
+}
+
+func (m *RouteNextHop) Reset()                    { *m = RouteNextHop{} }
+func (m *RouteNextHop) String() string            { return proto1.CompactTextString(m) }
+func (*RouteNextHop) ProtoMessage()               {}
+func (*RouteNextHop) Descriptor() ([]byte, []int) { return fileDescriptorFelixbackend, []int{53} }
+
+func (m *RouteNextHop) GetNodeName() string {
+	if m != nil {
+		return m.NodeName
+	}
+	return ""
+}
+
+func (m *RouteNextHop) GetNodeIp() string {
+	if m != nil {
+		return m.NodeIp
+	}
+	return ""
+}
+
+func (m *RouteNextHop) GetWeight() int32 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
 type RouteRemove struct {
 	Dst string `protobuf:"bytes,2,opt,name=dst,proto3" json:"dst,omitempty"`
 }
@@ -3360,7 +3468,7 @@ type RouteRemove struct {
 func (m *RouteRemove) Reset()                    { *m = RouteRemove{} }
 func (m *RouteRemove) String() string            { return proto1.CompactTextString(m) }
 func (*RouteRemove) ProtoMessage()               {}
-func (*RouteRemove) Descriptor() ([]byte, []int) { return fileDescriptorFelixbackend, []int{53} }
+func (*RouteRemove) Descriptor() ([]byte, []int) { return fileDescriptorFelixbackend, []int{54} }
 
 func (m *RouteRemove) GetDst() string {
 	if m != nil {
@@ -3370,17 +3478,20 @@ func (m *RouteRemove) GetDst() string {
 }
 
 type VXLANTunnelEndpointUpdate struct {
-	Node           string `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
-	Mac            string `protobuf:"bytes,2,opt,name=mac,proto3" json:"mac,omitempty"`
-	Ipv4Addr       string `protobuf:"bytes,3,opt,name=ipv4_addr,json=ipv4Addr,proto3" json:"ipv4_addr,omitempty"`
-	ParentDeviceIp string `protobuf:"bytes,4,opt,name=parent_device_ip,json=parentDeviceIp,proto3" json:"parent_device_ip,omitempty"`
+	Node             string `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Mac              string `protobuf:"bytes,2,opt,name=mac,proto3" json:"mac,omitempty"`
+	Ipv4Addr         string `protobuf:"bytes,3,opt,name=ipv4_addr,json=ipv4Addr,proto3" json:"ipv4_addr,omitempty"`
+	ParentDeviceIp   string `protobuf:"bytes,4,opt,name=parent_device_ip,json=parentDeviceIp,proto3" json:"parent_device_ip,omitempty"`
+	MacV6            string `protobuf:"bytes,5,opt,name=mac_v6,json=macV6,proto3" json:"mac_v6,omitempty"`
+	Ipv6Addr         string `protobuf:"bytes,6,opt,name=ipv6_addr,json=ipv6Addr,proto3" json:"ipv6_addr,omitempty"`
+	ParentDeviceIpv6 string `protobuf:"bytes,7,opt,name=parent_device_ipv6,json=parentDeviceIpv6,proto3" json:"parent_device_ipv6,omitempty"`
 }
 
 func (m *VXLANTunnelEndpointUpdate) Reset()         { *m = VXLANTunnelEndpointUpdate{} }
 func (m *VXLANTunnelEndpointUpdate) String() string { return proto1.CompactTextString(m) }
 func (*VXLANTunnelEndpointUpdate) ProtoMessage()    {}
 func (*VXLANTunnelEndpointUpdate) Descriptor() ([]byte, []int) {
-	return fileDescriptorFelixbackend, []int{54}
+	return fileDescriptorFelixbackend, []int{55}
 }
 
 func (m *VXLANTunnelEndpointUpdate) GetNode() string {
@@ -3411,6 +3522,27 @@ func (m *VXLANTunnelEndpointUpdate) GetParentDeviceIp() string {
 	return ""
 }
 
+func (m *VXLANTunnelEndpointUpdate) GetMacV6() string {
+	if m != nil {
+		return m.MacV6
+	}
+	return ""
+}
+
+func (m *VXLANTunnelEndpointUpdate) GetIpv6Addr() string {
+	if m != nil {
+		return m.Ipv6Addr
+	}
+	return ""
+}
+
+func (m *VXLANTunnelEndpointUpdate) GetParentDeviceIpv6() string {
+	if m != nil {
+		return m.ParentDeviceIpv6
+	}
+	return ""
+}
+
 type VXLANTunnelEndpointRemove struct {
 	Node string `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
 }
@@ -3419,7 +3551,7 @@ func (m *VXLANTunnelEndpointRemove) Reset()         { *m = VXLANTunnelEndpointRe
 func (m *VXLANTunnelEndpointRemove) String() string { return proto1.CompactTextString(m) }
 func (*VXLANTunnelEndpointRemove) ProtoMessage()    {}
 func (*VXLANTunnelEndpointRemove) Descriptor() ([]byte, []int) {
-	return fileDescriptorFelixbackend, []int{55}
+	return fileDescriptorFelixbackend, []int{56}
 }
 
 func (m *VXLANTunnelEndpointRemove) GetNode() string {
@@ -3436,13 +3568,15 @@ type WireguardEndpointUpdate struct {
 	PublicKey string `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
 	// The IP address of the wireguard interface.
 	InterfaceIpv4Addr string `protobuf:"bytes,3,opt,name=interface_ipv4_addr,json=interfaceIpv4Addr,proto3" json:"interface_ipv4_addr,omitempty"`
+	// The IPv6 address of the wireguard interface.
+	InterfaceIpv6Addr string `protobuf:"bytes,4,opt,name=interface_ipv6_addr,json=interfaceIpv6Addr,proto3" json:"interface_ipv6_addr,omitempty"`
 }
 
 func (m *WireguardEndpointUpdate) Reset()         { *m = WireguardEndpointUpdate{} }
 func (m *WireguardEndpointUpdate) String() string { return proto1.CompactTextString(m) }
 func (*WireguardEndpointUpdate) ProtoMessage()    {}
 func (*WireguardEndpointUpdate) Descriptor() ([]byte, []int) {
-	return fileDescriptorFelixbackend, []int{56}
+	return fileDescriptorFelixbackend, []int{57}
 }
 
 func (m *WireguardEndpointUpdate) GetHostname() string {
@@ -3466,6 +3600,13 @@ func (m *WireguardEndpointUpdate) GetInterfaceIpv4Addr() string {
 	return ""
 }
 
+func (m *WireguardEndpointUpdate) GetInterfaceIpv6Addr() string {
+	if m != nil {
+		return m.InterfaceIpv6Addr
+	}
+	return ""
+}
+
 type WireguardEndpointRemove struct {
 	// The name of the wireguard host.
 	Hostname string `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
@@ -3475,7 +3616,7 @@ func (m *WireguardEndpointRemove) Reset()         { *m = WireguardEndpointRemove
 func (m *WireguardEndpointRemove) String() string { return proto1.CompactTextString(m) }
 func (*WireguardEndpointRemove) ProtoMessage()    {}
 func (*WireguardEndpointRemove) Descriptor() ([]byte, []int) {
-	return fileDescriptorFelixbackend, []int{57}
+	return fileDescriptorFelixbackend, []int{58}
 }
 
 func (m *WireguardEndpointRemove) GetHostname() string {
@@ -3494,7 +3635,7 @@ func (m *GlobalBGPConfigUpdate) Reset()         { *m = GlobalBGPConfigUpdate{} }
 func (m *GlobalBGPConfigUpdate) String() string { return proto1.CompactTextString(m) }
 func (*GlobalBGPConfigUpdate) ProtoMessage()    {}
 func (*GlobalBGPConfigUpdate) Descriptor() ([]byte, []int) {
-	return fileDescriptorFelixbackend, []int{58}
+	return fileDescriptorFelixbackend, []int{59}
 }
 
 func (m *GlobalBGPConfigUpdate) GetServiceClusterCidrs() []string {
@@ -3566,6 +3707,7 @@ func init() {
 	proto1.RegisterType((*NamespaceID)(nil), "felix.NamespaceID")
 	proto1.RegisterType((*TunnelType)(nil), "felix.TunnelType")
 	proto1.RegisterType((*RouteUpdate)(nil), "felix.RouteUpdate")
+	proto1.RegisterType((*RouteNextHop)(nil), "felix.RouteNextHop")
 	proto1.RegisterType((*RouteRemove)(nil), "felix.RouteRemove")
 	proto1.RegisterType((*VXLANTunnelEndpointUpdate)(nil), "felix.VXLANTunnelEndpointUpdate")
 	proto1.RegisterType((*VXLANTunnelEndpointRemove)(nil), "felix.VXLANTunnelEndpointRemove")
@@ -4391,6 +4533,21 @@ func (m *IPSetUpdate) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintFelixbackend(dAtA, i, uint64(m.Type))
 	}
+	if len(m.Domains) > 0 {
+		for _, s := range m.Domains {
+			dAtA[i] = 0x22
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
 	return i, nil
 }
 
@@ -4765,6 +4922,16 @@ func (m *Policy) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.Namespace)))
 		i += copy(dAtA[i:], m.Namespace)
 	}
+	if m.AuditOnly {
+		dAtA[i] = 0x30
+		i++
+		if m.AuditOnly {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
 	return i, nil
 }
 
@@ -5671,6 +5838,26 @@ func (m *WorkloadEndpoint) MarshalTo(dAtA []byte) (int, error) {
 			i += n
 		}
 	}
+	if m.IngressBandwidth != 0 {
+		dAtA[i] = 0x50
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(m.IngressBandwidth))
+	}
+	if m.IngressBurst != 0 {
+		dAtA[i] = 0x58
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(m.IngressBurst))
+	}
+	if m.EgressBandwidth != 0 {
+		dAtA[i] = 0x60
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(m.EgressBandwidth))
+	}
+	if m.EgressBurst != 0 {
+		dAtA[i] = 0x68
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(m.EgressBurst))
+	}
 	return i, nil
 }
 
@@ -6200,6 +6387,12 @@ func (m *WireguardStatusUpdate) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.PublicKey)))
 		i += copy(dAtA[i:], m.PublicKey)
 	}
+	if len(m.InterfaceIpv6Addr) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.InterfaceIpv6Addr)))
+		i += copy(dAtA[i:], m.InterfaceIpv6Addr)
+	}
 	return i, nil
 }
 
@@ -6686,6 +6879,53 @@ func (m *RouteUpdate) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i += n72
 	}
+	if len(m.NextHops) > 0 {
+		for _, msg := range m.NextHops {
+			dAtA[i] = 0x5a
+			i++
+			i = encodeVarintFelixbackend(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	return i, nil
+}
+
+func (m *RouteNextHop) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RouteNextHop) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.NodeName) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.NodeName)))
+		i += copy(dAtA[i:], m.NodeName)
+	}
+	if len(m.NodeIp) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.NodeIp)))
+		i += copy(dAtA[i:], m.NodeIp)
+	}
+	if m.Weight != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(m.Weight))
+	}
 	return i, nil
 }
 
@@ -6752,6 +6992,24 @@ func (m *VXLANTunnelEndpointUpdate) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.ParentDeviceIp)))
 		i += copy(dAtA[i:], m.ParentDeviceIp)
 	}
+	if len(m.MacV6) > 0 {
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.MacV6)))
+		i += copy(dAtA[i:], m.MacV6)
+	}
+	if len(m.Ipv6Addr) > 0 {
+		dAtA[i] = 0x32
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.Ipv6Addr)))
+		i += copy(dAtA[i:], m.Ipv6Addr)
+	}
+	if len(m.ParentDeviceIpv6) > 0 {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.ParentDeviceIpv6)))
+		i += copy(dAtA[i:], m.ParentDeviceIpv6)
+	}
 	return i, nil
 }
 
@@ -6812,6 +7070,12 @@ func (m *WireguardEndpointUpdate) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.InterfaceIpv4Addr)))
 		i += copy(dAtA[i:], m.InterfaceIpv4Addr)
 	}
+	if len(m.InterfaceIpv6Addr) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintFelixbackend(dAtA, i, uint64(len(m.InterfaceIpv6Addr)))
+		i += copy(dAtA[i:], m.InterfaceIpv6Addr)
+	}
 	return i, nil
 }
 
@@ -7268,6 +7532,12 @@ func (m *IPSetUpdate) Size() (n int) {
 	if m.Type != 0 {
 		n += 1 + sovFelixbackend(uint64(m.Type))
 	}
+	if len(m.Domains) > 0 {
+		for _, s := range m.Domains {
+			l = len(s)
+			n += 1 + l + sovFelixbackend(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -7418,6 +7688,9 @@ func (m *Policy) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovFelixbackend(uint64(l))
 	}
+	if m.AuditOnly {
+		n += 2
+	}
 	return n
 }
 
@@ -7818,6 +8091,18 @@ func (m *WorkloadEndpoint) Size() (n int) {
 			n += 1 + l + sovFelixbackend(uint64(l))
 		}
 	}
+	if m.IngressBandwidth != 0 {
+		n += 1 + sovFelixbackend(uint64(m.IngressBandwidth))
+	}
+	if m.IngressBurst != 0 {
+		n += 1 + sovFelixbackend(uint64(m.IngressBurst))
+	}
+	if m.EgressBandwidth != 0 {
+		n += 1 + sovFelixbackend(uint64(m.EgressBandwidth))
+	}
+	if m.EgressBurst != 0 {
+		n += 1 + sovFelixbackend(uint64(m.EgressBurst))
+	}
 	return n
 }
 
@@ -8031,6 +8316,10 @@ func (m *WireguardStatusUpdate) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovFelixbackend(uint64(l))
 	}
+	l = len(m.InterfaceIpv6Addr)
+	if l > 0 {
+		n += 1 + l + sovFelixbackend(uint64(l))
+	}
 	return n
 }
 
@@ -8228,6 +8517,29 @@ func (m *RouteUpdate) Size() (n int) {
 		l = m.TunnelType.Size()
 		n += 1 + l + sovFelixbackend(uint64(l))
 	}
+	if len(m.NextHops) > 0 {
+		for _, e := range m.NextHops {
+			l = e.Size()
+			n += 1 + l + sovFelixbackend(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *RouteNextHop) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.NodeName)
+	if l > 0 {
+		n += 1 + l + sovFelixbackend(uint64(l))
+	}
+	l = len(m.NodeIp)
+	if l > 0 {
+		n += 1 + l + sovFelixbackend(uint64(l))
+	}
+	if m.Weight != 0 {
+		n += 1 + sovFelixbackend(uint64(m.Weight))
+	}
 	return n
 }
 
@@ -8260,6 +8572,18 @@ func (m *VXLANTunnelEndpointUpdate) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovFelixbackend(uint64(l))
 	}
+	l = len(m.MacV6)
+	if l > 0 {
+		n += 1 + l + sovFelixbackend(uint64(l))
+	}
+	l = len(m.Ipv6Addr)
+	if l > 0 {
+		n += 1 + l + sovFelixbackend(uint64(l))
+	}
+	l = len(m.ParentDeviceIpv6)
+	if l > 0 {
+		n += 1 + l + sovFelixbackend(uint64(l))
+	}
 	return n
 }
 
@@ -8288,6 +8612,10 @@ func (m *WireguardEndpointUpdate) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovFelixbackend(uint64(l))
 	}
+	l = len(m.InterfaceIpv6Addr)
+	if l > 0 {
+		n += 1 + l + sovFelixbackend(uint64(l))
+	}
 	return n
 }
 
@@ -9932,6 +10260,35 @@ func (m *IPSetUpdate) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Domains", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthFelixbackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Domains = append(m.Domains, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipFelixbackend(dAtA[iNdEx:])
@@ -11026,6 +11383,26 @@ func (m *Policy) Unmarshal(dAtA []byte) error {
 			}
 			m.Namespace = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AuditOnly", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.AuditOnly = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipFelixbackend(dAtA[iNdEx:])
@@ -13440,12 +13817,88 @@ func (m *WorkloadEndpoint) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipFelixbackend(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IngressBandwidth", wireType)
+			}
+			m.IngressBandwidth = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.IngressBandwidth |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IngressBurst", wireType)
+			}
+			m.IngressBurst = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.IngressBurst |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EgressBandwidth", wireType)
+			}
+			m.EgressBandwidth = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.EgressBandwidth |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EgressBurst", wireType)
+			}
+			m.EgressBurst = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.EgressBurst |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipFelixbackend(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
 			if skippy < 0 {
 				return ErrInvalidLengthFelixbackend
 			}
@@ -14982,6 +15435,35 @@ func (m *WireguardStatusUpdate) Unmarshal(dAtA []byte) error {
 			}
 			m.PublicKey = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InterfaceIpv6Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthFelixbackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.InterfaceIpv6Addr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipFelixbackend(dAtA[iNdEx:])
@@ -16621,6 +17103,164 @@ func (m *RouteUpdate) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NextHops", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthFelixbackend
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.NextHops = append(m.NextHops, &RouteNextHop{})
+			if err := m.NextHops[len(m.NextHops)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipFelixbackend(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthFelixbackend
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *RouteNextHop) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowFelixbackend
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: RouteNextHop: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: RouteNextHop: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NodeName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthFelixbackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.NodeName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NodeIp", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthFelixbackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.NodeIp = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Weight", wireType)
+			}
+			m.Weight = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Weight |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipFelixbackend(dAtA[iNdEx:])
@@ -16866,6 +17506,93 @@ func (m *VXLANTunnelEndpointUpdate) Unmarshal(dAtA []byte) error {
 			}
 			m.ParentDeviceIp = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MacV6", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthFelixbackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MacV6 = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ipv6Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthFelixbackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ipv6Addr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ParentDeviceIpv6", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthFelixbackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ParentDeviceIpv6 = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipFelixbackend(dAtA[iNdEx:])
@@ -17082,6 +17809,35 @@ func (m *WireguardEndpointUpdate) Unmarshal(dAtA []byte) error {
 			}
 			m.InterfaceIpv4Addr = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InterfaceIpv6Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowFelixbackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthFelixbackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.InterfaceIpv6Addr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipFelixbackend(dAtA[iNdEx:])
@@ -17398,220 +18154,232 @@ var (
 func init() { proto1.RegisterFile("felixbackend.proto", fileDescriptorFelixbackend) }
 
 var fileDescriptorFelixbackend = []byte{
-	// 3427 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x5a, 0xdd, 0x6e, 0x1c, 0xc7,
-	0x95, 0x66, 0x0f, 0xc9, 0xe1, 0xcc, 0x99, 0x1f, 0xb6, 0x8a, 0x22, 0x39, 0xa4, 0x24, 0x4a, 0x6e,
-	0x59, 0x10, 0xad, 0x85, 0x25, 0x81, 0x96, 0x28, 0xcb, 0x0b, 0xc8, 0x20, 0x39, 0xb4, 0x38, 0xb6,
-	0x34, 0x24, 0x9a, 0xb4, 0xbc, 0x5e, 0x18, 0xe8, 0x6d, 0x4e, 0x17, 0xc9, 0x5e, 0xf5, 0x74, 0xb7,
-	0xbb, 0x6b, 0xf8, 0xb3, 0x7b, 0x95, 0x20, 0x17, 0x49, 0x80, 0x20, 0xb9, 0x0a, 0xf2, 0x00, 0x41,
-	0xae, 0xf2, 0x06, 0xb9, 0x0e, 0x60, 0xdf, 0xe5, 0x05, 0x02, 0x24, 0xce, 0x13, 0xe4, 0x0d, 0x82,
-	0xfa, 0xed, 0xdf, 0xa1, 0xa4, 0x20, 0xc8, 0x15, 0xa7, 0xce, 0xf9, 0xce, 0x57, 0xa7, 0x4e, 0x9d,
-	0xae, 0x3a, 0x55, 0x45, 0x40, 0x47, 0xd8, 0x73, 0xcf, 0x0f, 0xed, 0xc1, 0x6b, 0xec, 0x3b, 0xf7,
-	0xc3, 0x28, 0x20, 0x01, 0x9a, 0x66, 0x32, 0xa3, 0x05, 0x8d, 0xfd, 0x0b, 0x7f, 0x60, 0xe2, 0x6f,
-	0x47, 0x38, 0x26, 0xc6, 0x5f, 0x75, 0x68, 0x1c, 0x04, 0x5d, 0x9b, 0xd8, 0xa1, 0x67, 0xfb, 0x18,
-	0xad, 0xc2, 0x8c, 0xeb, 0x5b, 0xf1, 0x85, 0x3f, 0xe8, 0x68, 0xb7, 0xb4, 0xd5, 0xc6, 0x5a, 0xeb,
-	0x3e, 0xb3, 0xbb, 0xdf, 0xf3, 0xa9, 0xd9, 0xce, 0x84, 0x59, 0x75, 0xd9, 0x2f, 0xf4, 0x04, 0x9a,
-	0x6e, 0x18, 0x63, 0x62, 0x8d, 0x42, 0xc7, 0x26, 0xb8, 0x53, 0x61, 0x70, 0x24, 0xe1, 0x7b, 0xfb,
-	0x98, 0x7c, 0xc9, 0x34, 0x3b, 0x13, 0x66, 0x83, 0x21, 0x79, 0x13, 0x3d, 0x07, 0xc4, 0x0d, 0x1d,
-	0xec, 0x11, 0x5b, 0x9a, 0x4f, 0x32, 0xf3, 0xc5, 0xb4, 0x79, 0x97, 0xea, 0x15, 0x87, 0xce, 0x8c,
-	0x52, 0xb2, 0xc4, 0x83, 0x08, 0x0f, 0x83, 0x53, 0xdc, 0x99, 0x2a, 0x7a, 0x60, 0x32, 0x8d, 0xf2,
-	0x80, 0x37, 0xd1, 0x1e, 0xcc, 0xdb, 0x03, 0xe2, 0x9e, 0x62, 0x2b, 0x8c, 0x82, 0x23, 0xd7, 0xc3,
-	0xd2, 0x89, 0x69, 0xc6, 0xb0, 0x2c, 0x18, 0x36, 0x18, 0x66, 0x8f, 0x43, 0x94, 0x1f, 0x73, 0x76,
-	0x51, 0x5c, 0xc2, 0x28, 0x7c, 0xaa, 0x8e, 0x67, 0x54, 0xbe, 0x65, 0x19, 0x85, 0x8f, 0x2f, 0xe1,
-	0xaa, 0x64, 0x0c, 0x3c, 0x77, 0x70, 0x21, 0x5d, 0x9c, 0x61, 0x84, 0x4b, 0x59, 0x42, 0x86, 0x50,
-	0x1e, 0x22, 0xbb, 0x20, 0x2d, 0xd2, 0x09, 0xff, 0x6a, 0x63, 0xe9, 0x94, 0x7b, 0x19, 0xba, 0xc4,
-	0xbb, 0x93, 0x20, 0x26, 0x16, 0xf6, 0x9d, 0x30, 0x70, 0x7d, 0x95, 0x04, 0xf5, 0x0c, 0xdd, 0x4e,
-	0x10, 0x93, 0x6d, 0x81, 0x48, 0xbc, 0x3b, 0x29, 0x48, 0x8b, 0x74, 0xc2, 0x3b, 0x18, 0x4b, 0x97,
-	0x78, 0x77, 0x52, 0x90, 0xa2, 0xaf, 0xa1, 0x73, 0x16, 0x44, 0xaf, 0xbd, 0xc0, 0x76, 0x0a, 0x1e,
-	0x36, 0x18, 0xe5, 0x0d, 0x41, 0xf9, 0x95, 0x80, 0x15, 0xbc, 0x5c, 0x38, 0x2b, 0xd5, 0x94, 0x53,
-	0x0b, 0x6f, 0x9b, 0x97, 0x52, 0x2b, 0x8f, 0x0b, 0xd4, 0xc2, 0xeb, 0x4f, 0xa0, 0x35, 0x08, 0xfc,
-	0x23, 0xf7, 0x58, 0xba, 0xda, 0x62, 0x7c, 0x73, 0x82, 0x6f, 0x8b, 0xe9, 0x94, 0x83, 0xcd, 0x41,
-	0xaa, 0xad, 0x02, 0x38, 0xc4, 0xc4, 0x76, 0xec, 0xe4, 0xab, 0x6a, 0x17, 0x02, 0xf8, 0x52, 0x20,
-	0xb2, 0xf3, 0x91, 0x95, 0xa2, 0xbb, 0x30, 0x1b, 0xd3, 0x05, 0xc2, 0x1f, 0x60, 0xcb, 0x1f, 0x0d,
-	0x0f, 0x71, 0xd4, 0x99, 0xbd, 0xa5, 0xad, 0x4e, 0x99, 0x6d, 0x29, 0xee, 0x33, 0x29, 0xda, 0x00,
-	0xdd, 0x0d, 0xed, 0xa1, 0x15, 0x06, 0x81, 0x27, 0xfb, 0xd4, 0x59, 0x9f, 0xf3, 0xea, 0x33, 0xdc,
-	0x78, 0xb9, 0x17, 0x04, 0x9e, 0xea, 0xaf, 0x4d, 0x0d, 0x12, 0x49, 0x96, 0x42, 0x44, 0xf2, 0x4a,
-	0x29, 0x85, 0x8a, 0xa0, 0xa2, 0xc8, 0x65, 0xa3, 0x1a, 0xbd, 0xa0, 0x41, 0x63, 0x47, 0x9f, 0x4d,
-	0x9f, 0xac, 0x14, 0xed, 0xc3, 0x42, 0x8c, 0xa3, 0x53, 0x77, 0x80, 0x2d, 0x7b, 0x30, 0x08, 0x46,
-	0x49, 0xf2, 0xcc, 0x31, 0xc2, 0x6b, 0x82, 0x70, 0x9f, 0x83, 0x36, 0x38, 0x46, 0x0d, 0xf0, 0x6a,
-	0x5c, 0x22, 0x2f, 0x23, 0x15, 0x5e, 0x5e, 0xbd, 0x84, 0x54, 0xf9, 0x99, 0x23, 0x15, 0x9e, 0x6e,
-	0x81, 0xee, 0xdb, 0x43, 0x1c, 0x87, 0xf6, 0x40, 0xad, 0x61, 0xf3, 0x8c, 0x6e, 0x41, 0xd0, 0xf5,
-	0xa5, 0x5a, 0xb9, 0x37, 0xeb, 0x67, 0x45, 0x59, 0x12, 0xe1, 0xd3, 0x42, 0x39, 0x89, 0x72, 0x27,
-	0x21, 0x11, 0x9e, 0x3c, 0x81, 0x66, 0x14, 0x8c, 0x88, 0xf2, 0x62, 0x31, 0xb3, 0x16, 0x9b, 0x54,
-	0x95, 0xec, 0x06, 0x51, 0xd2, 0x4c, 0x0c, 0x45, 0xcf, 0x9d, 0xa2, 0x61, 0xb2, 0x88, 0x47, 0x49,
-	0x13, 0x6d, 0x41, 0xe3, 0x94, 0xe0, 0x50, 0x76, 0xb8, 0xc4, 0xec, 0x6e, 0x09, 0xbb, 0x57, 0xff,
-	0xf5, 0x62, 0xa3, 0x7f, 0x30, 0xf2, 0x7d, 0xec, 0x15, 0x3e, 0x6d, 0xa0, 0x66, 0x6a, 0xec, 0x9c,
-	0x44, 0x74, 0xbe, 0xfc, 0x26, 0x12, 0xe5, 0x0a, 0x23, 0x11, 0x9e, 0x7c, 0x03, 0x4b, 0x67, 0x6e,
-	0x84, 0x8f, 0x47, 0x76, 0x54, 0x5c, 0x6f, 0xae, 0x31, 0xca, 0x15, 0xb9, 0x28, 0x48, 0x5c, 0xc1,
-	0xab, 0xc5, 0xb3, 0x72, 0xd5, 0x18, 0x76, 0xe1, 0xf0, 0xf5, 0xcb, 0xd9, 0x95, 0xbb, 0x45, 0x76,
-	0xe1, 0xfb, 0x57, 0xd0, 0x39, 0xf6, 0x82, 0x43, 0xdb, 0xb3, 0x0e, 0x8f, 0x43, 0x2b, 0xbb, 0xfe,
-	0xdc, 0x60, 0xe4, 0xd7, 0x05, 0xf9, 0x73, 0x06, 0xdb, 0x7c, 0xbe, 0x97, 0x5b, 0x88, 0xe6, 0xb9,
-	0xfd, 0xe6, 0x71, 0x98, 0x56, 0x6c, 0xd6, 0x61, 0x26, 0xb4, 0x2f, 0xe8, 0x32, 0x67, 0xfc, 0x62,
-	0x1a, 0x5a, 0x9f, 0x45, 0xc1, 0x30, 0xa9, 0x32, 0xf6, 0x60, 0x3e, 0x8c, 0x82, 0x01, 0x8e, 0x63,
-	0x2b, 0x26, 0x36, 0x19, 0xc5, 0xd9, 0x2a, 0x40, 0x6e, 0x97, 0x7b, 0x1c, 0xb3, 0xcf, 0x20, 0xc9,
-	0x06, 0x1c, 0x16, 0xc5, 0xe8, 0x7f, 0xe0, 0x5a, 0x76, 0x07, 0xc9, 0xf2, 0xf2, 0xd2, 0xe0, 0x66,
-	0xc9, 0x46, 0x92, 0x23, 0xef, 0x9c, 0x8c, 0xd1, 0x8d, 0xed, 0x41, 0xcc, 0xc4, 0xf4, 0x1b, 0x7a,
-	0x50, 0x53, 0x51, 0xd2, 0x83, 0x98, 0x0b, 0x0f, 0x6e, 0x16, 0xf7, 0x96, 0xec, 0x38, 0x78, 0x39,
-	0x71, 0x7b, 0xcc, 0x16, 0x93, 0x1b, 0xcb, 0xf5, 0xb3, 0x4b, 0xf4, 0x97, 0xf6, 0x26, 0xc6, 0x34,
-	0xf3, 0x16, 0xbd, 0xa9, 0x71, 0x8d, 0xe9, 0x4d, 0x8c, 0xad, 0x64, 0x47, 0xa9, 0x95, 0xee, 0x28,
-	0xaf, 0x20, 0xc9, 0xd5, 0xdc, 0xe0, 0xeb, 0x99, 0x7c, 0x54, 0xc9, 0x9e, 0x1b, 0xf5, 0xfc, 0x59,
-	0x99, 0x22, 0x9d, 0x8f, 0x3f, 0xd6, 0xa0, 0x99, 0xce, 0x55, 0xf4, 0x04, 0xaa, 0x3c, 0xf3, 0x3b,
-	0xda, 0xad, 0xc9, 0xd4, 0x2c, 0xa6, 0x41, 0xa2, 0xb1, 0xed, 0x93, 0xe8, 0xc2, 0x14, 0xf0, 0xe5,
-	0xa7, 0xd0, 0x48, 0x89, 0x91, 0x0e, 0x93, 0xaf, 0xf1, 0x05, 0x2b, 0x9c, 0xeb, 0x26, 0xfd, 0x89,
-	0xae, 0xc2, 0xf4, 0xa9, 0xed, 0x8d, 0x78, 0x75, 0x5c, 0x37, 0x79, 0xe3, 0x93, 0xca, 0xc7, 0x9a,
-	0x51, 0x83, 0x2a, 0x2f, 0xa9, 0x8d, 0xdf, 0x68, 0xd0, 0x48, 0x95, 0xcb, 0xa8, 0x0d, 0x15, 0xd7,
-	0x11, 0x24, 0x15, 0xd7, 0x41, 0x1d, 0x98, 0x19, 0x62, 0x1a, 0x9b, 0xb8, 0x53, 0xb9, 0x35, 0xb9,
-	0x5a, 0x37, 0x65, 0x13, 0x3d, 0x84, 0x29, 0x72, 0x11, 0xf2, 0xaf, 0xa6, 0xad, 0x02, 0x93, 0xe2,
-	0xe2, 0xbf, 0x0f, 0x2e, 0x42, 0x6c, 0x32, 0xa4, 0xf1, 0x21, 0xd4, 0x95, 0x08, 0x55, 0xa1, 0xd2,
-	0xdb, 0xd3, 0x27, 0xd0, 0x2c, 0xed, 0xdf, 0xda, 0xe8, 0x77, 0xad, 0xbd, 0x5d, 0xf3, 0x40, 0xd7,
-	0xd0, 0x0c, 0x4c, 0xf6, 0xb7, 0x0f, 0xf4, 0x8a, 0x11, 0x82, 0x9e, 0xaf, 0xc4, 0x0b, 0xee, 0xdd,
-	0x86, 0x96, 0xed, 0x38, 0xd8, 0xb1, 0xb2, 0x4e, 0x36, 0x99, 0xf0, 0xa5, 0xf0, 0xf4, 0x2e, 0xcc,
-	0xf2, 0x9c, 0x4a, 0x60, 0x93, 0x0c, 0xd6, 0x16, 0x62, 0x01, 0x34, 0x6e, 0x88, 0x58, 0x88, 0xb4,
-	0xc9, 0x75, 0x66, 0xd8, 0x30, 0x57, 0x52, 0x95, 0xa3, 0x5b, 0x0a, 0xd6, 0x58, 0xd3, 0x93, 0xc5,
-	0x83, 0x22, 0x7a, 0x5d, 0xe6, 0xe5, 0x2a, 0xcc, 0x88, 0xca, 0x5c, 0x1c, 0x54, 0xda, 0x59, 0x98,
-	0x29, 0xd5, 0xc6, 0x93, 0x5c, 0x17, 0xc2, 0x93, 0x37, 0x76, 0x61, 0xdc, 0x84, 0xba, 0x12, 0x20,
-	0x04, 0x53, 0x74, 0x8b, 0x14, 0xae, 0xb3, 0xdf, 0x46, 0x00, 0x33, 0x02, 0x80, 0x1e, 0x42, 0xcb,
-	0xf5, 0x0f, 0x83, 0x91, 0xef, 0x58, 0xd1, 0xc8, 0xc3, 0xb1, 0x48, 0xbc, 0x86, 0xdc, 0xf6, 0x46,
-	0x1e, 0x36, 0x9b, 0x02, 0x41, 0x1b, 0x31, 0x5a, 0x83, 0x76, 0x30, 0x22, 0x69, 0x93, 0x4a, 0xd1,
-	0xa4, 0x25, 0x21, 0xcc, 0xc6, 0xf8, 0x06, 0x50, 0xf1, 0x80, 0x80, 0x6e, 0xa6, 0x46, 0x32, 0x2b,
-	0x47, 0xc2, 0x00, 0x22, 0x56, 0x77, 0xa0, 0xca, 0x0f, 0x09, 0x22, 0x54, 0xad, 0x0c, 0xc8, 0x14,
-	0x4a, 0xe3, 0x71, 0x96, 0x5d, 0xc4, 0xe9, 0x4d, 0xec, 0xc6, 0x1a, 0xd4, 0x64, 0x9b, 0x46, 0x89,
-	0xb8, 0x38, 0x92, 0x51, 0xa2, 0xbf, 0x55, 0xe4, 0x2a, 0xa9, 0xc8, 0xfd, 0x51, 0x83, 0x2a, 0x37,
-	0xfa, 0xf7, 0x44, 0x0e, 0x5d, 0x87, 0xfa, 0xc8, 0x27, 0x11, 0x3d, 0x40, 0x3b, 0xec, 0xf3, 0xaa,
-	0x99, 0x89, 0x00, 0x2d, 0x41, 0x2d, 0x8c, 0xb0, 0xe5, 0xf8, 0x36, 0x61, 0x3b, 0x4b, 0x8d, 0x66,
-	0x0f, 0xee, 0xfa, 0x36, 0xa1, 0x86, 0xaa, 0x34, 0x62, 0x7b, 0x42, 0xdd, 0x4c, 0x04, 0xc6, 0xcf,
-	0xdb, 0x30, 0x45, 0x3b, 0x40, 0x0b, 0x50, 0xa5, 0xa7, 0xaa, 0xc0, 0x17, 0x43, 0x17, 0x2d, 0xf4,
-	0x00, 0xc0, 0x0d, 0xad, 0x53, 0x1c, 0xc5, 0x54, 0x57, 0x61, 0xdf, 0xb5, 0xae, 0xbe, 0xeb, 0x57,
-	0x5c, 0x6e, 0xd6, 0xdd, 0x50, 0xfc, 0x44, 0xff, 0x41, 0x5d, 0x09, 0x48, 0x30, 0x08, 0x3c, 0xb1,
-	0x79, 0xce, 0x26, 0xc9, 0xc9, 0xc4, 0xa6, 0x02, 0xa0, 0x45, 0x98, 0x89, 0xa3, 0x81, 0xe5, 0x63,
-	0xea, 0x36, 0xfd, 0xfa, 0xaa, 0x71, 0x34, 0xe8, 0x63, 0x82, 0x3e, 0x84, 0x3a, 0x55, 0x84, 0x41,
-	0x44, 0xe2, 0xce, 0x34, 0x8b, 0x8e, 0xca, 0xf1, 0x20, 0x22, 0xa6, 0xed, 0x1f, 0x63, 0xb3, 0x16,
-	0x47, 0x03, 0xda, 0x8a, 0x29, 0x8f, 0x13, 0x13, 0xc6, 0x53, 0xe5, 0x3c, 0x4e, 0x4c, 0x04, 0x0f,
-	0x55, 0x70, 0x9e, 0x99, 0x71, 0x3c, 0x4e, 0x4c, 0x38, 0xcf, 0x0d, 0xa8, 0xbb, 0x83, 0x61, 0x68,
-	0xb1, 0x45, 0x8c, 0x6e, 0x07, 0xd3, 0x3b, 0x13, 0x66, 0x8d, 0x8a, 0xd8, 0xfa, 0xf4, 0x0c, 0xda,
-	0x4a, 0x6d, 0x0d, 0x02, 0x47, 0xee, 0x00, 0xb2, 0x2c, 0xed, 0x09, 0xe0, 0x86, 0xef, 0x6c, 0x05,
-	0x0e, 0x3b, 0x14, 0x49, 0x5b, 0xda, 0x46, 0xb7, 0xa1, 0x4d, 0x47, 0xe5, 0x86, 0x56, 0x8c, 0x89,
-	0xe5, 0x3a, 0x71, 0x07, 0x98, 0xb7, 0x8d, 0x38, 0x1a, 0xf4, 0xc2, 0x7d, 0x4c, 0x7a, 0x4e, 0x4c,
-	0x41, 0xd4, 0xe5, 0x14, 0xa8, 0xc1, 0x41, 0x4e, 0x4c, 0x14, 0xe8, 0x09, 0x2c, 0xb1, 0xc0, 0xd9,
-	0x43, 0xec, 0xb0, 0xd1, 0xa5, 0xf1, 0x4d, 0x86, 0xbf, 0x4a, 0x43, 0x49, 0xf5, 0x74, 0x68, 0x69,
-	0x43, 0x16, 0xa9, 0x52, 0xc3, 0x16, 0x37, 0xa4, 0xb1, 0x2b, 0x18, 0xae, 0x41, 0xd3, 0x0f, 0x88,
-	0xa5, 0xe6, 0xf6, 0xa8, 0x7c, 0x6e, 0x1b, 0x7e, 0x40, 0x64, 0x03, 0xad, 0x00, 0x6d, 0x5a, 0x72,
-	0x8a, 0x8f, 0x19, 0x7d, 0xdd, 0x0f, 0xc8, 0x3e, 0x9f, 0xe5, 0x47, 0xd0, 0x92, 0x7a, 0x3e, 0x43,
-	0x27, 0x63, 0x66, 0xa8, 0xc1, 0x6d, 0xf8, 0x24, 0x09, 0x56, 0x39, 0xe1, 0xae, 0x62, 0xed, 0xf2,
-	0x39, 0x17, 0xac, 0xc9, 0xbc, 0xff, 0xef, 0x25, 0xac, 0x5d, 0x39, 0xf5, 0xef, 0x73, 0xab, 0x64,
-	0xfa, 0x5f, 0xb3, 0xe9, 0xd7, 0x18, 0x4a, 0x4e, 0x2c, 0xda, 0x06, 0x94, 0x41, 0xf1, 0x2c, 0xf0,
-	0x2e, 0xcd, 0x02, 0xcd, 0x9c, 0x4d, 0x51, 0xb0, 0x44, 0xb8, 0xc7, 0x69, 0x72, 0xc9, 0x30, 0xe4,
-	0x1b, 0x10, 0x1f, 0xab, 0x0a, 0xbc, 0xc0, 0xe6, 0x72, 0xc2, 0x57, 0xd8, 0x6e, 0x2a, 0x2d, 0x9e,
-	0xc1, 0x0d, 0x15, 0xf0, 0xd2, 0x19, 0x0e, 0x99, 0xd9, 0xa2, 0x98, 0x82, 0xc2, 0x24, 0x0b, 0xfb,
-	0xf1, 0x19, 0xf2, 0xad, 0xb2, 0xef, 0x96, 0x27, 0xc9, 0x7c, 0x10, 0xb9, 0xc7, 0xae, 0x6f, 0x7b,
-	0xcc, 0x89, 0x18, 0x7b, 0x78, 0x40, 0x82, 0xa8, 0x13, 0xb1, 0x45, 0x65, 0x4e, 0x2a, 0xf7, 0xa3,
-	0xc1, 0xbe, 0x50, 0x65, 0x6c, 0x68, 0xc7, 0xca, 0x26, 0xce, 0xda, 0x74, 0x63, 0xa2, 0x6c, 0xb6,
-	0xe1, 0x66, 0xa6, 0x9f, 0xe4, 0xb8, 0xa8, 0xac, 0x09, 0xb3, 0xbe, 0x9e, 0xea, 0x51, 0x1d, 0x1a,
-	0x4b, 0x69, 0xe4, 0x98, 0x73, 0x34, 0xa3, 0x2c, 0x8d, 0x18, 0x75, 0x96, 0xe6, 0x29, 0x2c, 0x29,
-	0x1a, 0x19, 0x7e, 0x45, 0x70, 0xca, 0x08, 0x16, 0x24, 0xa0, 0xcf, 0x22, 0x3f, 0xd6, 0x34, 0x13,
-	0x80, 0xb3, 0x82, 0x69, 0x3a, 0x06, 0x5f, 0xf2, 0x25, 0x20, 0x7f, 0x86, 0x1f, 0xda, 0x64, 0x70,
-	0xd2, 0x39, 0xcf, 0x1c, 0x5b, 0xb2, 0x47, 0xf8, 0x97, 0x14, 0x61, 0x2e, 0xc4, 0xd4, 0x8d, 0x82,
-	0x9c, 0xd2, 0x72, 0x27, 0xca, 0x68, 0x2f, 0xde, 0x4c, 0xeb, 0x50, 0x17, 0x8b, 0xb4, 0x0f, 0x00,
-	0x4e, 0x08, 0x09, 0x05, 0xcf, 0xff, 0x65, 0xaa, 0x96, 0x9d, 0x83, 0x83, 0x3d, 0x6e, 0x5d, 0xa7,
-	0x18, 0x69, 0x50, 0x93, 0xb7, 0x27, 0x9d, 0xff, 0xcf, 0xdc, 0x3b, 0xd1, 0xfd, 0x4a, 0x5d, 0x90,
-	0x28, 0x10, 0xad, 0x4a, 0xe9, 0x66, 0x6a, 0xb9, 0x4e, 0xe7, 0x7b, 0xb1, 0x87, 0xd1, 0x76, 0xcf,
-	0xd9, 0xac, 0xc2, 0x14, 0xfd, 0x60, 0x37, 0x01, 0x6a, 0xf2, 0xe3, 0xfd, 0xbc, 0x5a, 0xfb, 0x4e,
-	0xd3, 0xbf, 0xd7, 0x4c, 0xf0, 0x82, 0x63, 0x2b, 0x8c, 0xf0, 0x91, 0x7b, 0x6e, 0x3c, 0x87, 0xb9,
-	0x32, 0xd7, 0x97, 0xa1, 0xa6, 0xa6, 0x84, 0x13, 0xab, 0x36, 0x2d, 0xa7, 0x59, 0xd2, 0x88, 0x1a,
-	0x93, 0x37, 0x8c, 0xdf, 0x6a, 0x50, 0x57, 0x83, 0xe2, 0xe5, 0x32, 0x39, 0x09, 0x1c, 0x5e, 0x1a,
-	0xb0, 0x72, 0x99, 0x35, 0xd1, 0x43, 0x98, 0x0e, 0x6d, 0x72, 0x22, 0xf7, 0xff, 0xe5, 0x7c, 0x3c,
-	0xee, 0xef, 0xd9, 0xe4, 0x84, 0x47, 0x86, 0x03, 0x97, 0xbf, 0x80, 0xba, 0x92, 0xa1, 0x05, 0x98,
-	0xc6, 0xe7, 0xf6, 0x80, 0x70, 0xaf, 0x76, 0x26, 0x4c, 0xde, 0x44, 0x1d, 0xa8, 0xf2, 0x11, 0xf1,
-	0x92, 0x65, 0x67, 0xc2, 0x14, 0xed, 0xcd, 0x26, 0x00, 0xe5, 0xe1, 0xb3, 0x60, 0xfc, 0x5a, 0x83,
-	0x66, 0x3a, 0x98, 0xe8, 0x33, 0x68, 0xd8, 0xbe, 0x1f, 0x10, 0x9b, 0x6e, 0xfd, 0xb2, 0x90, 0x79,
-	0xbf, 0x24, 0xec, 0xf7, 0x37, 0x12, 0x18, 0x3f, 0x80, 0xa4, 0x0d, 0x97, 0x9f, 0x81, 0x9e, 0x07,
-	0xbc, 0xd3, 0x51, 0xe4, 0x29, 0xcc, 0xe6, 0x16, 0x51, 0x56, 0x98, 0xd1, 0x55, 0x99, 0xda, 0x4f,
-	0xf3, 0xb3, 0x03, 0x95, 0xb1, 0xe5, 0xb7, 0xc2, 0x65, 0xf4, 0xb7, 0xf1, 0x02, 0x6a, 0x6a, 0xfb,
-	0xe9, 0x40, 0x55, 0x9c, 0xec, 0x34, 0xb1, 0x95, 0x8b, 0x36, 0xba, 0x9a, 0x2e, 0xe9, 0x76, 0x26,
-	0x78, 0x51, 0xb7, 0xa9, 0x43, 0x9b, 0xeb, 0xad, 0x20, 0x62, 0x6b, 0x81, 0xf1, 0x18, 0xea, 0x6a,
-	0xbb, 0xa0, 0xfe, 0x1e, 0xb9, 0x51, 0x4c, 0x84, 0x0f, 0xbc, 0x41, 0x9d, 0xf0, 0xec, 0x98, 0x48,
-	0x27, 0xe8, 0x6f, 0xe3, 0x97, 0x1a, 0xa0, 0xfc, 0xe1, 0xb4, 0xd7, 0xa5, 0x67, 0x8e, 0x20, 0x1a,
-	0x9c, 0xe0, 0x98, 0x44, 0x36, 0x09, 0x22, 0x9a, 0xa9, 0x7c, 0xe8, 0xed, 0xb4, 0xb8, 0xe7, 0xa0,
-	0x9b, 0xd0, 0x50, 0x27, 0x61, 0x97, 0x97, 0x7b, 0x75, 0x13, 0xa4, 0x88, 0x03, 0xd4, 0x09, 0xd9,
-	0x75, 0x58, 0xc9, 0x57, 0x37, 0x41, 0x8a, 0x7a, 0xce, 0xe7, 0x53, 0x35, 0x4d, 0xaf, 0x98, 0x35,
-	0x7a, 0xb2, 0x67, 0x03, 0x39, 0x87, 0x85, 0xf2, 0x9b, 0x65, 0xf4, 0x41, 0xaa, 0x3c, 0x5e, 0x1a,
-	0x73, 0xb0, 0x16, 0x65, 0xf8, 0x47, 0x50, 0x93, 0x5d, 0x88, 0xdb, 0x85, 0xc5, 0x71, 0x57, 0xcb,
-	0x0a, 0x68, 0xfc, 0xae, 0x02, 0x7a, 0x5e, 0x4d, 0x43, 0x49, 0x4f, 0xd2, 0xf2, 0x34, 0xc2, 0x1b,
-	0x65, 0x85, 0x36, 0x4d, 0x9b, 0xa1, 0x3d, 0x10, 0x21, 0xa0, 0x3f, 0xe9, 0xd8, 0xe5, 0x93, 0x06,
-	0xdd, 0x91, 0x78, 0xdd, 0x08, 0x42, 0x44, 0x37, 0xa1, 0x6b, 0x50, 0x77, 0xc3, 0xd3, 0x47, 0xb4,
-	0x38, 0xe0, 0xb5, 0x63, 0xdd, 0xac, 0x51, 0x41, 0x1f, 0x13, 0xa9, 0x5c, 0xe7, 0xca, 0xaa, 0x52,
-	0xae, 0x33, 0xe5, 0x1d, 0x98, 0xa6, 0x15, 0xbf, 0xac, 0x14, 0x65, 0x71, 0x73, 0xe0, 0xe2, 0xa8,
-	0xe7, 0x1f, 0x05, 0x26, 0xd7, 0xa2, 0x0f, 0xa0, 0xc6, 0x3b, 0xb0, 0x49, 0xa7, 0xc6, 0x90, 0x6d,
-	0x75, 0x2f, 0x49, 0x18, 0x70, 0x86, 0xf5, 0x67, 0x13, 0x01, 0x5d, 0x67, 0xd0, 0xfa, 0x58, 0xe8,
-	0x7a, 0xdf, 0x26, 0xc6, 0x56, 0x71, 0x8a, 0xc4, 0x09, 0xe6, 0xed, 0xa7, 0xc8, 0xd8, 0x80, 0x76,
-	0xfa, 0xa6, 0xa7, 0xd7, 0xcd, 0xa7, 0x4a, 0xe5, 0x8d, 0xa9, 0xe2, 0x01, 0x2a, 0x3e, 0x93, 0xa0,
-	0x3b, 0x29, 0x1f, 0xe6, 0x4b, 0xee, 0x94, 0x44, 0x8a, 0x3c, 0x48, 0xa5, 0xc8, 0x64, 0x66, 0xd5,
-	0xce, 0xbc, 0x95, 0x24, 0xe9, 0xf1, 0xf7, 0x0a, 0x34, 0xd3, 0xaa, 0xb2, 0x73, 0x6a, 0x7e, 0xca,
-	0x2b, 0x85, 0x29, 0x57, 0x13, 0x37, 0x79, 0xe9, 0xc4, 0xdd, 0x87, 0x39, 0x7c, 0x1e, 0xe2, 0x01,
-	0xc1, 0x8e, 0xc5, 0x66, 0xd0, 0x76, 0x9c, 0x48, 0xa6, 0xd0, 0x15, 0xa9, 0xea, 0x85, 0xa7, 0x8f,
-	0x36, 0xa8, 0x22, 0x8f, 0x5f, 0x17, 0xf8, 0xe9, 0x02, 0x7e, 0x9d, 0xe3, 0x3f, 0x86, 0x59, 0x75,
-	0x26, 0xb3, 0xb8, 0x43, 0xd5, 0x72, 0x87, 0xda, 0x0a, 0x77, 0xc0, 0x3c, 0x7b, 0x0c, 0x6d, 0x79,
-	0x80, 0xb3, 0x2e, 0x4d, 0xc1, 0xa6, 0x38, 0xd7, 0x71, 0xb3, 0x47, 0xd0, 0x3a, 0x0a, 0xa2, 0x33,
-	0x3b, 0x92, 0xdd, 0xd5, 0xc6, 0x58, 0x09, 0x14, 0xb3, 0x32, 0xfe, 0x33, 0x3b, 0xc3, 0x22, 0xcb,
-	0xde, 0x6e, 0x86, 0x8d, 0x08, 0x6a, 0x92, 0xb6, 0x74, 0xae, 0x3e, 0x00, 0xdd, 0xf5, 0x8f, 0x23,
-	0x1c, 0xc7, 0xfc, 0x61, 0xcf, 0x55, 0x9b, 0xe3, 0xac, 0x90, 0xef, 0x09, 0x31, 0x5d, 0x0f, 0x71,
-	0x0e, 0x29, 0xee, 0x60, 0x70, 0x06, 0x68, 0x3c, 0x81, 0x19, 0xf1, 0xb9, 0xa0, 0x79, 0xa8, 0xe2,
-	0x73, 0x5a, 0x92, 0xca, 0xa5, 0x03, 0x9f, 0x93, 0x5e, 0x48, 0xc5, 0x2c, 0xc1, 0x43, 0xb9, 0x99,
-	0x50, 0x87, 0x43, 0xc3, 0x84, 0xb9, 0x92, 0x2b, 0x5b, 0x74, 0x1b, 0x5a, 0x6e, 0x1c, 0x58, 0xc4,
-	0x1d, 0xe2, 0x98, 0xd8, 0x43, 0xc9, 0xd5, 0x74, 0xe3, 0xe0, 0x40, 0xca, 0xe8, 0x89, 0x78, 0x14,
-	0x52, 0x08, 0xa3, 0xd4, 0x4c, 0xd1, 0x32, 0x42, 0xe8, 0x8c, 0xbb, 0xae, 0x7d, 0xdb, 0xaf, 0xe4,
-	0x43, 0xa8, 0xf2, 0x8b, 0x44, 0x71, 0x9f, 0x21, 0xa1, 0xb9, 0x8b, 0x4a, 0x01, 0x32, 0x56, 0xa1,
-	0x9d, 0xd5, 0x50, 0xdf, 0x04, 0x81, 0xa8, 0x74, 0x04, 0x72, 0xa3, 0xcc, 0xb7, 0x77, 0x9b, 0xdf,
-	0x73, 0xb8, 0x7e, 0xd9, 0x2d, 0xee, 0xbb, 0xec, 0x17, 0xef, 0x38, 0xcc, 0xde, 0xb8, 0x9e, 0xdf,
-	0x7d, 0x19, 0x5c, 0x87, 0xf9, 0xd2, 0xdb, 0x58, 0x74, 0x03, 0x20, 0x1c, 0x1d, 0x7a, 0xee, 0xc0,
-	0x4a, 0x8a, 0x91, 0x3a, 0x97, 0x7c, 0x81, 0x2f, 0x8c, 0x97, 0xfc, 0xcb, 0xc8, 0x3d, 0x3e, 0x2e,
-	0x83, 0x5a, 0x1d, 0x65, 0x01, 0x28, 0xdb, 0x6a, 0xb3, 0xa1, 0x2b, 0x83, 0xc8, 0x3d, 0xb6, 0x39,
-	0xd0, 0x05, 0x21, 0x4f, 0x27, 0xc6, 0xf1, 0x4f, 0xd3, 0x6d, 0x43, 0x3b, 0xfb, 0x78, 0x59, 0x72,
-	0xf5, 0x39, 0x15, 0x06, 0x81, 0x27, 0xe2, 0x3d, 0x9b, 0x7f, 0xae, 0x64, 0x4a, 0xe3, 0x56, 0x42,
-	0x33, 0xe6, 0x52, 0xf3, 0x19, 0xd4, 0x24, 0x82, 0x15, 0x59, 0xae, 0xa3, 0x6e, 0xc4, 0xe8, 0x6f,
-	0xb4, 0x02, 0x30, 0xb4, 0xe3, 0x6f, 0x47, 0x38, 0xb2, 0x45, 0xf9, 0x55, 0x33, 0x53, 0x12, 0xe3,
-	0x0f, 0x1a, 0x5c, 0x2d, 0x7b, 0x8b, 0x44, 0x77, 0x53, 0x53, 0xb8, 0x58, 0x7a, 0x8a, 0x10, 0xa9,
-	0xf3, 0x29, 0x54, 0x3d, 0xfb, 0x10, 0x7b, 0xb2, 0x34, 0xbe, 0x7b, 0xc9, 0x0b, 0xe7, 0xfd, 0x17,
-	0x0c, 0x29, 0x2e, 0xc2, 0xb9, 0xd9, 0xf2, 0x53, 0x68, 0xa4, 0xc4, 0xef, 0x54, 0x7d, 0x7e, 0x9a,
-	0x77, 0x5e, 0xbd, 0x18, 0xbc, 0x9d, 0xf3, 0x46, 0x17, 0xf4, 0xbc, 0x3c, 0x7b, 0x0d, 0xa7, 0xe5,
-	0xae, 0xe1, 0x4a, 0xaf, 0x18, 0x7f, 0xaf, 0xc1, 0x6c, 0xee, 0xb1, 0x14, 0x19, 0x29, 0x17, 0x50,
-	0xfe, 0x2d, 0x54, 0x84, 0xee, 0x93, 0x5c, 0xe8, 0x8c, 0xf2, 0x87, 0xd7, 0x7f, 0x75, 0xd4, 0x1e,
-	0xa7, 0xbc, 0x15, 0x01, 0x7b, 0x0b, 0x6f, 0x8d, 0xf7, 0xa0, 0x91, 0x12, 0x95, 0xde, 0x52, 0x1f,
-	0x00, 0xf0, 0x37, 0xcf, 0x03, 0x51, 0xf4, 0xbb, 0xa1, 0x58, 0xfe, 0x6b, 0x26, 0xfb, 0xcd, 0xbc,
-	0x3a, 0xf7, 0x6c, 0x5f, 0xa4, 0x22, 0x6f, 0xd0, 0x90, 0xab, 0x97, 0x17, 0x79, 0x65, 0xaa, 0x04,
-	0xc6, 0x9f, 0x2b, 0xd0, 0x48, 0xbd, 0x02, 0xa3, 0xf7, 0x53, 0x07, 0x8c, 0xe4, 0x8a, 0x93, 0x21,
-	0x92, 0xe7, 0x0a, 0xf4, 0x11, 0x34, 0xdd, 0x90, 0xff, 0x67, 0x00, 0x43, 0xf3, 0x0b, 0xd1, 0x2b,
-	0xea, 0x43, 0xa3, 0x9f, 0x0c, 0x83, 0x83, 0x1b, 0xca, 0xdf, 0x34, 0x8c, 0x4e, 0x4c, 0x64, 0x0d,
-	0xeb, 0xc4, 0x04, 0x19, 0xd0, 0x62, 0xf7, 0x0d, 0x81, 0x83, 0xd9, 0x41, 0x43, 0x54, 0xf0, 0x0d,
-	0x27, 0x26, 0xfd, 0xc0, 0xc1, 0x34, 0x22, 0x68, 0x05, 0x1a, 0x0a, 0xe3, 0x86, 0xf2, 0xea, 0x56,
-	0x20, 0x7a, 0x21, 0x2d, 0x8a, 0x62, 0x7b, 0x88, 0xad, 0x78, 0x74, 0xe8, 0x63, 0xc2, 0x9e, 0xc6,
-	0x6a, 0x26, 0x50, 0xd1, 0x3e, 0x93, 0xa0, 0xf7, 0xa0, 0x49, 0xcb, 0x89, 0x60, 0x44, 0x8e, 0x03,
-	0xd7, 0x3f, 0x66, 0xf7, 0x99, 0x35, 0xb3, 0xe1, 0xdb, 0x64, 0x57, 0x88, 0xd0, 0x1d, 0x68, 0x7b,
-	0xc1, 0xc0, 0xf6, 0x2c, 0x79, 0xb6, 0x60, 0x17, 0x9a, 0x35, 0xb3, 0xc5, 0xa4, 0x72, 0x71, 0x45,
-	0x6b, 0xd0, 0x20, 0x6c, 0x06, 0xf8, 0xa0, 0xf9, 0x3f, 0xc1, 0xc8, 0x41, 0x27, 0x73, 0x63, 0x02,
-	0x51, 0xbf, 0x8d, 0x9b, 0x22, 0xbc, 0x22, 0x17, 0x44, 0x0c, 0x2a, 0x2a, 0x06, 0xc6, 0x4f, 0x35,
-	0x58, 0x1a, 0xfb, 0x2a, 0xce, 0x12, 0x81, 0x9e, 0xed, 0x64, 0x22, 0xd0, 0x33, 0xa0, 0x38, 0x0b,
-	0x54, 0x92, 0xb3, 0x40, 0x66, 0xb9, 0x9c, 0xcc, 0x2e, 0x97, 0x68, 0x15, 0xf4, 0xd0, 0x8e, 0xb0,
-	0x4f, 0x2c, 0x07, 0xb3, 0xbb, 0x0c, 0x37, 0x14, 0x71, 0x6e, 0x73, 0x79, 0x97, 0x89, 0x7b, 0xa1,
-	0xf1, 0xa0, 0xd4, 0x13, 0xe1, 0x79, 0x89, 0x27, 0xc6, 0x4f, 0x34, 0x58, 0x1c, 0xf3, 0x72, 0x7e,
-	0xe9, 0xf2, 0x9e, 0xdd, 0x7e, 0x2a, 0xb9, 0xed, 0x87, 0xd6, 0x9b, 0xae, 0x4f, 0x70, 0x74, 0x64,
-	0x33, 0x6f, 0xb3, 0x03, 0xbb, 0xa2, 0x54, 0xb2, 0x40, 0x35, 0x1e, 0x97, 0x78, 0xf1, 0xe6, 0x4d,
-	0xc6, 0xf8, 0x91, 0x06, 0xf3, 0xa5, 0x8f, 0xe7, 0x68, 0x0d, 0xe6, 0xe5, 0xc5, 0xcf, 0xc0, 0x1b,
-	0xc5, 0x04, 0x47, 0x16, 0x5d, 0xf0, 0xe5, 0xc5, 0xc5, 0x9c, 0x50, 0x6e, 0x71, 0xdd, 0x16, 0x55,
-	0xa1, 0x47, 0xc9, 0xff, 0x91, 0xe0, 0x73, 0x82, 0x23, 0xdf, 0xf6, 0x84, 0x51, 0x45, 0xdc, 0x43,
-	0x73, 0xed, 0xb6, 0x50, 0x32, 0xab, 0x7b, 0xab, 0x50, 0x57, 0xcf, 0x07, 0x68, 0x06, 0x26, 0x37,
-	0xfa, 0x5f, 0xeb, 0x13, 0xa8, 0x06, 0x53, 0xbd, 0xbd, 0x57, 0x8f, 0xf4, 0x29, 0xf1, 0x6b, 0x5d,
-	0xaf, 0xde, 0xfb, 0x99, 0x06, 0x75, 0xf5, 0x19, 0xa2, 0x16, 0xd4, 0xb7, 0x7a, 0x5d, 0xd3, 0xea,
-	0xf5, 0x3f, 0xdb, 0xd5, 0x27, 0xd0, 0x1c, 0xcc, 0x9a, 0xdb, 0x2f, 0x77, 0x0f, 0xb6, 0xad, 0xaf,
-	0x76, 0xcd, 0x2f, 0x5e, 0xec, 0x6e, 0x74, 0x75, 0x0d, 0xcd, 0x42, 0x43, 0x08, 0x77, 0x76, 0xf7,
-	0x0f, 0xf4, 0x0a, 0x42, 0xd0, 0x7e, 0xb1, 0xbb, 0xb5, 0xf1, 0x22, 0x01, 0x4d, 0xa2, 0x36, 0x00,
-	0x97, 0x31, 0xcc, 0x14, 0xba, 0x02, 0x2d, 0x61, 0x74, 0xf0, 0x65, 0xbf, 0xbf, 0xfd, 0x42, 0x9f,
-	0x46, 0x3a, 0x34, 0x39, 0x44, 0x48, 0xaa, 0xf7, 0x9e, 0x02, 0x24, 0xdf, 0x38, 0xf5, 0xb1, 0xbf,
-	0xdb, 0xdf, 0xd6, 0x27, 0x50, 0x13, 0x6a, 0xfd, 0x5d, 0x6b, 0xbb, 0xbf, 0xb5, 0xb1, 0xa7, 0x6b,
-	0xa8, 0x0e, 0xd3, 0x2c, 0x9d, 0xf4, 0x0a, 0x1f, 0x46, 0x6f, 0x4f, 0x9f, 0x5c, 0x7b, 0x06, 0xc0,
-	0x1f, 0x8c, 0xd8, 0xff, 0x2a, 0x3e, 0x84, 0x29, 0xf6, 0x57, 0x2e, 0x8b, 0xa9, 0xff, 0x80, 0x5c,
-	0x96, 0xb2, 0xd4, 0x7f, 0x41, 0x3e, 0xd4, 0x36, 0x17, 0xbf, 0xfb, 0x61, 0x45, 0xfb, 0xd3, 0x0f,
-	0x2b, 0xda, 0x5f, 0x7e, 0x58, 0xd1, 0x7e, 0xf5, 0xb7, 0x95, 0x89, 0xff, 0x9e, 0x66, 0x77, 0xf1,
-	0x87, 0x55, 0xf6, 0xe7, 0xa3, 0x7f, 0x04, 0x00, 0x00, 0xff, 0xff, 0x0e, 0xfb, 0xbe, 0xec, 0x63,
-	0x29, 0x00, 0x00,
+	// 3619 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x5a, 0x4b, 0x73, 0xdb, 0xc8,
+	0x76, 0x16, 0x48, 0x91, 0x22, 0x0f, 0x1f, 0x82, 0x5b, 0x2f, 0x4a, 0x7e, 0xe9, 0x62, 0xc6, 0x65,
+	0xd9, 0x37, 0xe3, 0x71, 0x79, 0x6c, 0xf9, 0x7a, 0x52, 0xe5, 0x5b, 0x92, 0xa8, 0xb1, 0x78, 0xc7,
+	0xa6, 0x54, 0x90, 0xc6, 0x93, 0x9b, 0xba, 0x55, 0x08, 0x04, 0xb4, 0x24, 0xc4, 0x20, 0x80, 0x01,
+	0x9a, 0x7a, 0x24, 0xab, 0x64, 0x97, 0x54, 0xa5, 0x6e, 0x56, 0xf9, 0x05, 0x59, 0x66, 0x95, 0xca,
+	0x2e, 0xfb, 0xd4, 0xcc, 0x2e, 0x3f, 0x21, 0x99, 0x64, 0x95, 0x5d, 0xfe, 0x41, 0xaa, 0x9f, 0x78,
+	0x10, 0x94, 0xec, 0x54, 0x2a, 0x2b, 0xa2, 0xcf, 0xe3, 0xc3, 0xe9, 0xd3, 0x07, 0x7d, 0x4e, 0x9f,
+	0x26, 0xa0, 0x13, 0xec, 0x7b, 0x97, 0xc7, 0xb6, 0xf3, 0x01, 0x07, 0xee, 0x93, 0x28, 0x0e, 0x49,
+	0x88, 0x6a, 0x8c, 0x66, 0x74, 0xa0, 0x75, 0x78, 0x15, 0x38, 0x26, 0xfe, 0x61, 0x8c, 0x13, 0x62,
+	0xfc, 0xbb, 0x0e, 0xad, 0xa3, 0xb0, 0x6f, 0x13, 0x3b, 0xf2, 0xed, 0x00, 0xa3, 0x0d, 0x98, 0xf3,
+	0x02, 0x2b, 0xb9, 0x0a, 0x9c, 0x9e, 0xb6, 0xae, 0x6d, 0xb4, 0x9e, 0x75, 0x9e, 0x30, 0xbd, 0x27,
+	0x83, 0x80, 0xaa, 0xed, 0xcd, 0x98, 0x75, 0x8f, 0x3d, 0xa1, 0x97, 0xd0, 0xf6, 0xa2, 0x04, 0x13,
+	0x6b, 0x1c, 0xb9, 0x36, 0xc1, 0xbd, 0x0a, 0x13, 0x47, 0x52, 0xfc, 0xe0, 0x10, 0x93, 0xef, 0x18,
+	0x67, 0x6f, 0xc6, 0x6c, 0x31, 0x49, 0x3e, 0x44, 0x6f, 0x00, 0x71, 0x45, 0x17, 0xfb, 0xc4, 0x96,
+	0xea, 0x55, 0xa6, 0xbe, 0x92, 0x55, 0xef, 0x53, 0xbe, 0xc2, 0xd0, 0x99, 0x52, 0x86, 0x96, 0x5a,
+	0x10, 0xe3, 0x51, 0x78, 0x8e, 0x7b, 0xb3, 0x93, 0x16, 0x98, 0x8c, 0xa3, 0x2c, 0xe0, 0x43, 0x74,
+	0x00, 0x4b, 0xb6, 0x43, 0xbc, 0x73, 0x6c, 0x45, 0x71, 0x78, 0xe2, 0xf9, 0x58, 0x1a, 0x51, 0x63,
+	0x08, 0x6b, 0x02, 0x61, 0x8b, 0xc9, 0x1c, 0x70, 0x11, 0x65, 0xc7, 0x82, 0x3d, 0x49, 0x2e, 0x41,
+	0x14, 0x36, 0xd5, 0xa7, 0x23, 0x2a, 0xdb, 0x16, 0xec, 0x49, 0x32, 0x7a, 0x07, 0x8b, 0x12, 0x31,
+	0xf4, 0x3d, 0xe7, 0x4a, 0x9a, 0x38, 0xc7, 0x00, 0x57, 0xf3, 0x80, 0x4c, 0x42, 0x59, 0x88, 0xec,
+	0x09, 0xea, 0x24, 0x9c, 0xb0, 0xaf, 0x31, 0x15, 0x4e, 0x99, 0x87, 0xec, 0x09, 0x2a, 0x85, 0x3b,
+	0x0b, 0x13, 0x62, 0xe1, 0xc0, 0x8d, 0x42, 0x2f, 0x50, 0x41, 0xd0, 0xcc, 0xc1, 0xed, 0x85, 0x09,
+	0xd9, 0x15, 0x12, 0xa9, 0x75, 0x67, 0x13, 0xd4, 0x49, 0x38, 0x61, 0x1d, 0x4c, 0x85, 0x4b, 0xad,
+	0x3b, 0x9b, 0xa0, 0xa2, 0xdf, 0x42, 0xef, 0x22, 0x8c, 0x3f, 0xf8, 0xa1, 0xed, 0x4e, 0x58, 0xd8,
+	0x62, 0x90, 0x77, 0x05, 0xe4, 0xf7, 0x42, 0x6c, 0xc2, 0xca, 0xe5, 0x8b, 0x52, 0x4e, 0x39, 0xb4,
+	0xb0, 0xb6, 0x7d, 0x2d, 0xb4, 0xb2, 0x78, 0xf9, 0xa2, 0x94, 0x83, 0xbe, 0x86, 0x8e, 0x13, 0x06,
+	0x27, 0xde, 0xa9, 0x34, 0xb5, 0xc3, 0xf0, 0x16, 0x04, 0xde, 0x0e, 0xe3, 0x29, 0x03, 0xdb, 0x4e,
+	0x66, 0xac, 0x1c, 0x38, 0xc2, 0xc4, 0x76, 0xed, 0xf4, 0xab, 0xea, 0x4e, 0x38, 0xf0, 0x9d, 0x90,
+	0xc8, 0xaf, 0x47, 0x9e, 0x8a, 0x1e, 0xc2, 0x7c, 0x42, 0x37, 0x88, 0xc0, 0xc1, 0x56, 0x30, 0x1e,
+	0x1d, 0xe3, 0xb8, 0x37, 0xbf, 0xae, 0x6d, 0xcc, 0x9a, 0x5d, 0x49, 0x1e, 0x32, 0x2a, 0xda, 0x02,
+	0xdd, 0x8b, 0xec, 0x91, 0x15, 0x85, 0xa1, 0x2f, 0xdf, 0xa9, 0xb3, 0x77, 0x2e, 0xa9, 0xcf, 0x70,
+	0xeb, 0xdd, 0x41, 0x18, 0xfa, 0xea, 0x7d, 0x5d, 0xaa, 0x90, 0x52, 0xf2, 0x10, 0xc2, 0x93, 0xb7,
+	0x4a, 0x21, 0x94, 0x07, 0x15, 0x44, 0x21, 0x1a, 0xd5, 0xec, 0x05, 0x0c, 0x9a, 0x3a, 0xfb, 0x7c,
+	0xf8, 0xe4, 0xa9, 0xe8, 0x10, 0x96, 0x13, 0x1c, 0x9f, 0x7b, 0x0e, 0xb6, 0x6c, 0xc7, 0x09, 0xc7,
+	0x69, 0xf0, 0x2c, 0x30, 0xc0, 0xdb, 0x02, 0xf0, 0x90, 0x0b, 0x6d, 0x71, 0x19, 0x35, 0xc1, 0xc5,
+	0xa4, 0x84, 0x5e, 0x06, 0x2a, 0xac, 0x5c, 0xbc, 0x06, 0x54, 0xd9, 0xb9, 0x98, 0x94, 0xd0, 0xd1,
+	0x0e, 0xe8, 0x81, 0x3d, 0xc2, 0x49, 0x64, 0x3b, 0x6a, 0x0f, 0x5b, 0x62, 0x70, 0xcb, 0x02, 0x6e,
+	0x28, 0xd9, 0xca, 0xbc, 0xf9, 0x20, 0x4f, 0xca, 0x83, 0x08, 0x9b, 0x96, 0xcb, 0x41, 0x94, 0x39,
+	0xf3, 0x41, 0x9e, 0x44, 0xf7, 0xe2, 0x38, 0x1c, 0x13, 0x65, 0xc5, 0x4a, 0x6e, 0x2f, 0x36, 0x29,
+	0x2b, 0xcd, 0x06, 0x71, 0x3a, 0x4c, 0x15, 0xc5, 0x9b, 0x7b, 0x93, 0x8a, 0xe9, 0x26, 0x1e, 0xa7,
+	0x43, 0xb4, 0x03, 0xad, 0x73, 0x82, 0x23, 0xf9, 0xc2, 0x55, 0xa6, 0xb7, 0x2e, 0xf4, 0xde, 0xff,
+	0xd1, 0xdb, 0xad, 0xe1, 0xd1, 0x38, 0x08, 0xb0, 0x3f, 0xf1, 0x69, 0x03, 0x55, 0x53, 0x73, 0xe7,
+	0x20, 0xe2, 0xe5, 0x6b, 0x37, 0x81, 0x28, 0x53, 0x18, 0x88, 0xb0, 0xe4, 0x77, 0xb0, 0x7a, 0xe1,
+	0xc5, 0xf8, 0x74, 0x6c, 0xc7, 0x93, 0xfb, 0xcd, 0x6d, 0x06, 0x79, 0x4f, 0x6e, 0x0a, 0x52, 0x6e,
+	0xc2, 0xaa, 0x95, 0x8b, 0x72, 0xd6, 0x14, 0x74, 0x61, 0xf0, 0x9d, 0xeb, 0xd1, 0x95, 0xb9, 0x2b,
+	0x17, 0xe5, 0x2c, 0xf4, 0x3d, 0xf4, 0x4e, 0xfd, 0xf0, 0xd8, 0xf6, 0xad, 0xe3, 0xd3, 0xc8, 0xca,
+	0xef, 0x3f, 0x77, 0x19, 0xf8, 0x1d, 0x01, 0xfe, 0x86, 0x89, 0x6d, 0xbf, 0x39, 0x28, 0x6c, 0x44,
+	0x4b, 0x5c, 0x7f, 0xfb, 0x34, 0xca, 0x32, 0xb6, 0x9b, 0x30, 0x17, 0xd9, 0x57, 0x74, 0x9b, 0x33,
+	0xfe, 0xa6, 0x06, 0x9d, 0x6f, 0xe2, 0x70, 0x94, 0x56, 0x19, 0x07, 0xb0, 0x14, 0xc5, 0xa1, 0x83,
+	0x93, 0xc4, 0x4a, 0x88, 0x4d, 0xc6, 0x49, 0xbe, 0x0a, 0x90, 0xe9, 0xf2, 0x80, 0xcb, 0x1c, 0x32,
+	0x91, 0x34, 0x01, 0x47, 0x93, 0x64, 0xf4, 0x27, 0x70, 0x3b, 0x9f, 0x41, 0xf2, 0xb8, 0xbc, 0x34,
+	0xb8, 0x5f, 0x92, 0x48, 0x0a, 0xe0, 0xbd, 0xb3, 0x29, 0xbc, 0xa9, 0x6f, 0x10, 0x2b, 0x51, 0xbb,
+	0xe1, 0x0d, 0x6a, 0x29, 0x7a, 0x67, 0x53, 0x78, 0xc8, 0x87, 0xfb, 0x93, 0xb9, 0x25, 0x3f, 0x0f,
+	0x5e, 0x4e, 0x7c, 0x36, 0x25, 0xc5, 0x14, 0xe6, 0x72, 0xe7, 0xe2, 0x1a, 0xfe, 0xb5, 0x6f, 0x13,
+	0x73, 0x9a, 0xfb, 0x88, 0xb7, 0xa9, 0x79, 0xdd, 0xb9, 0xb8, 0x86, 0x5f, 0x96, 0x51, 0x1a, 0xa5,
+	0x19, 0xe5, 0x3d, 0xa4, 0xb1, 0x5a, 0x98, 0x7c, 0x33, 0x17, 0x8f, 0x2a, 0xd8, 0x0b, 0xb3, 0x5e,
+	0xba, 0x28, 0x63, 0x64, 0xe3, 0xf1, 0x2f, 0x35, 0x68, 0x67, 0x63, 0x15, 0xbd, 0x84, 0x3a, 0x8f,
+	0xfc, 0x9e, 0xb6, 0x5e, 0xcd, 0xac, 0x62, 0x56, 0x48, 0x0c, 0x76, 0x03, 0x12, 0x5f, 0x99, 0x42,
+	0x7c, 0xed, 0x15, 0xb4, 0x32, 0x64, 0xa4, 0x43, 0xf5, 0x03, 0xbe, 0x62, 0x85, 0x73, 0xd3, 0xa4,
+	0x8f, 0x68, 0x11, 0x6a, 0xe7, 0xb6, 0x3f, 0xe6, 0xd5, 0x71, 0xd3, 0xe4, 0x83, 0xaf, 0x2b, 0xbf,
+	0xd2, 0x8c, 0x06, 0xd4, 0x79, 0x49, 0x6d, 0xfc, 0xa3, 0x06, 0xad, 0x4c, 0xb9, 0x8c, 0xba, 0x50,
+	0xf1, 0x5c, 0x01, 0x52, 0xf1, 0x5c, 0xd4, 0x83, 0xb9, 0x11, 0xa6, 0xbe, 0x49, 0x7a, 0x95, 0xf5,
+	0xea, 0x46, 0xd3, 0x94, 0x43, 0xf4, 0x14, 0x66, 0xc9, 0x55, 0xc4, 0xbf, 0x9a, 0xae, 0x72, 0x4c,
+	0x06, 0x8b, 0x3f, 0x1f, 0x5d, 0x45, 0xd8, 0x64, 0x92, 0x14, 0xcb, 0x0d, 0x47, 0xb6, 0x17, 0x24,
+	0xbd, 0x59, 0x8e, 0x25, 0x86, 0xc6, 0x17, 0xd0, 0x54, 0xc2, 0xa8, 0x0e, 0x95, 0xc1, 0x81, 0x3e,
+	0x83, 0xe6, 0xa9, 0x65, 0xd6, 0xd6, 0xb0, 0x6f, 0x1d, 0xec, 0x9b, 0x47, 0xba, 0x86, 0xe6, 0xa0,
+	0x3a, 0xdc, 0x3d, 0xd2, 0x2b, 0x46, 0x04, 0x7a, 0xb1, 0x46, 0x9f, 0x30, 0xfc, 0x33, 0xe8, 0xd8,
+	0xae, 0x8b, 0x5d, 0x2b, 0x6f, 0x7e, 0x9b, 0x11, 0xdf, 0x89, 0x39, 0x3c, 0x84, 0x79, 0x1e, 0x6d,
+	0xa9, 0x58, 0x95, 0x89, 0x75, 0x05, 0x59, 0x08, 0x1a, 0x77, 0x85, 0x97, 0x44, 0x40, 0x15, 0x5e,
+	0x66, 0xd8, 0xb0, 0x50, 0x52, 0xaf, 0xa3, 0x75, 0x25, 0xd6, 0x7a, 0xa6, 0xa7, 0xdb, 0x0a, 0x95,
+	0x18, 0xf4, 0x99, 0x95, 0x1b, 0x30, 0x27, 0x6a, 0x76, 0x71, 0x84, 0xe9, 0xe6, 0xc5, 0x4c, 0xc9,
+	0x36, 0x5e, 0x16, 0x5e, 0x21, 0x2c, 0xb9, 0xf1, 0x15, 0xc6, 0x7d, 0x68, 0x2a, 0x02, 0x42, 0x30,
+	0x4b, 0x93, 0xa7, 0x30, 0x9d, 0x3d, 0x1b, 0x21, 0xcc, 0x09, 0x01, 0xf4, 0x14, 0x3a, 0x5e, 0x70,
+	0x1c, 0x8e, 0x03, 0xd7, 0x8a, 0xc7, 0x3e, 0x4e, 0x44, 0x48, 0xb6, 0x64, 0x42, 0x1c, 0xfb, 0xd8,
+	0x6c, 0x0b, 0x09, 0x3a, 0x48, 0xd0, 0x33, 0xe8, 0x86, 0x63, 0x92, 0x55, 0xa9, 0x4c, 0xaa, 0x74,
+	0xa4, 0x08, 0xd3, 0x31, 0x7e, 0x07, 0x68, 0xf2, 0xe8, 0x80, 0xee, 0x67, 0x66, 0x32, 0x2f, 0x67,
+	0xc2, 0x04, 0x84, 0xaf, 0x1e, 0x40, 0x9d, 0x1f, 0x1f, 0x7a, 0x95, 0xdc, 0xe1, 0x90, 0x0b, 0x99,
+	0x82, 0x69, 0xbc, 0xc8, 0xa3, 0x0b, 0x3f, 0xdd, 0x84, 0x6e, 0x3c, 0x83, 0x86, 0x1c, 0x53, 0x2f,
+	0x11, 0x0f, 0xc7, 0xd2, 0x4b, 0xf4, 0x59, 0x79, 0xae, 0x92, 0xf1, 0xdc, 0x7f, 0x6a, 0x50, 0xe7,
+	0x4a, 0xff, 0x3f, 0x9e, 0x43, 0x77, 0xa0, 0x39, 0x0e, 0x48, 0x4c, 0x8f, 0xd6, 0x2e, 0xfb, 0xf0,
+	0x1a, 0x66, 0x4a, 0x40, 0xab, 0xd0, 0x88, 0x62, 0x6c, 0xb9, 0x81, 0x4d, 0x58, 0xce, 0x69, 0xd0,
+	0xe8, 0xc1, 0xfd, 0xc0, 0x26, 0x54, 0x51, 0x15, 0x4d, 0x2c, 0x5b, 0x34, 0xcd, 0x94, 0x80, 0xee,
+	0x02, 0xd8, 0x63, 0xd7, 0x23, 0x56, 0x18, 0xf8, 0x57, 0x6c, 0x9b, 0x6f, 0x98, 0x4d, 0x46, 0xd9,
+	0x0f, 0xfc, 0x2b, 0xe3, 0xaf, 0xbb, 0x30, 0x4b, 0xdf, 0x8f, 0x96, 0xa1, 0x4e, 0x8f, 0x63, 0x61,
+	0x20, 0x3c, 0x23, 0x46, 0xe8, 0x4b, 0x00, 0x2f, 0xb2, 0xce, 0x71, 0x9c, 0x50, 0x5e, 0x85, 0x6d,
+	0x08, 0xba, 0xda, 0x10, 0xde, 0x73, 0xba, 0xd9, 0xf4, 0x22, 0xf1, 0x88, 0x7e, 0x49, 0x2d, 0x0d,
+	0x49, 0xe8, 0x84, 0x7e, 0xaf, 0x9a, 0x5f, 0x13, 0x41, 0x36, 0x95, 0x00, 0x5a, 0x81, 0xb9, 0x24,
+	0x76, 0xac, 0x00, 0x13, 0xb1, 0x6d, 0xd4, 0x93, 0xd8, 0x19, 0x62, 0x82, 0xbe, 0x80, 0x26, 0x65,
+	0x44, 0x61, 0x4c, 0x92, 0x5e, 0x8d, 0x39, 0x4f, 0x7d, 0x02, 0x61, 0x4c, 0x4c, 0x3b, 0x38, 0xc5,
+	0x66, 0x23, 0x89, 0x1d, 0x3a, 0x4a, 0x28, 0x8e, 0x9b, 0x10, 0x86, 0x53, 0xe7, 0x38, 0x6e, 0x42,
+	0x04, 0x0e, 0x65, 0x70, 0x9c, 0xb9, 0x69, 0x38, 0x6e, 0x42, 0x38, 0xce, 0x5d, 0x68, 0x7a, 0xce,
+	0x28, 0xb2, 0xd8, 0xee, 0x47, 0xf3, 0x48, 0x6d, 0x6f, 0xc6, 0x6c, 0x50, 0x12, 0xdb, 0xbe, 0x5e,
+	0x43, 0x57, 0xb1, 0x2d, 0x27, 0x74, 0x65, 0xea, 0x90, 0xf5, 0xec, 0x40, 0x08, 0x6e, 0x05, 0xee,
+	0x4e, 0xe8, 0xb2, 0xd3, 0x94, 0xd4, 0xa5, 0x63, 0xf4, 0x19, 0x74, 0xe9, 0xac, 0xbc, 0xc8, 0xa2,
+	0xdd, 0x05, 0xcf, 0x4d, 0x7a, 0xc0, 0xac, 0x6d, 0x25, 0xb1, 0x33, 0x88, 0x0e, 0x31, 0x19, 0xb8,
+	0x09, 0x15, 0xa2, 0x26, 0x67, 0x84, 0x5a, 0x5c, 0xc8, 0x4d, 0x88, 0x12, 0x7a, 0x09, 0xab, 0xcc,
+	0x71, 0xf6, 0x08, 0xbb, 0x6c, 0x76, 0x59, 0xf9, 0x36, 0x93, 0x5f, 0xa4, 0xae, 0xa4, 0x7c, 0x3a,
+	0xb5, 0xac, 0x22, 0xf3, 0x54, 0xa9, 0x62, 0x87, 0x2b, 0x52, 0xdf, 0x4d, 0x28, 0x3e, 0x83, 0x76,
+	0x10, 0x12, 0x4b, 0xad, 0xed, 0x49, 0xf9, 0xda, 0xb6, 0x82, 0x90, 0xc8, 0x01, 0xba, 0x07, 0x74,
+	0x68, 0xc9, 0x25, 0x3e, 0x65, 0xf0, 0xcd, 0x20, 0x24, 0x87, 0x7c, 0x95, 0x9f, 0x43, 0x47, 0xf2,
+	0xf9, 0x0a, 0x9d, 0x4d, 0x59, 0xa1, 0x16, 0xd7, 0xe1, 0x8b, 0x24, 0x50, 0xe5, 0x82, 0x7b, 0x0a,
+	0xb5, 0x9f, 0x90, 0x0c, 0x6a, 0xba, 0xee, 0x7f, 0x7a, 0x0d, 0x6a, 0x5f, 0x2e, 0xfd, 0xe7, 0x5c,
+	0x2b, 0x5d, 0xfe, 0x0f, 0x6c, 0xf9, 0x35, 0x26, 0x25, 0x17, 0x16, 0xed, 0x02, 0xca, 0x49, 0xf1,
+	0x28, 0xf0, 0xaf, 0x8d, 0x02, 0xcd, 0x9c, 0xcf, 0x40, 0x50, 0x12, 0x7a, 0x0c, 0x48, 0x4e, 0x3c,
+	0xe3, 0xfe, 0x11, 0xcf, 0x4f, 0x7c, 0xae, 0xca, 0xf1, 0x42, 0xb6, 0x10, 0x13, 0x81, 0x92, 0xed,
+	0x67, 0xc2, 0xe2, 0x35, 0xdc, 0x55, 0x0e, 0x2f, 0x5d, 0xe1, 0x88, 0xa9, 0xad, 0x88, 0x25, 0x98,
+	0x58, 0x64, 0xa1, 0x3f, 0x3d, 0x42, 0x7e, 0x50, 0xfa, 0xfd, 0xf2, 0x20, 0x59, 0x0a, 0x63, 0xef,
+	0xd4, 0x0b, 0x6c, 0x9f, 0x19, 0x91, 0x60, 0x1f, 0x3b, 0x24, 0x8c, 0x7b, 0x31, 0xdb, 0x54, 0x16,
+	0x24, 0xf3, 0x30, 0x76, 0x0e, 0x05, 0x2b, 0xa7, 0x43, 0x5f, 0xac, 0x74, 0x92, 0xbc, 0x4e, 0x3f,
+	0x21, 0x4a, 0x67, 0x17, 0xee, 0xe7, 0xde, 0x93, 0x9e, 0x33, 0x95, 0x36, 0x61, 0xda, 0x77, 0x32,
+	0x6f, 0x54, 0xa7, 0xcd, 0x52, 0x18, 0x39, 0xe7, 0x02, 0xcc, 0x38, 0x0f, 0x23, 0x66, 0x9d, 0x87,
+	0x79, 0x05, 0xab, 0x0a, 0x46, 0xba, 0x5f, 0x01, 0x9c, 0x33, 0x80, 0x65, 0x29, 0x30, 0x64, 0x9e,
+	0x9f, 0xaa, 0x9a, 0x73, 0xc0, 0xc5, 0x84, 0x6a, 0xd6, 0x07, 0xdf, 0xf1, 0x2d, 0xa0, 0x78, 0xf8,
+	0x1f, 0xd9, 0xc4, 0x39, 0xeb, 0x5d, 0xe6, 0xce, 0x3b, 0xf9, 0xb3, 0xff, 0x3b, 0x2a, 0x61, 0x2e,
+	0x27, 0xb1, 0x53, 0x42, 0xa7, 0xb0, 0xdc, 0x88, 0x32, 0xd8, 0xab, 0x9b, 0x61, 0xdd, 0x84, 0x94,
+	0xd0, 0x69, 0x1e, 0x39, 0x23, 0x24, 0x12, 0x38, 0x7f, 0x96, 0x2b, 0x6a, 0xf6, 0x8e, 0x8e, 0x0e,
+	0xb8, 0x76, 0x93, 0xca, 0x48, 0x85, 0x86, 0x6c, 0xbb, 0xf4, 0xfe, 0x3c, 0xd7, 0xb0, 0xa2, 0xf9,
+	0x4a, 0x75, 0x56, 0x94, 0x10, 0x2d, 0x41, 0x69, 0xae, 0xb5, 0x3c, 0xb7, 0xf7, 0x93, 0xc8, 0x61,
+	0x74, 0x3c, 0x70, 0xb7, 0xeb, 0x30, 0x4b, 0x3f, 0xd8, 0x6d, 0x80, 0x86, 0xfc, 0x78, 0x7f, 0x53,
+	0x6f, 0xfc, 0xa8, 0xe9, 0x3f, 0x69, 0x26, 0xf8, 0xe1, 0xa9, 0x15, 0xc5, 0xf8, 0xc4, 0xbb, 0x34,
+	0xde, 0xc0, 0x42, 0x99, 0xe9, 0x6b, 0xd0, 0x50, 0x4b, 0xc2, 0x81, 0xd5, 0x98, 0xd6, 0xe1, 0x2c,
+	0x68, 0x44, 0x09, 0xca, 0x07, 0xc6, 0xdf, 0x6b, 0xd0, 0x54, 0x93, 0xe2, 0x75, 0x36, 0x39, 0x0b,
+	0x5d, 0x5e, 0x39, 0x34, 0x4d, 0x39, 0x44, 0x4f, 0xa1, 0x16, 0xd9, 0xe4, 0x4c, 0x96, 0x07, 0x6b,
+	0x45, 0x7f, 0x3c, 0x39, 0xb0, 0xc9, 0x19, 0x7b, 0x32, 0xb9, 0xe0, 0xda, 0xb7, 0xd0, 0x54, 0x34,
+	0xb4, 0x0c, 0x35, 0x7c, 0x69, 0x3b, 0x84, 0x5b, 0xb5, 0x37, 0x63, 0xf2, 0x21, 0xea, 0x41, 0x9d,
+	0xcf, 0x88, 0x57, 0x34, 0xb4, 0xb7, 0xce, 0xc7, 0xdb, 0x6d, 0x00, 0x8a, 0xc3, 0x57, 0xc1, 0xf8,
+	0x3b, 0x0d, 0xda, 0x59, 0x67, 0xa2, 0x6f, 0xa0, 0x65, 0x07, 0x41, 0x48, 0x6c, 0x9a, 0xfa, 0x65,
+	0x9d, 0xf3, 0x79, 0x89, 0xdb, 0x9f, 0x6c, 0xa5, 0x62, 0xfc, 0xe4, 0x92, 0x55, 0x5c, 0x7b, 0x0d,
+	0x7a, 0x51, 0xe0, 0x93, 0xce, 0x30, 0xaf, 0x60, 0xbe, 0xb0, 0x89, 0xb2, 0xba, 0x8d, 0xee, 0xca,
+	0x54, 0xbf, 0x26, 0x0e, 0x1d, 0x08, 0x66, 0xd9, 0xf6, 0x5b, 0xe1, 0x34, 0xfa, 0x6c, 0xbc, 0x85,
+	0x86, 0x4a, 0x3f, 0x3d, 0xa8, 0x8b, 0x23, 0xa1, 0x26, 0x52, 0xb9, 0x18, 0xa3, 0xc5, 0x6c, 0xc5,
+	0xb7, 0x37, 0xc3, 0x6b, 0xbe, 0x6d, 0x1d, 0xba, 0x9c, 0x6f, 0x85, 0x31, 0xdb, 0x0b, 0x8c, 0x17,
+	0xd0, 0x54, 0xe9, 0x82, 0xda, 0x7b, 0xe2, 0xc5, 0x09, 0x11, 0x36, 0xf0, 0x01, 0x35, 0xc2, 0xb7,
+	0x13, 0x22, 0x8d, 0xa0, 0xcf, 0xc6, 0xef, 0x35, 0x40, 0xc5, 0x53, 0xed, 0xa0, 0x4f, 0x8f, 0x24,
+	0x61, 0xec, 0x9c, 0xe1, 0x84, 0xc4, 0x36, 0x09, 0x63, 0x1a, 0xa9, 0x7c, 0xea, 0xdd, 0x2c, 0x79,
+	0xe0, 0xa2, 0xfb, 0xd0, 0x52, 0x47, 0x68, 0x8f, 0x57, 0x83, 0x4d, 0x13, 0x24, 0x89, 0x0b, 0xa8,
+	0xa3, 0xb5, 0xe7, 0xb2, 0x8a, 0xb0, 0x69, 0x82, 0x24, 0x0d, 0xdc, 0xdf, 0xcc, 0x36, 0x34, 0xbd,
+	0x62, 0x36, 0x68, 0x4b, 0x80, 0x4d, 0xe4, 0x12, 0x96, 0xcb, 0x5b, 0xd2, 0xe8, 0x51, 0xa6, 0x7a,
+	0x5e, 0x9d, 0x72, 0x22, 0x17, 0x55, 0xfa, 0x57, 0xd0, 0x90, 0xaf, 0xe8, 0xd5, 0x72, 0xd7, 0x2a,
+	0x45, 0x05, 0x53, 0x09, 0x1a, 0xff, 0x52, 0x05, 0xbd, 0xc8, 0xa6, 0xae, 0xa4, 0x47, 0x70, 0x79,
+	0x58, 0xe1, 0x83, 0xb2, 0x3a, 0x9c, 0x86, 0xcd, 0xc8, 0x76, 0x84, 0x0b, 0xe8, 0x23, 0x9d, 0xbb,
+	0xbc, 0x0b, 0xa1, 0x19, 0x89, 0xd7, 0x8d, 0x20, 0x48, 0x34, 0x09, 0xdd, 0x86, 0xa6, 0x17, 0x9d,
+	0x3f, 0xa7, 0xc5, 0x01, 0xaf, 0x1d, 0x9b, 0x66, 0x83, 0x12, 0x86, 0x98, 0x48, 0xe6, 0x26, 0x67,
+	0xd6, 0x15, 0x73, 0x93, 0x31, 0x1f, 0x40, 0x8d, 0x1e, 0x08, 0x64, 0xa5, 0x28, 0x8b, 0x9b, 0x23,
+	0x0f, 0xc7, 0x83, 0xe0, 0x24, 0x34, 0x39, 0x17, 0x3d, 0x82, 0x06, 0x7f, 0x81, 0x4d, 0x7a, 0x8d,
+	0xf5, 0x6a, 0xe6, 0x68, 0x37, 0xb4, 0x09, 0x13, 0x9c, 0x63, 0xef, 0xb3, 0x89, 0x10, 0xdd, 0x64,
+	0xa2, 0xcd, 0xa9, 0xa2, 0x9b, 0x54, 0xf4, 0x97, 0x70, 0xcb, 0x0b, 0x4e, 0x63, 0xda, 0xbb, 0x3a,
+	0xb6, 0x03, 0xf7, 0xc2, 0x73, 0xc9, 0x19, 0xbb, 0xa8, 0xa8, 0x9a, 0xba, 0x60, 0x6c, 0x4b, 0x3a,
+	0x3d, 0x02, 0x2b, 0xe1, 0x31, 0x8d, 0xc9, 0x16, 0x13, 0x6c, 0x4b, 0x41, 0x4a, 0x43, 0x8f, 0x40,
+	0xc7, 0x45, 0xc0, 0x36, 0x93, 0x9b, 0xc7, 0x05, 0xbc, 0x5f, 0x40, 0x1b, 0x67, 0xe1, 0x3a, 0x4c,
+	0xac, 0x85, 0x53, 0x34, 0x63, 0x67, 0x32, 0x84, 0xc4, 0x01, 0xec, 0xe3, 0x43, 0xc8, 0xd8, 0x82,
+	0x6e, 0xb6, 0x85, 0x35, 0xe8, 0x17, 0x43, 0xb9, 0x72, 0x63, 0x28, 0xfb, 0x80, 0x26, 0xef, 0x7f,
+	0xd0, 0x83, 0x8c, 0x0d, 0x4b, 0x25, 0xcd, 0x32, 0x11, 0xc2, 0x5f, 0x66, 0x42, 0xb8, 0x9a, 0xcb,
+	0x2a, 0x59, 0xe1, 0x4c, 0xf8, 0xfe, 0x77, 0x05, 0xda, 0x59, 0x56, 0xd9, 0x31, 0xbb, 0x18, 0x92,
+	0x95, 0x89, 0x90, 0x54, 0x81, 0x55, 0xbd, 0x36, 0xb0, 0x9e, 0xc0, 0x02, 0xbe, 0x8c, 0xb0, 0x43,
+	0xb0, 0x6b, 0xb1, 0x08, 0xb3, 0x5d, 0x37, 0x96, 0x21, 0x7e, 0x4b, 0xb2, 0x06, 0xd1, 0xf9, 0xf3,
+	0x2d, 0xd7, 0x9d, 0x94, 0xdf, 0x14, 0xf2, 0xb5, 0x09, 0xf9, 0x4d, 0x2e, 0xff, 0x2b, 0x98, 0x57,
+	0x47, 0x4a, 0x8b, 0x1b, 0x54, 0x2f, 0x37, 0xa8, 0xab, 0xe4, 0x8e, 0x98, 0x65, 0x2f, 0xa0, 0x2b,
+	0xcf, 0x9f, 0xd6, 0xb5, 0x9f, 0x48, 0x5b, 0x1c, 0x4b, 0xb9, 0xda, 0x73, 0xe8, 0x9c, 0x84, 0xf1,
+	0x05, 0x6d, 0xb9, 0x71, 0xad, 0xc6, 0x14, 0x2d, 0x21, 0xc5, 0xb4, 0x8c, 0x3f, 0xcc, 0xaf, 0xb0,
+	0x88, 0xb2, 0x8f, 0x5b, 0x61, 0x23, 0x86, 0x86, 0x84, 0x2d, 0x5d, 0xab, 0x47, 0x20, 0xbf, 0x26,
+	0x7e, 0x63, 0xe9, 0xa9, 0xe4, 0x3d, 0x2f, 0xe8, 0x07, 0x82, 0x4c, 0xf7, 0x6b, 0x5c, 0x90, 0x14,
+	0x2d, 0x24, 0x9c, 0x13, 0x34, 0x5e, 0xc2, 0x9c, 0xf8, 0x9c, 0xd1, 0x12, 0xd4, 0xf1, 0x25, 0x2d,
+	0x99, 0xe5, 0xd6, 0x86, 0x2f, 0xc9, 0x20, 0xa2, 0x64, 0x16, 0xe0, 0x91, 0x4c, 0x76, 0xd4, 0xe0,
+	0xc8, 0x30, 0x61, 0xa1, 0xa4, 0x17, 0xcd, 0xbe, 0xee, 0x24, 0xb4, 0x88, 0x37, 0xc2, 0x09, 0xb1,
+	0x47, 0x12, 0xab, 0xed, 0x25, 0xe1, 0x91, 0xa4, 0xd1, 0x13, 0xfb, 0x38, 0xa2, 0x22, 0x0c, 0x52,
+	0x33, 0xc5, 0xc8, 0x88, 0xa0, 0x37, 0xad, 0x0f, 0xfd, 0xb1, 0x5f, 0xc9, 0x17, 0x50, 0xe7, 0x1d,
+	0xd2, 0x5e, 0x25, 0x27, 0x9a, 0xc7, 0x34, 0x85, 0x90, 0xb1, 0x01, 0xdd, 0x3c, 0x87, 0xda, 0x26,
+	0x00, 0x44, 0x25, 0x26, 0x24, 0xb7, 0xca, 0x6c, 0xfb, 0xb4, 0xf5, 0xbd, 0x84, 0x3b, 0xd7, 0xb5,
+	0xa7, 0x3f, 0x25, 0x9f, 0x7d, 0xe2, 0x34, 0x07, 0xd3, 0xde, 0xfc, 0xe9, 0xdb, 0xe0, 0x09, 0x2c,
+	0x95, 0xb6, 0x99, 0x69, 0xbb, 0x26, 0x1a, 0x1f, 0xfb, 0x9e, 0x63, 0xa5, 0xc5, 0x52, 0x93, 0x53,
+	0xbe, 0xc5, 0x57, 0xf4, 0x83, 0xf7, 0x02, 0x82, 0xe3, 0x13, 0x7a, 0x46, 0x51, 0x5f, 0xbc, 0x88,
+	0xa9, 0x5b, 0x8a, 0x25, 0xbf, 0x78, 0xe3, 0x1d, 0xff, 0x92, 0x0a, 0xb7, 0xb0, 0x6b, 0xa0, 0x76,
+	0x53, 0x59, 0xd0, 0xca, 0xb1, 0x4a, 0x9e, 0x19, 0x5c, 0x96, 0xec, 0xca, 0xe0, 0xc4, 0xbc, 0xff,
+	0xd7, 0x70, 0xbb, 0xd0, 0xcd, 0xdf, 0xe2, 0x96, 0x74, 0x7a, 0x67, 0xa3, 0x30, 0xf4, 0xc5, 0xfa,
+	0xcc, 0x17, 0xef, 0x6d, 0x19, 0xd3, 0x58, 0x4f, 0x61, 0xa6, 0xf4, 0x70, 0x5f, 0x43, 0x43, 0x4a,
+	0xb0, 0xa2, 0xd1, 0x73, 0x55, 0x03, 0x90, 0x3e, 0xa3, 0x7b, 0x00, 0x23, 0x3b, 0xf9, 0x61, 0x8c,
+	0x63, 0x5b, 0x94, 0x93, 0x0d, 0x33, 0x43, 0x31, 0xfe, 0x59, 0x83, 0xc5, 0xb2, 0x4b, 0x59, 0xf4,
+	0x30, 0xb3, 0xe4, 0x2b, 0xa5, 0xa7, 0x22, 0x11, 0x6a, 0xbf, 0x86, 0xba, 0x6f, 0x1f, 0x63, 0x5f,
+	0x96, 0xfa, 0x0f, 0xaf, 0xb9, 0xea, 0x7d, 0xf2, 0x96, 0x49, 0x8a, 0x1b, 0x01, 0xae, 0x46, 0x6f,
+	0x04, 0x32, 0xe4, 0x4f, 0xaa, 0xa6, 0x7f, 0x5d, 0x34, 0x5e, 0x5d, 0x9d, 0x7c, 0x9c, 0xf1, 0x46,
+	0x1f, 0xf4, 0x22, 0x3d, 0xdf, 0x75, 0xd4, 0x8a, 0x5d, 0xc7, 0xb2, 0x8e, 0xea, 0x3f, 0x68, 0x30,
+	0x5f, 0xb8, 0x35, 0x46, 0x46, 0xc6, 0x04, 0x54, 0xbc, 0x14, 0x16, 0xae, 0xfb, 0xba, 0xe0, 0x3a,
+	0xa3, 0xfc, 0x06, 0xfa, 0xff, 0xda, 0x6b, 0x2f, 0x32, 0xd6, 0x0a, 0x87, 0x7d, 0x84, 0xb5, 0xc6,
+	0x2f, 0xa0, 0x95, 0x21, 0x95, 0x36, 0xe5, 0x8f, 0x00, 0xf8, 0xe5, 0xef, 0x91, 0x38, 0xc4, 0x78,
+	0x91, 0x48, 0x17, 0x0d, 0x93, 0x3d, 0x33, 0xab, 0x2e, 0x7d, 0x3b, 0x10, 0xa1, 0xc8, 0x07, 0xd4,
+	0xe5, 0xea, 0x0a, 0x4a, 0x76, 0x88, 0x15, 0xc1, 0xf8, 0x7d, 0x15, 0x5a, 0x99, 0xeb, 0x70, 0xf4,
+	0x79, 0xe6, 0xc0, 0x94, 0xb6, 0x6c, 0x99, 0x44, 0xe6, 0xde, 0xe6, 0x2b, 0xfa, 0x57, 0x27, 0xfe,
+	0x17, 0x09, 0x26, 0xcd, 0x1b, 0xbc, 0xb7, 0xd4, 0x87, 0x46, 0x3f, 0x19, 0x26, 0x0e, 0x5e, 0x24,
+	0x9f, 0xa9, 0x1b, 0xdd, 0x84, 0xc8, 0x9a, 0xdc, 0x4d, 0x08, 0x32, 0xa0, 0xc3, 0xfa, 0x27, 0xa1,
+	0x8b, 0xd9, 0xc1, 0x49, 0x9c, 0x48, 0x68, 0xcb, 0x72, 0x18, 0xba, 0x98, 0x7a, 0x84, 0xb6, 0xed,
+	0x94, 0x8c, 0x17, 0xc9, 0x4e, 0xb5, 0x90, 0x18, 0x44, 0xb4, 0x88, 0x4a, 0xec, 0x11, 0xb6, 0x92,
+	0xf1, 0x31, 0x6d, 0xeb, 0xcd, 0xf1, 0xaf, 0x90, 0x92, 0x0e, 0x19, 0x85, 0xd6, 0xa8, 0xb4, 0xfc,
+	0x08, 0xc7, 0xe4, 0x34, 0xf4, 0x82, 0x53, 0xd6, 0x9f, 0x6d, 0x98, 0xad, 0xc0, 0x26, 0xfb, 0x82,
+	0x84, 0x1e, 0x40, 0xd7, 0x0f, 0x1d, 0xdb, 0xb7, 0xe4, 0x59, 0x89, 0x35, 0x68, 0x1b, 0x66, 0x87,
+	0x51, 0xe5, 0x66, 0x8c, 0x9e, 0x41, 0x8b, 0xb0, 0x15, 0xe0, 0x93, 0xe6, 0xff, 0x06, 0x92, 0x93,
+	0x4e, 0xd7, 0xc6, 0x04, 0xa2, 0x9e, 0xd1, 0x53, 0x68, 0x06, 0x34, 0xb3, 0x9f, 0x85, 0x11, 0xef,
+	0xc8, 0x66, 0x1a, 0x12, 0xd4, 0xa9, 0x43, 0x7c, 0x49, 0xf6, 0xc2, 0xc8, 0x6c, 0x04, 0xfc, 0x81,
+	0xde, 0x85, 0xb4, 0xb3, 0x1c, 0xba, 0x17, 0xa6, 0x0e, 0x12, 0x1b, 0x65, 0x20, 0xbd, 0xb3, 0x02,
+	0x73, 0xd2, 0x33, 0x3c, 0x14, 0xeb, 0x01, 0x77, 0xcb, 0x32, 0xd4, 0x2f, 0xb0, 0x77, 0x7a, 0xc6,
+	0xfd, 0x5d, 0x33, 0xc5, 0xc8, 0xb8, 0x2f, 0x96, 0x5b, 0xc4, 0xa6, 0x58, 0x93, 0x8a, 0x5a, 0x13,
+	0xe3, 0xbf, 0x34, 0x58, 0x9d, 0xfa, 0x77, 0x05, 0x16, 0x98, 0xa1, 0x2b, 0xed, 0x60, 0xcf, 0xf2,
+	0xac, 0x55, 0x49, 0xcf, 0x5a, 0xb9, 0xed, 0xbb, 0x9a, 0xdf, 0xbe, 0xd1, 0x06, 0xe8, 0x91, 0x1d,
+	0xe3, 0x80, 0x58, 0x2e, 0x66, 0xbd, 0x22, 0x2f, 0x12, 0xeb, 0xde, 0xe5, 0xf4, 0x3e, 0x23, 0xf3,
+	0xea, 0x67, 0x64, 0x3b, 0xd6, 0xf9, 0xa6, 0x58, 0xf5, 0xda, 0xc8, 0x76, 0xde, 0x6f, 0xaa, 0xb3,
+	0x18, 0x43, 0xaf, 0x2b, 0x74, 0x96, 0xba, 0xd0, 0x1f, 0x00, 0x2a, 0xa2, 0x9f, 0x6f, 0xb2, 0xa8,
+	0x68, 0x9a, 0x7a, 0x1e, 0xff, 0x7c, 0xd3, 0xf8, 0xb2, 0x74, 0xae, 0xc2, 0x37, 0x25, 0x73, 0x35,
+	0xfe, 0x49, 0x83, 0x95, 0x29, 0x7f, 0x9a, 0xb8, 0x36, 0xa1, 0xe5, 0x13, 0x74, 0xe5, 0xa6, 0x04,
+	0x9d, 0x73, 0x5d, 0x2e, 0x41, 0x73, 0x1f, 0x4e, 0x49, 0xe8, 0xb3, 0xd3, 0x12, 0xfa, 0x8b, 0x12,
+	0xab, 0x6f, 0x4e, 0xc3, 0xc6, 0x5f, 0x68, 0xb0, 0x54, 0xfa, 0x3f, 0x0b, 0xda, 0x7d, 0x95, 0xad,
+	0x3e, 0xc7, 0x1f, 0x27, 0x04, 0xc7, 0x16, 0x4d, 0x89, 0xb2, 0x55, 0xb5, 0x20, 0x98, 0x3b, 0x9c,
+	0xb7, 0x43, 0x59, 0xe8, 0x79, 0xfa, 0x97, 0x23, 0x7c, 0x49, 0x70, 0x4c, 0x9b, 0x97, 0x5c, 0xa9,
+	0x22, 0x6e, 0x1e, 0x38, 0x77, 0x57, 0x30, 0x99, 0xd6, 0xe3, 0x0d, 0x7a, 0x11, 0x2c, 0x6f, 0x89,
+	0xe6, 0xa0, 0xba, 0x35, 0xfc, 0xad, 0x3e, 0x83, 0x1a, 0x30, 0x3b, 0x38, 0x78, 0xff, 0x5c, 0x9f,
+	0x15, 0x4f, 0x9b, 0x7a, 0xfd, 0xf1, 0x5f, 0x69, 0xd0, 0x54, 0x1b, 0x15, 0xea, 0x40, 0x73, 0x67,
+	0xd0, 0x37, 0xad, 0xc1, 0xf0, 0x9b, 0x7d, 0x7d, 0x06, 0x2d, 0xc0, 0xbc, 0xb9, 0xfb, 0x6e, 0xff,
+	0x68, 0xd7, 0xfa, 0x7e, 0xdf, 0xfc, 0xf6, 0xed, 0xfe, 0x56, 0x5f, 0xd7, 0xe8, 0x7d, 0xb2, 0x20,
+	0xee, 0xed, 0x1f, 0x1e, 0xe9, 0x15, 0x84, 0xa0, 0xfb, 0x76, 0x7f, 0x67, 0xeb, 0x6d, 0x2a, 0x54,
+	0x45, 0x5d, 0x00, 0x4e, 0x63, 0x32, 0xb3, 0xe8, 0x16, 0x74, 0x84, 0xd2, 0xd1, 0x77, 0xc3, 0xe1,
+	0xee, 0x5b, 0xbd, 0x86, 0x74, 0x68, 0x73, 0x11, 0x41, 0xa9, 0x3f, 0x7e, 0x05, 0x90, 0xee, 0x82,
+	0xd4, 0xc6, 0xe1, 0xfe, 0x70, 0x57, 0x9f, 0x41, 0x6d, 0x68, 0x0c, 0xf7, 0xad, 0xdd, 0xe1, 0xce,
+	0xd6, 0x81, 0xae, 0xa1, 0x26, 0xd4, 0x58, 0xf8, 0xe9, 0x15, 0x3e, 0x8d, 0xc1, 0x81, 0x5e, 0x7d,
+	0xf6, 0x1a, 0x80, 0xdf, 0x20, 0xb2, 0xbf, 0xb5, 0x3e, 0x85, 0x59, 0xf6, 0x2b, 0x13, 0x47, 0xe6,
+	0xcf, 0xb2, 0x6b, 0x92, 0x96, 0xf9, 0xc3, 0xec, 0x53, 0x6d, 0x7b, 0xe5, 0xc7, 0x9f, 0xef, 0x69,
+	0xff, 0xfa, 0xf3, 0x3d, 0xed, 0xdf, 0x7e, 0xbe, 0xa7, 0xfd, 0xed, 0x7f, 0xdc, 0x9b, 0xf9, 0xe3,
+	0x1a, 0xbb, 0x7d, 0x39, 0xae, 0xb3, 0x9f, 0xaf, 0xfe, 0x67, 0x00, 0xcc, 0x12, 0xf4, 0x59, 0x8e,
+	0x2b, 0x00, 0x00,
 }
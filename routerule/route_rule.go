@@ -25,6 +25,7 @@ import (
 
 	"golang.org/x/sys/unix"
 
+	"github.com/projectcalico/felix/dryrun"
 	"github.com/projectcalico/felix/logutils"
 )
 
@@ -79,6 +80,9 @@ type RouteRules struct {
 	newNetlinkHandle func() (HandleIface, error)
 
 	opRecorder logutils.OpRecorder
+
+	// dryRun, if non-nil, receives the rule additions and deletions instead of the dataplane.
+	dryRun *dryrun.Recorder
 }
 
 func New(
@@ -90,6 +94,7 @@ func New(
 	netlinkTimeout time.Duration,
 	newNetlinkHandle func() (HandleIface, error),
 	opRecorder logutils.OpRecorder,
+	dryRun *dryrun.Recorder,
 ) (*RouteRules, error) {
 	if tableIndexSet.Len() == 0 {
 		return nil, TableIndexFailed
@@ -123,6 +128,7 @@ func New(
 		newNetlinkHandle: newNetlinkHandle,
 		netlinkTimeout:   netlinkTimeout,
 		opRecorder:       opRecorder,
+		dryRun:           dryRun,
 	}, nil
 }
 
@@ -259,6 +265,19 @@ func (r *RouteRules) Apply() error {
 
 	updatesFailed := false
 
+	if r.dryRun != nil {
+		toRemove.Iter(func(item interface{}) error {
+			r.recordDryRun("delete rule %v", item)
+			return nil
+		})
+		toAdd.Iter(func(item interface{}) error {
+			r.recordDryRun("add rule %v", item)
+			return nil
+		})
+		r.inSync = true
+		return nil
+	}
+
 	toRemove.Iter(func(item interface{}) error {
 		rule := item.(*Rule)
 		if err := nl.RuleDel(rule.nlRule); err != nil {
@@ -291,6 +310,10 @@ func (r *RouteRules) Apply() error {
 	return nil
 }
 
+func (r *RouteRules) recordDryRun(format string, args ...interface{}) {
+	r.dryRun.Record(fmt.Sprint("routerule-v", r.IPVersion), fmt.Sprintf(format, args...))
+}
+
 func ipVersionToNetlinkFamily(ipVersion int) int {
 	family := unix.AF_INET
 	if ipVersion == 6 {
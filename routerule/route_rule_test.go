@@ -23,6 +23,7 @@ import (
 
 	"golang.org/x/sys/unix"
 
+	"github.com/projectcalico/felix/dryrun"
 	"github.com/projectcalico/felix/logutils"
 	. "github.com/projectcalico/felix/routerule"
 
@@ -60,6 +61,7 @@ var _ = Describe("RouteRules Construct", func() {
 			10*time.Second,
 			dataplane.NewNetlinkHandle,
 			logutils.NewSummarizer("test loop"),
+			nil,
 		)
 		Expect(err).To(HaveOccurred())
 	})
@@ -77,6 +79,7 @@ var _ = Describe("RouteRules Construct", func() {
 			10*time.Second,
 			dataplane.NewNetlinkHandle,
 			logutils.NewSummarizer("test loop"),
+			nil,
 		)
 		Expect(err).To(HaveOccurred())
 
@@ -91,6 +94,7 @@ var _ = Describe("RouteRules Construct", func() {
 			10*time.Second,
 			dataplane.NewNetlinkHandle,
 			logutils.NewSummarizer("test loop"),
+			nil,
 		)
 		Expect(err).To(HaveOccurred())
 	})
@@ -109,6 +113,7 @@ var _ = Describe("RouteRules Construct", func() {
 			10*time.Second,
 			dataplane.NewNetlinkHandle,
 			logutils.NewSummarizer("test loop"),
+			nil,
 		)
 		Expect(err).NotTo(HaveOccurred())
 	})
@@ -140,6 +145,7 @@ var _ = Describe("RouteRules", func() {
 			10*time.Second,
 			dataplane.NewNetlinkHandle,
 			logutils.NewSummarizer("test loop"),
+			nil,
 		)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(rrs).ToNot(BeNil())
@@ -386,6 +392,76 @@ var _ = Describe("RouteRules", func() {
 	})
 })
 
+var _ = Describe("RouteRules in dry-run mode", func() {
+	var dataplane *mockDataplane
+	var rrs *RouteRules
+	var recorder *dryrun.Recorder
+
+	BeforeEach(func() {
+		dataplane = &mockDataplane{
+			ruleKeyToRule:   map[string]netlink.Rule{},
+			addedRuleKeys:   set.New(),
+			deletedRuleKeys: set.New(),
+		}
+		recorder = dryrun.NewRecorder(0)
+
+		tableIndexSet := set.New()
+		tableIndexSet.Add(10)
+		tableIndexSet.Add(250)
+
+		var err error
+		rrs, err = New(
+			4,
+			100,
+			tableIndexSet,
+			RulesMatchSrcFWMarkTable,
+			RulesMatchSrcFWMark,
+			10*time.Second,
+			dataplane.NewNetlinkHandle,
+			logutils.NewSummarizer("test loop"),
+			recorder,
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should record the changes instead of making them", func() {
+		staleRule := netlink.Rule{
+			Priority:          100,
+			Family:            unix.AF_INET,
+			Src:               mustParseCIDR("10.0.0.2/32"),
+			Mark:              0x200,
+			Mask:              0x200,
+			Table:             10,
+			Goto:              -1,
+			Flow:              -1,
+			SuppressIfgroup:   -1,
+			SuppressPrefixlen: -1,
+		}
+		dataplane.addMockRule(&staleRule)
+		rrs.SetRule(NewRule(4, 100).
+			MatchSrcAddress(*mustParseCIDR("10.0.0.3/32")).
+			MatchFWMark(0x400).
+			GoToTable(250))
+
+		Expect(rrs.Apply()).To(Succeed())
+
+		Expect(dataplane.ruleKeyToRule).To(ConsistOf(staleRule))
+		Expect(dataplane.addedRuleKeys.Len()).To(BeZero())
+		Expect(dataplane.deletedRuleKeys.Len()).To(BeZero())
+
+		changes, _ := recorder.Changes()
+		var recorded []string
+		for _, c := range changes {
+			Expect(c.Component).To(Equal("routerule-v4"))
+			recorded = append(recorded, c.Change)
+		}
+		Expect(recorded).To(ConsistOf(
+			"delete rule 100: from 10.0.0.2/32 fwmark 0x200/0x200 lookup 10",
+			"add rule 100: from 10.0.0.3/32 fwmark 0x400/0x400 lookup 250",
+		))
+	})
+})
+
 var _ = Describe("Tests to verify netlink interface", func() {
 	It("Should give expected error for missing interface", func() {
 		_, err := netlink.LinkByName("dsfhjakdhfjk")
@@ -15,6 +15,7 @@
 package routerule
 
 import (
+	"fmt"
 	"net"
 
 	"github.com/projectcalico/felix/ip"
@@ -60,6 +61,23 @@ func (r *Rule) LogCxt() *log.Entry {
 	})
 }
 
+// String describes the rule in the style of "ip rule list".
+func (r *Rule) String() string {
+	desc := fmt.Sprintf("%d:", r.nlRule.Priority)
+	if r.nlRule.Invert {
+		desc += " not"
+	}
+	if r.nlRule.Src != nil {
+		desc += fmt.Sprintf(" from %v", r.nlRule.Src)
+	} else {
+		desc += " from all"
+	}
+	if r.nlRule.Mask != 0 {
+		desc += fmt.Sprintf(" fwmark %#x/%#x", r.nlRule.Mark, r.nlRule.Mask)
+	}
+	return desc + fmt.Sprintf(" lookup %d", r.nlRule.Table)
+}
+
 func (r *Rule) markMatchesWithMask(mark, mask uint32) *Rule {
 	logCxt := log.WithFields(log.Fields{
 		"mark": mark,
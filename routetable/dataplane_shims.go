@@ -18,11 +18,12 @@ import (
 	"net"
 	"os/exec"
 
+	"github.com/projectcalico/felix/conntrack"
 	"github.com/projectcalico/felix/ip"
 )
 
 type conntrackIface interface {
-	RemoveConntrackFlows(ipVersion uint8, ipAddr net.IP)
+	RemoveConntrackFlowsForIPs(ipVersion uint8, ipAddrs []net.IP, filter conntrack.FlowFilter) (int, error)
 }
 
 func addStaticARPEntry(cidr ip.CIDR, destMAC net.HardwareAddr, ifaceName string) error {
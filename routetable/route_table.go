@@ -32,6 +32,8 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/set"
 
 	"github.com/projectcalico/felix/conntrack"
+	"github.com/projectcalico/felix/drift"
+	"github.com/projectcalico/felix/dryrun"
 	"github.com/projectcalico/felix/ifacemonitor"
 	"github.com/projectcalico/felix/ip"
 	"github.com/projectcalico/felix/logutils"
@@ -101,11 +103,35 @@ type L2Target struct {
 	GW ip.Addr
 }
 
+// NextHop is one of the next hops of a multipath (ECMP) route.
+type NextHop struct {
+	GW ip.Addr
+	// Weight is the relative share of flows that the kernel sends via this next hop, from 1 to 256.  Zero is
+	// treated as 1.
+	Weight int
+}
+
+// hops returns the next hop's weight in the form that netlink uses, which is one less than the weight.
+func (h NextHop) hops() int {
+	switch {
+	case h.Weight <= 1:
+		return 0
+	case h.Weight > 256:
+		return 255
+	default:
+		return h.Weight - 1
+	}
+}
+
 type Target struct {
 	Type    TargetType
 	CIDR    ip.CIDR
 	GW      ip.Addr
 	DestMAC net.HardwareAddr
+
+	// MultiPath, if non-empty, makes this an ECMP route that spreads flows over the given next hops, all of
+	// which are reached through the route's interface.  GW should be nil for such a route.
+	MultiPath []NextHop
 }
 
 func (t Target) Equal(t2 Target) bool {
@@ -163,12 +189,21 @@ type RouteTable struct {
 	ifacePrefixRegexp     *regexp.Regexp
 	includeNoInterface    bool
 
+	// resyncReason is the reason for the pending QueueResync() (or start-of-day) resync.
+	resyncReason string
+	// ifaceNameToResyncReason holds the reason for each interface's pending full resync, which we
+	// include in any drift that the resync finds.
+	ifaceNameToResyncReason map[string]string
+
 	ifaceNameToTargets             map[string]map[ip.CIDR]Target
 	ifaceNameToL2Targets           map[string][]L2Target
 	ifaceNameToFirstSeen           map[string]time.Time
 	pendingIfaceNameToDeltaTargets map[string]map[ip.CIDR]*Target
 	pendingIfaceNameToL2Targets    map[string][]L2Target
 
+	// queuedConntrackDeletions holds the IPs whose conntrack entries need deleting but whose
+	// deletion has not yet been started.
+	queuedConntrackDeletions set.Set
 	pendingConntrackCleanups map[ip.Addr]chan struct{}
 
 	// Whether this route table is managing vxlan routes.
@@ -189,6 +224,11 @@ type RouteTable struct {
 	time              timeshim.Interface
 
 	opReporter logutils.OpRecorder
+
+	// dryRun, if non-nil, receives the route, ARP and FDB changes instead of the kernel.
+	dryRun *dryrun.Recorder
+	// driftReporter receives the out-of-sync routes that we find when we resync.
+	driftReporter *drift.Reporter
 }
 
 func New(
@@ -201,6 +241,8 @@ func New(
 	removeExternalRoutes bool,
 	tableIndex int,
 	opReporter logutils.OpRecorder,
+	dryRun *dryrun.Recorder,
+	driftReporter *drift.Reporter,
 ) *RouteTable {
 	return NewWithShims(
 		interfaceRegexes,
@@ -216,6 +258,8 @@ func New(
 		removeExternalRoutes,
 		tableIndex,
 		opReporter,
+		dryRun,
+		driftReporter,
 	)
 }
 
@@ -234,6 +278,8 @@ func NewWithShims(
 	removeExternalRoutes bool,
 	tableIndex int,
 	opReporter logutils.OpRecorder,
+	dryRun *dryrun.Recorder,
+	driftReporter *drift.Reporter,
 ) *RouteTable {
 	var regexpParts []string
 	includeNoOIF := false
@@ -270,7 +316,10 @@ func NewWithShims(
 		pendingIfaceNameToDeltaTargets: map[string]map[ip.CIDR]*Target{},
 		pendingIfaceNameToL2Targets:    map[string][]L2Target{},
 		reSync:                         true,
+		resyncReason:                   drift.ResyncStartup,
 		ifaceNameToUpdateType:          map[string]updateType{},
+		ifaceNameToResyncReason:        map[string]string{},
+		queuedConntrackDeletions:       set.New(),
 		pendingConntrackCleanups:       map[ip.Addr]chan struct{}{},
 		newNetlinkHandle:               newNetlinkHandle,
 		netlinkTimeout:                 netlinkTimeout,
@@ -283,6 +332,8 @@ func NewWithShims(
 		removeExternalRoutes:           removeExternalRoutes,
 		tableIndex:                     tableIndex,
 		opReporter:                     opReporter,
+		dryRun:                         dryRun,
+		driftReporter:                  driftReporter,
 	}
 }
 
@@ -294,7 +345,7 @@ func (r *RouteTable) OnIfaceStateChanged(ifaceName string, state ifacemonitor.St
 	}
 	if state == ifacemonitor.StateUp {
 		logCxt.Debug("Interface up, marking for route sync")
-		r.ifaceNameToUpdateType[ifaceName] = updateTypeFullResync
+		r.markIfaceForResync(ifaceName, "interface up")
 		r.onIfaceSeen(ifaceName)
 	}
 }
@@ -319,6 +370,15 @@ func (r *RouteTable) markIfaceForUpdate(ifaceName string, resync bool) {
 	}
 }
 
+// markIfaceForResync marks an interface for a full resync, recording the reason for the resync if the
+// interface doesn't already have one pending.
+func (r *RouteTable) markIfaceForResync(ifaceName string, reason string) {
+	r.markIfaceForUpdate(ifaceName, true)
+	if _, ok := r.ifaceNameToResyncReason[ifaceName]; !ok {
+		r.ifaceNameToResyncReason[ifaceName] = reason
+	}
+}
+
 // SetRoutes sets the full set of targets for the specified interface. This recalculates the deltas from the current
 // set of programmed routes.
 func (r *RouteTable) SetRoutes(ifaceName string, targets []Target) {
@@ -405,6 +465,9 @@ func (r *RouteTable) SetL2Routes(ifaceName string, targets []L2Target) {
 
 func (r *RouteTable) QueueResync() {
 	r.logCxt.Debug("Queueing a resync of routing table.")
+	if !r.reSync {
+		r.resyncReason = "refresh"
+	}
 	r.reSync = true
 }
 
@@ -478,7 +541,7 @@ func (r *RouteTable) Apply() error {
 			if r.ifacePrefixRegexp.MatchString(ifaceName) {
 				r.logCxt.WithField("ifaceName", ifaceName).Debug(
 					"Resync: found calico-owned interface")
-				r.markIfaceForUpdate(ifaceName, true)
+				r.markIfaceForResync(ifaceName, r.resyncReason)
 				r.onIfaceSeen(ifaceName)
 				seen.Add(ifaceName)
 			}
@@ -503,7 +566,7 @@ func (r *RouteTable) Apply() error {
 		// If we are managing no-OIF routes then add that to our dirty set.
 		if r.includeNoInterface {
 			log.Debug("Flag no OIF for full re-sync")
-			r.markIfaceForUpdate(InterfaceNone, true)
+			r.markIfaceForResync(InterfaceNone, r.resyncReason)
 		}
 
 		r.reSync = false
@@ -554,11 +617,19 @@ func (r *RouteTable) Apply() error {
 				// The interface might be flapping or being deleted. Flag that it will require a full re-sync
 				logCxt.Warn("Failed to sync routes to interface even after retries. " +
 					"Leaving it dirty, requiring a full sync.")
-				r.markIfaceForUpdate(ifaceName, true)
+				r.markIfaceForResync(ifaceName, drift.ResyncUpdateFailure)
 			}
 		}
 	}
 
+	// Tidy up the resync reasons for interfaces that are now in sync.
+	for ifaceName := range r.ifaceNameToResyncReason {
+		if _, ok := r.ifaceNameToUpdateType[ifaceName]; !ok {
+			delete(r.ifaceNameToResyncReason, ifaceName)
+		}
+	}
+
+	r.startConntrackDeletions()
 	r.cleanUpPendingConntrackDeletions()
 
 	// Don't return a failure if there are only interfaces in the cleanup grace period.
@@ -589,7 +660,7 @@ func (r *RouteTable) syncRoutesForLink(ifaceName string, fullSync bool, firstTry
 			cidr := item.(ip.CIDR)
 			if _, ok := cidrsToTarget[cidr]; !ok {
 				// Route is deleted and CIDR should not be routable anymore - remove conntrack entries.
-				r.startConntrackDeletion(cidr.Addr())
+				r.queueConntrackDeletion(cidr.Addr())
 			}
 			return nil
 		})
@@ -605,11 +676,18 @@ func (r *RouteTable) syncRoutesForLink(ifaceName string, fullSync bool, firstTry
 	if fullSync {
 		// Performing a full re-sync.  Start by applying the deltas so that we don't delete routes that are required.
 		logCxt.Debug("Reconcile against kernel programming")
-		_, _ = r.applyRouteDeltas(ifaceName, deletedConnCIDRs)
+		pendingCreates, pendingDeletes := r.applyRouteDeltas(ifaceName, deletedConnCIDRs)
+
+		// The routes that the deltas change haven't been programmed yet so the resync shouldn't report them as
+		// drift.
+		pendingCIDRs := set.New()
+		for _, target := range append(pendingCreates, pendingDeletes...) {
+			pendingCIDRs.Add(target.CIDR)
+		}
 
 		// Now do the resync - this will update our deltas again based on what is not programmed (it's a little bit
 		// circuitous, but simplifies the code paths for resync and delta processing).
-		if routesToDelete, resyncErr = r.fullResyncRoutesForLink(logCxt, ifaceName, deletedConnCIDRs); resyncErr != nil && resyncErr != IfaceGrace {
+		if routesToDelete, resyncErr = r.fullResyncRoutesForLink(logCxt, ifaceName, deletedConnCIDRs, pendingCIDRs); resyncErr != nil && resyncErr != IfaceGrace {
 			// If we hit anything other than an interface-in-grace error, exit now.
 			r.logCxt.WithError(resyncErr).Info("Hit error doing kernel reconciliation")
 			return r.filterErrorByIfaceState(ifaceName, resyncErr, UpdateFailed, firstTry)
@@ -619,7 +697,7 @@ func (r *RouteTable) syncRoutesForLink(ifaceName string, fullSync bool, firstTry
 		for _, target := range r.ifaceNameToTargets[ifaceName] {
 			if r.ipVersion == 4 && target.DestMAC != nil {
 				// TODO(smc) clean up/sync old ARP entries
-				err := r.addStaticARPEntry(target.CIDR, target.DestMAC, ifaceName)
+				err := r.setStaticARPEntry(target.CIDR, target.DestMAC, ifaceName)
 				if err != nil {
 					logCxt.WithError(err).Warn("Failed to set ARP entry")
 					updatesFailed = true
@@ -650,6 +728,10 @@ func (r *RouteTable) syncRoutesForLink(ifaceName string, fullSync bool, firstTry
 
 	// Delete the combined set of routes.
 	for _, route := range routesToDelete {
+		if r.dryRun != nil {
+			r.recordDryRun("del route %v dev %s", routeDescription(route), ifaceName)
+			continue
+		}
 		if err := nl.RouteDel(&route); err != nil {
 			logCxt.WithError(err).Warn("Failed to delete route")
 			updatesFailed = true
@@ -663,7 +745,9 @@ func (r *RouteTable) syncRoutesForLink(ifaceName string, fullSync bool, firstTry
 		// In case this IP is being re-used, wait for any previous conntrack entry
 		// to be cleaned up.  (No-op if there are no pending deletes.)
 		r.waitForPendingConntrackDeletion(target.CIDR.Addr())
-		if err := nl.RouteAdd(&route); err != nil {
+		if r.dryRun != nil {
+			r.recordDryRun("add route %v dev %s", routeDescription(route), ifaceName)
+		} else if err := nl.RouteAdd(&route); err != nil {
 			if firstTry {
 				logCxt.WithError(err).Debug("Failed to add route on first attempt, retrying...")
 			} else {
@@ -673,7 +757,7 @@ func (r *RouteTable) syncRoutesForLink(ifaceName string, fullSync bool, firstTry
 		}
 		if r.ipVersion == 4 && target.DestMAC != nil {
 			// TODO(smc) clean up/sync old ARP entries
-			err := r.addStaticARPEntry(target.CIDR, target.DestMAC, ifaceName)
+			err := r.setStaticARPEntry(target.CIDR, target.DestMAC, ifaceName)
 			if err != nil {
 				logCxt.WithError(err).Warn("Failed to set ARP entry")
 				updatesFailed = true
@@ -763,13 +847,28 @@ func (r *RouteTable) createL3Route(linkAttrs *netlink.LinkAttrs, target Target)
 		route.SetFlag(syscall.RTNH_F_ONLINK)
 	}
 
+	if len(target.MultiPath) > 0 {
+		// The interface and flags of a multipath route belong to its next hops.  The kernel doesn't report an
+		// interface for the route itself so we leave it out here too, which keeps the route the same as the one
+		// that we list back during a resync.
+		route.LinkIndex = 0
+		for _, nh := range target.MultiPath {
+			route.MultiPath = append(route.MultiPath, &netlink.NexthopInfo{
+				LinkIndex: linkIndex,
+				Gw:        nh.GW.AsNetIP(),
+				Hops:      nh.hops(),
+				Flags:     route.Flags,
+			})
+		}
+	}
+
 	return route
 }
 
 // fullResyncRoutesForLink performs a full resync of the routes by first listing current routes and correlating against
 // the expected set. After correlation, it will create a set of routes to delete and update the delta routes to add
-// back any missing routes.
-func (r *RouteTable) fullResyncRoutesForLink(logCxt *log.Entry, ifaceName string, deletedConnCIDRs set.Set) ([]netlink.Route, error) {
+// back any missing routes.  Problems with routes other than those in pendingCIDRs are reported as drift.
+func (r *RouteTable) fullResyncRoutesForLink(logCxt *log.Entry, ifaceName string, deletedConnCIDRs, pendingCIDRs set.Set) ([]netlink.Route, error) {
 	// Get the netlink client and the link attributes
 	nl, err := r.getNetlink()
 	if err != nil {
@@ -793,16 +892,21 @@ func (r *RouteTable) fullResyncRoutesForLink(logCxt *log.Entry, ifaceName string
 	// was oper down before we tried to do the sync but that prevented us from removing
 	// routes from an interface in some corner cases (such as being admin up but oper
 	// down).
+	//
+	// Multipath routes don't have an interface of their own, only their next hops do, so we can't ask netlink to
+	// filter by interface.  Netlink filters the routes in user space anyway so we list the whole table and then
+	// pick out the routes that use this interface ourselves.
 	routeFilter := &netlink.Route{
 		Table: r.tableIndex,
 	}
-	routeFilterFlags := netlink.RT_FILTER_OIF
+	var routeFilterFlags uint64
 	if r.tableIndex != 0 {
 		routeFilterFlags |= netlink.RT_FILTER_TABLE
 	}
+	var linkIndex int
 	if linkAttrs != nil {
 		// Link attributes might be nil for the special "no-OIF" interface name.
-		routeFilter.LinkIndex = linkAttrs.Index
+		linkIndex = linkAttrs.Index
 	}
 	programmedRoutes, err := nl.RouteListFiltered(r.netlinkFamily, routeFilter, routeFilterFlags)
 	if err != nil {
@@ -826,8 +930,12 @@ func (r *RouteTable) fullResyncRoutesForLink(logCxt *log.Entry, ifaceName string
 		r.pendingIfaceNameToDeltaTargets[ifaceName] = pendingDeltaTargets
 	}
 	alreadyCorrectCIDRs := set.New()
+	incorrectCIDRs := set.New()
 	leaveDirty := false
 	for _, route := range programmedRoutes {
+		if !routeUsesLink(route, linkIndex) {
+			continue
+		}
 		logCxt.Debugf("Processing route: %v %v %v", route.Table, route.LinkIndex, route.Dst)
 		var dest ip.CIDR
 		if route.Dst != nil {
@@ -861,6 +969,9 @@ func (r *RouteTable) fullResyncRoutesForLink(logCxt *log.Entry, ifaceName string
 				(route.Gw != nil && expectedTarget.GW != nil && !route.Gw.Equal(expectedTarget.GW.AsNetIP())) {
 				routeProblems = append(routeProblems, "incorrect gateway")
 			}
+			if !nextHopsMatch(route.MultiPath, expectedTarget.MultiPath) {
+				routeProblems = append(routeProblems, "incorrect next hops")
+			}
 		}
 		if len(routeProblems) == 0 {
 			logCxt.Debug("Route is correct")
@@ -874,6 +985,10 @@ func (r *RouteTable) fullResyncRoutesForLink(logCxt *log.Entry, ifaceName string
 			continue
 		}
 		logCxt.WithField("routeProblems", routeProblems).Info("Remove old route")
+		if !pendingCIDRs.Contains(dest) {
+			r.reportDrift(ifaceName, routeDescription(route), strings.Join(routeProblems, ", "))
+		}
+		incorrectCIDRs.Add(dest)
 		routesToDelete = append(routesToDelete, route)
 		if dest != nil {
 			deletedConnCIDRs.Add(dest)
@@ -889,6 +1004,10 @@ func (r *RouteTable) fullResyncRoutesForLink(logCxt *log.Entry, ifaceName string
 		logCxt := logCxt.WithField("cidr", cidr)
 		logCxt.Info("Deleting from expected targets")
 		delete(expectedTargets, cidr)
+		if !incorrectCIDRs.Contains(cidr) && !pendingCIDRs.Contains(cidr) {
+			// Routes that were incorrect have already been reported.
+			r.reportDrift(ifaceName, cidr.String(), "route missing")
+		}
 
 		// If we do not have an update that supercedes this entry, then add it back in as an update so that we add
 		// the route.
@@ -911,6 +1030,63 @@ func (r *RouteTable) fullResyncRoutesForLink(logCxt *log.Entry, ifaceName string
 	return routesToDelete, nil
 }
 
+// routeUsesLink returns true if the route sends traffic through the interface with the given index (0 for the
+// "no-OIF" interface).  A multipath route uses an interface if all of its next hops do.
+func routeUsesLink(route netlink.Route, linkIndex int) bool {
+	if len(route.MultiPath) == 0 {
+		return route.LinkIndex == linkIndex
+	}
+	for _, nh := range route.MultiPath {
+		if nh.LinkIndex != linkIndex {
+			return false
+		}
+	}
+	return true
+}
+
+// nextHopsMatch returns true if the programmed next hops of a route have the expected gateways and weights, in
+// any order.
+func nextHopsMatch(programmed []*netlink.NexthopInfo, expected []NextHop) bool {
+	if len(programmed) != len(expected) {
+		return false
+	}
+	matched := make([]bool, len(programmed))
+	for _, nh := range expected {
+		found := false
+		for i, p := range programmed {
+			if !matched[i] && p.Gw.Equal(nh.GW.AsNetIP()) && p.Hops == nh.hops() {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// reportDrift tells the drift reporter that a full resync of the given interface found a problem
+// with a route.
+func (r *RouteTable) reportDrift(ifaceName, route, problem string) {
+	reason := r.ifaceNameToResyncReason[ifaceName]
+	if reason == "" {
+		// The last attempt to sync an interface is always a full resync.
+		reason = drift.ResyncUpdateFailure
+	}
+	if drift.IsExpected(reason) {
+		return
+	}
+	r.driftReporter.Report(drift.Event{
+		Subsystem: drift.SubsystemRouteTable,
+		Component: r.componentName(),
+		Object:    fmt.Sprintf("%s dev %s", route, ifaceName),
+		Problem:   problem,
+		Resync:    reason,
+	})
+}
+
 func (r *RouteTable) syncL2RoutesForLink(ifaceName string) error {
 	logCxt := r.logCxt.WithField("ifaceName", ifaceName)
 	logCxt.Debug("Syncing interface L2 routes")
@@ -941,7 +1117,7 @@ func (r *RouteTable) syncL2RoutesForLink(ifaceName string) error {
 	}
 
 	// Get the current set of neighbors on this interface.
-	existingNeigh, err := netlink.NeighList(linkAttrs.Index, netlink.FAMILY_V4)
+	existingNeigh, err := netlink.NeighList(linkAttrs.Index, r.netlinkFamily)
 	if err != nil {
 		return err
 	}
@@ -953,6 +1129,11 @@ func (r *RouteTable) syncL2RoutesForLink(ifaceName string) error {
 	for _, existing := range existingNeigh {
 		if _, ok := expected[existing.HardwareAddr.String()]; !ok {
 			logCxt.WithField("neighbor", existing).Debug("Neighbor should no longer be programmed")
+			if r.dryRun != nil {
+				r.recordDryRun("del neighbor %v lladdr %v dev %s and its FDB entry",
+					existing.IP, existing.HardwareAddr, ifaceName)
+				continue
+			}
 
 			// Remove the FDB entry for this neighbor.
 			n := netlink.Neigh{
@@ -987,6 +1168,15 @@ func (r *RouteTable) syncL2RoutesForLink(ifaceName string) error {
 	// For each expected target, ensure that it is programmed. If the value has changed since last programming, this
 	// will update it.
 	for _, target := range expectedTargets {
+		if r.dryRun != nil {
+			// The real dataplane rewrites every entry but only report the ones that would
+			// change.  We only list the ARP entries; the FDB entries are updated alongside them.
+			if !neighborProgrammed(existingNeigh, target) {
+				r.recordDryRun("set neighbor %v lladdr %v dev %s and its FDB entry for %v",
+					target.GW, target.VTEPMAC, ifaceName, target.IP)
+			}
+			continue
+		}
 		if err = r.ensureL2Dataplane(linkAttrs, target); err != nil {
 			logCxt.WithError(err).Warnf("Failed to sync L2 dataplane for interface")
 			updatesFailed = true
@@ -1036,20 +1226,111 @@ func (r *RouteTable) ensureL2Dataplane(linkAttrs *netlink.LinkAttrs, target L2Ta
 	return nil
 }
 
-// startConntrackDeletion starts the deletion of conntrack entries for the given CIDR in the background.  Pending
+// neighborProgrammed returns true if the ARP entry for the given target is already in the list of
+// neighbors.
+func neighborProgrammed(neighbors []netlink.Neigh, target L2Target) bool {
+	for _, n := range neighbors {
+		if n.IP.Equal(target.GW.AsNetIP()) && n.HardwareAddr.String() == target.VTEPMAC.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// setStaticARPEntry adds a static ARP entry, or, in dry-run mode, records that we would have.
+func (r *RouteTable) setStaticARPEntry(cidr ip.CIDR, destMAC net.HardwareAddr, ifaceName string) error {
+	if r.dryRun != nil {
+		r.recordDryRun("set ARP entry %v lladdr %v dev %s", cidr.Addr(), destMAC, ifaceName)
+		return nil
+	}
+	return r.addStaticARPEntry(cidr, destMAC, ifaceName)
+}
+
+// recordDryRun passes a change that we've skipped to the dry-run recorder.
+func (r *RouteTable) recordDryRun(format string, args ...interface{}) {
+	r.dryRun.Record(r.componentName(), fmt.Sprintf(format, args...))
+}
+
+// componentName returns the name that we use for this route table in dry-run changes and drift events.
+func (r *RouteTable) componentName() string {
+	component := fmt.Sprint("routetable-v", r.ipVersion)
+	if r.tableIndex != 0 {
+		component = fmt.Sprintf("%s-table-%d", component, r.tableIndex)
+	}
+	return component
+}
+
+// routeDescription formats a route for the dry-run log and drift events, leaving out the fields
+// that aren't set.
+func routeDescription(route netlink.Route) string {
+	desc := "default"
+	if route.Dst != nil {
+		desc = route.Dst.String()
+	}
+	switch route.Type {
+	case syscall.RTN_BLACKHOLE:
+		desc = "blackhole " + desc
+	case syscall.RTN_PROHIBIT:
+		desc = "prohibit " + desc
+	case syscall.RTN_THROW:
+		desc = "throw " + desc
+	}
+	if route.Gw != nil {
+		desc += fmt.Sprintf(" via %v", route.Gw)
+	}
+	for _, nh := range route.MultiPath {
+		desc += fmt.Sprintf(" nexthop via %v weight %d", nh.Gw, nh.Hops+1)
+	}
+	if route.Src != nil {
+		desc += fmt.Sprintf(" src %v", route.Src)
+	}
+	if route.Table != 0 && route.Table != syscall.RT_TABLE_MAIN {
+		desc += fmt.Sprintf(" table %d", route.Table)
+	}
+	if route.Protocol != 0 {
+		desc += fmt.Sprintf(" proto %d", route.Protocol)
+	}
+	return desc
+}
+
+// queueConntrackDeletion queues the deletion of conntrack entries for the given IP.  Queued deletions are
+// started as a single batch by startConntrackDeletions.
+func (r *RouteTable) queueConntrackDeletion(ipAddr ip.Addr) {
+	if r.dryRun != nil {
+		r.recordDryRun("delete conntrack flows for %v", ipAddr)
+		return
+	}
+	r.queuedConntrackDeletions.Add(ipAddr)
+}
+
+// startConntrackDeletions starts the deletion of conntrack entries for the queued IPs in the background.  Pending
 // deletions are tracked in the pendingConntrackCleanups map so we can block waiting for them later.
 //
 // It's important to do the conntrack deletions in the background because scanning the conntrack
 // table is very slow if there are a lot of entries.  Previously, we did the deletion synchronously
-// but that led to lengthy Apply() calls on the critical path.
-func (r *RouteTable) startConntrackDeletion(ipAddr ip.Addr) {
-	log.WithField("ip", ipAddr).Debug("Starting goroutine to delete conntrack entries")
+// but that led to lengthy Apply() calls on the critical path.  For the same reason, we delete the
+// entries for all the queued IPs in one scan of the table, rather than one scan per IP.
+func (r *RouteTable) startConntrackDeletions() {
+	if r.queuedConntrackDeletions.Len() == 0 {
+		return
+	}
 	done := make(chan struct{})
-	r.pendingConntrackCleanups[ipAddr] = done
+	var ipAddrs []net.IP
+	r.queuedConntrackDeletions.Iter(func(item interface{}) error {
+		ipAddr := item.(ip.Addr)
+		r.pendingConntrackCleanups[ipAddr] = done
+		ipAddrs = append(ipAddrs, ipAddr.AsNetIP())
+		return set.RemoveItem
+	})
+	log.WithField("ips", ipAddrs).Debug("Starting goroutine to delete conntrack entries")
 	go func() {
 		defer close(done)
-		r.conntrack.RemoveConntrackFlows(r.ipVersion, ipAddr.AsNetIP())
-		log.WithField("ip", ipAddr).Debug("Deleted conntrack entries")
+		_, err := r.conntrack.RemoveConntrackFlowsForIPs(r.ipVersion, ipAddrs, conntrack.FlowFilter{})
+		if err != nil {
+			log.WithError(err).WithField("ips", ipAddrs).Warn("Failed to delete conntrack entries")
+			return
+		}
+		log.WithField("ips", ipAddrs).Debug("Deleted conntrack entries")
 	}()
 }
 
@@ -1071,6 +1352,10 @@ func (r *RouteTable) cleanUpPendingConntrackDeletions() {
 
 // waitForPendingConntrackDeletion waits for any pending conntrack deletions (if any) for the given IP to complete.
 func (r *RouteTable) waitForPendingConntrackDeletion(ipAddr ip.Addr) {
+	if r.queuedConntrackDeletions.Contains(ipAddr) {
+		// The deletion hasn't been started yet; start it now along with any others in the queue.
+		r.startConntrackDeletions()
+	}
 	if c := r.pendingConntrackCleanups[ipAddr]; c != nil {
 		log.WithField("ip", ipAddr).Info("Waiting for pending conntrack deletion to finish")
 		<-c
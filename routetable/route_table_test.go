@@ -15,6 +15,8 @@
 package routetable_test
 
 import (
+	"github.com/projectcalico/felix/drift"
+	"github.com/projectcalico/felix/dryrun"
 	"github.com/projectcalico/felix/logutils"
 	. "github.com/projectcalico/felix/routetable"
 
@@ -71,6 +73,8 @@ var _ = Describe("RouteTable v6", func() {
 			true,
 			0,
 			logutils.NewSummarizer("test"),
+			nil,
+			nil,
 		)
 	})
 
@@ -125,6 +129,8 @@ var _ = Describe("RouteTable", func() {
 			true,
 			0,
 			logutils.NewSummarizer("test"),
+			nil,
+			nil,
 		)
 	})
 
@@ -223,6 +229,8 @@ var _ = Describe("RouteTable", func() {
 				net.ParseIP("10.0.0.1").To4(),
 				net.ParseIP("10.0.0.3").To4(),
 			))
+			Expect(dataplane.GetNumConntrackDeletionBatches()).To(Equal(1),
+				"Expected the deletions for both interfaces to be done in one batch")
 		})
 		It("Should clear out a source address when source address is not set", func() {
 			updateLink := dataplane.AddIface(5, "cali5", true, true)
@@ -267,6 +275,8 @@ var _ = Describe("RouteTable", func() {
 					true,
 					0,
 					logutils.NewSummarizer("test"),
+					nil,
+					nil,
 				)
 			})
 			It("Should delete routes without a source address", func() {
@@ -385,6 +395,8 @@ var _ = Describe("RouteTable", func() {
 					true,
 					0,
 					logutils.NewSummarizer("test"),
+					nil,
+					nil,
 				)
 			})
 			It("Should delete routes without a protocol", func() {
@@ -999,6 +1011,8 @@ var _ = Describe("RouteTable (main table)", func() {
 			true,
 			0,
 			logutils.NewSummarizer("test"),
+			nil,
+			nil,
 		)
 	})
 
@@ -1098,6 +1112,8 @@ var _ = Describe("RouteTable (table 100)", func() {
 			true,
 			100,
 			logutils.NewSummarizer("test"),
+			nil,
+			nil,
 		)
 	})
 
@@ -1286,6 +1302,278 @@ var _ = Describe("Tests to verify netlink interface", func() {
 	})
 })
 
+var _ = Describe("RouteTable in dry-run mode", func() {
+	var dataplane *mocknetlink.MockNetlinkDataplane
+	var rt *RouteTable
+	var recorder *dryrun.Recorder
+
+	BeforeEach(func() {
+		dataplane = mocknetlink.New()
+		t := mocktime.New()
+		t.SetAutoIncrement(11 * time.Second)
+		recorder = dryrun.NewRecorder(0)
+		rt = NewWithShims(
+			[]string{"^cali.*"},
+			4,
+			dataplane.NewMockNetlink,
+			false,
+			10*time.Second,
+			dataplane.AddStaticArpEntry,
+			dataplane,
+			t,
+			nil,
+			FelixRouteProtocol,
+			true,
+			0,
+			logutils.NewSummarizer("test"),
+			recorder,
+			nil,
+		)
+	})
+
+	It("should record the changes instead of making them", func() {
+		cali1 := dataplane.AddIface(1, "cali1", true, true)
+		dataplane.AddIface(3, "cali3", true, true)
+		staleRoute := netlink.Route{
+			LinkIndex: cali1.LinkAttrs.Index,
+			Dst:       mustParseCIDR("10.0.0.1/32"),
+			Type:      syscall.RTN_UNICAST,
+			Protocol:  FelixRouteProtocol,
+			Scope:     netlink.SCOPE_LINK,
+		}
+		dataplane.AddMockRoute(&staleRoute)
+		rt.SetRoutes("cali3", []Target{
+			{CIDR: ip.MustParseCIDROrIP("10.0.0.3"), DestMAC: mac1},
+		})
+
+		Expect(rt.Apply()).To(Succeed())
+
+		Expect(dataplane.RouteKeyToRoute).To(ConsistOf(staleRoute))
+		Expect(dataplane.AddedRouteKeys).To(BeEmpty())
+		Expect(dataplane.DeletedRouteKeys).To(BeEmpty())
+		Expect(dataplane.HasStaticArpEntry(ip.MustParseCIDROrIP("10.0.0.3"), mac1, "cali3")).To(BeFalse())
+		Consistently(dataplane.GetDeletedConntrackEntries).Should(BeEmpty())
+
+		changes, _ := recorder.Changes()
+		var recorded []string
+		for _, c := range changes {
+			Expect(c.Component).To(Equal("routetable-v4"))
+			recorded = append(recorded, c.Change)
+		}
+		Expect(recorded).To(ConsistOf(
+			"del route 10.0.0.1/32 proto 3 dev cali1",
+			"delete conntrack flows for 10.0.0.1",
+			"add route 10.0.0.3/32 proto 3 dev cali3",
+			"set ARP entry 10.0.0.3 lladdr 00:11:22:33:44:51 dev cali3",
+		))
+	})
+})
+
+var _ = Describe("RouteTable drift reporting", func() {
+	var dataplane *mocknetlink.MockNetlinkDataplane
+	var rt *RouteTable
+	var reporter *drift.Reporter
+
+	BeforeEach(func() {
+		dataplane = mocknetlink.New()
+		t := mocktime.New()
+		t.SetAutoIncrement(11 * time.Second)
+		reporter = drift.NewReporter(0, "")
+		rt = NewWithShims(
+			[]string{"^cali.*"},
+			4,
+			dataplane.NewMockNetlink,
+			false,
+			10*time.Second,
+			dataplane.AddStaticArpEntry,
+			dataplane,
+			t,
+			nil,
+			FelixRouteProtocol,
+			true,
+			0,
+			logutils.NewSummarizer("test"),
+			nil,
+			reporter,
+		)
+	})
+
+	It("should not report the differences found by the startup resync", func() {
+		cali1 := dataplane.AddIface(1, "cali1", true, true)
+		staleRoute := netlink.Route{
+			LinkIndex: cali1.LinkAttrs.Index,
+			Dst:       &ip13,
+			Type:      syscall.RTN_UNICAST,
+			Protocol:  FelixRouteProtocol,
+			Scope:     netlink.SCOPE_LINK,
+		}
+		dataplane.AddMockRoute(&staleRoute)
+		rt.SetRoutes("cali1", []Target{
+			{CIDR: ip.MustParseCIDROrIP("10.0.0.1"), DestMAC: mac1},
+		})
+		Expect(rt.Apply()).To(Succeed())
+
+		Expect(dataplane.DeletedRouteKeys.Len()).To(Equal(1))
+		events, _ := reporter.Events()
+		Expect(events).To(BeEmpty())
+	})
+
+	It("should report routes that another process has changed", func() {
+		cali1 := dataplane.AddIface(1, "cali1", true, true)
+		rt.SetRoutes("cali1", []Target{
+			{CIDR: ip.MustParseCIDROrIP("10.0.0.1"), DestMAC: mac1},
+			{CIDR: ip.MustParseCIDROrIP("10.0.0.2"), DestMAC: mac1},
+		})
+		Expect(rt.Apply()).To(Succeed())
+		events, _ := reporter.Events()
+		Expect(events).To(BeEmpty())
+
+		// Remove one of our routes and add one that we don't expect.
+		dataplane.RemoveMockRoute(&netlink.Route{
+			LinkIndex: cali1.LinkAttrs.Index,
+			Dst:       &ip1,
+		})
+		dataplane.AddMockRoute(&netlink.Route{
+			LinkIndex: cali1.LinkAttrs.Index,
+			Dst:       &ip13,
+			Type:      syscall.RTN_UNICAST,
+			Protocol:  FelixRouteProtocol,
+			Scope:     netlink.SCOPE_LINK,
+		})
+		rt.QueueResync()
+		Expect(rt.Apply()).To(Succeed())
+
+		events, _ = reporter.Events()
+		var problems []string
+		for _, e := range events {
+			Expect(e.Subsystem).To(Equal(drift.SubsystemRouteTable))
+			Expect(e.Component).To(Equal("routetable-v4"))
+			Expect(e.Resync).To(Equal("refresh"))
+			problems = append(problems, e.Object+": "+e.Problem)
+		}
+		Expect(problems).To(ConsistOf(
+			"10.0.0.1/32 dev cali1: route missing",
+			"10.0.1.3/32 proto 3 dev cali1: unexpected route",
+		))
+	})
+})
+
+var _ = Describe("RouteTable with ECMP routes", func() {
+	var dataplane *mocknetlink.MockNetlinkDataplane
+	var rt *RouteTable
+	var reporter *drift.Reporter
+	var vxlanIface *mocknetlink.MockLink
+	var ecmpTarget Target
+	var ecmpRoute netlink.Route
+
+	BeforeEach(func() {
+		dataplane = mocknetlink.New()
+		t := mocktime.New()
+		t.SetAutoIncrement(11 * time.Second)
+		reporter = drift.NewReporter(0, "")
+		rt = NewWithShims(
+			[]string{"^vxlan.calico$"},
+			4,
+			dataplane.NewMockNetlink,
+			true,
+			10*time.Second,
+			dataplane.AddStaticArpEntry,
+			dataplane,
+			t,
+			nil,
+			FelixRouteProtocol,
+			true,
+			0,
+			logutils.NewSummarizer("test"),
+			nil,
+			reporter,
+		)
+		vxlanIface = dataplane.AddIface(5, "vxlan.calico", true, true)
+		ecmpTarget = Target{
+			Type: TargetTypeVXLAN,
+			CIDR: ip.MustParseCIDROrIP("10.0.1.0/26"),
+			MultiPath: []NextHop{
+				{GW: ip.FromString("10.0.2.1"), Weight: 1},
+				{GW: ip.FromString("10.0.2.2"), Weight: 3},
+			},
+		}
+		ecmpRoute = netlink.Route{
+			Dst:      mustParseCIDR("10.0.1.0/26"),
+			Type:     syscall.RTN_UNICAST,
+			Protocol: FelixRouteProtocol,
+			Scope:    netlink.SCOPE_UNIVERSE,
+			Flags:    syscall.RTNH_F_ONLINK,
+			MultiPath: []*netlink.NexthopInfo{
+				{
+					LinkIndex: vxlanIface.LinkAttrs.Index,
+					Gw:        net.ParseIP("10.0.2.1").To4(),
+					Hops:      0,
+					Flags:     syscall.RTNH_F_ONLINK,
+				},
+				{
+					LinkIndex: vxlanIface.LinkAttrs.Index,
+					Gw:        net.ParseIP("10.0.2.2").To4(),
+					Hops:      2,
+					Flags:     syscall.RTNH_F_ONLINK,
+				},
+			},
+		}
+		rt.SetRoutes("vxlan.calico", []Target{ecmpTarget})
+		Expect(rt.Apply()).To(Succeed())
+	})
+
+	It("should program a route with a next hop for each gateway", func() {
+		Expect(dataplane.RouteKeyToRoute).To(HaveLen(1))
+		Expect(dataplane.RouteKeyToRoute[mocknetlink.KeyForRoute(&ecmpRoute)]).To(Equal(ecmpRoute))
+	})
+
+	It("should leave the route alone on resync", func() {
+		rt.QueueResync()
+		Expect(rt.Apply()).To(Succeed())
+		Expect(dataplane.DeletedRouteKeys).To(BeEmpty())
+		Expect(dataplane.RouteKeyToRoute[mocknetlink.KeyForRoute(&ecmpRoute)]).To(Equal(ecmpRoute))
+		events, _ := reporter.Events()
+		Expect(events).To(BeEmpty())
+	})
+
+	It("should fix the next hops if another process changes them", func() {
+		badRoute := ecmpRoute
+		badRoute.MultiPath = ecmpRoute.MultiPath[:1]
+		dataplane.AddMockRoute(&badRoute)
+		rt.QueueResync()
+		Expect(rt.Apply()).To(Succeed())
+		Expect(dataplane.RouteKeyToRoute[mocknetlink.KeyForRoute(&ecmpRoute)]).To(Equal(ecmpRoute))
+
+		events, _ := reporter.Events()
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Object).To(Equal(
+			"10.0.1.0/26 nexthop via 10.0.2.1 weight 1 proto 3 dev vxlan.calico"))
+		Expect(events[0].Problem).To(Equal("incorrect next hops"))
+	})
+
+	It("should replace the route when it changes to a single gateway", func() {
+		rt.RouteUpdate("vxlan.calico", Target{
+			Type: TargetTypeVXLAN,
+			CIDR: ip.MustParseCIDROrIP("10.0.1.0/26"),
+			GW:   ip.FromString("10.0.2.1"),
+		})
+		Expect(rt.Apply()).To(Succeed())
+		Expect(dataplane.RouteKeyToRoute).To(HaveLen(1))
+		Expect(dataplane.RouteKeyToRoute).To(HaveKeyWithValue(
+			fmt.Sprintf("254-%d-10.0.1.0/26", vxlanIface.LinkAttrs.Index),
+			netlink.Route{
+				LinkIndex: vxlanIface.LinkAttrs.Index,
+				Dst:       mustParseCIDR("10.0.1.0/26"),
+				Gw:        net.ParseIP("10.0.2.1").To4(),
+				Type:      syscall.RTN_UNICAST,
+				Protocol:  FelixRouteProtocol,
+				Scope:     netlink.SCOPE_UNIVERSE,
+				Flags:     syscall.RTNH_F_ONLINK,
+			},
+		))
+	})
+})
+
 var _ = Describe("Tests to verify ip version is policed", func() {
 	It("Should panic with an invalid IP version", func() {
 		Expect(func() {
@@ -1305,6 +1593,8 @@ var _ = Describe("Tests to verify ip version is policed", func() {
 				true,
 				100,
 				logutils.NewSummarizer("test"),
+				nil,
+				nil,
 			)
 		}).To(Panic())
 	})
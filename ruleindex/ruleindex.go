@@ -0,0 +1,167 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ruleindex maps the rule IDs that the dataplane reports (in NFLOG prefixes and rule
+// counters) back to their policies and profiles, and local addresses back to their workload
+// endpoints.  It is fed from the calculation graph's messages to the dataplane.
+package ruleindex
+
+import (
+	"strings"
+
+	"github.com/projectcalico/felix/proto"
+)
+
+const (
+	DirectionIngress = "ingress"
+	DirectionEgress  = "egress"
+)
+
+// Rule identifies a policy or profile rule.  For policy rules, Tier and Policy are set; for
+// profile rules, Profile is set.
+type Rule struct {
+	Tier      string
+	Policy    string
+	Profile   string
+	Direction string
+	// Index is the position of the rule in its policy or profile's rules for its direction.
+	Index int
+	ID    string
+	// Action is the rule's action, as in proto.Rule.
+	Action      string
+	Annotations map[string]string
+}
+
+// Index tracks the rules of the active policies and profiles and the addresses of the local
+// workload endpoints.  It is not safe for concurrent use.
+type Index struct {
+	ruleByID map[string]Rule
+	// ruleIDsByOwner maps from a policy/profile (as returned by policyOwner/profileOwner)
+	// to the IDs of its rules, so that we can clean up when it changes.
+	ruleIDsByOwner map[string][]string
+
+	endpointByIP map[string]proto.WorkloadEndpointID
+	ipsByWEP     map[proto.WorkloadEndpointID][]string
+}
+
+func New() *Index {
+	return &Index{
+		ruleByID:       map[string]Rule{},
+		ruleIDsByOwner: map[string][]string{},
+		endpointByIP:   map[string]proto.WorkloadEndpointID{},
+		ipsByWEP:       map[proto.WorkloadEndpointID][]string{},
+	}
+}
+
+// OnUpdate processes a message from the calculation graph, picking out the policies, profiles
+// and endpoints.  For a policy or profile message, it returns the rules that the policy or
+// profile had before and after the update, so that callers that keep per-rule state can update
+// it.  A rule that is in both was kept.
+func (x *Index) OnUpdate(msg interface{}) (oldRules, newRules []Rule) {
+	switch msg := msg.(type) {
+	case *proto.ActivePolicyUpdate:
+		owner := policyOwner(msg.Id)
+		oldRules = x.removeRules(owner)
+		newRules = x.addRules(owner, Rule{Tier: msg.Id.Tier, Policy: msg.Id.Name},
+			msg.Policy.InboundRules, msg.Policy.OutboundRules)
+	case *proto.ActivePolicyRemove:
+		oldRules = x.removeRules(policyOwner(msg.Id))
+	case *proto.ActiveProfileUpdate:
+		owner := profileOwner(msg.Id)
+		oldRules = x.removeRules(owner)
+		newRules = x.addRules(owner, Rule{Profile: msg.Id.Name},
+			msg.Profile.InboundRules, msg.Profile.OutboundRules)
+	case *proto.ActiveProfileRemove:
+		oldRules = x.removeRules(profileOwner(msg.Id))
+	case *proto.WorkloadEndpointUpdate:
+		x.removeWorkload(*msg.Id)
+		var ips []string
+		for _, cidr := range append(msg.Endpoint.Ipv4Nets, msg.Endpoint.Ipv6Nets...) {
+			ip := strings.Split(cidr, "/")[0]
+			ips = append(ips, ip)
+			x.endpointByIP[ip] = *msg.Id
+		}
+		x.ipsByWEP[*msg.Id] = ips
+	case *proto.WorkloadEndpointRemove:
+		x.removeWorkload(*msg.Id)
+	}
+	return
+}
+
+func policyOwner(id *proto.PolicyID) string {
+	return "policy:" + id.Tier + "/" + id.Name
+}
+
+func profileOwner(id *proto.ProfileID) string {
+	return "profile:" + id.Name
+}
+
+func (x *Index) addRules(owner string, template Rule, inbound, outbound []*proto.Rule) []Rule {
+	var added []Rule
+	add := func(rules []*proto.Rule, direction string) {
+		for i, r := range rules {
+			if r.RuleId == "" {
+				continue
+			}
+			rule := template
+			rule.Direction = direction
+			rule.Index = i
+			rule.ID = r.RuleId
+			rule.Action = r.Action
+			rule.Annotations = r.GetMetadata().GetAnnotations()
+			x.ruleByID[r.RuleId] = rule
+			added = append(added, rule)
+		}
+	}
+	add(inbound, DirectionIngress)
+	add(outbound, DirectionEgress)
+	if len(added) == 0 {
+		return nil
+	}
+	ids := make([]string, len(added))
+	for i, r := range added {
+		ids[i] = r.ID
+	}
+	x.ruleIDsByOwner[owner] = ids
+	return added
+}
+
+func (x *Index) removeRules(owner string) []Rule {
+	var removed []Rule
+	for _, id := range x.ruleIDsByOwner[owner] {
+		removed = append(removed, x.ruleByID[id])
+		delete(x.ruleByID, id)
+	}
+	delete(x.ruleIDsByOwner, owner)
+	return removed
+}
+
+func (x *Index) removeWorkload(id proto.WorkloadEndpointID) {
+	for _, ip := range x.ipsByWEP[id] {
+		delete(x.endpointByIP, ip)
+	}
+	delete(x.ipsByWEP, id)
+}
+
+// Rule returns the rule with the given ID, if it belongs to an active policy or profile.
+func (x *Index) Rule(id string) (Rule, bool) {
+	r, ok := x.ruleByID[id]
+	return r, ok
+}
+
+// Endpoint returns the ID of the local workload endpoint with the given IP address, if any.
+func (x *Index) Endpoint(ip string) (proto.WorkloadEndpointID, bool) {
+	id, ok := x.endpointByIP[ip]
+	return id, ok
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ruleindex_test
+
+import (
+	. "github.com/projectcalico/felix/ruleindex"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/felix/proto"
+)
+
+var _ = Describe("Index", func() {
+	var index *Index
+	polID := proto.PolicyID{Tier: "default", Name: "pol-1"}
+	wepID := proto.WorkloadEndpointID{OrchestratorId: "k8s", WorkloadId: "ns/pod", EndpointId: "eth0"}
+
+	ruleA := Rule{Tier: "default", Policy: "pol-1", Direction: DirectionIngress, ID: "rule-a", Action: "allow"}
+	ruleB := Rule{Tier: "default", Policy: "pol-1", Direction: DirectionEgress, Index: 1, ID: "rule-b", Action: "deny",
+		Annotations: map[string]string{"owner": "team-a"}}
+
+	BeforeEach(func() {
+		index = New()
+	})
+
+	lookupRule := func(id string) Rule {
+		r, ok := index.Rule(id)
+		Expect(ok).To(BeTrue(), "rule "+id+" not found")
+		return r
+	}
+	lookupEndpoint := func(ip string) proto.WorkloadEndpointID {
+		id, ok := index.Endpoint(ip)
+		Expect(ok).To(BeTrue(), "no endpoint for "+ip)
+		return id
+	}
+
+	It("should attribute the rules of a policy", func() {
+		oldRules, newRules := index.OnUpdate(&proto.ActivePolicyUpdate{
+			Id: &polID,
+			Policy: &proto.Policy{
+				InboundRules: []*proto.Rule{{Action: "allow", RuleId: "rule-a"}},
+				OutboundRules: []*proto.Rule{
+					{Action: "allow"},
+					{Action: "deny", RuleId: "rule-b", Metadata: &proto.RuleMetadata{
+						Annotations: map[string]string{"owner": "team-a"},
+					}},
+				},
+			},
+		})
+		Expect(oldRules).To(BeEmpty())
+		Expect(newRules).To(ConsistOf(ruleA, ruleB))
+		Expect(lookupRule("rule-a")).To(Equal(ruleA))
+		Expect(lookupRule("rule-b")).To(Equal(ruleB))
+	})
+
+	It("should attribute the rules of a profile", func() {
+		_, newRules := index.OnUpdate(&proto.ActiveProfileUpdate{
+			Id:      &proto.ProfileID{Name: "prof-1"},
+			Profile: &proto.Profile{InboundRules: []*proto.Rule{{Action: "allow", RuleId: "rule-p"}}},
+		})
+		expected := Rule{Profile: "prof-1", Direction: DirectionIngress, ID: "rule-p", Action: "allow"}
+		Expect(newRules).To(ConsistOf(expected))
+		Expect(lookupRule("rule-p")).To(Equal(expected))
+	})
+
+	It("should return the old rules and forget removed ones when a policy changes", func() {
+		index.OnUpdate(&proto.ActivePolicyUpdate{
+			Id: &polID,
+			Policy: &proto.Policy{
+				InboundRules: []*proto.Rule{{Action: "allow", RuleId: "rule-a"}},
+			},
+		})
+		oldRules, newRules := index.OnUpdate(&proto.ActivePolicyUpdate{
+			Id: &polID,
+			Policy: &proto.Policy{
+				OutboundRules: []*proto.Rule{{Action: "allow"}, {Action: "deny", RuleId: "rule-b"}},
+			},
+		})
+		Expect(oldRules).To(ConsistOf(ruleA))
+		Expect(newRules).To(HaveLen(1))
+		_, ok := index.Rule("rule-a")
+		Expect(ok).To(BeFalse())
+
+		oldRules, newRules = index.OnUpdate(&proto.ActivePolicyRemove{Id: &polID})
+		Expect(oldRules).To(HaveLen(1))
+		Expect(newRules).To(BeEmpty())
+		_, ok = index.Rule("rule-b")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should map the addresses of workload endpoints", func() {
+		index.OnUpdate(&proto.WorkloadEndpointUpdate{
+			Id: &wepID,
+			Endpoint: &proto.WorkloadEndpoint{
+				Ipv4Nets: []string{"10.65.0.2/32"},
+				Ipv6Nets: []string{"fd00::2/128"},
+			},
+		})
+		Expect(lookupEndpoint("10.65.0.2")).To(Equal(wepID))
+		Expect(lookupEndpoint("fd00::2")).To(Equal(wepID))
+
+		By("Forgetting addresses that the endpoint no longer has")
+		index.OnUpdate(&proto.WorkloadEndpointUpdate{
+			Id:       &wepID,
+			Endpoint: &proto.WorkloadEndpoint{Ipv4Nets: []string{"10.65.0.3/32"}},
+		})
+		_, ok := index.Endpoint("10.65.0.2")
+		Expect(ok).To(BeFalse())
+		Expect(lookupEndpoint("10.65.0.3")).To(Equal(wepID))
+
+		By("Forgetting all addresses when the endpoint is removed")
+		index.OnUpdate(&proto.WorkloadEndpointRemove{Id: &wepID})
+		_, ok = index.Endpoint("10.65.0.3")
+		Expect(ok).To(BeFalse())
+	})
+})
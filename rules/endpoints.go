@@ -20,6 +20,7 @@ import (
 	"github.com/projectcalico/felix/hashutils"
 	. "github.com/projectcalico/felix/iptables"
 	"github.com/projectcalico/felix/proto"
+	"github.com/projectcalico/libcalico-go/lib/set"
 )
 
 const (
@@ -34,6 +35,7 @@ func (r *DefaultRuleRenderer) WorkloadEndpointToIptablesChains(
 	ingressPolicies []string,
 	egressPolicies []string,
 	profileIDs []string,
+	auditOnlyPolicies set.Set,
 ) []*Chain {
 	allowVXLANEncapFromWorkloads := r.Config.AllowVXLANPacketsFromWorkloads
 	allowIPIPEncapFromWorkloads := r.Config.AllowIPIPPacketsFromWorkloads
@@ -42,6 +44,7 @@ func (r *DefaultRuleRenderer) WorkloadEndpointToIptablesChains(
 		// Chain for traffic _to_ the endpoint.
 		r.endpointIptablesChain(
 			ingressPolicies,
+			auditOnlyPolicies,
 			profileIDs,
 			ifaceName,
 			PolicyInboundPfx,
@@ -59,6 +62,7 @@ func (r *DefaultRuleRenderer) WorkloadEndpointToIptablesChains(
 		// unless explicitly overridden.
 		r.endpointIptablesChain(
 			egressPolicies,
+			auditOnlyPolicies,
 			profileIDs,
 			ifaceName,
 			PolicyOutboundPfx,
@@ -95,6 +99,7 @@ func (r *DefaultRuleRenderer) HostEndpointToFilterChains(
 	ingressForwardPolicyNames []string,
 	egressForwardPolicyNames []string,
 	profileIDs []string,
+	auditOnlyPolicies set.Set,
 ) []*Chain {
 	log.WithField("ifaceName", ifaceName).Debug("Rendering filter host endpoint chain.")
 	result := []*Chain{}
@@ -102,6 +107,7 @@ func (r *DefaultRuleRenderer) HostEndpointToFilterChains(
 		// Chain for output traffic _to_ the endpoint.
 		r.endpointIptablesChain(
 			egressPolicyNames,
+			auditOnlyPolicies,
 			profileIDs,
 			ifaceName,
 			PolicyOutboundPfx,
@@ -117,6 +123,7 @@ func (r *DefaultRuleRenderer) HostEndpointToFilterChains(
 		// Chain for input traffic _from_ the endpoint.
 		r.endpointIptablesChain(
 			ingressPolicyNames,
+			auditOnlyPolicies,
 			profileIDs,
 			ifaceName,
 			PolicyInboundPfx,
@@ -132,6 +139,7 @@ func (r *DefaultRuleRenderer) HostEndpointToFilterChains(
 		// Chain for forward traffic _to_ the endpoint.
 		r.endpointIptablesChain(
 			egressForwardPolicyNames,
+			auditOnlyPolicies,
 			profileIDs,
 			ifaceName,
 			PolicyOutboundPfx,
@@ -147,6 +155,7 @@ func (r *DefaultRuleRenderer) HostEndpointToFilterChains(
 		// Chain for forward traffic _from_ the endpoint.
 		r.endpointIptablesChain(
 			ingressForwardPolicyNames,
+			auditOnlyPolicies,
 			profileIDs,
 			ifaceName,
 			PolicyInboundPfx,
@@ -187,6 +196,7 @@ func (r *DefaultRuleRenderer) HostEndpointToMangleEgressChains(
 		// manipulations that might need to apply to our allowed traffic.
 		r.endpointIptablesChain(
 			egressPolicyNames,
+			nil,
 			profileIDs,
 			ifaceName,
 			PolicyOutboundPfx,
@@ -212,6 +222,7 @@ func (r *DefaultRuleRenderer) HostEndpointToRawChains(
 		// Chain for traffic _to_ the endpoint.
 		r.endpointIptablesChain(
 			egressPolicyNames,
+			nil,
 			nil, // We don't render profiles into the raw table.
 			ifaceName,
 			PolicyOutboundPfx,
@@ -227,6 +238,7 @@ func (r *DefaultRuleRenderer) HostEndpointToRawChains(
 		// Chain for traffic _from_ the endpoint.
 		r.endpointIptablesChain(
 			ingressPolicyNames,
+			nil,
 			nil, // We don't render profiles into the raw table.
 			ifaceName,
 			PolicyInboundPfx,
@@ -252,6 +264,7 @@ func (r *DefaultRuleRenderer) HostEndpointToMangleIngressChains(
 		// outgoing traffic through a host endpoint.
 		r.endpointIptablesChain(
 			preDNATPolicyNames,
+			nil,
 			nil, // We don't render profiles into the raw table.
 			ifaceName,
 			PolicyInboundPfx,
@@ -300,6 +313,7 @@ func (r *DefaultRuleRenderer) endpointSetMarkChain(
 
 func (r *DefaultRuleRenderer) endpointIptablesChain(
 	policyNames []string,
+	auditOnlyPolicies set.Set,
 	profileIds []string,
 	name string,
 	policyPrefix PolicyChainNamePrefix,
@@ -365,6 +379,11 @@ func (r *DefaultRuleRenderer) endpointIptablesChain(
 		})
 	}
 
+	// Audit-only policies (those whose names are in auditOnlyPolicies) never accept, drop or pass
+	// traffic so, if they're the only policies, we treat the endpoint as if it had no policies at
+	// all.  In particular, we don't add the "drop if no policies passed packet" rule, so staging a
+	// policy on an endpoint that has no other policies doesn't start dropping its traffic.
+	enforcingPolicies := false
 	if len(policyNames) > 0 {
 		// Clear the "pass" mark.  If a policy sets that mark, we'll skip the rest of the policies and
 		// continue processing the profiles, if there are any.
@@ -377,6 +396,9 @@ func (r *DefaultRuleRenderer) endpointIptablesChain(
 
 		// Then, jump to each policy in turn.
 		for _, polID := range policyNames {
+			if auditOnlyPolicies == nil || !auditOnlyPolicies.Contains(polID) {
+				enforcingPolicies = true
+			}
 			polChainName := PolicyChainName(
 				policyPrefix,
 				&proto.PolicyID{Name: polID},
@@ -405,20 +427,22 @@ func (r *DefaultRuleRenderer) endpointIptablesChain(
 			})
 		}
 
-		if chainType == chainTypeNormal || chainType == chainTypeForward {
+		if enforcingPolicies && (chainType == chainTypeNormal || chainType == chainTypeForward) {
 			// When rendering normal and forward rules, if no policy marked the packet as "pass", drop the
 			// packet.
 			//
 			// For untracked and pre-DNAT rules, we don't do that because there may be
 			// normal rules still to be applied to the packet in the filter table.
+			rules = r.appendDefaultDenyNflogRules(rules, Match().MarkClear(r.IptablesMarkPass))
 			rules = append(rules, Rule{
 				Match:   Match().MarkClear(r.IptablesMarkPass),
 				Action:  DropAction{},
 				Comment: []string{"Drop if no policies passed packet"},
 			})
 		}
+	}
 
-	} else if chainType == chainTypeForward {
+	if !enforcingPolicies && chainType == chainTypeForward {
 		// Forwarded traffic is allowed when there are no policies with
 		// applyOnForward that apply to this endpoint (and in this direction).
 		rules = append(rules, Rule{
@@ -452,6 +476,7 @@ func (r *DefaultRuleRenderer) endpointIptablesChain(
 		// For untracked rules, we don't do that because there may be tracked rules
 		// still to be applied to the packet in the filter table.
 		//if dropIfNoProfilesMatched {
+		rules = r.appendDefaultDenyNflogRules(rules, Match())
 		rules = append(rules, Rule{
 			Match:   Match(),
 			Action:  DropAction{},
@@ -466,6 +491,21 @@ func (r *DefaultRuleRenderer) endpointIptablesChain(
 	}
 }
 
+// appendDefaultDenyNflogRules appends rules that record, for flow logs and the denied-packet
+// log, that the endpoint chain is about to drop a packet because no policy or profile allowed it.
+func (r *DefaultRuleRenderer) appendDefaultDenyNflogRules(rules []Rule, match MatchCriteria) []Rule {
+	if r.FlowLogsEnabled {
+		rules = append(rules, Rule{
+			Match:  match,
+			Action: NflogAction{Group: r.FlowLogsNFLOGGroup, Prefix: FlowLogDenyPrefix},
+		})
+	}
+	if r.DeniedPacketLogEnabled {
+		rules = append(rules, r.deniedPacketLogRule(match, ""))
+	}
+	return rules
+}
+
 func (r *DefaultRuleRenderer) appendConntrackRules(rules []Rule, allowAction Action) []Rule {
 	// Allow return packets for established connections.
 	if allowAction != (AcceptAction{}) {
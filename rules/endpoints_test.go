@@ -24,6 +24,7 @@ import (
 
 	"github.com/projectcalico/felix/ipsets"
 	. "github.com/projectcalico/felix/iptables"
+	"github.com/projectcalico/libcalico-go/lib/set"
 )
 
 var _ = Describe("Endpoints", func() {
@@ -99,7 +100,7 @@ var _ = Describe("Endpoints", func() {
 					true,
 					nil,
 					nil,
-					nil)).To(Equal(trimSMChain(kubeIPVSEnabled, []*Chain{
+					nil, nil)).To(Equal(trimSMChain(kubeIPVSEnabled, []*Chain{
 					{
 						Name: "cali-tw-cali1234",
 						Rules: []Rule{
@@ -146,6 +147,7 @@ var _ = Describe("Endpoints", func() {
 					nil,
 					nil,
 					nil,
+					nil,
 				)).To(Equal(trimSMChain(kubeIPVSEnabled, []*Chain{
 					{
 						Name: "cali-tw-cali1234",
@@ -178,6 +180,7 @@ var _ = Describe("Endpoints", func() {
 					[]string{"ai", "bi"},
 					[]string{"ae", "be"},
 					[]string{"prof1", "prof2"},
+					nil,
 				)).To(Equal(trimSMChain(kubeIPVSEnabled, []*Chain{
 					{
 						Name: "cali-tw-cali1234",
@@ -270,12 +273,120 @@ var _ = Describe("Endpoints", func() {
 				})))
 			})
 
+			It("should not drop at the end of the tier if all the policies are audit-only", func() {
+				chains := renderer.WorkloadEndpointToIptablesChains(
+					"cali1234",
+					epMarkMapper,
+					true,
+					[]string{"ai"},
+					nil,
+					[]string{"prof1"},
+					set.From("ai"),
+				)
+				Expect(chains[0]).To(Equal(&Chain{
+					Name: "cali-tw-cali1234",
+					Rules: []Rule{
+						// conntrack rules.
+						{Match: Match().ConntrackState("RELATED,ESTABLISHED"),
+							Action: AcceptAction{}},
+						{Match: Match().ConntrackState("INVALID"),
+							Action: DropAction{}},
+
+						{Action: ClearMarkAction{Mark: 0x8}},
+
+						{Comment: []string{"Start of policies"},
+							Action: ClearMarkAction{Mark: 0x10}},
+						{Match: Match().MarkClear(0x10),
+							Action: JumpAction{Target: "cali-pi-ai"}},
+						{Match: Match().MarkSingleBitSet(0x8),
+							Action:  ReturnAction{},
+							Comment: []string{"Return if policy accepted"}},
+
+						{Action: JumpAction{Target: "cali-pri-prof1"}},
+						{Match: Match().MarkSingleBitSet(0x8),
+							Action:  ReturnAction{},
+							Comment: []string{"Return if profile accepted"}},
+
+						{Action: DropAction{},
+							Comment: []string{"Drop if no profiles matched"}},
+					},
+				}))
+			})
+
+			It("should record the default drops for flow logs when enabled", func() {
+				rrConfigFlowLogs := rrConfigNormalMangleReturn
+				rrConfigFlowLogs.FlowLogsEnabled = true
+				rrConfigFlowLogs.FlowLogsNFLOGGroup = 4
+				renderer = NewRenderer(rrConfigFlowLogs)
+				chains := renderer.WorkloadEndpointToIptablesChains(
+					"cali1234",
+					epMarkMapper,
+					true,
+					[]string{"ai"},
+					nil,
+					[]string{"prof1"},
+					nil,
+				)
+				Expect(chains[0].Rules[len(chains[0].Rules)-6:]).To(Equal([]Rule{
+					{Match: Match().MarkClear(0x10),
+						Action: NflogAction{Group: 4, Prefix: "D|"}},
+					{Match: Match().MarkClear(0x10),
+						Action:  DropAction{},
+						Comment: []string{"Drop if no policies passed packet"}},
+
+					{Action: JumpAction{Target: "cali-pri-prof1"}},
+					{Match: Match().MarkSingleBitSet(0x8),
+						Action:  ReturnAction{},
+						Comment: []string{"Return if profile accepted"}},
+
+					{Match: Match(),
+						Action: NflogAction{Group: 4, Prefix: "D|"}},
+					{Match: Match(),
+						Action:  DropAction{},
+						Comment: []string{"Drop if no profiles matched"}},
+				}))
+			})
+
+			It("should copy the default drops to the denied-packet log when enabled", func() {
+				rrConfigDenyLog := rrConfigNormalMangleReturn
+				rrConfigDenyLog.DeniedPacketLogEnabled = true
+				rrConfigDenyLog.DeniedPacketLogNFLOGGroup = 5
+				renderer = NewRenderer(rrConfigDenyLog)
+				chains := renderer.WorkloadEndpointToIptablesChains(
+					"cali1234",
+					epMarkMapper,
+					true,
+					[]string{"ai"},
+					nil,
+					[]string{"prof1"},
+					nil,
+				)
+				Expect(chains[0].Rules[len(chains[0].Rules)-6:]).To(Equal([]Rule{
+					{Match: Match().MarkClear(0x10),
+						Action: NflogAction{Group: 5, Prefix: "D|"}},
+					{Match: Match().MarkClear(0x10),
+						Action:  DropAction{},
+						Comment: []string{"Drop if no policies passed packet"}},
+
+					{Action: JumpAction{Target: "cali-pri-prof1"}},
+					{Match: Match().MarkSingleBitSet(0x8),
+						Action:  ReturnAction{},
+						Comment: []string{"Return if profile accepted"}},
+
+					{Match: Match(),
+						Action: NflogAction{Group: 5, Prefix: "D|"}},
+					{Match: Match(),
+						Action:  DropAction{},
+						Comment: []string{"Drop if no profiles matched"}},
+				}))
+			})
+
 			It("should render a host endpoint", func() {
 				Expect(renderer.HostEndpointToFilterChains("eth0",
 					epMarkMapper,
 					[]string{"ai", "bi"}, []string{"ae", "be"},
 					[]string{"afi", "bfi"}, []string{"afe", "bfe"},
-					[]string{"prof1", "prof2"})).To(Equal(trimSMChain(kubeIPVSEnabled, []*Chain{
+					[]string{"prof1", "prof2"}, nil)).To(Equal(trimSMChain(kubeIPVSEnabled, []*Chain{
 					{
 						Name: "cali-th-eth0",
 						Rules: []Rule{
@@ -527,6 +638,7 @@ var _ = Describe("Endpoints", func() {
 					nil,
 					nil,
 					nil,
+					nil,
 				)).To(Equal(trimSMChain(kubeIPVSEnabled, []*Chain{
 					{
 						Name: "cali-tw-cali1234",
@@ -613,6 +725,7 @@ var _ = Describe("Endpoints", func() {
 						nil,
 						nil,
 						nil,
+						nil,
 					)).To(Equal(trimSMChain(kubeIPVSEnabled, []*Chain{
 						{
 							Name: "cali-tw-cali1234",
@@ -664,6 +777,7 @@ var _ = Describe("Endpoints", func() {
 						nil,
 						nil,
 						nil,
+						nil,
 					)).To(Equal(trimSMChain(kubeIPVSEnabled, []*Chain{
 						{
 							Name: "cali-tw-cali1234",
@@ -716,6 +830,7 @@ var _ = Describe("Endpoints", func() {
 						nil,
 						nil,
 						nil,
+						nil,
 					)).To(Equal(trimSMChain(kubeIPVSEnabled, []*Chain{
 						{
 							Name: "cali-tw-cali1234",
@@ -107,6 +107,35 @@ func (r *DefaultRuleRenderer) NATOutgoingChain(natOutgoingActive bool, ipVersion
 	}
 }
 
+// EgressSNAT is an IP set of workloads whose traffic that leaves the cluster is source NATted to
+// ToAddr.
+type EgressSNAT struct {
+	IPSetID string
+	ToAddr  string
+}
+
+// EgressSNATChain returns the IPv4 chain that source NATs traffic that leaves the cluster from
+// the workloads in each IP set.  Since SNAT is a terminating action, the chain takes precedence
+// over the NAT outgoing chain.
+func (r *DefaultRuleRenderer) EgressSNATChain(snats []EgressSNAT) *iptables.Chain {
+	ipConf := r.ipSetConfig(4)
+	allIPsSetName := ipConf.NameForMainIPSet(IPSetIDNATOutgoingAllPools)
+
+	var rules []iptables.Rule
+	for _, snat := range snats {
+		rules = append(rules, iptables.Rule{
+			Match: iptables.Match().
+				SourceIPSet(ipConf.NameForMainIPSet(snat.IPSetID)).
+				NotDestIPSet(allIPsSetName),
+			Action: iptables.SNATAction{ToAddr: snat.ToAddr},
+		})
+	}
+	return &iptables.Chain{
+		Name:  ChainEgressSNAT,
+		Rules: rules,
+	}
+}
+
 func (r *DefaultRuleRenderer) DNATsToIptablesChains(dnats map[string]string) []*iptables.Chain {
 	// Extract and sort map keys so we can program rules in a determined order.
 	sortedExtIps := make([]string, 0, len(dnats))
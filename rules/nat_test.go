@@ -216,6 +216,28 @@ var _ = Describe("NAT", func() {
 			},
 		}))
 	})
+	It("should render egress SNAT rules", func() {
+		Expect(renderer.EgressSNATChain([]EgressSNAT{
+			{IPSetID: "egress-snat-0", ToAddr: "192.0.2.10"},
+			{IPSetID: "egress-snat-2", ToAddr: "192.0.2.11"},
+		})).To(Equal(&Chain{
+			Name: "cali-egress-snat",
+			Rules: []Rule{
+				{
+					Action: SNATAction{ToAddr: "192.0.2.10"},
+					Match: Match().
+						SourceIPSet("cali40egress-snat-0").
+						NotDestIPSet("cali40all-ipam-pools"),
+				},
+				{
+					Action: SNATAction{ToAddr: "192.0.2.11"},
+					Match: Match().
+						SourceIPSet("cali40egress-snat-2").
+						NotDestIPSet("cali40all-ipam-pools"),
+				},
+			},
+		}))
+	})
 	It("should render nothing when inactive", func() {
 		Expect(renderer.NATOutgoingChain(false, 4)).To(Equal(&Chain{
 			Name:  "cali-nat-outgoing",
@@ -29,13 +29,17 @@ import (
 // ruleRenderer defined in rules_defs.go.
 
 func (r *DefaultRuleRenderer) PolicyToIptablesChains(policyID *proto.PolicyID, policy *proto.Policy, ipVersion uint8) []*iptables.Chain {
+	protoRulesToIptablesRules := r.ProtoRulesToIptablesRules
+	if policy.AuditOnly {
+		protoRulesToIptablesRules = r.protoRulesToAuditIptablesRules
+	}
 	inbound := iptables.Chain{
 		Name:  PolicyChainName(PolicyInboundPfx, policyID),
-		Rules: r.ProtoRulesToIptablesRules(policy.InboundRules, ipVersion),
+		Rules: protoRulesToIptablesRules(policy.InboundRules, ipVersion),
 	}
 	outbound := iptables.Chain{
 		Name:  PolicyChainName(PolicyOutboundPfx, policyID),
-		Rules: r.ProtoRulesToIptablesRules(policy.OutboundRules, ipVersion),
+		Rules: protoRulesToIptablesRules(policy.OutboundRules, ipVersion),
 	}
 	return []*iptables.Chain{&inbound, &outbound}
 }
@@ -59,6 +63,18 @@ func (r *DefaultRuleRenderer) ProtoRulesToIptablesRules(protoRules []*proto.Rule
 	}
 	return rules
 }
+
+// protoRulesToAuditIptablesRules renders the rules of an audit-only policy.  Each rule logs the
+// action that it would have taken and then returns to the endpoint chain without setting the
+// accept or pass mark so that the packet carries on to the next policy as if this one wasn't
+// there.
+func (r *DefaultRuleRenderer) protoRulesToAuditIptablesRules(protoRules []*proto.Rule, ipVersion uint8) []iptables.Rule {
+	var rules []iptables.Rule
+	for _, protoRule := range protoRules {
+		rules = append(rules, r.protoRuleToIptablesRules(protoRule, ipVersion, true)...)
+	}
+	return rules
+}
 func filterNets(mixedCIDRs []string, ipVersion uint8) (filtered []string, filteredAll bool) {
 	if len(mixedCIDRs) == 0 {
 		return nil, false
@@ -128,6 +144,10 @@ func FilterRuleToIPVersion(ipVersion uint8, pRule *proto.Rule) *proto.Rule {
 }
 
 func (r *DefaultRuleRenderer) ProtoRuleToIptablesRules(pRule *proto.Rule, ipVersion uint8) []iptables.Rule {
+	return r.protoRuleToIptablesRules(pRule, ipVersion, false)
+}
+
+func (r *DefaultRuleRenderer) protoRuleToIptablesRules(pRule *proto.Rule, ipVersion uint8, auditOnly bool) []iptables.Rule {
 
 	ruleCopy := FilterRuleToIPVersion(ipVersion, pRule)
 	if ruleCopy == nil {
@@ -259,8 +279,26 @@ func (r *DefaultRuleRenderer) ProtoRuleToIptablesRules(pRule *proto.Rule, ipVers
 		// success.  Add a match on that bit to the calculated rule.
 		match = match.MarkSingleBitSet(matchBlockBuilder.markAllBlocksPass)
 	}
-	markBit, actions := r.CalculateActions(ruleCopy, ipVersion)
+	var markBit uint32
+	var actions []iptables.Action
 	rs := matchBlockBuilder.Rules
+	// counterRuleIdx is the index of the rule that sees every packet that matches the policy
+	// rule; normally, the first rule after the match blocks.
+	counterRuleIdx := len(rs)
+	if auditOnly {
+		rs = append(rs, r.calculateAuditRules(ruleCopy, match)...)
+		// The LOG rule may be rate limited so the final RETURN is the one that sees every packet.
+		counterRuleIdx = len(rs) - 1
+	} else {
+		markBit, actions = r.CalculateActions(ruleCopy, ipVersion)
+		if ruleCopy.Action == "deny" && r.DeniedPacketLogEnabled {
+			rs = append(rs, r.deniedPacketLogRule(match, pRule.RuleId))
+			if r.DeniedPacketLogRateLimit > 0 {
+				// The NFLOG rule is rate limited so count the packets on the next rule.
+				counterRuleIdx = len(rs)
+			}
+		}
+	}
 	if markBit != 0 {
 		// The rule needs to do more than one action. Render a rule that
 		// executes the match criteria and sets the given mark bit if it
@@ -278,6 +316,11 @@ func (r *DefaultRuleRenderer) ProtoRuleToIptablesRules(pRule *proto.Rule, ipVers
 		})
 	}
 
+	if r.PolicyCountersEnabled && pRule.RuleId != "" && len(rs) > counterRuleIdx {
+		// Tag the rule so that the table can attribute its counters to the policy rule.
+		rs[counterRuleIdx].PolicyRuleID = pRule.RuleId
+	}
+
 	// Render rule annotations as comments on each rule.
 	for i := range rs {
 		for k, v := range pRule.GetMetadata().GetAnnotations() {
@@ -510,6 +553,7 @@ func (r *DefaultRuleRenderer) CalculateActions(pRule *proto.Rule, ipVersion uint
 		// Allow needs to set the accept mark, and then return to the calling chain for
 		// further processing.
 		mark = r.IptablesMarkAccept
+		actions = r.appendFlowLogAction(actions, FlowLogAllowPrefix, pRule.RuleId)
 		actions = append(actions, iptables.ReturnAction{})
 	case "next-tier", "pass":
 		// pass (called next-tier in the API for historical reasons) needs to set the pass
@@ -518,6 +562,7 @@ func (r *DefaultRuleRenderer) CalculateActions(pRule *proto.Rule, ipVersion uint
 		actions = append(actions, iptables.ReturnAction{})
 	case "deny":
 		// Deny maps to DROP.
+		actions = r.appendFlowLogAction(actions, FlowLogDenyPrefix, pRule.RuleId)
 		actions = append(actions, iptables.DropAction{})
 	case "log":
 		// This rule should log.
@@ -530,6 +575,76 @@ func (r *DefaultRuleRenderer) CalculateActions(pRule *proto.Rule, ipVersion uint
 	return
 }
 
+// appendFlowLogAction appends an NFLOG action that records the rule's verdict for flow logs, if
+// flow logs are enabled.  Only the first packet of each flow reaches the policy chains so this
+// doesn't copy every packet to Felix.
+func (r *DefaultRuleRenderer) appendFlowLogAction(actions []iptables.Action, prefix, ruleID string) []iptables.Action {
+	if !r.FlowLogsEnabled {
+		return actions
+	}
+	return append(actions, iptables.NflogAction{
+		Group:  r.FlowLogsNFLOGGroup,
+		Prefix: prefix + ruleID,
+	})
+}
+
+// deniedPacketLogRule returns a rule that copies the packets that match to the denied-packet log.
+// If configured, the rule is rate limited, with a burst of one second's worth of packets, so that
+// a flood of denied packets can't swamp Felix; the logger limits the records that it writes to
+// the same rate.
+func (r *DefaultRuleRenderer) deniedPacketLogRule(match iptables.MatchCriteria, ruleID string) iptables.Rule {
+	if r.DeniedPacketLogRateLimit > 0 {
+		match = append(iptables.Match(), match...).Limit(r.DeniedPacketLogRateLimit, r.DeniedPacketLogRateLimit)
+	}
+	return iptables.Rule{
+		Match: match,
+		Action: iptables.NflogAction{
+			Group:  r.DeniedPacketLogNFLOGGroup,
+			Prefix: FlowLogDenyPrefix + ruleID,
+		},
+	}
+}
+
+// calculateAuditRules returns the rules for a rule in an audit-only policy.  Since the actions
+// don't touch the mark bits, the match can simply be repeated for each action.  If configured, the
+// LOG rule is rate limited so that a busy audit-only policy can't flood the kernel log.
+func (r *DefaultRuleRenderer) calculateAuditRules(pRule *proto.Rule, match iptables.MatchCriteria) []iptables.Rule {
+	var action string
+	switch pRule.Action {
+	case "", "allow":
+		action = "allow"
+	case "next-tier", "pass":
+		action = "pass"
+	case "deny":
+		action = "deny"
+	case "log":
+		// Log rules don't enforce anything so they're rendered as normal.
+		return []iptables.Rule{{
+			Match:  match,
+			Action: iptables.LogAction{Prefix: r.IptablesLogPrefix},
+		}}
+	default:
+		log.WithField("action", pRule.Action).Panic("Unknown rule action")
+	}
+	logMatch := append(iptables.Match(), match...)
+	if r.AuditLogRateLimit > 0 {
+		logMatch = logMatch.Limit(r.AuditLogRateLimit, r.AuditLogRateLimitBurst)
+	}
+	return []iptables.Rule{
+		{
+			Match: logMatch,
+			Action: iptables.LogAction{
+				Prefix: fmt.Sprintf("%s-audit-%s", r.IptablesLogPrefix, action),
+			},
+		},
+		// The first matching rule decides what the policy would have done.
+		{
+			Match:  match,
+			Action: iptables.ReturnAction{},
+		},
+	}
+}
+
 func appendProtocolMatch(match iptables.MatchCriteria, protocol *proto.Protocol, logCxt *log.Entry) iptables.MatchCriteria {
 	if protocol == nil {
 		return match
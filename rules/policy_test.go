@@ -15,6 +15,8 @@
 package rules_test
 
 import (
+	"strings"
+
 	. "github.com/projectcalico/felix/rules"
 
 	. "github.com/onsi/ginkgo"
@@ -288,6 +290,187 @@ var _ = Describe("Protobuf rule to iptables rule conversion", func() {
 		ruleTestData...,
 	)
 
+	DescribeTable(
+		"Rules should record their verdicts for flow logs when enabled",
+		func(ipVer int, in proto.Rule, expMatch string) {
+			rrConfigFlowLogs := rrConfigNormal
+			rrConfigFlowLogs.FlowLogsEnabled = true
+			rrConfigFlowLogs.FlowLogsNFLOGGroup = 4
+			renderer := NewRenderer(rrConfigFlowLogs)
+			in.RuleId = "abcdefghijklmnop"
+
+			By("Rendering an allow rule")
+			in.Action = "allow"
+			rules := renderer.ProtoRuleToIptablesRules(&in, uint8(ipVer))
+			Expect(len(rules)).To(Equal(3))
+			Expect(rules[0].Match.Render()).To(Equal(expMatch))
+			Expect(rules[0].Action).To(Equal(iptables.SetMarkAction{Mark: 0x80}))
+			Expect(rules[1]).To(Equal(iptables.Rule{
+				Match:  iptables.Match().MarkSingleBitSet(0x80),
+				Action: iptables.NflogAction{Group: 4, Prefix: "A|abcdefghijklmnop"},
+			}))
+			Expect(rules[2]).To(Equal(iptables.Rule{
+				Match:  iptables.Match().MarkSingleBitSet(0x80),
+				Action: iptables.ReturnAction{},
+			}))
+
+			By("Rendering a deny rule")
+			in.Action = "deny"
+			rules = renderer.ProtoRuleToIptablesRules(&in, uint8(ipVer))
+			Expect(len(rules)).To(Equal(2))
+			Expect(rules[0].Match.Render()).To(Equal(expMatch))
+			Expect(rules[0].Action).To(Equal(iptables.NflogAction{Group: 4, Prefix: "D|abcdefghijklmnop"}))
+			Expect(rules[1].Match.Render()).To(Equal(expMatch))
+			Expect(rules[1].Action).To(Equal(iptables.DropAction{}))
+
+			By("Rendering a pass rule")
+			in.Action = "pass"
+			rules = renderer.ProtoRuleToIptablesRules(&in, uint8(ipVer))
+			Expect(len(rules)).To(Equal(2))
+		},
+		ruleTestData...,
+	)
+
+	DescribeTable(
+		"Deny rules should copy packets to the denied-packet log when enabled",
+		func(ipVer int, in proto.Rule, expMatch string) {
+			rrConfigDenyLog := rrConfigNormal
+			rrConfigDenyLog.DeniedPacketLogEnabled = true
+			rrConfigDenyLog.DeniedPacketLogNFLOGGroup = 5
+			renderer := NewRenderer(rrConfigDenyLog)
+			in.RuleId = "abcdefghijklmnop"
+
+			By("Rendering a deny rule")
+			in.Action = "deny"
+			rules := renderer.ProtoRuleToIptablesRules(&in, uint8(ipVer))
+			Expect(len(rules)).To(Equal(2))
+			Expect(rules[0].Match.Render()).To(Equal(expMatch))
+			Expect(rules[0].Action).To(Equal(iptables.NflogAction{Group: 5, Prefix: "D|abcdefghijklmnop"}))
+			Expect(rules[1].Match.Render()).To(Equal(expMatch))
+			Expect(rules[1].Action).To(Equal(iptables.DropAction{}))
+
+			By("Rendering an allow rule")
+			in.Action = "allow"
+			rules = renderer.ProtoRuleToIptablesRules(&in, uint8(ipVer))
+			Expect(len(rules)).To(Equal(2))
+		},
+		ruleTestData...,
+	)
+
+	DescribeTable(
+		"Deny rules should rate limit the copies to the denied-packet log when configured",
+		func(ipVer int, in proto.Rule, expMatch string) {
+			rrConfigDenyLog := rrConfigNormal
+			rrConfigDenyLog.DeniedPacketLogEnabled = true
+			rrConfigDenyLog.DeniedPacketLogNFLOGGroup = 5
+			rrConfigDenyLog.DeniedPacketLogRateLimit = 100
+			rrConfigDenyLog.PolicyCountersEnabled = true
+			renderer := NewRenderer(rrConfigDenyLog)
+			in.RuleId = "abcdefghijklmnop"
+			in.Action = "deny"
+			rules := renderer.ProtoRuleToIptablesRules(&in, uint8(ipVer))
+			Expect(len(rules)).To(Equal(2))
+			expLimitedMatch := strings.TrimSpace(expMatch + " -m limit --limit 100/second --limit-burst 100")
+			Expect(rules[0].Match.Render()).To(Equal(expLimitedMatch))
+			Expect(rules[0].Action).To(Equal(iptables.NflogAction{Group: 5, Prefix: "D|abcdefghijklmnop"}))
+			Expect(rules[0].PolicyRuleID).To(BeEmpty())
+			Expect(rules[1].Match.Render()).To(Equal(expMatch))
+			Expect(rules[1].Action).To(Equal(iptables.DropAction{}))
+			By("Counting packets on the unlimited DROP rule")
+			Expect(rules[1].PolicyRuleID).To(Equal("abcdefghijklmnop"))
+		},
+		ruleTestData...,
+	)
+
+	DescribeTable(
+		"Rules should be tagged with their rule ID when policy counters are enabled",
+		func(ipVer int, in proto.Rule, expMatch string) {
+			rrConfigCounters := rrConfigNormal
+			rrConfigCounters.PolicyCountersEnabled = true
+			renderer := NewRenderer(rrConfigCounters)
+			in.RuleId = "abcdefghijklmnop"
+			rules := renderer.ProtoRuleToIptablesRules(&in, uint8(ipVer))
+			Expect(len(rules)).To(Equal(2))
+			Expect(rules[0].PolicyRuleID).To(Equal("abcdefghijklmnop"))
+			Expect(rules[1].PolicyRuleID).To(BeEmpty())
+
+			By("Leaving the rule untagged if counters are disabled")
+			rules = NewRenderer(rrConfigNormal).ProtoRuleToIptablesRules(&in, uint8(ipVer))
+			Expect(rules[0].PolicyRuleID).To(BeEmpty())
+		},
+		ruleTestData...,
+	)
+
+	It("should tag the rule after the match blocks when policy counters are enabled", func() {
+		rrConfigCounters := rrConfigNormal
+		rrConfigCounters.PolicyCountersEnabled = true
+		renderer := NewRenderer(rrConfigCounters)
+		rules := renderer.ProtoRuleToIptablesRules(&proto.Rule{
+			Action: "deny",
+			RuleId: "abcdefghijklmnop",
+			SrcNet: []string{"10.0.0.0/8", "11.0.0.0/8"},
+		}, 4)
+		var tagged []int
+		for i, r := range rules {
+			if r.PolicyRuleID != "" {
+				tagged = append(tagged, i)
+			}
+		}
+		Expect(tagged).To(Equal([]int{len(rules) - 1}))
+		Expect(rules[len(rules)-1].Action).To(Equal(iptables.DropAction{}))
+	})
+
+	DescribeTable(
+		"Audit-only policy rules should log and return without setting marks",
+		func(ipVer int, in proto.Rule, expMatch string) {
+			renderer := NewRenderer(rrConfigNormal)
+			for _, action := range []string{"allow", "pass", "deny"} {
+				By("Rendering for action " + action)
+				in.Action = action
+				chains := renderer.PolicyToIptablesChains(
+					&proto.PolicyID{Name: "staged:pol"},
+					&proto.Policy{InboundRules: []*proto.Rule{&in}, AuditOnly: true},
+					uint8(ipVer),
+				)
+				rules := chains[0].Rules
+				Expect(len(rules)).To(Equal(2))
+				Expect(rules[0].Match.Render()).To(Equal(expMatch))
+				Expect(rules[0].Action).To(Equal(iptables.LogAction{Prefix: "calico-packet-audit-" + action}))
+				Expect(rules[1].Match.Render()).To(Equal(expMatch))
+				Expect(rules[1].Action).To(Equal(iptables.ReturnAction{}))
+			}
+		},
+		ruleTestData...,
+	)
+
+	It("should rate limit audit-only LOG rules and count on the RETURN rule", func() {
+		rrConfigAudit := rrConfigNormal
+		rrConfigAudit.AuditLogRateLimit = 10
+		rrConfigAudit.AuditLogRateLimitBurst = 20
+		rrConfigAudit.PolicyCountersEnabled = true
+		renderer := NewRenderer(rrConfigAudit)
+		chains := renderer.PolicyToIptablesChains(
+			&proto.PolicyID{Name: "staged:pol"},
+			&proto.Policy{
+				InboundRules: []*proto.Rule{{
+					Action:   "deny",
+					Protocol: &proto.Protocol{NumberOrName: &proto.Protocol_Name{Name: "tcp"}},
+					RuleId:   "abcdefghijklmnop",
+				}},
+				AuditOnly: true,
+			},
+			4,
+		)
+		rules := chains[0].Rules
+		Expect(rules).To(HaveLen(2))
+		Expect(rules[0].Match.Render()).To(Equal("-p tcp -m limit --limit 10/second --limit-burst 20"))
+		Expect(rules[0].Action).To(Equal(iptables.LogAction{Prefix: "calico-packet-audit-deny"}))
+		Expect(rules[0].PolicyRuleID).To(BeEmpty())
+		Expect(rules[1].Match.Render()).To(Equal("-p tcp"))
+		Expect(rules[1].Action).To(Equal(iptables.ReturnAction{}))
+		Expect(rules[1].PolicyRuleID).To(Equal("abcdefghijklmnop"))
+	})
+
 	const (
 		clearBothMarksRule       = "-A test --jump MARK --set-mark 0x0/0x600"
 		preSetAllBlocksMarkRule  = "-A test --jump MARK --set-mark 0x200/0x600"
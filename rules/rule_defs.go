@@ -26,6 +26,7 @@ import (
 	"github.com/projectcalico/felix/iptables"
 	"github.com/projectcalico/felix/proto"
 	"github.com/projectcalico/libcalico-go/lib/numorstring"
+	"github.com/projectcalico/libcalico-go/lib/set"
 )
 
 const (
@@ -51,6 +52,7 @@ const (
 	ChainNATPostrouting = ChainNamePrefix + "POSTROUTING"
 	ChainNATOutput      = ChainNamePrefix + "OUTPUT"
 	ChainNATOutgoing    = ChainNamePrefix + "nat-outgoing"
+	ChainEgressSNAT     = ChainNamePrefix + "egress-snat"
 
 	ChainManglePrerouting  = ChainNamePrefix + "PREROUTING"
 	ChainManglePostrouting = ChainNamePrefix + "POSTROUTING"
@@ -58,6 +60,10 @@ const (
 	IPSetIDNATOutgoingAllPools  = "all-ipam-pools"
 	IPSetIDNATOutgoingMasqPools = "masq-ipam-pools"
 
+	// IPSetIDEgressSNATPrefix is the prefix of the IP sets of workloads that use an egress SNAT
+	// address.  It is followed by the index of the egress policy.
+	IPSetIDEgressSNATPrefix = "egress-snat-"
+
 	IPSetIDAllHostNets        = "all-hosts-net"
 	IPSetIDAllVXLANSourceNets = "all-vxlan-net"
 	IPSetIDThisHostIPs        = "this-host"
@@ -99,6 +105,14 @@ const (
 
 	RuleHashPrefix = "cali:"
 
+	// FlowLogAllowPrefix and FlowLogDenyPrefix are the prefixes of the NFLOG messages that
+	// record policy verdicts for flow logs.  For a verdict from a policy or profile rule, the
+	// prefix is followed by the rule's ID.  The drops at the end of the endpoint chains, when
+	// no policy or profile allowed the packet, have no rule ID.  The denied-packet log uses
+	// the same deny prefix on its own NFLOG group.
+	FlowLogAllowPrefix = "A|"
+	FlowLogDenyPrefix  = "D|"
+
 	// HistoricNATRuleInsertRegex is a regex pattern to match to match
 	// special-case rules inserted by old versions of felix.  Specifically,
 	// Python felix used to insert a masquerade rule directly into the
@@ -175,6 +189,7 @@ type RuleRenderer interface {
 		ingressPolicies []string,
 		egressPolicies []string,
 		profileIDs []string,
+		auditOnlyPolicies set.Set,
 	) []*iptables.Chain
 
 	WorkloadInterfaceAllowChains(endpoints map[proto.WorkloadEndpointID]*proto.WorkloadEndpoint) []*iptables.Chain
@@ -196,6 +211,7 @@ type RuleRenderer interface {
 		ingressForwardPolicyNames []string,
 		egressForwardPolicyNames []string,
 		profileIDs []string,
+		auditOnlyPolicies set.Set,
 	) []*iptables.Chain
 	HostEndpointToMangleEgressChains(
 		ifaceName string,
@@ -218,6 +234,7 @@ type RuleRenderer interface {
 
 	MakeNatOutgoingRule(protocol string, action iptables.Action, ipVersion uint8) iptables.Rule
 	NATOutgoingChain(active bool, ipVersion uint8) *iptables.Chain
+	EgressSNATChain(snats []EgressSNAT) *iptables.Chain
 
 	DNATsToIptablesChains(dnats map[string]string) []*iptables.Chain
 	SNATsToIptablesChains(snats map[string]string) []*iptables.Chain
@@ -281,12 +298,18 @@ type Config struct {
 
 	WireguardEnabled       bool
 	WireguardInterfaceName string
+	// WireguardInterfaceIPv6Addr is the (optional) IPv6 address of the wireguard interface.
+	WireguardInterfaceIPv6Addr net.IP
 
 	IptablesLogPrefix         string
 	EndpointToHostAction      string
 	IptablesFilterAllowAction string
 	IptablesMangleAllowAction string
 
+	// AuditLogRateLimit and AuditLogRateLimitBurst limit the LOG rules of audit-only policies.
+	AuditLogRateLimit      int
+	AuditLogRateLimitBurst int
+
 	FailsafeInboundHostPorts  []config.ProtoPort
 	FailsafeOutboundHostPorts []config.ProtoPort
 
@@ -297,8 +320,27 @@ type Config struct {
 
 	NATOutgoingAddress net.IP
 	BPFEnabled         bool
+	// EgressSNATEnabled adds a jump to the egress SNAT chain, which source NATs traffic from
+	// workloads that have an egress SNAT address, ahead of the NAT outgoing chain.
+	EgressSNATEnabled bool
 
 	ServiceLoopPrevention string
+
+	DNSSnoopingEnabled    bool
+	DNSSnoopingNFLOGGroup uint16
+	// DNSTrustedServers are the addresses or CIDRs of the DNS servers whose responses we snoop.
+	DNSTrustedServers []string
+
+	FlowLogsEnabled    bool
+	FlowLogsNFLOGGroup uint16
+
+	DeniedPacketLogEnabled    bool
+	DeniedPacketLogNFLOGGroup uint16
+	// DeniedPacketLogRateLimit limits, per rule, the packets that are copied to the denied-packet
+	// log, in packets per second.  Zero means no limit.
+	DeniedPacketLogRateLimit int
+
+	PolicyCountersEnabled bool
 }
 
 var unusedBitsInBPFMode = map[string]bool{
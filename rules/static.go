@@ -225,14 +225,15 @@ func (r *DefaultRuleRenderer) filterInputChain(ipVersion uint8) *Chain {
 		)
 	}
 
-	if ipVersion == 4 && r.VXLANEnabled {
+	if r.VXLANEnabled {
 		// VXLAN is enabled, filter incoming VXLAN packets that match our VXLAN port to ensure they
-		// come from a recognised host and are going to a local address on the host.
+		// come from a recognised host and are going to a local address on the host.  Each IP version
+		// has its own overlay so each has its own set of allowed sources.
 		inputRules = append(inputRules,
 			Rule{
 				Match: Match().ProtocolNum(ProtoUDP).
 					DestPorts(uint16(r.Config.VXLANPort)).
-					SourceIPSet(r.IPSetConfigV4.NameForMainIPSet(IPSetIDAllVXLANSourceNets)).
+					SourceIPSet(r.ipSetConfig(ipVersion).NameForMainIPSet(IPSetIDAllVXLANSourceNets)).
 					DestAddrType(AddrTypeLocal),
 				Action:  r.filterAllowAction,
 				Comment: []string{"Allow VXLAN packets from whitelisted hosts"},
@@ -766,10 +767,15 @@ func (r *DefaultRuleRenderer) StaticNATPostroutingChains(ipVersion uint8) []*Cha
 		{
 			Action: JumpAction{Target: ChainFIPSnat},
 		},
-		{
-			Action: JumpAction{Target: ChainNATOutgoing},
-		},
 	}
+	if ipVersion == 4 && r.EgressSNATEnabled {
+		rules = append(rules, Rule{
+			Action: JumpAction{Target: ChainEgressSNAT},
+		})
+	}
+	rules = append(rules, Rule{
+		Action: JumpAction{Target: ChainNATOutgoing},
+	})
 
 	var tunnelIfaces []string
 
@@ -784,6 +790,10 @@ func (r *DefaultRuleRenderer) StaticNATPostroutingChains(ipVersion uint8) []*Cha
 		// wireguard enabled.
 		tunnelIfaces = append(tunnelIfaces, r.WireguardInterfaceName)
 	}
+	if ipVersion == 6 && r.WireguardEnabled && len(r.WireguardInterfaceName) > 0 && r.WireguardInterfaceIPv6Addr != nil {
+		// The IPv6 wireguard address comes from config so we only need the rule if it is set.
+		tunnelIfaces = append(tunnelIfaces, r.WireguardInterfaceName)
+	}
 
 	for _, tunnel := range tunnelIfaces {
 		// Add a rule to catch packets that are being sent down a tunnel from an
@@ -853,6 +863,26 @@ func (r *DefaultRuleRenderer) StaticMangleTableChains(ipVersion uint8) []*Chain
 func (r *DefaultRuleRenderer) StaticManglePreroutingChain(ipVersion uint8) *Chain {
 	rules := []Rule{}
 
+	// Copy DNS queries from local workloads to the trusted DNS servers to userspace so that the
+	// snooper only accepts responses to queries that it has seen.  This needs to come first
+	// because resolvers often send several queries from the same socket, which makes all but
+	// the first part of an established connection.
+	if r.DNSSnoopingEnabled {
+		servers, _ := filterNets(r.DNSTrustedServers, ipVersion)
+		for _, prefix := range r.WorkloadIfacePrefixes {
+			for _, server := range servers {
+				rules = append(rules, Rule{
+					Match: Match().
+						Protocol("udp").
+						DestPorts(53).
+						InInterface(prefix + "+").
+						ConntrackOrigDestNet(server),
+					Action: NflogAction{Group: r.DNSSnoopingNFLOGGroup},
+				})
+			}
+		}
+	}
+
 	// ACCEPT or RETURN immediately if packet matches an existing connection.  Note that we also
 	// have a rule like this at the start of each pre-endpoint chain; the functional difference
 	// with placing this rule here is that it will also apply to packets that may be unrelated
@@ -906,6 +936,29 @@ func (r *DefaultRuleRenderer) StaticManglePreroutingChain(ipVersion uint8) *Chai
 func (r *DefaultRuleRenderer) StaticManglePostroutingChain(ipVersion uint8) *Chain {
 	rules := []Rule{}
 
+	// Copy DNS responses that are on their way to local workloads to userspace so that we can
+	// populate the IP sets of domain-based rules.  This needs to come first because forwarded
+	// packets return early, below.  We see responses from both remote and host-networked DNS
+	// servers here.  Only replies on connections that a workload opened to a trusted DNS
+	// server are copied; matching on the original destination of the connection, rather than
+	// the source of the packet, covers DNS services that are DNATted to their backends.
+	if r.DNSSnoopingEnabled {
+		servers, _ := filterNets(r.DNSTrustedServers, ipVersion)
+		for _, prefix := range r.WorkloadIfacePrefixes {
+			for _, server := range servers {
+				rules = append(rules, Rule{
+					Match: Match().
+						Protocol("udp").
+						SourcePorts(53).
+						OutInterface(prefix + "+").
+						ConntrackReply().
+						ConntrackOrigDestNet(server),
+					Action: NflogAction{Group: r.DNSSnoopingNFLOGGroup},
+				})
+			}
+		}
+	}
+
 	// Note, we use RETURN as the Allow action in this chain, rather than ACCEPT because the
 	// mangle table is typically used, if at all, for packet manipulations that might need to
 	// apply to our allowed traffic.
@@ -931,6 +931,17 @@ var _ = Describe("Static", func() {
 
 				checkManglePostrouting(4, kubeIPVSEnabled)
 
+				It("IPv6: should only allow VXLAN packets from IPv6 VTEPs", func() {
+					Expect(findChain(rr.StaticFilterTableChains(6), "cali-INPUT").Rules).To(ContainElement(Rule{
+						Match: Match().ProtocolNum(ProtoUDP).
+							DestPorts(uint16(conf.VXLANPort)).
+							SourceIPSet("cali60all-vxlan-net").
+							DestAddrType(AddrTypeLocal),
+						Action:  AcceptAction{},
+						Comment: []string{"Allow VXLAN packets from whitelisted hosts"},
+					}))
+				})
+
 				It("IPv4: Should return expected NAT postrouting chain", func() {
 					Expect(rr.StaticNATPostroutingChains(4)).To(Equal([]*Chain{
 						{
@@ -983,6 +994,54 @@ var _ = Describe("Static", func() {
 				})
 			})
 
+			Describe("with wireguard enabled and an IPv6 interface address", func() {
+				BeforeEach(func() {
+					conf.WireguardEnabled = true
+					conf.WireguardInterfaceName = "wireguard.cali"
+					conf.WireguardInterfaceIPv6Addr = net.ParseIP("fd00:10::1")
+				})
+
+				It("IPv6: Should return expected NAT postrouting chain", func() {
+					Expect(rr.StaticNATPostroutingChains(6)).To(Equal([]*Chain{
+						{
+							Name: "cali-POSTROUTING",
+							Rules: []Rule{
+								{Action: JumpAction{Target: "cali-fip-snat"}},
+								{Action: JumpAction{Target: "cali-nat-outgoing"}},
+								{
+									Match: Match().
+										OutInterface("wireguard.cali").
+										NotSrcAddrType(AddrTypeLocal, true).
+										SrcAddrType(AddrTypeLocal, false),
+									Action: MasqAction{},
+								},
+							},
+						},
+					}))
+				})
+			})
+
+			Describe("with egress SNAT enabled", func() {
+				BeforeEach(func() {
+					conf.EgressSNATEnabled = true
+				})
+
+				It("IPv4: Should jump to the egress SNAT chain before NAT outgoing", func() {
+					Expect(rr.StaticNATPostroutingChains(4)[0].Rules[:3]).To(Equal([]Rule{
+						{Action: JumpAction{Target: "cali-fip-snat"}},
+						{Action: JumpAction{Target: "cali-egress-snat"}},
+						{Action: JumpAction{Target: "cali-nat-outgoing"}},
+					}))
+				})
+
+				It("IPv6: Should not jump to the egress SNAT chain", func() {
+					Expect(rr.StaticNATPostroutingChains(6)[0].Rules).To(Equal([]Rule{
+						{Action: JumpAction{Target: "cali-fip-snat"}},
+						{Action: JumpAction{Target: "cali-nat-outgoing"}},
+					}))
+				})
+			})
+
 			It("IPv4: Should return expected NAT postrouting chain", func() {
 				Expect(rr.StaticNATPostroutingChains(6)).To(Equal([]*Chain{
 					{
@@ -1356,6 +1415,67 @@ var _ = Describe("Static", func() {
 			})
 		}
 	})
+
+	Describe("with DNS snooping enabled", func() {
+		BeforeEach(func() {
+			conf = Config{
+				WorkloadIfacePrefixes: []string{"cali", "tap"},
+				IPSetConfigV4:         ipsets.NewIPVersionConfig(ipsets.IPFamilyV4, "cali", nil, nil),
+				IPSetConfigV6:         ipsets.NewIPVersionConfig(ipsets.IPFamilyV6, "cali", nil, nil),
+				IptablesMarkAccept:    0x10,
+				IptablesMarkPass:      0x20,
+				IptablesMarkScratch0:  0x40,
+				IptablesMarkScratch1:  0x80,
+				IptablesMarkEndpoint:  0xff00,
+				DNSSnoopingEnabled:    true,
+				DNSSnoopingNFLOGGroup: 3,
+				DNSTrustedServers:     []string{"10.96.0.10", "fd00:96::a"},
+			}
+		})
+
+		for _, ipVersion := range []uint8{4, 6} {
+			ipVersion := ipVersion
+			server := map[uint8]string{4: "10.96.0.10", 6: "fd00:96::a"}[ipVersion]
+
+			It(fmt.Sprintf("should copy IPv%d DNS responses from trusted servers to the NFLOG group", ipVersion), func() {
+				chain := rr.StaticManglePostroutingChain(ipVersion)
+				Expect(chain.Rules[:3]).To(Equal([]Rule{
+					{
+						Match: Match().Protocol("udp").SourcePorts(53).OutInterface("cali+").
+							ConntrackReply().ConntrackOrigDestNet(server),
+						Action: NflogAction{Group: 3},
+					},
+					{
+						Match: Match().Protocol("udp").SourcePorts(53).OutInterface("tap+").
+							ConntrackReply().ConntrackOrigDestNet(server),
+						Action: NflogAction{Group: 3},
+					},
+					{
+						Match:  Match().MarkSingleBitSet(0x10),
+						Action: ReturnAction{},
+					},
+				}))
+			})
+
+			It(fmt.Sprintf("should copy IPv%d DNS queries to trusted servers to the NFLOG group", ipVersion), func() {
+				chain := rr.StaticManglePreroutingChain(ipVersion)
+				Expect(chain.Rules[:3]).To(Equal([]Rule{
+					{
+						Match:  Match().Protocol("udp").DestPorts(53).InInterface("cali+").ConntrackOrigDestNet(server),
+						Action: NflogAction{Group: 3},
+					},
+					{
+						Match:  Match().Protocol("udp").DestPorts(53).InInterface("tap+").ConntrackOrigDestNet(server),
+						Action: NflogAction{Group: 3},
+					},
+					{
+						Match:  Match().ConntrackState("RELATED,ESTABLISHED"),
+						Action: AcceptAction{},
+					},
+				}))
+			})
+		}
+	})
 })
 
 func findChain(chains []*Chain, name string) *Chain {
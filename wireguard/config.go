@@ -1,5 +1,10 @@
 package wireguard
 
+import (
+	"net"
+	"time"
+)
+
 type Config struct {
 	// Wireguard configuration
 	Enabled             bool
@@ -9,4 +14,21 @@ type Config struct {
 	RoutingTableIndex   int
 	InterfaceName       string
 	MTU                 int
+
+	// IPv6 configuration.  When EnableIPv6 is set, IPv6 workload and tunnel CIDRs are routed over the same
+	// wireguard device (and to the same peers) as IPv4 ones.  InterfaceIPv6Addr is optional.
+	EnableIPv6        bool
+	InterfaceIPv6Addr net.IP
+
+	// KeyRotationInterval is the interval after which the private key is replaced and the new public key published.
+	// Zero disables rotation.  Since the age of a key already on the device is unknown, the interval is measured from
+	// when the key was first seen by this process.  Rotation interrupts wireguard traffic to and from this node until
+	// the peers have learned the new public key.
+	KeyRotationInterval time.Duration
+
+	// HostEncryptionEnabled routes the host addresses of other nodes over wireguard, as well as their workload and
+	// tunnel addresses, so that host-networked traffic between nodes is encrypted too.  The wireguard device marks its
+	// own UDP traffic with FirewallMark, which the routing rule excludes from the wireguard table, so traffic to the
+	// peers' endpoint addresses does not loop.
+	HostEncryptionEnabled bool
 }
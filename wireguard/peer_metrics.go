@@ -0,0 +1,167 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/projectcalico/libcalico-go/lib/set"
+
+	"github.com/projectcalico/felix/ip"
+)
+
+var (
+	peerHandshakeAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "felix_wireguard_peer_handshake_age_seconds",
+		Help: "Time since the latest handshake with a wireguard peer.  Handshakes only happen while there is " +
+			"traffic, roughly every two minutes; there is no series for a peer that has never completed one.",
+	}, []string{"peer_node"})
+	peerRxBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "felix_wireguard_peer_rx_bytes",
+		Help: "Number of bytes received from a wireguard peer.",
+	}, []string{"peer_node"})
+	peerTxBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "felix_wireguard_peer_tx_bytes",
+		Help: "Number of bytes sent to a wireguard peer.",
+	}, []string{"peer_node"})
+	peerAllowedIPsMatch = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "felix_wireguard_peer_allowed_ips_match",
+		Help: "1 if a wireguard peer is programmed with the expected allowed IPs, 0 if the peer is missing or " +
+			"its allowed IPs differ.",
+	}, []string{"peer_node"})
+)
+
+func init() {
+	prometheus.MustRegister(peerHandshakeAge)
+	prometheus.MustRegister(peerRxBytes)
+	prometheus.MustRegister(peerTxBytes)
+	prometheus.MustRegister(peerAllowedIPsMatch)
+}
+
+// peerTrafficCounts is the most recent reading of a peer's traffic counters.  The device's counters are running
+// totals that restart when the peer is re-created, so we record the reading to calculate the increase next time.
+type peerTrafficCounts struct {
+	publicKey wgtypes.Key
+	rxBytes   int64
+	txBytes   int64
+}
+
+// UpdatePeerMetrics reads the peers from the wireguard device and updates the per-peer Prometheus metrics.  It is a
+// no-op if wireguard is not enabled, or the device is not yet up.
+func (w *Wireguard) UpdatePeerMetrics() {
+	if !w.config.Enabled || w.wireguardNotSupported || !w.ifaceUp {
+		w.removeAllPeerMetrics()
+		return
+	}
+
+	wireguardClient, err := w.getWireguardClient()
+	if err != nil {
+		log.WithError(err).Debug("Unable to get wireguard client to update peer metrics")
+		return
+	}
+	device, err := wireguardClient.DeviceByName(w.config.InterfaceName)
+	if err != nil {
+		log.WithError(err).Warn("Failed to read wireguard device to update peer metrics")
+		w.closeWireguardClient()
+		return
+	}
+
+	devicePeers := map[wgtypes.Key]wgtypes.Peer{}
+	for _, peer := range device.Peers {
+		devicePeers[peer.PublicKey] = peer
+	}
+
+	seen := map[string]bool{}
+	for name, node := range w.nodes {
+		if name == w.hostname || !w.shouldProgramWireguardPeer(name, node) {
+			continue
+		}
+		seen[name] = true
+		logCxt := log.WithField("node", name)
+
+		peer, ok := devicePeers[node.publicKey]
+		if !ok {
+			logCxt.Debug("Expected wireguard peer is not programmed")
+			peerHandshakeAge.DeleteLabelValues(name)
+			peerAllowedIPsMatch.WithLabelValues(name).Set(0)
+			continue
+		}
+
+		if peer.LastHandshakeTime.IsZero() {
+			peerHandshakeAge.DeleteLabelValues(name)
+		} else {
+			peerHandshakeAge.WithLabelValues(name).Set(w.time.Since(peer.LastHandshakeTime).Seconds())
+		}
+
+		last := w.peerTrafficCounts[name]
+		if last.publicKey != peer.PublicKey || peer.ReceiveBytes < last.rxBytes || peer.TransmitBytes < last.txBytes {
+			// New peer, or the peer has been re-created since our last reading.
+			last = peerTrafficCounts{publicKey: peer.PublicKey}
+		}
+		peerRxBytes.WithLabelValues(name).Add(float64(peer.ReceiveBytes - last.rxBytes))
+		peerTxBytes.WithLabelValues(name).Add(float64(peer.TransmitBytes - last.txBytes))
+		w.peerTrafficCounts[name] = peerTrafficCounts{
+			publicKey: peer.PublicKey,
+			rxBytes:   peer.ReceiveBytes,
+			txBytes:   peer.TransmitBytes,
+		}
+
+		if allowedIPsMatch(node.cidrs, peer) {
+			peerAllowedIPsMatch.WithLabelValues(name).Set(1)
+		} else {
+			logCxt.Debug("Wireguard peer allowed IPs do not match expected CIDRs")
+			peerAllowedIPsMatch.WithLabelValues(name).Set(0)
+		}
+	}
+
+	// Remove the series for peers that are no longer expected.
+	for name := range w.peerMetricsNodes {
+		if !seen[name] {
+			removePeerMetrics(name)
+			delete(w.peerTrafficCounts, name)
+		}
+	}
+	w.peerMetricsNodes = seen
+}
+
+func (w *Wireguard) removeAllPeerMetrics() {
+	for name := range w.peerMetricsNodes {
+		removePeerMetrics(name)
+	}
+	w.peerMetricsNodes = map[string]bool{}
+	w.peerTrafficCounts = map[string]peerTrafficCounts{}
+}
+
+func removePeerMetrics(name string) {
+	peerHandshakeAge.DeleteLabelValues(name)
+	peerRxBytes.DeleteLabelValues(name)
+	peerTxBytes.DeleteLabelValues(name)
+	peerAllowedIPsMatch.DeleteLabelValues(name)
+}
+
+// allowedIPsMatch returns true if the peer's allowed IPs are exactly the expected CIDRs.
+func allowedIPsMatch(expected set.Set, peer wgtypes.Peer) bool {
+	if len(peer.AllowedIPs) != expected.Len() {
+		return false
+	}
+	for i := range peer.AllowedIPs {
+		if !expected.Contains(ip.CIDRFromIPNet(&peer.AllowedIPs[i])) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"net"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/projectcalico/felix/ifacemonitor"
+	"github.com/projectcalico/felix/ip"
+	"github.com/projectcalico/felix/logutils"
+	mocknetlink "github.com/projectcalico/felix/netlinkshim/mocknetlink"
+	"github.com/projectcalico/felix/timeshim/mocktime"
+)
+
+var _ = Describe("Wireguard peer metrics", func() {
+	const (
+		ifaceName = "wireguard-metrics"
+		peer      = "metrics-peer"
+	)
+	var (
+		wgDataplane, rtDataplane, rrDataplane *mocknetlink.MockNetlinkDataplane
+		t                                     *mocktime.MockTime
+		wg                                    *Wireguard
+		link                                  *mocknetlink.MockLink
+		peerKey                               wgtypes.Key
+		peerCIDR                              = ip.MustParseCIDROrIP("192.168.1.0/24")
+	)
+
+	BeforeEach(func() {
+		wgDataplane = mocknetlink.New()
+		rtDataplane = mocknetlink.New()
+		rrDataplane = mocknetlink.New()
+		t = mocktime.New()
+
+		wg = NewWithShims(
+			"metrics-host",
+			&Config{
+				Enabled:             true,
+				ListeningPort:       1000,
+				FirewallMark:        10,
+				RoutingRulePriority: 98,
+				RoutingTableIndex:   99,
+				InterfaceName:       ifaceName,
+				MTU:                 2000,
+			},
+			rtDataplane.NewMockNetlink,
+			rrDataplane.NewMockNetlink,
+			wgDataplane.NewMockNetlink,
+			wgDataplane.NewMockWireguard,
+			10*time.Second,
+			t,
+			syscall.RTPROT_BOOT,
+			func(wgtypes.Key, ip.Addr) error { return nil },
+			logutils.NewSummarizer("test loop"),
+		)
+
+		Expect(wg.Apply()).NotTo(HaveOccurred())
+		wgDataplane.SetIface(ifaceName, true, true)
+		wg.OnIfaceStateChanged(ifaceName, ifacemonitor.StateUp)
+		Expect(wg.Apply()).NotTo(HaveOccurred())
+		link = wgDataplane.NameToLink[ifaceName]
+
+		key, err := wgtypes.GeneratePrivateKey()
+		Expect(err).NotTo(HaveOccurred())
+		peerKey = key.PublicKey()
+		wg.EndpointUpdate(peer, ip.FromString("1.2.3.5"))
+		wg.EndpointWireguardUpdate(peer, peerKey, nil, nil)
+		wg.RouteUpdate(peer, peerCIDR)
+		Expect(wg.Apply()).NotTo(HaveOccurred())
+		Expect(link.WireguardPeers).To(HaveKey(peerKey))
+	})
+
+	AfterEach(func() {
+		removePeerMetrics(peer)
+	})
+
+	setPeerStats := func(handshake time.Time, rx, tx int64) {
+		p := link.WireguardPeers[peerKey]
+		p.LastHandshakeTime = handshake
+		p.ReceiveBytes = rx
+		p.TransmitBytes = tx
+		link.WireguardPeers[peerKey] = p
+	}
+
+	It("should export handshake age, traffic and allowed IPs for a peer", func() {
+		setPeerStats(t.Now().Add(-30*time.Second), 1000, 2000)
+		wg.UpdatePeerMetrics()
+
+		Expect(testutil.ToFloat64(peerHandshakeAge.WithLabelValues(peer))).To(BeNumerically("==", 30))
+		Expect(testutil.ToFloat64(peerRxBytes.WithLabelValues(peer))).To(BeNumerically("==", 1000))
+		Expect(testutil.ToFloat64(peerTxBytes.WithLabelValues(peer))).To(BeNumerically("==", 2000))
+		Expect(testutil.ToFloat64(peerAllowedIPsMatch.WithLabelValues(peer))).To(BeNumerically("==", 1))
+	})
+
+	It("should not export a handshake age before the first handshake", func() {
+		wg.UpdatePeerMetrics()
+		Expect(testutil.CollectAndCount(peerHandshakeAge)).To(Equal(0))
+		Expect(testutil.ToFloat64(peerAllowedIPsMatch.WithLabelValues(peer))).To(BeNumerically("==", 1))
+	})
+
+	It("should add the increase in the traffic counters, handling a reset", func() {
+		setPeerStats(t.Now(), 1000, 2000)
+		wg.UpdatePeerMetrics()
+		setPeerStats(t.Now(), 1500, 2100)
+		wg.UpdatePeerMetrics()
+		Expect(testutil.ToFloat64(peerRxBytes.WithLabelValues(peer))).To(BeNumerically("==", 1500))
+		Expect(testutil.ToFloat64(peerTxBytes.WithLabelValues(peer))).To(BeNumerically("==", 2100))
+
+		// Peer re-created, so the device counters restart.
+		setPeerStats(t.Now(), 100, 50)
+		wg.UpdatePeerMetrics()
+		Expect(testutil.ToFloat64(peerRxBytes.WithLabelValues(peer))).To(BeNumerically("==", 1600))
+		Expect(testutil.ToFloat64(peerTxBytes.WithLabelValues(peer))).To(BeNumerically("==", 2150))
+	})
+
+	It("should report a mismatch if the allowed IPs differ from those expected", func() {
+		p := link.WireguardPeers[peerKey]
+		p.AllowedIPs = append(p.AllowedIPs, ip.MustParseCIDROrIP("10.0.0.0/24").ToIPNet())
+		link.WireguardPeers[peerKey] = p
+		wg.UpdatePeerMetrics()
+		Expect(testutil.ToFloat64(peerAllowedIPsMatch.WithLabelValues(peer))).To(BeNumerically("==", 0))
+
+		p.AllowedIPs = []net.IPNet{}
+		link.WireguardPeers[peerKey] = p
+		wg.UpdatePeerMetrics()
+		Expect(testutil.ToFloat64(peerAllowedIPsMatch.WithLabelValues(peer))).To(BeNumerically("==", 0))
+	})
+
+	It("should report a mismatch if the peer is missing", func() {
+		delete(link.WireguardPeers, peerKey)
+		wg.UpdatePeerMetrics()
+		Expect(testutil.ToFloat64(peerAllowedIPsMatch.WithLabelValues(peer))).To(BeNumerically("==", 0))
+	})
+
+	It("should remove the series when the peer is removed", func() {
+		setPeerStats(t.Now(), 1000, 2000)
+		wg.UpdatePeerMetrics()
+		Expect(testutil.CollectAndCount(peerAllowedIPsMatch)).To(Equal(1))
+
+		wg.EndpointRemove(peer)
+		wg.EndpointWireguardRemove(peer)
+		wg.RouteRemove(peerCIDR)
+		Expect(wg.Apply()).NotTo(HaveOccurred())
+		wg.UpdatePeerMetrics()
+		Expect(testutil.CollectAndCount(peerHandshakeAge)).To(Equal(0))
+		Expect(testutil.CollectAndCount(peerRxBytes)).To(Equal(0))
+		Expect(testutil.CollectAndCount(peerTxBytes)).To(Equal(0))
+		Expect(testutil.CollectAndCount(peerAllowedIPsMatch)).To(Equal(0))
+	})
+})
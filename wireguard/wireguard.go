@@ -26,6 +26,7 @@ import (
 
 	"github.com/projectcalico/libcalico-go/lib/set"
 
+	"github.com/projectcalico/felix/conntrack"
 	"github.com/projectcalico/felix/ifacemonitor"
 	"github.com/projectcalico/felix/ip"
 	"github.com/projectcalico/felix/logutils"
@@ -57,13 +58,13 @@ var (
 
 const (
 	wireguardType = "wireguard"
-	ipVersion     = 4
-	ipPrefixLen   = 32
 )
 
 type noOpConnTrack struct{}
 
-func (*noOpConnTrack) RemoveConntrackFlows(ipVersion uint8, ipAddr net.IP) {}
+func (*noOpConnTrack) RemoveConntrackFlowsForIPs(ipVersion uint8, ipAddrs []net.IP, filter conntrack.FlowFilter) (int, error) {
+	return 0, nil
+}
 
 type nodeData struct {
 	ipv4EndpointAddr      ip.Addr
@@ -121,14 +122,20 @@ type Wireguard struct {
 	time                                 timeshim.Interface
 
 	// State information.
-	inSyncWireguard                    bool
-	inSyncLink                         bool
-	inSyncInterfaceAddr                bool
-	ifaceUp                            bool
-	wireguardNotSupported              bool
-	ourPublicKey                       *wgtypes.Key
-	ourIPv4InterfaceAddr               ip.Addr
-	ourPublicKeyAgreesWithDataplaneMsg bool
+	inSyncWireguard                 bool
+	inSyncLink                      bool
+	inSyncInterfaceAddr             bool
+	ifaceUp                         bool
+	wireguardNotSupported           bool
+	ourPublicKey                    *wgtypes.Key
+	ourPublicKeyTime                time.Time
+	ourIPv4InterfaceAddr            ip.Addr
+	ourIPv6InterfaceAddr            ip.Addr
+	ourStatusAgreesWithDataplaneMsg bool
+
+	// Per-peer metrics state: the nodes that have peer metrics series, and the most recent traffic counter readings.
+	peerMetricsNodes  map[string]bool
+	peerTrafficCounts map[string]peerTrafficCounts
 
 	// Local workload information
 	localIPs          set.Set
@@ -149,12 +156,14 @@ type Wireguard struct {
 	// CIDR to node mappings - this is updated synchronously.
 	cidrToNodeName map[ip.CIDR]string
 
-	// Wireguard routing table and rule managers
-	routetable *routetable.RouteTable
-	routerule  *routerule.RouteRules
+	// Wireguard routing table and rule managers.  The IPv6 ones are nil if IPv6 is not enabled.
+	routetable   *routetable.RouteTable
+	routerule    *routerule.RouteRules
+	routetableV6 *routetable.RouteTable
+	routeruleV6  *routerule.RouteRules
 
-	// Callback function used to notify of public key updates for the local nodeData
-	statusCallback func(publicKey wgtypes.Key) error
+	// Callback function used to notify of public key and IPv6 interface address updates for the local nodeData
+	statusCallback func(publicKey wgtypes.Key, ipv6InterfaceAddr ip.Addr) error
 	opRecorder     logutils.OpRecorder
 }
 
@@ -163,7 +172,7 @@ func New(
 	config *Config,
 	netlinkTimeout time.Duration,
 	deviceRouteProtocol int,
-	statusCallback func(publicKey wgtypes.Key) error,
+	statusCallback func(publicKey wgtypes.Key, ipv6InterfaceAddr ip.Addr) error,
 	opRecorder logutils.OpRecorder,
 ) *Wireguard {
 	return NewWithShims(
@@ -192,44 +201,54 @@ func NewWithShims(
 	netlinkTimeout time.Duration,
 	timeShim timeshim.Interface,
 	deviceRouteProtocol int,
-	statusCallback func(publicKey wgtypes.Key) error,
+	statusCallback func(publicKey wgtypes.Key, ipv6InterfaceAddr ip.Addr) error,
 	opRecorder logutils.OpRecorder,
 ) *Wireguard {
-	// Create routetable. We provide dummy callbacks for ARP and conntrack processing.
-	rt := routetable.NewWithShims(
-		[]string{"^" + config.InterfaceName + "$", routetable.InterfaceNone},
-		ipVersion,
-		newRoutetableNetlink,
-		false, // vxlan
-		netlinkTimeout,
-		func(cidr ip.CIDR, destMAC net.HardwareAddr, ifaceName string) error { return nil }, // addStaticARPEntry
-		&noOpConnTrack{},
-		timeShim,
-		nil, // deviceRouteSourceAddress
-		deviceRouteProtocol,
-		true, // removeExternalRoutes
-		config.RoutingTableIndex,
-		opRecorder,
-	)
-	// Create routerule.
-	rr, err := routerule.New(
-		ipVersion,
-		config.RoutingRulePriority,
-		set.From(config.RoutingTableIndex),
-		routerule.RulesMatchSrcFWMarkTable,
-		routerule.RulesMatchSrcFWMarkTable,
-		netlinkTimeout,
-		func() (routerule.HandleIface, error) {
-			return newRouteRuleNetlink()
-		},
-		opRecorder,
-	)
-	if err != nil && config.Enabled {
-		// Wireguard is enabled, but could not create a routerule manager. This is unexpected.
-		log.WithError(err).Panic("Unexpected error creating rule manager")
+	// Create routetable and routerule for each IP version. IPv6 CIDRs are routed over the same device, and to the same
+	// peers, as IPv4 ones.  Neither has a dry-run recorder: WireGuard can't be enabled in dry-run mode, and the
+	// dataplane doesn't create us at all in that mode.
+	newRouteTable := func(ipVersion uint8) *routetable.RouteTable {
+		// We provide dummy callbacks for ARP and conntrack processing.
+		return routetable.NewWithShims(
+			[]string{"^" + config.InterfaceName + "$", routetable.InterfaceNone},
+			ipVersion,
+			newRoutetableNetlink,
+			false, // vxlan
+			netlinkTimeout,
+			func(cidr ip.CIDR, destMAC net.HardwareAddr, ifaceName string) error { return nil }, // addStaticARPEntry
+			&noOpConnTrack{},
+			timeShim,
+			nil, // deviceRouteSourceAddress
+			deviceRouteProtocol,
+			true, // removeExternalRoutes
+			config.RoutingTableIndex,
+			opRecorder,
+			nil, // dryRun
+			nil, // driftReporter
+		)
+	}
+	newRouteRule := func(ipVersion int) *routerule.RouteRules {
+		rr, err := routerule.New(
+			ipVersion,
+			config.RoutingRulePriority,
+			set.From(config.RoutingTableIndex),
+			routerule.RulesMatchSrcFWMarkTable,
+			routerule.RulesMatchSrcFWMarkTable,
+			netlinkTimeout,
+			func() (routerule.HandleIface, error) {
+				return newRouteRuleNetlink()
+			},
+			opRecorder,
+			nil, // dryRun
+		)
+		if err != nil && config.Enabled {
+			// Wireguard is enabled, but could not create a routerule manager. This is unexpected.
+			log.WithError(err).Panic("Unexpected error creating rule manager")
+		}
+		return rr
 	}
 
-	return &Wireguard{
+	w := &Wireguard{
 		hostname:             hostname,
 		config:               config,
 		newNetlinkClient:     newWireguardNetlink,
@@ -239,13 +258,23 @@ func NewWithShims(
 		cidrToNodeName:       map[ip.CIDR]string{},
 		publicKeyToNodeNames: map[wgtypes.Key]set.Set{},
 		nodeUpdates:          map[string]*nodeUpdateData{},
-		routetable:           rt,
-		routerule:            rr,
+		routetable:           newRouteTable(4),
+		routerule:            newRouteRule(4),
 		statusCallback:       statusCallback,
 		localIPs:             set.New(),
 		localCIDRs:           set.New(),
+		peerMetricsNodes:     map[string]bool{},
+		peerTrafficCounts:    map[string]peerTrafficCounts{},
 		opRecorder:           opRecorder,
 	}
+	if config.EnableIPv6 {
+		w.routetableV6 = newRouteTable(6)
+		w.routeruleV6 = newRouteRule(6)
+		if config.InterfaceIPv6Addr != nil {
+			w.ourIPv6InterfaceAddr = ip.FromNetIP(config.InterfaceIPv6Addr)
+		}
+	}
+	return w
 }
 
 func (w *Wireguard) OnIfaceStateChanged(ifaceName string, state ifacemonitor.State) {
@@ -266,8 +295,10 @@ func (w *Wireguard) OnIfaceStateChanged(ifaceName string, state ifacemonitor.Sta
 		w.ifaceUp = false
 	}
 
-	// Notify the wireguard routetable module.
-	w.routetable.OnIfaceStateChanged(ifaceName, state)
+	// Notify the wireguard routetable modules.
+	for _, rt := range w.routeTables() {
+		rt.OnIfaceStateChanged(ifaceName, state)
+	}
 }
 
 func (w *Wireguard) EndpointUpdate(name string, ipv4Addr ip.Addr) {
@@ -324,6 +355,9 @@ func (w *Wireguard) RouteUpdate(name string, cidr ip.CIDR) {
 	if !w.config.Enabled {
 		logCxt.Debug("Not enabled - ignoring")
 		return
+	} else if cidr.Version() == 6 && !w.config.EnableIPv6 {
+		logCxt.Debug("IPv6 not enabled - ignoring")
+		return
 	}
 
 	// Determine which node this CIDR belongs to.
@@ -387,10 +421,10 @@ func (w *Wireguard) routeRemove(name string, cidr ip.CIDR) {
 // programmed - if it is then no further update is required.
 func (w *Wireguard) localWorkloadCIDRAdd(cidr ip.CIDR) {
 	log.WithField("cidr", cidr).Debug("localWorkloadCIDRAdd")
-	// Split the local CIDRs into actual /32 workload IPs and the CIDR blocks for the node. We assume the CIDR blocks
-	// are not overlapping, and so we add rules for each CIDR to route to wireguard, and only include the /32 workload
+	// Split the local CIDRs into actual /32 (or /128) workload IPs and the CIDR blocks for the node. We assume the CIDR
+	// blocks are not overlapping, and so we add rules for each CIDR to route to wireguard, and only include the workload
 	// IPs if not covered by the CIDR blocks.
-	if cidr.Prefix() == ipPrefixLen {
+	if isSingleAddressCIDR(cidr) {
 		w.localIPs.Add(cidr.Addr())
 	} else {
 		w.localCIDRs.Add(cidr)
@@ -423,7 +457,7 @@ func (w *Wireguard) localWorkloadCIDRAdd(cidr ip.CIDR) {
 // we only need to update the local CIDRs if the CIDR being removed is one of the ones programmed.
 func (w *Wireguard) localWorkloadCIDRRemove(cidr ip.CIDR) {
 	log.WithField("cidr", cidr).Debug("localWorkloadCIDRRemove")
-	if cidr.Prefix() == ipPrefixLen {
+	if isSingleAddressCIDR(cidr) {
 		w.localIPs.Discard(cidr.Addr())
 	} else {
 		w.localCIDRs.Discard(cidr)
@@ -474,8 +508,15 @@ func (w *Wireguard) peerAllowedCIDRRemove(name string, cidr ip.CIDR) {
 	w.setNodeUpdate(name, update)
 }
 
-func (w *Wireguard) EndpointWireguardUpdate(name string, publicKey wgtypes.Key, ipv4InterfaceAddr ip.Addr) {
-	logCxt := log.WithFields(log.Fields{"node": name, "publicKey": publicKey, "ipv4InterfaceAddr": ipv4InterfaceAddr})
+func (w *Wireguard) EndpointWireguardUpdate(
+	name string, publicKey wgtypes.Key, ipv4InterfaceAddr, ipv6InterfaceAddr ip.Addr,
+) {
+	logCxt := log.WithFields(log.Fields{
+		"node":              name,
+		"publicKey":         publicKey,
+		"ipv4InterfaceAddr": ipv4InterfaceAddr,
+		"ipv6InterfaceAddr": ipv6InterfaceAddr,
+	})
 	logCxt.Debug("EndpointWireguardUpdate")
 	if !w.config.Enabled {
 		log.Debug("Not enabled - ignoring")
@@ -488,7 +529,13 @@ func (w *Wireguard) EndpointWireguardUpdate(name string, publicKey wgtypes.Key,
 			// Public key does not match that stored. Flag as not in-sync, we will update the value from the dataplane
 			// and publish.
 			logCxt.Debug("Stored public key does not match key queried from dataplane")
-			w.ourPublicKeyAgreesWithDataplaneMsg = false
+			w.ourStatusAgreesWithDataplaneMsg = false
+		}
+		if !addrsEqual(w.publishedIPv6InterfaceAddr(), ipv6InterfaceAddr) {
+			// The IPv6 interface address comes from our config rather than from the datastore. If the published value
+			// does not match ours then republish.
+			logCxt.Debug("Published IPv6 interface address does not match configured address")
+			w.ourStatusAgreesWithDataplaneMsg = false
 		}
 		if w.ourIPv4InterfaceAddr != ipv4InterfaceAddr {
 			logCxt.Debug("Local interface addr updated")
@@ -521,7 +568,7 @@ func (w *Wireguard) EndpointWireguardRemove(name string) {
 		return
 	}
 	if name == w.hostname {
-		w.EndpointWireguardUpdate(name, zeroKey, nil)
+		w.EndpointWireguardUpdate(name, zeroKey, nil, nil)
 		return
 	}
 
@@ -553,12 +600,14 @@ func (w *Wireguard) QueueResync() {
 	// the Apply processing until the next resync.
 	w.wireguardNotSupported = false
 
-	// Flag the routetable for resync.
-	w.routetable.QueueResync()
+	// Flag the routetables for resync.
+	for _, rt := range w.routeTables() {
+		rt.QueueResync()
+	}
 
-	// Flag the routerule for resync.
-	if w.routerule != nil {
-		w.routerule.QueueResync()
+	// Flag the routerules for resync.
+	for _, rr := range w.routeRules() {
+		rr.QueueResync()
 	}
 }
 
@@ -566,15 +615,18 @@ func (w *Wireguard) Apply() (err error) {
 	// If the key is not in-sync and is known then send as a status update.
 	defer func() {
 		// If we need to send the key then send on the callback method.
-		if !w.ourPublicKeyAgreesWithDataplaneMsg && w.ourPublicKey != nil {
-			log.WithField("ourPublicKey", *w.ourPublicKey).Info("Public key out of sync or updated")
-			if errKey := w.statusCallback(*w.ourPublicKey); errKey != nil {
+		if !w.ourStatusAgreesWithDataplaneMsg && w.ourPublicKey != nil {
+			log.WithFields(log.Fields{
+				"ourPublicKey":         *w.ourPublicKey,
+				"ourIPv6InterfaceAddr": w.publishedIPv6InterfaceAddr(),
+			}).Info("Public key or IPv6 interface address out of sync or updated")
+			if errKey := w.statusCallback(*w.ourPublicKey, w.publishedIPv6InterfaceAddr()); errKey != nil {
 				err = errKey
 				return
 			}
 
 			// We have sent the key status update.
-			w.ourPublicKeyAgreesWithDataplaneMsg = true
+			w.ourStatusAgreesWithDataplaneMsg = true
 		}
 	}()
 
@@ -685,20 +737,26 @@ func (w *Wireguard) Apply() (err error) {
 
 	// The following can be done in parallel:
 	// - Update the link address
-	// - Update the routetable
+	// - Update the routetables
 	// - Update the wireguard device.
 	var wg sync.WaitGroup
-	var errLink, errWireguard, errRoutes error
+	var errLink, errWireguard, errRoutes, errRoutesV6 error
 
-	// Update link address if out of sync.
+	// Update link addresses if out of sync.
 	if !w.inSyncInterfaceAddr {
 		log.Info("Ensure wireguard interface address is correct")
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if errLink = w.ensureLinkAddressV4(netlinkClient); errLink == nil {
-				w.inSyncInterfaceAddr = true
+			if errLink = w.ensureLinkAddress(netlinkClient, 4, w.ourIPv4InterfaceAddr); errLink != nil {
+				return
 			}
+			if w.config.EnableIPv6 {
+				if errLink = w.ensureLinkAddress(netlinkClient, 6, w.ourIPv6InterfaceAddr); errLink != nil {
+					return
+				}
+			}
+			w.inSyncInterfaceAddr = true
 		}()
 	}
 
@@ -709,6 +767,21 @@ func (w *Wireguard) Apply() (err error) {
 		defer wg.Done()
 		errRoutes = w.routetable.Apply()
 	}()
+	if w.routetableV6 != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errRoutesV6 = w.routetableV6.Apply()
+		}()
+	}
+
+	// If our key is due to be rotated then perform a full resync of the wireguard configuration, which will replace
+	// the key.
+	if w.inSyncWireguard && w.keyRotationDue() {
+		log.Warn("Wireguard key rotation is due, resync wireguard configuration; wireguard traffic to and from " +
+			"this node will be dropped until the peers learn the new public key")
+		w.inSyncWireguard = false
+	}
 
 	// Apply wireguard configuration.
 	wg.Add(1)
@@ -746,7 +819,8 @@ func (w *Wireguard) Apply() (err error) {
 				// Store and flag our key is not in sync so that a status update will be sent.
 				log.WithField("publicKey", publicKey).Info("Public key has been updated, send status notification")
 				w.ourPublicKey = &publicKey
-				w.ourPublicKeyAgreesWithDataplaneMsg = false
+				w.ourPublicKeyTime = w.time.Now()
+				w.ourStatusAgreesWithDataplaneMsg = false
 			}
 		}
 		w.inSyncWireguard = true
@@ -768,17 +842,19 @@ func (w *Wireguard) Apply() (err error) {
 		w.closeNetlinkClient()
 	}
 
-	if errLink != nil || errRoutes != nil || errWireguard != nil {
+	if errLink != nil || errRoutes != nil || errRoutesV6 != nil || errWireguard != nil {
 		return ErrUpdateFailed
 	}
 
 	// Once the wireguard and routing configuration is in place we can add the routing rules to start using the new
 	// routing table.
 	log.Debug("Ensure routing rules are configured")
-	w.addRouteRule()
-	if err = w.routerule.Apply(); err != nil {
-		// Error updating the ip rule.
-		return ErrUpdateFailed
+	w.addRouteRules()
+	for _, rr := range w.routeRules() {
+		if err = rr.Apply(); err != nil {
+			// Error updating the ip rule.
+			return ErrUpdateFailed
+		}
 	}
 
 	return nil
@@ -898,7 +974,7 @@ func (w *Wireguard) handlePeerAndRouteDeletionFromNodeUpdates(conflictingKeys se
 			// takes care of its own kernel-cache synchronization.
 			node.cidrs.Iter(func(item interface{}) error {
 				cidr := item.(ip.CIDR)
-				w.routetable.RouteRemove(w.config.InterfaceName, cidr)
+				w.routeTableForCIDR(cidr).RouteRemove(w.config.InterfaceName, cidr)
 				delete(w.cidrToNodeName, cidr)
 				logCxt.WithField("cidr", cidr).Debug("Deleting route")
 				return nil
@@ -1022,7 +1098,7 @@ func (w *Wireguard) updateRouteTableFromNodeUpdates() {
 		update.cidrsDeleted.Iter(func(item interface{}) error {
 			cidr := item.(ip.CIDR)
 			logCxt.WithField("cidr", cidr).Debug("Removing CIDR from routetable interface")
-			w.routetable.RouteRemove(ifaceName, cidr)
+			w.routeTableForCIDR(cidr).RouteRemove(ifaceName, cidr)
 			return nil
 		})
 	}
@@ -1073,9 +1149,9 @@ func (w *Wireguard) updateRouteTableFromNodeUpdates() {
 				// routetable component groups by interface and we are essentially moving routes between the wireguard
 				// interface and the "none" interface.
 				updateLogCxt.WithField("ifacename", deleteIfaceName).Debug("Wireguard routing has changed - delete previous route for interface")
-				w.routetable.RouteRemove(deleteIfaceName, cidr)
+				w.routeTableForCIDR(cidr).RouteRemove(deleteIfaceName, cidr)
 			}
-			w.routetable.RouteUpdate(ifaceName, routetable.Target{
+			w.routeTableForCIDR(cidr).RouteUpdate(ifaceName, routetable.Target{
 				Type: targetType,
 				CIDR: cidr,
 			})
@@ -1219,9 +1295,15 @@ func (w *Wireguard) constructWireguardDeltaForResync(wireguardClient netlinkshim
 	}
 
 	publicKey := device.PublicKey
-	if device.PrivateKey == zeroKey || device.PublicKey == zeroKey {
-		// One of the private or public key is not set. Generate a new private key and return the corresponding
-		// public key.
+	if device.PrivateKey == zeroKey || device.PublicKey == zeroKey || w.keyRotationDue() {
+		// One of the private or public key is not set, or our key is due to be rotated. Generate a new private key and
+		// return the corresponding public key.
+		//
+		// Only the key is replaced; the peers and routes are left in place so that traffic is never sent
+		// unencrypted. Rotation is not hitless: a wireguard device only has one private key so, as soon as it is
+		// replaced, the existing sessions are lost and handshakes fail in both directions until each peer has
+		// learned our new public key from the datastore. Until then, traffic between this node and its peers is
+		// dropped.
 		log.Info("Generate new private/public keypair")
 		pkey, err := wgtypes.GeneratePrivateKey()
 		if err != nil {
@@ -1426,31 +1508,39 @@ func (w *Wireguard) ensureNoLink(netlinkClient netlinkshim.Interface) error {
 	return nil
 }
 
-// ensureLinkAddressV4 ensures the wireguard link to set to the required local IP address.  It removes any other
-// addresses.
-func (w *Wireguard) ensureLinkAddressV4(netlinkClient netlinkshim.Interface) error {
-	logCxt := log.WithField("ifaceName", w.config.InterfaceName)
-	logCxt.Debug("Setting local IPv4 address on link.")
+// ensureLinkAddress ensures the wireguard link to set to the required local IP address of the given IP version.  It
+// removes any other addresses of that version (other than IPv6 link-local addresses, which are managed by the kernel).
+func (w *Wireguard) ensureLinkAddress(netlinkClient netlinkshim.Interface, ipVersion int, ifaceAddr ip.Addr) error {
+	logCxt := log.WithFields(log.Fields{"ifaceName": w.config.InterfaceName, "ipVersion": ipVersion})
+	logCxt.Debug("Setting local address on link.")
 	link, err := netlinkClient.LinkByName(w.config.InterfaceName)
 	if err != nil {
 		logCxt.WithError(err).Warn("Failed to get device")
 		return err
 	}
 
-	addrs, err := netlinkClient.AddrList(link, netlink.FAMILY_V4)
+	family, maskLen := netlink.FAMILY_V4, 32
+	if ipVersion == 6 {
+		family, maskLen = netlink.FAMILY_V6, 128
+	}
+	addrs, err := netlinkClient.AddrList(link, family)
 	if err != nil {
 		logCxt.WithError(err).Warn("failed to list interface addresses")
 		return err
 	}
 
 	var address net.IP
-	if w.ourIPv4InterfaceAddr != nil {
-		address = w.ourIPv4InterfaceAddr.AsNetIP()
+	if ifaceAddr != nil {
+		address = ifaceAddr.AsNetIP()
 	}
 
 	found := false
 	for _, oldAddr := range addrs {
 		addrLogCxt := logCxt.WithField("addr", oldAddr)
+		if ipVersion == 6 && oldAddr.IP.IsLinkLocalUnicast() {
+			addrLogCxt.Debug("Ignoring link-local address.")
+			continue
+		}
 		if address != nil && oldAddr.IP.Equal(address) {
 			addrLogCxt.Debug("Address already present.")
 			found = true
@@ -1467,7 +1557,7 @@ func (w *Wireguard) ensureLinkAddressV4(netlinkClient netlinkshim.Interface) err
 		addrLogCxt := logCxt.WithField("addr", address)
 		if !found {
 			addrLogCxt.Info("address not present on wireguard device, adding it")
-			mask := net.CIDRMask(32, 32)
+			mask := net.CIDRMask(maskLen, maskLen)
 			ipNet := net.IPNet{
 				IP:   address.Mask(mask), // Mask the IP to match ParseCIDR()'s behaviour.
 				Mask: mask,
@@ -1486,47 +1576,59 @@ func (w *Wireguard) ensureLinkAddressV4(netlinkClient netlinkshim.Interface) err
 	return nil
 }
 
-// addRouteRule adds a routing rule to use the wireguard table.
-func (w *Wireguard) addRouteRule() {
-	w.routerule.SetRule(routerule.NewRule(ipVersion, w.config.RoutingRulePriority).
+// addRouteRules adds a routing rule to use the wireguard table for each IP version.
+func (w *Wireguard) addRouteRules() {
+	w.routerule.SetRule(routerule.NewRule(4, w.config.RoutingRulePriority).
 		GoToTable(w.config.RoutingTableIndex).
 		MatchFWMarkWithMask(0, uint32(w.config.FirewallMark)))
+	if w.routeruleV6 != nil {
+		w.routeruleV6.SetRule(routerule.NewRule(6, w.config.RoutingRulePriority).
+			GoToTable(w.config.RoutingTableIndex).
+			MatchFWMarkWithMask(0, uint32(w.config.FirewallMark)))
+	}
 }
 
 // ensureDisabled ensures all calico-installed wireguard configuration is removed.
 func (w *Wireguard) ensureDisabled(netlinkClient netlinkshim.Interface) error {
-	var errRule, errLink, errRoutes error
+	var errLink error
 	wg := sync.WaitGroup{}
 
-	if w.routerule != nil {
+	rrs := w.routeRules()
+	errRules := make([]error, len(rrs))
+	for i := range rrs {
 		wg.Add(1)
-		go func() {
+		go func(i int) {
 			defer wg.Done()
-			errRule = w.routerule.Apply()
-		}()
+			errRules[i] = rrs[i].Apply()
+		}(i)
 	}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		errLink = w.ensureNoLink(netlinkClient)
 	}()
+	var rts []*routetable.RouteTable
 	if w.config.RoutingTableIndex > 0 {
-		// Only attempt automatic cleanup of the routing table if it is not the default table.
+		// Only attempt automatic cleanup of the routing tables if it is not the default table.
+		rts = w.routeTables()
+	}
+	errRoutes := make([]error, len(rts))
+	for i := range rts {
 		wg.Add(1)
-		go func() {
+		go func(i int) {
 			defer wg.Done()
-			// The routetable configuration will be empty since we will not send updates, so applying this will remove the
-			// old routes if so configured.
-			errRoutes = w.routetable.Apply()
-		}()
-		wg.Wait()
+			// The routetable configuration will be empty since we will not send updates, so applying this will remove
+			// the old routes if so configured.
+			errRoutes[i] = rts[i].Apply()
+		}(i)
 	}
+	wg.Wait()
 
-	if errRule != nil || errLink != nil {
+	if firstError(errRules) != nil || errLink != nil {
 		// Failed to delete the rule or link.  Close the netlink client as a precaution.
 		w.closeNetlinkClient()
 		return ErrUpdateFailed
-	} else if errRoutes != nil {
+	} else if firstError(errRoutes) != nil {
 		// Routes are handled by a separate module which takes care of its own netlink client lifecycle.
 		return ErrUpdateFailed
 	}
@@ -1666,6 +1768,77 @@ func (w *Wireguard) setAllInSync(inSync bool) {
 	w.inSyncInterfaceAddr = inSync
 }
 
+// routeTables returns the route tables for each enabled IP version.
+func (w *Wireguard) routeTables() []*routetable.RouteTable {
+	if w.routetableV6 != nil {
+		return []*routetable.RouteTable{w.routetable, w.routetableV6}
+	}
+	return []*routetable.RouteTable{w.routetable}
+}
+
+// routeRules returns the route rules for each enabled IP version. The IPv4 route rules may be nil if wireguard is not
+// enabled.
+func (w *Wireguard) routeRules() []*routerule.RouteRules {
+	var rrs []*routerule.RouteRules
+	if w.routerule != nil {
+		rrs = append(rrs, w.routerule)
+	}
+	if w.routeruleV6 != nil {
+		rrs = append(rrs, w.routeruleV6)
+	}
+	return rrs
+}
+
+// routeTableForCIDR returns the route table for the CIDR's IP version. IPv6 CIDRs are filtered out on receipt if IPv6
+// is not enabled, so the route table is never nil.
+func (w *Wireguard) routeTableForCIDR(cidr ip.CIDR) *routetable.RouteTable {
+	if cidr.Version() == 6 {
+		return w.routetableV6
+	}
+	return w.routetable
+}
+
+// publishedIPv6InterfaceAddr returns the IPv6 interface address that we publish along with our public key. We only
+// publish an address if we have a valid public key - otherwise the address is of no use to other nodes.
+func (w *Wireguard) publishedIPv6InterfaceAddr() ip.Addr {
+	if w.ourPublicKey == nil || *w.ourPublicKey == zeroKey {
+		return nil
+	}
+	return w.ourIPv6InterfaceAddr
+}
+
+// keyRotationDue returns true if key rotation is enabled and our current key has been in use for at least the
+// rotation interval.
+func (w *Wireguard) keyRotationDue() bool {
+	if w.config.KeyRotationInterval <= 0 || w.ourPublicKey == nil || *w.ourPublicKey == zeroKey {
+		return false
+	}
+	return w.time.Since(w.ourPublicKeyTime) >= w.config.KeyRotationInterval
+}
+
+// isSingleAddressCIDR returns true if the CIDR is a /32 (IPv4) or /128 (IPv6).
+func isSingleAddressCIDR(cidr ip.CIDR) bool {
+	return cidr.Prefix() == cidr.Addr().AsCIDR().Prefix()
+}
+
+// addrsEqual returns true if both addresses are nil, or if they are the same address.
+func addrsEqual(a, b ip.Addr) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.String() == b.String()
+}
+
+// firstError returns the first non-nil error in the slice, or nil if there are none.
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // getOnlyItemInSet returns the only item in the set, or nil if the set is nil or the set does not contain only one
 // item.
 func getOnlyItemInSet(s set.Set) interface{} {
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync"
 	"syscall"
 	"time"
 
@@ -33,6 +34,7 @@ import (
 
 	"github.com/projectcalico/felix/ifacemonitor"
 	"github.com/projectcalico/felix/ip"
+	"github.com/projectcalico/felix/netlinkshim"
 	mocknetlink "github.com/projectcalico/felix/netlinkshim/mocknetlink"
 	"github.com/projectcalico/felix/timeshim/mocktime"
 )
@@ -123,15 +125,17 @@ type mockStatus struct {
 	numCallbacks int
 	err          error
 	key          wgtypes.Key
+	ipv6Addr     ip.Addr
 }
 
-func (m *mockStatus) status(publicKey wgtypes.Key) error {
-	log.Debugf("Status update with public key: %s", publicKey)
+func (m *mockStatus) status(publicKey wgtypes.Key, ipv6InterfaceAddr ip.Addr) error {
+	log.Debugf("Status update with public key: %s, IPv6 interface address: %v", publicKey, ipv6InterfaceAddr)
 	m.numCallbacks++
 	if m.err != nil {
 		return m.err
 	}
 	m.key = publicKey
+	m.ipv6Addr = ipv6InterfaceAddr
 
 	log.Debugf("Num callbacks: %d", m.numCallbacks)
 	return nil
@@ -272,6 +276,16 @@ var _ = Describe("Enable wireguard", func() {
 				Expect(rrDataplane.AddedRules).To(ConsistOf(*rule))
 			})
 
+			It("should ignore IPv6 CIDRs when IPv6 is not enabled", func() {
+				rtDataplane.ResetDeltas()
+				wg.RouteUpdate(peer1, ip.MustParseCIDROrIP("fd00:1::/120"))
+				err := wg.Apply()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(rtDataplane.AddedRouteKeys).To(BeEmpty())
+				Expect(rrDataplane.AddedRules).To(ConsistOf(*rule))
+			})
+
 			It("should delete invalid rules jumping to the wireguard table", func() {
 				incorrectRule := netlink.NewRule()
 				incorrectRule.Priority = rulePriority + 10
@@ -298,7 +312,7 @@ var _ = Describe("Enable wireguard", func() {
 				Expect(s.key).To(Equal(key.PublicKey()))
 
 				ipv4 := ip.FromString("1.2.3.4")
-				wg.EndpointWireguardUpdate(hostname, zeroKey, ipv4)
+				wg.EndpointWireguardUpdate(hostname, zeroKey, ipv4, nil)
 				err := wg.Apply()
 				Expect(err).NotTo(HaveOccurred())
 				link = wgDataplane.NameToLink[ifaceName]
@@ -320,7 +334,7 @@ var _ = Describe("Enable wireguard", func() {
 				key := link.WireguardPrivateKey
 
 				ipv4 := ip.FromString("1.2.3.4")
-				wg.EndpointWireguardUpdate(hostname, key.PublicKey(), ipv4)
+				wg.EndpointWireguardUpdate(hostname, key.PublicKey(), ipv4, nil)
 				err := wg.Apply()
 				Expect(err).NotTo(HaveOccurred())
 				link = wgDataplane.NameToLink[ifaceName]
@@ -403,12 +417,12 @@ var _ = Describe("Enable wireguard", func() {
 				var link *mocknetlink.MockLink
 				BeforeEach(func() {
 					Expect(s.numCallbacks).To(Equal(1))
-					wg.EndpointWireguardUpdate(hostname, s.key, nil)
+					wg.EndpointWireguardUpdate(hostname, s.key, nil, nil)
 					key_peer1 = mustGeneratePrivateKey().PublicKey()
-					wg.EndpointWireguardUpdate(peer1, key_peer1, nil)
+					wg.EndpointWireguardUpdate(peer1, key_peer1, nil, nil)
 					wg.EndpointUpdate(peer1, ipv4_peer1)
 					key_peer2 = mustGeneratePrivateKey().PublicKey()
-					wg.EndpointWireguardUpdate(peer2, key_peer2, nil)
+					wg.EndpointWireguardUpdate(peer2, key_peer2, nil, nil)
 					wg.EndpointUpdate(peer2, ipv4_peer2)
 					wg.RouteUpdate(hostname, cidr_local)
 					err := wg.Apply()
@@ -468,8 +482,8 @@ var _ = Describe("Enable wireguard", func() {
 				It("should have no updates for backing out a peer key update", func() {
 					wgDataplane.ResetDeltas()
 					rtDataplane.ResetDeltas()
-					wg.EndpointWireguardUpdate(peer1, key_peer2, nil)
-					wg.EndpointWireguardUpdate(peer1, key_peer1, nil)
+					wg.EndpointWireguardUpdate(peer1, key_peer2, nil, nil)
+					wg.EndpointWireguardUpdate(peer1, key_peer1, nil, nil)
 					err := wg.Apply()
 					Expect(err).NotTo(HaveOccurred())
 					Expect(wgDataplane.WireguardConfigUpdated).To(BeFalse())
@@ -479,7 +493,7 @@ var _ = Describe("Enable wireguard", func() {
 					wgDataplane.ResetDeltas()
 					rtDataplane.ResetDeltas()
 					wg.EndpointUpdate(peer3, ipv4_peer3)
-					wg.EndpointWireguardUpdate(peer3, key_peer1, nil)
+					wg.EndpointWireguardUpdate(peer3, key_peer1, nil, nil)
 					wg.EndpointRemove(peer3)
 					wg.EndpointWireguardRemove(peer3)
 					err := wg.Apply()
@@ -518,7 +532,7 @@ var _ = Describe("Enable wireguard", func() {
 							wgPeers[k] = p
 						}
 
-						wg.EndpointWireguardUpdate(peer2, key_peer1, nil)
+						wg.EndpointWireguardUpdate(peer2, key_peer1, nil, nil)
 						rtDataplane.ResetDeltas()
 						err := wg.Apply()
 						Expect(err).NotTo(HaveOccurred())
@@ -543,7 +557,7 @@ var _ = Describe("Enable wireguard", func() {
 					})
 
 					It("should add both nodes when conflicting public keys updated to no longer conflict", func() {
-						wg.EndpointWireguardUpdate(peer2, key_peer2, nil)
+						wg.EndpointWireguardUpdate(peer2, key_peer2, nil, nil)
 						err := wg.Apply()
 						Expect(err).NotTo(HaveOccurred())
 						Expect(link.WireguardPeers).To(HaveKey(key_peer1))
@@ -797,7 +811,7 @@ var _ = Describe("Enable wireguard", func() {
 							var key_peer3 wgtypes.Key
 							BeforeEach(func() {
 								key_peer3 = mustGeneratePrivateKey()
-								wg.EndpointWireguardUpdate(peer3, key_peer3, nil)
+								wg.EndpointWireguardUpdate(peer3, key_peer3, nil, nil)
 								rtDataplane.ResetDeltas()
 								err := wg.Apply()
 								Expect(err).NotTo(HaveOccurred())
@@ -883,7 +897,7 @@ var _ = Describe("Enable wireguard", func() {
 		link.WireguardFirewallMark = 11
 
 		ipv4 := ip.FromString("1.2.3.4")
-		wg.EndpointWireguardUpdate(hostname, key, ipv4)
+		wg.EndpointWireguardUpdate(hostname, key, ipv4, nil)
 
 		err = wg.Apply()
 		Expect(err).NotTo(HaveOccurred())
@@ -908,7 +922,7 @@ var _ = Describe("Enable wireguard", func() {
 			wgDataplane.FailuresToSimulate = mocknetlink.FailNextLinkAddNotSupported
 
 			// Set the wireguard interface ip address
-			wg.EndpointWireguardUpdate(hostname, zeroKey, ipv4_peer1)
+			wg.EndpointWireguardUpdate(hostname, zeroKey, ipv4_peer1, nil)
 
 			// No error should occur
 			err := wg.Apply()
@@ -969,7 +983,7 @@ var _ = Describe("Enable wireguard", func() {
 				apply := newApplyWithErrors(wg, 1)
 
 				// Set the wireguard interface ip address
-				wg.EndpointWireguardUpdate(hostname, zeroKey, ipv4_int1)
+				wg.EndpointWireguardUpdate(hostname, zeroKey, ipv4_int1, nil)
 				err := apply.Apply()
 				Expect(err).NotTo(HaveOccurred())
 
@@ -988,11 +1002,11 @@ var _ = Describe("Enable wireguard", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				// Change the wireguard interface ip address
-				wg.EndpointWireguardUpdate(hostname, zeroKey, ipv4_int2)
+				wg.EndpointWireguardUpdate(hostname, zeroKey, ipv4_int2, nil)
 
 				// Add a single wireguard peer with a single route
 				key_peer1 = mustGeneratePrivateKey()
-				wg.EndpointWireguardUpdate(peer1, key_peer1, nil)
+				wg.EndpointWireguardUpdate(peer1, key_peer1, nil, nil)
 				wg.EndpointUpdate(peer1, ipv4_peer1)
 				wg.RouteUpdate(peer1, cidr_1)
 				wg.RouteUpdate(peer1, cidr_2)
@@ -1060,7 +1074,7 @@ var _ = Describe("Enable wireguard", func() {
 
 						// Add peer2 with one of the same CIDRs as the previous peer1, and one different CIDR
 						key_peer2 = mustGeneratePrivateKey()
-						wg.EndpointWireguardUpdate(peer2, key_peer2, nil)
+						wg.EndpointWireguardUpdate(peer2, key_peer2, nil, nil)
 						wg.EndpointUpdate(peer2, ipv4_peer2)
 						wg.RouteUpdate(peer2, cidr_1)
 						wg.RouteUpdate(peer2, cidr_3)
@@ -1112,7 +1126,7 @@ var _ = Describe("Enable wireguard", func() {
 
 				// Set the wireguard interface ip address. No error should occur because "not supported" is perfectly
 				// valid.
-				wg.EndpointWireguardUpdate(hostname, zeroKey, ipv4_peer1)
+				wg.EndpointWireguardUpdate(hostname, zeroKey, ipv4_peer1, nil)
 				err := wg.Apply()
 				Expect(err).NotTo(HaveOccurred())
 
@@ -1173,10 +1187,10 @@ var _ = Describe("Enable wireguard", func() {
 				wg.EndpointUpdate(peer2, ipv4_peer2)
 				wg.EndpointUpdate(peer3, ipv4_peer3)
 				wg.EndpointUpdate(peer4, ipv4_peer4)
-				wg.EndpointWireguardUpdate(peer1, key_peer1, nil)
-				wg.EndpointWireguardUpdate(peer2, key_peer2, nil)
-				wg.EndpointWireguardUpdate(peer3, key_peer3, nil)
-				wg.EndpointWireguardUpdate(peer4, key_peer3, nil) // Peer 3 and 4 declaring same public key
+				wg.EndpointWireguardUpdate(peer1, key_peer1, nil, nil)
+				wg.EndpointWireguardUpdate(peer2, key_peer2, nil, nil)
+				wg.EndpointWireguardUpdate(peer3, key_peer3, nil, nil)
+				wg.EndpointWireguardUpdate(peer4, key_peer3, nil, nil) // Peer 3 and 4 declaring same public key
 				wg.RouteUpdate(peer1, cidr_1)
 				wg.RouteUpdate(peer2, cidr_2)
 				wg.RouteUpdate(peer3, cidr_3)
@@ -1264,6 +1278,318 @@ var _ = Describe("Enable wireguard", func() {
 	}
 })
 
+// sharedMockNetlink allows the IPv4 and IPv6 route tables (and rules) to hold netlink handles to the same mock
+// dataplane at the same time. The underlying mock handle is closed when the last shared handle is deleted.
+type sharedMockNetlink struct {
+	*mocknetlink.MockNetlinkDataplane
+	lock    sync.Mutex
+	numOpen int
+}
+
+func (s *sharedMockNetlink) NewMockNetlink() (netlinkshim.Interface, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.numOpen == 0 {
+		if _, err := s.MockNetlinkDataplane.NewMockNetlink(); err != nil {
+			return nil, err
+		}
+	}
+	s.numOpen++
+	return &sharedMockHandle{s}, nil
+}
+
+type sharedMockHandle struct {
+	*sharedMockNetlink
+}
+
+func (h *sharedMockHandle) Delete() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.numOpen--
+	if h.numOpen == 0 {
+		h.MockNetlinkDataplane.Delete()
+	}
+}
+
+var _ = Describe("Enable wireguard with IPv6", func() {
+	var wgDataplane, rtDataplane, rrDataplane *mocknetlink.MockNetlinkDataplane
+	var t *mocktime.MockTime
+	var s *mockStatus
+	var wg *Wireguard
+	var rule, ruleV6 *netlink.Rule
+	var link *mocknetlink.MockLink
+
+	ipv6_int := ip.FromString("fd00:10::1")
+	cidr_v6_1 := ip.MustParseCIDROrIP("fd00:1::/120")
+	ipnet_v6_1 := cidr_v6_1.ToIPNet()
+
+	BeforeEach(func() {
+		wgDataplane = mocknetlink.New()
+		rtDataplane = mocknetlink.New()
+		rrDataplane = mocknetlink.New()
+		t = mocktime.New()
+		s = &mockStatus{}
+		t.SetAutoIncrement(11 * time.Second)
+
+		wg = NewWithShims(
+			hostname,
+			&Config{
+				Enabled:             true,
+				ListeningPort:       listeningPort,
+				FirewallMark:        firewallMark,
+				RoutingRulePriority: rulePriority,
+				RoutingTableIndex:   tableIndex,
+				InterfaceName:       ifaceName,
+				MTU:                 mtu,
+				EnableIPv6:          true,
+				InterfaceIPv6Addr:   ipv6_int.AsNetIP(),
+			},
+			(&sharedMockNetlink{MockNetlinkDataplane: rtDataplane}).NewMockNetlink,
+			(&sharedMockNetlink{MockNetlinkDataplane: rrDataplane}).NewMockNetlink,
+			wgDataplane.NewMockNetlink,
+			wgDataplane.NewMockWireguard,
+			10*time.Second,
+			t,
+			FelixRouteProtocol,
+			s.status,
+			logutils.NewSummarizer("test loop"),
+		)
+
+		rule = netlink.NewRule()
+		rule.Family = netlink.FAMILY_V4
+		rule.Priority = rulePriority
+		rule.Table = tableIndex
+		rule.Mark = 0
+		rule.Mask = firewallMark
+
+		ruleV6 = netlink.NewRule()
+		ruleV6.Family = netlink.FAMILY_V6
+		ruleV6.Priority = rulePriority
+		ruleV6.Table = tableIndex
+		ruleV6.Mark = 0
+		ruleV6.Mask = firewallMark
+
+		err := wg.Apply()
+		Expect(err).NotTo(HaveOccurred())
+		wgDataplane.SetIface(ifaceName, true, true)
+		wg.OnIfaceStateChanged(ifaceName, ifacemonitor.StateUp)
+		err = wg.Apply()
+		Expect(err).NotTo(HaveOccurred())
+		link = wgDataplane.NameToLink[ifaceName]
+	})
+
+	It("should publish the IPv6 interface address with the public key", func() {
+		Expect(s.numCallbacks).To(Equal(1))
+		Expect(s.key).To(Equal(link.WireguardPublicKey))
+		Expect(s.ipv6Addr).To(Equal(ipv6_int))
+	})
+
+	It("should program the IPv6 interface address", func() {
+		Expect(link.Addrs).To(HaveLen(1))
+		Expect(link.Addrs[0].IP).To(Equal(ipv6_int.AsNetIP()))
+		ones, bits := link.Addrs[0].Mask.Size()
+		Expect(ones).To(Equal(128))
+		Expect(bits).To(Equal(128))
+	})
+
+	It("should add both IPv4 and IPv6 routing rules", func() {
+		Expect(rrDataplane.AddedRules).To(ConsistOf(*rule, *ruleV6))
+	})
+
+	It("should resend status if the published IPv6 address does not match", func() {
+		wg.EndpointWireguardUpdate(hostname, s.key, nil, nil)
+		err := wg.Apply()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.numCallbacks).To(Equal(2))
+		Expect(s.ipv6Addr).To(Equal(ipv6_int))
+
+		// Once the published address agrees there is no further status update.
+		wg.EndpointWireguardUpdate(hostname, s.key, nil, ipv6_int)
+		err = wg.Apply()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.numCallbacks).To(Equal(2))
+	})
+
+	Describe("create a peer with IPv4 and IPv6 destinations", func() {
+		var key_peer1 wgtypes.Key
+		var routekey_1, routekey_v6_1 string
+
+		BeforeEach(func() {
+			key_peer1 = mustGeneratePrivateKey().PublicKey()
+			rtDataplane.NameToLink[ifaceName] = link
+			routekey_1 = fmt.Sprintf("%d-%d-%s", tableIndex, link.LinkAttrs.Index, cidr_1)
+			routekey_v6_1 = fmt.Sprintf("%d-%d-%s", tableIndex, link.LinkAttrs.Index, cidr_v6_1)
+
+			wg.EndpointUpdate(peer1, ipv4_peer1)
+			wg.EndpointWireguardUpdate(peer1, key_peer1, nil, nil)
+			wg.RouteUpdate(peer1, cidr_1)
+			wg.RouteUpdate(peer1, cidr_v6_1)
+			err := wg.Apply()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should include both families in the peer allowed IPs", func() {
+			Expect(link.WireguardPeers).To(HaveKey(key_peer1))
+			Expect(link.WireguardPeers[key_peer1].Endpoint).To(Equal(&net.UDPAddr{
+				IP:   ipv4_peer1.AsNetIP(),
+				Port: 1000,
+			}))
+			Expect(link.WireguardPeers[key_peer1].AllowedIPs).To(ConsistOf(ipnet_1, ipnet_v6_1))
+		})
+
+		It("should route both families to the wireguard device", func() {
+			Expect(rtDataplane.AddedRouteKeys).To(HaveKey(routekey_1))
+			Expect(rtDataplane.AddedRouteKeys).To(HaveKey(routekey_v6_1))
+			Expect(rtDataplane.RouteKeyToRoute[routekey_v6_1].Dst.String()).To(Equal(cidr_v6_1.String()))
+		})
+
+		It("should remove the IPv6 route when the CIDR is removed", func() {
+			rtDataplane.ResetDeltas()
+			wg.RouteRemove(cidr_v6_1)
+			err := wg.Apply()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rtDataplane.DeletedRouteKeys).To(HaveKey(routekey_v6_1))
+			Expect(link.WireguardPeers[key_peer1].AllowedIPs).To(ConsistOf(ipnet_1))
+		})
+	})
+})
+
+var _ = Describe("Wireguard key rotation", func() {
+	var wgDataplane, rtDataplane, rrDataplane *mocknetlink.MockNetlinkDataplane
+	var t *mocktime.MockTime
+	var s *mockStatus
+	var wg *Wireguard
+	var link *mocknetlink.MockLink
+	var key_peer1 wgtypes.Key
+
+	rotationInterval := time.Hour
+
+	BeforeEach(func() {
+		wgDataplane = mocknetlink.New()
+		rtDataplane = mocknetlink.New()
+		rrDataplane = mocknetlink.New()
+		t = mocktime.New()
+		s = &mockStatus{}
+
+		wg = NewWithShims(
+			hostname,
+			&Config{
+				Enabled:             true,
+				ListeningPort:       listeningPort,
+				FirewallMark:        firewallMark,
+				RoutingRulePriority: rulePriority,
+				RoutingTableIndex:   tableIndex,
+				InterfaceName:       ifaceName,
+				MTU:                 mtu,
+				KeyRotationInterval: rotationInterval,
+			},
+			rtDataplane.NewMockNetlink,
+			rrDataplane.NewMockNetlink,
+			wgDataplane.NewMockNetlink,
+			wgDataplane.NewMockWireguard,
+			10*time.Second,
+			t,
+			FelixRouteProtocol,
+			s.status,
+			logutils.NewSummarizer("test loop"),
+		)
+
+		err := wg.Apply()
+		Expect(err).NotTo(HaveOccurred())
+		wgDataplane.SetIface(ifaceName, true, true)
+		wg.OnIfaceStateChanged(ifaceName, ifacemonitor.StateUp)
+		err = wg.Apply()
+		Expect(err).NotTo(HaveOccurred())
+		link = wgDataplane.NameToLink[ifaceName]
+		rtDataplane.NameToLink[ifaceName] = link
+
+		key_peer1 = mustGeneratePrivateKey().PublicKey()
+		wg.EndpointUpdate(peer1, ipv4_peer1)
+		wg.EndpointWireguardUpdate(peer1, key_peer1, nil, nil)
+		wg.RouteUpdate(peer1, cidr_1)
+		err = wg.Apply()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(s.numCallbacks).To(Equal(1))
+		Expect(s.key).To(Equal(link.WireguardPublicKey))
+	})
+
+	It("should not rotate the key before the rotation interval", func() {
+		originalKey := link.WireguardPrivateKey
+		t.IncrementTime(rotationInterval - time.Minute)
+		err := wg.Apply()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(link.WireguardPrivateKey).To(Equal(originalKey))
+		Expect(s.numCallbacks).To(Equal(1))
+	})
+
+	It("should replace the peer but keep routing over wireguard when a peer rotates its key", func() {
+		routekey_1 := fmt.Sprintf("%d-%d-%s", tableIndex, link.LinkAttrs.Index, cidr_1)
+		Expect(rtDataplane.RouteKeyToRoute).To(HaveKey(routekey_1))
+		rtDataplane.ResetDeltas()
+
+		key_peer1_rotated := mustGeneratePrivateKey().PublicKey()
+		wg.EndpointWireguardUpdate(peer1, key_peer1_rotated, nil, nil)
+		err := wg.Apply()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(link.WireguardPeers).To(HaveLen(1))
+		Expect(link.WireguardPeers).To(HaveKey(key_peer1_rotated))
+		Expect(link.WireguardPeers[key_peer1_rotated].AllowedIPs).To(Equal([]net.IPNet{ipnet_1}))
+		Expect(rtDataplane.DeletedRouteKeys).To(BeEmpty())
+		Expect(rtDataplane.RouteKeyToRoute).To(HaveKey(routekey_1))
+	})
+
+	Describe("after the rotation interval", func() {
+		var originalKey wgtypes.Key
+
+		BeforeEach(func() {
+			originalKey = link.WireguardPrivateKey
+			rtDataplane.ResetDeltas()
+			t.IncrementTime(rotationInterval)
+			err := wg.Apply()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should replace the key and publish the new public key", func() {
+			Expect(link.WireguardPrivateKey).NotTo(Equal(originalKey))
+			Expect(link.WireguardPrivateKey.PublicKey()).To(Equal(link.WireguardPublicKey))
+			Expect(s.numCallbacks).To(Equal(2))
+			Expect(s.key).To(Equal(link.WireguardPublicKey))
+		})
+
+		It("should leave the peers and routes in place", func() {
+			Expect(wgDataplane.NumLinkAddCalls).To(Equal(1))
+			Expect(link.WireguardPeers).To(HaveLen(1))
+			Expect(link.WireguardPeers[key_peer1]).To(Equal(wgtypes.Peer{
+				PublicKey: key_peer1,
+				Endpoint: &net.UDPAddr{
+					IP:   ipv4_peer1.AsNetIP(),
+					Port: 1000,
+				},
+				AllowedIPs: []net.IPNet{ipnet_1},
+			}))
+			Expect(rtDataplane.DeletedRouteKeys).To(BeEmpty())
+		})
+
+		It("should rotate again after a further rotation interval", func() {
+			rotatedKey := link.WireguardPrivateKey
+			t.IncrementTime(rotationInterval - time.Minute)
+			err := wg.Apply()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link.WireguardPrivateKey).To(Equal(rotatedKey))
+
+			t.IncrementTime(time.Minute)
+			err = wg.Apply()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link.WireguardPrivateKey).NotTo(Equal(rotatedKey))
+			Expect(s.numCallbacks).To(Equal(3))
+			Expect(s.key).To(Equal(link.WireguardPublicKey))
+		})
+	})
+})
+
 var _ = Describe("Wireguard (disabled)", func() {
 	var wgDataplane, rtDataplane, rrDataplane *mocknetlink.MockNetlinkDataplane
 	var t *mocktime.MockTime
@@ -1327,7 +1653,7 @@ var _ = Describe("Wireguard (disabled)", func() {
 	Describe("With some endpoint updates", func() {
 		BeforeEach(func() {
 			wg.EndpointUpdate(peer1, ipv4_peer1)
-			wg.EndpointWireguardUpdate(peer1, mustGeneratePrivateKey().PublicKey(), nil)
+			wg.EndpointWireguardUpdate(peer1, mustGeneratePrivateKey().PublicKey(), nil, nil)
 			wg.RouteUpdate(peer1, cidr_1)
 			err := wg.Apply()
 			Expect(err).NotTo(HaveOccurred())